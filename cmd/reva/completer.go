@@ -85,7 +85,7 @@ func (c *Completer) argumentCompleter(args ...string) []prompt.Suggest {
 			return prompt.FilterHasPrefix(c.loginArgumentCompleter(), args[1], true)
 		}
 
-	case "ls", "mkdir":
+	case "ls", "mkdir", "reindex":
 		if len(args) == 2 {
 			return prompt.FilterHasPrefix(c.lsArgumentCompleter(true), args[1], true)
 		}
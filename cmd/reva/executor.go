@@ -46,12 +46,14 @@ func (e *Executor) Execute(s string) {
 	args := strings.Split(s, " ")
 
 	// Verify that the configuration is set, either in memory or in a file.
+	// The commands not connecting to reva do not need it.
+	offline := args[0] == "configure" || args[0] == "hash-secrets"
 	if conf == nil || conf.Host == "" {
 		c, err := readConfig()
-		if err != nil && args[0] != "configure" {
+		if err != nil && !offline {
 			fmt.Println("reva is not configured, please pass the -host flag or run the configure command")
 			return
-		} else if args[0] != "configure" {
+		} else if !offline {
 			conf = c
 		}
 	}
@@ -0,0 +1,66 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	authjson "github.com/cs3org/reva/pkg/auth/manager/json"
+	"github.com/cs3org/reva/pkg/auth/password"
+)
+
+var hashSecretsCommand = func() *command {
+	cmd := newCommand("hash-secrets")
+	cmd.Description = func() string {
+		return "hash the plaintext secrets of a json users file, or print the hash of a password"
+	}
+	cmd.Usage = func() string { return "Usage: hash-secrets [<users.json>]" }
+
+	cmd.Action = func(w ...io.Writer) error {
+		switch cmd.NArg() {
+		case 0:
+			fmt.Print("password: ")
+			secret, err := readPassword(0)
+			fmt.Println()
+			if err != nil {
+				return err
+			}
+			if secret == "" {
+				return errors.New("the password cannot be empty")
+			}
+			hash, err := password.Hash(secret)
+			if err != nil {
+				return err
+			}
+			fmt.Println(hash)
+		case 1:
+			n, err := authjson.MigrateSecrets(cmd.Arg(0))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%d secrets hashed\n", n)
+		default:
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+		return nil
+	}
+	return cmd
+}
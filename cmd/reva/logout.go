@@ -0,0 +1,89 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	gwpb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
+)
+
+var logoutCommand = func() *command {
+	cmd := newCommand("logout")
+	cmd.Description = func() string { return "logout from the reva server, revoking the access token" }
+	cmd.Usage = func() string { return "Usage: logout [-flags]" }
+	allFlag := cmd.Bool("all", false, "revoke all the sessions of the user, on every device")
+
+	cmd.ResetFlags = func() {
+		*allFlag = false
+	}
+
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() != 0 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+
+		if _, err := readToken(); err != nil {
+			fmt.Println("not logged in")
+			return nil
+		}
+
+		conn, err := getConn()
+		if err != nil {
+			return err
+		}
+		client := gwpb.NewSessionsAPIClient(conn)
+
+		ctx := getAuthContext()
+		var status *rpc.Status
+		if *allFlag {
+			res, err := client.RevokeUserSessions(ctx, &gwpb.RevokeUserSessionsRequest{})
+			if err != nil {
+				return err
+			}
+			status = res.Status
+		} else {
+			res, err := client.RevokeToken(ctx, &gwpb.RevokeTokenRequest{})
+			if err != nil {
+				return err
+			}
+			status = res.Status
+		}
+
+		switch {
+		case status.Code == rpc.Code_CODE_OK:
+		case status.Code == rpc.Code_CODE_UNIMPLEMENTED && !*allFlag:
+			// the server does not revoke tokens, just forget it
+			fmt.Println("the server does not support token revocation, the token stays valid until it expires")
+		default:
+			return formatError(status)
+		}
+
+		if err := os.Remove(getTokenFile()); err != nil {
+			return err
+		}
+		fmt.Println("OK")
+		return nil
+	}
+	return cmd
+}
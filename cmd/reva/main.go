@@ -47,6 +47,8 @@ var (
 		versionCommand(),
 		configureCommand(),
 		loginCommand(),
+		logoutCommand(),
+		hashSecretsCommand(),
 		whoamiCommand(),
 		lsCommand(),
 		listVersionsCommand(),
@@ -56,6 +58,7 @@ var (
 		rmCommand(),
 		moveCommand(),
 		mkdirCommand(),
+		reindexCommand(),
 		ocmFindAcceptedUsersCommand(),
 		ocmRemoveAcceptedUser(),
 		ocmInviteGenerateCommand(),
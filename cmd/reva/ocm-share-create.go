@@ -54,9 +54,10 @@ func ocmShareCreateCommand() *command {
 	datatx := cmd.Bool("datatx", false, "create a share for a data transfer")
 
 	rol := cmd.String("rol", "viewer", "the permission for the share (viewer or editor) / applies to webdav and webapp")
+	expiration := cmd.String("expiration", "", "set expiration time (format <yyyy-mm-dd>)")
 
 	cmd.ResetFlags = func() {
-		*grantType, *grantee, *idp, *rol, *userType, *webdav, *webapp, *datatx = "user", "", "", "viewer", "primary", false, false, false
+		*grantType, *grantee, *idp, *rol, *userType, *webdav, *webapp, *datatx, *expiration = "user", "", "", "viewer", "primary", false, false, false, ""
 	}
 
 	cmd.Action = func(w ...io.Writer) error {
@@ -77,6 +78,11 @@ func ocmShareCreateCommand() *command {
 			*webdav = true
 		}
 
+		exp, err := parseExpiration(*expiration)
+		if err != nil {
+			return err
+		}
+
 		fn := cmd.Args()[0]
 
 		ctx := getAuthContext()
@@ -161,6 +167,7 @@ func ocmShareCreateCommand() *command {
 			},
 			RecipientMeshProvider: providerInfo.ProviderInfo,
 			AccessMethods:         am,
+			Expiration:            exp,
 		}
 
 		shareRes, err := client.CreateOCMShare(ctx, shareRequest)
@@ -174,14 +181,14 @@ func ocmShareCreateCommand() *command {
 
 		t := table.NewWriter()
 		t.SetOutputMirror(os.Stdout)
-		t.AppendHeader(table.Row{"#", "Owner.Idp", "Owner.OpaqueId", "ResourceId", "Type", "Grantee.Idp", "Grantee.OpaqueId", "Created", "Updated"})
+		t.AppendHeader(table.Row{"#", "Owner.Idp", "Owner.OpaqueId", "ResourceId", "Type", "Grantee.Idp", "Grantee.OpaqueId", "Created", "Updated", "Expiration"})
 		// TODO (gdelmont): expose protocols info
 
 		s := shareRes.Share
 		t.AppendRows([]table.Row{
 			{s.Id.OpaqueId, s.Owner.Idp, s.Owner.OpaqueId, s.ResourceId.String(),
 				s.Grantee.Type.String(), s.Grantee.GetUserId().Idp, s.Grantee.GetUserId().OpaqueId,
-				time.Unix(int64(s.Ctime.Seconds), 0), time.Unix(int64(s.Mtime.Seconds), 0)},
+				time.Unix(int64(s.Ctime.Seconds), 0), time.Unix(int64(s.Mtime.Seconds), 0), formatTime(s.Expiration)},
 		})
 		t.Render()
 
@@ -190,6 +197,19 @@ func ocmShareCreateCommand() *command {
 	return cmd
 }
 
+// parseExpiration parses an expiration date in the <yyyy-mm-dd> format.
+// An empty expiration returns a nil timestamp.
+func parseExpiration(e string) (*types.Timestamp, error) {
+	if e == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(layoutTime, e)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid expiration")
+	}
+	return &types.Timestamp{Seconds: uint64(t.Unix())}, nil
+}
+
 func getAccessMethods(webdav, webapp, datatx bool, rol string) ([]*ocm.AccessMethod, error) {
 	var m []*ocm.AccessMethod
 	if webdav {
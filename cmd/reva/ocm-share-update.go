@@ -32,11 +32,13 @@ func ocmShareUpdateCommand() *command {
 	cmd.Description = func() string { return "update an OCM share" }
 	cmd.Usage = func() string { return "Usage: ocm-share-update [-flags] <share_id>" }
 
-	webdavRol := cmd.String("webdav-rol", "viewer", "the permission for the WebDAV access method (viewer or editor)")
-	webappViewMode := cmd.String("webapp-mode", "view", "the view mode for the Webapp access method (read or write)")
+	webdavRol := cmd.String("webdav-rol", "", "the permission for the WebDAV access method (viewer or editor)")
+	webappViewMode := cmd.String("webapp-mode", "", "the view mode for the Webapp access method (viewer or editor)")
+	expiration := cmd.String("expiration", "", "set expiration time (format <yyyy-mm-dd>)")
 
+	// only the fields given as flags are updated
 	cmd.ResetFlags = func() {
-		*webdavRol, *webappViewMode = "viewer", "read"
+		*webdavRol, *webappViewMode, *expiration = "", "", ""
 	}
 	cmd.Action = func(w ...io.Writer) error {
 		if cmd.NArg() < 1 {
@@ -45,8 +47,8 @@ func ocmShareUpdateCommand() *command {
 
 		id := cmd.Args()[0]
 
-		if *webdavRol == "" && *webappViewMode == "" {
-			return errors.New("use at least one of -webdav-rol or -webapp-mode flag")
+		if *webdavRol == "" && *webappViewMode == "" && *expiration == "" {
+			return errors.New("use at least one of -webdav-rol, -webapp-mode or -expiration flag")
 		}
 
 		ctx := getAuthContext()
@@ -102,6 +104,18 @@ func ocmShareUpdateCommand() *command {
 			})
 		}
 
+		if *expiration != "" {
+			exp, err := parseExpiration(*expiration)
+			if err != nil {
+				return err
+			}
+			shareRequest.Field = append(shareRequest.Field, &ocm.UpdateOCMShareRequest_UpdateField{
+				Field: &ocm.UpdateOCMShareRequest_UpdateField_Expiration{
+					Expiration: exp,
+				},
+			})
+		}
+
 		shareRes, err := shareClient.UpdateOCMShare(ctx, shareRequest)
 		if err != nil {
 			return err
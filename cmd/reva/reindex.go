@@ -0,0 +1,64 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	sppb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
+	"github.com/pkg/errors"
+)
+
+func reindexCommand() *command {
+	cmd := newCommand("reindex")
+	cmd.Description = func() string {
+		return "rebuilds the search index of a container, to pick up the changes made out of band"
+	}
+	cmd.Usage = func() string { return "Usage: reindex [-flags] <container_name>" }
+	cmd.Action = func(w ...io.Writer) error {
+		if cmd.NArg() < 1 {
+			return errors.New("Invalid arguments: " + cmd.Usage())
+		}
+
+		fn := cmd.Args()[0]
+
+		conn, err := getConn()
+		if err != nil {
+			return err
+		}
+		client := sppb.NewSearchAPIClient(conn)
+
+		ctx := getAuthContext()
+		res, err := client.Reindex(ctx, &sppb.ReindexRequest{Ref: &provider.Reference{Path: fn}})
+		if err != nil {
+			return err
+		}
+
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return formatError(res.Status)
+		}
+
+		fmt.Println("OK")
+		return nil
+	}
+	return cmd
+}
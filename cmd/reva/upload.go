@@ -95,6 +95,10 @@ func uploadCommand() *command {
 						Decoder: "plain",
 						Value:   []byte(strconv.FormatInt(md.Size(), 10)),
 					},
+					"protocol": {
+						Decoder: "plain",
+						Value:   []byte(*protocolFlag),
+					},
 				},
 			},
 		}
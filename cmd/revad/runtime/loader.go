@@ -47,12 +47,15 @@ import (
 	_ "github.com/cs3org/reva/pkg/prom/loader"
 	_ "github.com/cs3org/reva/pkg/publicshare/manager/loader"
 	_ "github.com/cs3org/reva/pkg/rhttp/datatx/manager/loader"
+	_ "github.com/cs3org/reva/pkg/search/loader"
 	_ "github.com/cs3org/reva/pkg/share/cache/loader"
 	_ "github.com/cs3org/reva/pkg/share/cache/warmup/loader"
 	_ "github.com/cs3org/reva/pkg/share/manager/loader"
 	_ "github.com/cs3org/reva/pkg/storage/favorite/loader"
 	_ "github.com/cs3org/reva/pkg/storage/fs/loader"
 	_ "github.com/cs3org/reva/pkg/storage/registry/loader"
+	_ "github.com/cs3org/reva/pkg/tags/loader"
 	_ "github.com/cs3org/reva/pkg/token/manager/loader"
+	_ "github.com/cs3org/reva/pkg/token/revocation/loader"
 	_ "github.com/cs3org/reva/pkg/user/manager/loader"
 )
@@ -33,6 +33,7 @@ import (
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/token"
 	tokenmgr "github.com/cs3org/reva/pkg/token/manager/registry"
+	revocationregistry "github.com/cs3org/reva/pkg/token/revocation/registry"
 	"github.com/cs3org/reva/pkg/user"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
@@ -51,7 +52,10 @@ type config struct {
 	TokenManager  string                            `mapstructure:"token_manager"`
 	TokenManagers map[string]map[string]interface{} `mapstructure:"token_managers"`
 	GatewayAddr   string                            `mapstructure:"gateway_addr"`
-	blockedUsers  []string
+	// RevocationStore, if set, is used to reject the revoked tokens.
+	RevocationStore  string                            `mapstructure:"revocation_store"`
+	RevocationStores map[string]map[string]interface{} `mapstructure:"revocation_stores"`
+	blockedUsers     []string
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -93,6 +97,11 @@ func NewUnary(m map[string]interface{}, unprotected []string) (grpc.UnaryServerI
 		return nil, errors.Wrap(err, "auth: error creating token manager")
 	}
 
+	tokenManager, err = revocationregistry.WrapManager(tokenManager, conf.RevocationStore, conf.RevocationStores)
+	if err != nil {
+		return nil, err
+	}
+
 	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		log := appctx.GetLogger(ctx)
 
@@ -153,6 +162,11 @@ func NewStream(m map[string]interface{}, unprotected []string) (grpc.StreamServe
 		return nil, errtypes.NotFound("auth: token manager not found: " + conf.TokenManager)
 	}
 
+	tokenManager, err = revocationregistry.WrapManager(tokenManager, conf.RevocationStore, conf.RevocationStores)
+	if err != nil {
+		return nil, err
+	}
+
 	interceptor := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		ctx := ss.Context()
 		log := appctx.GetLogger(ctx)
@@ -26,36 +26,41 @@ import (
 	"google.golang.org/grpc/metadata"
 )
 
+// forwardedHeaders are the headers describing the client
+// forwarded to the services called in turn.
+var forwardedHeaders = []string{appctx.UserAgentHeader, appctx.ClientAddrHeader}
+
 // NewUnary returns a new unary interceptor that adds
-// the useragent to the context.
+// the useragent and the client address to the context.
 func NewUnary() grpc.UnaryServerInterceptor {
 	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if md, ok := metadata.FromIncomingContext(ctx); ok {
-			if lst, ok := md[appctx.UserAgentHeader]; ok && len(lst) != 0 {
-				ctx = metadata.AppendToOutgoingContext(ctx, appctx.UserAgentHeader, lst[0])
-			}
-		}
-		return handler(ctx, req)
+		return handler(forward(ctx), req)
 	}
 	return interceptor
 }
 
 // NewStream returns a new server stream interceptor
-// that adds the user agent to the context.
+// that adds the user agent and the client address to the context.
 func NewStream() grpc.StreamServerInterceptor {
 	interceptor := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		ctx := ss.Context()
-		if md, ok := metadata.FromIncomingContext(ctx); ok {
-			if lst, ok := md[appctx.UserAgentHeader]; ok && len(lst) != 0 {
-				ctx = metadata.AppendToOutgoingContext(ctx, appctx.UserAgentHeader, lst[0])
-			}
-		}
+		ctx := forward(ss.Context())
 		wrapped := newWrappedServerStream(ctx, ss)
 		return handler(srv, wrapped)
 	}
 	return interceptor
 }
 
+func forward(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, h := range forwardedHeaders {
+			if lst, ok := md[h]; ok && len(lst) != 0 {
+				ctx = metadata.AppendToOutgoingContext(ctx, h, lst[0])
+			}
+		}
+	}
+	return ctx
+}
+
 func newWrappedServerStream(ctx context.Context, ss grpc.ServerStream) *wrappedServerStream {
 	return &wrappedServerStream{ServerStream: ss, newCtx: ctx}
 }
@@ -0,0 +1,377 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strconv"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/datagateway"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/httpclient"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// crossStorageMove moves a resource between two different storage providers.
+func (s *svc) crossStorageMove(ctx context.Context, req *provider.MoveRequest, srcProvider, dstProvider *registry.ProviderInfo) (*provider.MoveResponse, error) {
+	srcClient, err := s.getStorageProviderClient(ctx, srcProvider)
+	if err != nil {
+		return &provider.MoveResponse{
+			Status: status.NewInternal(ctx, err, "error connecting to storage provider="+srcProvider.Address),
+		}, nil
+	}
+	dstClient, err := s.getStorageProviderClient(ctx, dstProvider)
+	if err != nil {
+		return &provider.MoveResponse{
+			Status: status.NewInternal(ctx, err, "error connecting to storage provider="+dstProvider.Address),
+		}, nil
+	}
+
+	appctx.GetLogger(ctx).Info().Str("src", req.Source.String()).Str("dst", req.Destination.String()).
+		Str("src_provider", srcProvider.Address).Str("dst_provider", dstProvider.Address).
+		Msg("gateway: moving resource across storage providers")
+
+	m := &crossStorageMover{
+		src:      srcClient,
+		dst:      dstClient,
+		copyFile: s.crossStorageCopyFile,
+	}
+	return m.move(ctx, req), nil
+}
+
+// crossStorageMover moves resources from one storage provider to another.
+// The tree is recreated on the destination, the files are streamed through the
+// data gateway together with their arbitrary metadata and grants, and the source
+// is deleted only once every copied file has been verified against its source.
+type crossStorageMover struct {
+	src, dst provider.ProviderAPIClient
+	// copyFile transfers the content of a single file to dst.
+	copyFile func(ctx context.Context, info *provider.ResourceInfo, dst *provider.Reference) error
+}
+
+func (m *crossStorageMover) move(ctx context.Context, req *provider.MoveRequest) *provider.MoveResponse {
+	log := appctx.GetLogger(ctx)
+
+	srcStatRes, err := m.src.Stat(ctx, &provider.StatRequest{Ref: req.Source, ArbitraryMetadataKeys: []string{"*"}})
+	if err != nil {
+		return &provider.MoveResponse{
+			Status: status.NewInternal(ctx, err, "gateway: error stating ref:"+req.Source.String()),
+		}
+	}
+	if srcStatRes.Status.Code != rpc.Code_CODE_OK {
+		return &provider.MoveResponse{
+			Status: srcStatRes.Status,
+		}
+	}
+
+	dstStatRes, err := m.dst.Stat(ctx, &provider.StatRequest{Ref: req.Destination})
+	if err != nil {
+		return &provider.MoveResponse{
+			Status: status.NewInternal(ctx, err, "gateway: error stating ref:"+req.Destination.String()),
+		}
+	}
+	switch dstStatRes.Status.Code {
+	case rpc.Code_CODE_NOT_FOUND:
+	case rpc.Code_CODE_OK:
+		return &provider.MoveResponse{
+			Status: status.NewAlreadyExists(ctx, nil, "gateway: destination already exists: "+req.Destination.String()),
+		}
+	default:
+		return &provider.MoveResponse{
+			Status: dstStatRes.Status,
+		}
+	}
+
+	if err := m.copy(ctx, srcStatRes.Info, req.Destination); err != nil {
+		// do not leave a partial copy behind, the source is still untouched
+		if res, derr := m.dst.Delete(ctx, &provider.DeleteRequest{Ref: req.Destination}); derr != nil || res.Status.Code != rpc.Code_CODE_OK {
+			log.Error().Err(derr).Str("dst", req.Destination.String()).Msg("gateway: error cleaning up partial cross storage copy")
+		}
+		return &provider.MoveResponse{
+			Status: status.NewStatusFromErrType(ctx, "cross storage move "+req.Source.String(), err),
+		}
+	}
+
+	delRes, err := m.src.Delete(ctx, &provider.DeleteRequest{Ref: req.Source})
+	if err != nil {
+		return &provider.MoveResponse{
+			Status: status.NewInternal(ctx, err, "gateway: error deleting source after cross storage copy: "+req.Source.String()),
+		}
+	}
+	if delRes.Status.Code != rpc.Code_CODE_OK {
+		log.Error().Str("src", req.Source.String()).Interface("status", delRes.Status).
+			Msg("gateway: resource was copied but the source could not be deleted")
+		return &provider.MoveResponse{
+			Status: delRes.Status,
+		}
+	}
+
+	return &provider.MoveResponse{
+		Status: status.NewOK(ctx),
+	}
+}
+
+// copy recursively copies the resource described by info to dst.
+func (m *crossStorageMover) copy(ctx context.Context, info *provider.ResourceInfo, dst *provider.Reference) error {
+	switch info.Type {
+	case provider.ResourceType_RESOURCE_TYPE_CONTAINER:
+		createRes, err := m.dst.CreateContainer(ctx, &provider.CreateContainerRequest{Ref: dst})
+		if err != nil {
+			return errors.Wrap(err, "gateway: error calling CreateContainer")
+		}
+		if createRes.Status.Code != rpc.Code_CODE_OK {
+			return errFromStatus(createRes.Status, "create container "+dst.String())
+		}
+
+		listRes, err := m.src.ListContainer(ctx, &provider.ListContainerRequest{
+			Ref:                   &provider.Reference{ResourceId: info.Id},
+			ArbitraryMetadataKeys: []string{"*"},
+		})
+		if err != nil {
+			return errors.Wrap(err, "gateway: error calling ListContainer")
+		}
+		if listRes.Status.Code != rpc.Code_CODE_OK {
+			return errFromStatus(listRes.Status, "list container "+info.Path)
+		}
+
+		for _, child := range listRes.Infos {
+			if err := m.copy(ctx, child, childReference(dst, path.Base(child.Path))); err != nil {
+				return err
+			}
+		}
+	case provider.ResourceType_RESOURCE_TYPE_FILE:
+		if err := m.copyFile(ctx, info, dst); err != nil {
+			return err
+		}
+		if err := verifyCopy(ctx, m.dst, info, dst); err != nil {
+			return err
+		}
+	default:
+		return errtypes.NotSupported("gateway: cross storage copy of resource type " + info.Type.String())
+	}
+
+	if md := info.GetArbitraryMetadata().GetMetadata(); len(md) > 0 {
+		res, err := m.dst.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+			Ref:               dst,
+			ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: md},
+		})
+		if err != nil {
+			return errors.Wrap(err, "gateway: error calling SetArbitraryMetadata")
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return errFromStatus(res.Status, "set arbitrary metadata "+dst.String())
+		}
+	}
+
+	return m.copyGrants(ctx, info, dst)
+}
+
+// copyGrants sets the grants of the source resource on its copy.
+// A move is refused when the grants cannot be carried over,
+// as the resource would otherwise lose its shares silently.
+func (m *crossStorageMover) copyGrants(ctx context.Context, info *provider.ResourceInfo, dst *provider.Reference) error {
+	listRes, err := m.src.ListGrants(ctx, &provider.ListGrantsRequest{Ref: &provider.Reference{ResourceId: info.Id}})
+	if err != nil {
+		return errors.Wrap(err, "gateway: error calling ListGrants")
+	}
+	switch listRes.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_UNIMPLEMENTED:
+		// a storage without grants has nothing to carry over
+		return nil
+	default:
+		return errFromStatus(listRes.Status, "list grants "+info.Path)
+	}
+
+	for _, g := range listRes.Grants {
+		res, err := m.dst.AddGrant(ctx, &provider.AddGrantRequest{Ref: dst, Grant: g})
+		if err != nil {
+			return errors.Wrap(err, "gateway: error calling AddGrant")
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return errFromStatus(res.Status, "add grant on "+dst.String())
+		}
+	}
+
+	return nil
+}
+
+// crossStorageCopyFile streams a single file through the data gateway.
+func (s *svc) crossStorageCopyFile(ctx context.Context, info *provider.ResourceInfo, dst *provider.Reference) error {
+	downRes, err := s.initiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: &provider.Reference{ResourceId: info.Id}})
+	if err != nil {
+		return err
+	}
+	if downRes.Status.Code != rpc.Code_CODE_OK {
+		return errFromStatus(downRes.Status, "initiate download "+info.Path)
+	}
+
+	upRes, err := s.initiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: dst,
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				"Upload-Length": {
+					Decoder: "plain",
+					Value:   []byte(strconv.FormatUint(info.Size, 10)),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if upRes.Status.Code != rpc.Code_CODE_OK {
+		return errFromStatus(upRes.Status, "initiate upload "+dst.String())
+	}
+
+	downloadEP, downloadToken, ok := getDownloadEndpoint(downRes.Protocols)
+	if !ok {
+		return errtypes.NotSupported("gateway: source storage does not offer a simple download")
+	}
+	uploadEP, uploadToken, ok := getUploadEndpoint(upRes.Protocols)
+	if !ok {
+		return errtypes.NotSupported("gateway: destination storage does not offer a simple upload")
+	}
+
+	client := httpclient.New()
+
+	httpDownloadReq, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadEP, nil)
+	if err != nil {
+		return err
+	}
+	httpDownloadReq.Header.Set(datagateway.TokenTransportHeader, downloadToken)
+
+	httpDownloadRes, err := client.Do(httpDownloadReq)
+	if err != nil {
+		return errors.Wrap(err, "gateway: error downloading "+info.Path)
+	}
+	defer httpDownloadRes.Body.Close()
+	if httpDownloadRes.StatusCode != http.StatusOK {
+		return errtypes.InternalError("gateway: error downloading " + info.Path + ": " + httpDownloadRes.Status)
+	}
+
+	httpUploadReq, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadEP, httpDownloadRes.Body)
+	if err != nil {
+		return err
+	}
+	httpUploadReq.ContentLength = int64(info.Size)
+	httpUploadReq.Header.Set(datagateway.TokenTransportHeader, uploadToken)
+
+	httpUploadRes, err := client.Do(httpUploadReq)
+	if err != nil {
+		return errors.Wrap(err, "gateway: error uploading "+dst.String())
+	}
+	defer httpUploadRes.Body.Close()
+	if httpUploadRes.StatusCode != http.StatusOK && httpUploadRes.StatusCode != http.StatusCreated {
+		return errtypes.InternalError("gateway: error uploading " + dst.String() + ": " + httpUploadRes.Status)
+	}
+
+	return nil
+}
+
+// verifyCopy checks that the copied file matches the source in size and,
+// when both storages compute the same checksum type, in checksum.
+func verifyCopy(ctx context.Context, dstClient provider.ProviderAPIClient, src *provider.ResourceInfo, dst *provider.Reference) error {
+	res, err := dstClient.Stat(ctx, &provider.StatRequest{Ref: dst})
+	if err != nil {
+		return errors.Wrap(err, "gateway: error calling Stat")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return errFromStatus(res.Status, "stat copied file "+dst.String())
+	}
+
+	if res.Info.Size != src.Size {
+		return errtypes.ChecksumMismatch("gateway: size of the copy of " + src.Path + " does not match the source")
+	}
+
+	srcXS, dstXS := src.GetChecksum(), res.Info.GetChecksum()
+	if srcXS.GetSum() != "" && dstXS.GetSum() != "" && srcXS.Type == dstXS.Type && srcXS.Sum != dstXS.Sum {
+		return errtypes.ChecksumMismatch("gateway: checksum of the copy of " + src.Path + " does not match the source")
+	}
+
+	return nil
+}
+
+func getDownloadEndpoint(protocols []*gateway.FileDownloadProtocol) (string, string, bool) {
+	for _, p := range protocols {
+		if p.Protocol == "simple" || p.Protocol == "spaces" {
+			return p.DownloadEndpoint, p.Token, true
+		}
+	}
+	return "", "", false
+}
+
+func getUploadEndpoint(protocols []*gateway.FileUploadProtocol) (string, string, bool) {
+	for _, p := range protocols {
+		if p.Protocol == "simple" {
+			return p.UploadEndpoint, p.Token, true
+		}
+	}
+	return "", "", false
+}
+
+// childReference returns a reference to the child name of ref,
+// keeping ref relative if it was relative.
+func childReference(ref *provider.Reference, name string) *provider.Reference {
+	if utils.IsRelativeReference(ref) {
+		return &provider.Reference{
+			ResourceId: ref.ResourceId,
+			Path:       utils.MakeRelativePath(path.Join(ref.Path, name)),
+		}
+	}
+	if ref.Path == "" {
+		return &provider.Reference{
+			ResourceId: ref.ResourceId,
+			Path:       utils.MakeRelativePath(name),
+		}
+	}
+	return &provider.Reference{
+		ResourceId: ref.ResourceId,
+		Path:       path.Join(ref.Path, name),
+	}
+}
+
+func errFromStatus(st *rpc.Status, msg string) error {
+	switch st.Code {
+	case rpc.Code_CODE_NOT_FOUND:
+		return errtypes.NotFound(msg)
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		return errtypes.PermissionDenied(msg)
+	case rpc.Code_CODE_ALREADY_EXISTS:
+		return errtypes.AlreadyExists(msg)
+	case rpc.Code_CODE_UNIMPLEMENTED:
+		return errtypes.NotSupported(msg)
+	case rpc.Code_CODE_INSUFFICIENT_STORAGE:
+		return errtypes.InsufficientStorage(msg)
+	case rpc.Code_CODE_INVALID_ARGUMENT, rpc.Code_CODE_FAILED_PRECONDITION:
+		return errtypes.BadRequest(msg)
+	default:
+		return errtypes.InternalError(msg + ": " + st.Message)
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"google.golang.org/grpc"
+)
+
+// fakeStorage is an in-memory storage provider addressing resources by path,
+// which is also used as their opaque id.
+type fakeStorage struct {
+	provider.ProviderAPIClient
+	nodes    map[string]*fakeNode
+	noGrants bool
+}
+
+type fakeNode struct {
+	info   *provider.ResourceInfo
+	grants []*provider.Grant
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{nodes: map[string]*fakeNode{}}
+}
+
+func (f *fakeStorage) add(p string, t provider.ResourceType, size uint64, md map[string]string, grants ...*provider.Grant) {
+	f.nodes[p] = &fakeNode{
+		info: &provider.ResourceInfo{
+			Id:                &provider.ResourceId{OpaqueId: p},
+			Path:              p,
+			Type:              t,
+			Size:              size,
+			ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: md},
+		},
+		grants: grants,
+	}
+}
+
+func (f *fakeStorage) path(ref *provider.Reference) string {
+	if ref.Path == "" {
+		return ref.GetResourceId().GetOpaqueId()
+	}
+	return ref.Path
+}
+
+func (f *fakeStorage) paths() []string {
+	var paths []string
+	for p := range f.nodes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func statusNotFound() *rpc.Status { return &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND} }
+
+func statusOK() *rpc.Status { return &rpc.Status{Code: rpc.Code_CODE_OK} }
+
+func (f *fakeStorage) Stat(ctx context.Context, req *provider.StatRequest, opts ...grpc.CallOption) (*provider.StatResponse, error) {
+	n, found := f.nodes[f.path(req.Ref)]
+	if !found {
+		return &provider.StatResponse{Status: statusNotFound()}, nil
+	}
+	return &provider.StatResponse{Status: statusOK(), Info: n.info}, nil
+}
+
+func (f *fakeStorage) CreateContainer(ctx context.Context, req *provider.CreateContainerRequest, opts ...grpc.CallOption) (*provider.CreateContainerResponse, error) {
+	p := f.path(req.Ref)
+	if _, found := f.nodes[p]; found {
+		return &provider.CreateContainerResponse{Status: &rpc.Status{Code: rpc.Code_CODE_ALREADY_EXISTS}}, nil
+	}
+	f.add(p, provider.ResourceType_RESOURCE_TYPE_CONTAINER, 0, nil)
+	return &provider.CreateContainerResponse{Status: statusOK()}, nil
+}
+
+func (f *fakeStorage) ListContainer(ctx context.Context, req *provider.ListContainerRequest, opts ...grpc.CallOption) (*provider.ListContainerResponse, error) {
+	p := f.path(req.Ref)
+	if _, found := f.nodes[p]; !found {
+		return &provider.ListContainerResponse{Status: statusNotFound()}, nil
+	}
+	res := &provider.ListContainerResponse{Status: statusOK()}
+	for _, c := range f.paths() {
+		if path.Dir(c) == p && c != p {
+			res.Infos = append(res.Infos, f.nodes[c].info)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeStorage) SetArbitraryMetadata(ctx context.Context, req *provider.SetArbitraryMetadataRequest, opts ...grpc.CallOption) (*provider.SetArbitraryMetadataResponse, error) {
+	n, found := f.nodes[f.path(req.Ref)]
+	if !found {
+		return &provider.SetArbitraryMetadataResponse{Status: statusNotFound()}, nil
+	}
+	n.info.ArbitraryMetadata = req.ArbitraryMetadata
+	return &provider.SetArbitraryMetadataResponse{Status: statusOK()}, nil
+}
+
+func (f *fakeStorage) ListGrants(ctx context.Context, req *provider.ListGrantsRequest, opts ...grpc.CallOption) (*provider.ListGrantsResponse, error) {
+	n, found := f.nodes[f.path(req.Ref)]
+	if !found {
+		return &provider.ListGrantsResponse{Status: statusNotFound()}, nil
+	}
+	return &provider.ListGrantsResponse{Status: statusOK(), Grants: n.grants}, nil
+}
+
+func (f *fakeStorage) AddGrant(ctx context.Context, req *provider.AddGrantRequest, opts ...grpc.CallOption) (*provider.AddGrantResponse, error) {
+	if f.noGrants {
+		return &provider.AddGrantResponse{Status: &rpc.Status{Code: rpc.Code_CODE_UNIMPLEMENTED}}, nil
+	}
+	n, found := f.nodes[f.path(req.Ref)]
+	if !found {
+		return &provider.AddGrantResponse{Status: statusNotFound()}, nil
+	}
+	n.grants = append(n.grants, req.Grant)
+	return &provider.AddGrantResponse{Status: statusOK()}, nil
+}
+
+func (f *fakeStorage) Delete(ctx context.Context, req *provider.DeleteRequest, opts ...grpc.CallOption) (*provider.DeleteResponse, error) {
+	p := f.path(req.Ref)
+	if _, found := f.nodes[p]; !found {
+		return &provider.DeleteResponse{Status: statusNotFound()}, nil
+	}
+	for c := range f.nodes {
+		if c == p || strings.HasPrefix(c, p+"/") {
+			delete(f.nodes, c)
+		}
+	}
+	return &provider.DeleteResponse{Status: statusOK()}, nil
+}
+
+func TestCrossStorageMove(t *testing.T) {
+	grant := &provider.Grant{
+		Grantee: &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id:   &provider.Grantee_UserId{UserId: &userpb.UserId{OpaqueId: "einstein", Idp: "cernbox.cern.ch"}},
+		},
+		Permissions: &provider.ResourcePermissions{Stat: true, InitiateFileDownload: true},
+	}
+
+	tests := []struct {
+		description string
+		// failing is the source file whose transfer fails
+		failing string
+		// truncated is the source file whose copy is one byte short
+		truncated string
+		noGrants  bool
+		dstExists bool
+		code      rpc.Code
+	}{
+		{description: "move a tree", code: rpc.Code_CODE_OK},
+		{description: "transfer failure", failing: "/src/dir/b.txt", code: rpc.Code_CODE_INTERNAL},
+		{description: "size mismatch", truncated: "/src/dir/b.txt", code: rpc.Code_CODE_INTERNAL},
+		{description: "destination without grants", noGrants: true, code: rpc.Code_CODE_UNIMPLEMENTED},
+		{description: "destination exists", dstExists: true, code: rpc.Code_CODE_ALREADY_EXISTS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			src, dst := newFakeStorage(), newFakeStorage()
+			dst.noGrants = tt.noGrants
+			src.add("/src", provider.ResourceType_RESOURCE_TYPE_CONTAINER, 0, map[string]string{"color": "red"})
+			src.add("/src/a.txt", provider.ResourceType_RESOURCE_TYPE_FILE, 3, map[string]string{"tag": "a"})
+			src.add("/src/dir", provider.ResourceType_RESOURCE_TYPE_CONTAINER, 0, nil, grant)
+			src.add("/src/dir/b.txt", provider.ResourceType_RESOURCE_TYPE_FILE, 5, nil)
+			srcPaths := src.paths()
+			if tt.dstExists {
+				dst.add("/dst", provider.ResourceType_RESOURCE_TYPE_CONTAINER, 0, nil)
+			}
+
+			m := &crossStorageMover{
+				src: src,
+				dst: dst,
+				copyFile: func(ctx context.Context, info *provider.ResourceInfo, ref *provider.Reference) error {
+					if info.Path == tt.failing {
+						return errtypes.InternalError("transfer failed")
+					}
+					size := info.Size
+					if info.Path == tt.truncated {
+						size--
+					}
+					dst.add(ref.Path, provider.ResourceType_RESOURCE_TYPE_FILE, size, nil)
+					return nil
+				},
+			}
+
+			res := m.move(context.Background(), &provider.MoveRequest{
+				Source:      &provider.Reference{Path: "/src"},
+				Destination: &provider.Reference{Path: "/dst"},
+			})
+			if res.Status.Code != tt.code {
+				t.Fatalf("got status %v, expected %v", res.Status.Code, tt.code)
+			}
+
+			if tt.code != rpc.Code_CODE_OK {
+				// the source is untouched and no partial copy is left behind
+				if got := strings.Join(src.paths(), ","); got != strings.Join(srcPaths, ",") {
+					t.Fatalf("source was modified: %s", got)
+				}
+				expected := ""
+				if tt.dstExists {
+					expected = "/dst"
+				}
+				if got := strings.Join(dst.paths(), ","); got != expected {
+					t.Fatalf("got destination %q, expected %q", got, expected)
+				}
+				return
+			}
+
+			if len(src.nodes) != 0 {
+				t.Fatalf("source was not deleted: %v", src.paths())
+			}
+			if got := strings.Join(dst.paths(), ","); got != "/dst,/dst/a.txt,/dst/dir,/dst/dir/b.txt" {
+				t.Fatalf("unexpected destination tree %s", got)
+			}
+			if md := dst.nodes["/dst"].info.ArbitraryMetadata.Metadata; md["color"] != "red" {
+				t.Fatalf("metadata of the container was not copied: %v", md)
+			}
+			if md := dst.nodes["/dst/a.txt"].info.ArbitraryMetadata.Metadata; md["tag"] != "a" {
+				t.Fatalf("metadata of the file was not copied: %v", md)
+			}
+			if g := dst.nodes["/dst/dir"].grants; len(g) != 1 || g[0] != grant {
+				t.Fatalf("grants were not copied: %v", g)
+			}
+		})
+	}
+}
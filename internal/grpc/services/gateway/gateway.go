@@ -27,6 +27,8 @@ import (
 
 	"github.com/ReneKroon/ttlcache/v2"
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	gwpb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
+	sppb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/share/cache"
@@ -34,6 +36,8 @@ import (
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/token"
 	"github.com/cs3org/reva/pkg/token/manager/registry"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	revocationregistry "github.com/cs3org/reva/pkg/token/revocation/registry"
 	"github.com/cs3org/reva/pkg/utils/cfg"
 	"google.golang.org/grpc"
 )
@@ -77,6 +81,9 @@ type config struct {
 	ResourceInfoCacheDriver  string                            `mapstructure:"resource_info_cache_type"`
 	ResourceInfoCacheTTL     int                               `mapstructure:"resource_info_cache_ttl"`
 	ResourceInfoCacheDrivers map[string]map[string]interface{} `mapstructure:"resource_info_caches"`
+	// RevocationStore, if set, enables the revocation of the tokens.
+	RevocationStore  string                            `mapstructure:"revocation_store"`
+	RevocationStores map[string]map[string]interface{} `mapstructure:"revocation_stores"`
 }
 
 // sets defaults.
@@ -121,9 +128,12 @@ func (c *config) ApplyDefaults() {
 }
 
 type svc struct {
+	gwpb.UnimplementedSessionsAPIServer
+	sppb.UnimplementedSearchAPIServer
 	c                    *config
 	dataGatewayURL       url.URL
 	tokenmgr             token.Manager
+	revocations          revocation.Store
 	etagCache            *ttlcache.Cache `mapstructure:"etag_cache"`
 	createHomeCache      *ttlcache.Cache `mapstructure:"create_home_cache"`
 	resourceInfoCache    cache.ResourceInfoCache
@@ -150,6 +160,14 @@ func New(ctx context.Context, m map[string]interface{}) (rgrpc.Service, error) {
 		return nil, err
 	}
 
+	var revocations revocation.Store
+	if c.RevocationStore != "" {
+		if revocations, err = revocationregistry.GetStore(c.RevocationStore, c.RevocationStores); err != nil {
+			return nil, err
+		}
+		tokenManager = revocation.NewManager(tokenManager, revocations)
+	}
+
 	etagCache := ttlcache.NewCache()
 	_ = etagCache.SetTTL(time.Duration(c.EtagCacheTTL) * time.Second)
 	etagCache.SkipTTLExtensionOnHit(true)
@@ -162,6 +180,7 @@ func New(ctx context.Context, m map[string]interface{}) (rgrpc.Service, error) {
 		c:               &c,
 		dataGatewayURL:  *u,
 		tokenmgr:        tokenManager,
+		revocations:     revocations,
 		etagCache:       etagCache,
 		createHomeCache: createHomeCache,
 	}
@@ -177,6 +196,8 @@ func New(ctx context.Context, m map[string]interface{}) (rgrpc.Service, error) {
 
 func (s *svc) Register(ss *grpc.Server) {
 	gateway.RegisterGatewayAPIServer(ss, s)
+	gwpb.RegisterSessionsAPIServer(ss, s)
+	sppb.RegisterSearchAPIServer(ss, s)
 }
 
 func (s *svc) Close() error {
@@ -194,6 +215,7 @@ func (s *svc) UnprotectedEndpoints() []string {
 		"/cs3.gateway.v1beta1.GatewayAPI/GetAuthProvider",
 		"/cs3.gateway.v1beta1.GatewayAPI/ListAuthProviders",
 		"/cs3.gateway.v1beta1.GatewayAPI/CreateOCMCoreShare",
+		"/cs3.gateway.v1beta1.GatewayAPI/DeleteOCMCoreShare",
 		"/cs3.gateway.v1beta1.GatewayAPI/AcceptInvite",
 		"/cs3.gateway.v1beta1.GatewayAPI/GetAcceptedUser",
 		"/cs3.gateway.v1beta1.GatewayAPI/IsProviderAllowed",
@@ -211,6 +233,7 @@ func (s *svc) UnprotectedEndpoints() []string {
 		"/cs3.auth.registry.v1beta1.RegistryAPI/GetAuthProvider",
 		"/cs3.auth.registry.v1beta1.RegistryAPI/ListAuthProviders",
 		"/cs3.ocm.core.v1beta1.OcmCoreAPI/CreateOCMCoreShare",
+		"/cs3.ocm.core.v1beta1.OcmCoreAPI/DeleteOCMCoreShare",
 		"/cs3.ocm.invite.v1beta1.InviteAPI/AcceptInvite",
 		"/cs3.ocm.invite.v1beta1.InviteAPI/GetAcceptedUser",
 		"/cs3.ocm.provider.v1beta1.ProviderAPI/IsProviderAllowed",
@@ -0,0 +1,145 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	registry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
+	sppb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+func (s *svc) getSearchClient(_ context.Context, p *registry.ProviderInfo) (sppb.SearchAPIClient, error) {
+	c, err := pool.GetSearchClient(pool.Endpoint(p.Address))
+	if err != nil {
+		return nil, errors.Wrap(err, "gateway: error getting a search client")
+	}
+	return c, nil
+}
+
+// searchRef returns the reference to search in the provider p for the
+// resources below ref. A path above the mount point of the provider
+// is searched from the mount point.
+func searchRef(ref *provider.Reference, p *registry.ProviderInfo) *provider.Reference {
+	resPath := ref.GetPath()
+	if utils.IsRelativeReference(ref) || resPath == "" || strings.HasPrefix(resPath, p.ProviderPath) {
+		return ref
+	}
+	return &provider.Reference{Path: p.ProviderPath}
+}
+
+func (s *svc) Search(ctx context.Context, req *sppb.SearchRequest) (*sppb.SearchResponse, error) {
+	providers, err := s.findProviders(ctx, req.Ref)
+	if err != nil {
+		return &sppb.SearchResponse{
+			Status: status.NewStatusFromErrType(ctx, "search ref: "+req.Ref.String(), err),
+		}, nil
+	}
+	providers = getUniqueProviders(providers)
+
+	if len(providers) == 1 {
+		c, err := s.getSearchClient(ctx, providers[0])
+		if err != nil {
+			return &sppb.SearchResponse{
+				Status: status.NewInternal(ctx, err, "error connecting to storage provider="+providers[0].Address),
+			}, nil
+		}
+		return c.Search(ctx, &sppb.SearchRequest{Opaque: req.Opaque, Ref: searchRef(req.Ref, providers[0]), Pattern: req.Pattern, Limit: req.Limit, Offset: req.Offset})
+	}
+
+	// the results of the providers are merged, so every provider
+	// must return enough results for the requested page
+	var limit uint32
+	if req.Limit > 0 {
+		limit = req.Offset + req.Limit
+	}
+	log := appctx.GetLogger(ctx)
+	infos := []*provider.ResourceInfo{}
+	for _, p := range s.filterProvidersByUserAgent(ctx, providers) {
+		c, err := s.getSearchClient(ctx, p)
+		if err != nil {
+			log.Err(err).Msg("error connecting to storage provider=" + p.Address)
+			continue
+		}
+		ref := searchRef(req.Ref, p)
+		res, err := c.Search(ctx, &sppb.SearchRequest{Opaque: req.Opaque, Ref: ref, Pattern: req.Pattern, Limit: limit})
+		if err != nil {
+			log.Err(err).Msgf("gateway: error calling Search %s: %+v", ref.String(), p)
+			continue
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			log.Debug().Interface("status", res.Status).Str("provider", p.Address).Msg("gateway: skipping provider in search")
+			continue
+		}
+		infos = append(infos, res.Infos...)
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Path < infos[j].Path })
+	if int(req.Offset) >= len(infos) {
+		infos = infos[:0]
+	} else {
+		infos = infos[req.Offset:]
+	}
+	if req.Limit > 0 && int(req.Limit) < len(infos) {
+		infos = infos[:req.Limit]
+	}
+	return &sppb.SearchResponse{
+		Status: status.NewOK(ctx),
+		Infos:  infos,
+	}, nil
+}
+
+func (s *svc) Reindex(ctx context.Context, req *sppb.ReindexRequest) (*sppb.ReindexResponse, error) {
+	providers, err := s.findProviders(ctx, req.Ref)
+	if err != nil {
+		return &sppb.ReindexResponse{
+			Status: status.NewStatusFromErrType(ctx, "reindex ref: "+req.Ref.String(), err),
+		}, nil
+	}
+	providers = getUniqueProviders(providers)
+
+	for _, p := range providers {
+		c, err := s.getSearchClient(ctx, p)
+		if err != nil {
+			return &sppb.ReindexResponse{
+				Status: status.NewInternal(ctx, err, "error connecting to storage provider="+p.Address),
+			}, nil
+		}
+		res, err := c.Reindex(ctx, &sppb.ReindexRequest{Opaque: req.Opaque, Ref: searchRef(req.Ref, p)})
+		if err != nil {
+			return nil, err
+		}
+		// the providers without index have nothing to rebuild
+		if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_UNIMPLEMENTED {
+			return res, nil
+		}
+	}
+	return &sppb.ReindexResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
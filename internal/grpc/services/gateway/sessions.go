@@ -0,0 +1,102 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package gateway
+
+import (
+	"context"
+
+	permissions "github.com/cs3org/go-cs3apis/cs3/permissions/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	gwpb "github.com/cs3org/reva/internal/grpc/services/gateway/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// revokeSessionsPermission allows to revoke the sessions of the other users.
+const revokeSessionsPermission = "revoke-sessions"
+
+func (s *svc) RevokeToken(ctx context.Context, req *gwpb.RevokeTokenRequest) (*gwpb.RevokeTokenResponse, error) {
+	if s.revocations == nil {
+		return &gwpb.RevokeTokenResponse{
+			Status: status.NewUnimplemented(ctx, nil, "token revocation is not enabled"),
+		}, nil
+	}
+
+	tkn, ok := appctx.ContextGetToken(ctx)
+	if !ok || tkn == "" {
+		return &gwpb.RevokeTokenResponse{
+			Status: status.NewUnauthenticated(ctx, errtypes.InvalidCredentials("token not found"), "access token not found"),
+		}, nil
+	}
+
+	if err := revocation.Revoke(ctx, s.revocations, s.tokenmgr, tkn); err != nil {
+		return &gwpb.RevokeTokenResponse{
+			Status: status.NewInternal(ctx, err, "error revoking token"),
+		}, nil
+	}
+
+	return &gwpb.RevokeTokenResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
+
+func (s *svc) RevokeUserSessions(ctx context.Context, req *gwpb.RevokeUserSessionsRequest) (*gwpb.RevokeUserSessionsResponse, error) {
+	if s.revocations == nil {
+		return &gwpb.RevokeUserSessionsResponse{
+			Status: status.NewUnimplemented(ctx, nil, "token revocation is not enabled"),
+		}, nil
+	}
+
+	user := appctx.ContextMustGetUser(ctx)
+	userID := user.Id
+	if req.UserId != nil && !utils.UserEqual(req.UserId, user.Id) {
+		res, err := s.CheckPermission(ctx, &permissions.CheckPermissionRequest{
+			Permission: revokeSessionsPermission,
+			SubjectRef: &permissions.SubjectReference{
+				Spec: &permissions.SubjectReference_UserId{UserId: user.Id},
+			},
+		})
+		if err != nil {
+			return &gwpb.RevokeUserSessionsResponse{
+				Status: status.NewInternal(ctx, err, "error checking permission"),
+			}, nil
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			err := errtypes.PermissionDenied("gateway: not allowed to revoke the sessions of other users")
+			return &gwpb.RevokeUserSessionsResponse{
+				Status: status.NewPermissionDenied(ctx, err, "not allowed to revoke the sessions of other users"),
+			}, nil
+		}
+		userID = req.UserId
+	}
+
+	if err := revocation.RevokeUser(ctx, s.revocations, userID); err != nil {
+		return &gwpb.RevokeUserSessionsResponse{
+			Status: status.NewInternal(ctx, errors.Wrap(err, "gateway: error revoking sessions"), "error revoking sessions"),
+		}, nil
+	}
+
+	return &gwpb.RevokeUserSessionsResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
@@ -20,11 +20,23 @@ package gateway
 
 import (
 	"context"
+	"strings"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/spaces"
+	"github.com/cs3org/reva/pkg/utils"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
 )
 
 func (s *svc) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
@@ -75,11 +87,53 @@ func (s *svc) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorag
 	return res, nil
 }
 
+// DeleteStorageSpace deletes a space on the storage provider holding it.
+// When the space is disabled, the shares on resources within the space
+// are removed as well, whoever created them. The spaces registry is then
+// notified, so that the space is removed from the projects catalogue.
 func (s *svc) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) (*provider.DeleteStorageSpaceResponse, error) {
-	c, err := pool.GetSpacesClient(pool.Endpoint(s.c.SpacesEndpoint))
+	log := appctx.GetLogger(ctx)
+
+	ref, err := spaceReference(req.GetId())
 	if err != nil {
 		return &provider.DeleteStorageSpaceResponse{
-			Status: status.NewInternal(ctx, err, "error getting spaces client"),
+			Status: status.NewInvalidArg(ctx, err.Error()),
+		}, nil
+	}
+
+	// the shares need to be collected before the space is disabled,
+	// as afterwards the resources they point to cannot be resolved anymore
+	var shares []*sharesInSpace
+	if !isPurge(req.Opaque) {
+		statRes, err := s.Stat(ctx, &provider.StatRequest{Ref: ref})
+		if err != nil {
+			return nil, errors.Wrap(err, "gateway: error calling Stat")
+		}
+		if statRes.Status.Code != rpc.Code_CODE_OK {
+			return &provider.DeleteStorageSpaceResponse{
+				Status: statRes.Status,
+			}, nil
+		}
+
+		shares, err = s.listSharesInSpace(ctx, statRes.Info)
+		if err != nil {
+			return &provider.DeleteStorageSpaceResponse{
+				Status: status.NewInternal(ctx, err, "error listing shares in space"),
+			}, nil
+		}
+	}
+
+	providers, err := s.findProviders(ctx, ref)
+	if err != nil {
+		return &provider.DeleteStorageSpaceResponse{
+			Status: status.NewStatusFromErrType(ctx, "error finding storage provider", err),
+		}, nil
+	}
+
+	c, err := pool.GetSpacesClient(pool.Endpoint(providers[0].Address))
+	if err != nil {
+		return &provider.DeleteStorageSpaceResponse{
+			Status: status.NewInternal(ctx, err, "error getting storage provider client"),
 		}, nil
 	}
 
@@ -87,6 +141,223 @@ func (s *svc) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorag
 	if err != nil {
 		return nil, errors.Wrap(err, "gateway: error calling DeleteStorageSpace")
 	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return res, nil
+	}
+
+	for _, ss := range shares {
+		s.removeSharesInSpace(ss)
+	}
+
+	sc, err := pool.GetSpacesClient(pool.Endpoint(s.c.SpacesEndpoint))
+	if err != nil {
+		log.Error().Err(err).Msg("gateway: error getting spaces client")
+		return res, nil
+	}
+	catRes, err := sc.DeleteStorageSpace(ctx, req)
+	switch {
+	case err != nil:
+		log.Error().Err(err).Msg("gateway: error removing space from the spaces registry")
+	case catRes.Status.Code != rpc.Code_CODE_OK && catRes.Status.Code != rpc.Code_CODE_NOT_FOUND:
+		// spaces not in the catalogue, e.g. personal spaces, are not an error
+		log.Error().Interface("status", catRes.Status).Msg("gateway: error removing space from the spaces registry")
+	}
 
 	return res, nil
 }
+
+// spaceReference returns a reference to the root of the space with the given id,
+// that is either encoded with spaces.EncodeSpaceID or in the form <storage_id>!<opaque_id>.
+func spaceReference(id *provider.StorageSpaceId) (*provider.Reference, error) {
+	if id.GetOpaqueId() == "" {
+		return nil, errtypes.BadRequest("missing space id")
+	}
+	if _, p, ok := spaces.DecodeSpaceID(id.OpaqueId); ok {
+		return &provider.Reference{Path: p}, nil
+	}
+	rid, err := spaces.ResourceIdFromString(id.OpaqueId)
+	if err != nil {
+		return nil, errtypes.BadRequest("invalid space id " + id.OpaqueId)
+	}
+	return &provider.Reference{ResourceId: rid}, nil
+}
+
+func isPurge(o *typesv1beta1.Opaque) bool {
+	if o == nil || o.Map == nil {
+		return false
+	}
+	e, ok := o.Map["purge"]
+	return ok && e.Decoder == "plain" && string(e.Value) == "true"
+}
+
+// sharesInSpace holds shares on the resources of a space,
+// along with the context they were listed and can be removed in.
+type sharesInSpace struct {
+	ctx          context.Context
+	shares       []*collaboration.Share
+	publicShares []*link.PublicShare
+}
+
+// listSharesInSpace returns the shares and the public links on the resources
+// within the given space root. As the share providers only list the shares
+// owned or created by the caller, they are listed both as the user in context
+// and on behalf of the space owner, who owns the resources of the space and
+// hence the shares the space members created on them.
+func (s *svc) listSharesInSpace(ctx context.Context, root *provider.ResourceInfo) ([]*sharesInSpace, error) {
+	ctxs := []context.Context{ctx}
+	if u, ok := appctx.ContextGetUser(ctx); root.Owner != nil && (!ok || !utils.UserEqual(u.Id, root.Owner)) {
+		ownerCtx, err := s.impersonate(ctx, root.Owner)
+		if err != nil {
+			return nil, errors.Wrap(err, "gateway: error impersonating the space owner")
+		}
+		ctxs = append(ctxs, ownerCtx)
+	}
+
+	var list []*sharesInSpace
+	seen := map[string]struct{}{}
+	for _, ctx := range ctxs {
+		sharesRes, err := s.ListShares(ctx, &collaboration.ListSharesRequest{})
+		if err != nil {
+			return nil, err
+		}
+		if sharesRes.Status.Code != rpc.Code_CODE_OK {
+			return nil, status.NewErrorFromCode(sharesRes.Status.Code, "gateway")
+		}
+
+		ss := &sharesInSpace{ctx: ctx}
+		for _, share := range sharesRes.Shares {
+			if _, ok := seen[share.Id.GetOpaqueId()]; ok {
+				continue
+			}
+			ok, err := s.isInSpace(ctx, root, share.ResourceId)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				seen[share.Id.GetOpaqueId()] = struct{}{}
+				ss.shares = append(ss.shares, share)
+			}
+		}
+
+		publicSharesRes, err := s.ListPublicShares(ctx, &link.ListPublicSharesRequest{})
+		if err != nil {
+			return nil, err
+		}
+		if publicSharesRes.Status.Code != rpc.Code_CODE_OK {
+			return nil, status.NewErrorFromCode(publicSharesRes.Status.Code, "gateway")
+		}
+
+		for _, share := range publicSharesRes.Share {
+			if _, ok := seen["link:"+share.Id.GetOpaqueId()]; ok {
+				continue
+			}
+			ok, err := s.isInSpace(ctx, root, share.ResourceId)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				seen["link:"+share.Id.GetOpaqueId()] = struct{}{}
+				ss.publicShares = append(ss.publicShares, share)
+			}
+		}
+
+		list = append(list, ss)
+	}
+
+	return list, nil
+}
+
+// impersonate returns a context authenticated as the user with the given id,
+// for the operations the gateway carries out on behalf of other users.
+func (s *svc) impersonate(ctx context.Context, id *userpb.UserId) (context.Context, error) {
+	userRes, err := s.GetUser(ctx, &userpb.GetUserRequest{UserId: id})
+	if err != nil {
+		return nil, err
+	}
+	if userRes.Status.Code != rpc.Code_CODE_OK {
+		return nil, status.NewErrorFromCode(userRes.Status.Code, "gateway")
+	}
+
+	ownerScope, err := scope.AddOwnerScope(nil)
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.tokenmgr.MintToken(ctx, userRes.User, ownerScope)
+	if err != nil {
+		return nil, err
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(appctx.TokenHeader, token)
+	ctx = metadata.NewOutgoingContext(ctx, md)
+	ctx = appctx.ContextSetToken(ctx, token)
+	ctx = appctx.ContextSetUser(ctx, userRes.User)
+	return ctx, nil
+}
+
+func (s *svc) isInSpace(ctx context.Context, root *provider.ResourceInfo, id *provider.ResourceId) (bool, error) {
+	if id.GetStorageId() != root.Id.GetStorageId() {
+		return false, nil
+	}
+	if utils.ResourceIDEqual(id, root.Id) {
+		return true, nil
+	}
+
+	statRes, err := s.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: id}})
+	if err != nil {
+		return false, err
+	}
+	switch statRes.Status.Code {
+	case rpc.Code_CODE_OK:
+		return strings.HasPrefix(statRes.Info.Path, strings.TrimSuffix(root.Path, "/")+"/"), nil
+	case rpc.Code_CODE_NOT_FOUND:
+		// dangling share, not our business
+		return false, nil
+	default:
+		return false, status.NewErrorFromCode(statRes.Status.Code, "gateway")
+	}
+}
+
+// removeSharesInSpace removes the given shares from the share providers.
+// The grants are not removed, as they went away together with the space.
+func (s *svc) removeSharesInSpace(ss *sharesInSpace) {
+	ctx, shares, publicShares := ss.ctx, ss.shares, ss.publicShares
+	log := appctx.GetLogger(ctx)
+
+	if len(shares) > 0 {
+		c, err := pool.GetUserShareProviderClient(pool.Endpoint(s.c.UserShareProviderEndpoint))
+		if err != nil {
+			log.Error().Err(err).Msg("gateway: error getting user share provider client")
+		} else {
+			for _, share := range shares {
+				res, err := c.RemoveShare(ctx, &collaboration.RemoveShareRequest{
+					Ref: &collaboration.ShareReference{
+						Spec: &collaboration.ShareReference_Id{Id: share.Id},
+					},
+				})
+				if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+					log.Error().Err(err).Interface("share", share.Id).Msg("gateway: error removing share of deleted space")
+				}
+			}
+		}
+	}
+
+	if len(publicShares) > 0 {
+		c, err := pool.GetPublicShareProviderClient(pool.Endpoint(s.c.PublicShareProviderEndpoint))
+		if err != nil {
+			log.Error().Err(err).Msg("gateway: error getting public share provider client")
+			return
+		}
+		for _, share := range publicShares {
+			res, err := c.RemovePublicShare(ctx, &link.RemovePublicShareRequest{
+				Ref: &link.PublicShareReference{
+					Spec: &link.PublicShareReference_Id{Id: share.Id},
+				},
+			})
+			if err != nil || res.Status.Code != rpc.Code_CODE_OK {
+				log.Error().Err(err).Interface("share", share.Id).Msg("gateway: error removing public share of deleted space")
+			}
+		}
+	}
+}
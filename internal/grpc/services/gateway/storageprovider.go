@@ -21,6 +21,7 @@ package gateway
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/url"
 	"path"
 	"strings"
@@ -78,6 +79,41 @@ func (s *svc) sign(_ context.Context, target, versionKey string) (string, error)
 	return tkn, nil
 }
 
+func uploadConcat(req *provider.InitiateFileUploadRequest) string {
+	if req.Opaque == nil || req.Opaque.Map == nil {
+		return ""
+	}
+	val := req.Opaque.Map["Upload-Concat"]
+	if val == nil {
+		return ""
+	}
+	return string(val.Value)
+}
+
+// uploadIDs resolves the transfer tokens handed out for partial uploads
+// to the ids of the uploads in the storage provider.
+func (s *svc) uploadIDs(tokens []string) ([]string, error) {
+	if len(tokens) == 0 {
+		return nil, errtypes.BadRequest("gateway: no partial uploads to concatenate")
+	}
+	ids := make([]string, 0, len(tokens))
+	for _, tkn := range tokens {
+		claims := transferClaims{}
+		_, err := jwt.ParseWithClaims(tkn, &claims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(s.c.TransferSharedSecret), nil
+		})
+		if err != nil {
+			return nil, errtypes.PermissionDenied("gateway: invalid transfer token for partial upload")
+		}
+		u, err := url.Parse(claims.Target)
+		if err != nil {
+			return nil, errtypes.BadRequest("gateway: invalid target for partial upload")
+		}
+		ids = append(ids, path.Base(u.Path))
+	}
+	return ids, nil
+}
+
 func (s *svc) CreateHome(ctx context.Context, req *provider.CreateHomeRequest) (*provider.CreateHomeResponse, error) {
 	log := appctx.GetLogger(ctx)
 
@@ -493,6 +529,19 @@ func (s *svc) initiateFileUpload(ctx context.Context, req *provider.InitiateFile
 		}, nil
 	}
 
+	if concat := uploadConcat(req); strings.HasPrefix(concat, "final;") {
+		ids, err := s.uploadIDs(strings.Fields(strings.TrimPrefix(concat, "final;")))
+		if err != nil {
+			return &gateway.InitiateFileUploadResponse{
+				Status: status.NewStatusFromErrType(ctx, "initiateFileUpload ref="+req.Ref.String(), err),
+			}, nil
+		}
+		req.Opaque.Map["Upload-Concat"] = &types.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte("final;" + strings.Join(ids, " ")),
+		}
+	}
+
 	storageRes, err := c.InitiateFileUpload(ctx, req)
 	if err != nil {
 		if gstatus.Code(err) == codes.PermissionDenied {
@@ -959,22 +1008,20 @@ func (s *svc) move(ctx context.Context, req *provider.MoveRequest) (*provider.Mo
 		}, nil
 	}
 
-	// if providers are not the same we do not implement cross storage move yet.
+	// references spread across several storage providers, eg. /eos, cannot be moved.
 	if len(srcProviders) != 1 || len(dstProviders) != 1 {
 		res := &provider.MoveResponse{
-			Status: status.NewUnimplemented(ctx, nil, "gateway: cross storage copy not yet implemented"),
+			Status: status.NewUnimplemented(ctx, nil, "gateway: move of references spread across storage providers not implemented"),
 		}
 		return res, nil
 	}
 
 	srcProvider, dstProvider := srcProviders[0], dstProviders[0]
 
-	// if providers are not the same we do not implement cross storage copy yet.
+	// if providers are not the same the gateway copies the data
+	// and deletes the source once the copy is complete.
 	if srcProvider.Address != dstProvider.Address {
-		res := &provider.MoveResponse{
-			Status: status.NewUnimplemented(ctx, nil, "gateway: cross storage copy not yet implemented"),
-		}
-		return res, nil
+		return s.crossStorageMove(ctx, req, srcProvider, dstProvider)
 	}
 
 	c, err := s.getStorageProviderClient(ctx, srcProvider)
@@ -1466,8 +1513,99 @@ func (s *svc) handleCS3Ref(ctx context.Context, opaque string) (*provider.Resour
 	return res.Info, nil
 }
 
-func (s *svc) ListContainerStream(_ *provider.ListContainerStreamRequest, _ gateway.GatewayAPI_ListContainerStreamServer) error {
-	return errtypes.NotSupported("Unimplemented")
+func (s *svc) ListContainerStream(req *provider.ListContainerStreamRequest, ss gateway.GatewayAPI_ListContainerStreamServer) error {
+	ctx := ss.Context()
+
+	if !utils.IsRelativeReference(req.Ref) {
+		p, st := s.getPath(ctx, req.Ref, req.ArbitraryMetadataKeys...)
+		if st.Code != rpc.Code_CODE_OK {
+			return ss.Send(&provider.ListContainerStreamResponse{Status: st})
+		}
+
+		// the home and the share folder are virtual views whose entries need
+		// to be resolved one by one: they are listed at once and then sent
+		if path.Clean(p) == s.getHome(ctx) || s.inSharedFolder(ctx, p) {
+			res, err := s.ListContainer(ctx, &provider.ListContainerRequest{
+				Opaque:                req.Opaque,
+				Ref:                   req.Ref,
+				ArbitraryMetadataKeys: req.ArbitraryMetadataKeys,
+			})
+			if err != nil {
+				return err
+			}
+			return sendListContainer(ss, res)
+		}
+	}
+
+	providers, err := s.findProviders(ctx, req.Ref)
+	if err != nil {
+		return ss.Send(&provider.ListContainerStreamResponse{
+			Status: status.NewStatusFromErrType(ctx, "listContainerStream ref: "+req.Ref.String(), err),
+		})
+	}
+	providers = getUniqueProviders(providers)
+
+	resPath := req.Ref.GetPath()
+	if len(providers) != 1 || !(utils.IsRelativeReference(req.Ref) || resPath == "" || strings.HasPrefix(resPath, providers[0].ProviderPath)) {
+		// entries contributed by several providers need to be merged
+		res, err := s.listContainerAcrossProviders(ctx, &provider.ListContainerRequest{
+			Opaque:                req.Opaque,
+			Ref:                   req.Ref,
+			ArbitraryMetadataKeys: req.ArbitraryMetadataKeys,
+		}, providers)
+		if err != nil {
+			return err
+		}
+		return sendListContainer(ss, res)
+	}
+
+	c, err := s.getStorageProviderClient(ctx, providers[0])
+	if err != nil {
+		return ss.Send(&provider.ListContainerStreamResponse{
+			Status: status.NewInternal(ctx, err, "error connecting to storage provider="+providers[0].Address),
+		})
+	}
+	stream, err := c.ListContainerStream(ctx, req)
+	if err != nil {
+		return errors.Wrap(err, "gateway: error calling ListContainerStream")
+	}
+	for first := true; ; first = false {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if first && gstatus.Code(err) == codes.Unimplemented {
+			// providers not supporting streams are listed at once
+			res, err := c.ListContainer(ctx, &provider.ListContainerRequest{
+				Opaque:                req.Opaque,
+				Ref:                   req.Ref,
+				ArbitraryMetadataKeys: req.ArbitraryMetadataKeys,
+			})
+			if err != nil {
+				return errors.Wrap(err, "gateway: error calling ListContainer")
+			}
+			return sendListContainer(ss, res)
+		}
+		if err != nil {
+			return errors.Wrap(err, "gateway: error receiving from ListContainerStream")
+		}
+		if err := ss.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+// sendListContainer sends the entries of an already computed listing over a stream.
+func sendListContainer(ss gateway.GatewayAPI_ListContainerStreamServer, res *provider.ListContainerResponse) error {
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return ss.Send(&provider.ListContainerStreamResponse{Status: res.Status})
+	}
+	for _, info := range res.Infos {
+		if err := ss.Send(&provider.ListContainerStreamResponse{Status: res.Status, Info: info}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *svc) listHome(ctx context.Context, req *provider.ListContainerRequest) (*provider.ListContainerResponse, error) {
@@ -1934,9 +2072,30 @@ func (s *svc) getSharedFolder(ctx context.Context) string {
 }
 
 func (s *svc) CreateSymlink(ctx context.Context, req *provider.CreateSymlinkRequest) (*provider.CreateSymlinkResponse, error) {
-	return &provider.CreateSymlinkResponse{
-		Status: status.NewUnimplemented(ctx, errtypes.NotSupported("CreateSymlink not implemented"), "CreateSymlink not implemented"),
-	}, nil
+	// the target of a link under the share folder would be resolved
+	// in the namespace of the share owner rather than the one of the user
+	if utils.IsAbsolutePathReference(req.Ref) && s.inSharedFolder(ctx, req.Ref.Path) {
+		return &provider.CreateSymlinkResponse{
+			Status: status.NewInvalidArg(ctx, "symlinks cannot be created under the share folder"),
+		}, nil
+	}
+
+	c, err := s.find(ctx, req.Ref)
+	if err != nil {
+		return &provider.CreateSymlinkResponse{
+			Status: status.NewStatusFromErrType(ctx, "CreateSymlink ref="+req.Ref.String(), err),
+		}, nil
+	}
+
+	res, err := c.CreateSymlink(ctx, req)
+	if err != nil {
+		if gstatus.Code(err) == codes.PermissionDenied {
+			return &provider.CreateSymlinkResponse{Status: &rpc.Status{Code: rpc.Code_CODE_PERMISSION_DENIED}}, nil
+		}
+		return nil, errors.Wrap(err, "gateway: error calling CreateSymlink")
+	}
+
+	return res, nil
 }
 
 func (s *svc) ListFileVersions(ctx context.Context, req *provider.ListFileVersionsRequest) (*provider.ListFileVersionsResponse, error) {
@@ -1971,8 +2130,59 @@ func (s *svc) RestoreFileVersion(ctx context.Context, req *provider.RestoreFileV
 	return res, nil
 }
 
-func (s *svc) ListRecycleStream(_ *provider.ListRecycleStreamRequest, _ gateway.GatewayAPI_ListRecycleStreamServer) error {
-	return errtypes.NotSupported("ListRecycleStream unimplemented")
+func (s *svc) ListRecycleStream(req *provider.ListRecycleStreamRequest, ss gateway.GatewayAPI_ListRecycleStreamServer) error {
+	ctx := ss.Context()
+	c, err := s.find(ctx, req.GetRef())
+	if err != nil {
+		return ss.Send(&provider.ListRecycleStreamResponse{
+			Status: status.NewStatusFromErrType(ctx, "ListRecycleStream ref="+req.Ref.String(), err),
+		})
+	}
+
+	stream, err := c.ListRecycleStream(ctx, req)
+	if err != nil {
+		return errors.Wrap(err, "gateway: error calling ListRecycleStream")
+	}
+	for first := true; ; first = false {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if first && gstatus.Code(err) == codes.Unimplemented {
+			// providers not supporting streams are listed at once
+			res, err := c.ListRecycle(ctx, &provider.ListRecycleRequest{
+				Opaque:   req.Opaque,
+				Ref:      req.Ref,
+				Key:      req.Key,
+				FromTs:   req.FromTs,
+				ToTs:     req.ToTs,
+				PageSize: req.PageSize,
+			})
+			if err != nil {
+				return errors.Wrap(err, "gateway: error calling ListRecycle")
+			}
+			return sendListRecycle(ss, res)
+		}
+		if err != nil {
+			return errors.Wrap(err, "gateway: error receiving from ListRecycleStream")
+		}
+		if err := ss.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+// sendListRecycle sends the items of an already computed listing over a stream.
+func sendListRecycle(ss gateway.GatewayAPI_ListRecycleStreamServer, res *provider.ListRecycleResponse) error {
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return ss.Send(&provider.ListRecycleStreamResponse{Status: res.Status})
+	}
+	for _, item := range res.RecycleItems {
+		if err := ss.Send(&provider.ListRecycleStreamResponse{Status: res.Status, RecycleItem: item}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // TODO use the ListRecycleRequest.Ref to only list the trash of a specific storage.
@@ -523,12 +523,8 @@ func (s *svc) UpdateReceivedShare(ctx context.Context, req *collaboration.Update
 					return &collaboration.UpdateReceivedShareResponse{Status: rpcStatus}, nil
 				}
 			}
-		case "mount_point":
-			// TODO(labkode): implementing updating mount point
-			err = errtypes.NotSupported("gateway: update of mount point is not yet implemented")
-			return &collaboration.UpdateReceivedShareResponse{
-				Status: status.NewUnimplemented(ctx, err, "error updating received share"),
-			}, nil
+		case "mount_point", "hidden":
+			// stored by the share manager, nothing to do on the storage
 		default:
 			return nil, errtypes.NotSupported("updating " + req.UpdateMask.Paths[i] + " is not supported")
 		}
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"time"
 
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	ocmcore "github.com/cs3org/go-cs3apis/cs3/ocm/core/v1beta1"
 	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
 	providerpb "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
@@ -101,7 +102,10 @@ func (s *service) Close() error {
 }
 
 func (s *service) UnprotectedEndpoints() []string {
-	return []string{"/cs3.ocm.core.v1beta1.OcmCoreAPI/CreateOCMCoreShare"}
+	return []string{
+		"/cs3.ocm.core.v1beta1.OcmCoreAPI/CreateOCMCoreShare",
+		"/cs3.ocm.core.v1beta1.OcmCoreAPI/DeleteOCMCoreShare",
+	}
 }
 
 // CreateOCMCoreShare is called when a remote OCM request comes into this reva instance.
@@ -151,6 +155,46 @@ func (s *service) UpdateOCMCoreShare(ctx context.Context, req *ocmcore.UpdateOCM
 	return nil, errtypes.NotSupported("not implemented")
 }
 
+// DeleteOCMCoreShare is called when a remote OCM provider notifies that a share
+// has been removed. The request is not authenticated, so the received share is
+// looked up by the shared secret in the opaque, and its remote id must match req.Id.
 func (s *service) DeleteOCMCoreShare(ctx context.Context, req *ocmcore.DeleteOCMCoreShareRequest) (*ocmcore.DeleteOCMCoreShareResponse, error) {
-	return nil, errtypes.NotSupported("not implemented")
+	var secret string
+	if e, ok := req.GetOpaque().GetMap()["shared_secret"]; ok {
+		secret = string(e.Value)
+	}
+	if secret == "" {
+		return &ocmcore.DeleteOCMCoreShareResponse{
+			Status: status.NewInvalidArg(ctx, "missing shared secret"),
+		}, nil
+	}
+
+	share, err := s.repo.GetReceivedShare(ctx, nil, &ocm.ShareReference{
+		Spec: &ocm.ShareReference_Token{
+			Token: secret,
+		},
+	})
+	if err == nil && share.RemoteShareId != req.Id {
+		err = errtypes.NotFound(req.Id)
+	}
+	if err != nil {
+		return &ocmcore.DeleteOCMCoreShareResponse{
+			Status: status.NewStatusFromErrType(ctx, "error getting received share", err),
+		}, nil
+	}
+
+	user := &userpb.User{Id: share.Grantee.GetUserId()}
+	if err := s.repo.DeleteReceivedShare(ctx, user, &ocm.ShareReference{
+		Spec: &ocm.ShareReference_Id{
+			Id: share.Id,
+		},
+	}); err != nil {
+		return &ocmcore.DeleteOCMCoreShareResponse{
+			Status: status.NewStatusFromErrType(ctx, "error deleting received share", err),
+		}, nil
+	}
+
+	return &ocmcore.DeleteOCMCoreShareResponse{
+		Status: status.NewOK(ctx),
+	}, nil
 }
@@ -31,6 +31,7 @@ import (
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/ocm/invite"
 	"github.com/cs3org/reva/pkg/ocm/invite/repository/registry"
+	"github.com/cs3org/reva/pkg/ocm/signature"
 	"github.com/cs3org/reva/pkg/plugin"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
@@ -57,8 +58,9 @@ type config struct {
 	TokenExpiration   string                            `mapstructure:"token_expiration"`
 	OCMClientTimeout  int                               `mapstructure:"ocm_timeout"`
 	OCMClientInsecure bool                              `mapstructure:"ocm_insecure"`
-	GatewaySVC        string                            `mapstructure:"gatewaysvc"                                    validate:"required"`
-	ProviderDomain    string                            `docs:"The same domain registered in the provider authorizer" mapstructure:"provider_domain" validate:"required"`
+	GatewaySVC        string                            `mapstructure:"gatewaysvc"                                                                                     validate:"required"`
+	ProviderDomain    string                            `docs:"The same domain registered in the provider authorizer"                                                  mapstructure:"provider_domain" validate:"required"`
+	SigningKey        string                            `docs:";Path to the PEM encoded RSA key signing the OCM requests, the same published by the wellknown service for the same provider_domain" mapstructure:"signing_key"`
 
 	tokenExpiration time.Duration
 }
@@ -109,10 +111,19 @@ func New(ctx context.Context, m map[string]interface{}) (rgrpc.Service, error) {
 		return nil, err
 	}
 
+	ocmClient := ocmd.NewClient(time.Duration(c.OCMClientTimeout)*time.Second, c.OCMClientInsecure)
+	if c.SigningKey != "" {
+		signer, err := signature.NewSignerFromFile(c.ProviderDomain, c.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		ocmClient.WithSigner(signer)
+	}
+
 	service := &service{
 		conf:      &c,
 		repo:      repo,
-		ocmClient: ocmd.NewClient(time.Duration(c.OCMClientTimeout)*time.Second, c.OCMClientInsecure),
+		ocmClient: ocmClient,
 	}
 	return service, nil
 }
@@ -26,6 +26,7 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"slices"
 	"strings"
 	"text/template"
 	"time"
@@ -43,6 +44,7 @@ import (
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/ocm/share"
 	"github.com/cs3org/reva/pkg/ocm/share/repository/registry"
+	"github.com/cs3org/reva/pkg/ocm/signature"
 	"github.com/cs3org/reva/pkg/plugin"
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
@@ -51,6 +53,7 @@ import (
 	"github.com/cs3org/reva/pkg/storage/utils/walker"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/cs3org/reva/pkg/utils/cfg"
+	"github.com/cs3org/reva/pkg/utils/list"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 )
@@ -65,14 +68,16 @@ func init() {
 }
 
 type config struct {
-	Driver         string                            `mapstructure:"driver"`
-	Drivers        map[string]map[string]interface{} `mapstructure:"drivers"`
-	ClientTimeout  int                               `mapstructure:"client_timeout"`
-	ClientInsecure bool                              `mapstructure:"client_insecure"`
-	GatewaySVC     string                            `mapstructure:"gatewaysvc"                                    validate:"required"`
-	ProviderDomain string                            `docs:"The same domain registered in the provider authorizer" mapstructure:"provider_domain" validate:"required"`
-	WebDAVEndpoint string                            `mapstructure:"webdav_endpoint"                               validate:"required"`
-	WebappTemplate string                            `mapstructure:"webapp_template"                               validate:"required"`
+	Driver             string                            `mapstructure:"driver"`
+	Drivers            map[string]map[string]interface{} `mapstructure:"drivers"`
+	ClientTimeout      int                               `mapstructure:"client_timeout"`
+	ClientInsecure     bool                              `mapstructure:"client_insecure"`
+	GatewaySVC         string                            `mapstructure:"gatewaysvc"                                                                                           validate:"required"`
+	ProviderDomain     string                            `docs:"The same domain registered in the provider authorizer"                                                        mapstructure:"provider_domain" validate:"required"`
+	WebDAVEndpoint     string                            `mapstructure:"webdav_endpoint"                                                                                      validate:"required"`
+	WebappTemplate     string                            `mapstructure:"webapp_template"                                                                                      validate:"required"`
+	JanitorRunInterval int                               `docs:"60;The interval in seconds between two removals of the expired shares, a negative value disables the removal" mapstructure:"janitor_run_interval"`
+	SigningKey         string                            `docs:";Path to the PEM encoded RSA key signing the OCM requests, the same published by the wellknown service for the same provider_domain"       mapstructure:"signing_key"`
 }
 
 type service struct {
@@ -91,6 +96,9 @@ func (c *config) ApplyDefaults() {
 	if c.ClientTimeout == 0 {
 		c.ClientTimeout = 10
 	}
+	if c.JanitorRunInterval == 0 {
+		c.JanitorRunInterval = 60
+	}
 
 	c.GatewaySVC = sharedconf.GetGatewaySVC(c.GatewaySVC)
 }
@@ -130,6 +138,13 @@ func New(ctx context.Context, m map[string]interface{}) (rgrpc.Service, error) {
 	walker := walker.NewWalker(gateway)
 
 	ocmcl := ocmd.NewClient(time.Duration(c.ClientTimeout)*time.Second, c.ClientInsecure)
+	if c.SigningKey != "" {
+		signer, err := signature.NewSignerFromFile(c.ProviderDomain, c.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		ocmcl.WithSigner(signer)
+	}
 	service := &service{
 		conf:       &c,
 		repo:       repo,
@@ -139,9 +154,135 @@ func New(ctx context.Context, m map[string]interface{}) (rgrpc.Service, error) {
 		walker:     walker,
 	}
 
+	if lister, ok := repo.(share.ExpiredSharesLister); ok && c.JanitorRunInterval > 0 {
+		go service.startJanitorRun(lister)
+	}
+
 	return service, nil
 }
 
+func (s *service) startJanitorRun(lister share.ExpiredSharesLister) {
+	ticker := time.NewTicker(time.Duration(s.conf.JanitorRunInterval) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.removeExpiredShares(context.Background(), lister)
+	}
+}
+
+// removeExpiredShares removes the expired shares, notifying
+// the recipients that the shares are no more available.
+func (s *service) removeExpiredShares(ctx context.Context, lister share.ExpiredSharesLister) {
+	log := appctx.GetLogger(ctx)
+	shares, err := lister.ListExpiredShares(ctx, time.Now())
+	if err != nil {
+		log.Error().Err(err).Msg("ocmshareprovider: error listing expired shares")
+		return
+	}
+	if len(shares) == 0 {
+		return
+	}
+
+	providers, err := s.listProvidersByDomain(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("ocmshareprovider: error listing mesh providers")
+	}
+
+	for _, ocmshare := range shares {
+		// the share is removed even if the recipient could not be notified,
+		// as the expired share is anyway not accessible anymore
+		if err := s.notifyUnshare(ctx, providers[ocmshare.Grantee.GetUserId().GetIdp()], ocmshare, "the share has expired"); err != nil {
+			log.Warn().Err(err).Str("share", ocmshare.Id.OpaqueId).Msg("ocmshareprovider: error notifying the recipient of an expired share")
+		}
+		owner := &userpb.User{Id: ocmshare.Owner}
+		ref := &ocm.ShareReference{Spec: &ocm.ShareReference_Id{Id: ocmshare.Id}}
+		if err := s.repo.DeleteShare(ctx, owner, ref); err != nil {
+			log.Error().Err(err).Str("share", ocmshare.Id.OpaqueId).Msg("ocmshareprovider: error removing expired share")
+			continue
+		}
+		log.Info().Str("share", ocmshare.Id.OpaqueId).Msg("ocmshareprovider: removed expired share")
+	}
+}
+
+func (s *service) listProvidersByDomain(ctx context.Context) (map[string]*ocmprovider.ProviderInfo, error) {
+	res, err := s.gateway.ListAllProviders(ctx, &ocmprovider.ListAllProvidersRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, errors.New(res.Status.Message)
+	}
+	providers := make(map[string]*ocmprovider.ProviderInfo, len(res.Providers))
+	for _, p := range res.Providers {
+		providers[p.Domain] = p
+	}
+	return providers, nil
+}
+
+func (s *service) getProviderInfo(ctx context.Context, domain string) (*ocmprovider.ProviderInfo, error) {
+	res, err := s.gateway.GetInfoByDomain(ctx, &ocmprovider.GetInfoByDomainRequest{
+		Domain: domain,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, errors.New(res.Status.Message)
+	}
+	return res.ProviderInfo, nil
+}
+
+// notify sends the notification to the given mesh provider.
+func (s *service) notify(ctx context.Context, p *ocmprovider.ProviderInfo, n *ocmd.NotificationRequest) error {
+	if p == nil {
+		return errtypes.NotFound("mesh provider")
+	}
+	endpoint, err := getOCMEndpoint(p)
+	if err != nil {
+		return err
+	}
+	return s.client.Notify(ctx, endpoint, n)
+}
+
+// notifyUnshare informs the provider of the recipient that the share was removed.
+func (s *service) notifyUnshare(ctx context.Context, p *ocmprovider.ProviderInfo, ocmshare *ocm.Share, message string) error {
+	return s.notify(ctx, p, &ocmd.NotificationRequest{
+		NotificationType: ocmd.NotificationShareUnshared,
+		ResourceType:     "file",
+		ProviderID:       ocmshare.Id.OpaqueId,
+		Notification: &ocmd.Notification{
+			SharedSecret: ocmshare.Token,
+			Message:      message,
+		},
+	})
+}
+
+// notifyShareState informs the provider of a received share
+// that the share was accepted or declined by the recipient.
+func (s *service) notifyShareState(ctx context.Context, rs *ocm.ReceivedShare) error {
+	var t string
+	switch rs.State {
+	case ocm.ShareState_SHARE_STATE_ACCEPTED:
+		t = ocmd.NotificationShareAccepted
+	case ocm.ShareState_SHARE_STATE_REJECTED:
+		t = ocmd.NotificationShareDeclined
+	default:
+		return nil
+	}
+
+	p, err := s.getProviderInfo(ctx, rs.Owner.GetIdp())
+	if err != nil {
+		return err
+	}
+	return s.notify(ctx, p, &ocmd.NotificationRequest{
+		NotificationType: t,
+		ResourceType:     "file",
+		ProviderID:       rs.RemoteShareId,
+		Notification: &ocmd.Notification{
+			SharedSecret: share.SharedSecret(rs),
+		},
+	})
+}
+
 func (s *service) Close() error {
 	return nil
 }
@@ -369,9 +510,19 @@ func (s *service) CreateOCMShare(ctx context.Context, req *ocm.CreateOCMShareReq
 }
 
 func (s *service) RemoveOCMShare(ctx context.Context, req *ocm.RemoveOCMShareRequest) (*ocm.RemoveOCMShareResponse, error) {
-	// TODO (gdelmont): notify the remote provider using the /notification ocm endpoint
-	// https://cs3org.github.io/OCM-API/docs.html?branch=develop&repo=OCM-API&user=cs3org#/paths/~1notifications/post
 	user := appctx.ContextMustGetUser(ctx)
+	ocmshare, err := s.repo.GetShare(ctx, user, req.Ref)
+	if err != nil {
+		if errors.Is(err, share.ErrShareNotFound) {
+			return &ocm.RemoveOCMShareResponse{
+				Status: status.NewNotFound(ctx, "share does not exist"),
+			}, nil
+		}
+		return &ocm.RemoveOCMShareResponse{
+			Status: status.NewInternal(ctx, err, "error getting share"),
+		}, nil
+	}
+
 	if err := s.repo.DeleteShare(ctx, user, req.Ref); err != nil {
 		if errors.Is(err, share.ErrShareNotFound) {
 			return &ocm.RemoveOCMShareResponse{
@@ -383,6 +534,16 @@ func (s *service) RemoveOCMShare(ctx context.Context, req *ocm.RemoveOCMShareReq
 		}, nil
 	}
 
+	// the share is not accessible anymore, so an error
+	// notifying the recipient does not fail the request
+	p, err := s.getProviderInfo(ctx, ocmshare.Grantee.GetUserId().GetIdp())
+	if err == nil {
+		err = s.notifyUnshare(ctx, p, ocmshare, "the share has been removed by its owner")
+	}
+	if err != nil {
+		appctx.GetLogger(ctx).Warn().Err(err).Str("share", ocmshare.Id.OpaqueId).Msg("ocmshareprovider: error notifying the recipient of a removed share")
+	}
+
 	return &ocm.RemoveOCMShareResponse{
 		Status: status.NewOK(ctx),
 	}, nil
@@ -405,6 +566,11 @@ func (s *service) GetOCMShare(ctx context.Context, req *ocm.GetOCMShareRequest)
 			Status: status.NewInternal(ctx, err, "error getting share"),
 		}, nil
 	}
+	if share.IsExpired(ocmshare) {
+		return &ocm.GetOCMShareResponse{
+			Status: status.NewNotFound(ctx, "share does not exist"),
+		}, nil
+	}
 
 	return &ocm.GetOCMShareResponse{
 		Status: status.NewOK(ctx),
@@ -428,6 +594,11 @@ func (s *service) GetOCMShareByToken(ctx context.Context, req *ocm.GetOCMShareBy
 			Status: status.NewInternal(ctx, err, "error getting share"),
 		}, nil
 	}
+	if share.IsExpired(ocmshare) {
+		return &ocm.GetOCMShareByTokenResponse{
+			Status: status.NewNotFound(ctx, "share does not exist"),
+		}, nil
+	}
 
 	return &ocm.GetOCMShareByTokenResponse{
 		Status: status.NewOK(ctx),
@@ -446,7 +617,7 @@ func (s *service) ListOCMShares(ctx context.Context, req *ocm.ListOCMSharesReque
 
 	res := &ocm.ListOCMSharesResponse{
 		Status: status.NewOK(ctx),
-		Shares: shares,
+		Shares: list.Filter(shares, func(s *ocm.Share) bool { return !share.IsExpired(s) }),
 	}
 	return res, nil
 }
@@ -487,7 +658,7 @@ func (s *service) ListReceivedOCMShares(ctx context.Context, req *ocm.ListReceiv
 
 	res := &ocm.ListReceivedOCMSharesResponse{
 		Status: status.NewOK(ctx),
-		Shares: shares,
+		Shares: list.Filter(shares, func(s *ocm.ReceivedShare) bool { return !share.IsExpired(s) }),
 	}
 	return res, nil
 }
@@ -506,6 +677,20 @@ func (s *service) UpdateReceivedOCMShare(ctx context.Context, req *ocm.UpdateRec
 		}, nil
 	}
 
+	if slices.Contains(req.UpdateMask.GetPaths(), "state") {
+		rs, err := s.repo.GetReceivedShare(ctx, user, &ocm.ShareReference{
+			Spec: &ocm.ShareReference_Id{
+				Id: req.Share.Id,
+			},
+		})
+		if err == nil {
+			err = s.notifyShareState(ctx, rs)
+		}
+		if err != nil {
+			appctx.GetLogger(ctx).Warn().Err(err).Str("share", req.Share.Id.OpaqueId).Msg("ocmshareprovider: error notifying the provider of a received share")
+		}
+	}
+
 	res := &ocm.UpdateReceivedOCMShareResponse{
 		Status: status.NewOK(ctx),
 	}
@@ -525,6 +710,11 @@ func (s *service) GetReceivedOCMShare(ctx context.Context, req *ocm.GetReceivedO
 			Status: status.NewInternal(ctx, err, "error getting received share"),
 		}, nil
 	}
+	if share.IsExpired(ocmshare) {
+		return &ocm.GetReceivedOCMShareResponse{
+			Status: status.NewNotFound(ctx, "share does not exist"),
+		}, nil
+	}
 
 	res := &ocm.GetReceivedOCMShareResponse{
 		Status: status.NewOK(ctx),
@@ -577,7 +577,24 @@ func addShare(i *provider.ResourceInfo, ls *link.PublicShare) error {
 }
 
 func (s *service) ListContainerStream(req *provider.ListContainerStreamRequest, ss provider.ProviderAPI_ListContainerStreamServer) error {
-	return gstatus.Errorf(codes.Unimplemented, "method not implemented")
+	// the listing is proxied to the gateway and rewritten, it is sent once complete
+	res, err := s.ListContainer(ss.Context(), &provider.ListContainerRequest{
+		Opaque:                req.Opaque,
+		Ref:                   req.Ref,
+		ArbitraryMetadataKeys: req.ArbitraryMetadataKeys,
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return ss.Send(&provider.ListContainerStreamResponse{Status: res.Status})
+	}
+	for _, info := range res.Infos {
+		if err := ss.Send(&provider.ListContainerStreamResponse{Status: res.Status, Info: info}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *service) ListContainer(ctx context.Context, req *provider.ListContainerRequest) (*provider.ListContainerResponse, error) {
@@ -308,9 +308,38 @@ func (s *service) UpdateStorageSpace(ctx context.Context, req *provider.UpdateSt
 	return nil, errors.New("not yet implemented")
 }
 
+// DeleteStorageSpace removes the project space from the catalogue. The space
+// itself is deleted by the gateway on the storage provider holding it.
 func (s *service) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) (*provider.DeleteStorageSpaceResponse, error) {
-	// As for the creation, the deletion of a space is implemented externally for now
-	return nil, errors.New("not supported")
+	user := appctx.ContextMustGetUser(ctx)
+
+	storageID, path, ok := spaces.DecodeSpaceID(req.GetId().GetOpaqueId())
+	if !ok {
+		// only project spaces are stored in the catalogue
+		return &provider.DeleteStorageSpaceResponse{
+			Status: status.NewNotFound(ctx, "space not found in the catalogue"),
+		}, nil
+	}
+
+	purge := false
+	if e, ok := req.GetOpaque().GetMap()["purge"]; ok && e.Decoder == "plain" {
+		purge = string(e.Value) == "true"
+	}
+
+	if err := s.projects.DeleteProject(ctx, user, storageID, path, purge); err != nil {
+		var st *rpcv1beta1.Status
+		switch err.(type) {
+		case errtypes.IsNotFound:
+			st = status.NewNotFound(ctx, "space not found in the catalogue")
+		case errtypes.PermissionDenied:
+			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		default:
+			st = status.NewInternal(ctx, err, "error deleting project")
+		}
+		return &provider.DeleteStorageSpaceResponse{Status: st}, nil
+	}
+
+	return &provider.DeleteStorageSpaceResponse{Status: status.NewOK(ctx)}, nil
 }
 
 func (s *service) Register(ss *grpc.Server) {
@@ -0,0 +1,111 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storageprovider
+
+import (
+	"context"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	sppb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+func searchStatus(ctx context.Context, err error, msg string) *rpc.Status {
+	switch err.(type) {
+	case errtypes.IsNotFound:
+		return status.NewNotFound(ctx, "path not found when "+msg)
+	case errtypes.PermissionDenied:
+		return status.NewPermissionDenied(ctx, err, "permission denied")
+	case errtypes.BadRequest:
+		return status.NewInvalidArg(ctx, err.Error())
+	default:
+		appctx.GetLogger(ctx).Error().Err(err).Msg("storageprovider: error " + msg)
+		return status.NewInternal(ctx, err, "error "+msg)
+	}
+}
+
+func (s *service) Search(ctx context.Context, req *sppb.SearchRequest) (*sppb.SearchResponse, error) {
+	searcher, ok := s.storage.(search.Searcher)
+	if !ok {
+		return &sppb.SearchResponse{
+			Status: status.NewUnimplemented(ctx, nil, "storage driver does not support searching"),
+		}, nil
+	}
+	newRef, err := s.unwrap(ctx, req.Ref)
+	if err != nil {
+		return &sppb.SearchResponse{
+			Status: status.NewInternal(ctx, err, "error unwrapping path"),
+		}, nil
+	}
+
+	mds, err := searcher.Search(ctx, newRef, search.Query{
+		Pattern: req.Pattern,
+		Limit:   int(req.Limit),
+		Offset:  int(req.Offset),
+	})
+	if err != nil {
+		return &sppb.SearchResponse{
+			Status: searchStatus(ctx, err, "searching "+req.Ref.String()),
+		}, nil
+	}
+
+	prefixMountpoint := utils.IsAbsoluteReference(req.Ref)
+	for _, md := range mds {
+		if err := s.wrap(ctx, md, prefixMountpoint); err != nil {
+			return &sppb.SearchResponse{
+				Status: status.NewInternal(ctx, err, "error wrapping path"),
+			}, nil
+		}
+		s.fixPermissions(md)
+		s.stripNonUtf8Metadata(ctx, md)
+		stripUserTags(ctx, md)
+	}
+	return &sppb.SearchResponse{
+		Status: status.NewOK(ctx),
+		Infos:  mds,
+	}, nil
+}
+
+func (s *service) Reindex(ctx context.Context, req *sppb.ReindexRequest) (*sppb.ReindexResponse, error) {
+	searcher, ok := s.storage.(search.Searcher)
+	if !ok {
+		return &sppb.ReindexResponse{
+			Status: status.NewUnimplemented(ctx, nil, "storage driver does not support searching"),
+		}, nil
+	}
+	newRef, err := s.unwrap(ctx, req.Ref)
+	if err != nil {
+		return &sppb.ReindexResponse{
+			Status: status.NewInternal(ctx, err, "error unwrapping path"),
+		}, nil
+	}
+
+	if err := searcher.Reindex(ctx, newRef); err != nil {
+		return &sppb.ReindexResponse{
+			Status: searchStatus(ctx, err, "reindexing "+req.Ref.String()),
+		}, nil
+	}
+	return &sppb.ReindexResponse{
+		Status: status.NewOK(ctx),
+	}, nil
+}
@@ -22,6 +22,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path"
@@ -32,8 +33,11 @@ import (
 	"unicode/utf8"
 
 	"github.com/cernbox/reva-plugins/storage/eoshomewrapper"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	sppb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/mime"
@@ -41,8 +45,10 @@ import (
 	"github.com/cs3org/reva/pkg/rgrpc"
 	"github.com/cs3org/reva/pkg/rgrpc/status"
 	"github.com/cs3org/reva/pkg/rhttp/router"
+	"github.com/cs3org/reva/pkg/spaces"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/tags"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/cs3org/reva/pkg/utils/cfg"
 	"github.com/google/uuid"
@@ -105,6 +111,8 @@ func (c *config) ApplyDefaults() {
 }
 
 type service struct {
+	sppb.UnimplementedSearchAPIServer
+
 	conf               *config
 	storage            storage.FS
 	mountPath, mountID string
@@ -120,6 +128,8 @@ func (s *service) UnprotectedEndpoints() []string { return []string{} }
 
 func (s *service) Register(ss *grpc.Server) {
 	provider.RegisterProviderAPIServer(ss, s)
+	provider.RegisterSpacesAPIServer(ss, s)
+	sppb.RegisterSearchAPIServer(ss, s)
 }
 
 func parseXSTypes(xsTypes map[string]uint32) ([]*provider.ResourceChecksumPriority, error) {
@@ -215,6 +225,7 @@ func (s *service) SetArbitraryMetadata(ctx context.Context, req *provider.SetArb
 		}, nil
 	}
 
+	ctx = appctx.ContextSetLockID(ctx, req.LockId)
 	if err := s.storage.SetArbitraryMetadata(ctx, newRef, req.ArbitraryMetadata); err != nil {
 		var st *rpc.Status
 		switch err.(type) {
@@ -222,6 +233,8 @@ func (s *service) SetArbitraryMetadata(ctx context.Context, req *provider.SetArb
 			st = status.NewNotFound(ctx, "path not found when setting arbitrary metadata")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err, "resource is locked")
 		default:
 			st = status.NewInternal(ctx, err, "error setting arbitrary metadata: "+req.Ref.String())
 		}
@@ -246,6 +259,7 @@ func (s *service) UnsetArbitraryMetadata(ctx context.Context, req *provider.Unse
 		}, nil
 	}
 
+	ctx = appctx.ContextSetLockID(ctx, req.LockId)
 	if err := s.storage.UnsetArbitraryMetadata(ctx, newRef, req.ArbitraryMetadataKeys); err != nil {
 		var st *rpc.Status
 		switch err.(type) {
@@ -253,6 +267,8 @@ func (s *service) UnsetArbitraryMetadata(ctx context.Context, req *provider.Unse
 			st = status.NewNotFound(ctx, "path not found when unsetting arbitrary metadata")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err, "resource is locked")
 		default:
 			log.Error().Err(err).Str("ref", req.Ref.String()).Any("keys", req.ArbitraryMetadataKeys).Msg("error unsetting arbitrary metadata")
 			st = status.NewInternal(ctx, err, "error unsetting arbitrary metadata: "+req.Ref.String())
@@ -454,7 +470,8 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 	metadata := map[string]string{}
 	var uploadLength int64
 	if req.Opaque != nil && req.Opaque.Map != nil {
-		if req.Opaque.Map["Upload-Length"] != nil {
+		// final uploads have no length of their own
+		if req.Opaque.Map["Upload-Length"] != nil && len(req.Opaque.Map["Upload-Length"].Value) > 0 {
 			var err error
 			uploadLength, err = strconv.ParseInt(string(req.Opaque.Map["Upload-Length"].Value), 10, 64)
 			if err != nil {
@@ -467,11 +484,28 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 		if req.Opaque.Map["Upload-Checksum"] != nil {
 			metadata["checksum"] = string(req.Opaque.Map["Upload-Checksum"].Value)
 		}
+		// TUS concatenation, either 'partial' or 'final;[upload ids]'
+		if req.Opaque.Map["Upload-Concat"] != nil {
+			metadata["concat"] = string(req.Opaque.Map["Upload-Concat"].Value)
+		}
+		// the upload protocol the client is going to use
+		if req.Opaque.Map["protocol"] != nil {
+			metadata["protocol"] = string(req.Opaque.Map["protocol"].Value)
+		}
 		// ownCloud mtime to set for the uploaded file
 		if req.Opaque.Map["X-OC-Mtime"] != nil {
 			metadata["mtime"] = string(req.Opaque.Map["X-OC-Mtime"].Value)
 		}
 	}
+	if req.LockId != "" {
+		metadata["lockid"] = req.LockId
+	}
+	if metadata["concat"] != "" && !storage.ConcatenatesUploads(s.storage) {
+		err := errtypes.NotSupported("the storage cannot concatenate uploads")
+		return &provider.InitiateFileUploadResponse{
+			Status: status.NewUnimplemented(ctx, err, "upload concatenation not supported"),
+		}, nil
+	}
 	uploadIDs, err := s.storage.InitiateUpload(ctx, newRef, uploadLength, metadata)
 	if err != nil {
 		var st *rpc.Status
@@ -493,6 +527,10 @@ func (s *service) InitiateFileUpload(ctx context.Context, req *provider.Initiate
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
 		case errtypes.InsufficientStorage:
 			st = status.NewInsufficientStorage(ctx, err, "insufficient storage")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err, "resource is locked")
+		case errtypes.IsNotSupported:
+			st = status.NewUnimplemented(ctx, err, "upload not supported")
 		default:
 			st = status.NewInternal(ctx, err, "error getting upload id: "+req.Ref.String())
 		}
@@ -574,11 +612,32 @@ func (s *service) CreateHome(ctx context.Context, req *provider.CreateHomeReques
 func (s *service) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
 	resp, err := s.storage.CreateStorageSpace(ctx, req)
 	if err != nil {
-		return nil, err
+		var st *rpc.Status
+		switch err.(type) {
+		case errtypes.IsAlreadyExists:
+			st = status.NewAlreadyExists(ctx, err, "space already exists")
+		case errtypes.PermissionDenied:
+			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.BadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
+		case errtypes.NotSupported:
+			st = status.NewUnimplemented(ctx, err, "not implemented")
+		default:
+			st = status.NewInternal(ctx, err, "error creating space")
+		}
+		return &provider.CreateStorageSpaceResponse{
+			Status: st,
+		}, nil
 	}
 
-	resp.StorageSpace.Root = &provider.ResourceId{StorageId: s.mountID, OpaqueId: resp.StorageSpace.Id.OpaqueId}
-	resp.StorageSpace.Id = &provider.StorageSpaceId{OpaqueId: s.mountID + "!" + resp.StorageSpace.Id.OpaqueId}
+	if hasNodeID(resp.StorageSpace) {
+		if resp.StorageSpace.Root.StorageId == "" {
+			resp.StorageSpace.Root.StorageId = s.mountID
+		}
+	} else {
+		resp.StorageSpace.Root = &provider.ResourceId{StorageId: s.mountID, OpaqueId: resp.StorageSpace.Id.OpaqueId}
+	}
+	resp.StorageSpace.Id = &provider.StorageSpaceId{OpaqueId: s.mountID + "!" + resp.StorageSpace.Root.OpaqueId}
 	return resp, nil
 }
 
@@ -629,12 +688,72 @@ func (s *service) ListStorageSpaces(ctx context.Context, req *provider.ListStora
 }
 
 func (s *service) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
-	return s.storage.UpdateStorageSpace(ctx, req)
+	resp, err := s.storage.UpdateStorageSpace(ctx, req)
+	if err != nil {
+		var st *rpc.Status
+		switch err.(type) {
+		case errtypes.IsNotFound:
+			st = status.NewNotFound(ctx, "space not found")
+		case errtypes.PermissionDenied:
+			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.BadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
+		case errtypes.NotSupported:
+			st = status.NewUnimplemented(ctx, err, "not implemented")
+		default:
+			st = status.NewInternal(ctx, err, "error updating space")
+		}
+		return &provider.UpdateStorageSpaceResponse{
+			Status: st,
+		}, nil
+	}
+
+	if hasNodeID(resp.StorageSpace) {
+		if resp.StorageSpace.Root.StorageId == "" {
+			resp.StorageSpace.Root.StorageId = s.mountID
+		}
+		if resp.StorageSpace.Id == nil || resp.StorageSpace.Id.OpaqueId == "" {
+			resp.StorageSpace.Id = &provider.StorageSpaceId{OpaqueId: s.mountID + "!" + resp.StorageSpace.Root.OpaqueId}
+		}
+	}
+	return resp, nil
 }
 
 func (s *service) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) (*provider.DeleteStorageSpaceResponse, error) {
+	// space ids encoding the path of the root refer to the global namespace
+	if storageID, p, ok := spaces.DecodeSpaceID(req.GetId().GetOpaqueId()); ok {
+		fn, err := s.trimMountPrefix(p)
+		if err != nil {
+			return &provider.DeleteStorageSpaceResponse{
+				Status: status.NewInvalidArg(ctx, err.Error()),
+			}, nil
+		}
+		req.Id = &provider.StorageSpaceId{OpaqueId: spaces.EncodeSpaceID(storageID, fn)}
+	}
+
+	if err := s.storage.DeleteStorageSpace(ctx, req); err != nil {
+		var st *rpc.Status
+		switch err.(type) {
+		case errtypes.IsNotFound:
+			st = status.NewNotFound(ctx, "space not found")
+		case errtypes.PermissionDenied:
+			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.BadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err, "space is locked")
+		case errtypes.NotSupported:
+			st = status.NewUnimplemented(ctx, err, "not implemented")
+		default:
+			st = status.NewInternal(ctx, err, "error deleting space: "+req.Id.String())
+		}
+		return &provider.DeleteStorageSpaceResponse{
+			Status: st,
+		}, nil
+	}
+
 	return &provider.DeleteStorageSpaceResponse{
-		Status: status.NewUnimplemented(ctx, errtypes.NotSupported("DeleteStorageSpace not implemented"), "DeleteStorageSpace not implemented"),
+		Status: status.NewOK(ctx),
 	}, nil
 }
 
@@ -711,6 +830,7 @@ func (s *service) Delete(ctx context.Context, req *provider.DeleteRequest) (*pro
 		}, nil
 	}
 
+	ctx = appctx.ContextSetLockID(ctx, req.LockId)
 	if err := s.storage.Delete(ctx, newRef); err != nil {
 		var st *rpc.Status
 		switch err.(type) {
@@ -718,6 +838,8 @@ func (s *service) Delete(ctx context.Context, req *provider.DeleteRequest) (*pro
 			st = status.NewNotFound(ctx, "path not found when creating container")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err, "resource is locked")
 		default:
 			st = status.NewInternal(ctx, err, "error deleting file: "+req.Ref.String())
 		}
@@ -746,6 +868,10 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 		}, nil
 	}
 
+	ctx = appctx.ContextSetLockID(ctx, req.LockId)
+	if req.Opaque != nil && req.Opaque.Map["destination_lockid"] != nil {
+		ctx = appctx.ContextSetDestinationLockID(ctx, string(req.Opaque.Map["destination_lockid"].Value))
+	}
 	if err := s.storage.Move(ctx, sourceRef, targetRef); err != nil {
 		var st *rpc.Status
 		switch err.(type) {
@@ -753,6 +879,8 @@ func (s *service) Move(ctx context.Context, req *provider.MoveRequest) (*provide
 			st = status.NewNotFound(ctx, "path not found when moving")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err, "resource is locked")
 		default:
 			st = status.NewInternal(ctx, err, "error moving: "+sourceRef.String())
 		}
@@ -816,7 +944,9 @@ func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provide
 	}
 	s.fixPermissions(md)
 	s.stripNonUtf8Metadata(ctx, md)
+	stripUserTags(ctx, md)
 	s.addSpaceInfo(md)
+	s.addUploadInfo(md)
 	res := &provider.StatResponse{
 		Status: status.NewOK(ctx),
 		Info:   md,
@@ -825,6 +955,24 @@ func (s *service) Stat(ctx context.Context, req *provider.StatRequest) (*provide
 	return res, nil
 }
 
+// addUploadInfo lets the clients know whether the uploads to the folder
+// described by md can be concatenated.
+func (s *service) addUploadInfo(md *provider.ResourceInfo) {
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER || !storage.ConcatenatesUploads(s.storage) {
+		return
+	}
+	if md.Opaque == nil {
+		md.Opaque = &typesv1beta1.Opaque{}
+	}
+	if md.Opaque.Map == nil {
+		md.Opaque.Map = map[string]*typesv1beta1.OpaqueEntry{}
+	}
+	md.Opaque.Map["tus_concatenation"] = &typesv1beta1.OpaqueEntry{
+		Decoder: "plain",
+		Value:   []byte("true"),
+	}
+}
+
 func pathLevels(p string) int {
 	if p == "/" {
 		return 0
@@ -864,6 +1012,16 @@ func (s *service) stripNonUtf8Metadata(ctx context.Context, md *provider.Resourc
 	}
 }
 
+// stripUserTags removes from the arbitrary metadata the tags
+// set by the users other than the one in ctx.
+func stripUserTags(ctx context.Context, md *provider.ResourceInfo) {
+	var id *userpb.UserId
+	if u, ok := appctx.ContextGetUser(ctx); ok {
+		id = u.Id
+	}
+	tags.StripUserTags(md, id)
+}
+
 func (s *service) statVirtualView(ctx context.Context, ref *provider.Reference) (*provider.StatResponse, error) {
 	// The reference in the request encompasses this provider
 	// So we need to stat root, and update the required path
@@ -914,19 +1072,10 @@ func (s *service) ListContainerStream(req *provider.ListContainerStreamRequest,
 		return nil
 	}
 
-	mds, err := s.storage.ListFolder(ctx, newRef, req.ArbitraryMetadataKeys)
+	mds, err := storage.ListFolderStream(ctx, s.storage, newRef, req.ArbitraryMetadataKeys)
 	if err != nil {
-		var st *rpc.Status
-		switch err.(type) {
-		case errtypes.IsNotFound:
-			st = status.NewNotFound(ctx, "path not found when listing container")
-		case errtypes.PermissionDenied:
-			st = status.NewPermissionDenied(ctx, err, "permission denied")
-		default:
-			st = status.NewInternal(ctx, err, "error listing container: "+req.Ref.String())
-		}
 		res := &provider.ListContainerStreamResponse{
-			Status: st,
+			Status: listContainerStatus(ctx, req.Ref, err),
 		}
 		if err := ss.Send(res); err != nil {
 			log.Error().Err(err).Msg("ListContainerStream: error sending response")
@@ -934,9 +1083,25 @@ func (s *service) ListContainerStream(req *provider.ListContainerStreamRequest,
 		}
 		return nil
 	}
+	defer mds.Close()
 
 	prefixMountpoint := utils.IsAbsoluteReference(req.Ref)
-	for _, md := range mds {
+	for {
+		md, err := mds.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// the entries sent so far must be discarded by the client
+			res := &provider.ListContainerStreamResponse{
+				Status: listContainerStatus(ctx, req.Ref, err),
+			}
+			if err := ss.Send(res); err != nil {
+				log.Error().Err(err).Msg("ListContainerStream: error sending response")
+				return err
+			}
+			return nil
+		}
 		if err := s.wrap(ctx, md, prefixMountpoint); err != nil {
 			res := &provider.ListContainerStreamResponse{
 				Status: status.NewInternal(ctx, err, "error wrapping path"),
@@ -947,6 +1112,7 @@ func (s *service) ListContainerStream(req *provider.ListContainerStreamRequest,
 			}
 			return nil
 		}
+		stripUserTags(ctx, md)
 		res := &provider.ListContainerStreamResponse{
 			Info:   md,
 			Status: status.NewOK(ctx),
@@ -957,7 +1123,17 @@ func (s *service) ListContainerStream(req *provider.ListContainerStreamRequest,
 			return err
 		}
 	}
-	return nil
+}
+
+func listContainerStatus(ctx context.Context, ref *provider.Reference, err error) *rpc.Status {
+	switch err.(type) {
+	case errtypes.IsNotFound:
+		return status.NewNotFound(ctx, "path not found when listing container")
+	case errtypes.PermissionDenied:
+		return status.NewPermissionDenied(ctx, err, "permission denied")
+	default:
+		return status.NewInternal(ctx, err, "error listing container: "+ref.String())
+	}
 }
 
 func (s *service) ListContainer(ctx context.Context, req *provider.ListContainerRequest) (*provider.ListContainerResponse, error) {
@@ -1016,6 +1192,7 @@ func (s *service) ListContainer(ctx context.Context, req *provider.ListContainer
 		}
 		s.fixPermissions(md)
 		s.stripNonUtf8Metadata(ctx, md)
+		stripUserTags(ctx, md)
 		infos = append(infos, md)
 	}
 	res := &provider.ListContainerResponse{
@@ -1141,6 +1318,7 @@ func (s *service) RestoreFileVersion(ctx context.Context, req *provider.RestoreF
 		}, nil
 	}
 
+	ctx = appctx.ContextSetLockID(ctx, req.LockId)
 	if err := s.storage.RestoreRevision(ctx, newRef, req.Key); err != nil {
 		var st *rpc.Status
 		switch err.(type) {
@@ -1148,6 +1326,8 @@ func (s *service) RestoreFileVersion(ctx context.Context, req *provider.RestoreF
 			st = status.NewNotFound(ctx, "path not found when restoring file versions")
 		case errtypes.PermissionDenied:
 			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.IsLocked:
+			st = status.NewLocked(ctx, err, "resource is locked")
 		default:
 			st = status.NewInternal(ctx, err, "error restoring version: "+req.Ref.String())
 		}
@@ -1172,21 +1352,10 @@ func (s *service) ListRecycleStream(req *provider.ListRecycleStreamRequest, ss p
 	}
 
 	key, itemPath := router.ShiftPath(req.Key)
-	items, err := s.storage.ListRecycle(ctx, ref.GetPath(), key, itemPath, req.FromTs, req.ToTs)
+	items, err := storage.ListRecycleStream(ctx, s.storage, ref.GetPath(), key, itemPath, req.FromTs, req.ToTs)
 	if err != nil {
-		var st *rpc.Status
-		switch err.(type) {
-		case errtypes.IsNotFound:
-			st = status.NewNotFound(ctx, "path not found when listing recycle stream")
-		case errtypes.PermissionDenied:
-			st = status.NewPermissionDenied(ctx, err, "permission denied")
-		case errtypes.BadRequest:
-			st = status.NewInvalidArg(ctx, "too many days or too many entries")
-		default:
-			st = status.NewInternal(ctx, err, "error listing recycle stream")
-		}
 		res := &provider.ListRecycleStreamResponse{
-			Status: st,
+			Status: listRecycleStatus(ctx, err),
 		}
 		if err := ss.Send(res); err != nil {
 			log.Error().Err(err).Msg("ListRecycleStream: error sending response")
@@ -1194,9 +1363,24 @@ func (s *service) ListRecycleStream(req *provider.ListRecycleStreamRequest, ss p
 		}
 		return nil
 	}
+	defer items.Close()
 
 	// TODO(labkode): CRITICAL: fill recycle info with storage provider.
-	for _, item := range items {
+	for {
+		item, err := items.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			res := &provider.ListRecycleStreamResponse{
+				Status: listRecycleStatus(ctx, err),
+			}
+			if err := ss.Send(res); err != nil {
+				log.Error().Err(err).Msg("ListRecycleStream: error sending response")
+				return err
+			}
+			return nil
+		}
 		res := &provider.ListRecycleStreamResponse{
 			RecycleItem: item,
 			Status:      status.NewOK(ctx),
@@ -1206,7 +1390,19 @@ func (s *service) ListRecycleStream(req *provider.ListRecycleStreamRequest, ss p
 			return err
 		}
 	}
-	return nil
+}
+
+func listRecycleStatus(ctx context.Context, err error) *rpc.Status {
+	switch err.(type) {
+	case errtypes.IsNotFound:
+		return status.NewNotFound(ctx, "path not found when listing recycle stream")
+	case errtypes.PermissionDenied:
+		return status.NewPermissionDenied(ctx, err, "permission denied")
+	case errtypes.BadRequest:
+		return status.NewInvalidArg(ctx, "too many days or too many entries")
+	default:
+		return status.NewInternal(ctx, err, "error listing recycle stream")
+	}
 }
 
 func (s *service) ListRecycle(ctx context.Context, req *provider.ListRecycleRequest) (*provider.ListRecycleResponse, error) {
@@ -1540,8 +1736,35 @@ func (s *service) CreateReference(ctx context.Context, req *provider.CreateRefer
 }
 
 func (s *service) CreateSymlink(ctx context.Context, req *provider.CreateSymlinkRequest) (*provider.CreateSymlinkResponse, error) {
+	newRef, err := s.unwrap(ctx, req.Ref)
+	if err != nil {
+		return &provider.CreateSymlinkResponse{
+			Status: status.NewInternal(ctx, err, "error unwrapping path"),
+		}, nil
+	}
+	if err := s.storage.CreateSymlink(ctx, newRef, req.Target); err != nil {
+		var st *rpc.Status
+		switch err.(type) {
+		case errtypes.IsNotFound:
+			st = status.NewNotFound(ctx, "path not found when creating symlink")
+		case errtypes.AlreadyExists:
+			st = status.NewAlreadyExists(ctx, err, "symlink already exists")
+		case errtypes.PermissionDenied:
+			st = status.NewPermissionDenied(ctx, err, "permission denied")
+		case errtypes.BadRequest:
+			st = status.NewInvalidArg(ctx, err.Error())
+		case errtypes.NotSupported:
+			st = status.NewUnimplemented(ctx, err, "symlinks are not supported by the storage")
+		default:
+			st = status.NewInternal(ctx, err, "error creating symlink: "+req.Ref.String())
+		}
+		return &provider.CreateSymlinkResponse{
+			Status: st,
+		}, nil
+	}
+
 	return &provider.CreateSymlinkResponse{
-		Status: status.NewUnimplemented(ctx, errtypes.NotSupported("CreateSymlink not implemented"), "CreateSymlink not implemented"),
+		Status: status.NewOK(ctx),
 	}, nil
 }
 
@@ -21,6 +21,7 @@ package auth
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -43,6 +44,7 @@ import (
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/token"
 	tokenmgr "github.com/cs3org/reva/pkg/token/manager/registry"
+	revocationregistry "github.com/cs3org/reva/pkg/token/revocation/registry"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -66,6 +68,9 @@ type config struct {
 	TokenManagers          map[string]map[string]interface{} `mapstructure:"token_managers"`
 	TokenWriter            string                            `mapstructure:"token_writer"`
 	TokenWriters           map[string]map[string]interface{} `mapstructure:"token_writers"`
+	// RevocationStore, if set, is used to reject the revoked tokens.
+	RevocationStore  string                            `mapstructure:"revocation_store"`
+	RevocationStores map[string]map[string]interface{} `mapstructure:"revocation_stores"`
 }
 
 func parseConfig(m map[string]interface{}) (*config, error) {
@@ -146,6 +151,11 @@ func New(m map[string]interface{}, unprotected []string) (global.Middleware, err
 		return nil, err
 	}
 
+	tokenManager, err = revocationregistry.WrapManager(tokenManager, conf.RevocationStore, conf.RevocationStores)
+	if err != nil {
+		return nil, err
+	}
+
 	i, ok := tokenwriterregistry.NewTokenFuncs[conf.TokenWriter]
 	if !ok {
 		return nil, fmt.Errorf("token writer not found: %s", conf.TokenWriter)
@@ -254,7 +264,10 @@ func authenticateUser(w http.ResponseWriter, r *http.Request, conf *config, toke
 
 	log.Debug().Msgf("AuthenticateRequest: type: %s, client_id: %s against %s", req.Type, req.ClientId, conf.GatewaySvc)
 
-	res, err := client.Authenticate(ctx, req)
+	// the address of the client is forwarded to the auth providers,
+	// which may throttle the failed logins per client
+	authCtx := metadata.AppendToOutgoingContext(ctx, appctx.ClientAddrHeader, clientAddr(r))
+	res, err := client.Authenticate(authCtx, req)
 	if err != nil {
 		logError(isUnprotectedEndpoint, log, err, "error calling Authenticate", http.StatusUnauthorized, w)
 		return nil, err
@@ -294,6 +307,15 @@ func authenticateUser(w http.ResponseWriter, r *http.Request, conf *config, toke
 	return ctxWithUserInfo(ctx, r, u, token), nil
 }
 
+// clientAddr returns the host the request was received from.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func ctxWithUserInfo(ctx context.Context, r *http.Request, user *userpb.User, token string) context.Context {
 	ctx = appctx.ContextSetUser(ctx, user)
 	ctx = appctx.ContextSetToken(ctx, token)
@@ -38,6 +38,7 @@ import (
 	_ "github.com/cs3org/reva/internal/http/services/pprof"
 	_ "github.com/cs3org/reva/internal/http/services/preferences"
 	_ "github.com/cs3org/reva/internal/http/services/prometheus"
+	_ "github.com/cs3org/reva/internal/http/services/scim"
 	_ "github.com/cs3org/reva/internal/http/services/wellknown"
 	// Add your own service here.
 )
@@ -30,6 +30,7 @@ import (
 	"github.com/cs3org/reva/internal/http/services/wellknown"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ocm/signature"
 	"github.com/pkg/errors"
 )
 
@@ -52,6 +53,7 @@ var ErrInvalidParameters = errors.New("invalid parameters")
 // OCMClient is the client for an OCM provider.
 type OCMClient struct {
 	client *http.Client
+	signer *signature.Signer
 }
 
 // NewClient returns a new OCMClient.
@@ -67,6 +69,22 @@ func NewClient(timeout time.Duration, insecure bool) *OCMClient {
 	}
 }
 
+// WithSigner makes the client sign the requests sent to the remote providers.
+func (c *OCMClient) WithSigner(s *signature.Signer) *OCMClient {
+	c.signer = s
+	return c
+}
+
+// do sends the request, signing it if the client has a signer.
+func (c *OCMClient) do(req *http.Request) (*http.Response, error) {
+	if c.signer != nil {
+		if err := c.signer.Sign(req); err != nil {
+			return nil, err
+		}
+	}
+	return c.client.Do(req)
+}
+
 // Discover returns a number of properties used to discover the capabilities offered by a remote cloud storage.
 // https://cs3org.github.io/OCM-API/docs.html?branch=develop&repo=OCM-API&user=cs3org#/paths/~1ocm-provider/get
 func (c *OCMClient) Discover(ctx context.Context, endpoint string) (*wellknown.OcmDiscoveryData, error) {
@@ -142,7 +160,7 @@ func (c *OCMClient) NewShare(ctx context.Context, endpoint string, r *NewShareRe
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "error doing request")
 	}
@@ -170,6 +188,47 @@ func (c *OCMClient) parseNewShareResponse(r *http.Response) (*NewShareResponse,
 	return nil, errtypes.InternalError(string(body))
 }
 
+// Notify sends a notification about a share to the remote system.
+// https://cs3org.github.io/OCM-API/docs.html?branch=develop&repo=OCM-API&user=cs3org#/paths/~1notifications/post
+func (c *OCMClient) Notify(ctx context.Context, endpoint string, r *NotificationRequest) error {
+	url, err := url.JoinPath(endpoint, "notifications")
+	if err != nil {
+		return err
+	}
+
+	body, err := r.toJSON()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return errors.Wrap(err, "error creating request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return errors.Wrap(err, "error doing request")
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	case http.StatusBadRequest:
+		return ErrInvalidParameters
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrServiceNotTrusted
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "error decoding response body")
+	}
+	return errtypes.InternalError(string(b))
+}
+
 // InviteAccepted informs the remote end that the invitation was accepted to start sharing
 // https://cs3org.github.io/OCM-API/docs.html?branch=develop&repo=OCM-API&user=cs3org#/paths/~1invite-accepted/post
 func (c *OCMClient) InviteAccepted(ctx context.Context, endpoint string, r *InviteAcceptedRequest) (*RemoteUser, error) {
@@ -189,7 +248,7 @@ func (c *OCMClient) InviteAccepted(ctx context.Context, endpoint string, r *Invi
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "error doing request")
 	}
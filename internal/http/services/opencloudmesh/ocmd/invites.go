@@ -38,6 +38,7 @@ import (
 
 type invitesHandler struct {
 	gatewayClient gateway.GatewayAPIClient
+	signatures    *signatureVerifier
 }
 
 func (h *invitesHandler) init(c *config) error {
@@ -55,6 +56,12 @@ func (h *invitesHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
 
+	signer, err := h.signatures.verify(w, r)
+	if err != nil {
+		reqres.WriteError(w, r, reqres.APIErrorUntrustedService, "invalid request signature", err)
+		return
+	}
+
 	req, err := getAcceptInviteRequest(r)
 	if err != nil {
 		reqres.WriteError(w, r, reqres.APIErrorInvalidParameter, "missing parameters in request", err)
@@ -66,6 +73,10 @@ func (h *invitesHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
 		reqres.WriteError(w, r, reqres.APIErrorInvalidParameter, "token, userID and recipiendProvider must not be null", nil)
 		return
 	}
+	if !signedBy(signer, req.RecipientProvider) {
+		reqres.WriteError(w, r, reqres.APIErrorUntrustedService, "request not signed by the recipient provider", nil)
+		return
+	}
 
 	clientIP, err := utils.GetClientIP(r)
 	if err != nil {
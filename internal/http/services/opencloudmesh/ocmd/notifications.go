@@ -19,21 +19,28 @@
 package ocmd
 
 import (
-	"io"
+	"context"
+	"encoding/json"
 	"mime"
 	"net/http"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
-
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	ocmcore "github.com/cs3org/go-cs3apis/cs3/ocm/core/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/internal/http/services/reqres"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
 )
 
-// var validate = validator.New()
-
 type notifHandler struct {
 	gatewayClient gateway.GatewayAPIClient
+	machineSecret string
+	signatures    *signatureVerifier
 }
 
 func (h *notifHandler) init(c *config) error {
@@ -42,16 +49,10 @@ func (h *notifHandler) init(c *config) error {
 	if err != nil {
 		return err
 	}
+	h.machineSecret = c.MachineSecret
 	return nil
 }
 
-// type notificationRequest struct {
-//	NotificationType string `json:"notificationType" validate:"required"`
-//	ResourceType     string `json:"resourceType" validate:"required"`
-//	ProviderId       string `json:"providerId" validate:"required"`
-//	Notification 	 ...	`json:"notification"`
-//}
-
 // Example of payload from Nextcloud:
 // {
 //   "notificationType": <one of "SHARE_ACCEPTED", "SHARE_DECLINED", "REQUEST_RESHARE", "SHARE_UNSHARED", "RESHARE_UNDO", "RESHARE_CHANGE_PERMISSION">,
@@ -72,35 +73,167 @@ func (h *notifHandler) init(c *config) error {
 func (h *notifHandler) Notifications(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
-	req, err := getNotification(r)
+	if _, err := h.signatures.verify(w, r); err != nil {
+		reqres.WriteError(w, r, reqres.APIErrorUntrustedService, "invalid request signature", err)
+		return
+	}
+
+	req, err := getNotificationRequest(r)
 	if err != nil {
 		reqres.WriteError(w, r, reqres.APIErrorInvalidParameter, err.Error(), nil)
 		return
 	}
+	log.Info().Str("type", req.NotificationType).Str("provider_id", req.ProviderID).Msg("OCM /notifications request received")
+
+	switch req.NotificationType {
+	case NotificationShareAccepted, NotificationShareDeclined, NotificationUserRemoved:
+		h.handleRecipientNotification(w, r, req)
+	case NotificationShareUnshared:
+		h.handleUnshare(w, r, req)
+	case NotificationRequestReshare, NotificationReshareUndo, NotificationReshareChangePermission:
+		reqres.WriteError(w, r, reqres.APIErrorUnimplemented, "notification type not supported: "+req.NotificationType, nil)
+	default:
+		reqres.WriteError(w, r, reqres.APIErrorInvalidParameter, "unknown notification type: "+req.NotificationType, nil)
+	}
+}
 
-	// TODO(lopresti) this is all to be implemented. For now we just log what we got
-	log.Debug().Msgf("Received OCM notification: %+v", req)
+// handleRecipientNotification handles the notifications sent by the recipient
+// of a share created on this provider, identified by its shared secret.
+func (h *notifHandler) handleRecipientNotification(w http.ResponseWriter, r *http.Request, req *NotificationRequest) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	shareRes, err := h.gatewayClient.GetOCMShareByToken(ctx, &ocm.GetOCMShareByTokenRequest{
+		Token: req.Notification.SharedSecret,
+	})
+	switch {
+	case err != nil:
+		reqres.WriteError(w, r, reqres.APIErrorServerError, "error getting ocm share by token", err)
+		return
+	case shareRes.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		reqres.WriteError(w, r, reqres.APIErrorUntrustedService, "invalid shared secret", nil)
+		return
+	case shareRes.Status.Code != rpc.Code_CODE_OK:
+		reqres.WriteError(w, r, reqres.APIErrorServerError, "error getting ocm share by token", errors.New(shareRes.Status.Message))
+		return
+	case shareRes.Share.Id.OpaqueId != req.ProviderID:
+		reqres.WriteError(w, r, reqres.APIErrorUntrustedService, "invalid shared secret", nil)
+		return
+	}
+	ocmshare := shareRes.Share
+
+	if req.NotificationType == NotificationShareAccepted {
+		log.Info().Str("share", ocmshare.Id.OpaqueId).Msg("ocm share accepted by the recipient")
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	// the share was declined or the recipient does not exist anymore,
+	// so the share is removed on behalf of its owner
+	if h.machineSecret == "" {
+		reqres.WriteError(w, r, reqres.APIErrorUnimplemented, "removal of declined shares is not enabled", nil)
+		return
+	}
+	ownerCtx, err := h.impersonate(ctx, ocmshare.Owner)
+	if err != nil {
+		reqres.WriteError(w, r, reqres.APIErrorServerError, "error impersonating the owner of the share", err)
+		return
+	}
+
+	res, err := h.gatewayClient.RemoveOCMShare(ownerCtx, &ocm.RemoveOCMShareRequest{
+		Ref: &ocm.ShareReference{
+			Spec: &ocm.ShareReference_Id{
+				Id: ocmshare.Id,
+			},
+		},
+	})
+	if err != nil {
+		reqres.WriteError(w, r, reqres.APIErrorServerError, "error removing ocm share", err)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+		reqres.WriteError(w, r, reqres.APIErrorServerError, "error removing ocm share", errors.New(res.Status.Message))
+		return
+	}
+	log.Info().Str("share", ocmshare.Id.OpaqueId).Str("type", req.NotificationType).Msg("ocm share removed after the notification of the recipient")
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// handleUnshare removes the received share that was removed by its owner.
+func (h *notifHandler) handleUnshare(w http.ResponseWriter, r *http.Request, req *NotificationRequest) {
+	ctx := r.Context()
+	res, err := h.gatewayClient.DeleteOCMCoreShare(ctx, &ocmcore.DeleteOCMCoreShareRequest{
+		Id: req.ProviderID,
+		Opaque: &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				"shared_secret": {
+					Decoder: "plain",
+					Value:   []byte(req.Notification.SharedSecret),
+				},
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		reqres.WriteError(w, r, reqres.APIErrorServerError, "error removing received share", err)
+		return
+	case res.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		reqres.WriteError(w, r, reqres.APIErrorUntrustedService, "invalid shared secret", nil)
+		return
+	case res.Status.Code != rpc.Code_CODE_OK:
+		reqres.WriteError(w, r, reqres.APIErrorServerError, "error removing received share", errors.New(res.Status.Message))
+		return
+	}
 
-	// this is to please Nextcloud
 	w.WriteHeader(http.StatusCreated)
 }
 
-func getNotification(r *http.Request) (string, error) { // (*notificationRequest, error)
-	// var req notificationRequest
+// impersonate returns a context authenticated as the given user,
+// using the machine authentication.
+func (h *notifHandler) impersonate(ctx context.Context, userID *userpb.UserId) (context.Context, error) {
+	userRes, err := h.gatewayClient.GetUser(ctx, &userpb.GetUserRequest{
+		UserId:                 userID,
+		SkipFetchingUserGroups: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if userRes.Status.Code != rpc.Code_CODE_OK {
+		return nil, errors.New(userRes.Status.Message)
+	}
+
+	authRes, err := h.gatewayClient.Authenticate(ctx, &gateway.AuthenticateRequest{
+		Type:         "machine",
+		ClientId:     userRes.User.Username,
+		ClientSecret: h.machineSecret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if authRes.Status.Code != rpc.Code_CODE_OK {
+		return nil, errors.New(authRes.Status.Message)
+	}
+
+	ctx = appctx.ContextSetToken(ctx, authRes.Token)
+	ctx = appctx.ContextSetUser(ctx, authRes.User)
+	ctx = metadata.AppendToOutgoingContext(ctx, appctx.TokenHeader, authRes.Token)
+	return ctx, nil
+}
+
+func getNotificationRequest(r *http.Request) (*NotificationRequest, error) {
+	var req NotificationRequest
 	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err == nil && contentType == "application/json" {
-		bytes, _ := io.ReadAll(r.Body)
-		return string(bytes), nil
-		// if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		//	return nil, err
-		//}
-		// } else {
-		//	return nil, errors.New("body request not recognised")
-	}
-	return "", nil
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, errors.Wrap(err, "malformed OCM /notifications request")
+		}
+	} else {
+		return nil, errors.New("malformed OCM /notifications request payload")
+	}
 	// validate the request
-	// if err := validate.Struct(req); err != nil {
-	//	return nil, err
-	//}
-	// return &req, nil
+	if err := validate.Struct(req); err != nil {
+		return nil, err
+	}
+	return &req, nil
 }
@@ -0,0 +1,98 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocmd
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdexlab/go-render/render"
+)
+
+func TestGetNotificationRequest(t *testing.T) {
+	tests := []struct {
+		description string
+		contentType string
+		body        string
+		expected    *NotificationRequest
+		err         bool
+	}{
+		{
+			description: "valid notification",
+			contentType: "application/json",
+			body:        `{"notificationType":"SHARE_UNSHARED","resourceType":"file","providerId":"1","notification":{"sharedSecret":"secret","message":"the share has expired"}}`,
+			expected: &NotificationRequest{
+				NotificationType: NotificationShareUnshared,
+				ResourceType:     "file",
+				ProviderID:       "1",
+				Notification: &Notification{
+					SharedSecret: "secret",
+					Message:      "the share has expired",
+				},
+			},
+		},
+		{
+			description: "missing shared secret",
+			contentType: "application/json",
+			body:        `{"notificationType":"SHARE_ACCEPTED","resourceType":"file","providerId":"1","notification":{"message":"accepted"}}`,
+			err:         true,
+		},
+		{
+			description: "missing notification",
+			contentType: "application/json",
+			body:        `{"notificationType":"SHARE_ACCEPTED","resourceType":"file","providerId":"1"}`,
+			err:         true,
+		},
+		{
+			description: "missing provider id",
+			contentType: "application/json",
+			body:        `{"notificationType":"SHARE_DECLINED","resourceType":"file","notification":{"sharedSecret":"secret"}}`,
+			err:         true,
+		},
+		{
+			description: "not a json payload",
+			contentType: "text/plain",
+			body:        "SHARE_ACCEPTED",
+			err:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/notifications", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+
+			got, err := getNotificationRequest(r)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", render.AsCode(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("not expected error: %+v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("notifications do not match. got=%+v expected=%+v", render.AsCode(got), render.AsCode(tt.expected))
+			}
+		})
+	}
+}
@@ -35,8 +35,11 @@ func init() {
 
 type config struct {
 	Prefix                     string `mapstructure:"prefix"`
-	GatewaySvc                 string `mapstructure:"gatewaysvc"                    validate:"required"`
+	GatewaySvc                 string `mapstructure:"gatewaysvc"                                                                                                         validate:"required"`
 	ExposeRecipientDisplayName bool   `mapstructure:"expose_recipient_display_name"`
+	MachineSecret              string `docs:";The secret of the machine authentication, used to remove the shares declined by their recipients on behalf of the owners." mapstructure:"machine_secret"`
+	SignatureVerification      string `docs:"optional;How the signatures of the incoming requests are verified: off, optional (only signed requests) or required"        mapstructure:"signature_verification"`
+	SignatureInsecure          bool   `docs:"false;Whether to skip the TLS verification when getting the public keys of the remote providers"                            mapstructure:"signature_insecure"`
 }
 
 func (c *config) ApplyDefaults() {
@@ -44,6 +47,9 @@ func (c *config) ApplyDefaults() {
 	if c.Prefix == "" {
 		c.Prefix = "ocm"
 	}
+	if c.SignatureVerification == "" {
+		c.SignatureVerification = signaturesOptional
+	}
 }
 
 type svc struct {
@@ -77,6 +83,14 @@ func (s *svc) routerInit() error {
 	invitesHandler := new(invitesHandler)
 	notifHandler := new(notifHandler)
 
+	signatures, err := newSignatureVerifier(s.Conf)
+	if err != nil {
+		return err
+	}
+	sharesHandler.signatures = signatures
+	invitesHandler.signatures = signatures
+	notifHandler.signatures = signatures
+
 	if err := sharesHandler.init(s.Conf); err != nil {
 		return err
 	}
@@ -52,6 +52,7 @@ var validate = validator.New()
 type sharesHandler struct {
 	gatewayClient              gateway.GatewayAPIClient
 	exposeRecipientDisplayName bool
+	signatures                 *signatureVerifier
 }
 
 func (h *sharesHandler) init(c *config) error {
@@ -68,6 +69,12 @@ func (h *sharesHandler) init(c *config) error {
 func (h *sharesHandler) CreateShare(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
+	signer, err := h.signatures.verify(w, r)
+	if err != nil {
+		reqres.WriteError(w, r, reqres.APIErrorUntrustedService, "invalid request signature", err)
+		return
+	}
+
 	req, err := getCreateShareRequest(r)
 	log.Info().Any("req", req).Msg("OCM /shares request received")
 	if err != nil {
@@ -81,6 +88,10 @@ func (h *sharesHandler) CreateShare(w http.ResponseWriter, r *http.Request) {
 		reqres.WriteError(w, r, reqres.APIErrorInvalidParameter, err.Error(), nil)
 		return
 	}
+	if !signedBy(signer, meshProvider) {
+		reqres.WriteError(w, r, reqres.APIErrorUntrustedService, "request not signed by the provider of the sender", nil)
+		return
+	}
 
 	clientIP, err := utils.GetClientIP(r)
 	if err != nil {
@@ -0,0 +1,146 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocmd
+
+import (
+	"context"
+	"crypto/rsa"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/wellknown"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ocm/signature"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/pkg/errors"
+)
+
+// The levels of verification of the signatures of the incoming OCM requests.
+const (
+	// signaturesOff disables the verification of the signatures.
+	signaturesOff = "off"
+	// signaturesOptional verifies only the signed requests, accepting the unsigned ones.
+	signaturesOptional = "optional"
+	// signaturesRequired rejects the unsigned requests.
+	signaturesRequired = "required"
+)
+
+// maxBodyLength is the maximum length of the body of the incoming OCM
+// requests, that is read in full to check its digest.
+const maxBodyLength = 1 << 20
+
+type signatureVerifier struct {
+	level    string
+	verifier *signature.Verifier
+}
+
+func newSignatureVerifier(c *config) (*signatureVerifier, error) {
+	switch c.SignatureVerification {
+	case signaturesOff, signaturesOptional, signaturesRequired:
+	default:
+		return nil, errors.New("ocmd: invalid signature verification level " + c.SignatureVerification)
+	}
+
+	gw, err := pool.GetGatewayServiceClient(pool.Endpoint(c.GatewaySvc))
+	if err != nil {
+		return nil, err
+	}
+	client := NewClient(time.Duration(10)*time.Second, c.SignatureInsecure)
+	return &signatureVerifier{
+		level: c.SignatureVerification,
+		verifier: signature.NewVerifier(func(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+			return resolveKey(ctx, gw, client, keyID)
+		}),
+	}, nil
+}
+
+// resolveKey gets the public key from the discovery document of the provider owning it.
+// The key id comes from the request, so the provider must be trusted before
+// its discovery document is fetched.
+func resolveKey(ctx context.Context, gw gateway.GatewayAPIClient, client *OCMClient, keyID string) (*rsa.PublicKey, error) {
+	domain, err := signature.Domain(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTrusted(ctx, gw, domain); err != nil {
+		return nil, err
+	}
+	disco, err := client.Discover(ctx, "https://"+domain)
+	if err != nil {
+		return nil, err
+	}
+	// the previous keys are published while the provider rotates its key
+	for _, k := range append([]*wellknown.PublicKey{disco.PublicKey}, disco.PreviousPublicKeys...) {
+		if k != nil && k.KeyID == keyID {
+			return signature.ParsePublicKey(k.PublicKeyPem)
+		}
+	}
+	return nil, errtypes.NotFound("key " + keyID)
+}
+
+// checkTrusted checks that the provider owning the domain
+// is registered in the provider authorizer.
+func checkTrusted(ctx context.Context, gw gateway.GatewayAPIClient, domain string) error {
+	res, err := gw.GetInfoByDomain(ctx, &ocmprovider.GetInfoByDomainRequest{Domain: domain})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return errtypes.PermissionDenied("provider " + domain + " is not trusted")
+	}
+	// the lookup in the provider authorizer is not exact
+	if d := res.ProviderInfo.GetDomain(); d == "" || !signedBy(domain, d) {
+		return errtypes.PermissionDenied("provider " + domain + " is not trusted")
+	}
+	return nil
+}
+
+// verify verifies the signature of r, returning the domain of the provider that
+// signed it, or an empty string if r is not signed and signatures are not required.
+func (v *signatureVerifier) verify(w http.ResponseWriter, r *http.Request) (string, error) {
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyLength)
+	}
+	if v.level == signaturesOff {
+		return "", nil
+	}
+	keyID, err := v.verifier.Verify(r)
+	if err == signature.ErrNotSigned && v.level == signaturesOptional {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return signature.Domain(keyID)
+}
+
+// signedBy returns whether a request signed by the given
+// provider was sent on behalf of the domain.
+func signedBy(signer, domain string) bool {
+	if signer == "" || strings.EqualFold(signer, domain) {
+		return true
+	}
+	host, _, err := net.SplitHostPort(signer)
+	return err == nil && strings.EqualFold(host, domain)
+}
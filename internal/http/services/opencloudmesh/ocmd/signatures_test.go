@@ -0,0 +1,208 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	ocmprovider "github.com/cs3org/go-cs3apis/cs3/ocm/provider/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/cs3org/reva/internal/http/services/wellknown"
+	"github.com/cs3org/reva/pkg/ocm/signature"
+	"google.golang.org/grpc"
+)
+
+func TestSignedBy(t *testing.T) {
+	tests := []struct {
+		signer   string
+		domain   string
+		expected bool
+	}{
+		{signer: "", domain: "cernbox.cern.ch", expected: true},
+		{signer: "cernbox.cern.ch", domain: "cernbox.cern.ch", expected: true},
+		{signer: "CERNBox.cern.ch", domain: "cernbox.cern.ch", expected: true},
+		{signer: "cernbox.cern.ch:443", domain: "cernbox.cern.ch", expected: true},
+		{signer: "cernbox.cern.ch:443", domain: "cernbox.cern.ch:443", expected: true},
+		{signer: "cesnet.cz", domain: "cernbox.cern.ch", expected: false},
+		{signer: "cernbox.cern.ch.evil.org", domain: "cernbox.cern.ch", expected: false},
+	}
+
+	for _, tt := range tests {
+		if got := signedBy(tt.signer, tt.domain); got != tt.expected {
+			t.Errorf("signedBy(%q, %q) = %v, expected %v", tt.signer, tt.domain, got, tt.expected)
+		}
+	}
+}
+
+type authorizerGateway struct {
+	gateway.GatewayAPIClient
+	providers map[string]string
+}
+
+func (g *authorizerGateway) GetInfoByDomain(_ context.Context, req *ocmprovider.GetInfoByDomainRequest, _ ...grpc.CallOption) (*ocmprovider.GetInfoByDomainResponse, error) {
+	d, ok := g.providers[req.Domain]
+	if !ok {
+		return &ocmprovider.GetInfoByDomainResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+	}
+	return &ocmprovider.GetInfoByDomainResponse{
+		Status:       &rpc.Status{Code: rpc.Code_CODE_OK},
+		ProviderInfo: &ocmprovider.ProviderInfo{Domain: d},
+	}, nil
+}
+
+func TestResolveKey(t *testing.T) {
+	current, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var disco wellknown.OcmDiscoveryData
+	var discovered int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&discovered, 1)
+		_ = json.NewEncoder(w).Encode(&disco)
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	domain := u.Host
+
+	publicKey := func(key *rsa.PrivateKey) *wellknown.PublicKey {
+		pem, err := signature.EncodePublicKey(&key.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &wellknown.PublicKey{KeyID: signature.KeyID(domain, &key.PublicKey), PublicKeyPem: pem}
+	}
+	disco.PublicKey = publicKey(current)
+	disco.PreviousPublicKeys = []*wellknown.PublicKey{publicKey(previous)}
+
+	unknown, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		providers   map[string]string
+		keyID       string
+		expected    *rsa.PublicKey
+		discovered  bool
+	}{
+		{
+			description: "current key",
+			providers:   map[string]string{domain: u.Hostname()},
+			keyID:       signature.KeyID(domain, &current.PublicKey),
+			expected:    &current.PublicKey,
+			discovered:  true,
+		},
+		{
+			description: "previous key during a rotation",
+			providers:   map[string]string{domain: u.Hostname()},
+			keyID:       signature.KeyID(domain, &previous.PublicKey),
+			expected:    &previous.PublicKey,
+			discovered:  true,
+		},
+		{
+			description: "unknown key",
+			providers:   map[string]string{domain: u.Hostname()},
+			keyID:       signature.KeyID(domain, &unknown.PublicKey),
+			discovered:  true,
+		},
+		{
+			description: "untrusted provider",
+			providers:   map[string]string{},
+			keyID:       signature.KeyID(domain, &current.PublicKey),
+		},
+		{
+			description: "provider matched loosely by the authorizer",
+			providers:   map[string]string{domain: "evil.org"},
+			keyID:       signature.KeyID(domain, &current.PublicKey),
+		},
+	}
+
+	client := NewClient(time.Second, true)
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			atomic.StoreInt32(&discovered, 0)
+			key, err := resolveKey(context.Background(), &authorizerGateway{providers: tt.providers}, client, tt.keyID)
+			switch {
+			case tt.expected == nil && err == nil:
+				t.Fatalf("expected an error, got key %v", key)
+			case tt.expected != nil && err != nil:
+				t.Fatalf("unexpected error %v", err)
+			case tt.expected != nil && !tt.expected.Equal(key):
+				t.Fatal("unexpected key")
+			}
+			if got := atomic.LoadInt32(&discovered) > 0; got != tt.discovered {
+				t.Errorf("discovery document fetched: %v, expected %v", got, tt.discovered)
+			}
+		})
+	}
+}
+
+func TestVerifyBodyLength(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := signature.NewSigner("cernbox.cern.ch", key)
+	v := &signatureVerifier{
+		level: signaturesRequired,
+		verifier: signature.NewVerifier(func(context.Context, string) (*rsa.PublicKey, error) {
+			return &key.PublicKey, nil
+		}),
+	}
+
+	tests := []struct {
+		description string
+		length      int
+		valid       bool
+	}{
+		{description: "small body", length: 1024, valid: true},
+		{description: "body at the limit", length: maxBodyLength, valid: true},
+		{description: "body over the limit", length: maxBodyLength + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/ocm/shares", strings.NewReader(strings.Repeat("a", tt.length)))
+			if err := signer.Sign(r); err != nil {
+				t.Fatal(err)
+			}
+			_, err := v.verify(httptest.NewRecorder(), r)
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("expected valid %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
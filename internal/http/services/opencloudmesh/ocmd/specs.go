@@ -92,6 +92,47 @@ type NewShareResponse struct {
 	RecipientDisplayName string `json:"recipientDisplayName"`
 }
 
+// The types of the OCM notifications.
+const (
+	// NotificationShareAccepted is sent by the recipient when a share is accepted.
+	NotificationShareAccepted = "SHARE_ACCEPTED"
+	// NotificationShareDeclined is sent by the recipient when a share is declined.
+	NotificationShareDeclined = "SHARE_DECLINED"
+	// NotificationShareUnshared is sent by the provider when a share is removed by its owner.
+	NotificationShareUnshared = "SHARE_UNSHARED"
+	// NotificationUserRemoved is sent by the recipient when the recipient user does not exist anymore.
+	NotificationUserRemoved = "USER_REMOVED"
+	// NotificationRequestReshare is sent by the recipient to ask for resharing a share.
+	NotificationRequestReshare = "REQUEST_RESHARE"
+	// NotificationReshareUndo is sent by the provider when a reshare is removed.
+	NotificationReshareUndo = "RESHARE_UNDO"
+	// NotificationReshareChangePermission is sent by the provider when the permissions of a reshare are changed.
+	NotificationReshareChangePermission = "RESHARE_CHANGE_PERMISSION"
+)
+
+// NotificationRequest contains the payload of an OCM /notifications request.
+// https://cs3org.github.io/OCM-API/docs.html?branch=develop&repo=OCM-API&user=cs3org#/paths/~1notifications/post
+type NotificationRequest struct {
+	NotificationType string        `json:"notificationType" validate:"required"` // type of the notification
+	ResourceType     string        `json:"resourceType"     validate:"required"` // type of the shared resource
+	ProviderID       string        `json:"providerId"       validate:"required"` // identifier of the share at the provider side
+	Notification     *Notification `json:"notification"     validate:"required"` // details of the notification
+}
+
+// Notification contains the details of an OCM notification.
+type Notification struct {
+	SharedSecret string `json:"sharedSecret"      validate:"required"` // secret of the share the notification is about
+	Message      string `json:"message,omitempty"`                     // human-readable message
+}
+
+func (r *NotificationRequest) toJSON() (io.Reader, error) {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(r); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
 // Protocols is the list of OCM protocols.
 type Protocols []Protocol
 
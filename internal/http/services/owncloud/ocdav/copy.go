@@ -42,6 +42,8 @@ type copy struct {
 	destination *provider.Reference
 	depth       string
 	successCode int
+	// lockID is the id of the lock held on the destination, if submitted
+	lockID string
 }
 
 type intermediateDirRefFunc func() (*provider.Reference, *rpc.Status, error)
@@ -191,7 +193,8 @@ func (s *svc) executePathCopy(ctx context.Context, client gateway.GatewayAPIClie
 		// 2. get upload url
 
 		uReq := &provider.InitiateFileUploadRequest{
-			Ref: cp.destination,
+			Ref:    cp.destination,
+			LockId: cp.lockID,
 			Opaque: &typespb.Opaque{
 				Map: map[string]*typespb.OpaqueEntry{
 					"Upload-Length": {
@@ -405,7 +408,8 @@ func (s *svc) executeSpacesCopy(ctx context.Context, w http.ResponseWriter, clie
 		}
 		// 2. get upload url
 		uReq := &provider.InitiateFileUploadRequest{
-			Ref: cp.destination,
+			Ref:    cp.destination,
+			LockId: cp.lockID,
 			Opaque: &typespb.Opaque{
 				Map: map[string]*typespb.OpaqueEntry{
 					HeaderUploadLength: {
@@ -516,6 +520,17 @@ func (s *svc) prepareCopy(ctx context.Context, w http.ResponseWriter, r *http.Re
 		return nil
 	}
 
+	// only the destination is modified, the lists may refer to its parent
+	dstRes := &ifResource{href: hrefPath(r.Header.Get(HeaderDestination)), ref: dstRef}
+	resources := []*ifResource{requestResource(r, srcRef), dstRes}
+	if parent := parentResource(dstRes); parent != nil {
+		resources = append(resources, parent)
+	}
+	lockIDs, ok := s.checkIfHeader(ctx, w, r, client, *log, resources...)
+	if !ok {
+		return nil
+	}
+
 	srcStatReq := &provider.StatRequest{Ref: srcRef}
 	srcStatRes, err := client.Stat(ctx, srcStatReq)
 	if err != nil {
@@ -567,7 +582,7 @@ func (s *svc) prepareCopy(ctx context.Context, w http.ResponseWriter, r *http.Re
 		}
 
 		// delete existing tree
-		delReq := &provider.DeleteRequest{Ref: dstRef}
+		delReq := &provider.DeleteRequest{Ref: dstRef, LockId: lockIDs[1]}
 		delRes, err := client.Delete(ctx, delReq)
 		if err != nil {
 			log.Error().Err(err).Msg("error sending grpc delete request")
@@ -612,7 +627,7 @@ func (s *svc) prepareCopy(ctx context.Context, w http.ResponseWriter, r *http.Re
 		// TODO what if intermediate is a file?
 	}
 
-	return &copy{sourceInfo: srcStatRes.Info, depth: depth, successCode: successCode, destination: dstRef}
+	return &copy{sourceInfo: srcStatRes.Info, depth: depth, successCode: successCode, destination: dstRef, lockID: lockIDs[1]}
 }
 
 func extractOverwrite(w http.ResponseWriter, r *http.Request) (string, error) {
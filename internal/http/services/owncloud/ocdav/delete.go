@@ -46,7 +46,12 @@ func (s *svc) handleDelete(ctx context.Context, w http.ResponseWriter, r *http.R
 		return
 	}
 
-	req := &provider.DeleteRequest{Ref: ref}
+	lockIDs, ok := s.checkIfHeader(ctx, w, r, client, log, requestResource(r, ref))
+	if !ok {
+		return
+	}
+
+	req := &provider.DeleteRequest{Ref: ref, LockId: lockIDs[0]}
 	res, err := client.Delete(ctx, req)
 	if err != nil {
 		log.Error().Err(err).Msg("error performing delete grpc request")
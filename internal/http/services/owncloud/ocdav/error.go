@@ -122,6 +122,9 @@ func HandleErrorStatus(log *zerolog.Logger, w http.ResponseWriter, s *rpc.Status
 	case rpc.Code_CODE_FAILED_PRECONDITION:
 		log.Debug().Interface("status", s).Msg("destination does not exist")
 		w.WriteHeader(http.StatusConflict)
+	case rpc.Code_CODE_LOCKED:
+		log.Debug().Interface("status", s).Msg("resource is locked")
+		w.WriteHeader(http.StatusLocked)
 	default:
 		log.Error().Interface("status", s).Msg("grpc request failed")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -0,0 +1,338 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+var errInvalidIf = errors.New("webdav: invalid If header")
+
+// ifHeader is the parsed If header of a request, see
+// http://www.webdav.org/specs/rfc4918.html#HEADER_If
+type ifHeader struct {
+	lists []ifList
+}
+
+// ifList is a list of conditions, that is true when all the conditions are.
+// Untagged lists apply to the request-URI.
+type ifList struct {
+	resourceTag string
+	conditions  []ifCondition
+}
+
+// ifCondition matches either a state token or an entity tag.
+type ifCondition struct {
+	not   bool
+	token string
+	etag  string
+}
+
+// parseIfHeader parses the If header, following the grammar
+// of http://www.webdav.org/specs/rfc4918.html#rfc.section.10.4.2
+func parseIfHeader(h string) (ifHeader, error) {
+	var ih ifHeader
+	tagged, tag := false, ""
+	s := strings.TrimSpace(h)
+	for s != "" {
+		switch s[0] {
+		case '<':
+			end := strings.IndexByte(s, '>')
+			if end < 0 || (len(ih.lists) > 0 && !tagged) {
+				return ifHeader{}, errInvalidIf
+			}
+			tagged, tag = true, s[1:end]
+			s = s[end+1:]
+		case '(':
+			if len(ih.lists) > 0 && tagged != (ih.lists[0].resourceTag != "") {
+				return ifHeader{}, errInvalidIf
+			}
+			l, rest, err := parseIfList(s[1:])
+			if err != nil {
+				return ifHeader{}, err
+			}
+			l.resourceTag = tag
+			ih.lists = append(ih.lists, l)
+			s = rest
+		default:
+			return ifHeader{}, errInvalidIf
+		}
+		s = strings.TrimSpace(s)
+	}
+	if len(ih.lists) == 0 {
+		return ifHeader{}, errInvalidIf
+	}
+	return ih, nil
+}
+
+// parseIfList parses the conditions of a list up to the closing parenthesis,
+// and returns the rest of the header.
+func parseIfList(s string) (ifList, string, error) {
+	var l ifList
+	for {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return ifList{}, "", errInvalidIf
+		}
+		if s[0] == ')' {
+			if len(l.conditions) == 0 {
+				return ifList{}, "", errInvalidIf
+			}
+			return l, s[1:], nil
+		}
+
+		var c ifCondition
+		if len(s) > 3 && strings.EqualFold(s[:3], "not") {
+			c.not = true
+			s = strings.TrimSpace(s[3:])
+		}
+		switch {
+		case strings.HasPrefix(s, "<"):
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return ifList{}, "", errInvalidIf
+			}
+			c.token, s = s[1:end], s[end+1:]
+		case strings.HasPrefix(s, "["):
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return ifList{}, "", errInvalidIf
+			}
+			c.etag, s = s[1:end], s[end+1:]
+		default:
+			return ifList{}, "", errInvalidIf
+		}
+		l.conditions = append(l.conditions, c)
+	}
+}
+
+// ifResource is a resource the lists of an If header may apply to.
+type ifResource struct {
+	// href is the path of the URL the client addresses the resource with.
+	href string
+	ref  *provider.Reference
+
+	fetched bool
+	info    *provider.ResourceInfo
+	lock    *provider.Lock
+}
+
+// state fetches the etag and the lock of the resource, at most once.
+func (res *ifResource) state(ctx context.Context, client gateway.GatewayAPIClient) error {
+	if res.fetched {
+		return nil
+	}
+
+	sRes, err := client.Stat(ctx, &provider.StatRequest{Ref: res.ref})
+	if err != nil {
+		return err
+	}
+	switch sRes.Status.Code {
+	case rpc.Code_CODE_OK:
+		res.info = sRes.Info
+	case rpc.Code_CODE_NOT_FOUND:
+		// unmapped resources have neither an etag nor a lock
+		res.fetched = true
+		return nil
+	default:
+		return errors.New("error stating resource: " + sRes.Status.Message)
+	}
+
+	lRes, err := client.GetLock(ctx, &provider.GetLockRequest{Ref: res.ref})
+	if err != nil {
+		return err
+	}
+	switch lRes.Status.Code {
+	case rpc.Code_CODE_OK:
+		if !lockExpired(lRes.Lock) {
+			res.lock = lRes.Lock
+		}
+	case rpc.Code_CODE_NOT_FOUND, rpc.Code_CODE_UNIMPLEMENTED:
+	default:
+		return errors.New("error getting lock: " + lRes.Status.Message)
+	}
+	res.fetched = true
+	return nil
+}
+
+func (c ifCondition) matches(res *ifResource) bool {
+	var match bool
+	switch {
+	case c.token != "":
+		match = res.lock != nil && c.token == lockToken(res.lock.LockId)
+	case res.info != nil:
+		match = strings.Trim(strings.TrimPrefix(c.etag, "W/"), `"`) == strings.Trim(res.info.Etag, `"`)
+	}
+	return match != c.not
+}
+
+// hrefPath returns the cleaned path of the given URL, as used to compare resources.
+func hrefPath(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	return path.Clean("/" + u.Path)
+}
+
+// evaluate returns whether the If header holds for the given resources.
+// Lists tagged with resources other than the given ones never match.
+func (ih ifHeader) evaluate(ctx context.Context, client gateway.GatewayAPIClient, resources []*ifResource) (bool, error) {
+	for _, l := range ih.lists {
+		res := resources[0]
+		if l.resourceTag != "" {
+			res = nil
+			for _, r := range resources {
+				if hrefPath(l.resourceTag) == r.href {
+					res = r
+					break
+				}
+			}
+		}
+		if res == nil {
+			continue
+		}
+		if err := res.state(ctx, client); err != nil {
+			return false, err
+		}
+
+		match := true
+		for _, c := range l.conditions {
+			if !c.matches(res) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// tokens returns all the state tokens submitted in the header.
+func (ih ifHeader) tokens() []string {
+	var tokens []string
+	for _, l := range ih.lists {
+		for _, c := range l.conditions {
+			if c.token != "" && !c.not {
+				tokens = append(tokens, c.token)
+			}
+		}
+	}
+	return tokens
+}
+
+// checkIfHeader evaluates the If header of the request, if any, against
+// the given resources, the first one being the request-URI. It returns
+// for every resource the id of its lock, when the client submitted the
+// token of that lock. Without If header, the lock id eventually passed
+// in the X-Lock-Id header is returned for all the resources.
+// If the request cannot be performed, the error is written to w and
+// false is returned.
+func (s *svc) checkIfHeader(ctx context.Context, w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, log zerolog.Logger, resources ...*ifResource) ([]string, bool) {
+	ids := make([]string, len(resources))
+
+	h := r.Header.Get(HeaderIf)
+	if h == "" {
+		for i := range ids {
+			ids[i] = r.Header.Get(HeaderLockID)
+		}
+		return ids, true
+	}
+
+	ih, err := parseIfHeader(h)
+	if err != nil {
+		log.Debug().Err(err).Str("if", h).Msg("error parsing If header")
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+
+	ok, err := ih.evaluate(ctx, client, resources)
+	if err != nil {
+		log.Error().Err(err).Msg("error evaluating If header")
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	if !ok {
+		log.Debug().Str("if", h).Msg("If header does not match")
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return nil, false
+	}
+
+	tokens := ih.tokens()
+	for i, res := range resources {
+		if len(tokens) == 0 {
+			break
+		}
+		if err := res.state(ctx, client); err != nil {
+			log.Error().Err(err).Msg("error evaluating If header")
+			w.WriteHeader(http.StatusInternalServerError)
+			return nil, false
+		}
+		if res.lock == nil {
+			continue
+		}
+		for _, t := range tokens {
+			if t == lockToken(res.lock.LockId) {
+				ids[i] = res.lock.LockId
+				break
+			}
+		}
+	}
+	return ids, true
+}
+
+// requestHref returns the escaped path of the request-URI, as sent by the client.
+func requestHref(r *http.Request) string {
+	u, err := url.Parse(r.RequestURI)
+	if err != nil {
+		return r.URL.EscapedPath()
+	}
+	return u.EscapedPath()
+}
+
+// requestResource returns the resource addressed by the request-URI.
+func requestResource(r *http.Request, ref *provider.Reference) *ifResource {
+	return &ifResource{href: hrefPath(r.RequestURI), ref: ref}
+}
+
+// parentResource returns the parent collection of the given resource, as
+// tagged lists may refer to it when a member is added or removed. It returns
+// nil when the parent cannot be addressed by path.
+func parentResource(res *ifResource) *ifResource {
+	p := res.ref.GetPath()
+	if p == "" || p == "/" || p == "." || res.href == "/" {
+		return nil
+	}
+	return &ifResource{
+		href: path.Dir(res.href),
+		ref:  &provider.Reference{ResourceId: res.ref.GetResourceId(), Path: path.Dir(p)},
+	}
+}
@@ -19,33 +19,408 @@
 package ocdav
 
 import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 )
 
-// TODO(jfd) implement lock.
-func (s *svc) handleLock(w http.ResponseWriter, r *http.Request, ns string) {
-	log := appctx.GetLogger(r.Context())
-	xml := `<?xml version="1.0" encoding="utf-8"?>
-	<prop xmlns="DAV:">
-		<lockdiscovery>
-			<activelock>
-				<allprop/>
-				<timeout>Second-604800</timeout>
-				<depth>Infinity</depth>
-				<locktoken>
-				<href>opaquelocktoken:00000000-0000-0000-0000-000000000000</href>
-				</locktoken>
-			</activelock>
-		</lockdiscovery>
-	</prop>`
-
-	w.Header().Set("Content-Type", "text/xml; charset=\"utf-8\"")
-	w.Header().Set("Lock-Token",
-		"opaquelocktoken:00000000-0000-0000-0000-000000000000")
-	_, err := w.Write([]byte(xml))
+const (
+	// lockTokenPrefix is the URI scheme of the lock tokens, the CS3 lock id
+	// being the rest of the token, see http://www.webdav.org/specs/rfc4918.html#opaquelocktoken.uri.scheme
+	lockTokenPrefix = "opaquelocktoken:"
+	// maxLockTimeout is the longest time a lock is granted for,
+	// also used when the client asks for an infinite timeout.
+	maxLockTimeout = 7 * 24 * time.Hour
+)
+
+var errInvalidLockInfo = errors.New("webdav: invalid lock info")
+
+// http://www.webdav.org/specs/rfc4918.html#ELEMENT_lockinfo
+type lockInfoXML struct {
+	XMLName   xml.Name  `xml:"DAV: lockinfo"`
+	Exclusive *struct{} `xml:"lockscope>exclusive"`
+	Shared    *struct{} `xml:"lockscope>shared"`
+	Write     *struct{} `xml:"locktype>write"`
+	Owner     ownerXML  `xml:"owner"`
+}
+
+// http://www.webdav.org/specs/rfc4918.html#ELEMENT_owner
+type ownerXML struct {
+	// XML is the content of the element, re-encoded so that it carries
+	// the declarations of the namespaces it uses.
+	XML string
+}
+
+// UnmarshalXML re-encodes the content of the owner element, as the
+// prefixes the client used are not declared in the responses.
+func (o *ownerXML) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var b strings.Builder
+	enc := xml.NewEncoder(&b)
+	for depth := 0; ; {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := t.(type) {
+		case xml.StartElement:
+			depth++
+			attrs := tok.Attr[:0:0]
+			for _, a := range tok.Attr {
+				// the encoder declares the namespaces on its own
+				if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+					continue
+				}
+				attrs = append(attrs, a)
+			}
+			tok.Attr = attrs
+			t = tok
+		case xml.EndElement:
+			if depth == 0 {
+				if err := enc.Flush(); err != nil {
+					return err
+				}
+				o.XML = b.String()
+				return nil
+			}
+			depth--
+		case xml.CharData:
+		default:
+			// comments, processing instructions and directives are dropped
+			continue
+		}
+		if err := enc.EncodeToken(t); err != nil {
+			return err
+		}
+	}
+}
+
+// readLockInfo parses the body of a LOCK request. An empty body
+// means that the client wants to refresh an existing lock.
+func readLockInfo(r io.Reader) (li lockInfoXML, refresh bool, err error) {
+	c := countingReader{r: r}
+	if err = xml.NewDecoder(&c).Decode(&li); err != nil {
+		if err == io.EOF && c.n == 0 {
+			return lockInfoXML{}, true, nil
+		}
+		return lockInfoXML{}, false, errInvalidLockInfo
+	}
+	if li.Write == nil || (li.Exclusive == nil) == (li.Shared == nil) {
+		return lockInfoXML{}, false, errInvalidLockInfo
+	}
+	return li, false, nil
+}
+
+// parseTimeout returns the first supported timeout of the Timeout header,
+// see http://www.webdav.org/specs/rfc4918.html#HEADER_Timeout
+func parseTimeout(h string) (time.Duration, error) {
+	if h == "" {
+		return maxLockTimeout, nil
+	}
+	for _, t := range strings.Split(h, ",") {
+		t = strings.TrimSpace(t)
+		if t == "Infinite" {
+			return maxLockTimeout, nil
+		}
+		if !strings.HasPrefix(t, "Second-") {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimPrefix(t, "Second-"), 10, 32)
+		if err != nil {
+			continue
+		}
+		if d := time.Duration(n) * time.Second; d < maxLockTimeout {
+			return d, nil
+		}
+		return maxLockTimeout, nil
+	}
+	return 0, errors.New("webdav: invalid timeout " + h)
+}
+
+func lockToken(lockID string) string {
+	return lockTokenPrefix + lockID
+}
+
+func lockExpired(l *provider.Lock) bool {
+	return l.GetExpiration() != nil && time.Unix(int64(l.Expiration.Seconds), 0).Before(time.Now())
+}
+
+func lockExpiration(timeout time.Duration) *types.Timestamp {
+	return &types.Timestamp{Seconds: uint64(time.Now().Add(timeout).Unix())}
+}
+
+// activeLockXML renders the given lock as a DAV:activelock element.
+// The owner is only known when the lock is created or refreshed.
+func (s *svc) activeLockXML(l *provider.Lock, root, owner string) string {
+	var b strings.Builder
+	b.WriteString("<d:activelock><d:locktype><d:write/></d:locktype><d:lockscope>")
+	if l.Type == provider.LockType_LOCK_TYPE_SHARED {
+		b.WriteString("<d:shared/>")
+	} else {
+		b.WriteString("<d:exclusive/>")
+	}
+	// locks never apply to the members of a collection
+	b.WriteString("</d:lockscope><d:depth>0</d:depth>")
+	switch {
+	case owner != "":
+		b.WriteString("<d:owner>" + owner + "</d:owner>")
+	case l.AppName != "":
+		b.WriteString("<d:owner>")
+		b.Write(s.xmlEscaped(l.AppName))
+		b.WriteString("</d:owner>")
+	}
+	if l.Expiration != nil {
+		timeout := time.Until(time.Unix(int64(l.Expiration.Seconds), 0))
+		if timeout < 0 {
+			timeout = 0
+		}
+		fmt.Fprintf(&b, "<d:timeout>Second-%d</d:timeout>", int64(timeout.Seconds()))
+	} else {
+		b.WriteString("<d:timeout>Infinite</d:timeout>")
+	}
+	b.WriteString("<d:locktoken><d:href>")
+	b.Write(s.xmlEscaped(lockToken(l.LockId)))
+	b.WriteString("</d:href></d:locktoken><d:lockroot><d:href>")
+	b.Write(s.xmlEscaped(root))
+	b.WriteString("</d:href></d:lockroot></d:activelock>")
+	return b.String()
+}
+
+// supportedLockXML is the value of the DAV:supportedlock property.
+const supportedLockXML = "<d:lockentry><d:lockscope><d:exclusive/></d:lockscope><d:locktype><d:write/></d:locktype></d:lockentry>" +
+	"<d:lockentry><d:lockscope><d:shared/></d:lockscope><d:locktype><d:write/></d:locktype></d:lockentry>"
+
+// writeDavError writes the given precondition code of
+// http://www.webdav.org/specs/rfc4918.html#precondition.postcondition.xml.elements
+func writeDavError(w http.ResponseWriter, status int, condition string, log zerolog.Logger) {
+	w.Header().Set(HeaderContentType, "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := w.Write([]byte(xml.Header + `<d:error xmlns:d="DAV:"><d:` + condition + `/></d:error>`)); err != nil {
+		log.Err(err).Msg("error writing response")
+	}
+}
+
+func (s *svc) handlePathLock(w http.ResponseWriter, r *http.Request, ns string) {
+	ctx := r.Context()
+	fn := path.Join(ns, r.URL.Path)
+
+	sublog := appctx.GetLogger(ctx).With().Str("path", fn).Logger()
+	s.handleLock(ctx, w, r, &provider.Reference{Path: fn}, sublog)
+}
+
+func (s *svc) handleSpacesLock(w http.ResponseWriter, r *http.Request, spaceID string) {
+	ctx := r.Context()
+	sublog := appctx.GetLogger(ctx).With().Str("spaceid", spaceID).Str("path", r.URL.Path).Logger()
+
+	ref, status, err := s.lookUpStorageSpaceReference(ctx, spaceID, r.URL.Path)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending a grpc request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, status)
+		return
+	}
+
+	s.handleLock(ctx, w, r, ref, sublog)
+}
+
+// handleLock creates or refreshes a lock, see http://www.webdav.org/specs/rfc4918.html#METHOD_LOCK
+// Locks are backed by the CS3 locks of the storage providers, and always have depth 0.
+func (s *svc) handleLock(ctx context.Context, w http.ResponseWriter, r *http.Request, ref *provider.Reference, log zerolog.Logger) {
+	timeout, err := parseTimeout(r.Header.Get(HeaderTimeout))
 	if err != nil {
+		log.Debug().Err(err).Msg("error parsing timeout")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	li, refresh, err := readLockInfo(r.Body)
+	if err != nil {
+		log.Debug().Err(err).Msg("error reading lock info")
+		w.WriteHeader(http.StatusBadRequest)
+		b, err := Marshal(exception{
+			code:    SabredavBadRequest,
+			message: err.Error(),
+		})
+		HandleWebdavError(&log, w, b, err)
+		return
+	}
+
+	client, err := s.getClient()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	u, ok := appctx.ContextGetUser(ctx)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if refresh {
+		s.refreshLock(ctx, w, r, client, ref, u.Id, timeout, log)
+		return
+	}
+
+	sRes, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+	if err != nil {
+		log.Error().Err(err).Msg("error sending grpc stat request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	created := false
+	switch sRes.Status.Code {
+	case rpc.Code_CODE_OK:
+		depth := r.Header.Get(HeaderDepth)
+		if sRes.Info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER && depth != "0" {
+			log.Debug().Str("depth", depth).Msg("infinite locks on collections not supported")
+			w.WriteHeader(http.StatusBadRequest)
+			b, err := Marshal(exception{
+				code:    SabredavBadRequest,
+				message: "Collections can only be locked with Depth: 0",
+				header:  HeaderDepth,
+			})
+			HandleWebdavError(&log, w, b, err)
+			return
+		}
+	case rpc.Code_CODE_NOT_FOUND:
+		// locking an unmapped URL creates an empty resource,
+		// see http://www.webdav.org/specs/rfc4918.html#rfc.section.7.3
+		tRes, err := client.TouchFile(ctx, &provider.TouchFileRequest{Ref: ref})
+		if err != nil {
+			log.Error().Err(err).Msg("error sending grpc touch file request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if tRes.Status.Code != rpc.Code_CODE_OK {
+			if tRes.Status.Code == rpc.Code_CODE_NOT_FOUND {
+				// 409 if intermediate dir is missing
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			HandleErrorStatus(&log, w, tRes.Status)
+			return
+		}
+		created = true
+	default:
+		HandleErrorStatus(&log, w, sRes.Status)
+		return
+	}
+
+	lock := &provider.Lock{
+		LockId:     uuid.New().String(),
+		Type:       provider.LockType_LOCK_TYPE_WRITE,
+		User:       u.Id,
+		Expiration: lockExpiration(timeout),
+	}
+	if li.Shared != nil {
+		lock.Type = provider.LockType_LOCK_TYPE_SHARED
+	}
+
+	res, err := client.SetLock(ctx, &provider.SetLockRequest{Ref: ref, Lock: lock})
+	if err != nil {
+		log.Error().Err(err).Msg("error sending grpc set lock request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		if res.Status.Code == rpc.Code_CODE_FAILED_PRECONDITION || res.Status.Code == rpc.Code_CODE_LOCKED {
+			log.Debug().Interface("status", res.Status).Msg("resource already locked")
+			writeDavError(w, http.StatusLocked, "no-conflicting-lock", log)
+			return
+		}
+		HandleErrorStatus(&log, w, res.Status)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	w.Header().Set(HeaderLockToken, "<"+lockToken(lock.LockId)+">")
+	s.writeLockDiscovery(w, r, status, lock, li.Owner.XML, log)
+}
+
+// refreshLock extends the timeout of the lock whose token is submitted in the If header.
+func (s *svc) refreshLock(ctx context.Context, w http.ResponseWriter, r *http.Request, client gateway.GatewayAPIClient, ref *provider.Reference, user *userpb.UserId, timeout time.Duration, log zerolog.Logger) {
+	if r.Header.Get(HeaderIf) == "" {
+		// a refresh must submit the lock token
+		writeDavError(w, http.StatusPreconditionFailed, "lock-token-submitted", log)
+		return
+	}
+	ids, ok := s.checkIfHeader(ctx, w, r, client, log, requestResource(r, ref))
+	if !ok {
+		return
+	}
+	if ids[0] == "" {
+		writeDavError(w, http.StatusPreconditionFailed, "lock-token-matches-request-uri", log)
+		return
+	}
+
+	lRes, err := client.GetLock(ctx, &provider.GetLockRequest{Ref: ref})
+	if err != nil {
+		log.Error().Err(err).Msg("error sending grpc get lock request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if lRes.Status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&log, w, lRes.Status)
+		return
+	}
+
+	lock := &provider.Lock{
+		LockId:     lRes.Lock.LockId,
+		Type:       lRes.Lock.Type,
+		User:       user,
+		AppName:    lRes.Lock.AppName,
+		Expiration: lockExpiration(timeout),
+	}
+	res, err := client.RefreshLock(ctx, &provider.RefreshLockRequest{Ref: ref, Lock: lock})
+	if err != nil {
+		log.Error().Err(err).Msg("error sending grpc refresh lock request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		if res.Status.Code == rpc.Code_CODE_FAILED_PRECONDITION {
+			writeDavError(w, http.StatusPreconditionFailed, "lock-token-matches-request-uri", log)
+			return
+		}
+		HandleErrorStatus(&log, w, res.Status)
+		return
+	}
+
+	s.writeLockDiscovery(w, r, http.StatusOK, lock, "", log)
+}
+
+func (s *svc) writeLockDiscovery(w http.ResponseWriter, r *http.Request, status int, lock *provider.Lock, owner string, log zerolog.Logger) {
+	body := xml.Header + `<d:prop xmlns:d="DAV:"><d:lockdiscovery>` +
+		s.activeLockXML(lock, requestHref(r), owner) +
+		`</d:lockdiscovery></d:prop>`
+
+	w.Header().Set(HeaderContentType, "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := w.Write([]byte(body)); err != nil {
 		log.Err(err).Msg("error writing response")
 	}
 }
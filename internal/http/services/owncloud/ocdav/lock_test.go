@@ -0,0 +1,320 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+func TestParseIfHeader(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected []ifList
+	}{
+		{
+			header: "(<opaquelocktoken:a>)",
+			expected: []ifList{
+				{conditions: []ifCondition{{token: "opaquelocktoken:a"}}},
+			},
+		},
+		{
+			header: `(<opaquelocktoken:a> ["etag"]) (Not <DAV:no-lock>)`,
+			expected: []ifList{
+				{conditions: []ifCondition{{token: "opaquelocktoken:a"}, {etag: `"etag"`}}},
+				{conditions: []ifCondition{{not: true, token: "DAV:no-lock"}}},
+			},
+		},
+		{
+			header: `<http://example.org/dav/a> (<opaquelocktoken:a>) </dav/b> (<opaquelocktoken:b>) (["etag"])`,
+			expected: []ifList{
+				{resourceTag: "http://example.org/dav/a", conditions: []ifCondition{{token: "opaquelocktoken:a"}}},
+				{resourceTag: "/dav/b", conditions: []ifCondition{{token: "opaquelocktoken:b"}}},
+				{resourceTag: "/dav/b", conditions: []ifCondition{{etag: `"etag"`}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		ih, err := parseIfHeader(tt.header)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.header, err)
+		}
+		if !reflect.DeepEqual(ih.lists, tt.expected) {
+			t.Fatalf("%s: got %+v, expected %+v", tt.header, ih.lists, tt.expected)
+		}
+	}
+
+	for _, header := range []string{
+		"",
+		"<opaquelocktoken:a>",
+		"()",
+		"(<opaquelocktoken:a>",
+		"(opaquelocktoken:a)",
+		"(<opaquelocktoken:a>) </dav/b> (<opaquelocktoken:b>)",
+	} {
+		if _, err := parseIfHeader(header); err == nil {
+			t.Fatalf("%q: expected an error", header)
+		}
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":                               maxLockTimeout,
+		"Infinite":                       maxLockTimeout,
+		"Second-600":                     10 * time.Minute,
+		"Second-4100000000":              maxLockTimeout,
+		"Infinite, Second-600":           maxLockTimeout,
+		"Extension-1, Second-3600":       time.Hour,
+		"Second-invalid, Second-60":      time.Minute,
+		"Second-60, Infinite, Second-10": time.Minute,
+	}
+	for header, expected := range tests {
+		got, err := parseTimeout(header)
+		if err != nil {
+			t.Fatalf("%q: %v", header, err)
+		}
+		if got != expected {
+			t.Fatalf("%q: got %v, expected %v", header, got, expected)
+		}
+	}
+	if _, err := parseTimeout("Second-"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestReadLockInfo(t *testing.T) {
+	li, refresh, err := readLockInfo(strings.NewReader(`<?xml version="1.0" encoding="utf-8" ?>
+<D:lockinfo xmlns:D="DAV:">
+	<D:lockscope><D:exclusive/></D:lockscope>
+	<D:locktype><D:write/></D:locktype>
+	<D:owner><D:href>http://example.org/~einstein</D:href></D:owner>
+</D:lockinfo>`))
+	if err != nil || refresh {
+		t.Fatalf("unexpected result %v %v", refresh, err)
+	}
+	if li.Exclusive == nil || li.Shared != nil || !strings.Contains(li.Owner.XML, "http://example.org/~einstein") {
+		t.Fatalf("unexpected lock info %+v", li)
+	}
+
+	// the prefixes declared by the client are not known in the responses
+	li, _, err = readLockInfo(strings.NewReader(`<D:lockinfo xmlns:D="DAV:" xmlns:E="http://example.org/ns">
+	<D:lockscope><D:shared/></D:lockscope>
+	<D:locktype><D:write/></D:locktype>
+	<D:owner><E:contact E:kind="mail">einstein@example.org</E:contact></D:owner>
+</D:lockinfo>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var owner struct {
+		XMLName xml.Name
+		Kind    string `xml:"http://example.org/ns kind,attr"`
+		Value   string `xml:",chardata"`
+	}
+	if err := xml.Unmarshal([]byte(li.Owner.XML), &owner); err != nil {
+		t.Fatalf("%s: %v", li.Owner.XML, err)
+	}
+	if owner.XMLName.Space != "http://example.org/ns" || owner.XMLName.Local != "contact" || owner.Kind != "mail" || owner.Value != "einstein@example.org" {
+		t.Fatalf("unexpected owner %s", li.Owner.XML)
+	}
+
+	if _, refresh, err := readLockInfo(strings.NewReader("")); err != nil || !refresh {
+		t.Fatalf("an empty body should refresh the lock, got %v %v", refresh, err)
+	}
+
+	for _, body := range []string{
+		`<D:lockinfo xmlns:D="DAV:"><D:locktype><D:write/></D:locktype></D:lockinfo>`,
+		`<D:lockinfo xmlns:D="DAV:"><D:lockscope><D:exclusive/><D:shared/></D:lockscope><D:locktype><D:write/></D:locktype></D:lockinfo>`,
+		`<D:lockinfo xmlns:D="DAV:"><D:lockscope><D:exclusive/></D:lockscope></D:lockinfo>`,
+		`<D:lockinfo xmlns:D="DAV:">`,
+	} {
+		if _, _, err := readLockInfo(strings.NewReader(body)); err == nil {
+			t.Fatalf("%s: expected an error", body)
+		}
+	}
+}
+
+// lockGateway is a gateway holding a single resource.
+type lockGateway struct {
+	gateway.GatewayAPIClient
+	info *provider.ResourceInfo
+	lock *provider.Lock
+}
+
+func (g *lockGateway) Stat(ctx context.Context, req *provider.StatRequest, opts ...grpc.CallOption) (*provider.StatResponse, error) {
+	if g.info == nil {
+		return &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+	}
+	return &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Info: g.info}, nil
+}
+
+func (g *lockGateway) GetLock(ctx context.Context, req *provider.GetLockRequest, opts ...grpc.CallOption) (*provider.GetLockResponse, error) {
+	if g.lock == nil {
+		return &provider.GetLockResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+	}
+	return &provider.GetLockResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Lock: g.lock}, nil
+}
+
+func TestCheckIfHeader(t *testing.T) {
+	client := &lockGateway{
+		info: &provider.ResourceInfo{Etag: `"etag"`},
+		lock: &provider.Lock{LockId: "a", Type: provider.LockType_LOCK_TYPE_WRITE},
+	}
+
+	tests := []struct {
+		description string
+		header      string
+		status      int
+		lockID      string
+	}{
+		{description: "no If header", status: http.StatusOK},
+		{description: "lock token", header: "(<opaquelocktoken:a>)", status: http.StatusOK, lockID: "a"},
+		{description: "lock token and etag", header: `(<opaquelocktoken:a> ["etag"])`, status: http.StatusOK, lockID: "a"},
+		{description: "wrong lock token", header: "(<opaquelocktoken:b>)", status: http.StatusPreconditionFailed},
+		{description: "wrong etag", header: `(<opaquelocktoken:a> ["other"])`, status: http.StatusPreconditionFailed},
+		{description: "any list may match", header: "(<opaquelocktoken:b>) (<opaquelocktoken:a>)", status: http.StatusOK, lockID: "a"},
+		{description: "negation", header: "(Not <DAV:no-lock>)", status: http.StatusOK},
+		{description: "tagged with the request-URI", header: "<http://example.org/dav/files/a.txt> (<opaquelocktoken:a>)", status: http.StatusOK, lockID: "a"},
+		{description: "tagged with another resource", header: "</dav/files/b.txt> (<opaquelocktoken:a>)", status: http.StatusPreconditionFailed},
+		{description: "malformed", header: "<opaquelocktoken:a>", status: http.StatusBadRequest},
+	}
+
+	s := &svc{}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			r := httptest.NewRequest("DELETE", "/dav/files/a.txt", nil)
+			if tt.header != "" {
+				r.Header.Set(HeaderIf, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			ref := &provider.Reference{Path: "/a.txt"}
+			ids, ok := s.checkIfHeader(context.Background(), w, r, client, zerolog.Nop(), requestResource(r, ref))
+			if status := w.Result().StatusCode; status != tt.status {
+				t.Fatalf("got status %d, expected %d", status, tt.status)
+			}
+			if ok != (tt.status == http.StatusOK) {
+				t.Fatalf("unexpected result %v", ok)
+			}
+			if ok && ids[0] != tt.lockID {
+				t.Fatalf("got lock id %q, expected %q", ids[0], tt.lockID)
+			}
+		})
+	}
+}
+
+// locksGateway is a gateway holding locks on several resources, by path.
+type locksGateway struct {
+	gateway.GatewayAPIClient
+	locks map[string]*provider.Lock
+}
+
+func (g *locksGateway) Stat(ctx context.Context, req *provider.StatRequest, opts ...grpc.CallOption) (*provider.StatResponse, error) {
+	return &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Info: &provider.ResourceInfo{Path: req.Ref.Path, Etag: `"etag"`}}, nil
+}
+
+func (g *locksGateway) GetLock(ctx context.Context, req *provider.GetLockRequest, opts ...grpc.CallOption) (*provider.GetLockResponse, error) {
+	l, ok := g.locks[req.Ref.Path]
+	if !ok {
+		return &provider.GetLockResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+	}
+	return &provider.GetLockResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Lock: l}, nil
+}
+
+func TestCheckIfHeaderMove(t *testing.T) {
+	client := &locksGateway{locks: map[string]*provider.Lock{
+		"/a.txt":     {LockId: "a", Type: provider.LockType_LOCK_TYPE_WRITE},
+		"/dir/b.txt": {LockId: "b", Type: provider.LockType_LOCK_TYPE_WRITE},
+		"/dir":       {LockId: "dir", Type: provider.LockType_LOCK_TYPE_WRITE},
+	}}
+
+	tests := []struct {
+		description string
+		header      string
+		status      int
+		lockIDs     []string
+	}{
+		{
+			description: "source and destination tokens",
+			header:      "</dav/files/a.txt> (<opaquelocktoken:a>) </dav/files/dir/b.txt> (<opaquelocktoken:b>)",
+			status:      http.StatusOK,
+			lockIDs:     []string{"a", "b", "", ""},
+		},
+		{
+			description: "destination token only",
+			header:      "</dav/files/dir/b.txt> (<opaquelocktoken:b>)",
+			status:      http.StatusOK,
+			lockIDs:     []string{"", "b", "", ""},
+		},
+		{
+			description: "tagged with the destination parent",
+			header:      "</dav/files/dir> (<opaquelocktoken:dir>)",
+			status:      http.StatusOK,
+			lockIDs:     []string{"", "", "", "dir"},
+		},
+		{
+			description: "wrong destination token",
+			header:      "</dav/files/dir/b.txt> (<opaquelocktoken:a>)",
+			status:      http.StatusPreconditionFailed,
+		},
+	}
+
+	s := &svc{}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			r := httptest.NewRequest("MOVE", "/dav/files/a.txt", nil)
+			r.Header.Set(HeaderDestination, "http://example.org/dav/files/dir/b.txt")
+			r.Header.Set(HeaderIf, tt.header)
+			w := httptest.NewRecorder()
+
+			src := requestResource(r, &provider.Reference{Path: "/a.txt"})
+			dst := &ifResource{href: hrefPath(r.Header.Get(HeaderDestination)), ref: &provider.Reference{Path: "/dir/b.txt"}}
+			ids, ok := s.checkIfHeader(context.Background(), w, r, client, zerolog.Nop(), src, dst, parentResource(src), parentResource(dst))
+			if status := w.Result().StatusCode; status != tt.status {
+				t.Fatalf("got status %d, expected %d", status, tt.status)
+			}
+			if ok && !reflect.DeepEqual(ids, tt.lockIDs) {
+				t.Fatalf("got lock ids %q, expected %q", ids, tt.lockIDs)
+			}
+		})
+	}
+}
+
+func TestParentResource(t *testing.T) {
+	res := &ifResource{href: "/dav/files/dir/b.txt", ref: &provider.Reference{Path: "/dir/b.txt"}}
+	parent := parentResource(res)
+	if parent == nil || parent.href != "/dav/files/dir" || parent.ref.Path != "/dir" {
+		t.Fatalf("unexpected parent %+v", parent)
+	}
+	if parent := parentResource(&ifResource{href: "/dav/spaces/x", ref: &provider.Reference{ResourceId: &provider.ResourceId{OpaqueId: "x"}}}); parent != nil {
+		t.Fatalf("a resource addressed by id has no parent, got %+v", parent)
+	}
+}
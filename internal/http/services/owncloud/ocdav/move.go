@@ -27,6 +27,7 @@ import (
 
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/spaces"
@@ -134,6 +135,19 @@ func (s *svc) handleMove(ctx context.Context, w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// the destination is addressed by the Destination header, the lists may
+	// also refer to the parents, whose members change
+	resources := []*ifResource{requestResource(r, src), {href: hrefPath(r.Header.Get(HeaderDestination)), ref: dst}}
+	for _, res := range resources[:2] {
+		if parent := parentResource(res); parent != nil {
+			resources = append(resources, parent)
+		}
+	}
+	lockIDs, ok := s.checkIfHeader(ctx, w, r, client, log, resources...)
+	if !ok {
+		return
+	}
+
 	// check src exists
 	srcStatReq := &provider.StatRequest{Ref: src}
 	srcStatRes, err := client.Stat(ctx, srcStatReq)
@@ -180,7 +194,7 @@ func (s *svc) handleMove(ctx context.Context, w http.ResponseWriter, r *http.Req
 		}
 
 		// delete existing tree
-		delReq := &provider.DeleteRequest{Ref: dst}
+		delReq := &provider.DeleteRequest{Ref: dst, LockId: lockIDs[1]}
 		delRes, err := client.Delete(ctx, delReq)
 		if err != nil {
 			log.Error().Err(err).Msg("error sending grpc delete request")
@@ -224,7 +238,18 @@ func (s *svc) handleMove(ctx context.Context, w http.ResponseWriter, r *http.Req
 		// TODO what if intermediate is a file?
 	}
 
-	mReq := &provider.MoveRequest{Source: src, Destination: dst}
+	// the destination may be locked with a different lock than the source
+	mReq := &provider.MoveRequest{Source: src, Destination: dst, LockId: lockIDs[0]}
+	if lockIDs[1] != "" {
+		mReq.Opaque = &typespb.Opaque{
+			Map: map[string]*typespb.OpaqueEntry{
+				"destination_lockid": {
+					Decoder: "plain",
+					Value:   []byte(lockIDs[1]),
+				},
+			},
+		}
+	}
 	mRes, err := client.Move(ctx, mReq)
 	if err != nil {
 		log.Error().Err(err).Msg("error sending move grpc request")
@@ -44,6 +44,8 @@ import (
 	"github.com/cs3org/reva/pkg/storage/favorite"
 	"github.com/cs3org/reva/pkg/storage/favorite/registry"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/cs3org/reva/pkg/tags"
+	tagsregistry "github.com/cs3org/reva/pkg/tags/registry"
 	"github.com/cs3org/reva/pkg/utils/cfg"
 	"github.com/pkg/errors"
 )
@@ -123,6 +125,8 @@ type Config struct {
 	PublicURL                    string                            `mapstructure:"public_url"`
 	FavoriteStorageDriver        string                            `mapstructure:"favorite_storage_driver"`
 	FavoriteStorageDrivers       map[string]map[string]interface{} `mapstructure:"favorite_storage_drivers"`
+	TagsManager                  string                            `docs:"memory;The driver used to store the system tags. ocdav and ocgraph share the store when configured with the same driver, a persistent one is needed when they run in different processes." mapstructure:"tags_manager"`
+	TagsManagers                 map[string]map[string]interface{} `docs:"url:pkg/tags/sqlite/sqlite.go" mapstructure:"tags_managers"`
 	PublicLinkDownload           *ConfigPublicLinkDownload         `mapstructure:"publiclink_download"`
 	DisabledOpenInAppPaths       []string                          `mapstructure:"disabled_open_in_app_paths"`
 	Notifications                map[string]interface{}            `docs:"nil; settings for the notification helper" mapstructure:"notifications"`
@@ -137,6 +141,10 @@ func (c *Config) ApplyDefaults() {
 		c.FavoriteStorageDriver = "memory"
 	}
 
+	if c.TagsManager == "" {
+		c.TagsManager = "memory"
+	}
+
 	if c.OCMNamespace == "" {
 		c.OCMNamespace = "/ocm"
 	}
@@ -151,6 +159,7 @@ type svc struct {
 	webDavHandler        *WebDavHandler
 	davHandler           *DavHandler
 	favoritesManager     favorite.Manager
+	tagsManager          tags.Manager
 	myOfficeFilesManager myofficefiles.Manager
 	client               *httpclient.Client
 	notificationHelper   *notificationhelper.NotificationHelper
@@ -175,6 +184,11 @@ func New(ctx context.Context, m map[string]interface{}) (global.Service, error)
 		return nil, err
 	}
 
+	tm, err := tagsregistry.GetManager(ctx, c.TagsManager, c.TagsManagers[c.TagsManager])
+	if err != nil {
+		return nil, err
+	}
+
 	myOfficeFilesManager, err := myofficefiles.New(ctx, c.GatewaySvc, c.MyOfficeFilesAllowedProjects)
 	if err != nil {
 		return nil, err
@@ -191,6 +205,7 @@ func New(ctx context.Context, m map[string]interface{}) (global.Service, error)
 			httpclient.RoundTripper(tr),
 		),
 		favoritesManager:     fm,
+		tagsManager:          tm,
 		notificationHelper:   notificationhelper.New("ocdav", c.Notifications, log),
 		myOfficeFilesManager: myOfficeFilesManager,
 	}
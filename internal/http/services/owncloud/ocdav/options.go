@@ -39,7 +39,7 @@ func (s *svc) handleOptions(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add(HeaderAccessControlExposeHeaders, strings.Join([]string{HeaderTusResumable, HeaderTusVersion, HeaderTusExtension}, ","))
 		w.Header().Set(HeaderTusResumable, "1.0.0") // TODO(jfd): only for dirs?
 		w.Header().Set(HeaderTusVersion, "1.0.0")
-		w.Header().Set(HeaderTusExtension, "creation,creation-with-upload,checksum,expiration")
+		w.Header().Set(HeaderTusExtension, tusExtensions)
 		w.Header().Set(HeaderTusChecksumAlgorithm, "md5,sha1,crc32")
 	}
 	w.WriteHeader(http.StatusNoContent)
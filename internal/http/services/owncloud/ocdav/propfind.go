@@ -33,6 +33,7 @@ import (
 	"strings"
 	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
@@ -48,6 +49,7 @@ import (
 	"github.com/cs3org/reva/pkg/publicshare"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/tags"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/cs3org/reva/pkg/utils/resourceid"
 	"github.com/rs/zerolog"
@@ -59,6 +61,7 @@ const (
 	_nsOCS      = "http://open-collaboration-services.org/ns"
 
 	_propOcFavorite = "http://owncloud.org/ns/favorite"
+	_propOcTags     = "http://owncloud.org/ns/tags"
 
 	// RFC1123 time that mimics oc10. time.RFC1123 would end in "UTC", see https://github.com/golang/go/issues/13781
 	RFC1123 = "Mon, 02 Jan 2006 15:04:05 GMT"
@@ -84,12 +87,12 @@ func (s *svc) handlePathPropfind(w http.ResponseWriter, r *http.Request, ns stri
 
 	ref := &provider.Reference{Path: fn}
 
-	parentInfo, resourceInfos, ok := s.getResourceInfos(ctx, w, r, pf, ref, false, sublog)
+	parentInfo, resourceInfos, children, ok := s.getResourceInfos(ctx, w, r, pf, ref, false, sublog)
 	if !ok {
 		// getResourceInfos handles responses in case of an error so we can just return here.
 		return
 	}
-	s.propfindResponse(ctx, w, r, ns, pf, parentInfo, resourceInfos, sublog)
+	s.propfindResponse(ctx, w, r, ns, pf, parentInfo, resourceInfos, children, sublog)
 }
 
 func (s *svc) handleSpacesPropfind(w http.ResponseWriter, r *http.Request, spaceID string) {
@@ -117,7 +120,7 @@ func (s *svc) handleSpacesPropfind(w http.ResponseWriter, r *http.Request, space
 		return
 	}
 
-	parentInfo, resourceInfos, ok := s.getResourceInfos(ctx, w, r, pf, ref, true, sublog)
+	parentInfo, resourceInfos, children, ok := s.getResourceInfos(ctx, w, r, pf, ref, true, sublog)
 	if !ok {
 		// getResourceInfos handles responses in case of an error so we can just return here.
 		return
@@ -134,18 +137,19 @@ func (s *svc) handleSpacesPropfind(w http.ResponseWriter, r *http.Request, space
 	for i := range resourceInfos {
 		resourceInfos[i].Path = path.Join("/", spaceID, resourceInfos[i].Path)
 	}
+	if children != nil {
+		children.fix = func(info *provider.ResourceInfo) {
+			info.Path = path.Join("/", spaceID, info.Path)
+		}
+	}
 
-	s.propfindResponse(ctx, w, r, "", pf, parentInfo, resourceInfos, sublog)
+	s.propfindResponse(ctx, w, r, "", pf, parentInfo, resourceInfos, children, sublog)
 }
 
-func (s *svc) propfindResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, namespace string, pf propfindXML, parentInfo *provider.ResourceInfo, resourceInfos []*provider.ResourceInfo, log zerolog.Logger) {
-	linkFilters := make([]*link.ListPublicSharesRequest_Filter, 0, len(resourceInfos))
-	shareFilters := make([]*collaboration.Filter, 0, len(resourceInfos))
-	for i := range resourceInfos {
-		linkFilters = append(linkFilters, publicshare.ResourceIDFilter(resourceInfos[i].Id))
-		shareFilters = append(shareFilters, share.ResourceIDFilter(resourceInfos[i].Id))
-	}
-
+// propfindResponse writes the multistatus for resourceInfos followed by the one for
+// the children of the collection, if any. The responses are written in batches, so
+// that large collections do not need to be held in memory.
+func (s *svc) propfindResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, namespace string, pf propfindXML, parentInfo *provider.ResourceInfo, resourceInfos []*provider.ResourceInfo, children *childrenStream, log zerolog.Logger) {
 	client, err := s.getClient()
 	if err != nil {
 		log.Error().Err(err).Msg("error getting grpc client")
@@ -153,11 +157,71 @@ func (s *svc) propfindResponse(ctx context.Context, w http.ResponseWriter, r *ht
 		return
 	}
 
-	var linkshares map[string]struct{}
+	infos := &propfindInfos{infos: resourceInfos, children: children}
+	written := false
+	for {
+		batch, err := infos.nextBatch(propfindBatchSize)
+		if err != nil {
+			log.Error().Err(err).Msg("error listing collection")
+			if !written {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			// leave the multistatus unterminated: a truncated listing
+			// would make sync clients delete the missing entries
+			return
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		usershares, linkshares := s.listSharesOf(ctx, client, parentInfo.Path, batch, log)
+		responsesXML, err := s.multistatusResponses(ctx, &pf, batch, namespace, usershares, linkshares)
+		if err != nil {
+			log.Error().Err(err).Msg("error formatting propfind")
+			if !written {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			return
+		}
+
+		if !written {
+			setPropfindHeaders(w, parentInfo)
+			w.WriteHeader(http.StatusMultiStatus)
+			if _, err := w.Write([]byte(multistatusStart)); err != nil {
+				log.Err(err).Msg("error writing response")
+				return
+			}
+			written = true
+		}
+		if _, err := w.Write(responsesXML); err != nil {
+			log.Err(err).Msg("error writing response")
+			return
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+
+	if _, err := w.Write([]byte(multistatusEnd)); err != nil {
+		log.Err(err).Msg("error writing response")
+	}
+}
+
+// listSharesOf returns the ids of the resources in infos that are shared with users or by link.
+func (s *svc) listSharesOf(ctx context.Context, client gateway.GatewayAPIClient, parentPath string, infos []*provider.ResourceInfo, log zerolog.Logger) (usershares, linkshares map[string]struct{}) {
+	linkFilters := make([]*link.ListPublicSharesRequest_Filter, 0, len(infos))
+	shareFilters := make([]*collaboration.Filter, 0, len(infos))
+	for i := range infos {
+		linkFilters = append(linkFilters, publicshare.ResourceIDFilter(infos[i].Id))
+		shareFilters = append(shareFilters, share.ResourceIDFilter(infos[i].Id))
+	}
+
 	listResp, err := client.ListPublicShares(ctx, &link.ListPublicSharesRequest{
 		Opaque: &types.Opaque{
 			Map: map[string]*types.OpaqueEntry{
-				appctx.ResoucePathCtx: {Decoder: "plain", Value: []byte(parentInfo.Path)},
+				appctx.ResoucePathCtx: {Decoder: "plain", Value: []byte(parentPath)},
 			},
 		},
 		Filters: linkFilters,
@@ -171,12 +235,11 @@ func (s *svc) propfindResponse(ctx context.Context, w http.ResponseWriter, r *ht
 		log.Error().Err(err).Msg("propfindResponse: couldn't list public shares")
 	}
 
-	var usershares map[string]struct{}
 	listSharesResp, err := client.ListShares(ctx, &collaboration.ListSharesRequest{
 		Filters: shareFilters,
 		Opaque: &types.Opaque{
 			Map: map[string]*types.OpaqueEntry{
-				appctx.ResoucePathCtx: {Decoder: "plain", Value: []byte(parentInfo.Path)},
+				appctx.ResoucePathCtx: {Decoder: "plain", Value: []byte(parentPath)},
 			},
 		},
 	})
@@ -188,36 +251,92 @@ func (s *svc) propfindResponse(ctx context.Context, w http.ResponseWriter, r *ht
 	} else {
 		log.Error().Err(err).Msg("propfindResponse: couldn't list user shares")
 	}
+	return usershares, linkshares
+}
 
-	propRes, err := s.multistatusResponse(ctx, &pf, resourceInfos, namespace, usershares, linkshares)
-	if err != nil {
-		log.Error().Err(err).Msg("error formatting propfind")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+func setPropfindHeaders(w http.ResponseWriter, parentInfo *provider.ResourceInfo) {
 	w.Header().Set(HeaderDav, "1, 3, extended-mkcol")
 	w.Header().Set(HeaderContentType, "application/xml; charset=utf-8")
 
-	var disableTus bool
+	var disableTus, concatenation bool
 	// let clients know this collection supports tus.io POST requests to start uploads
 	if parentInfo.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
 		if parentInfo.Opaque != nil {
 			_, disableTus = parentInfo.Opaque.Map["disable_tus"]
+			_, concatenation = parentInfo.Opaque.Map["tus_concatenation"]
 		}
 		if !disableTus {
+			extensions := tusExtensions
+			if concatenation {
+				extensions += ",concatenation"
+			}
 			w.Header().Add(HeaderAccessControlExposeHeaders, strings.Join([]string{HeaderTusResumable, HeaderTusVersion, HeaderTusExtension}, ", "))
 			w.Header().Set(HeaderTusResumable, "1.0.0")
 			w.Header().Set(HeaderTusVersion, "1.0.0")
-			w.Header().Set(HeaderTusExtension, "creation,creation-with-upload,checksum,expiration")
+			w.Header().Set(HeaderTusExtension, extensions)
 		}
 	}
-	w.WriteHeader(http.StatusMultiStatus)
-	if _, err := w.Write([]byte(propRes)); err != nil {
-		log.Err(err).Msg("error writing response")
+}
+
+// childrenStream returns the children of a collection as they are received from the gateway.
+type childrenStream struct {
+	stream gateway.GatewayAPI_ListContainerStreamClient
+	// first is received upfront, to report errors before the response is started
+	first *provider.ResourceInfo
+	fix   func(*provider.ResourceInfo)
+}
+
+func (c *childrenStream) next() (*provider.ResourceInfo, error) {
+	info := c.first
+	if info != nil {
+		c.first = nil
+	} else {
+		res, err := c.stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return nil, errors.Errorf("ocdav: error listing collection: %s %s", res.Status.Code, res.Status.Message)
+		}
+		info = res.Info
+	}
+	if c.fix != nil {
+		c.fix(info)
+	}
+	return info, nil
+}
+
+// propfindInfos returns the resource infos of a propfind response in batches.
+type propfindInfos struct {
+	infos    []*provider.ResourceInfo
+	children *childrenStream
+}
+
+func (p *propfindInfos) nextBatch(size int) ([]*provider.ResourceInfo, error) {
+	if len(p.infos) > 0 {
+		batch := p.infos
+		p.infos = nil
+		return batch, nil
+	}
+
+	var batch []*provider.ResourceInfo
+	for p.children != nil && len(batch) < size {
+		info, err := p.children.next()
+		if err == io.EOF {
+			p.children = nil
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		batch = append(batch, info)
 	}
+	return batch, nil
 }
 
-func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *http.Request, pf propfindXML, ref *provider.Reference, spacesPropfind bool, log zerolog.Logger) (*provider.ResourceInfo, []*provider.ResourceInfo, bool) {
+// getResourceInfos returns the info of the resource targeted by the propfind, the infos
+// to be reported and, for collections listed with depth 1, the stream of their children.
+func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *http.Request, pf propfindXML, ref *provider.Reference, spacesPropfind bool, log zerolog.Logger) (*provider.ResourceInfo, []*provider.ResourceInfo, *childrenStream, bool) {
 	depth := r.Header.Get(HeaderDepth)
 	if depth == "" {
 		depth = "1"
@@ -232,14 +351,14 @@ func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *ht
 			message: m,
 		})
 		HandleWebdavError(&log, w, b, err)
-		return nil, nil, false
+		return nil, nil, nil, false
 	}
 
 	client, err := s.getClient()
 	if err != nil {
 		log.Error().Err(err).Msg("error getting grpc client")
 		w.WriteHeader(http.StatusInternalServerError)
-		return nil, nil, false
+		return nil, nil, nil, false
 	}
 
 	var metadataKeys []string
@@ -254,7 +373,7 @@ func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *ht
 	} else {
 		for i := range pf.Prop {
 			if requiresExplicitFetching(&pf.Prop[i]) {
-				metadataKeys = append(metadataKeys, metadataKeyOf(&pf.Prop[i]))
+				metadataKeys = append(metadataKeys, propMetadataKey(ctx, &pf.Prop[i]))
 			}
 		}
 	}
@@ -267,7 +386,7 @@ func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *ht
 	if err != nil {
 		log.Error().Err(err).Interface("req", req).Msg("error sending a stat request to the gateway")
 		w.WriteHeader(http.StatusInternalServerError)
-		return nil, nil, false
+		return nil, nil, nil, false
 	} else if res.Status.Code != rpc.Code_CODE_OK {
 		if res.Status.Code == rpc.Code_CODE_NOT_FOUND {
 			w.WriteHeader(http.StatusNotFound)
@@ -277,10 +396,10 @@ func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *ht
 				message: m,
 			})
 			HandleWebdavError(&log, w, b, err)
-			return nil, nil, false
+			return nil, nil, nil, false
 		}
 		HandleErrorStatus(&log, w, res.Status)
-		return nil, nil, false
+		return nil, nil, nil, false
 	}
 
 	if spacesPropfind {
@@ -294,7 +413,7 @@ func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *ht
 	case depth == "0":
 		// https://www.ietf.org/rfc/rfc2518.txt:
 		// the method is to be applied only to the resource
-		return parentInfo, resourceInfos, true
+		return parentInfo, resourceInfos, nil, true
 	case !spacesPropfind && parentInfo.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER:
 		// The propfind is requested for a file that exists
 		// In this case, we can stat the parent directory and return both
@@ -307,7 +426,7 @@ func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *ht
 		if err != nil {
 			log.Error().Err(err).Interface("req", req).Msg("error sending a grpc stat request")
 			w.WriteHeader(http.StatusInternalServerError)
-			return nil, nil, false
+			return nil, nil, nil, false
 		} else if parentRes.Status.Code != rpc.Code_CODE_OK {
 			if parentRes.Status.Code == rpc.Code_CODE_NOT_FOUND {
 				w.WriteHeader(http.StatusNotFound)
@@ -317,30 +436,41 @@ func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *ht
 					message: m,
 				})
 				HandleWebdavError(&log, w, b, err)
-				return nil, nil, false
+				return nil, nil, nil, false
 			}
 			HandleErrorStatus(&log, w, parentRes.Status)
-			return nil, nil, false
+			return nil, nil, nil, false
 		}
 		parentInfo = parentRes.Info
 
 	case parentInfo.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER && depth == "1":
-		req := &provider.ListContainerRequest{
+		req := &provider.ListContainerStreamRequest{
 			Ref:                   ref,
 			ArbitraryMetadataKeys: metadataKeys,
 		}
-		res, err := client.ListContainer(ctx, req)
+		stream, err := client.ListContainerStream(ctx, req)
 		if err != nil {
-			log.Error().Err(err).Msg("error sending list container grpc request")
+			log.Error().Err(err).Msg("error sending list container stream grpc request")
 			w.WriteHeader(http.StatusInternalServerError)
-			return nil, nil, false
+			return nil, nil, nil, false
 		}
 
+		// errors preventing the listing are reported in the first message
+		res, err := stream.Recv()
+		if err == io.EOF {
+			// empty collection
+			return parentInfo, resourceInfos, nil, true
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("error receiving from list container stream")
+			w.WriteHeader(http.StatusInternalServerError)
+			return nil, nil, nil, false
+		}
 		if res.Status.Code != rpc.Code_CODE_OK {
 			HandleErrorStatus(&log, w, res.Status)
-			return nil, nil, false
+			return nil, nil, nil, false
 		}
-		resourceInfos = append(resourceInfos, res.Infos...)
+		return parentInfo, resourceInfos, &childrenStream{stream: stream, first: res.Info}, true
 
 	case depth == "infinity":
 		// FIXME: doesn't work cross-storage as the results will have the wrong paths!
@@ -367,11 +497,11 @@ func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *ht
 			if err != nil {
 				log.Error().Err(err).Str("path", path).Msg("error sending list container grpc request")
 				w.WriteHeader(http.StatusInternalServerError)
-				return nil, nil, false
+				return nil, nil, nil, false
 			}
 			if res.Status.Code != rpc.Code_CODE_OK {
 				HandleErrorStatus(&log, w, res.Status)
-				return nil, nil, false
+				return nil, nil, nil, false
 			}
 
 			stack = stack[:len(stack)-1]
@@ -397,7 +527,7 @@ func (s *svc) getResourceInfos(ctx context.Context, w http.ResponseWriter, r *ht
 		}
 	}
 
-	return parentInfo, resourceInfos, true
+	return parentInfo, resourceInfos, nil, true
 }
 
 func requiresExplicitFetching(n *xml.Name) bool {
@@ -413,7 +543,7 @@ func requiresExplicitFetching(n *xml.Name) bool {
 		}
 	case _nsOwncloud:
 		switch n.Local {
-		case "favorite", "share-types", "checksums", "size":
+		case "favorite", "share-types", "checksums", "size", "tags", "systemtags":
 			return true
 		default:
 			return false
@@ -455,24 +585,34 @@ func readPropfind(r io.Reader) (pf propfindXML, status int, err error) {
 	return pf, 0, nil
 }
 
+const (
+	multistatusStart = `<?xml version="1.0" encoding="utf-8"?><d:multistatus xmlns:d="DAV:" ` +
+		`xmlns:s="http://sabredav.org/ns" xmlns:oc="http://owncloud.org/ns">`
+	multistatusEnd = `</d:multistatus>`
+
+	// number of entries whose shares are looked up and responses written at once.
+	propfindBatchSize = 1000
+)
+
 func (s *svc) multistatusResponse(ctx context.Context, pf *propfindXML, mds []*provider.ResourceInfo, ns string, usershares, linkshares map[string]struct{}) (string, error) {
+	responsesXML, err := s.multistatusResponses(ctx, pf, mds, ns, usershares, linkshares)
+	if err != nil {
+		return "", err
+	}
+	return multistatusStart + string(responsesXML) + multistatusEnd, nil
+}
+
+// multistatusResponses returns the xml responses for mds, without the enclosing multistatus element.
+func (s *svc) multistatusResponses(ctx context.Context, pf *propfindXML, mds []*provider.ResourceInfo, ns string, usershares, linkshares map[string]struct{}) ([]byte, error) {
 	responses := make([]*responseXML, 0, len(mds))
 	for i := range mds {
 		res, err := s.mdToPropResponse(ctx, pf, mds[i], ns, usershares, linkshares)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		responses = append(responses, res)
 	}
-	responsesXML, err := xml.Marshal(&responses)
-	if err != nil {
-		return "", err
-	}
-
-	msg := `<?xml version="1.0" encoding="utf-8"?><d:multistatus xmlns:d="DAV:" `
-	msg += `xmlns:s="http://sabredav.org/ns" xmlns:oc="http://owncloud.org/ns">`
-	msg += string(responsesXML) + `</d:multistatus>`
-	return msg, nil
+	return xml.Marshal(&responses)
 }
 
 func (s *svc) xmlEscaped(val string) []byte {
@@ -717,6 +857,10 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 			propstatOK.Prop = append(propstatOK.Prop, s.newPropRaw("oc:checksums", checksums.String()))
 		}
 
+		if md.Type == provider.ResourceType_RESOURCE_TYPE_SYMLINK {
+			propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:symlink-target", md.Target))
+		}
+
 		// ls do not report any properties as missing by default
 		if ls == nil {
 			// favorites from arbitrary metadata
@@ -729,6 +873,14 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 			} else {
 				propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:favorite", "0"))
 			}
+			// tags are only reported when set
+			if u, ok := appctx.ContextGetUser(ctx); ok && len(tags.Get(md, tags.UserTagsKey(u.Id))) > 0 {
+				p, _ := s.userTagsProp(ctx, md)
+				propstatOK.Prop = append(propstatOK.Prop, p)
+			}
+			if len(tags.Get(md, tags.SystemTagsKey)) > 0 {
+				propstatOK.Prop = append(propstatOK.Prop, s.systemTagsProp(md))
+			}
 		}
 		// TODO return other properties ... but how do we put them in a namespace?
 	} else {
@@ -851,6 +1003,19 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 						// link share root collection has no favorite
 						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:favorite", ""))
 					}
+				case "tags":
+					// tags are private to the user, and not exposed in link shares
+					if p, ok := s.userTagsProp(ctx, md); ok && ls == nil {
+						propstatOK.Prop = append(propstatOK.Prop, p)
+					} else {
+						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:tags", ""))
+					}
+				case "systemtags":
+					if ls == nil {
+						propstatOK.Prop = append(propstatOK.Prop, s.systemTagsProp(md))
+					} else {
+						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:systemtags", ""))
+					}
 				case "checksums": // desktop ... not really ... the desktop sends the OC-Checksum header
 
 					// stay bug compatible with oc10, see https://github.com/owncloud/core/pull/38304#issuecomment-762185241
@@ -961,6 +1126,12 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 							propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:signature-auth", ""))
 						}
 					}
+				case "symlink-target":
+					if md.Type == provider.ResourceType_RESOURCE_TYPE_SYMLINK {
+						propstatOK.Prop = append(propstatOK.Prop, s.newProp("oc:symlink-target", md.Target))
+					} else {
+						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("oc:symlink-target", ""))
+					}
 				case "privatelink": // phoenix only
 					// <oc:privatelink>https://phoenix.owncloud.com/f/9</oc:privatelink>
 					fallthrough
@@ -1034,6 +1205,14 @@ func (s *svc) mdToPropResponse(ctx context.Context, pf *propfindXML, md *provide
 					} else {
 						propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("d:quota-available-bytes", ""))
 					}
+				case "lockdiscovery": // RFC 4918
+					if l := md.GetLock(); l != nil && !lockExpired(l) {
+						propstatOK.Prop = append(propstatOK.Prop, s.newPropRaw("d:lockdiscovery", s.activeLockXML(l, encodePath(ref), "")))
+					} else {
+						propstatOK.Prop = append(propstatOK.Prop, s.newProp("d:lockdiscovery", ""))
+					}
+				case "supportedlock": // RFC 4918
+					propstatOK.Prop = append(propstatOK.Prop, s.newPropRaw("d:supportedlock", supportedLockXML))
 				default:
 					propstatNotFound.Prop = append(propstatNotFound.Prop, s.newProp("d:"+pf.Prop[i].Local, ""))
 				}
@@ -169,12 +169,19 @@ func (s *svc) handleProppatch(ctx context.Context, w http.ResponseWriter, r *htt
 		return nil, nil, false
 	}
 
+	lockIDs, ok := s.checkIfHeader(ctx, w, r, c, log, requestResource(r, ref))
+	if !ok {
+		return nil, nil, false
+	}
+
 	rreq := &provider.UnsetArbitraryMetadataRequest{
 		Ref:                   ref,
 		ArbitraryMetadataKeys: []string{""},
+		LockId:                lockIDs[0],
 	}
 	sreq := &provider.SetArbitraryMetadataRequest{
-		Ref: ref,
+		Ref:    ref,
+		LockId: lockIDs[0],
 		ArbitraryMetadata: &provider.ArbitraryMetadata{
 			Metadata: map[string]string{},
 		},
@@ -193,6 +200,18 @@ func (s *svc) handleProppatch(ctx context.Context, w http.ResponseWriter, r *htt
 			key := fmt.Sprintf("%s/%s", patches[i].Props[j].XMLName.Space, patches[i].Props[j].XMLName.Local)
 			value := string(patches[i].Props[j].InnerXML)
 			remove := patches[i].Remove
+			// the tags of the user are stored under a per user key
+			if key == _propOcTags {
+				if !s.proppatchTags(ctx, w, c, ref, patches[i].Props[j].InnerXML, remove, log) {
+					return nil, nil, false
+				}
+				if remove {
+					removedProps = append(removedProps, propNameXML)
+				} else {
+					acceptedProps = append(acceptedProps, propNameXML)
+				}
+				continue
+			}
 			// boolean flags may be "set" to false as well
 			if s.isBooleanProperty(key) {
 				// Make boolean properties either "0" or "1"
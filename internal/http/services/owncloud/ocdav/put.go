@@ -151,6 +151,11 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	lockIDs, ok := s.checkIfHeader(ctx, w, r, client, log, requestResource(r, ref))
+	if !ok {
+		return
+	}
+
 	info := sRes.Info
 	if info != nil {
 		if info.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
@@ -219,6 +224,7 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 	uReq := &provider.InitiateFileUploadRequest{
 		Ref:    ref,
 		Opaque: &typespb.Opaque{Map: opaqueMap},
+		LockId: lockIDs[0],
 	}
 
 	if userInCtxHasUploaderRole(ctx) {
@@ -271,8 +277,8 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		return
 	}
 	httpReq.Header.Set(datagateway.TokenTransportHeader, token)
-	if lockid := r.Header.Get(HeaderLockID); lockid != "" {
-		httpReq.Header.Set(HeaderLockID, lockid)
+	if lockIDs[0] != "" {
+		httpReq.Header.Set(HeaderLockID, lockIDs[0])
 	}
 	if lockholder := r.Header.Get(HeaderLockHolder); lockholder != "" {
 		httpReq.Header.Set(HeaderLockHolder, lockholder)
@@ -312,7 +318,7 @@ func (s *svc) handlePut(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	ok, err := chunking.IsChunked(ref.Path)
+	ok, err = chunking.IsChunked(ref.Path)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
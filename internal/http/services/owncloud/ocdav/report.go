@@ -19,15 +19,21 @@
 package ocdav
 
 import (
+	"context"
 	"encoding/xml"
 	"io"
 	"net/http"
+	"path"
 	"strings"
 
 	rpcv1beta1 "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/myofficefiles"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/tags"
+	"github.com/cs3org/reva/pkg/utils"
 )
 
 const (
@@ -47,7 +53,7 @@ func (s *svc) handleReport(w http.ResponseWriter, r *http.Request, ns string) {
 		return
 	}
 	if rep.SearchFiles != nil {
-		s.doSearchFiles(w, r, rep.SearchFiles)
+		s.doSearchFiles(w, r, rep.SearchFiles, ns)
 		return
 	}
 
@@ -61,16 +67,22 @@ func (s *svc) handleReport(w http.ResponseWriter, r *http.Request, ns string) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
-func (s *svc) doSearchFiles(w http.ResponseWriter, r *http.Request, sf *reportSearchFiles) {
+func (s *svc) doSearchFiles(w http.ResponseWriter, r *http.Request, sf *reportSearchFiles, ns string) {
 	ctx := r.Context()
 	log := appctx.GetLogger(ctx)
-	_, err := s.getClient()
-	if err != nil {
-		log.Error().Err(err).Msg("error getting grpc client")
-		w.WriteHeader(http.StatusInternalServerError)
+
+	if sf.Search.Pattern == "" || sf.Search.Limit < 0 || sf.Search.Offset < 0 {
+		log.Debug().Interface("search", sf.Search).Msg("invalid search")
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	w.WriteHeader(http.StatusNotImplemented)
+
+	q := search.Query{
+		Pattern: sf.Search.Pattern,
+		Limit:   sf.Search.Limit,
+		Offset:  sf.Search.Offset,
+	}
+	s.writeSearchResults(w, r, &propfindXML{Prop: sf.Prop}, path.Join(ns, r.URL.Path), ns, q)
 }
 
 func (s *svc) doFilterFiles(w http.ResponseWriter, r *http.Request, ff *reportFilterFiles, namespace string) {
@@ -86,19 +98,24 @@ func (s *svc) doFilterFiles(w http.ResponseWriter, r *http.Request, ff *reportFi
 		return
 	}
 
-	if ff.Rules.Favorite {
-		// List the users favorite resources.
-		currentUser := appctx.ContextMustGetUser(ctx)
-		favorites, err := s.favoritesManager.ListFavorites(ctx, currentUser.Id)
+	if ff.Rules.Favorite || len(ff.Rules.SystemTags) > 0 {
+		ids, names, err := s.filterFilesResources(ctx, &ff.Rules)
 		if err != nil {
-			log.Error().Err(err).Msg("error getting favorites")
+			if _, ok := err.(errtypes.IsNotFound); ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			log.Error().Err(err).Msg("error filtering resources")
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		resourceInfos = make([]*provider.ResourceInfo, 0, len(favorites))
-		for i := range favorites {
-			statRes, err := client.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: favorites[i]}})
+		resourceInfos = make([]*provider.ResourceInfo, 0, len(ids))
+		for i := range ids {
+			statRes, err := client.Stat(ctx, &provider.StatRequest{
+				Ref:                   &provider.Reference{ResourceId: ids[i]},
+				ArbitraryMetadataKeys: []string{tags.SystemTagsKey},
+			})
 			if err != nil {
 				log.Error().Err(err).Msg("error getting resource info")
 				continue
@@ -108,6 +125,11 @@ func (s *svc) doFilterFiles(w http.ResponseWriter, r *http.Request, ff *reportFi
 				continue
 			}
 
+			// the tags manager may lag behind the metadata of the resource
+			if len(tags.Subtract(names, tags.Get(statRes.Info, tags.SystemTagsKey))) > 0 {
+				continue
+			}
+
 			// If global URLs are not supported, return only the file path
 			if s.c.WebdavNamespace != "" {
 				// The paths we receive have the format /user/<username>/<filepath>
@@ -155,7 +177,6 @@ func (s *svc) doFilterFiles(w http.ResponseWriter, r *http.Request, ff *reportFi
 
 type report struct {
 	SearchFiles *reportSearchFiles
-	// FilterFiles TODO add this for tag based search
 	FilterFiles *reportFilterFiles `xml:"filter-files"`
 }
 type reportSearchFiles struct {
@@ -165,7 +186,7 @@ type reportSearchFiles struct {
 	Search  reportSearchFilesSearch `xml:"search"`
 }
 type reportSearchFilesSearch struct {
-	Pattern string `xml:"search"`
+	Pattern string `xml:"pattern"`
 	Limit   int    `xml:"limit"`
 	Offset  int    `xml:"offset"`
 }
@@ -179,11 +200,55 @@ type reportFilterFiles struct {
 
 type reportFilterFilesRules struct {
 	Favorite      bool     `xml:"favorite"`
-	SystemTag     int      `xml:"systemtag"`
+	SystemTags    []string `xml:"systemtag"`
 	MyOfficeFiles string   `xml:"my-office-files"`
 	Projects      []string `xml:"projects"`
 }
 
+// filterFilesResources returns the resources matching both the favorite and
+// the system tags rules, along with the names of the required system tags.
+func (s *svc) filterFilesResources(ctx context.Context, rules *reportFilterFilesRules) ([]*provider.ResourceId, []string, error) {
+	var ids []*provider.ResourceId
+	if rules.Favorite {
+		currentUser := appctx.ContextMustGetUser(ctx)
+		favorites, err := s.favoritesManager.ListFavorites(ctx, currentUser.Id)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids = favorites
+	}
+
+	names := make([]string, 0, len(rules.SystemTags))
+	for i, id := range rules.SystemTags {
+		t, err := s.tagsManager.GetTag(ctx, strings.TrimSpace(id))
+		if err != nil {
+			return nil, nil, err
+		}
+		tagged, err := s.tagsManager.ListResources(ctx, t.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		names = append(names, t.Name)
+
+		if i == 0 && !rules.Favorite {
+			ids = tagged
+			continue
+		}
+		// the rules are and-ed
+		matching := []*provider.ResourceId{}
+		for _, id := range ids {
+			for _, tid := range tagged {
+				if utils.ResourceIDEqual(id, tid) {
+					matching = append(matching, id)
+					break
+				}
+			}
+		}
+		ids = matching
+	}
+	return ids, names, nil
+}
+
 func readReport(r io.Reader) (rep *report, status int, err error) {
 	decoder := xml.NewDecoder(r)
 	rep = &report{}
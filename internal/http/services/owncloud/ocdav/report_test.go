@@ -61,3 +61,24 @@ func TestUnmarshallReportFilterFiles(t *testing.T) {
 		t.Error("Failed to correctly unmarshal filter-rules. Favorite is expected to be true.")
 	}
 }
+
+func TestUnmarshallReportFilterFilesSystemTags(t *testing.T) {
+	ffXML := `<oc:filter-files  xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+    <d:prop>
+        <oc:systemtags />
+    </d:prop>
+    <oc:filter-rules>
+        <oc:systemtag>1</oc:systemtag>
+        <oc:systemtag>3</oc:systemtag>
+    </oc:filter-rules>
+</oc:filter-files>`
+
+	report, status, err := readReport(strings.NewReader(ffXML))
+	if status != 0 || err != nil {
+		t.Fatal("Failed to unmarshal filter-files xml")
+	}
+
+	if tags := report.FilterFiles.Rules.SystemTags; len(tags) != 2 || tags[0] != "1" || tags[1] != "3" {
+		t.Errorf("Failed to correctly unmarshal filter-rules. Got system tags %v", tags)
+	}
+}
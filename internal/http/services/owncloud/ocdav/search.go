@@ -0,0 +1,314 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	sppb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/utils/resourceid"
+	"github.com/pkg/errors"
+)
+
+var errUnsupportedSearch = errors.New("unsupported search")
+
+// searchRequestXML is the body of a SEARCH request,
+// see https://www.rfc-editor.org/rfc/rfc5323.
+// Only the basic searches matching the display name
+// of the resources with a DAV:like operator are supported.
+type searchRequestXML struct {
+	XMLName     xml.Name        `xml:"DAV: searchrequest"`
+	BasicSearch *basicSearchXML `xml:"DAV: basicsearch"`
+}
+
+type basicSearchXML struct {
+	Select propfindProps  `xml:"DAV: select>prop"`
+	Scopes []searchScope  `xml:"DAV: from>scope"`
+	Where  searchWhereXML `xml:"DAV: where"`
+	Limit  int            `xml:"DAV: limit>nresults"`
+}
+
+type searchScope struct {
+	Href  string `xml:"DAV: href"`
+	Depth string `xml:"DAV: depth"`
+}
+
+type searchWhereXML struct {
+	Like  *searchLikeXML `xml:"DAV: like"`
+	Other []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+type searchLikeXML struct {
+	Prop    propfindProps `xml:"DAV: prop"`
+	Literal string        `xml:"DAV: literal"`
+}
+
+func readSearchRequest(r io.Reader) (*basicSearchXML, int, error) {
+	var sr searchRequestXML
+	if err := xml.NewDecoder(r).Decode(&sr); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if sr.BasicSearch == nil {
+		return nil, http.StatusNotImplemented, errors.Wrap(errUnsupportedSearch, "only basicsearch is supported")
+	}
+	bs := sr.BasicSearch
+	if len(bs.Scopes) != 1 {
+		return nil, http.StatusBadRequest, errors.New("exactly one scope is expected")
+	}
+	if d := bs.Scopes[0].Depth; d != "" && !strings.EqualFold(d, "infinity") {
+		return nil, http.StatusNotImplemented, errors.Wrap(errUnsupportedSearch, "only searches with infinite depth are supported")
+	}
+	if bs.Limit < 0 {
+		return nil, http.StatusBadRequest, errors.New("invalid limit")
+	}
+	return bs, 0, nil
+}
+
+// pattern returns the pattern to search for the names of the resources.
+func (w *searchWhereXML) pattern() (string, error) {
+	if w.Like == nil || len(w.Other) > 0 {
+		return "", errors.Wrap(errUnsupportedSearch, "only the like operator is supported")
+	}
+	if len(w.Like.Prop) != 1 || w.Like.Prop[0] != (xml.Name{Space: "DAV:", Local: "displayname"}) {
+		return "", errors.Wrap(errUnsupportedSearch, "only the displayname can be searched")
+	}
+	// the resources whose name contains the pattern are returned,
+	// so only the leading and trailing wildcards are accepted
+	p := strings.TrimSuffix(strings.TrimPrefix(w.Like.Literal, "%"), "%")
+	if p == "" || strings.Contains(p, "%") {
+		return "", errors.Wrap(errUnsupportedSearch, "unsupported literal "+w.Like.Literal)
+	}
+	return p, nil
+}
+
+// scopePath returns the path, relative to the base URI, of the collection to search.
+func scopePath(ctx context.Context, r *http.Request, href string) (string, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	if !path.IsAbs(u.Path) {
+		return path.Join("/", r.URL.Path, u.Path), nil
+	}
+	baseURI := ctx.Value(ctxKeyBaseURI).(string)
+	if u.Path != baseURI && !strings.HasPrefix(u.Path, strings.TrimSuffix(baseURI, "/")+"/") {
+		return "", errors.New("scope " + href + " is not below " + baseURI)
+	}
+	return path.Join("/", strings.TrimPrefix(u.Path, baseURI)), nil
+}
+
+func (s *svc) handlePathSearch(w http.ResponseWriter, r *http.Request, ns string) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	bs, status, err := readSearchRequest(r.Body)
+	if err != nil {
+		log.Debug().Err(err).Msg("error reading search request")
+		w.WriteHeader(status)
+		return
+	}
+	pattern, err := bs.Where.pattern()
+	if err != nil {
+		log.Debug().Err(err).Msg("error reading search request")
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	scope, err := scopePath(ctx, r, bs.Scopes[0].Href)
+	if err != nil {
+		log.Debug().Err(err).Msg("invalid search scope")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.writeSearchResults(w, r, &propfindXML{Prop: bs.Select}, path.Join(ns, scope), ns, search.Query{Pattern: pattern, Limit: bs.Limit})
+}
+
+// writeSearchResults writes the multistatus listing the resources
+// below fn matching q.
+func (s *svc) writeSearchResults(w http.ResponseWriter, r *http.Request, pf *propfindXML, fn, ns string, q search.Query) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	client, err := s.getClient()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	searcher, err := pool.GetSearchClient(pool.Endpoint(s.c.GatewaySvc))
+	if err != nil {
+		log.Error().Err(err).Msg("error getting grpc search client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	infos, st, err := s.searchResources(ctx, client, searcher, fn, ns, q)
+	if err != nil {
+		log.Error().Err(err).Str("path", fn).Msg("error searching resources")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if st.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(log, w, st)
+		return
+	}
+
+	if len(pf.Prop) == 0 {
+		pf = &propfindXML{Allprop: new(struct{})}
+	}
+	responsesXML, err := s.multistatusResponse(ctx, pf, infos, ns, nil, nil)
+	if err != nil {
+		log.Error().Err(err).Msg("error formatting propfind")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(HeaderDav, "1, 3, extended-mkcol")
+	w.Header().Set(HeaderContentType, "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := w.Write([]byte(responsesXML)); err != nil {
+		log.Err(err).Msg("error writing response")
+	}
+}
+
+// searchResources returns the resources below fn matching q. When fn is
+// the home of the user in a global namespace, the resources shared with
+// the user are searched as well. The storage providers only return the
+// resources the user has access to.
+func (s *svc) searchResources(ctx context.Context, client gateway.GatewayAPIClient, searcher sppb.SearchAPIClient, fn, ns string, q search.Query) ([]*provider.ResourceInfo, *rpc.Status, error) {
+	// the sources are merged, so every source must return enough results
+	// for the requested page
+	var limit uint32
+	if q.Limit > 0 {
+		limit = uint32(q.Offset + q.Limit)
+	}
+
+	res, err := searcher.Search(ctx, &sppb.SearchRequest{
+		Ref:     &provider.Reference{Path: fn},
+		Pattern: q.Pattern,
+		Limit:   limit,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, res.Status, nil
+	}
+	infos := res.Infos
+
+	if path.Clean(ns) == "/" {
+		home, err := client.GetHome(ctx, &provider.GetHomeRequest{})
+		if err != nil {
+			return nil, nil, err
+		}
+		if home.Status.Code == rpc.Code_CODE_OK && path.Clean(fn) == path.Clean(home.Path) {
+			shared, err := s.searchSharedResources(ctx, client, searcher, q.Pattern, limit)
+			if err != nil {
+				return nil, nil, err
+			}
+			infos = append(infos, shared...)
+		}
+	}
+
+	// a resource may be reachable from more than one source
+	seen := make(map[string]struct{}, len(infos))
+	unique := make([]*provider.ResourceInfo, 0, len(infos))
+	for _, info := range infos {
+		id := resourceid.OwnCloudResourceIDWrap(info.Id)
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, info)
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i].Path < unique[j].Path })
+
+	if q.Offset >= len(unique) {
+		return []*provider.ResourceInfo{}, res.Status, nil
+	}
+	unique = unique[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(unique) {
+		unique = unique[:q.Limit]
+	}
+	return unique, res.Status, nil
+}
+
+// searchSharedResources returns at most limit resources matching pattern in
+// each of the shares the user accepted, including the shared resources.
+func (s *svc) searchSharedResources(ctx context.Context, client gateway.GatewayAPIClient, searcher sppb.SearchAPIClient, pattern string, limit uint32) ([]*provider.ResourceInfo, error) {
+	log := appctx.GetLogger(ctx)
+
+	shares, err := client.ListReceivedShares(ctx, &collaboration.ListReceivedSharesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if shares.Status.Code != rpc.Code_CODE_OK {
+		return nil, errors.New("error listing received shares: " + shares.Status.Message)
+	}
+
+	lower := strings.ToLower(pattern)
+	infos := []*provider.ResourceInfo{}
+	for _, rs := range shares.Shares {
+		if rs.State != collaboration.ShareState_SHARE_STATE_ACCEPTED {
+			continue
+		}
+		ref := &provider.Reference{ResourceId: rs.Share.ResourceId}
+		stat, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+		if err != nil {
+			return nil, err
+		}
+		if stat.Status.Code != rpc.Code_CODE_OK {
+			log.Debug().Interface("status", stat.Status).Str("share", rs.Share.GetId().GetOpaqueId()).Msg("skipping share in search")
+			continue
+		}
+		if strings.Contains(strings.ToLower(path.Base(stat.Info.Path)), lower) {
+			infos = append(infos, stat.Info)
+		}
+		if stat.Info.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			continue
+		}
+
+		res, err := searcher.Search(ctx, &sppb.SearchRequest{Ref: ref, Pattern: pattern, Limit: limit})
+		if err != nil {
+			return nil, err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			log.Debug().Interface("status", res.Status).Str("share", rs.Share.GetId().GetOpaqueId()).Msg("skipping share in search")
+			continue
+		}
+		infos = append(infos, res.Infos...)
+	}
+	return infos, nil
+}
@@ -0,0 +1,307 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	sppb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
+	"github.com/cs3org/reva/pkg/search"
+	"google.golang.org/grpc"
+)
+
+const searchBody = `<?xml version="1.0" encoding="UTF-8"?>
+<d:searchrequest xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+  <d:basicsearch>
+    <d:select>
+      <d:prop>
+        <d:displayname/>
+        <oc:fileid/>
+      </d:prop>
+    </d:select>
+    <d:from>
+      <d:scope>
+        <d:href>%s</d:href>
+        <d:depth>%s</d:depth>
+      </d:scope>
+    </d:from>
+    <d:where>
+      %s
+    </d:where>
+    <d:limit>
+      <d:nresults>10</d:nresults>
+    </d:limit>
+  </d:basicsearch>
+</d:searchrequest>`
+
+func replaceAll(s string, values ...string) string {
+	for _, v := range values {
+		s = strings.Replace(s, "%s", v, 1)
+	}
+	return s
+}
+
+func TestReadSearchRequest(t *testing.T) {
+	like := `<d:like><d:prop><d:displayname/></d:prop><d:literal>%thesis%</d:literal></d:like>`
+	tests := []struct {
+		description string
+		body        string
+		status      int
+		pattern     string
+		unsupported bool
+	}{
+		{
+			description: "like on the display name",
+			body:        replaceAll(searchBody, "/remote.php/dav/files/einstein/docs", "infinity", like),
+			pattern:     "thesis",
+		},
+		{
+			description: "literal without wildcards",
+			body:        replaceAll(searchBody, "docs", "", `<d:like><d:prop><d:displayname/></d:prop><d:literal>thesis</d:literal></d:like>`),
+			pattern:     "thesis",
+		},
+		{
+			description: "wildcards within the literal",
+			body:        replaceAll(searchBody, "docs", "infinity", `<d:like><d:prop><d:displayname/></d:prop><d:literal>the%sis</d:literal></d:like>`),
+			unsupported: true,
+		},
+		{
+			description: "other properties",
+			body:        replaceAll(searchBody, "docs", "infinity", `<d:like><d:prop><d:getcontenttype/></d:prop><d:literal>%pdf%</d:literal></d:like>`),
+			unsupported: true,
+		},
+		{
+			description: "other operators",
+			body:        replaceAll(searchBody, "docs", "infinity", `<d:gt><d:prop><d:getcontentlength/></d:prop><d:literal>10</d:literal></d:gt>`),
+			unsupported: true,
+		},
+		{
+			description: "limited depth",
+			body:        replaceAll(searchBody, "docs", "1", like),
+			status:      http.StatusNotImplemented,
+		},
+		{
+			description: "invalid xml",
+			body:        "<d:searchrequest",
+			status:      http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			bs, status, err := readSearchRequest(strings.NewReader(tt.body))
+			if status != tt.status {
+				t.Fatalf("got status %d, expected %d: %v", status, tt.status, err)
+			}
+			if tt.status != 0 {
+				return
+			}
+			if len(bs.Select) != 2 || bs.Select[1] != (xml.Name{Space: "http://owncloud.org/ns", Local: "fileid"}) || bs.Limit != 10 {
+				t.Fatalf("unexpected search %+v", bs)
+			}
+			pattern, err := bs.Where.pattern()
+			if tt.unsupported {
+				if !errors.Is(err, errUnsupportedSearch) {
+					t.Fatalf("expected unsupported search, got %v", err)
+				}
+				return
+			}
+			if err != nil || pattern != tt.pattern {
+				t.Fatalf("got pattern %q, expected %q: %v", pattern, tt.pattern, err)
+			}
+		})
+	}
+}
+
+func TestScopePath(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKeyBaseURI, "/remote.php/dav/files/einstein")
+	r := httptest.NewRequest(MethodSearch, "/docs", nil).WithContext(ctx)
+
+	tests := []struct {
+		href     string
+		expected string
+		invalid  bool
+	}{
+		{href: "/remote.php/dav/files/einstein", expected: "/"},
+		{href: "/remote.php/dav/files/einstein/docs/2024%20papers/", expected: "/docs/2024 papers"},
+		{href: "https://cloud.example.org/remote.php/dav/files/einstein/docs", expected: "/docs"},
+		{href: "papers", expected: "/docs/papers"},
+		{href: "", expected: "/docs"},
+		{href: "/remote.php/dav/files/marie/docs", invalid: true},
+		{href: "/remote.php/dav/files/einstein2", invalid: true},
+	}
+
+	for _, tt := range tests {
+		got, err := scopePath(ctx, r, tt.href)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("scope %q should be rejected, got %q", tt.href, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("scope %q: got %q, expected %q: %v", tt.href, got, tt.expected, err)
+		}
+	}
+}
+
+func TestReadReportSearchFiles(t *testing.T) {
+	body := `<?xml version="1.0" encoding="UTF-8"?>
+<oc:search-files xmlns:a="DAV:" xmlns:oc="http://owncloud.org/ns">
+  <a:prop>
+    <oc:fileid/>
+  </a:prop>
+  <oc:search>
+    <oc:pattern>thesis</oc:pattern>
+    <oc:limit>30</oc:limit>
+    <oc:offset>60</oc:offset>
+  </oc:search>
+</oc:search-files>`
+
+	rep, _, err := readReport(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.SearchFiles == nil {
+		t.Fatal("expected a search-files report")
+	}
+	if s := rep.SearchFiles.Search; s.Pattern != "thesis" || s.Limit != 30 || s.Offset != 60 {
+		t.Fatalf("unexpected search %+v", s)
+	}
+}
+
+// searchClient returns the resources of the folders by path or by id.
+type searchClient struct {
+	sppb.SearchAPIClient
+	infos map[string][]*provider.ResourceInfo
+}
+
+func (c *searchClient) Search(_ context.Context, req *sppb.SearchRequest, _ ...grpc.CallOption) (*sppb.SearchResponse, error) {
+	key := req.Ref.GetPath()
+	if req.Ref.GetResourceId() != nil {
+		key = req.Ref.ResourceId.OpaqueId
+	}
+	infos := c.infos[key]
+	if req.Limit > 0 && int(req.Limit) < len(infos) {
+		infos = infos[:req.Limit]
+	}
+	return &sppb.SearchResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Infos: infos}, nil
+}
+
+// sharesGateway holds the home of the user and the shares accepted by the user.
+type sharesGateway struct {
+	gateway.GatewayAPIClient
+	shares map[string]*provider.ResourceInfo
+}
+
+func (g *sharesGateway) GetHome(context.Context, *provider.GetHomeRequest, ...grpc.CallOption) (*provider.GetHomeResponse, error) {
+	return &provider.GetHomeResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Path: "/home/einstein"}, nil
+}
+
+func (g *sharesGateway) ListReceivedShares(context.Context, *collaboration.ListReceivedSharesRequest, ...grpc.CallOption) (*collaboration.ListReceivedSharesResponse, error) {
+	res := &collaboration.ListReceivedSharesResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}
+	for id := range g.shares {
+		res.Shares = append(res.Shares, &collaboration.ReceivedShare{
+			State: collaboration.ShareState_SHARE_STATE_ACCEPTED,
+			Share: &collaboration.Share{ResourceId: &provider.ResourceId{StorageId: "s", OpaqueId: id}},
+		})
+	}
+	return res, nil
+}
+
+func (g *sharesGateway) Stat(_ context.Context, req *provider.StatRequest, _ ...grpc.CallOption) (*provider.StatResponse, error) {
+	return &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Info: g.shares[req.Ref.ResourceId.OpaqueId]}, nil
+}
+
+func TestSearchResources(t *testing.T) {
+	info := func(id, p string) *provider.ResourceInfo {
+		return &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "s", OpaqueId: id}, Path: p, Type: provider.ResourceType_RESOURCE_TYPE_FILE}
+	}
+	shared := &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "s", OpaqueId: "shared"}, Path: "/eos/marie/thesis", Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER}
+	client := &sharesGateway{shares: map[string]*provider.ResourceInfo{"shared": shared}}
+	searcher := &searchClient{infos: map[string][]*provider.ResourceInfo{
+		"/home/einstein":      {info("a", "/home/einstein/a-thesis.txt"), info("c", "/home/einstein/c-thesis.txt")},
+		"/home/einstein/docs": {info("d", "/home/einstein/docs/d-thesis.txt")},
+		"shared":              {info("b", "/eos/marie/thesis/b-thesis.txt"), info("a", "/home/einstein/a-thesis.txt")},
+	}}
+
+	tests := []struct {
+		description string
+		fn, ns      string
+		q           search.Query
+		expected    []string
+	}{
+		{
+			description: "the accepted shares are searched with the home",
+			fn:          "/home/einstein",
+			ns:          "/",
+			q:           search.Query{Pattern: "thesis"},
+			expected:    []string{"/eos/marie/thesis", "/eos/marie/thesis/b-thesis.txt", "/home/einstein/a-thesis.txt", "/home/einstein/c-thesis.txt"},
+		},
+		{
+			description: "pagination over the merged results",
+			fn:          "/home/einstein",
+			ns:          "/",
+			q:           search.Query{Pattern: "thesis", Limit: 2, Offset: 1},
+			expected:    []string{"/eos/marie/thesis/b-thesis.txt", "/home/einstein/a-thesis.txt"},
+		},
+		{
+			description: "the shares are not searched below the home",
+			fn:          "/home/einstein/docs",
+			ns:          "/",
+			q:           search.Query{Pattern: "thesis"},
+			expected:    []string{"/home/einstein/docs/d-thesis.txt"},
+		},
+		{
+			description: "the shares are not searched in a user namespace",
+			fn:          "/home/einstein",
+			ns:          "/home/einstein",
+			q:           search.Query{Pattern: "thesis"},
+			expected:    []string{"/home/einstein/a-thesis.txt", "/home/einstein/c-thesis.txt"},
+		},
+	}
+
+	s := &svc{}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			infos, st, err := s.searchResources(context.Background(), client, searcher, tt.fn, tt.ns, tt.q)
+			if err != nil || st.Code != rpc.Code_CODE_OK {
+				t.Fatalf("unexpected result %v %v", st, err)
+			}
+			got := []string{}
+			for _, info := range infos {
+				got = append(got, info.Path)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Fatalf("got %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/tags"
+	"github.com/rs/zerolog"
+)
+
+// propMetadataKey returns the arbitrary metadata key holding the property n
+// for the user in ctx.
+func propMetadataKey(ctx context.Context, n *xml.Name) string {
+	if n.Space == _nsOwncloud {
+		switch n.Local {
+		case "tags":
+			if u, ok := appctx.ContextGetUser(ctx); ok {
+				return tags.UserTagsKey(u.Id)
+			}
+		case "systemtags":
+			return tags.SystemTagsKey
+		}
+	}
+	return metadataKeyOf(n)
+}
+
+// newTagsProp returns the property listing the tags stored under key in md,
+// each one in an element named tag.
+func (s *svc) newTagsProp(md *provider.ResourceInfo, key, name, tag string) *propertyXML {
+	var b strings.Builder
+	for _, n := range tags.Get(md, key) {
+		b.WriteString("<" + tag + ">")
+		b.Write(s.xmlEscaped(n))
+		b.WriteString("</" + tag + ">")
+	}
+	return s.newPropRaw(name, b.String())
+}
+
+// userTagsProp returns the oc:tags property, listing the tags set by the user in ctx.
+func (s *svc) userTagsProp(ctx context.Context, md *provider.ResourceInfo) (*propertyXML, bool) {
+	u, ok := appctx.ContextGetUser(ctx)
+	if !ok {
+		return nil, false
+	}
+	return s.newTagsProp(md, tags.UserTagsKey(u.Id), "oc:tags", "oc:tag"), true
+}
+
+// systemTagsProp returns the oc:systemtags property, listing the system tags.
+func (s *svc) systemTagsProp(md *provider.ResourceInfo) *propertyXML {
+	return s.newTagsProp(md, tags.SystemTagsKey, "oc:systemtags", "oc:systemtag")
+}
+
+// parseTagsProp returns the tags in the value of a oc:tags property.
+// The tags are either enclosed in tag elements, or separated by commas.
+func parseTagsProp(value []byte) ([]string, error) {
+	names := []string{}
+	var text strings.Builder
+	d := xml.NewDecoder(bytes.NewReader(value))
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch e := t.(type) {
+		case xml.StartElement:
+			if e.Name.Local != "tag" {
+				return nil, errtypes.BadRequest("unexpected element " + e.Name.Local)
+			}
+			var n string
+			if err := d.DecodeElement(&n, &e); err != nil {
+				return nil, err
+			}
+			names = append(names, n)
+		case xml.CharData:
+			text.Write(e)
+		}
+	}
+	if len(names) == 0 {
+		return tags.Parse(text.String()), nil
+	}
+	if err := tags.Validate(names); err != nil {
+		return nil, err
+	}
+	return tags.Parse(tags.Format(names)), nil
+}
+
+// proppatchTags replaces the tags set by the current user on the resource
+// referenced by ref with the ones in value, or removes them all.
+func (s *svc) proppatchTags(ctx context.Context, w http.ResponseWriter, client gateway.GatewayAPIClient, ref *provider.Reference, value []byte, remove bool, log zerolog.Logger) bool {
+	var names []string
+	if !remove {
+		var err error
+		if names, err = parseTagsProp(value); err != nil {
+			log.Debug().Err(err).Msg("invalid tags")
+			w.WriteHeader(http.StatusBadRequest)
+			b, err := Marshal(exception{
+				code:    SabredavBadRequest,
+				message: fmt.Sprintf("Invalid tags: %v", err),
+			})
+			HandleWebdavError(&log, w, b, err)
+			return false
+		}
+	}
+
+	u := appctx.ContextMustGetUser(ctx)
+	if err := tags.SetUserTags(ctx, client, u.Id, ref, names); err != nil {
+		handleTagsError(w, err, log)
+		return false
+	}
+	return true
+}
+
+func handleTagsError(w http.ResponseWriter, err error, log zerolog.Logger) {
+	switch err.(type) {
+	case errtypes.IsNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case errtypes.IsPermissionDenied:
+		w.WriteHeader(http.StatusForbidden)
+		b, err := Marshal(exception{
+			code:    SabredavPermissionDenied,
+			message: "Permission denied to tag the resource",
+		})
+		HandleWebdavError(&log, w, b, err)
+	case errtypes.IsBadRequest:
+		w.WriteHeader(http.StatusBadRequest)
+	case errtypes.IsLocked:
+		w.WriteHeader(http.StatusLocked)
+	default:
+		log.Error().Err(err).Msg("error updating tags")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"reflect"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+func TestParseTagsProp(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{``, []string{}},
+		{`physics, maths`, []string{"physics", "maths"}},
+		{`<oc:tag xmlns:oc="http://owncloud.org/ns">physics</oc:tag><oc:tag xmlns:oc="http://owncloud.org/ns">quantum &amp; relativity</oc:tag>`, []string{"physics", "quantum & relativity"}},
+	}
+	for _, tt := range tests {
+		got, err := parseTagsProp([]byte(tt.value))
+		if err != nil {
+			t.Fatalf("parseTagsProp(%q): %v", tt.value, err)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("parseTagsProp(%q) = %v, expected %v", tt.value, got, tt.expected)
+		}
+	}
+
+	for _, value := range []string{`<oc:label xmlns:oc="http://owncloud.org/ns">physics</oc:label>`, `<oc:tag xmlns:oc="http://owncloud.org/ns">a,b</oc:tag>`} {
+		if _, err := parseTagsProp([]byte(value)); err == nil {
+			t.Errorf("parseTagsProp(%q): expected an error", value)
+		}
+	}
+}
+
+func TestSystemTagsProp(t *testing.T) {
+	s := &svc{c: &Config{}}
+	md := &provider.ResourceInfo{ArbitraryMetadata: &provider.ArbitraryMetadata{
+		Metadata: map[string]string{"tags": "physics,a<b"},
+	}}
+	p := s.systemTagsProp(md)
+	if expected := "<oc:systemtag>physics</oc:systemtag><oc:systemtag>a&lt;b</oc:systemtag>"; string(p.InnerXML) != expected {
+		t.Errorf("got %s, expected %s", p.InnerXML, expected)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"net/http"
 	"path"
 	"path/filepath"
@@ -29,6 +30,7 @@ import (
 	"strings"
 	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
@@ -40,6 +42,7 @@ import (
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/cs3org/reva/pkg/utils/resourceid"
+	"github.com/pkg/errors"
 )
 
 // TrashbinHandler handles trashbin requests.
@@ -274,88 +277,199 @@ func (h *TrashbinHandler) listTrashbin(w http.ResponseWriter, r *http.Request, s
 	toTS, _ := conversions.ParseTimestamp(r.URL.Query().Get("to"))
 
 	// ask gateway for recycle items
-	getRecycleRes, err := gc.ListRecycle(ctx, &provider.ListRecycleRequest{
+	items := &recycleStream{ctx: ctx, gc: gc, basePath: basePath, recursive: depth == "infinity"}
+	st, err := items.open(&provider.ListRecycleStreamRequest{
 		Ref:    &provider.Reference{Path: basePath},
 		FromTs: fromTS,
 		ToTs:   toTS,
 		Key:    path.Join(key, itemPath),
 	})
-
 	if err != nil {
-		sublog.Error().Err(err).Msg("error calling ListRecycle")
+		sublog.Error().Err(err).Msg("error calling ListRecycleStream")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	if getRecycleRes.Status.Code == rpc.Code_CODE_INVALID_ARGUMENT {
+	if st.Code == rpc.Code_CODE_INVALID_ARGUMENT {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	if getRecycleRes.Status.Code != rpc.Code_CODE_OK {
-		HandleErrorStatus(&sublog, w, getRecycleRes.Status)
+	if st.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, st)
 		return
 	}
 
-	items := getRecycleRes.RecycleItems
+	rootXML, err := xml.Marshal(h.trashRootResponse(ctx, s))
+	if err != nil {
+		sublog.Error().Err(err).Msg("error formatting propfind")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(HeaderDav, "1, 3, extended-mkcol")
+	w.Header().Set(HeaderContentType, "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	if _, err := w.Write([]byte(multistatusStart + string(rootXML))); err != nil {
+		sublog.Error().Err(err).Msg("error writing body")
+		return
+	}
 
-	if depth == "infinity" {
-		var stack []string
-		// check sub-containers in reverse order and add them to the stack
-		// the reversed order here will produce a more logical sorting of results
-		for i := len(items) - 1; i >= 0; i-- {
-			// for i := range res.Infos {
-			if items[i].Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
-				stack = append(stack, items[i].Key)
+	// the items are written in batches, so that large trashbins do not need to be held in memory.
+	// On errors the multistatus is left unterminated: a truncated listing would make sync
+	// clients drop the missing entries.
+	for done := false; !done; {
+		responses := make([]*responseXML, 0, propfindBatchSize)
+		for len(responses) < propfindBatchSize {
+			item, err := items.next()
+			if err == io.EOF {
+				done = true
+				break
 			}
-		}
-
-		for len(stack) > 0 {
-			key := stack[len(stack)-1]
-			getRecycleRes, err := gc.ListRecycle(ctx, &provider.ListRecycleRequest{Ref: &provider.Reference{Path: basePath}, Key: key})
 			if err != nil {
-				sublog.Error().Err(err).Msg("error calling ListRecycle")
-				w.WriteHeader(http.StatusInternalServerError)
+				sublog.Error().Err(err).Msg("error listing trashbin")
 				return
 			}
-
-			if getRecycleRes.Status.Code != rpc.Code_CODE_OK {
-				HandleErrorStatus(&sublog, w, getRecycleRes.Status)
+			res, err := h.itemToPropResponse(ctx, s, u, &pf, item, basePath)
+			if err != nil {
+				sublog.Error().Err(err).Msg("error formatting propfind")
 				return
 			}
-			items = append(items, getRecycleRes.RecycleItems...)
-
-			stack = stack[:len(stack)-1]
-			// check sub-containers in reverse order and add them to the stack
-			// the reversed order here will produce a more logical sorting of results
-			for i := len(getRecycleRes.RecycleItems) - 1; i >= 0; i-- {
-				// for i := range res.Infos {
-				if getRecycleRes.RecycleItems[i].Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
-					stack = append(stack, getRecycleRes.RecycleItems[i].Key)
-				}
-			}
+			responses = append(responses, res)
+		}
+		if len(responses) == 0 {
+			continue
+		}
+		responsesXML, err := xml.Marshal(&responses)
+		if err != nil {
+			sublog.Error().Err(err).Msg("error formatting propfind")
+			return
+		}
+		if _, err := w.Write(responsesXML); err != nil {
+			sublog.Error().Err(err).Msg("error writing body")
+			return
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
 		}
 	}
 
-	propRes, err := h.formatTrashPropfind(ctx, s, u, &pf, items, basePath)
+	if _, err := w.Write([]byte(multistatusEnd)); err != nil {
+		sublog.Error().Err(err).Msg("error writing body")
+	}
+}
+
+// recycleStream returns the items of a trashbin as they are received from the gateway.
+// When recursive, the containers are listed as well once the current listing is done.
+type recycleStream struct {
+	ctx       context.Context
+	gc        gateway.GatewayAPIClient
+	basePath  string
+	recursive bool
+
+	stream gateway.GatewayAPI_ListRecycleStreamClient
+	// first is received upfront, to report errors before the response is started
+	first *provider.RecycleItem
+	// containers of the current listing, and the ones left to be listed
+	containers []string
+	stack      []string
+}
+
+// open starts the listing of req, returning its status if it failed.
+func (r *recycleStream) open(req *provider.ListRecycleStreamRequest) (*rpc.Status, error) {
+	stream, err := r.gc.ListRecycleStream(r.ctx, req)
 	if err != nil {
-		sublog.Error().Err(err).Msg("error formatting propfind")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		return nil, err
+	}
+	res, err := stream.Recv()
+	if err == io.EOF {
+		r.stream, r.first = nil, nil
+		return &rpc.Status{Code: rpc.Code_CODE_OK}, nil
 	}
-	w.Header().Set(HeaderDav, "1, 3, extended-mkcol")
-	w.Header().Set(HeaderContentType, "application/xml; charset=utf-8")
-	w.WriteHeader(http.StatusMultiStatus)
-	_, err = w.Write([]byte(propRes))
 	if err != nil {
-		sublog.Error().Err(err).Msg("error writing body")
-		return
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return res.Status, nil
 	}
+	r.stream, r.first = stream, res.RecycleItem
+	return res.Status, nil
+}
+
+func (r *recycleStream) next() (*provider.RecycleItem, error) {
+	for {
+		item, err := r.recv()
+		if err != io.EOF {
+			if err == nil && r.recursive && item.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+				r.containers = append(r.containers, item.Key)
+			}
+			return item, err
+		}
+
+		// add the sub-containers in reverse order to the stack
+		// the reversed order here will produce a more logical sorting of results
+		for i := len(r.containers) - 1; i >= 0; i-- {
+			r.stack = append(r.stack, r.containers[i])
+		}
+		r.containers = nil
+		if len(r.stack) == 0 {
+			return nil, io.EOF
+		}
+		key := r.stack[len(r.stack)-1]
+		r.stack = r.stack[:len(r.stack)-1]
+		st, err := r.open(&provider.ListRecycleStreamRequest{Ref: &provider.Reference{Path: r.basePath}, Key: key})
+		if err != nil {
+			return nil, err
+		}
+		if st.Code != rpc.Code_CODE_OK {
+			return nil, errors.Errorf("ocdav: error listing trashbin: %s %s", st.Code, st.Message)
+		}
+	}
+}
+
+func (r *recycleStream) recv() (*provider.RecycleItem, error) {
+	if r.first != nil {
+		item := r.first
+		r.first = nil
+		return item, nil
+	}
+	if r.stream == nil {
+		return nil, io.EOF
+	}
+	res, err := r.stream.Recv()
+	if err == io.EOF {
+		r.stream = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, errors.Errorf("ocdav: error listing trashbin: %s %s", res.Status.Code, res.Status.Message)
+	}
+	return res.RecycleItem, nil
 }
 
 func (h *TrashbinHandler) formatTrashPropfind(ctx context.Context, s *svc, u *userpb.User, pf *propfindXML, items []*provider.RecycleItem, basePath string) (string, error) {
 	responses := make([]*responseXML, 0, len(items)+1)
 	// add trashbin dir . entry
-	responses = append(responses, &responseXML{
+	responses = append(responses, h.trashRootResponse(ctx, s))
+
+	for i := range items {
+		res, err := h.itemToPropResponse(ctx, s, u, pf, items[i], basePath)
+		if err != nil {
+			return "", err
+		}
+		responses = append(responses, res)
+	}
+	responsesXML, err := xml.Marshal(&responses)
+	if err != nil {
+		return "", err
+	}
+
+	return multistatusStart + string(responsesXML) + multistatusEnd, nil
+}
+
+// trashRootResponse returns the response for the trashbin collection itself.
+func (h *TrashbinHandler) trashRootResponse(ctx context.Context, s *svc) *responseXML {
+	return &responseXML{
 		Href: encodePath(ctx.Value(ctxKeyBaseURI).(string) + "/"), // url encode response.Href TODO
 		Propstat: []propstatXML{
 			{
@@ -374,24 +488,7 @@ func (h *TrashbinHandler) formatTrashPropfind(ctx context.Context, s *svc, u *us
 				},
 			},
 		},
-	})
-
-	for i := range items {
-		res, err := h.itemToPropResponse(ctx, s, u, pf, items[i], basePath)
-		if err != nil {
-			return "", err
-		}
-		responses = append(responses, res)
 	}
-	responsesXML, err := xml.Marshal(&responses)
-	if err != nil {
-		return "", err
-	}
-
-	msg := `<?xml version="1.0" encoding="utf-8"?><d:multistatus xmlns:d="DAV:" `
-	msg += `xmlns:s="http://sabredav.org/ns" xmlns:oc="http://owncloud.org/ns">`
-	msg += string(responsesXML) + `</d:multistatus>`
-	return msg, nil
 }
 
 // itemToPropResponse needs to create a listing that contains a key and destination
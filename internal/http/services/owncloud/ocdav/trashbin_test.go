@@ -0,0 +1,148 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"google.golang.org/grpc"
+)
+
+// recycleGateway lists the trashbin items by key, failing for the keys in broken.
+type recycleGateway struct {
+	gateway.GatewayAPIClient
+	items  map[string][]*provider.RecycleItem
+	broken map[string]bool
+}
+
+func (g *recycleGateway) ListRecycleStream(_ context.Context, req *provider.ListRecycleStreamRequest, _ ...grpc.CallOption) (gateway.GatewayAPI_ListRecycleStreamClient, error) {
+	var res []*provider.ListRecycleStreamResponse
+	if g.broken[req.Key] {
+		res = append(res, &provider.ListRecycleStreamResponse{Status: &rpc.Status{Code: rpc.Code_CODE_INTERNAL}})
+	}
+	for _, item := range g.items[req.Key] {
+		res = append(res, &provider.ListRecycleStreamResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, RecycleItem: item})
+	}
+	return &recycleStreamClient{res: res}, nil
+}
+
+type recycleStreamClient struct {
+	grpc.ClientStream
+	res []*provider.ListRecycleStreamResponse
+}
+
+func (c *recycleStreamClient) Recv() (*provider.ListRecycleStreamResponse, error) {
+	if len(c.res) == 0 {
+		return nil, io.EOF
+	}
+	res := c.res[0]
+	c.res = c.res[1:]
+	return res, nil
+}
+
+func TestRecycleStream(t *testing.T) {
+	dir := func(key string) *provider.RecycleItem {
+		return &provider.RecycleItem{Key: key, Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER}
+	}
+	file := func(key string) *provider.RecycleItem {
+		return &provider.RecycleItem{Key: key, Type: provider.ResourceType_RESOURCE_TYPE_FILE}
+	}
+	items := map[string][]*provider.RecycleItem{
+		"":    {dir("a"), file("f"), dir("b")},
+		"a":   {file("a/f"), dir("a/c")},
+		"a/c": {file("a/c/f")},
+		"b":   {file("b/f")},
+	}
+
+	tests := []struct {
+		description string
+		recursive   bool
+		broken      map[string]bool
+		expected    []string
+		fails       bool
+	}{
+		{
+			description: "depth 1",
+			expected:    []string{"a", "f", "b"},
+		},
+		{
+			description: "depth infinity",
+			recursive:   true,
+			expected:    []string{"a", "f", "b", "a/f", "a/c", "a/c/f", "b/f"},
+		},
+		{
+			description: "broken sub-container",
+			recursive:   true,
+			broken:      map[string]bool{"a/c": true},
+			expected:    []string{"a", "f", "b", "a/f", "a/c"},
+			fails:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			gc := &recycleGateway{items: items, broken: tt.broken}
+			stream := &recycleStream{ctx: context.Background(), gc: gc, recursive: tt.recursive}
+			st, err := stream.open(&provider.ListRecycleStreamRequest{})
+			if err != nil || st.Code != rpc.Code_CODE_OK {
+				t.Fatalf("error opening stream: %v %v", st, err)
+			}
+
+			var keys []string
+			for {
+				item, err := stream.next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					if !tt.fails {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					tt.fails = false
+					break
+				}
+				keys = append(keys, item.Key)
+			}
+			if tt.fails {
+				t.Fatal("expected an error")
+			}
+			if !reflect.DeepEqual(keys, tt.expected) {
+				t.Fatalf("got %v, expected %v", keys, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRecycleStreamOpen(t *testing.T) {
+	gc := &recycleGateway{broken: map[string]bool{"": true}}
+	stream := &recycleStream{ctx: context.Background(), gc: gc}
+	st, err := stream.open(&provider.ListRecycleStreamRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Code != rpc.Code_CODE_INTERNAL {
+		t.Fatalf("expected the listing status to be reported upfront, got %v", st)
+	}
+}
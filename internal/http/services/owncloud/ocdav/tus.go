@@ -27,6 +27,7 @@ import (
 	"strings"
 	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
@@ -85,10 +86,15 @@ func (s *svc) handleSpacesTusPost(w http.ResponseWriter, r *http.Request, spaceI
 	s.handleTusPost(ctx, w, r, meta, spaceRef, sublog)
 }
 
+// tusExtensions are the tus extensions supported for all the storages.
+// The concatenation is only advertised for the folders whose storage
+// reports it can concatenate the uploads.
+const tusExtensions = "creation,creation-with-upload,checksum,expiration"
+
 func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.Request, meta map[string]string, ref *provider.Reference, log zerolog.Logger) {
-	w.Header().Add(HeaderAccessControlAllowHeaders, strings.Join([]string{HeaderTusResumable, HeaderUploadLength, HeaderUploadMetadata, HeaderIfMatch}, ", "))
+	w.Header().Add(HeaderAccessControlAllowHeaders, strings.Join([]string{HeaderTusResumable, HeaderUploadLength, HeaderUploadMetadata, HeaderUploadConcat, HeaderUploadChecksum, HeaderIfMatch}, ", "))
 	w.Header().Add(HeaderAccessControlExposeHeaders, strings.Join([]string{HeaderTusResumable, HeaderLocation}, ", "))
-	w.Header().Set(HeaderTusExtension, "creation,creation-with-upload,checksum,expiration")
+	w.Header().Set(HeaderTusExtension, tusExtensions)
 
 	w.Header().Set(HeaderTusResumable, "1.0.0")
 
@@ -99,10 +105,23 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 		w.WriteHeader(http.StatusPreconditionFailed)
 		return
 	}
-	if r.Header.Get(HeaderUploadLength) == "" {
+
+	// the length of a final upload is the sum of the lengths of its partial uploads
+	concat := r.Header.Get(HeaderUploadConcat)
+	final := strings.HasPrefix(concat, "final;")
+	if concat != "" && concat != "partial" && !final {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !final && r.Header.Get(HeaderUploadLength) == "" {
 		w.WriteHeader(http.StatusPreconditionFailed)
 		return
 	}
+	// a final upload cannot carry any data
+	if final && r.Header.Get(HeaderContentType) == "application/offset+octet-stream" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
 	// r.Header.Get("OC-Checksum")
 	// TODO must be SHA1, ADLER32 or MD5 ... in capital letters????
 	// curl -X PUT https://demo.owncloud.com/remote.php/webdav/testcs.bin -u demo:demo -d '123' -v -H 'OC-Checksum: SHA1:40bd001563085fc35165329ea1ff5c5ecbdbbeef'
@@ -132,6 +151,13 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 		return
 	}
 
+	// the upload is addressed by the collection it is posted to, the lock
+	// token is bound to it and checked when the patches complete the file
+	lockIDs, ok := s.checkIfHeader(ctx, w, r, client, log, &ifResource{href: path.Join(hrefPath(r.RequestURI), meta["filename"]), ref: ref})
+	if !ok {
+		return
+	}
+
 	info := sRes.Info
 	if info != nil && info.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
 		log.Warn().Msg("resource is not a file")
@@ -152,11 +178,18 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 	}
 
 	opaqueMap := map[string]*typespb.OpaqueEntry{
-		HeaderUploadLength: {
+		// lets the storage prepare a tus upload
+		"protocol": {
 			Decoder: "plain",
-			Value:   []byte(r.Header.Get(HeaderUploadLength)),
+			Value:   []byte("tus"),
 		},
 	}
+	if !final {
+		opaqueMap[HeaderUploadLength] = &typespb.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(r.Header.Get(HeaderUploadLength)),
+		}
+	}
 
 	mtime := meta["mtime"]
 	if mtime != "" {
@@ -166,9 +199,47 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 		}
 	}
 
+	switch {
+	case concat == "partial":
+		opaqueMap[HeaderUploadConcat] = &typespb.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte(concat),
+		}
+	case final:
+		// the partial uploads are referenced by the urls handed out to the client,
+		// the last path segment being the transfer token of the upload
+		urls := strings.Fields(strings.TrimPrefix(concat, "final;"))
+		if len(urls) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		tokens := make([]string, 0, len(urls))
+		for _, u := range urls {
+			tokens = append(tokens, path.Base(u))
+		}
+		opaqueMap[HeaderUploadConcat] = &typespb.OpaqueEntry{
+			Decoder: "plain",
+			Value:   []byte("final;" + strings.Join(tokens, " ")),
+		}
+		// the checksum of a final upload covers the whole file
+		if checksum := r.Header.Get(HeaderUploadChecksum); checksum != "" {
+			cparts := strings.SplitN(checksum, " ", 2)
+			if len(cparts) != 2 {
+				log.Debug().Str("upload-checksum", checksum).Msg("invalid Upload-Checksum format, expected '[algorithm] [checksum]'")
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			opaqueMap[HeaderUploadChecksum] = &typespb.OpaqueEntry{
+				Decoder: "plain",
+				Value:   []byte(strings.ToLower(cparts[0]) + " " + cparts[1]),
+			}
+		}
+	}
+
 	// initiateUpload
 	uReq := &provider.InitiateFileUploadRequest{
-		Ref: ref,
+		Ref:    ref,
+		LockId: lockIDs[0],
 		Opaque: &typespb.Opaque{
 			Map: opaqueMap,
 		},
@@ -208,6 +279,15 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 
 	w.Header().Set(HeaderLocation, ep)
 
+	// the final upload is complete as soon as it is created
+	if final {
+		if !s.setUploadedFileHeaders(ctx, w, client, sReq, log) {
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
 	// for creation-with-upload extension forward bytes to dataprovider
 	// TODO check this really streams
 	if r.Header.Get(HeaderContentType) == "application/offset+octet-stream" {
@@ -252,71 +332,78 @@ func (s *svc) handleTusPost(ctx context.Context, w http.ResponseWriter, r *http.
 			return
 		}
 
-		// check if upload was fully completed
-		if length == 0 || httpRes.Header.Get(HeaderUploadOffset) == r.Header.Get(HeaderUploadLength) {
-			// get uploaded file metadata
-
-			sRes, err := client.Stat(ctx, sReq)
-			if err != nil {
-				log.Error().Err(err).Msg("error sending grpc stat request")
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-
-			if sRes.Status.Code != rpc.Code_CODE_OK && sRes.Status.Code != rpc.Code_CODE_NOT_FOUND {
-				if sRes.Status.Code == rpc.Code_CODE_PERMISSION_DENIED {
-					// the token expired during upload, so the stat failed
-					// and we can't do anything about it.
-					// the clients will handle this gracefully by doing a propfind on the file
-					w.WriteHeader(http.StatusOK)
-					return
-				}
-
-				HandleErrorStatus(&log, w, sRes.Status)
-				return
-			}
-
-			info := sRes.Info
-			if info == nil {
-				log.Error().Msg("No info found for uploaded file")
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
+		// check if upload was fully completed, partial uploads are only written once concatenated
+		if concat == "" && (length == 0 || httpRes.Header.Get(HeaderUploadOffset) == r.Header.Get(HeaderUploadLength)) {
 			if httpRes != nil && httpRes.Header != nil && httpRes.Header.Get(HeaderOCMtime) != "" {
 				// set the "accepted" value if returned in the upload response headers
 				w.Header().Set(HeaderOCMtime, httpRes.Header.Get(HeaderOCMtime))
 			}
-
-			// get WebDav permissions for file
-			isPublic := false
-			if info.Opaque != nil && info.Opaque.Map != nil {
-				if info.Opaque.Map["link-share"] != nil && info.Opaque.Map["link-share"].Decoder == "json" {
-					ls := &link.PublicShare{}
-					_ = json.Unmarshal(info.Opaque.Map["link-share"].Value, ls)
-					isPublic = ls != nil
-				}
+			if !s.setUploadedFileHeaders(ctx, w, client, sReq, log) {
+				return
 			}
-			isShared := !isCurrentUserOwner(ctx, info.Owner)
-			role := conversions.RoleFromResourcePermissions(info.PermissionSet)
-			permissions := role.WebDAVPermissions(
-				info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER,
-				isShared,
-				false,
-				isPublic,
-				s.isOpenable(info.Path),
-			)
-
-			w.Header().Set(HeaderContentType, info.MimeType)
-			w.Header().Set(HeaderOCFileID, resourceid.OwnCloudResourceIDWrap(info.Id))
-			w.Header().Set(HeaderOCETag, info.Etag)
-			w.Header().Set(HeaderETag, info.Etag)
-			w.Header().Set(HeaderOCPermissions, permissions)
-
-			t := utils.TSToTime(info.Mtime).UTC()
-			lastModifiedString := t.Format(time.RFC1123Z)
-			w.Header().Set(HeaderLastModified, lastModifiedString)
 		}
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
+
+// setUploadedFileHeaders sets the headers describing the file written by a completed upload.
+// It returns false if the response has already been written.
+func (s *svc) setUploadedFileHeaders(ctx context.Context, w http.ResponseWriter, client gateway.GatewayAPIClient, sReq *provider.StatRequest, log zerolog.Logger) bool {
+	sRes, err := client.Stat(ctx, sReq)
+	if err != nil {
+		log.Error().Err(err).Msg("error sending grpc stat request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	if sRes.Status.Code != rpc.Code_CODE_OK && sRes.Status.Code != rpc.Code_CODE_NOT_FOUND {
+		if sRes.Status.Code == rpc.Code_CODE_PERMISSION_DENIED {
+			// the token expired during upload, so the stat failed
+			// and we can't do anything about it.
+			// the clients will handle this gracefully by doing a propfind on the file
+			w.WriteHeader(http.StatusOK)
+			return false
+		}
+
+		HandleErrorStatus(&log, w, sRes.Status)
+		return false
+	}
+
+	info := sRes.Info
+	if info == nil {
+		log.Error().Msg("No info found for uploaded file")
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	// get WebDav permissions for file
+	isPublic := false
+	if info.Opaque != nil && info.Opaque.Map != nil {
+		if info.Opaque.Map["link-share"] != nil && info.Opaque.Map["link-share"].Decoder == "json" {
+			ls := &link.PublicShare{}
+			_ = json.Unmarshal(info.Opaque.Map["link-share"].Value, ls)
+			isPublic = ls != nil
+		}
+	}
+	isShared := !isCurrentUserOwner(ctx, info.Owner)
+	role := conversions.RoleFromResourcePermissions(info.PermissionSet)
+	permissions := role.WebDAVPermissions(
+		info.Type == provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+		isShared,
+		false,
+		isPublic,
+		s.isOpenable(info.Path),
+	)
+
+	w.Header().Set(HeaderContentType, info.MimeType)
+	w.Header().Set(HeaderOCFileID, resourceid.OwnCloudResourceIDWrap(info.Id))
+	w.Header().Set(HeaderOCETag, info.Etag)
+	w.Header().Set(HeaderETag, info.Etag)
+	w.Header().Set(HeaderOCPermissions, permissions)
+
+	t := utils.TSToTime(info.Mtime).UTC()
+	lastModifiedString := t.Format(time.RFC1123Z)
+	w.Header().Set(HeaderLastModified, lastModifiedString)
+	return true
+}
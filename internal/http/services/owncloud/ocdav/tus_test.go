@@ -0,0 +1,175 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package ocdav
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/internal/grpc/services/storageprovider"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/localhome"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+)
+
+var tusUser = &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein", Idp: "cernbox.cern.ch"}, Username: "einstein"}
+
+func init() {
+	// a driver unable to concatenate uploads
+	registry.Register("noconcat", func(ctx context.Context, m map[string]interface{}) (storage.FS, error) {
+		fs, err := localhome.New(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		return struct{ storage.FS }{fs}, nil
+	})
+}
+
+// uploadGateway forwards the requests made by the tus handlers to a storage provider.
+type uploadGateway struct {
+	gateway.UnimplementedGatewayAPIServer
+	sp provider.ProviderAPIServer
+}
+
+func (g *uploadGateway) Stat(ctx context.Context, req *provider.StatRequest) (*provider.StatResponse, error) {
+	return g.sp.Stat(appctx.ContextSetUser(ctx, tusUser), req)
+}
+
+func (g *uploadGateway) InitiateFileUpload(ctx context.Context, req *provider.InitiateFileUploadRequest) (*gateway.InitiateFileUploadResponse, error) {
+	res, err := g.sp.InitiateFileUpload(appctx.ContextSetUser(ctx, tusUser), req)
+	if err != nil {
+		return nil, err
+	}
+	protocols := make([]*gateway.FileUploadProtocol, 0, len(res.Protocols))
+	for _, p := range res.Protocols {
+		protocols = append(protocols, &gateway.FileUploadProtocol{Protocol: p.Protocol, UploadEndpoint: p.UploadEndpoint})
+	}
+	return &gateway.InitiateFileUploadResponse{Status: res.Status, Protocols: protocols}, nil
+}
+
+// setupUploads serves a storage provider with the given driver behind
+// a gateway, and returns the ocdav service using it along with the driver.
+func setupUploads(t *testing.T, driver string) (*svc, storage.FS) {
+	ctx := appctx.ContextSetUser(context.Background(), tusUser)
+	drivers := map[string]interface{}{driver: map[string]interface{}{"root": t.TempDir()}}
+	sp, err := storageprovider.New(ctx, map[string]interface{}{
+		"driver":          driver,
+		"drivers":         drivers,
+		"data_server_url": "http://localhost/data",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := sp.(provider.ProviderAPIServer)
+	if res, err := api.CreateHome(ctx, &provider.CreateHomeRequest{}); err != nil || res.Status.Code != rpc.Code_CODE_OK {
+		t.Fatalf("error creating home: %v %v", res, err)
+	}
+	fs, err := registry.NewFuncs[driver](ctx, drivers[driver].(map[string]interface{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	gateway.RegisterGatewayAPIServer(srv, &uploadGateway{sp: api})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return &svc{c: &Config{GatewaySvc: lis.Addr().String()}}, fs
+}
+
+func tusPost(s *svc, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/remote.php/webdav/", nil)
+	r.Header.Set(HeaderTusResumable, "1.0.0")
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	ctx := appctx.ContextSetUser(r.Context(), tusUser)
+	w := httptest.NewRecorder()
+	s.handleTusPost(ctx, w, r, map[string]string{"filename": "file"}, &provider.Reference{Path: "/file"}, zerolog.Nop())
+	return w
+}
+
+func TestTusConcatenation(t *testing.T) {
+	s, fs := setupUploads(t, "localhome")
+	ctx := appctx.ContextSetUser(context.Background(), tusUser)
+
+	locations := []string{}
+	for _, part := range []string{"hello ", "parallel ", "world"} {
+		w := tusPost(s, map[string]string{HeaderUploadConcat: "partial", HeaderUploadLength: strconv.Itoa(len(part))})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("partial upload: expected %d, got %d", http.StatusCreated, w.Code)
+		}
+		location := w.Header().Get(HeaderLocation)
+		if err := fs.Upload(ctx, &provider.Reference{Path: path.Base(location)}, io.NopCloser(strings.NewReader(part)), nil); err != nil {
+			t.Fatal(err)
+		}
+		locations = append(locations, location)
+	}
+
+	// the final upload carries no length of its own
+	w := tusPost(s, map[string]string{HeaderUploadConcat: "final;" + strings.Join(locations, " ")})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("final upload: expected %d, got %d", http.StatusCreated, w.Code)
+	}
+	r, err := fs.Download(ctx, &provider.Reference{Path: "/file"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "hello parallel world" {
+		t.Errorf("expected the concatenated content, got %q", content)
+	}
+}
+
+func TestTusConcatenationNotSupported(t *testing.T) {
+	s, _ := setupUploads(t, "noconcat")
+
+	w := tusPost(s, map[string]string{HeaderUploadConcat: "partial", HeaderUploadLength: "5"})
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("expected %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+
+	// uploads without concatenation are still accepted
+	w = tusPost(s, map[string]string{HeaderUploadLength: "5"})
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected %d, got %d", http.StatusCreated, w.Code)
+	}
+}
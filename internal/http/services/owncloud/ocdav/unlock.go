@@ -19,10 +19,130 @@
 package ocdav
 
 import (
+	"context"
 	"net/http"
+	"path"
+	"strings"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/rs/zerolog"
 )
 
-// TODO(jfd): implement unlock.
-func (s *svc) handleUnlock(w http.ResponseWriter, r *http.Request, ns string) {
-	w.WriteHeader(http.StatusNotImplemented)
+func (s *svc) handlePathUnlock(w http.ResponseWriter, r *http.Request, ns string) {
+	ctx := r.Context()
+	fn := path.Join(ns, r.URL.Path)
+
+	sublog := appctx.GetLogger(ctx).With().Str("path", fn).Logger()
+	s.handleUnlock(ctx, w, r, &provider.Reference{Path: fn}, sublog)
+}
+
+func (s *svc) handleSpacesUnlock(w http.ResponseWriter, r *http.Request, spaceID string) {
+	ctx := r.Context()
+	sublog := appctx.GetLogger(ctx).With().Str("spaceid", spaceID).Str("path", r.URL.Path).Logger()
+
+	ref, status, err := s.lookUpStorageSpaceReference(ctx, spaceID, r.URL.Path)
+	if err != nil {
+		sublog.Error().Err(err).Msg("error sending a grpc request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if status.Code != rpc.Code_CODE_OK {
+		HandleErrorStatus(&sublog, w, status)
+		return
+	}
+
+	s.handleUnlock(ctx, w, r, ref, sublog)
+}
+
+// handleUnlock removes the lock identified by the Lock-Token header,
+// see http://www.webdav.org/specs/rfc4918.html#METHOD_UNLOCK
+func (s *svc) handleUnlock(ctx context.Context, w http.ResponseWriter, r *http.Request, ref *provider.Reference, log zerolog.Logger) {
+	token := strings.TrimSpace(r.Header.Get(HeaderLockToken))
+	if !strings.HasPrefix(token, "<"+lockTokenPrefix) || !strings.HasSuffix(token, ">") {
+		log.Debug().Str("lock-token", token).Msg("invalid Lock-Token header")
+		w.WriteHeader(http.StatusBadRequest)
+		b, err := Marshal(exception{
+			code:    SabredavBadRequest,
+			message: "Missing or invalid Lock-Token header",
+			header:  HeaderLockToken,
+		})
+		HandleWebdavError(&log, w, b, err)
+		return
+	}
+	lockID := strings.TrimSuffix(strings.TrimPrefix(token, "<"+lockTokenPrefix), ">")
+
+	client, err := s.getClient()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	u, ok := appctx.ContextGetUser(ctx)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	lRes, err := client.GetLock(ctx, &provider.GetLockRequest{Ref: ref})
+	if err != nil {
+		log.Error().Err(err).Msg("error sending grpc get lock request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	switch {
+	case lRes.Status.Code == rpc.Code_CODE_NOT_FOUND:
+		// either the resource or the lock does not exist
+		sRes, err := client.Stat(ctx, &provider.StatRequest{Ref: ref})
+		if err != nil {
+			log.Error().Err(err).Msg("error sending grpc stat request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if sRes.Status.Code != rpc.Code_CODE_OK {
+			HandleErrorStatus(&log, w, sRes.Status)
+			return
+		}
+		writeDavError(w, http.StatusConflict, "lock-token-matches-request-uri", log)
+		return
+	case lRes.Status.Code != rpc.Code_CODE_OK:
+		HandleErrorStatus(&log, w, lRes.Status)
+		return
+	case lRes.Lock.LockId != lockID || lockExpired(lRes.Lock):
+		writeDavError(w, http.StatusConflict, "lock-token-matches-request-uri", log)
+		return
+	}
+
+	res, err := client.Unlock(ctx, &provider.UnlockRequest{
+		Ref: ref,
+		Lock: &provider.Lock{
+			LockId:  lockID,
+			Type:    lRes.Lock.Type,
+			User:    u.Id,
+			AppName: lRes.Lock.AppName,
+		},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("error sending grpc unlock request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		if res.Status.Code == rpc.Code_CODE_FAILED_PRECONDITION {
+			// the lock is held by someone else
+			w.WriteHeader(http.StatusForbidden)
+			b, err := Marshal(exception{
+				code:    SabredavPermissionDenied,
+				message: "The lock is held by another user",
+			})
+			HandleWebdavError(&log, w, b, err)
+			return
+		}
+		HandleErrorStatus(&log, w, res.Status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -35,6 +35,7 @@ const (
 	MethodMove      = "MOVE"
 	MethodCopy      = "COPY"
 	MethodReport    = "REPORT"
+	MethodSearch    = "SEARCH"
 )
 
 // Common HTTP headers.
@@ -51,6 +52,9 @@ const (
 	HeaderLocation                   = "Location"
 	HeaderRange                      = "Range"
 	HeaderIfMatch                    = "If-Match"
+	HeaderIf                         = "If"
+	HeaderLockToken                  = "Lock-Token"
+	HeaderTimeout                    = "Timeout"
 	HeaderChecksum                   = "Digest"
 )
 
@@ -70,6 +74,7 @@ const (
 	HeaderDestination          = "Destination"
 	HeaderOverwrite            = "Overwrite"
 	HeaderUploadChecksum       = "Upload-Checksum"
+	HeaderUploadConcat         = "Upload-Concat"
 	HeaderUploadLength         = "Upload-Length"
 	HeaderUploadMetadata       = "Upload-Metadata"
 	HeaderUploadOffset         = "Upload-Offset"
@@ -100,9 +105,9 @@ func (h *WebDavHandler) Handler(s *svc) http.Handler {
 		case MethodPropfind:
 			s.handlePathPropfind(w, r, ns)
 		case MethodLock:
-			s.handleLock(w, r, ns)
+			s.handlePathLock(w, r, ns)
 		case MethodUnlock:
-			s.handleUnlock(w, r, ns)
+			s.handlePathUnlock(w, r, ns)
 		case MethodProppatch:
 			s.handlePathProppatch(w, r, ns)
 		case MethodMkcol:
@@ -113,6 +118,8 @@ func (h *WebDavHandler) Handler(s *svc) http.Handler {
 			s.handlePathCopy(w, r, ns)
 		case MethodReport:
 			s.handleReport(w, r, ns)
+		case MethodSearch:
+			s.handlePathSearch(w, r, ns)
 		case http.MethodGet:
 			s.handlePathGet(w, r, ns)
 		case http.MethodPut:
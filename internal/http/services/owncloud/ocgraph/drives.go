@@ -36,6 +36,7 @@ import (
 	rpcv1beta1 "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	collaborationv1beta1 "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	providerpb "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/spaces"
@@ -331,6 +332,66 @@ func (s *svc) getSpace(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotFound)
 }
 
+// deleteSpace disables the space, or purges it if the Purge header is set.
+// A space must be disabled before being purged.
+func (s *svc) deleteSpace(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	spaceID := chi.URLParam(r, "space-id")
+	spaceID, _ = url.QueryUnescape(spaceID)
+	if spaceID == "" || isShareJail(spaceID) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	gw, err := s.getClient()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	req := &providerpb.DeleteStorageSpaceRequest{
+		Id: &providerpb.StorageSpaceId{OpaqueId: spaceID},
+	}
+	if r.Header.Get("Purge") == "T" {
+		req.Opaque = &typesv1beta1.Opaque{
+			Map: map[string]*typesv1beta1.OpaqueEntry{
+				"purge": {
+					Decoder: "plain",
+					Value:   []byte("true"),
+				},
+			},
+		}
+	}
+
+	res, err := gw.DeleteStorageSpace(ctx, req)
+	if err != nil {
+		log.Error().Err(err).Msg("error deleting space")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	switch res.Status.Code {
+	case rpcv1beta1.Code_CODE_OK:
+		w.WriteHeader(http.StatusNoContent)
+	case rpcv1beta1.Code_CODE_NOT_FOUND:
+		w.WriteHeader(http.StatusNotFound)
+	case rpcv1beta1.Code_CODE_PERMISSION_DENIED:
+		w.WriteHeader(http.StatusForbidden)
+	case rpcv1beta1.Code_CODE_INVALID_ARGUMENT, rpcv1beta1.Code_CODE_FAILED_PRECONDITION:
+		w.WriteHeader(http.StatusBadRequest)
+	case rpcv1beta1.Code_CODE_LOCKED:
+		w.WriteHeader(http.StatusLocked)
+	case rpcv1beta1.Code_CODE_UNIMPLEMENTED:
+		w.WriteHeader(http.StatusNotImplemented)
+	default:
+		log.Error().Int("code", int(res.Status.Code)).Str("message", res.Status.Message).Msg("error deleting space")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 func isShareJail(spaceID string) bool {
 	return spaceID == SHARE_JAIL_ID
 }
@@ -28,6 +28,8 @@ import (
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp/global"
 	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/tags"
+	"github.com/cs3org/reva/pkg/tags/registry"
 	"github.com/cs3org/reva/pkg/utils/cfg"
 	"github.com/go-chi/chi/v5"
 )
@@ -40,15 +42,22 @@ type config struct {
 	GatewaySvc string `mapstructure:"gatewaysvc"  validate:"required"`
 	WebDavBase string `mapstructure:"webdav_base" validate:"required"`
 	WebBase    string `mapstructure:"web_base"    validate:"required"`
+
+	TagsManager  string                            `docs:"memory;The driver used to store the system tags. ocdav and ocgraph share the store when configured with the same driver, a persistent one is needed when they run in different processes." mapstructure:"tags_manager"`
+	TagsManagers map[string]map[string]interface{} `docs:"url:pkg/tags/sqlite/sqlite.go"                     mapstructure:"tags_managers"`
 }
 
 func (c *config) ApplyDefaults() {
 	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+	if c.TagsManager == "" {
+		c.TagsManager = "memory"
+	}
 }
 
 type svc struct {
-	c      *config
-	router *chi.Mux
+	c           *config
+	router      *chi.Mux
+	tagsManager tags.Manager
 }
 
 func New(ctx context.Context, m map[string]interface{}) (global.Service, error) {
@@ -57,8 +66,14 @@ func New(ctx context.Context, m map[string]interface{}) (global.Service, error)
 		return nil, err
 	}
 
+	tm, err := registry.GetManager(ctx, c.TagsManager, c.TagsManagers[c.TagsManager])
+	if err != nil {
+		return nil, err
+	}
+
 	s := &svc{
-		c: &c,
+		c:           &c,
+		tagsManager: tm,
 	}
 	s.initRouter()
 
@@ -74,6 +89,12 @@ func (s *svc) initRouter() {
 		})
 		r.Route("/drives", func(r chi.Router) {
 			r.Get("/{space-id}", s.getSpace)
+			r.Delete("/{space-id}", s.deleteSpace)
+		})
+		r.Route("/extensions/org.libregraph/tags", func(r chi.Router) {
+			r.Get("/", s.listTags)
+			r.Put("/", s.assignTags)
+			r.Delete("/", s.unassignTags)
 		})
 	})
 	s.router.Route("/v1beta1", func(r chi.Router) {
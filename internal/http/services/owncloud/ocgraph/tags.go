@@ -0,0 +1,148 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// This package implements the APIs defined in https://owncloud.dev/apis/http/graph/
+
+package ocgraph
+
+import (
+	"encoding/json"
+	"net/http"
+
+	providerpb "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/spaces"
+	"github.com/cs3org/reva/pkg/tags"
+	libregraph "github.com/owncloud/libre-graph-api-go"
+	"github.com/rs/zerolog"
+)
+
+// listTags returns the names of all the system tags.
+func (s *svc) listTags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	list, err := s.tagsManager.ListTags(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("error listing tags")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(&libregraph.CollectionOfTags{
+		Value: tags.Names(list),
+	}); err != nil {
+		log.Error().Err(err).Msg("error marshalling tags as json")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// assignTags attaches system tags to a resource.
+func (s *svc) assignTags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	var assignment libregraph.TagAssignment
+	if err := json.NewDecoder(r.Body).Decode(&assignment); err != nil {
+		log.Debug().Err(err).Msg("error decoding tag assignment")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	ref, ok := tagsResourceReference(assignment.ResourceId)
+	if !ok {
+		log.Debug().Str("resource-id", assignment.ResourceId).Msg("resource id cannot be decoded")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	gw, err := s.getClient()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tags.Assign(ctx, gw, s.tagsManager, ref, assignment.Tags); err != nil {
+		handleTagsError(w, err, log)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// unassignTags detaches system tags from a resource.
+func (s *svc) unassignTags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	var unassignment libregraph.TagUnassignment
+	if err := json.NewDecoder(r.Body).Decode(&unassignment); err != nil {
+		log.Debug().Err(err).Msg("error decoding tag unassignment")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	ref, ok := tagsResourceReference(unassignment.ResourceId)
+	if !ok {
+		log.Debug().Str("resource-id", unassignment.ResourceId).Msg("resource id cannot be decoded")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	gw, err := s.getClient()
+	if err != nil {
+		log.Error().Err(err).Msg("error getting grpc client")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := tags.Unassign(ctx, gw, s.tagsManager, ref, unassignment.Tags); err != nil {
+		handleTagsError(w, err, log)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func tagsResourceReference(resourceID string) (*providerpb.Reference, bool) {
+	storageID, _, itemID, ok := spaces.DecodeResourceID(resourceID)
+	if !ok {
+		return nil, false
+	}
+	return &providerpb.Reference{
+		ResourceId: &providerpb.ResourceId{
+			StorageId: storageID,
+			OpaqueId:  itemID,
+		},
+	}, true
+}
+
+func handleTagsError(w http.ResponseWriter, err error, log *zerolog.Logger) {
+	switch err.(type) {
+	case errtypes.IsNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case errtypes.IsPermissionDenied:
+		w.WriteHeader(http.StatusForbidden)
+	case errtypes.IsBadRequest:
+		w.WriteHeader(http.StatusBadRequest)
+	case errtypes.IsLocked:
+		w.WriteHeader(http.StatusLocked)
+	default:
+		log.Error().Err(err).Msg("error updating tags")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -56,3 +56,24 @@ func TestMarshal(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestTusCapabilities(t *testing.T) {
+	c := &data.Capabilities{
+		Files: &data.CapabilitiesFiles{},
+		Dav:   &data.CapabilitiesDav{Chunking: "1.0"},
+	}
+	setCapabilitiesForChunkProtocol(chunkTUS, c)
+
+	if c.Files.BigFileChunking || c.Dav.Chunking != "" {
+		t.Fatalf("chunking should be disabled for tus clients, got %+v %+v", c.Files, c.Dav)
+	}
+	if c.Files.TusSupport == nil || c.Files.TusSupport.Version != "1.0.0" || c.Files.TusSupport.Extension != tusExtensions {
+		t.Fatalf("unexpected tus support %+v", c.Files.TusSupport)
+	}
+
+	c.Files.TusSupport = &data.CapabilitiesFilesTusSupport{Extension: "creation", MaxChunkSize: 1024}
+	setCapabilitiesForChunkProtocol(chunkTUS, c)
+	if c.Files.TusSupport.Extension != "creation" || c.Files.TusSupport.MaxChunkSize != 1024 {
+		t.Fatalf("the configured tus support should be kept, got %+v", c.Files.TusSupport)
+	}
+}
@@ -33,6 +33,12 @@ var (
 	chunkTUS chunkProtocol = "tus"
 )
 
+// tusExtensions are the TUS extensions supported by the ocdav service for
+// all the storages. The concatenation, allowing clients to upload the parts
+// of a file in parallel, is only supported by some of them: it can be added
+// to the configured extensions when all the storages support it.
+const tusExtensions = "creation,creation-with-upload,checksum,expiration"
+
 func (h *Handler) getCapabilitiesForUserAgent(_ context.Context, userAgent string) data.CapabilitiesData {
 	// Creating a copy of the capabilities struct is less expensive than taking a lock
 	c := *h.c.Capabilities
@@ -69,12 +75,17 @@ func setCapabilitiesForChunkProtocol(cp chunkProtocol, c *data.Capabilities) {
 
 		// TODO: infer from various TUS handlers from all known storages
 		// until now we take the manually configured tus options
-		// c.Capabilities.Files.TusSupport = &data.CapabilitiesFilesTusSupport{
-		// 	Version:            "1.0.0",
-		// 	Resumable:          "1.0.0",
-		// 	Extension:          "creation,creation-with-upload",
-		// 	MaxChunkSize:       0,
-		// 	HTTPMethodOverride: "",
-		// }
+		if c.Files.TusSupport == nil {
+			c.Files.TusSupport = &data.CapabilitiesFilesTusSupport{}
+		}
+		if c.Files.TusSupport.Version == "" {
+			c.Files.TusSupport.Version = "1.0.0"
+		}
+		if c.Files.TusSupport.Resumable == "" {
+			c.Files.TusSupport.Resumable = "1.0.0"
+		}
+		if c.Files.TusSupport.Extension == "" {
+			c.Files.TusSupport.Extension = tusExtensions
+		}
 	}
 }
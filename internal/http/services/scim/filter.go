@@ -0,0 +1,445 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// filter is a parsed SCIM filter, evaluated against
+// the JSON representation of a resource.
+// See https://www.rfc-editor.org/rfc/rfc7644#section-3.4.2.2
+type filter interface {
+	match(resource map[string]any) bool
+}
+
+type logicalFilter struct {
+	and         bool
+	left, right filter
+}
+
+func (f *logicalFilter) match(r map[string]any) bool {
+	if f.and {
+		return f.left.match(r) && f.right.match(r)
+	}
+	return f.left.match(r) || f.right.match(r)
+}
+
+type notFilter struct {
+	filter filter
+}
+
+func (f *notFilter) match(r map[string]any) bool {
+	return !f.filter.match(r)
+}
+
+// attrPath is the path of an attribute, as in urn:attribute.subAttribute.
+type attrPath struct {
+	urn  string
+	attr string
+	sub  string
+}
+
+// parseAttrPath parses the path p of an attribute of a resource with the given schemas,
+// the first one being the core schema. The attributes of the core schema are not
+// namespaced in the JSON representation of the resource, while the extension
+// ones are held by an object named after the schema.
+func parseAttrPath(p string, schemas []string) attrPath {
+	var a attrPath
+	for i, schema := range schemas {
+		if len(p) < len(schema) || !strings.EqualFold(p[:len(schema)], schema) {
+			continue
+		}
+		rest := p[len(schema):]
+		if rest == "" {
+			// the whole extension object
+			return attrPath{attr: schema}
+		}
+		if rest[0] != ':' {
+			continue
+		}
+		if i > 0 {
+			a.urn = schema
+		}
+		p = rest[1:]
+		break
+	}
+	a.attr, a.sub, _ = strings.Cut(p, ".")
+	return a
+}
+
+// values returns the values of the attribute in r. The values of a multi-valued
+// attribute are flattened, using the "value" sub-attribute of the complex ones.
+func (a attrPath) values(r map[string]any) []any {
+	if a.urn != "" {
+		ext, ok := lookup(r, a.urn).(map[string]any)
+		if !ok {
+			return nil
+		}
+		r = ext
+	}
+
+	var values []any
+	add := func(v any) {
+		if m, ok := v.(map[string]any); ok {
+			sub := a.sub
+			if sub == "" {
+				sub = "value"
+			}
+			v = lookup(m, sub)
+		} else if a.sub != "" {
+			return
+		}
+		if v != nil {
+			values = append(values, v)
+		}
+	}
+	switch v := lookup(r, a.attr).(type) {
+	case []any:
+		for _, e := range v {
+			add(e)
+		}
+	case nil:
+	default:
+		add(v)
+	}
+	return values
+}
+
+// lookup returns the value of the attribute of m, as
+// the names of the attributes are case insensitive.
+func lookup(m map[string]any, attr string) any {
+	if v, ok := m[attr]; ok {
+		return v
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, attr) {
+			return v
+		}
+	}
+	return nil
+}
+
+type presentFilter struct {
+	path attrPath
+}
+
+func (f *presentFilter) match(r map[string]any) bool {
+	for _, v := range f.path.values(r) {
+		if s, ok := v.(string); !ok || s != "" {
+			return true
+		}
+	}
+	return false
+}
+
+type compareFilter struct {
+	path  attrPath
+	op    string
+	value any
+}
+
+func (f *compareFilter) match(r map[string]any) bool {
+	values := f.path.values(r)
+	if f.op == "ne" {
+		for _, v := range values {
+			if compare(v, "eq", f.value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range values {
+		if compare(v, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(v any, op string, value any) bool {
+	switch v := v.(type) {
+	case string:
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		v, s = strings.ToLower(v), strings.ToLower(s)
+		switch op {
+		case "eq":
+			return v == s
+		case "co":
+			return strings.Contains(v, s)
+		case "sw":
+			return strings.HasPrefix(v, s)
+		case "ew":
+			return strings.HasSuffix(v, s)
+		case "gt":
+			return v > s
+		case "ge":
+			return v >= s
+		case "lt":
+			return v < s
+		case "le":
+			return v <= s
+		}
+	case float64:
+		n, ok := value.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return v == n
+		case "gt":
+			return v > n
+		case "ge":
+			return v >= n
+		case "lt":
+			return v < n
+		case "le":
+			return v <= n
+		}
+	case bool:
+		b, ok := value.(bool)
+		return ok && op == "eq" && v == b
+	}
+	return false
+}
+
+// valueFilter matches the resources having an element of
+// the multi-valued attribute matching the filter, as in emails[type eq "work"].
+type valueFilter struct {
+	path   attrPath
+	filter filter
+}
+
+func (f *valueFilter) match(r map[string]any) bool {
+	for _, e := range elements(r, f.path) {
+		if f.filter.match(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// elements returns the complex values of the multi-valued attribute in r.
+func elements(r map[string]any, a attrPath) []map[string]any {
+	if a.urn != "" {
+		ext, ok := lookup(r, a.urn).(map[string]any)
+		if !ok {
+			return nil
+		}
+		r = ext
+	}
+	list, _ := lookup(r, a.attr).([]any)
+	var elems []map[string]any
+	for _, e := range list {
+		if m, ok := e.(map[string]any); ok {
+			elems = append(elems, m)
+		}
+	}
+	return elems
+}
+
+var operators = map[string]bool{"eq": true, "ne": true, "co": true, "sw": true, "ew": true, "gt": true, "ge": true, "lt": true, "le": true}
+
+type filterParser struct {
+	tokens  []string
+	pos     int
+	schemas []string
+}
+
+// parseFilter parses the SCIM filter s, for the resources with the given schemas.
+func parseFilter(s string, schemas []string) (filter, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens, schemas: schemas}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in filter", p.tokens[p.pos])
+	}
+	return f, nil
+}
+
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string in filter")
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for ; j < len(s) && !strings.ContainsRune(" \t()[]\"", rune(s[j])); j++ {
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) next() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", fmt.Errorf("unexpected end of filter")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *filterParser) expect(token string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t != token {
+		return fmt.Errorf("expected %q in filter, got %q", token, t)
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "and") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filter, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case t == "(":
+		return p.parseGroup()
+	case strings.EqualFold(t, "not"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		f, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		return &notFilter{filter: f}, nil
+	case strings.ContainsAny(t, "\"()[]"):
+		return nil, fmt.Errorf("expected attribute in filter, got %q", t)
+	}
+
+	path := parseAttrPath(t, p.schemas)
+	if p.peek() == "[" {
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &valueFilter{path: path, filter: f}, nil
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	op = strings.ToLower(op)
+	if op == "pr" {
+		return &presentFilter{path: path}, nil
+	}
+	if !operators[op] {
+		return nil, fmt.Errorf("unknown operator %q in filter", op)
+	}
+
+	v, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal([]byte(v), &value); err != nil {
+		return nil, fmt.Errorf("invalid value %s in filter", v)
+	}
+	return &compareFilter{path: path, op: op, value: value}, nil
+}
+
+func (p *filterParser) parseGroup() (filter, error) {
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
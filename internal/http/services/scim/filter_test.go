@@ -0,0 +1,87 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scim
+
+import (
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	einstein := map[string]any{
+		"id":          "4c510ada",
+		"userName":    "einstein",
+		"displayName": "Albert Einstein",
+		"emails":      []any{map[string]any{"value": "einstein@example.org", "type": "work", "primary": true}},
+		"groups":      []any{map[string]any{"value": "sailing-lovers"}, map[string]any{"value": "physics-lovers"}},
+		"active":      true,
+		userExtSchema: map[string]any{"uidNumber": float64(123)},
+	}
+
+	tests := []struct {
+		description string
+		filter      string
+		expected    bool
+		err         bool
+	}{
+		{description: "equal", filter: `userName eq "einstein"`, expected: true},
+		{description: "case insensitive attribute and value", filter: `USERNAME Eq "Einstein"`, expected: true},
+		{description: "not equal", filter: `userName ne "einstein"`, expected: false},
+		{description: "contains", filter: `displayName co "ein"`, expected: true},
+		{description: "starts with", filter: `displayName sw "Albert"`, expected: true},
+		{description: "ends with", filter: `displayName ew "Curie"`, expected: false},
+		{description: "present", filter: `emails pr`, expected: true},
+		{description: "missing attribute", filter: `title pr`, expected: false},
+		{description: "multi-valued complex attribute", filter: `emails eq "einstein@example.org"`, expected: true},
+		{description: "sub-attribute", filter: `groups.value eq "physics-lovers"`, expected: true},
+		{description: "value filter", filter: `emails[type eq "work" and value co "example"]`, expected: true},
+		{description: "value filter not matching", filter: `emails[type eq "home"]`, expected: false},
+		{description: "boolean", filter: `active eq true`, expected: true},
+		{description: "core schema URN", filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName eq "einstein"`, expected: true},
+		{description: "extension attribute", filter: `urn:ietf:params:scim:schemas:extension:reva:2.0:User:uidNumber gt 100`, expected: true},
+		{description: "and", filter: `userName eq "einstein" and displayName eq "Marie Curie"`, expected: false},
+		{description: "or", filter: `userName eq "marie" or displayName co "einstein"`, expected: true},
+		{description: "precedence of and over or", filter: `userName eq "einstein" or userName eq "marie" and active eq false`, expected: true},
+		{description: "parentheses", filter: `(userName eq "einstein" or userName eq "marie") and active eq false`, expected: false},
+		{description: "not", filter: `not (userName eq "marie")`, expected: true},
+		{description: "escaped quote", filter: `displayName eq "Albert \"Einstein"`, expected: false},
+		{description: "unknown operator", filter: `userName is "einstein"`, err: true},
+		{description: "missing value", filter: `userName eq`, err: true},
+		{description: "unterminated string", filter: `userName eq "einstein`, err: true},
+		{description: "unbalanced parentheses", filter: `(userName eq "einstein"`, err: true},
+		{description: "trailing tokens", filter: `userName eq "einstein" "marie"`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			f, err := parseFilter(tt.filter, userSchemas)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.match(einstein); got != tt.expected {
+				t.Fatalf("got %t, expected %t", got, tt.expected)
+			}
+		})
+	}
+}
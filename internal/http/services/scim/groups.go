@@ -0,0 +1,219 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scim
+
+import (
+	"context"
+	"net/http"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var groupSchemas = []string{groupSchema, groupExtSchema}
+
+// lookupGroup returns the provisioned group with the given SCIM id.
+func (s *svc) lookupGroup(ctx context.Context, id string) (*grouppb.Group, *scimError) {
+	g, err := s.groups.GetGroup(ctx, &grouppb.GroupId{Idp: s.conf.IDP, OpaqueId: id}, false)
+	if err == nil && g.Id.OpaqueId != id {
+		// the group managers also look up the groups by name
+		err = errtypes.NotFound(id)
+	}
+	if err != nil {
+		return nil, toSCIMError(err, "error getting group")
+	}
+	return g, nil
+}
+
+// resourceToGroup returns the group described by r, with the given id.
+// The members must be provisioned users.
+func (s *svc) resourceToGroup(ctx context.Context, r *groupResource, id string) (*grouppb.Group, *scimError) {
+	if r.DisplayName == "" {
+		return nil, invalidValue("displayName is required")
+	}
+	g := &grouppb.Group{
+		Id:          &grouppb.GroupId{Idp: s.conf.IDP, OpaqueId: id},
+		GroupName:   r.DisplayName,
+		DisplayName: r.DisplayName,
+		Members:     []*userpb.UserId{},
+	}
+	if r.Extension != nil {
+		g.GidNumber = r.Extension.GIDNumber
+		g.Mail = r.Extension.Mail
+	}
+
+	seen := map[string]bool{}
+	for _, m := range r.Members {
+		if seen[m.Value] {
+			continue
+		}
+		seen[m.Value] = true
+		u, e := s.lookupUser(ctx, m.Value)
+		if e != nil {
+			if e.status == http.StatusNotFound {
+				return nil, invalidValue("unknown member " + m.Value + ", only users can be members")
+			}
+			return nil, e
+		}
+		g.Members = append(g.Members, u.Id)
+	}
+	return g, nil
+}
+
+func (s *svc) listGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := s.groups.FindGroups(r.Context(), "", false)
+	if err != nil {
+		writeError(w, r, toSCIMError(err, "error listing groups"))
+		return
+	}
+
+	resources := []map[string]any{}
+	for _, g := range groups {
+		if g.Id.Idp != s.conf.IDP {
+			continue
+		}
+		m, err := toMap(s.groupToResource(g))
+		if err != nil {
+			writeError(w, r, toSCIMError(err, "error encoding group"))
+			return
+		}
+		resources = append(resources, m)
+	}
+	writeList(w, r, resources, groupSchemas, "displayName")
+}
+
+func (s *svc) getGroup(w http.ResponseWriter, r *http.Request) {
+	g, e := s.lookupGroup(r.Context(), chi.URLParam(r, "id"))
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, s.groupToResource(g))
+}
+
+func (s *svc) createGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var res groupResource
+	if e := readJSON(w, r, &res); e != nil {
+		writeError(w, r, e)
+		return
+	}
+	g, e := s.resourceToGroup(ctx, &res, uuid.New().String())
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	created, err := s.groups.CreateGroup(ctx, g)
+	if err != nil {
+		writeError(w, r, toSCIMError(err, "error creating group"))
+		return
+	}
+	w.Header().Set("Location", s.location("Groups", created.Id.OpaqueId))
+	writeJSON(w, r, http.StatusCreated, s.groupToResource(created))
+}
+
+func (s *svc) replaceGroup(w http.ResponseWriter, r *http.Request) {
+	existing, e := s.lookupGroup(r.Context(), chi.URLParam(r, "id"))
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	var res groupResource
+	if e := readJSON(w, r, &res); e != nil {
+		writeError(w, r, e)
+		return
+	}
+	s.updateGroup(w, r, existing, &res)
+}
+
+func (s *svc) patchGroup(w http.ResponseWriter, r *http.Request) {
+	existing, e := s.lookupGroup(r.Context(), chi.URLParam(r, "id"))
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	var req patchRequest
+	if e := readJSON(w, r, &req); e != nil {
+		writeError(w, r, e)
+		return
+	}
+	m, err := toMap(s.groupToResource(existing))
+	if err != nil {
+		writeError(w, r, toSCIMError(err, "error encoding group"))
+		return
+	}
+	if e := applyPatch(m, req.Operations, groupSchemas); e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	var patched groupResource
+	if err := fromMap(m, &patched); err != nil {
+		writeError(w, r, invalidValue("invalid patched group: "+err.Error()))
+		return
+	}
+	s.updateGroup(w, r, existing, &patched)
+}
+
+// updateGroup replaces the attributes and the members of the existing group with the ones of res.
+func (s *svc) updateGroup(w http.ResponseWriter, r *http.Request, existing *grouppb.Group, res *groupResource) {
+	ctx := r.Context()
+	g, e := s.resourceToGroup(ctx, res, existing.Id.OpaqueId)
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+	// the group name is used in the ACLs, so only
+	// the display name is changed by the clients
+	g.GroupName = existing.GroupName
+	if g.GidNumber == 0 {
+		g.GidNumber = existing.GidNumber
+	}
+
+	if err := s.groups.UpdateGroup(ctx, g); err != nil {
+		writeError(w, r, toSCIMError(err, "error updating group"))
+		return
+	}
+	updated, e := s.lookupGroup(ctx, g.Id.OpaqueId)
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, s.groupToResource(updated))
+}
+
+func (s *svc) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	g, e := s.lookupGroup(ctx, chi.URLParam(r, "id"))
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+	if err := s.groups.DeleteGroup(ctx, g.Id); err != nil {
+		writeError(w, r, toSCIMError(err, "error deleting group"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
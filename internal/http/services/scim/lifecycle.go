@@ -0,0 +1,255 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scim
+
+import (
+	"context"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// lifecycle performs the operations following
+// the provisioning and the deprovisioning of the users.
+type lifecycle interface {
+	// userCreated is called once the user u is created.
+	userCreated(ctx context.Context, u *userpb.User) error
+	// userDeleting is called before the user u is deleted,
+	// which is aborted if an error is returned.
+	userDeleting(ctx context.Context, u *userpb.User) error
+}
+
+// gatewayLifecycle creates the homes of the new users and removes
+// the shares of the deleted ones through the gateway, on their behalf.
+// The shares received by the deleted users are removed on behalf
+// of their creators, while the remote ones are rejected.
+type gatewayLifecycle struct {
+	gatewayClient gateway.GatewayAPIClient
+	machineSecret string
+}
+
+// impersonate returns a context authenticated as the given user,
+// using the machine authentication.
+func (l *gatewayLifecycle) impersonate(ctx context.Context, u *userpb.User) (context.Context, error) {
+	authRes, err := l.gatewayClient.Authenticate(ctx, &gateway.AuthenticateRequest{
+		Type:         "machine",
+		ClientId:     u.Username,
+		ClientSecret: l.machineSecret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if authRes.Status.Code != rpc.Code_CODE_OK {
+		return nil, errors.New(authRes.Status.Message)
+	}
+
+	ctx = appctx.ContextSetToken(ctx, authRes.Token)
+	ctx = appctx.ContextSetUser(ctx, authRes.User)
+	ctx = metadata.AppendToOutgoingContext(ctx, appctx.TokenHeader, authRes.Token)
+	return ctx, nil
+}
+
+func (l *gatewayLifecycle) userCreated(ctx context.Context, u *userpb.User) error {
+	ctx, err := l.impersonate(ctx, u)
+	if err != nil {
+		return errors.Wrap(err, "error impersonating user")
+	}
+	res, err := l.gatewayClient.CreateHome(ctx, &provider.CreateHomeRequest{})
+	if err != nil {
+		return errors.Wrap(err, "error creating home")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return errors.New("error creating home: " + res.Status.Message)
+	}
+	return nil
+}
+
+func (l *gatewayLifecycle) userDeleting(ctx context.Context, u *userpb.User) error {
+	userCtx, err := l.impersonate(ctx, u)
+	if err != nil {
+		return errors.Wrap(err, "error impersonating user")
+	}
+	if err := l.removeShares(userCtx); err != nil {
+		return err
+	}
+	if err := l.removePublicShares(userCtx); err != nil {
+		return err
+	}
+	if err := l.removeOCMShares(userCtx); err != nil {
+		return err
+	}
+	if err := l.removeReceivedShares(ctx, userCtx, u); err != nil {
+		return err
+	}
+	return l.rejectReceivedOCMShares(userCtx)
+}
+
+func (l *gatewayLifecycle) removeShares(ctx context.Context) error {
+	res, err := l.gatewayClient.ListShares(ctx, &collaboration.ListSharesRequest{})
+	if err != nil {
+		return errors.Wrap(err, "error listing shares")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return errors.New("error listing shares: " + res.Status.Message)
+	}
+	for _, s := range res.Shares {
+		res, err := l.gatewayClient.RemoveShare(ctx, &collaboration.RemoveShareRequest{
+			Ref: &collaboration.ShareReference{Spec: &collaboration.ShareReference_Id{Id: s.Id}},
+		})
+		if err != nil {
+			return errors.Wrap(err, "error removing share")
+		}
+		if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+			return errors.New("error removing share: " + res.Status.Message)
+		}
+	}
+	return nil
+}
+
+func (l *gatewayLifecycle) removePublicShares(ctx context.Context) error {
+	res, err := l.gatewayClient.ListPublicShares(ctx, &link.ListPublicSharesRequest{})
+	if err != nil {
+		return errors.Wrap(err, "error listing public shares")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return errors.New("error listing public shares: " + res.Status.Message)
+	}
+	for _, s := range res.Share {
+		res, err := l.gatewayClient.RemovePublicShare(ctx, &link.RemovePublicShareRequest{
+			Ref: &link.PublicShareReference{Spec: &link.PublicShareReference_Id{Id: s.Id}},
+		})
+		if err != nil {
+			return errors.Wrap(err, "error removing public share")
+		}
+		if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+			return errors.New("error removing public share: " + res.Status.Message)
+		}
+	}
+	return nil
+}
+
+func (l *gatewayLifecycle) removeOCMShares(ctx context.Context) error {
+	res, err := l.gatewayClient.ListOCMShares(ctx, &ocm.ListOCMSharesRequest{})
+	if err != nil {
+		return errors.Wrap(err, "error listing ocm shares")
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_UNIMPLEMENTED:
+		// OCM is not enabled
+		return nil
+	default:
+		return errors.New("error listing ocm shares: " + res.Status.Message)
+	}
+	for _, s := range res.Shares {
+		res, err := l.gatewayClient.RemoveOCMShare(ctx, &ocm.RemoveOCMShareRequest{
+			Ref: &ocm.ShareReference{Spec: &ocm.ShareReference_Id{Id: s.Id}},
+		})
+		if err != nil {
+			return errors.Wrap(err, "error removing ocm share")
+		}
+		if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+			return errors.New("error removing ocm share: " + res.Status.Message)
+		}
+	}
+	return nil
+}
+
+// removeReceivedShares removes the shares granted to the user u,
+// listed in userCtx, on behalf of their creators.
+// The shares with the groups of the user are kept.
+func (l *gatewayLifecycle) removeReceivedShares(ctx, userCtx context.Context, u *userpb.User) error {
+	res, err := l.gatewayClient.ListReceivedShares(userCtx, &collaboration.ListReceivedSharesRequest{})
+	if err != nil {
+		return errors.Wrap(err, "error listing received shares")
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return errors.New("error listing received shares: " + res.Status.Message)
+	}
+	for _, rs := range res.Shares {
+		s := rs.Share
+		if !utils.UserEqual(s.Grantee.GetUserId(), u.Id) {
+			continue
+		}
+		creatorRes, err := l.gatewayClient.GetUser(userCtx, &userpb.GetUserRequest{UserId: s.Creator, SkipFetchingUserGroups: true})
+		if err != nil {
+			return errors.Wrap(err, "error getting share creator")
+		}
+		if creatorRes.Status.Code != rpc.Code_CODE_OK {
+			return errors.New("error getting share creator: " + creatorRes.Status.Message)
+		}
+		creatorCtx, err := l.impersonate(ctx, creatorRes.User)
+		if err != nil {
+			return errors.Wrap(err, "error impersonating share creator")
+		}
+		res, err := l.gatewayClient.RemoveShare(creatorCtx, &collaboration.RemoveShareRequest{
+			Ref: &collaboration.ShareReference{Spec: &collaboration.ShareReference_Id{Id: s.Id}},
+		})
+		if err != nil {
+			return errors.Wrap(err, "error removing received share")
+		}
+		if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+			return errors.New("error removing received share: " + res.Status.Message)
+		}
+	}
+	return nil
+}
+
+// rejectReceivedOCMShares rejects the shares received from
+// the remote users, which cannot be removed locally.
+func (l *gatewayLifecycle) rejectReceivedOCMShares(ctx context.Context) error {
+	res, err := l.gatewayClient.ListReceivedOCMShares(ctx, &ocm.ListReceivedOCMSharesRequest{})
+	if err != nil {
+		return errors.Wrap(err, "error listing received ocm shares")
+	}
+	switch res.Status.Code {
+	case rpc.Code_CODE_OK:
+	case rpc.Code_CODE_UNIMPLEMENTED:
+		// OCM is not enabled
+		return nil
+	default:
+		return errors.New("error listing received ocm shares: " + res.Status.Message)
+	}
+	for _, s := range res.Shares {
+		if s.State == ocm.ShareState_SHARE_STATE_REJECTED {
+			continue
+		}
+		res, err := l.gatewayClient.UpdateReceivedOCMShare(ctx, &ocm.UpdateReceivedOCMShareRequest{
+			Share:      &ocm.ReceivedShare{Id: s.Id, State: ocm.ShareState_SHARE_STATE_REJECTED},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"state"}},
+		})
+		if err != nil {
+			return errors.Wrap(err, "error rejecting received ocm share")
+		}
+		if res.Status.Code != rpc.Code_CODE_OK && res.Status.Code != rpc.Code_CODE_NOT_FOUND {
+			return errors.New("error rejecting received ocm share: " + res.Status.Message)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scim
+
+import (
+	"context"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// lifecycleGateway authenticates the users with their username as token
+// and records the shares removed and rejected, along with the token used.
+type lifecycleGateway struct {
+	gateway.GatewayAPIClient
+	users    map[string]*userpb.User
+	received []*collaboration.ReceivedShare
+	removed  map[string]string
+	rejected []string
+}
+
+func statusOK() *rpc.Status { return &rpc.Status{Code: rpc.Code_CODE_OK} }
+
+func token(ctx context.Context) string {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if t := md.Get(appctx.TokenHeader); len(t) == 1 {
+		return t[0]
+	}
+	return ""
+}
+
+func (g *lifecycleGateway) Authenticate(_ context.Context, req *gateway.AuthenticateRequest, _ ...grpc.CallOption) (*gateway.AuthenticateResponse, error) {
+	return &gateway.AuthenticateResponse{Status: statusOK(), Token: req.ClientId, User: g.users[req.ClientId]}, nil
+}
+
+func (g *lifecycleGateway) GetUser(_ context.Context, req *userpb.GetUserRequest, _ ...grpc.CallOption) (*userpb.GetUserResponse, error) {
+	for _, u := range g.users {
+		if u.Id.OpaqueId == req.UserId.OpaqueId {
+			return &userpb.GetUserResponse{Status: statusOK(), User: u}, nil
+		}
+	}
+	return &userpb.GetUserResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+}
+
+func (g *lifecycleGateway) ListShares(context.Context, *collaboration.ListSharesRequest, ...grpc.CallOption) (*collaboration.ListSharesResponse, error) {
+	return &collaboration.ListSharesResponse{Status: statusOK()}, nil
+}
+
+func (g *lifecycleGateway) ListPublicShares(context.Context, *link.ListPublicSharesRequest, ...grpc.CallOption) (*link.ListPublicSharesResponse, error) {
+	return &link.ListPublicSharesResponse{Status: statusOK()}, nil
+}
+
+func (g *lifecycleGateway) ListOCMShares(context.Context, *ocm.ListOCMSharesRequest, ...grpc.CallOption) (*ocm.ListOCMSharesResponse, error) {
+	return &ocm.ListOCMSharesResponse{Status: statusOK()}, nil
+}
+
+func (g *lifecycleGateway) ListReceivedShares(context.Context, *collaboration.ListReceivedSharesRequest, ...grpc.CallOption) (*collaboration.ListReceivedSharesResponse, error) {
+	return &collaboration.ListReceivedSharesResponse{Status: statusOK(), Shares: g.received}, nil
+}
+
+func (g *lifecycleGateway) RemoveShare(ctx context.Context, req *collaboration.RemoveShareRequest, _ ...grpc.CallOption) (*collaboration.RemoveShareResponse, error) {
+	g.removed[req.Ref.GetId().OpaqueId] = token(ctx)
+	return &collaboration.RemoveShareResponse{Status: statusOK()}, nil
+}
+
+func (g *lifecycleGateway) ListReceivedOCMShares(context.Context, *ocm.ListReceivedOCMSharesRequest, ...grpc.CallOption) (*ocm.ListReceivedOCMSharesResponse, error) {
+	return &ocm.ListReceivedOCMSharesResponse{Status: statusOK(), Shares: []*ocm.ReceivedShare{
+		{Id: &ocm.ShareId{OpaqueId: "remote"}, State: ocm.ShareState_SHARE_STATE_ACCEPTED},
+		{Id: &ocm.ShareId{OpaqueId: "remote-rejected"}, State: ocm.ShareState_SHARE_STATE_REJECTED},
+	}}, nil
+}
+
+func (g *lifecycleGateway) UpdateReceivedOCMShare(_ context.Context, req *ocm.UpdateReceivedOCMShareRequest, _ ...grpc.CallOption) (*ocm.UpdateReceivedOCMShareResponse, error) {
+	if req.Share.State == ocm.ShareState_SHARE_STATE_REJECTED {
+		g.rejected = append(g.rejected, req.Share.Id.OpaqueId)
+	}
+	return &ocm.UpdateReceivedOCMShareResponse{Status: statusOK()}, nil
+}
+
+func TestUserDeleting(t *testing.T) {
+	einstein := &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "einstein-id"}, Username: "einstein"}
+	marie := &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "marie-id"}, Username: "marie"}
+	received := func(id string, grantee *provider.Grantee) *collaboration.ReceivedShare {
+		return &collaboration.ReceivedShare{Share: &collaboration.Share{
+			Id:      &collaboration.ShareId{OpaqueId: id},
+			Creator: marie.Id,
+			Grantee: grantee,
+		}}
+	}
+	gw := &lifecycleGateway{
+		users: map[string]*userpb.User{"einstein": einstein, "marie": marie},
+		received: []*collaboration.ReceivedShare{
+			received("user-share", &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_USER, Id: &provider.Grantee_UserId{UserId: einstein.Id}}),
+			received("group-share", &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_GROUP}),
+		},
+		removed: map[string]string{},
+	}
+	l := &gatewayLifecycle{gatewayClient: gw, machineSecret: "secret"}
+
+	if err := l.userDeleting(context.Background(), einstein); err != nil {
+		t.Fatal(err)
+	}
+	if len(gw.removed) != 1 || gw.removed["user-share"] != "marie" {
+		t.Fatalf("expected the share with einstein to be removed by its creator, got %v", gw.removed)
+	}
+	if len(gw.rejected) != 1 || gw.rejected[0] != "remote" {
+		t.Fatalf("expected the accepted remote share to be rejected, got %v", gw.rejected)
+	}
+}
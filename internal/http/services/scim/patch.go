@@ -0,0 +1,234 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// applyPatch applies the operations of a PATCH request to the JSON representation r
+// of a resource with the given schemas.
+// See https://www.rfc-editor.org/rfc/rfc7644#section-3.5.2
+func applyPatch(r map[string]any, ops []patchOperation, schemas []string) *scimError {
+	if len(ops) > maxPatchOps {
+		return invalidValue("too many operations")
+	}
+	for _, op := range ops {
+		var value any
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return invalidValue("invalid value: " + err.Error())
+			}
+		}
+
+		var err *scimError
+		switch strings.ToLower(op.Op) {
+		case "add":
+			err = patchSet(r, op.Path, value, schemas, true)
+		case "replace":
+			err = patchSet(r, op.Path, value, schemas, false)
+		case "remove":
+			err = patchRemove(r, op.Path, schemas)
+		default:
+			err = invalidValue("unknown operation " + op.Op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchPath is the target of a patch operation, as in attr[filter].sub.
+type patchPath struct {
+	attrPath
+	filter filter
+}
+
+func parsePatchPath(p string, schemas []string) (*patchPath, *scimError) {
+	var pp patchPath
+	if i := strings.Index(p, "["); i >= 0 {
+		j := strings.LastIndex(p, "]")
+		if j < i {
+			return nil, &scimError{status: 400, scimType: "invalidPath", detail: "invalid path " + p}
+		}
+		f, err := parseFilter(p[i+1:j], nil)
+		if err != nil {
+			return nil, &scimError{status: 400, scimType: "invalidPath", detail: err.Error()}
+		}
+		pp.filter = f
+		pp.attrPath = parseAttrPath(p[:i], schemas)
+		if rest := p[j+1:]; rest != "" {
+			if !strings.HasPrefix(rest, ".") {
+				return nil, &scimError{status: 400, scimType: "invalidPath", detail: "invalid path " + p}
+			}
+			pp.sub = rest[1:]
+		}
+	} else {
+		pp.attrPath = parseAttrPath(p, schemas)
+	}
+	if pp.attr == "" {
+		return nil, &scimError{status: 400, scimType: "invalidPath", detail: "invalid path " + p}
+	}
+	return &pp, nil
+}
+
+// container returns the object holding the attribute of the path,
+// that is r itself or the extension object.
+func (p *patchPath) container(r map[string]any, create bool) map[string]any {
+	if p.urn == "" {
+		return r
+	}
+	key := keyOf(r, p.urn)
+	ext, ok := r[key].(map[string]any)
+	if !ok && create {
+		ext = map[string]any{}
+		r[key] = ext
+	}
+	return ext
+}
+
+// keyOf returns the key of the attribute in m, matched case insensitively.
+func keyOf(m map[string]any, attr string) string {
+	if _, ok := m[attr]; ok {
+		return attr
+	}
+	for k := range m {
+		if strings.EqualFold(k, attr) {
+			return k
+		}
+	}
+	return attr
+}
+
+func patchSet(r map[string]any, path string, value any, schemas []string, add bool) *scimError {
+	if path == "" {
+		attrs, ok := value.(map[string]any)
+		if !ok {
+			return invalidValue("the value of an operation without path must be an object")
+		}
+		for k, v := range attrs {
+			if err := patchSet(r, k, v, schemas, add); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	p, err := parsePatchPath(path, schemas)
+	if err != nil {
+		return err
+	}
+	c := p.container(r, true)
+	key := keyOf(c, p.attr)
+
+	if p.filter != nil {
+		matched := false
+		for _, e := range elements(c, attrPath{attr: key}) {
+			if !p.filter.match(e) {
+				continue
+			}
+			matched = true
+			if p.sub != "" {
+				e[keyOf(e, p.sub)] = value
+				continue
+			}
+			m, ok := value.(map[string]any)
+			if !ok {
+				return invalidValue("the value of " + path + " must be an object")
+			}
+			for k, v := range m {
+				e[keyOf(e, k)] = v
+			}
+		}
+		if !matched {
+			return &scimError{status: 400, scimType: "noTarget", detail: "no value matches " + path}
+		}
+		return nil
+	}
+
+	if p.sub != "" {
+		obj, ok := c[key].(map[string]any)
+		if !ok {
+			obj = map[string]any{}
+			c[key] = obj
+		}
+		obj[keyOf(obj, p.sub)] = value
+		return nil
+	}
+
+	existing, isList := c[key].([]any)
+	if values, ok := value.([]any); ok && isList && add {
+		c[key] = append(existing, values...)
+		return nil
+	}
+	if obj, ok := value.(map[string]any); ok {
+		if existing, ok := c[key].(map[string]any); ok && add {
+			for k, v := range obj {
+				existing[keyOf(existing, k)] = v
+			}
+			return nil
+		}
+	}
+	c[key] = value
+	return nil
+}
+
+func patchRemove(r map[string]any, path string, schemas []string) *scimError {
+	if path == "" {
+		return &scimError{status: 400, scimType: "noTarget", detail: "remove operations require a path"}
+	}
+	p, err := parsePatchPath(path, schemas)
+	if err != nil {
+		return err
+	}
+	c := p.container(r, false)
+	if c == nil {
+		return nil
+	}
+	key := keyOf(c, p.attr)
+
+	if p.filter != nil {
+		list, _ := c[key].([]any)
+		kept := []any{}
+		for _, e := range list {
+			m, ok := e.(map[string]any)
+			if !ok || !p.filter.match(m) {
+				kept = append(kept, e)
+				continue
+			}
+			if p.sub != "" {
+				delete(m, keyOf(m, p.sub))
+				kept = append(kept, m)
+			}
+		}
+		c[key] = kept
+		return nil
+	}
+
+	if p.sub != "" {
+		if obj, ok := c[key].(map[string]any); ok {
+			delete(obj, keyOf(obj, p.sub))
+		}
+		return nil
+	}
+	delete(c, key)
+	return nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+)
+
+const (
+	userSchema        = "urn:ietf:params:scim:schemas:core:2.0:User"
+	groupSchema       = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	userExtSchema     = "urn:ietf:params:scim:schemas:extension:reva:2.0:User"
+	groupExtSchema    = "urn:ietf:params:scim:schemas:extension:reva:2.0:Group"
+	listSchema        = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	errorSchema       = "urn:ietf:params:scim:api:messages:2.0:Error"
+	spConfigSchema    = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	contentTypeSCIM   = "application/scim+json"
+	defaultPageSize   = 100
+	maxPatchOps       = 1000
+	maxResourceLength = 1 << 20
+)
+
+type meta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+}
+
+type email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type reference struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// userExtension holds the attributes of the users not defined by the SCIM core schema.
+type userExtension struct {
+	UIDNumber    int64 `json:"uidNumber,omitempty"`
+	GIDNumber    int64 `json:"gidNumber,omitempty"`
+	MailVerified bool  `json:"mailVerified,omitempty"`
+}
+
+// userResource is the SCIM representation of a user.
+type userResource struct {
+	Schemas     []string       `json:"schemas"`
+	ID          string         `json:"id,omitempty"`
+	UserName    string         `json:"userName"`
+	DisplayName string         `json:"displayName,omitempty"`
+	UserType    string         `json:"userType,omitempty"`
+	Active      *bool          `json:"active,omitempty"`
+	Password    string         `json:"password,omitempty"`
+	Emails      []email        `json:"emails,omitempty"`
+	Groups      []reference    `json:"groups,omitempty"`
+	Extension   *userExtension `json:"urn:ietf:params:scim:schemas:extension:reva:2.0:User,omitempty"`
+	Meta        *meta          `json:"meta,omitempty"`
+}
+
+// groupExtension holds the attributes of the groups not defined by the SCIM core schema.
+type groupExtension struct {
+	GIDNumber int64  `json:"gidNumber,omitempty"`
+	Mail      string `json:"mail,omitempty"`
+}
+
+// groupResource is the SCIM representation of a group.
+type groupResource struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	DisplayName string          `json:"displayName"`
+	Members     []reference     `json:"members,omitempty"`
+	Extension   *groupExtension `json:"urn:ietf:params:scim:schemas:extension:reva:2.0:Group,omitempty"`
+	Meta        *meta           `json:"meta,omitempty"`
+}
+
+type listResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+type errorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type patchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []patchOperation `json:"Operations"`
+}
+
+var userTypes = map[userpb.UserType]string{
+	userpb.UserType_USER_TYPE_PRIMARY:     "primary",
+	userpb.UserType_USER_TYPE_SECONDARY:   "secondary",
+	userpb.UserType_USER_TYPE_SERVICE:     "service",
+	userpb.UserType_USER_TYPE_APPLICATION: "application",
+	userpb.UserType_USER_TYPE_GUEST:       "guest",
+	userpb.UserType_USER_TYPE_FEDERATED:   "federated",
+	userpb.UserType_USER_TYPE_LIGHTWEIGHT: "lightweight",
+}
+
+func parseUserType(s string) (userpb.UserType, bool) {
+	if s == "" {
+		return userpb.UserType_USER_TYPE_PRIMARY, true
+	}
+	for t, name := range userTypes {
+		if strings.EqualFold(s, name) {
+			return t, true
+		}
+	}
+	return userpb.UserType_USER_TYPE_INVALID, false
+}
+
+func (s *svc) userToResource(u *userpb.User, groups []reference, active bool) *userResource {
+	r := &userResource{
+		Schemas:     []string{userSchema, userExtSchema},
+		ID:          u.Id.OpaqueId,
+		UserName:    u.Username,
+		DisplayName: u.DisplayName,
+		UserType:    userTypes[u.Id.Type],
+		Active:      &active,
+		Groups:      groups,
+		Extension: &userExtension{
+			UIDNumber:    u.UidNumber,
+			GIDNumber:    u.GidNumber,
+			MailVerified: u.MailVerified,
+		},
+		Meta: &meta{ResourceType: "User", Location: s.location("Users", u.Id.OpaqueId)},
+	}
+	if u.Mail != "" {
+		r.Emails = []email{{Value: u.Mail, Type: "work", Primary: true}}
+	}
+	return r
+}
+
+// resourceToUser returns the user described by r, with the given id.
+// The password and the active flag of r are set separately.
+func (s *svc) resourceToUser(r *userResource, id string) (*userpb.User, *scimError) {
+	if r.UserName == "" {
+		return nil, invalidValue("userName is required")
+	}
+	userType, ok := parseUserType(r.UserType)
+	if !ok {
+		return nil, invalidValue("unknown userType " + r.UserType)
+	}
+
+	u := &userpb.User{
+		Id:          &userpb.UserId{Idp: s.conf.IDP, OpaqueId: id, Type: userType},
+		Username:    r.UserName,
+		DisplayName: r.DisplayName,
+		Mail:        primaryEmail(r.Emails),
+	}
+	if r.Extension != nil {
+		u.UidNumber = r.Extension.UIDNumber
+		u.GidNumber = r.Extension.GIDNumber
+		u.MailVerified = r.Extension.MailVerified
+	}
+	return u, nil
+}
+
+func primaryEmail(emails []email) string {
+	for _, e := range emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+func (s *svc) groupToResource(g *grouppb.Group) *groupResource {
+	r := &groupResource{
+		Schemas:     []string{groupSchema, groupExtSchema},
+		ID:          g.Id.OpaqueId,
+		DisplayName: g.DisplayName,
+		Extension:   &groupExtension{GIDNumber: g.GidNumber, Mail: g.Mail},
+		Meta:        &meta{ResourceType: "Group", Location: s.location("Groups", g.Id.OpaqueId)},
+	}
+	if r.DisplayName == "" {
+		r.DisplayName = g.GroupName
+	}
+	for _, m := range g.Members {
+		r.Members = append(r.Members, reference{Value: m.OpaqueId, Ref: s.location("Users", m.OpaqueId)})
+	}
+	return r
+}
+
+// toMap returns the JSON representation of v, on which
+// the filters are evaluated and the patches applied.
+func toMap(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+// fromMap decodes the JSON representation m into v.
+func fromMap(m map[string]any, v any) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,337 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package scim implements the SCIM 2.0 provisioning protocol for
+// the users and the groups, as specified in RFC 7643 and RFC 7644.
+package scim
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/group"
+	groupregistry "github.com/cs3org/reva/pkg/group/manager/registry"
+	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
+	"github.com/cs3org/reva/pkg/rhttp/global"
+	"github.com/cs3org/reva/pkg/sharedconf"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	revocationregistry "github.com/cs3org/reva/pkg/token/revocation/registry"
+	"github.com/cs3org/reva/pkg/user"
+	userregistry "github.com/cs3org/reva/pkg/user/manager/registry"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+	"github.com/go-chi/chi/v5"
+)
+
+func init() {
+	global.Register("scim", New)
+}
+
+type config struct {
+	Prefix           string                            `docs:"scim/v2;The prefix of the SCIM endpoints."                                                                                                    mapstructure:"prefix"`
+	GatewaySvc       string                            `docs:";The address of the gateway, used to create the homes and to remove the shares of the users."                                                 mapstructure:"gatewaysvc"`
+	Token            string                            `docs:";The bearer token the SCIM clients authenticate with."                                                                                        mapstructure:"token" validate:"required"`
+	IDP              string                            `docs:";The identity provider of the provisioned users and groups."                                                                                  mapstructure:"idp"   validate:"required"`
+	Driver           string                            `docs:"sql;The user and group manager storing the provisioned users and groups, shared with the user and group providers."                           mapstructure:"driver"`
+	Drivers          map[string]map[string]interface{} `docs:"url:pkg/user/manager/sql/sql.go"                                                                                                              mapstructure:"drivers"`
+	MachineSecret    string                            `docs:";The secret of the machine authentication, used to create the homes and remove the shares on behalf of the users. Both are skipped if empty." mapstructure:"machine_secret"`
+	RevocationStore  string                            `docs:";The revocation store shared with the gateway, where the sessions of the users are revoked when their password changes or they are deactivated or deleted." mapstructure:"revocation_store"`
+	RevocationStores map[string]map[string]interface{} `docs:"url:pkg/token/revocation/sql/sql.go"                                   mapstructure:"revocation_stores"`
+}
+
+func (c *config) ApplyDefaults() {
+	if c.Prefix == "" {
+		c.Prefix = "scim/v2"
+	}
+	if c.Driver == "" {
+		c.Driver = "sql"
+	}
+	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
+}
+
+type svc struct {
+	conf        *config
+	router      chi.Router
+	users       user.Provisioner
+	groups      group.Provisioner
+	lifecycle   lifecycle
+	revocations revocation.Store
+}
+
+// New returns a new SCIM service, provisioning the users and the groups
+// in a writable user and group manager.
+func New(ctx context.Context, m map[string]interface{}) (global.Service, error) {
+	var c config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+
+	users, groups, err := getDrivers(ctx, &c)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &svc{
+		conf:   &c,
+		users:  users,
+		groups: groups,
+	}
+	if c.MachineSecret != "" {
+		gw, err := pool.GetGatewayServiceClient(pool.Endpoint(c.GatewaySvc))
+		if err != nil {
+			return nil, err
+		}
+		s.lifecycle = &gatewayLifecycle{gatewayClient: gw, machineSecret: c.MachineSecret}
+	} else {
+		appctx.GetLogger(ctx).Warn().Msg("scim: machine_secret not set, the homes will not be created and the shares of the deleted users will not be removed")
+	}
+	if c.RevocationStore != "" {
+		if s.revocations, err = revocationregistry.GetStore(c.RevocationStore, c.RevocationStores); err != nil {
+			return nil, err
+		}
+	} else {
+		appctx.GetLogger(ctx).Warn().Msg("scim: revocation_store not set, the sessions of the users will not be revoked on password change, deactivation and deletion")
+	}
+	s.routerInit()
+	return s, nil
+}
+
+func getDrivers(ctx context.Context, c *config) (user.Provisioner, group.Provisioner, error) {
+	newUsers, ok := userregistry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, nil, errtypes.NotFound(fmt.Sprintf("driver %s not found for user manager", c.Driver))
+	}
+	newGroups, ok := groupregistry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, nil, errtypes.NotFound(fmt.Sprintf("driver %s not found for group manager", c.Driver))
+	}
+
+	um, err := newUsers(ctx, c.Drivers[c.Driver])
+	if err != nil {
+		return nil, nil, err
+	}
+	users, ok := um.(user.Provisioner)
+	if !ok {
+		return nil, nil, errtypes.NotSupported(fmt.Sprintf("the user manager %s does not support provisioning", c.Driver))
+	}
+	gm, err := newGroups(ctx, c.Drivers[c.Driver])
+	if err != nil {
+		return nil, nil, err
+	}
+	groups, ok := gm.(group.Provisioner)
+	if !ok {
+		return nil, nil, errtypes.NotSupported(fmt.Sprintf("the group manager %s does not support provisioning", c.Driver))
+	}
+	return users, groups, nil
+}
+
+func (s *svc) routerInit() {
+	r := chi.NewRouter()
+	r.Use(s.authenticate)
+	r.Get("/ServiceProviderConfig", s.serviceProviderConfig)
+	r.Route("/Users", func(r chi.Router) {
+		r.Get("/", s.listUsers)
+		r.Post("/", s.createUser)
+		r.Get("/{id}", s.getUser)
+		r.Put("/{id}", s.replaceUser)
+		r.Patch("/{id}", s.patchUser)
+		r.Delete("/{id}", s.deleteUser)
+	})
+	r.Route("/Groups", func(r chi.Router) {
+		r.Get("/", s.listGroups)
+		r.Post("/", s.createGroup)
+		r.Get("/{id}", s.getGroup)
+		r.Put("/{id}", s.replaceGroup)
+		r.Patch("/{id}", s.patchGroup)
+		r.Delete("/{id}", s.deleteGroup)
+	})
+	s.router = r
+}
+
+// Close performs cleanup.
+func (s *svc) Close() error {
+	return nil
+}
+
+func (s *svc) Prefix() string {
+	return s.conf.Prefix
+}
+
+// Unprotected returns all the endpoints, as the SCIM clients
+// authenticate with the bearer token of the service.
+func (s *svc) Unprotected() []string {
+	return []string{"/"}
+}
+
+func (s *svc) Handler() http.Handler {
+	return s.router
+}
+
+func (s *svc) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.conf.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			writeError(w, r, &scimError{status: http.StatusUnauthorized, detail: "invalid bearer token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *svc) location(resourceType, id string) string {
+	return path.Join("/", s.conf.Prefix, resourceType, id)
+}
+
+func (s *svc) serviceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]any{
+		"schemas":        []string{spConfigSchema},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": defaultPageSize},
+		"changePassword": map[string]bool{"supported": true},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]string{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "Authentication with the bearer token configured in the service",
+		}},
+	})
+}
+
+// scimError is an error as returned to the SCIM clients.
+// See https://www.rfc-editor.org/rfc/rfc7644#section-3.12
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func invalidValue(detail string) *scimError {
+	return &scimError{status: http.StatusBadRequest, scimType: "invalidValue", detail: detail}
+}
+
+// toSCIMError returns the SCIM error corresponding to err, returned by the managers.
+func toSCIMError(err error, msg string) *scimError {
+	switch err.(type) {
+	case errtypes.IsNotFound:
+		return &scimError{status: http.StatusNotFound, detail: msg + ": " + err.Error()}
+	case errtypes.IsAlreadyExists:
+		return &scimError{status: http.StatusConflict, scimType: "uniqueness", detail: msg + ": " + err.Error()}
+	case errtypes.IsBadRequest:
+		return invalidValue(msg + ": " + err.Error())
+	}
+	return &scimError{status: http.StatusInternalServerError, detail: msg + ": " + err.Error()}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, e *scimError) {
+	log := appctx.GetLogger(r.Context())
+	if e.status >= http.StatusInternalServerError {
+		log.Error().Str("detail", e.detail).Msg("scim: error handling request")
+	} else {
+		log.Debug().Int("status", e.status).Str("detail", e.detail).Msg("scim: invalid request")
+	}
+	writeJSON(w, r, e.status, &errorResponse{
+		Schemas:  []string{errorSchema},
+		Status:   strconv.Itoa(e.status),
+		SCIMType: e.scimType,
+		Detail:   e.detail,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", contentTypeSCIM)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		appctx.GetLogger(r.Context()).Error().Err(err).Msg("scim: error writing response")
+	}
+}
+
+// readJSON decodes the body of r into v.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) *scimError {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxResourceLength)).Decode(v); err != nil {
+		return &scimError{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "invalid request body: " + err.Error()}
+	}
+	return nil
+}
+
+// writeList writes the page of the resources matching the filter
+// of the request, sorted by the attribute sortAttr.
+// See https://www.rfc-editor.org/rfc/rfc7644#section-3.4.2
+func writeList(w http.ResponseWriter, r *http.Request, resources []map[string]any, schemas []string, sortAttr string) {
+	q := r.URL.Query()
+	if fs := q.Get("filter"); fs != "" {
+		f, err := parseFilter(fs, schemas)
+		if err != nil {
+			writeError(w, r, &scimError{status: http.StatusBadRequest, scimType: "invalidFilter", detail: err.Error()})
+			return
+		}
+		matching := []map[string]any{}
+		for _, res := range resources {
+			if f.match(res) {
+				matching = append(matching, res)
+			}
+		}
+		resources = matching
+	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		a, _ := lookup(resources[i], sortAttr).(string)
+		b, _ := lookup(resources[j], sortAttr).(string)
+		return strings.ToLower(a) < strings.ToLower(b)
+	})
+
+	startIndex, count := 1, defaultPageSize
+	if v := q.Get("startIndex"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, invalidValue("invalid startIndex "+v))
+			return
+		}
+		// values lower than 1 are interpreted as 1
+		startIndex = max(n, 1)
+	}
+	if v := q.Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, invalidValue("invalid count "+v))
+			return
+		}
+		count = min(max(n, 0), defaultPageSize)
+	}
+
+	page := []any{}
+	for i := startIndex - 1; i < len(resources) && len(page) < count; i++ {
+		page = append(page, resources[i])
+	}
+	writeJSON(w, r, http.StatusOK, &listResponse{
+		Schemas:      []string{listSchema},
+		TotalResults: len(resources),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	})
+}
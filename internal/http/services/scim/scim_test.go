@@ -0,0 +1,269 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	authsql "github.com/cs3org/reva/pkg/auth/manager/sql"
+	_ "github.com/cs3org/reva/pkg/group/manager/sql"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	_ "github.com/cs3org/reva/pkg/token/revocation/memory"
+	_ "github.com/cs3org/reva/pkg/user/manager/sql"
+)
+
+type fakeLifecycle struct {
+	created, deleted []string
+	deleteErr        error
+}
+
+func (l *fakeLifecycle) userCreated(_ context.Context, u *userpb.User) error {
+	l.created = append(l.created, u.Username)
+	return nil
+}
+
+func (l *fakeLifecycle) userDeleting(_ context.Context, u *userpb.User) error {
+	if l.deleteErr != nil {
+		return l.deleteErr
+	}
+	l.deleted = append(l.deleted, u.Username)
+	return nil
+}
+
+type fakeRevocations struct {
+	revocation.Store
+	revoked []string
+}
+
+func (r *fakeRevocations) RevokeUser(_ context.Context, u *userpb.UserId, _ time.Time) error {
+	r.revoked = append(r.revoked, u.OpaqueId)
+	return nil
+}
+
+type client struct {
+	t       *testing.T
+	handler http.Handler
+}
+
+func (c *client) do(method, target, body string) (int, map[string]any) {
+	c.t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("Content-Type", contentTypeSCIM)
+	w := httptest.NewRecorder()
+	c.handler.ServeHTTP(w, r)
+
+	var res map[string]any
+	if w.Body.Len() > 0 {
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			c.t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code, res
+}
+
+func (c *client) expect(status int, method, target, body string) map[string]any {
+	c.t.Helper()
+	code, res := c.do(method, target, body)
+	if code != status {
+		c.t.Fatalf("%s %s: got status %d, expected %d: %v", method, target, code, status, res)
+	}
+	return res
+}
+
+func filterQuery(f string) string {
+	return "?filter=" + url.QueryEscape(f)
+}
+
+func TestProvisioning(t *testing.T) {
+	ctx := context.Background()
+	dbFile := filepath.Join(t.TempDir(), "identity.db")
+	drivers := map[string]any{"sql": map[string]any{"db_file": dbFile}}
+	service, err := New(ctx, map[string]any{"token": "secret", "idp": "cernbox.cern.ch", "drivers": drivers, "revocation_store": "memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := service.(*svc)
+	if s.revocations == nil {
+		t.Fatal("expected the revocation store to be configured")
+	}
+	lc := &fakeLifecycle{}
+	s.lifecycle = lc
+	revocations := &fakeRevocations{}
+	s.revocations = revocations
+	c := &client{t: t, handler: s.Handler()}
+
+	r := httptest.NewRequest(http.MethodGet, "/Users", nil)
+	r.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("got status %d for a wrong token", w.Code)
+	}
+
+	// users
+	einstein := c.expect(http.StatusCreated, http.MethodPost, "/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "einstein",
+		"displayName": "Albert Einstein",
+		"emails": [{"value": "einstein@example.org", "primary": true}],
+		"password": "relativity"
+	}`)
+	einsteinID := einstein["id"].(string)
+	marie := c.expect(http.StatusCreated, http.MethodPost, "/Users", `{"userName": "marie", "userType": "lightweight"}`)
+	marieID := marie["id"].(string)
+	if len(lc.created) != 2 {
+		t.Fatalf("expected the homes to be created, got %v", lc.created)
+	}
+	c.expect(http.StatusConflict, http.MethodPost, "/Users", `{"userName": "einstein"}`)
+	c.expect(http.StatusBadRequest, http.MethodPost, "/Users", `{"displayName": "Nobody"}`)
+
+	authMgr, err := authsql.New(ctx, map[string]any{"db_file": dbFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := authMgr.Authenticate(ctx, "einstein", "relativity"); err != nil {
+		t.Fatalf("expected the password to be set: %v", err)
+	}
+
+	list := c.expect(http.StatusOK, http.MethodGet, "/Users"+filterQuery(`emails co "example.org"`), "")
+	if list["totalResults"].(float64) != 1 {
+		t.Fatalf("expected one user matching the filter, got %v", list)
+	}
+	c.expect(http.StatusBadRequest, http.MethodGet, "/Users"+filterQuery(`userName eq`), "")
+
+	list = c.expect(http.StatusOK, http.MethodGet, "/Users?startIndex=2&count=1", "")
+	resources := list["Resources"].([]any)
+	if list["totalResults"].(float64) != 2 || len(resources) != 1 || resources[0].(map[string]any)["userName"] != "marie" {
+		t.Fatalf("unexpected page %v", list)
+	}
+
+	patched := c.expect(http.StatusOK, http.MethodPatch, "/Users/"+einsteinID, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "replace", "path": "displayName", "value": "Prof. Einstein"},
+			{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "albert@example.org"},
+			{"op": "add", "value": {"urn:ietf:params:scim:schemas:extension:reva:2.0:User:uidNumber": 1879}}
+		]
+	}`)
+	ext := patched[userExtSchema].(map[string]any)
+	if patched["displayName"] != "Prof. Einstein" || patched["emails"].([]any)[0].(map[string]any)["value"] != "albert@example.org" || ext["uidNumber"].(float64) != 1879 {
+		t.Fatalf("user was not patched: %v", patched)
+	}
+
+	// the sessions are revoked when the password changes
+	revocations.revoked = nil
+	c.expect(http.StatusOK, http.MethodPatch, "/Users/"+einsteinID, `{"Operations": [{"op": "replace", "path": "password", "value": "quantum"}]}`)
+	if _, _, err := authMgr.Authenticate(ctx, "einstein", "quantum"); err != nil {
+		t.Fatalf("expected the password to be changed: %v", err)
+	}
+	if len(revocations.revoked) != 1 || revocations.revoked[0] != einsteinID {
+		t.Fatalf("expected the sessions of einstein to be revoked, got %v", revocations.revoked)
+	}
+	c.expect(http.StatusNotFound, http.MethodPatch, "/Users/unknown", `{"Operations": []}`)
+
+	// the deactivated users cannot log in and their sessions are revoked
+	revocations.revoked = nil
+	deactivated := c.expect(http.StatusOK, http.MethodPatch, "/Users/"+einsteinID, `{"Operations": [{"op": "replace", "path": "active", "value": false}]}`)
+	if deactivated["active"] != false {
+		t.Fatalf("user was not deactivated: %v", deactivated)
+	}
+	if _, _, err := authMgr.Authenticate(ctx, "einstein", "quantum"); err == nil {
+		t.Fatal("expected the deactivated user not to log in")
+	}
+	if len(revocations.revoked) != 1 || revocations.revoked[0] != einsteinID {
+		t.Fatalf("expected the sessions of einstein to be revoked, got %v", revocations.revoked)
+	}
+	list = c.expect(http.StatusOK, http.MethodGet, "/Users"+filterQuery(`active eq false`), "")
+	if list["totalResults"].(float64) != 1 {
+		t.Fatalf("expected one inactive user, got %v", list)
+	}
+	// a replacement without the active flag keeps the user inactive
+	replaced := c.expect(http.StatusOK, http.MethodPut, "/Users/"+einsteinID, `{"userName": "einstein", "displayName": "Prof. Einstein"}`)
+	if replaced["active"] != false {
+		t.Fatalf("user was reactivated: %v", replaced)
+	}
+	c.expect(http.StatusOK, http.MethodPatch, "/Users/"+einsteinID, `{"Operations": [{"op": "replace", "path": "active", "value": true}]}`)
+	if _, _, err := authMgr.Authenticate(ctx, "einstein", "quantum"); err != nil {
+		t.Fatalf("expected the reactivated user to log in: %v", err)
+	}
+
+	replaced = c.expect(http.StatusOK, http.MethodPut, "/Users/"+marieID, `{"userName": "marie", "displayName": "Marie Curie"}`)
+	if replaced["displayName"] != "Marie Curie" || replaced["userType"] != "primary" {
+		t.Fatalf("user was not replaced: %v", replaced)
+	}
+
+	// groups
+	group := c.expect(http.StatusCreated, http.MethodPost, "/Groups", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+		"displayName": "physics-lovers",
+		"members": [{"value": "`+einsteinID+`"}]
+	}`)
+	groupID := group["id"].(string)
+	c.expect(http.StatusBadRequest, http.MethodPost, "/Groups", `{"displayName": "sailing-lovers", "members": [{"value": "unknown"}]}`)
+
+	group = c.expect(http.StatusOK, http.MethodPatch, "/Groups/"+groupID, `{"Operations": [
+		{"op": "add", "path": "members", "value": [{"value": "`+marieID+`"}]},
+		{"op": "remove", "path": "members[value eq \"`+einsteinID+`\"]"},
+		{"op": "replace", "path": "displayName", "value": "Physics Lovers"}
+	]}`)
+	members := group["members"].([]any)
+	if group["displayName"] != "Physics Lovers" || len(members) != 1 || members[0].(map[string]any)["value"] != marieID {
+		t.Fatalf("group was not patched: %v", group)
+	}
+
+	user := c.expect(http.StatusOK, http.MethodGet, "/Users/"+marieID, "")
+	groups := user["groups"].([]any)
+	if len(groups) != 1 || groups[0].(map[string]any)["value"] != groupID {
+		t.Fatalf("expected marie to be member of the group: %v", user)
+	}
+	list = c.expect(http.StatusOK, http.MethodGet, "/Groups"+filterQuery(`members[value eq "`+marieID+`"]`), "")
+	if list["totalResults"].(float64) != 1 {
+		t.Fatalf("expected one group matching the filter, got %v", list)
+	}
+
+	// deprovisioning
+	lc.deleteErr = errors.New("gateway unavailable")
+	c.expect(http.StatusInternalServerError, http.MethodDelete, "/Users/"+einsteinID, "")
+	c.expect(http.StatusOK, http.MethodGet, "/Users/"+einsteinID, "")
+
+	lc.deleteErr = nil
+	revocations.revoked = nil
+	c.expect(http.StatusNoContent, http.MethodDelete, "/Users/"+einsteinID, "")
+	if len(revocations.revoked) != 1 || revocations.revoked[0] != einsteinID {
+		t.Fatalf("expected the sessions of einstein to be revoked, got %v", revocations.revoked)
+	}
+	c.expect(http.StatusNotFound, http.MethodGet, "/Users/"+einsteinID, "")
+	if len(lc.deleted) != 1 || lc.deleted[0] != "einstein" {
+		t.Fatalf("expected the shares of einstein to be removed, got %v", lc.deleted)
+	}
+
+	c.expect(http.StatusNoContent, http.MethodDelete, "/Groups/"+groupID, "")
+	c.expect(http.StatusNotFound, http.MethodGet, "/Groups/"+groupID, "")
+}
@@ -0,0 +1,317 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package scim
+
+import (
+	"context"
+	"net/http"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/password"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var userSchemas = []string{userSchema, userExtSchema}
+
+// lookupUser returns the provisioned user with the given SCIM id.
+func (s *svc) lookupUser(ctx context.Context, id string) (*userpb.User, *scimError) {
+	u, err := s.users.GetUser(ctx, &userpb.UserId{Idp: s.conf.IDP, OpaqueId: id}, false)
+	if err == nil && u.Id.OpaqueId != id {
+		// the user managers also look up the users by username
+		err = errtypes.NotFound(id)
+	}
+	if err != nil {
+		return nil, toSCIMError(err, "error getting user")
+	}
+	return u, nil
+}
+
+// userResource returns the SCIM representation of u, along with its groups.
+func (s *svc) userResource(ctx context.Context, u *userpb.User) (*userResource, *scimError) {
+	groups := []reference{}
+	for _, name := range u.Groups {
+		g, err := s.groups.GetGroupByClaim(ctx, "group_name", name, true)
+		if err != nil {
+			if _, ok := err.(errtypes.IsNotFound); ok {
+				continue
+			}
+			return nil, toSCIMError(err, "error getting group")
+		}
+		groups = append(groups, reference{Value: g.Id.OpaqueId, Ref: s.location("Groups", g.Id.OpaqueId), Display: g.DisplayName})
+	}
+	active, err := s.users.IsActive(ctx, u.Id)
+	if err != nil {
+		return nil, toSCIMError(err, "error getting user")
+	}
+	return s.userToResource(u, groups, active), nil
+}
+
+func (s *svc) listUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	users, err := s.users.FindUsers(ctx, "", false)
+	if err != nil {
+		writeError(w, r, toSCIMError(err, "error listing users"))
+		return
+	}
+
+	resources := []map[string]any{}
+	for _, u := range users {
+		if u.Id.Idp != s.conf.IDP {
+			continue
+		}
+		res, e := s.userResource(ctx, u)
+		if e != nil {
+			writeError(w, r, e)
+			return
+		}
+		m, err := toMap(res)
+		if err != nil {
+			writeError(w, r, toSCIMError(err, "error encoding user"))
+			return
+		}
+		resources = append(resources, m)
+	}
+	writeList(w, r, resources, userSchemas, "userName")
+}
+
+func (s *svc) getUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u, e := s.lookupUser(ctx, chi.URLParam(r, "id"))
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+	res, e := s.userResource(ctx, u)
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+func (s *svc) createUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := appctx.GetLogger(ctx)
+
+	var res userResource
+	if e := readJSON(w, r, &res); e != nil {
+		writeError(w, r, e)
+		return
+	}
+	u, e := s.resourceToUser(&res, uuid.New().String())
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	created, err := s.users.CreateUser(ctx, u)
+	if err != nil {
+		writeError(w, r, toSCIMError(err, "error creating user"))
+		return
+	}
+	active := res.Active == nil || *res.Active
+	if e := s.setCredentials(ctx, created.Id, res.Password, active); e != nil {
+		if err := s.users.DeleteUser(ctx, created.Id); err != nil {
+			log.Error().Err(err).Str("username", created.Username).Msg("scim: error deleting user without credentials")
+		}
+		writeError(w, r, e)
+		return
+	}
+
+	if s.lifecycle != nil {
+		// the home is also created at the first login,
+		// so the user is provisioned anyway
+		if err := s.lifecycle.userCreated(ctx, created); err != nil {
+			log.Warn().Err(err).Str("username", created.Username).Msg("scim: error creating home of the new user")
+		}
+	}
+
+	w.Header().Set("Location", s.location("Users", created.Id.OpaqueId))
+	writeJSON(w, r, http.StatusCreated, s.userToResource(created, []reference{}, active))
+}
+
+func (s *svc) replaceUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	existing, e := s.lookupUser(ctx, chi.URLParam(r, "id"))
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	var res userResource
+	if e := readJSON(w, r, &res); e != nil {
+		writeError(w, r, e)
+		return
+	}
+	s.updateUser(w, r, existing, &res)
+}
+
+func (s *svc) patchUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	existing, e := s.lookupUser(ctx, chi.URLParam(r, "id"))
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	var req patchRequest
+	if e := readJSON(w, r, &req); e != nil {
+		writeError(w, r, e)
+		return
+	}
+	res, e := s.userResource(ctx, existing)
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+	m, err := toMap(res)
+	if err != nil {
+		writeError(w, r, toSCIMError(err, "error encoding user"))
+		return
+	}
+	if e := applyPatch(m, req.Operations, userSchemas); e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	var patched userResource
+	if err := fromMap(m, &patched); err != nil {
+		writeError(w, r, invalidValue("invalid patched user: "+err.Error()))
+		return
+	}
+	s.updateUser(w, r, existing, &patched)
+}
+
+// updateUser replaces the attributes of the existing user with the ones of res.
+func (s *svc) updateUser(w http.ResponseWriter, r *http.Request, existing *userpb.User, res *userResource) {
+	ctx := r.Context()
+	u, e := s.resourceToUser(res, existing.Id.OpaqueId)
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+	wasActive, err := s.users.IsActive(ctx, u.Id)
+	if err != nil {
+		writeError(w, r, toSCIMError(err, "error getting user"))
+		return
+	}
+	// the uid and gid numbers are kept if not given
+	if u.UidNumber == 0 {
+		u.UidNumber = existing.UidNumber
+	}
+	if u.GidNumber == 0 {
+		u.GidNumber = existing.GidNumber
+	}
+
+	if err := s.users.UpdateUser(ctx, u); err != nil {
+		writeError(w, r, toSCIMError(err, "error updating user"))
+		return
+	}
+	// the user is kept active or inactive if not given
+	active := wasActive
+	if res.Active != nil {
+		active = *res.Active
+	}
+	if e := s.setCredentials(ctx, u.Id, res.Password, active); e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	updated, e := s.lookupUser(ctx, u.Id.OpaqueId)
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+	out, e := s.userResource(ctx, updated)
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, out)
+}
+
+// setCredentials sets the password of the user, if given,
+// and allows or denies its authentication.
+// The sessions of the user are revoked when it is deactivated.
+func (s *svc) setCredentials(ctx context.Context, uid *userpb.UserId, secret string, active bool) *scimError {
+	if secret != "" {
+		if e := s.setPassword(ctx, uid, secret); e != nil {
+			return e
+		}
+	}
+	if err := s.users.SetActive(ctx, uid, active); err != nil {
+		return toSCIMError(err, "error setting user active")
+	}
+	if !active {
+		return s.revokeSessions(ctx, uid)
+	}
+	return nil
+}
+
+func (s *svc) setPassword(ctx context.Context, uid *userpb.UserId, secret string) *scimError {
+	hash, err := password.Hash(secret)
+	if err != nil {
+		return toSCIMError(err, "error hashing password")
+	}
+	if err := s.users.SetPassword(ctx, uid, hash); err != nil {
+		return toSCIMError(err, "error setting password")
+	}
+	return s.revokeSessions(ctx, uid)
+}
+
+// revokeSessions revokes the tokens issued so far to the user,
+// if a revocation store is configured.
+func (s *svc) revokeSessions(ctx context.Context, uid *userpb.UserId) *scimError {
+	if s.revocations == nil {
+		return nil
+	}
+	if err := revocation.RevokeUser(ctx, s.revocations, uid); err != nil {
+		return toSCIMError(err, "error revoking the sessions of the user")
+	}
+	return nil
+}
+
+func (s *svc) deleteUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u, e := s.lookupUser(ctx, chi.URLParam(r, "id"))
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	if s.lifecycle != nil {
+		if err := s.lifecycle.userDeleting(ctx, u); err != nil {
+			writeError(w, r, toSCIMError(err, "error removing the shares of the user"))
+			return
+		}
+	}
+	if e := s.revokeSessions(ctx, u.Id); e != nil {
+		writeError(w, r, e)
+		return
+	}
+	if err := s.users.DeleteUser(ctx, u.Id); err != nil {
+		writeError(w, r, toSCIMError(err, "error deleting user"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,50 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wellknown
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/token/manager/jwt"
+)
+
+type jwksHandler struct {
+	data []byte
+}
+
+func (h *jwksHandler) init(keys []string) error {
+	set, err := jwt.KeySet(keys)
+	if err != nil {
+		return err
+	}
+	h.data, err = json.Marshal(set)
+	return err
+}
+
+// JWKS returns the JSON Web Key Set verifying the reva tokens.
+func (h *jwksHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	log := appctx.GetLogger(r.Context())
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(h.data); err != nil {
+		log.Error().Err(err).Msg("error writing jwks response")
+	}
+}
@@ -25,19 +25,24 @@ import (
 	"path/filepath"
 
 	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/ocm/signature"
+	"github.com/pkg/errors"
 )
 
 const OCMAPIVersion = "1.2.0"
 
 type OcmProviderConfig struct {
-	OCMPrefix          string `docs:"ocm;The prefix URL where the OCM API is served."                                          mapstructure:"ocm_prefix"`
-	Endpoint           string `docs:"This host's full URL. If it's not configured, it is assumed OCM is not available."        mapstructure:"endpoint"`
-	Provider           string `docs:"reva;A friendly name that defines this service."                                          mapstructure:"provider"`
-	WebdavRoot         string `docs:"/remote.php/dav/ocm;The root URL of the WebDAV endpoint to serve OCM shares."             mapstructure:"webdav_root"`
-	WebappRoot         string `docs:"/external/sciencemesh;The root URL to serve Web apps via OCM."                            mapstructure:"webapp_root"`
-	InviteAcceptDialog string `docs:"/sciencemesh-app/invitations;The frontend URL where to land when receiving an invitation" mapstructure:"invite_accept_dialog"`
-	EnableWebapp       bool   `docs:"false;Whether web apps are enabled in OCM shares."                                        mapstructure:"enable_webapp"`
-	EnableDatatx       bool   `docs:"false;Whether data transfers are enabled in OCM shares."                                  mapstructure:"enable_datatx"`
+	OCMPrefix           string   `docs:"ocm;The prefix URL where the OCM API is served."                                          mapstructure:"ocm_prefix"`
+	Endpoint            string   `docs:"This host's full URL. If it's not configured, it is assumed OCM is not available."        mapstructure:"endpoint"`
+	Provider            string   `docs:"reva;A friendly name that defines this service."                                          mapstructure:"provider"`
+	WebdavRoot          string   `docs:"/remote.php/dav/ocm;The root URL of the WebDAV endpoint to serve OCM shares."             mapstructure:"webdav_root"`
+	WebappRoot          string   `docs:"/external/sciencemesh;The root URL to serve Web apps via OCM."                            mapstructure:"webapp_root"`
+	InviteAcceptDialog  string   `docs:"/sciencemesh-app/invitations;The frontend URL where to land when receiving an invitation" mapstructure:"invite_accept_dialog"`
+	EnableWebapp        bool     `docs:"false;Whether web apps are enabled in OCM shares."                                        mapstructure:"enable_webapp"`
+	EnableDatatx        bool     `docs:"false;Whether data transfers are enabled in OCM shares."                                  mapstructure:"enable_datatx"`
+	SigningKey          string   `docs:";Path to the PEM encoded RSA key signing the OCM requests, to publish its public key."    mapstructure:"signing_key"`
+	ProviderDomain      string   `docs:";The domain identifying the signing key, the same configured in the OCM services."       mapstructure:"provider_domain"`
+	PreviousSigningKeys []string `docs:";Paths to the PEM encoded RSA keys replaced by the signing key, whose public keys stay published during the rotation." mapstructure:"previous_signing_keys"`
 }
 
 type OcmDiscoveryData struct {
@@ -48,6 +53,14 @@ type OcmDiscoveryData struct {
 	ResourceTypes      []resourceTypes `json:"resourceTypes" xml:"resourceTypes"`
 	Capabilities       []string        `json:"capabilities"  xml:"capabilities"`
 	InviteAcceptDialog string          `json:"inviteAcceptDialog" xml:"inviteAcceptDialog"`
+	PublicKey          *PublicKey      `json:"publicKey,omitempty" xml:"publicKey,omitempty"`
+	PreviousPublicKeys []*PublicKey    `json:"previousPublicKeys,omitempty" xml:"previousPublicKeys,omitempty"`
+}
+
+// PublicKey is the key used by a provider to sign its OCM requests.
+type PublicKey struct {
+	KeyID        string `json:"keyId"        xml:"keyId"`
+	PublicKeyPem string `json:"publicKeyPem" xml:"publicKeyPem"`
 }
 
 type resourceTypes struct {
@@ -84,7 +97,7 @@ func (c *OcmProviderConfig) ApplyDefaults() {
 	}
 }
 
-func (h *wkocmHandler) init(c *OcmProviderConfig) {
+func (h *wkocmHandler) init(c *OcmProviderConfig) error {
 	// generates the (static) data structure to be exposed by /.well-known/ocm:
 	// first prepare an empty and disabled payload
 	c.ApplyDefaults()
@@ -102,13 +115,13 @@ func (h *wkocmHandler) init(c *OcmProviderConfig) {
 
 	if c.Endpoint == "" {
 		h.data = d
-		return
+		return nil
 	}
 
 	endpointURL, err := url.Parse(c.Endpoint)
 	if err != nil {
 		h.data = d
-		return
+		return nil
 	}
 
 	// now prepare the enabled one
@@ -131,7 +144,43 @@ func (h *wkocmHandler) init(c *OcmProviderConfig) {
 	// for now we hardcode the capabilities, as this is currently only advisory
 	d.Capabilities = []string{"invites", "webdav-uri", "protocol-object"}
 	d.InviteAcceptDialog, _ = url.JoinPath(c.Endpoint, c.InviteAcceptDialog)
+
+	// publish the key used by the other services to sign the OCM requests,
+	// along with the keys it replaced until the rotation is over
+	if c.SigningKey != "" {
+		if c.ProviderDomain == "" {
+			return errors.New("wellknown: provider_domain is required to publish the signing key")
+		}
+		if d.PublicKey, err = publicKey(c.ProviderDomain, c.SigningKey); err != nil {
+			return err
+		}
+		for _, f := range c.PreviousSigningKeys {
+			pk, err := publicKey(c.ProviderDomain, f)
+			if err != nil {
+				return err
+			}
+			d.PreviousPublicKeys = append(d.PreviousPublicKeys, pk)
+		}
+	}
 	h.data = d
+	return nil
+}
+
+// publicKey returns the public key of the private key stored in file, with
+// the same key id that the signers of the provider domain use.
+func publicKey(domain, file string) (*PublicKey, error) {
+	key, err := signature.LoadPrivateKey(file)
+	if err != nil {
+		return nil, err
+	}
+	pem, err := signature.EncodePublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey{
+		KeyID:        signature.KeyID(domain, &key.PublicKey),
+		PublicKeyPem: pem,
+	}, nil
 }
 
 // This handler implements the OCM discovery endpoint specified in
@@ -0,0 +1,79 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package wellknown
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/ocm/signature"
+)
+
+func writeKey(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "key.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestPublishedKeys(t *testing.T) {
+	current, previous := writeKey(t), writeKey(t)
+
+	h := new(wkocmHandler)
+	err := h.init(&OcmProviderConfig{
+		Endpoint:            "https://ocm.cernbox.cern.ch/",
+		ProviderDomain:      "cernbox.cern.ch",
+		SigningKey:          current,
+		PreviousSigningKeys: []string{previous},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h.data.PublicKey == nil || len(h.data.PreviousPublicKeys) != 1 {
+		t.Fatalf("expected the current and the previous keys to be published, got %v %v", h.data.PublicKey, h.data.PreviousPublicKeys)
+	}
+
+	// the key ids match the ones of the signers of the OCM services
+	published := map[string]*PublicKey{current: h.data.PublicKey, previous: h.data.PreviousPublicKeys[0]}
+	for file, key := range published {
+		signer, err := signature.NewSignerFromFile("cernbox.cern.ch", file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.KeyID != signer.KeyID() {
+			t.Errorf("published key id %s does not match the signer key id %s", key.KeyID, signer.KeyID())
+		}
+	}
+
+	if err := new(wkocmHandler).init(&OcmProviderConfig{Endpoint: "https://ocm.cernbox.cern.ch/", SigningKey: current}); err == nil {
+		t.Error("expected an error publishing a key without provider domain")
+	}
+}
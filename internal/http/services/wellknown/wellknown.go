@@ -39,6 +39,7 @@ type svc struct {
 
 type config struct {
 	OCMProvider OcmProviderConfig `mapstructure:"ocmprovider"`
+	JWKSKeys    []string          `docs:";Paths to the PEM encoded keys verifying the reva tokens, published at /jwks.json." mapstructure:"jwks_keys"`
 }
 
 // New returns a new wellknown object.
@@ -62,8 +63,18 @@ func New(ctx context.Context, m map[string]interface{}) (global.Service, error)
 
 func (s *svc) routerInit() error {
 	wkocmHandler := new(wkocmHandler)
-	wkocmHandler.init(&s.Conf.OCMProvider)
+	if err := wkocmHandler.init(&s.Conf.OCMProvider); err != nil {
+		return err
+	}
 	s.router.Get("/ocm", wkocmHandler.Ocm)
+
+	if len(s.Conf.JWKSKeys) > 0 {
+		jwksHandler := new(jwksHandler)
+		if err := jwksHandler.init(s.Conf.JWKSKeys); err != nil {
+			return err
+		}
+		s.router.Get("/jwks.json", jwksHandler.JWKS)
+	}
 	return nil
 }
 
@@ -76,7 +87,7 @@ func (s *svc) Prefix() string {
 }
 
 func (s *svc) Unprotected() []string {
-	return []string{"/", "/ocm"}
+	return []string{"/", "/ocm", "/jwks.json"}
 }
 
 func (s *svc) Handler() http.Handler {
@@ -0,0 +1,41 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package appctx
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// ClientAddrHeader is the header used to forward the address
+// of the client the credentials were received from.
+const ClientAddrHeader = "x-client-addr"
+
+// ContextGetClientAddr returns the client address if set in the given context.
+func ContextGetClientAddr(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	if lst := md[ClientAddrHeader]; len(lst) != 0 {
+		return lst[0], true
+	}
+	return "", false
+}
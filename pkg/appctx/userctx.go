@@ -33,6 +33,8 @@ const (
 	scopeKey
 	idKey
 	pathKey
+	lockIDKey
+	destinationLockIDKey
 )
 
 // ContextGetUser returns the user if set in the given context.
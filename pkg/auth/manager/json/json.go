@@ -20,14 +20,20 @@ package json
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/json"
+	"net"
 	"os"
+	"time"
 
 	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/auth"
 	"github.com/cs3org/reva/pkg/auth/manager/registry"
+	"github.com/cs3org/reva/pkg/auth/password"
 	"github.com/cs3org/reva/pkg/auth/scope"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/utils/cfg"
@@ -54,17 +60,32 @@ type Credentials struct {
 
 type manager struct {
 	credentials map[string]*Credentials
+	throttle    *throttle
+	// trusted are the proxies whose forwarded client addresses are trusted.
+	trusted []*net.IPNet
+	// dummy is verified for the unknown users, so that they
+	// cannot be told apart from a wrong secret by timing.
+	dummy string
+	// plaintext counts the users with a plaintext secret.
+	plaintext int
 }
 
 type config struct {
 	// Users holds a path to a file containing json conforming the Users struct
-	Users string `mapstructure:"users"`
+	Users                      string   `docs:"/etc/revad/users.json;The file holding the users and their secrets, either plaintext or bcrypt/argon2id hashes."                                                                            mapstructure:"users"`
+	MaxFailedAttempts          int      `docs:"0;The consecutive failed logins of a user, from all the clients, after which its logins are rejected. Zero disables the limit."                                                             mapstructure:"max_failed_attempts"`
+	MaxFailedAttemptsPerClient int      `docs:"0;The consecutive failed logins of a user from a client after which its logins from that client are rejected. Zero disables the limit."                                                     mapstructure:"max_failed_attempts_per_client"`
+	TrustedProxies             []string `docs:"nil;The addresses or CIDR networks of the services, such as the gateway, trusted to forward the address of their clients. The address of the other callers is the one of their connection." mapstructure:"trusted_proxies"`
+	Lockout                    int      `docs:"300;The seconds after the last failed login for which the logins of a throttled user are rejected."                                                                                         mapstructure:"lockout"`
 }
 
 func (c *config) ApplyDefaults() {
 	if c.Users == "" {
 		c.Users = "/etc/revad/users.json"
 	}
+	if c.Lockout == 0 {
+		c.Lockout = 300
+	}
 }
 
 // New returns a new auth Manager.
@@ -74,6 +95,9 @@ func New(ctx context.Context, m map[string]interface{}) (auth.Manager, error) {
 	if err != nil {
 		return nil, err
 	}
+	if mgr.plaintext > 0 {
+		appctx.GetLogger(ctx).Warn().Int("users", mgr.plaintext).Msg("json: plaintext secrets found, hash them with `reva hash-secrets`")
+	}
 	return mgr, nil
 }
 
@@ -96,41 +120,92 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 		return err
 	}
 
+	m.plaintext = 0
 	for _, c := range credentials {
 		m.credentials[c.Username] = c
+		if !password.IsHash(c.Secret) {
+			m.plaintext++
+		}
+	}
+
+	// the unknown users are verified like the known ones,
+	// against a hash only if there are hashed secrets
+	m.dummy = ""
+	if m.plaintext < len(credentials) {
+		if m.dummy, err = password.Hash(""); err != nil {
+			return err
+		}
+	}
+
+	m.throttle = nil
+	if c.MaxFailedAttempts > 0 || c.MaxFailedAttemptsPerClient > 0 {
+		m.throttle = newThrottle(c.MaxFailedAttempts, c.MaxFailedAttemptsPerClient, time.Duration(c.Lockout)*time.Second)
+	}
+	m.trusted, err = parseNetworks(c.TrustedProxies)
+	return err
+}
+
+// verify tells whether secret matches the stored one, in constant time.
+// The stored secret is either a hash or a plaintext secret.
+func verify(stored, secret string) (bool, error) {
+	if password.IsHash(stored) {
+		return password.Verify(stored, secret)
 	}
-	return nil
+	// the digests have the same length, so that
+	// the length of the secret is not leaked
+	a, b := sha256.Sum256([]byte(stored)), sha256.Sum256([]byte(secret))
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1, nil
 }
 
 func (m *manager) Authenticate(ctx context.Context, username string, secret string) (*user.User, map[string]*authpb.Scope, error) {
-	if c, ok := m.credentials[username]; ok {
-		if c.Secret == secret {
-			var scopes map[string]*authpb.Scope
-			var err error
-			if c.ID != nil && (c.ID.Type == user.UserType_USER_TYPE_LIGHTWEIGHT || c.ID.Type == user.UserType_USER_TYPE_FEDERATED) {
-				scopes, err = scope.AddLightweightAccountScope(authpb.Role_ROLE_OWNER, nil)
-				if err != nil {
-					return nil, nil, err
-				}
-			} else {
-				scopes, err = scope.AddOwnerScope(nil)
-				if err != nil {
-					return nil, nil, err
-				}
-			}
-			return &user.User{
-				Id:           c.ID,
-				Username:     c.Username,
-				Mail:         c.Mail,
-				MailVerified: c.MailVerified,
-				DisplayName:  c.DisplayName,
-				Groups:       c.Groups,
-				UidNumber:    c.UIDNumber,
-				GidNumber:    c.GIDNumber,
-				Opaque:       c.Opaque,
-				// TODO add arbitrary keys as opaque data
-			}, scopes, nil
+	log := appctx.GetLogger(ctx)
+	c, ok := m.credentials[username]
+	if !ok {
+		// the unknown users are not throttled,
+		// so that they cannot evict the known ones
+		_, _ = verify(m.dummy, secret)
+		return nil, nil, errtypes.InvalidCredentials(username)
+	}
+
+	client := clientAddr(ctx, m.trusted)
+	if m.throttle != nil && !m.throttle.attempt(username, client) {
+		log.Warn().Str("username", username).Str("client", client).Msg("json: too many failed logins, rejecting login")
+		return nil, nil, errtypes.InvalidCredentials(username)
+	}
+
+	valid, err := verify(c.Secret, secret)
+	if err != nil {
+		log.Error().Err(err).Str("username", username).Msg("json: invalid secret hash")
+	}
+	if !valid {
+		return nil, nil, errtypes.InvalidCredentials(username)
+	}
+	if m.throttle != nil {
+		m.throttle.succeed(username, client)
+	}
+
+	var scopes map[string]*authpb.Scope
+	if c.ID != nil && (c.ID.Type == user.UserType_USER_TYPE_LIGHTWEIGHT || c.ID.Type == user.UserType_USER_TYPE_FEDERATED) {
+		scopes, err = scope.AddLightweightAccountScope(authpb.Role_ROLE_OWNER, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		scopes, err = scope.AddOwnerScope(nil)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
-	return nil, nil, errtypes.InvalidCredentials(username)
+	return &user.User{
+		Id:           c.ID,
+		Username:     c.Username,
+		Mail:         c.Mail,
+		MailVerified: c.MailVerified,
+		DisplayName:  c.DisplayName,
+		Groups:       c.Groups,
+		UidNumber:    c.UIDNumber,
+		GidNumber:    c.GIDNumber,
+		Opaque:       c.Opaque,
+		// TODO add arbitrary keys as opaque data
+	}, scopes, nil
 }
@@ -20,11 +20,22 @@ package json
 
 import (
 	"context"
+	"encoding/json"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/password"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 )
 
 var ctx = context.Background()
@@ -191,3 +202,239 @@ func TestGetAuthenticatedManager(t *testing.T) {
 		})
 	}
 }
+
+func writeUsers(t *testing.T, users string) string {
+	file := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(file, []byte(users), 0600); err != nil {
+		t.Fatalf("Error while writing users file: %v", err)
+	}
+	return file
+}
+
+func TestAuthenticateHashedSecrets(t *testing.T) {
+	argon, err := password.Hash("relativity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("radioactivity"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := []map[string]string{
+		{"username": "einstein", "secret": argon},
+		{"username": "marie", "secret": string(bcryptHash)},
+		{"username": "richard", "secret": "superfluidity"},
+		{"username": "enrico", "secret": "$argon2id$v=19$m=65536,t=0,p=4$c2FsdA$a2V5"},
+	}
+	data, err := json.Marshal(users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager, err := New(ctx, map[string]interface{}{"users": writeUsers(t, string(data))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description   string
+		username      string
+		secret        string
+		authenticated bool
+	}{
+		{description: "argon2id hash", username: "einstein", secret: "relativity", authenticated: true},
+		{description: "bcrypt hash", username: "marie", secret: "radioactivity", authenticated: true},
+		{description: "plaintext secret", username: "richard", secret: "superfluidity", authenticated: true},
+		{description: "wrong secret", username: "einstein", secret: "radioactivity", authenticated: false},
+		{description: "hash used as secret", username: "einstein", secret: argon, authenticated: false},
+		{description: "unknown user", username: "bob", secret: "", authenticated: false},
+		{description: "malformed hash", username: "enrico", secret: "fermi", authenticated: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			u, _, err := manager.Authenticate(ctx, tt.username, tt.secret)
+			if tt.authenticated {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.username, u.Username)
+			} else {
+				assert.EqualError(t, err, "error: invalid credentials: "+tt.username)
+			}
+		})
+	}
+}
+
+func TestThrottling(t *testing.T) {
+	file := writeUsers(t, `[{"username":"einstein","secret":"albert"},{"username":"marie","secret":"marie"}]`)
+
+	// the throttling is disabled by default
+	mgr, err := New(ctx, map[string]interface{}{"users": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		_, _, err := mgr.Authenticate(ctx, "einstein", "wrong")
+		assert.Error(t, err)
+	}
+	_, _, err = mgr.Authenticate(ctx, "einstein", "albert")
+	assert.NoError(t, err, "the throttling should be disabled")
+
+	_, err = New(ctx, map[string]interface{}{"users": file, "trusted_proxies": []string{"gateway"}})
+	assert.Error(t, err, "the trusted proxies should be addresses or networks")
+
+	mgr, err = New(ctx, map[string]interface{}{
+		"users":                          file,
+		"max_failed_attempts":            5,
+		"max_failed_attempts_per_client": 3,
+		"trusted_proxies":                []string{"10.0.0.0/8", "2001:db8::1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attacker, other := client("192.0.2.1", ""), client("192.0.2.2", "")
+
+	// a successful login resets the failures
+	for i := 0; i < 2; i++ {
+		_, _, err := mgr.Authenticate(attacker, "einstein", "wrong")
+		assert.Error(t, err)
+	}
+	_, _, err = mgr.Authenticate(attacker, "einstein", "albert")
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, _, err := mgr.Authenticate(attacker, "einstein", "wrong")
+		assert.Error(t, err)
+	}
+	_, _, err = mgr.Authenticate(attacker, "einstein", "albert")
+	assert.EqualError(t, err, "error: invalid credentials: einstein", "the user should be locked out from the client")
+	_, _, err = mgr.Authenticate(other, "einstein", "albert")
+	assert.NoError(t, err, "the user should not be locked out from the other clients")
+	_, _, err = mgr.Authenticate(attacker, "marie", "marie")
+	assert.NoError(t, err, "the other users should not be locked out")
+
+	// the addresses forwarded by the callers other than the trusted proxies are ignored
+	spoofing := []context.Context{client("192.0.2.3", "198.51.100.1"), client("192.0.2.3", "198.51.100.2"), client("192.0.2.3", "198.51.100.3")}
+	for _, c := range spoofing {
+		_, _, err := mgr.Authenticate(c, "marie", "wrong")
+		assert.Error(t, err)
+	}
+	_, _, err = mgr.Authenticate(client("192.0.2.3", "198.51.100.4"), "marie", "marie")
+	assert.EqualError(t, err, "error: invalid credentials: marie", "the forwarded address should be ignored")
+	_, _, err = mgr.Authenticate(other, "marie", "marie")
+	assert.NoError(t, err)
+
+	// the clients behind a trusted proxy are told apart
+	for i := 0; i < 3; i++ {
+		_, _, err := mgr.Authenticate(client("10.0.0.1", "203.0.113.1"), "marie", "wrong")
+		assert.Error(t, err)
+	}
+	_, _, err = mgr.Authenticate(client("10.0.0.2", "203.0.113.1"), "marie", "marie")
+	assert.EqualError(t, err, "error: invalid credentials: marie", "the user should be locked out from the forwarded client")
+	_, _, err = mgr.Authenticate(client("2001:db8::1", "203.0.113.2"), "marie", "marie")
+	assert.NoError(t, err, "the user should not be locked out from the other forwarded clients")
+
+	// the failures from all the clients count towards the limit of the user
+	for i := 0; i < 5; i++ {
+		_, _, err := mgr.Authenticate(client("192.0.2.1"+strconv.Itoa(i), ""), "einstein", "wrong")
+		assert.Error(t, err)
+	}
+	_, _, err = mgr.Authenticate(other, "einstein", "albert")
+	assert.EqualError(t, err, "error: invalid credentials: einstein", "the user should be locked out from all the clients")
+	_, _, err = mgr.Authenticate(ctx, "einstein", "albert")
+	assert.EqualError(t, err, "error: invalid credentials: einstein", "the user should be locked out from the unknown clients")
+
+	// the unknown users are not tracked
+	mgr, err = New(ctx, map[string]interface{}{"users": file, "max_failed_attempts": 3, "max_failed_attempts_per_client": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		_, _, err := mgr.Authenticate(attacker, "bob", "wrong")
+		assert.EqualError(t, err, "error: invalid credentials: bob")
+	}
+	assert.Equal(t, 0, mgr.(*manager).throttle.failures.Len(false))
+}
+
+// client returns a context holding the credentials received from addr,
+// which forwarded them on behalf of forwarded if not empty.
+func client(addr, forwarded string) context.Context {
+	ctx := peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 9142}})
+	if forwarded == "" {
+		return ctx
+	}
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(appctx.ClientAddrHeader, forwarded))
+}
+
+func TestConcurrentThrottling(t *testing.T) {
+	file := writeUsers(t, `[{"username":"einstein","secret":"albert"}]`)
+	mgr, err := New(ctx, map[string]interface{}{"users": file, "max_failed_attempts": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the attempts are reserved before the secrets are verified,
+	// so that the concurrent ones cannot exceed the limit
+	var wg sync.WaitGroup
+	var verified atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if mgr.(*manager).throttle.attempt("einstein", "") {
+				verified.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), verified.Load())
+
+	_, _, err = mgr.Authenticate(ctx, "einstein", "albert")
+	assert.EqualError(t, err, "error: invalid credentials: einstein", "the user should be locked out")
+}
+
+func TestMigrateSecrets(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("radioactivity"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := writeUsers(t, `[
+		{"id": {"opaque_id": "einstein", "idp": "cernbox.cern.ch"}, "username": "einstein", "secret": "relativity", "uid_number": 123},
+		{"username": "marie", "secret": "`+string(bcryptHash)+`"},
+		{"username": "richard"}
+	]`)
+
+	n, err := MigrateSecrets(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var users []*Credentials
+	if err := json.Unmarshal(data, &users); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, users, 3)
+	assert.Equal(t, "cernbox.cern.ch", users[0].ID.Idp)
+	assert.Equal(t, int64(123), users[0].UIDNumber)
+	assert.True(t, password.IsHash(users[0].Secret))
+	assert.Equal(t, string(bcryptHash), users[1].Secret)
+	assert.Empty(t, users[2].Secret)
+
+	manager, err := New(ctx, map[string]interface{}{"users": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = manager.Authenticate(ctx, "einstein", "relativity")
+	assert.NoError(t, err)
+
+	n, err = MigrateSecrets(file)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n, "the hashed secrets should not be hashed again")
+
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
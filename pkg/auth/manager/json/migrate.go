@@ -0,0 +1,101 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/cs3org/reva/pkg/auth/password"
+	"github.com/pkg/errors"
+)
+
+// MigrateSecrets replaces the plaintext secrets of the users in file with their hashes,
+// keeping the other fields of the users. It returns the number of hashed secrets.
+func MigrateSecrets(file string) (int, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	// the users are decoded as raw objects, so that the fields
+	// used by the json user manager are kept as they are
+	users := []map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &users); err != nil {
+		return 0, errors.Wrap(err, "json: error decoding users")
+	}
+
+	n := 0
+	for _, u := range users {
+		raw, ok := u["secret"]
+		if !ok {
+			continue
+		}
+		var secret string
+		if err := json.Unmarshal(raw, &secret); err != nil {
+			return 0, errors.Wrap(err, "json: error decoding secret")
+		}
+		if password.IsHash(secret) {
+			continue
+		}
+		hash, err := password.Hash(secret)
+		if err != nil {
+			return 0, err
+		}
+		if u["secret"], err = json.Marshal(hash); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+
+	data, err = json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	return n, writeFile(file, append(data, '\n'))
+}
+
+// writeFile replaces file with data atomically, keeping its permissions.
+func writeFile(file string, data []byte) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
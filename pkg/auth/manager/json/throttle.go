@@ -0,0 +1,167 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package json
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluele/gcache"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/peer"
+)
+
+// maxThrottledUsers bounds the logins whose failures are tracked.
+const maxThrottledUsers = 10000
+
+// throttleKey identifies the logins of a user, from all the clients
+// when client is empty, or from the given client.
+type throttleKey struct {
+	client, username string
+}
+
+// throttle counts the consecutive failed logins of the users, and locks them
+// out once they reach maxAttempts, whatever the client they come from.
+// The logins from a client can be locked out earlier, after maxClientAttempts,
+// so that a single client cannot exhaust the attempts of a user.
+// The failures are forgotten after lockout with no further failures,
+// and after a successful login.
+type throttle struct {
+	maxAttempts       int
+	maxClientAttempts int
+	lockout           time.Duration
+
+	mu       sync.Mutex
+	failures gcache.Cache
+}
+
+func newThrottle(maxAttempts, maxClientAttempts int, lockout time.Duration) *throttle {
+	return &throttle{
+		maxAttempts:       maxAttempts,
+		maxClientAttempts: maxClientAttempts,
+		lockout:           lockout,
+		failures:          gcache.New(maxThrottledUsers).LRU().Build(),
+	}
+}
+
+// limits returns the keys the logins of username from client are counted
+// under, along with their limits. A zero limit disables the key.
+func (t *throttle) limits(username, client string) map[throttleKey]int {
+	limits := map[throttleKey]int{}
+	if t.maxAttempts > 0 {
+		limits[throttleKey{username: username}] = t.maxAttempts
+	}
+	if t.maxClientAttempts > 0 && client != "" {
+		limits[throttleKey{client: client, username: username}] = t.maxClientAttempts
+	}
+	return limits
+}
+
+// attempt reserves a login attempt of username from client, returning false
+// if the login is rejected. The client is empty when it is not known.
+// The attempt is counted as failed until succeed is called,
+// so that the concurrent attempts cannot exceed the limits.
+func (t *throttle) attempt(username, client string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	limits := t.limits(username, client)
+	for key, limit := range limits {
+		if t.count(key) >= limit {
+			// the lockout is not extended by the attempts made meanwhile
+			return false
+		}
+	}
+	for key := range limits {
+		_ = t.failures.SetWithExpire(key, t.count(key)+1, t.lockout)
+	}
+	return true
+}
+
+// succeed forgets the failed logins of username.
+func (t *throttle) succeed(username, client string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.limits(username, client) {
+		t.failures.Remove(key)
+	}
+}
+
+func (t *throttle) count(key throttleKey) int {
+	v, err := t.failures.Get(key)
+	if err != nil {
+		return 0
+	}
+	return v.(int)
+}
+
+// parseNetworks parses the given addresses or networks in CIDR notation.
+func parseNetworks(addrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(addrs))
+	for _, a := range addrs {
+		if !strings.Contains(a, "/") {
+			ip := net.ParseIP(a)
+			if ip == nil {
+				return nil, errors.New("json: invalid address " + a)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(a)
+		if err != nil {
+			return nil, errors.Wrap(err, "json: invalid network "+a)
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
+
+// clientAddr returns the address of the client the credentials in ctx come
+// from, or an empty string if it is not known. It is the address of the caller,
+// unless the caller is one of the trusted proxies, which forward the address
+// of their own client. The address forwarded by the other callers is ignored,
+// as anyone could set it.
+func clientAddr(ctx context.Context, trusted []*net.IPNet) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			addr, _ := appctx.ContextGetClientAddr(ctx)
+			return addr
+		}
+	}
+	return ip.String()
+}
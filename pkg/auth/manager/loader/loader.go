@@ -29,5 +29,6 @@ import (
 	_ "github.com/cs3org/reva/pkg/auth/manager/ocmshares"
 	_ "github.com/cs3org/reva/pkg/auth/manager/oidc"
 	_ "github.com/cs3org/reva/pkg/auth/manager/publicshares"
+	_ "github.com/cs3org/reva/pkg/auth/manager/sql"
 	// Add your own here.
 )
@@ -34,6 +34,7 @@ import (
 	"github.com/cs3org/reva/pkg/auth/manager/registry"
 	"github.com/cs3org/reva/pkg/auth/scope"
 	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/ocm/share"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/sharedconf"
 	"github.com/cs3org/reva/pkg/utils"
@@ -103,6 +104,12 @@ func (m *manager) Authenticate(ctx context.Context, ocmshare, token string) (*us
 		return nil, nil, errtypes.InternalError(shareRes.Status.Message)
 	}
 
+	// an expired share does not grant access anymore
+	if share.IsExpired(shareRes.Share) {
+		log.Debug().Msg("ocm share expired")
+		return nil, nil, errtypes.NotFound("share expired")
+	}
+
 	// validate OCM share id if given (OCM v1.1)
 	if ocmshare != "" && shareRes.GetShare().GetId().GetOpaqueId() != ocmshare {
 		log.Error().Str("requested_share", ocmshare).Str("share_from_provider", shareRes.GetShare().GetId().GetOpaqueId()).Msg("mismatching ocm share id for existing secret")
@@ -0,0 +1,116 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sql implements an auth manager verifying the password hashes
+// stored in the database of the sql user manager.
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth"
+	"github.com/cs3org/reva/pkg/auth/manager/registry"
+	"github.com/cs3org/reva/pkg/auth/password"
+	"github.com/cs3org/reva/pkg/auth/scope"
+	"github.com/cs3org/reva/pkg/errtypes"
+	usersql "github.com/cs3org/reva/pkg/user/manager/sql"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type manager struct {
+	db *sql.DB
+	// dummy is verified for the unknown users, so that they
+	// cannot be told apart from a wrong password by timing.
+	dummy string
+}
+
+// New returns an auth manager verifying the passwords stored in a sql database.
+func New(ctx context.Context, m map[string]interface{}) (auth.Manager, error) {
+	var c usersql.Config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, errors.Wrap(err, "sql: error decoding config")
+	}
+
+	db, err := usersql.Open(ctx, &c)
+	if err != nil {
+		return nil, err
+	}
+	dummy, err := password.Hash("")
+	if err != nil {
+		return nil, err
+	}
+	return &manager{db: db, dummy: dummy}, nil
+}
+
+// passwordRow scans the password hash after the user columns.
+type passwordRow struct {
+	row  *sql.Row
+	hash *string
+}
+
+func (r passwordRow) Scan(dest ...any) error {
+	return r.row.Scan(append(dest, r.hash)...)
+}
+
+func (m *manager) Authenticate(ctx context.Context, username string, secret string) (*user.User, map[string]*authpb.Scope, error) {
+	var hash string
+	u, err := usersql.ScanUser(passwordRow{
+		row:  m.db.QueryRowContext(ctx, "SELECT "+usersql.UserColumns+", password FROM users WHERE username=? AND active", username),
+		hash: &hash,
+	})
+	switch {
+	case err == sql.ErrNoRows:
+		_, _ = password.Verify(m.dummy, secret)
+		return nil, nil, errtypes.InvalidCredentials(username)
+	case err != nil:
+		return nil, nil, errors.Wrap(err, "sql: error getting user")
+	}
+
+	ok, err := password.Verify(hash, secret)
+	if err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("username", username).Msg("sql: invalid password hash")
+		return nil, nil, errtypes.InvalidCredentials(username)
+	}
+	if !ok {
+		return nil, nil, errtypes.InvalidCredentials(username)
+	}
+
+	if u.Groups, err = usersql.UserGroups(ctx, m.db, u.Id); err != nil {
+		return nil, nil, err
+	}
+
+	var scopes map[string]*authpb.Scope
+	if u.Id.Type == user.UserType_USER_TYPE_LIGHTWEIGHT || u.Id.Type == user.UserType_USER_TYPE_FEDERATED {
+		scopes, err = scope.AddLightweightAccountScope(authpb.Role_ROLE_OWNER, nil)
+	} else {
+		scopes, err = scope.AddOwnerScope(nil)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return u, scopes, nil
+}
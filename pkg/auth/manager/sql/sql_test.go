@@ -0,0 +1,101 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/auth/password"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := New(ctx, map[string]interface{}{"db_file": filepath.Join(t.TempDir(), "identity.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mgr.(*manager)
+	defer m.db.Close()
+
+	argon, err := password.Hash("relativity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("radioactivity"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range [][]any{
+		{"cernbox.cern.ch", "4c510ada-c86b-4815-8820-42cdf82c3d51", 1, "einstein", "einstein@example.org", 1, "Albert Einstein", 123, 987, argon, 1},
+		{"cernbox.cern.ch", "f7fbf8c8-139b-4376-b307-cf0a8c2d0d9c", 7, "marie", "marie@example.org", 0, "Marie Curie", 456, 987, string(bcryptHash), 1},
+		{"cernbox.cern.ch", "932b4540-8d16-481e-8ef4-588e4b6b151c", 1, "richard", "richard@example.org", 0, "Richard Feynman", 789, 987, "superfluidity", 1},
+		{"cernbox.cern.ch", "e4fb0282-fabf-4cff-b1ee-90bdc01c4eef", 1, "bob", "bob@example.org", 0, "Bob", 1000, 987, argon, 0},
+	} {
+		if _, err := m.db.Exec("INSERT INTO users VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", row...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.db.Exec(`INSERT INTO user_groups VALUES ('cernbox.cern.ch', 'sailing-lovers', 'sailing-lovers', 'sailing@example.org', 'Sailing Lovers', 1234)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.db.Exec(`INSERT INTO group_members VALUES ('cernbox.cern.ch', 'sailing-lovers', 'cernbox.cern.ch', '4c510ada-c86b-4815-8820-42cdf82c3d51', 1)`); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		username    string
+		secret      string
+		userType    user.UserType
+		groups      []string
+	}{
+		{description: "argon2id hash", username: "einstein", secret: "relativity", userType: user.UserType_USER_TYPE_PRIMARY, groups: []string{"sailing-lovers"}},
+		{description: "bcrypt hash", username: "marie", secret: "radioactivity", userType: user.UserType_USER_TYPE_LIGHTWEIGHT, groups: []string{}},
+		{description: "wrong password", username: "einstein", secret: "radioactivity"},
+		{description: "plaintext passwords are not accepted", username: "richard", secret: "superfluidity"},
+		{description: "unknown user", username: "alice", secret: "relativity"},
+		{description: "inactive user", username: "bob", secret: "relativity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			u, scopes, err := m.Authenticate(ctx, tt.username, tt.secret)
+			if tt.groups == nil {
+				if _, ok := err.(errtypes.InvalidCredentials); !ok {
+					t.Fatalf("expected invalid credentials error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Username != tt.username || u.Id.Type != tt.userType || len(scopes) == 0 {
+				t.Fatalf("got user %+v with scopes %v", u, scopes)
+			}
+			if len(u.Groups) != len(tt.groups) || (len(tt.groups) > 0 && u.Groups[0] != tt.groups[0]) {
+				t.Fatalf("got groups %v, expected %v", u.Groups, tt.groups)
+			}
+		})
+	}
+}
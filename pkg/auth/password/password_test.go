@@ -0,0 +1,150 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package password
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerify(t *testing.T) {
+	argon, err := Hash("relativity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("relativity"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		hash        string
+		password    string
+		expected    bool
+		err         bool
+	}{
+		{
+			description: "argon2id - right password",
+			hash:        argon,
+			password:    "relativity",
+			expected:    true,
+		},
+		{
+			description: "argon2id - wrong password",
+			hash:        argon,
+			password:    "radioactivity",
+		},
+		{
+			description: "bcrypt - right password",
+			hash:        string(bcryptHash),
+			password:    "relativity",
+			expected:    true,
+		},
+		{
+			description: "bcrypt - wrong password",
+			hash:        string(bcryptHash),
+			password:    "radioactivity",
+		},
+		{
+			description: "plaintext secret",
+			hash:        "relativity",
+			password:    "relativity",
+			err:         true,
+		},
+		{
+			description: "malformed argon2id hash",
+			hash:        "$argon2id$v=19$m=65536,t=3$c2FsdA$a2V5",
+			password:    "relativity",
+			err:         true,
+		},
+		{
+			description: "argon2id - zero time",
+			hash:        "$argon2id$v=19$m=65536,t=0,p=4$c2FsdA$a2V5",
+			password:    "relativity",
+			err:         true,
+		},
+		{
+			description: "argon2id - zero threads",
+			hash:        "$argon2id$v=19$m=65536,t=3,p=0$c2FsdA$a2V5",
+			password:    "relativity",
+			err:         true,
+		},
+		{
+			description: "argon2id - zero memory",
+			hash:        "$argon2id$v=19$m=0,t=3,p=4$c2FsdA$a2V5",
+			password:    "relativity",
+			err:         true,
+		},
+		{
+			description: "argon2id - memory too high",
+			hash:        "$argon2id$v=19$m=4294967295,t=3,p=4$c2FsdA$a2V5",
+			password:    "relativity",
+			err:         true,
+		},
+		{
+			description: "argon2id - time too high",
+			hash:        "$argon2id$v=19$m=65536,t=4294967295,p=4$c2FsdA$a2V5",
+			password:    "relativity",
+			err:         true,
+		},
+		{
+			description: "argon2id - empty salt",
+			hash:        "$argon2id$v=19$m=65536,t=3,p=4$$a2V5",
+			password:    "relativity",
+			err:         true,
+		},
+		{
+			description: "argon2id - empty key",
+			hash:        "$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$",
+			password:    "relativity",
+			err:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			ok, err := Verify(tt.hash, tt.password)
+			if tt.err != (err != nil) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if ok != tt.expected {
+				t.Fatalf("expected %t, got %t", tt.expected, ok)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	h1, err := Hash("relativity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := Hash("relativity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 == h2 {
+		t.Fatal("expected hashes to be salted")
+	}
+	if !IsHash(h1) || IsHash("relativity") {
+		t.Fatal("unexpected IsHash result")
+	}
+}
@@ -19,11 +19,18 @@
 package crypto
 
 import (
+	"bytes"
 	"crypto/md5"
 	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"hash/adler32"
 	"io"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/errtypes"
 )
 
 // ComputeMD5XS computes the MD5 checksum.
@@ -53,3 +60,41 @@ func ComputeSHA1XS(r io.Reader) (string, error) {
 	}
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
+
+// VerifyChecksum checks that the content read from r matches the checksum,
+// in the "[algorithm] [checksum]" format of the tus Upload-Checksum header.
+// The checksum can be hex or base64 encoded.
+// It returns an errtypes.NotSupported error if the algorithm is unknown.
+func VerifyChecksum(r io.Reader, checksum string) error {
+	parts := strings.SplitN(checksum, " ", 2)
+	if len(parts) != 2 {
+		return errtypes.BadRequest("crypto: invalid checksum " + checksum)
+	}
+
+	var h hash.Hash
+	switch strings.ToLower(parts[0]) {
+	case "md5":
+		h = md5.New()
+	case "sha1":
+		h = sha1.New()
+	case "adler32":
+		h = adler32.New()
+	default:
+		return errtypes.NotSupported("crypto: checksum algorithm " + parts[0])
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+	sum := h.Sum(nil)
+
+	expected, err := hex.DecodeString(parts[1])
+	if err != nil || len(expected) != len(sum) {
+		if expected, err = base64.StdEncoding.DecodeString(parts[1]); err != nil {
+			return errtypes.BadRequest("crypto: invalid checksum " + checksum)
+		}
+	}
+	if !bytes.Equal(sum, expected) {
+		return errtypes.ChecksumMismatch(fmt.Sprintf("crypto: expected %s checksum %s, got %x", parts[0], parts[1], sum))
+	}
+	return nil
+}
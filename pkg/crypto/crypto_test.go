@@ -19,9 +19,12 @@
 package crypto
 
 import (
+	"fmt"
 	"io"
 	"strings"
 	"testing"
+
+	"github.com/cs3org/reva/pkg/errtypes"
 )
 
 func TestChecksums(t *testing.T) {
@@ -49,3 +52,24 @@ func TestChecksums(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyChecksum(t *testing.T) {
+	tests := map[string]error{
+		"sha1 2ef7bde608ce5404e97d5f042f95f89f1c232871":                           nil,
+		"SHA1 2ef7bde608ce5404e97d5f042f95f89f1c232871":                           nil,
+		"sha1 Lve95gjOVATpfV8EL5X4nxwjKHE=":                                       nil,
+		"adler32 1c49043e":                                                        nil,
+		"md5 ed076287532e86365e841e92bfc50d8c":                                    nil,
+		"md5 ed076287532e86365e841e92bfc50d8d":                                    errtypes.ChecksumMismatch(""),
+		"sha256 7f83b1657ff1fc53b92dc18148a1d65dfc2d4b1fa3d677284addd200126d9069": errtypes.NotSupported(""),
+		"sha1":            errtypes.BadRequest(""),
+		"sha1 not-base64": errtypes.BadRequest(""),
+	}
+
+	for checksum, expected := range tests {
+		err := VerifyChecksum(strings.NewReader("Hello World!"), checksum)
+		if fmt.Sprintf("%T", err) != fmt.Sprintf("%T", expected) {
+			t.Errorf("VerifyChecksum(%q) returned %v, expected an error of type %T", checksum, err, expected)
+		}
+	}
+}
@@ -0,0 +1,1066 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package eosmem implements an in-memory emulation of an EOS instance,
+// to be used in place of a real EOS when testing the EOS storage drivers.
+package eosmem
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/crypto"
+	"github.com/cs3org/reva/pkg/eosclient"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/acl"
+	"github.com/google/uuid"
+)
+
+const (
+	versionPrefix = ".sys.v#."
+	favoritesKey  = "http://owncloud.org/ns/favorite"
+	appLockKey    = "sys.app.lock"
+	versioningKey = "sys.versioning"
+)
+
+// Options to configure the Client.
+type Options struct {
+	// URL of the emulated instance, reported in the file infos.
+	// Default is root://eos-example.org
+	URL string
+
+	// TokenExpiry stores in seconds the time after which generated tokens will expire
+	// Default is 3600
+	TokenExpiry int
+
+	// MaxVersions is the number of versions kept for a file when its
+	// directory does not define the sys.versioning attribute.
+	// Default is 10
+	MaxVersions int
+
+	// Egroups maps the name of an egroup to the uids of its members.
+	// It is used to evaluate the egroup ACLs.
+	Egroups map[string][]string
+
+	// VersionInvariant identifies the files by the inode of their version
+	// folder, which is kept across versions, as the real clients do.
+	VersionInvariant bool
+}
+
+// ApplyDefaults fills the unset options with their default values.
+func (opt *Options) ApplyDefaults() {
+	if opt.URL == "" {
+		opt.URL = "root://eos-example.org"
+	}
+
+	if opt.TokenExpiry == 0 {
+		opt.TokenExpiry = 3600
+	}
+
+	if opt.MaxVersions == 0 {
+		opt.MaxVersions = 10
+	}
+}
+
+// Client emulates an EOS instance in memory. It implements the
+// eosclient.EOSClient interface with the semantics of the real
+// instance regarding ACLs, attributes, versions and the recycle bin.
+type Client struct {
+	opt *Options
+
+	mu        sync.Mutex
+	root      *node
+	inodes    map[uint64]*node
+	lastInode uint64
+	lastEtag  uint64
+	recycle   []*deletedEntry
+	quotas    []*eosclient.SetQuotaInfo
+	tokens    map[string]*token
+}
+
+var _ eosclient.EOSClient = (*Client)(nil)
+
+// New creates a new client with an empty namespace.
+func New(opt *Options) (*Client, error) {
+	opt.ApplyDefaults()
+	c := &Client{
+		opt:       opt,
+		inodes:    map[uint64]*node{},
+		tokens:    map[string]*token{},
+		lastInode: 1,
+	}
+	now := time.Now()
+	c.root = &node{
+		inode:    1,
+		children: map[string]*node{},
+		ctime:    now,
+		mtime:    now,
+		attrs:    map[string]string{},
+	}
+	c.inodes[1] = c.root
+	c.touch(c.root)
+	return c, nil
+}
+
+// AddACL adds an new acl to EOS with the given aclType.
+func (c *Client) AddACL(ctx context.Context, auth, rootAuth eosclient.Authorization, path string, pos uint, a *acl.Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, err := c.stat(auth, path)
+	if err != nil {
+		return err
+	}
+	id, err := c.identify(rootAuth)
+	if err != nil {
+		return err
+	}
+	if !c.can(id, n, 'm') {
+		return errtypes.PermissionDenied("eosclient: cannot change the acls of " + path)
+	}
+
+	// like `eos acl --recursive`, acls on directories apply to the whole tree
+	n.walk(func(e *node) {
+		if e == n || e.isDir() {
+			setACLEntry(e, pos, a)
+		}
+	})
+	return nil
+}
+
+// setACLEntry sets or, with empty permissions, removes the entry for the
+// type and qualifier of a. Existing entries are modified in place unless
+// a position is given.
+func setACLEntry(n *node, pos uint, a *acl.Entry) {
+	acls, err := acl.Parse(n.attrs["sys.acl"], acl.ShortTextForm)
+	if err != nil {
+		acls = &acl.ACLs{}
+	}
+
+	idx := len(acls.Entries)
+	for i, e := range acls.Entries {
+		if e.Type == a.Type && e.Qualifier == a.Qualifier {
+			idx = i
+			acls.Entries = append(acls.Entries[:i], acls.Entries[i+1:]...)
+			break
+		}
+	}
+
+	if a.Permissions != "" {
+		if pos != eosclient.EndPosition {
+			idx = int(pos) - 1
+		}
+		if idx > len(acls.Entries) {
+			idx = len(acls.Entries)
+		}
+		entry := &acl.Entry{Type: a.Type, Qualifier: a.Qualifier, Permissions: a.Permissions}
+		acls.Entries = append(acls.Entries[:idx], append([]*acl.Entry{entry}, acls.Entries[idx:]...)...)
+	}
+
+	if len(acls.Entries) == 0 {
+		delete(n.attrs, "sys.acl")
+		return
+	}
+	n.attrs["sys.acl"] = acls.Serialize()
+}
+
+// RemoveACL removes the acl from EOS.
+func (c *Client) RemoveACL(ctx context.Context, auth, rootAuth eosclient.Authorization, path string, a *acl.Entry) error {
+	return c.AddACL(ctx, auth, rootAuth, path, eosclient.EndPosition, &acl.Entry{Type: a.Type, Qualifier: a.Qualifier})
+}
+
+// UpdateACL updates the EOS acl.
+func (c *Client) UpdateACL(ctx context.Context, auth, rootAuth eosclient.Authorization, path string, position uint, a *acl.Entry) error {
+	return c.AddACL(ctx, auth, rootAuth, path, position, a)
+}
+
+// GetACL for a file.
+func (c *Client) GetACL(ctx context.Context, auth eosclient.Authorization, path, aclType, target string) (*acl.Entry, error) {
+	acls, err := c.ListACLs(ctx, auth, path)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range acls {
+		if a.Type == aclType && a.Qualifier == target {
+			return a, nil
+		}
+	}
+	return nil, errtypes.NotFound(fmt.Sprintf("%s:%s", aclType, target))
+}
+
+// ListACLs returns the list of ACLs present under the given path.
+func (c *Client) ListACLs(ctx context.Context, auth eosclient.Authorization, path string) ([]*acl.Entry, error) {
+	info, err := c.GetFileInfoByPath(ctx, auth, path)
+	if err != nil {
+		return nil, err
+	}
+	return info.SysACL.Entries, nil
+}
+
+// stat returns the entry at the given path if the caller can see it.
+func (c *Client) stat(auth eosclient.Authorization, p string) (*node, error) {
+	id, err := c.identify(auth)
+	if err != nil {
+		return nil, err
+	}
+	n, err := c.lookup(p)
+	if err != nil {
+		return nil, err
+	}
+	if !c.can(id, n, 'r') {
+		return nil, errtypes.PermissionDenied("eosclient: cannot access " + p)
+	}
+	return n, nil
+}
+
+func (c *Client) fileInfo(n *node) *eosclient.FileInfo {
+	attrs := make(map[string]string, len(n.attrs))
+	for k, v := range n.attrs {
+		if k != "user.acl" {
+			k = strings.TrimPrefix(k, "user.")
+		}
+		attrs[k] = v
+	}
+
+	entries := n.sysACL()
+	if !n.isDir() {
+		// files inherit the ACLs of their directory
+		entries = append(entries, n.parent.sysACL()...)
+	}
+
+	fid := n.inode
+	if n.parent != nil {
+		fid = n.parent.inode
+	}
+
+	info := &eosclient.FileInfo{
+		IsDir:      n.isDir(),
+		Inode:      n.inode,
+		FID:        fid,
+		UID:        n.uid,
+		GID:        n.gid,
+		MTimeSec:   uint64(n.mtime.Unix()),
+		MTimeNanos: uint32(n.mtime.Nanosecond()),
+		ATimeSec:   uint64(n.mtime.Unix()),
+		ATimeNanos: uint32(n.mtime.Nanosecond()),
+		CTimeSec:   uint64(n.ctime.Unix()),
+		CTimeNanos: uint32(n.ctime.Nanosecond()),
+		File:       n.path(),
+		ETag:       n.etag,
+		Instance:   c.opt.URL,
+		SysACL:     &acl.ACLs{Entries: entries},
+		Attrs:      attrs,
+	}
+	if n.isDir() {
+		info.TreeSize = n.treeSize()
+		info.Size = info.TreeSize
+		info.TreeCount = uint64(len(n.children))
+	} else {
+		info.Size = uint64(len(n.data))
+		xs, _ := crypto.ComputeAdler32XS(bytes.NewReader(n.data))
+		info.XS = &eosclient.Checksum{XSSum: xs, XSType: "adler"}
+	}
+	return info
+}
+
+// GetFileInfoByInode returns the FileInfo by the given inode.
+func (c *Client) GetFileInfoByInode(ctx context.Context, auth eosclient.Authorization, inode uint64) (*eosclient.FileInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return nil, err
+	}
+	n, ok := c.inodes[inode]
+	if !ok {
+		return nil, errtypes.NotFound(fmt.Sprintf("eosclient: inode %d", inode))
+	}
+	if !c.can(id, n, 'r') {
+		return nil, errtypes.PermissionDenied(fmt.Sprintf("eosclient: cannot access inode %d", inode))
+	}
+	if c.opt.VersionInvariant && n.isDir() && n.parent != nil && strings.HasPrefix(n.name, versionPrefix) {
+		// the inode of a version folder stands for its file
+		if f, ok := n.parent.children[strings.TrimPrefix(n.name, versionPrefix)]; ok && !f.isDir() {
+			info := c.fileInfo(f)
+			info.Inode = inode
+			return info, nil
+		}
+	}
+	return c.fileInfo(n), nil
+}
+
+// GetFileInfoByFXID returns the FileInfo by the given file id in hexadecimal.
+func (c *Client) GetFileInfoByFXID(ctx context.Context, auth eosclient.Authorization, fxid string) (*eosclient.FileInfo, error) {
+	inode, err := strconv.ParseUint(fxid, 16, 64)
+	if err != nil {
+		return nil, errtypes.BadRequest("eosclient: invalid fxid " + fxid)
+	}
+	return c.GetFileInfoByInode(ctx, auth, inode)
+}
+
+// GetFileInfoByPath returns the FilInfo at the given path.
+func (c *Client) GetFileInfoByPath(ctx context.Context, auth eosclient.Authorization, path string) (*eosclient.FileInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, err := c.stat(auth, path)
+	if err != nil {
+		return nil, err
+	}
+	info := c.fileInfo(n)
+	if c.opt.VersionInvariant && !n.isDir() && !strings.HasPrefix(n.parent.name, versionPrefix) {
+		// like the real clients, create the missing version folder on behalf of the owner
+		vf, ok := n.parent.children[versionPrefix+n.name]
+		if !ok {
+			vf = c.newNode(n.parent, versionPrefix+n.name, true, &identity{uid: n.uid, gid: n.gid})
+		}
+		info.Inode = vf.inode
+	}
+	return info, nil
+}
+
+func isValidAttribute(a *eosclient.Attribute) bool {
+	return (a.Type == eosclient.SystemAttr || a.Type == eosclient.UserAttr) && a.Key != ""
+}
+
+// canSetAttr checks that the identity can modify the attribute on the entry:
+// system attributes are reserved to sudoers.
+func (c *Client) canSetAttr(id *identity, n *node, attr *eosclient.Attribute) error {
+	if attr.Type == eosclient.SystemAttr && !id.privileged {
+		return errtypes.PermissionDenied("eosclient: cannot set system attributes on " + n.path())
+	}
+	if !c.can(id, n, 'w') {
+		return errtypes.PermissionDenied("eosclient: cannot set attributes on " + n.path())
+	}
+	return nil
+}
+
+// SetAttr sets an extended attributes on a path.
+func (c *Client) SetAttr(ctx context.Context, auth eosclient.Authorization, attr *eosclient.Attribute, errorIfExists, recursive bool, path, app string) error {
+	if !isValidAttribute(attr) {
+		return errtypes.BadRequest("eosclient: attr is invalid: " + attr.GetKey())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return err
+	}
+	n, err := c.lookup(path)
+	if err != nil {
+		return err
+	}
+	if err := c.canSetAttr(id, n, attr); err != nil {
+		return err
+	}
+
+	// favorites need to be stored per user
+	if attr.Type == eosclient.UserAttr && attr.Key == favoritesKey {
+		return setFavorite(ctx, n, recursive, true)
+	}
+
+	key := attr.GetKey()
+	if _, ok := n.attrs[key]; ok && errorIfExists {
+		return eosclient.AttrAlreadyExistsError
+	}
+	if key == appLockKey {
+		if err := checkAppLock(n, app); err != nil {
+			return err
+		}
+	}
+
+	applyToTree(n, recursive, func(e *node) {
+		e.attrs[key] = attr.Val
+	})
+	return nil
+}
+
+// applyToTree calls fn on n and, when recursive, on all the directories below it.
+func applyToTree(n *node, recursive bool, fn func(*node)) {
+	if !recursive {
+		fn(n)
+		return
+	}
+	n.walk(func(e *node) {
+		if e == n || e.isDir() {
+			fn(e)
+		}
+	})
+}
+
+func setFavorite(ctx context.Context, n *node, recursive, set bool) error {
+	u, ok := appctx.ContextGetUser(ctx)
+	if !ok {
+		return errtypes.UserRequired("eosclient: no user in ctx")
+	}
+
+	var err error
+	applyToTree(n, recursive, func(e *node) {
+		favs, perr := acl.Parse(e.attrs["user."+favoritesKey], acl.ShortTextForm)
+		if perr != nil {
+			err = perr
+			return
+		}
+		if set {
+			if err = favs.SetEntry(acl.TypeUser, u.Id.OpaqueId, "1"); err != nil {
+				return
+			}
+		} else {
+			favs.DeleteEntry(acl.TypeUser, u.Id.OpaqueId)
+		}
+		if v := favs.Serialize(); v != "" {
+			e.attrs["user."+favoritesKey] = v
+		} else {
+			delete(e.attrs, "user."+favoritesKey)
+		}
+	})
+	return err
+}
+
+// checkAppLock verifies that the app can modify a resource
+// eventually locked through the sys.app.lock attribute.
+// The lock has the form expires:<epoch>,type:<type>,owner:<user>:<app>.
+func checkAppLock(n *node, app string) error {
+	lock, ok := n.attrs[appLockKey]
+	if !ok {
+		return nil
+	}
+
+	var owner string
+	for _, field := range strings.Split(lock, ",") {
+		k, v, _ := strings.Cut(field, ":")
+		switch k {
+		case "expires":
+			expiration, err := strconv.ParseInt(v, 10, 64)
+			if err == nil && time.Unix(expiration, 0).Before(time.Now()) {
+				return nil
+			}
+		case "owner":
+			_, owner, _ = strings.Cut(v, ":")
+		}
+	}
+
+	if owner == "*" || owner == app {
+		return nil
+	}
+	return eosclient.FileIsLockedError
+}
+
+// UnsetAttr unsets an extended attribute on a path.
+func (c *Client) UnsetAttr(ctx context.Context, auth eosclient.Authorization, attr *eosclient.Attribute, recursive bool, path, app string) error {
+	if !isValidAttribute(attr) {
+		return errtypes.BadRequest("eosclient: attr is invalid: " + attr.GetKey())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return err
+	}
+	n, err := c.lookup(path)
+	if err != nil {
+		return err
+	}
+	if err := c.canSetAttr(id, n, attr); err != nil {
+		return err
+	}
+
+	if attr.Type == eosclient.UserAttr && attr.Key == favoritesKey {
+		return setFavorite(ctx, n, recursive, false)
+	}
+
+	key := attr.GetKey()
+	if _, ok := n.attrs[key]; !ok && !recursive {
+		return eosclient.AttrNotExistsError
+	}
+	if key == appLockKey {
+		if err := checkAppLock(n, app); err != nil {
+			return err
+		}
+	}
+
+	applyToTree(n, recursive, func(e *node) {
+		delete(e.attrs, key)
+	})
+	return nil
+}
+
+func newAttribute(key, val string) (*eosclient.Attribute, error) {
+	t, k, ok := strings.Cut(key, ".")
+	if !ok {
+		return nil, errtypes.InternalError("eosclient: wrong attr format: " + key)
+	}
+	attrType, err := eosclient.AttrStringToType(t)
+	if err != nil {
+		return nil, err
+	}
+	return &eosclient.Attribute{Type: attrType, Key: k, Val: val}, nil
+}
+
+// GetAttr returns the attribute specified by key.
+func (c *Client) GetAttr(ctx context.Context, auth eosclient.Authorization, key, path string) (*eosclient.Attribute, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, err := c.stat(auth, path)
+	if err != nil {
+		return nil, err
+	}
+	val, ok := n.attrs[key]
+	if !ok {
+		return nil, errtypes.NotFound(fmt.Sprintf("key %s not found", key))
+	}
+	return newAttribute(key, val)
+}
+
+// GetAttrs returns all the attributes of a resource.
+func (c *Client) GetAttrs(ctx context.Context, auth eosclient.Authorization, path string) ([]*eosclient.Attribute, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, err := c.stat(auth, path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(n.attrs))
+	for k := range n.attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]*eosclient.Attribute, 0, len(keys))
+	for _, k := range keys {
+		attr, err := newAttribute(k, n.attrs[k])
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+// GetQuota gets the quota of a user on the quota node defined by path.
+func (c *Client) GetQuota(ctx context.Context, username string, rootAuth eosclient.Authorization, path string) (*eosclient.QuotaInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(rootAuth)
+	if err != nil {
+		return nil, err
+	}
+	if !id.privileged {
+		return nil, errtypes.PermissionDenied("eosclient: quota can only be read by sudoers")
+	}
+
+	for _, q := range c.quotas {
+		if (q.Username == username || q.UID == username) && isAncestor(q.QuotaNode, path) {
+			usedBytes, usedInodes := c.quotaUsage(q)
+			return &eosclient.QuotaInfo{
+				TotalBytes:  q.MaxBytes,
+				UsedBytes:   usedBytes,
+				TotalInodes: q.MaxFiles,
+				UsedInodes:  usedInodes,
+			}, nil
+		}
+	}
+	return &eosclient.QuotaInfo{}, nil
+}
+
+// quotaUsage returns the bytes and inodes owned by the user under the quota node.
+func (c *Client) quotaUsage(q *eosclient.SetQuotaInfo) (bytes, inodes uint64) {
+	n, err := c.lookup(q.QuotaNode)
+	if err != nil {
+		return 0, 0
+	}
+	n.walk(func(e *node) {
+		if strconv.FormatUint(e.uid, 10) == q.UID {
+			bytes += uint64(len(e.data))
+			inodes++
+		}
+	})
+	return bytes, inodes
+}
+
+// checkQuota verifies that the owner of p can store size more bytes in it.
+func (c *Client) checkQuota(uid uint64, p string, size int64, newInode bool) error {
+	for _, q := range c.quotas {
+		if q.UID != strconv.FormatUint(uid, 10) || !isAncestor(q.QuotaNode, p) {
+			continue
+		}
+		usedBytes, usedInodes := c.quotaUsage(q)
+		if q.MaxBytes > 0 && int64(usedBytes)+size > int64(q.MaxBytes) {
+			return errtypes.InsufficientStorage("eosclient: quota exceeded for " + q.QuotaNode)
+		}
+		if newInode && q.MaxFiles > 0 && usedInodes+1 > q.MaxFiles {
+			return errtypes.InsufficientStorage("eosclient: inode quota exceeded for " + q.QuotaNode)
+		}
+	}
+	return nil
+}
+
+func isAncestor(parent, p string) bool {
+	parent, p = path.Clean(parent), path.Clean(p)
+	return parent == "/" || p == parent || strings.HasPrefix(p, parent+"/")
+}
+
+// SetQuota sets the quota of a user on the quota node defined by path.
+func (c *Client) SetQuota(ctx context.Context, rootAuth eosclient.Authorization, info *eosclient.SetQuotaInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(rootAuth)
+	if err != nil {
+		return err
+	}
+	if !id.privileged {
+		return errtypes.PermissionDenied("eosclient: quota can only be set by sudoers")
+	}
+
+	q := *info
+	q.QuotaNode = path.Clean(q.QuotaNode)
+	for i, existing := range c.quotas {
+		if existing.Username == q.Username && existing.QuotaNode == q.QuotaNode {
+			c.quotas[i] = &q
+			return nil
+		}
+	}
+	c.quotas = append(c.quotas, &q)
+	return nil
+}
+
+// Touch creates a 0-size,0-replica file in the EOS namespace.
+func (c *Client) Touch(ctx context.Context, auth eosclient.Authorization, p string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return err
+	}
+	parent, err := c.lookupDir(path.Dir(p))
+	if err != nil {
+		return err
+	}
+
+	if n, ok := parent.children[path.Base(p)]; ok {
+		if !c.can(id, n, 'u') {
+			return errtypes.PermissionDenied("eosclient: cannot touch " + p)
+		}
+		c.touch(n)
+		return nil
+	}
+
+	if !c.can(id, parent, 'w') {
+		return errtypes.PermissionDenied("eosclient: cannot create " + p)
+	}
+	if err := c.checkQuota(id.uid, p, 0, true); err != nil {
+		return err
+	}
+	c.newNode(parent, path.Base(p), false, id)
+	return nil
+}
+
+// Chown given path.
+func (c *Client) Chown(ctx context.Context, auth, chownauth eosclient.Authorization, path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return err
+	}
+	if !id.privileged {
+		return errtypes.PermissionDenied("eosclient: only sudoers can change the owner of " + path)
+	}
+	n, err := c.lookup(path)
+	if err != nil {
+		return err
+	}
+
+	uid, err := strconv.ParseUint(chownauth.Role.UID, 10, 64)
+	if err != nil {
+		return errtypes.BadRequest("eosclient: invalid uid " + chownauth.Role.UID)
+	}
+	gid, err := strconv.ParseUint(chownauth.Role.GID, 10, 64)
+	if err != nil {
+		return errtypes.BadRequest("eosclient: invalid gid " + chownauth.Role.GID)
+	}
+	n.uid, n.gid = uid, gid
+	return nil
+}
+
+// Chmod given path.
+func (c *Client) Chmod(ctx context.Context, auth eosclient.Authorization, mode, path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return err
+	}
+	n, err := c.lookup(path)
+	if err != nil {
+		return err
+	}
+	if !id.privileged && n.uid != id.uid {
+		return errtypes.PermissionDenied("eosclient: only the owner can change the mode of " + path)
+	}
+	n.mode = mode
+	return nil
+}
+
+// CreateDir creates a directory at the given path, including its parents.
+func (c *Client) CreateDir(ctx context.Context, auth eosclient.Authorization, p string) error {
+	if !path.IsAbs(p) {
+		return errtypes.BadRequest("eosclient: path must be absolute: " + p)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return err
+	}
+
+	n := c.root
+	for _, name := range strings.Split(strings.Trim(path.Clean(p), "/"), "/") {
+		if name == "" {
+			continue
+		}
+		child, ok := n.children[name]
+		switch {
+		case ok && !child.isDir():
+			return errtypes.AlreadyExists("eosclient: " + child.path())
+		case !ok:
+			if !c.can(id, n, 'w') {
+				return errtypes.PermissionDenied("eosclient: cannot create directories in " + n.path())
+			}
+			child = c.newNode(n, name, true, id)
+		}
+		n = child
+	}
+	return nil
+}
+
+// Remove removes the resource at the given path.
+func (c *Client) Remove(ctx context.Context, auth eosclient.Authorization, path string, noRecycle bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return err
+	}
+	n, err := c.lookup(path)
+	if err != nil {
+		return err
+	}
+	if n == c.root {
+		return errtypes.PermissionDenied("eosclient: cannot remove the root")
+	}
+	if !c.can(id, n.parent, 'd') {
+		return errtypes.PermissionDenied("eosclient: cannot remove " + path)
+	}
+
+	parent := n.parent
+	entries := []*node{n}
+	// the versions of a file go together with it
+	if vf, ok := parent.children[versionPrefix+n.name]; ok && !n.isDir() {
+		entries = append(entries, vf)
+	}
+	for _, e := range entries {
+		restorePath := e.path()
+		c.detach(e)
+		if !noRecycle {
+			c.recycle = append(c.recycle, newDeletedEntry(e, restorePath))
+		}
+	}
+	c.touch(parent)
+	return nil
+}
+
+// Rename renames the resource referenced by oldPath to newPath.
+func (c *Client) Rename(ctx context.Context, auth eosclient.Authorization, oldPath, newPath string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return err
+	}
+	n, err := c.lookup(oldPath)
+	if err != nil {
+		return err
+	}
+	if n == c.root {
+		return errtypes.BadRequest("eosclient: cannot rename the root")
+	}
+	parent, err := c.lookupDir(path.Dir(newPath))
+	if err != nil {
+		return err
+	}
+	if isAncestor(n.path(), parent.path()) {
+		return errtypes.BadRequest("eosclient: cannot move " + oldPath + " into itself")
+	}
+
+	name := path.Base(newPath)
+	if existing, ok := parent.children[name]; ok {
+		if existing == n {
+			return nil
+		}
+		return errtypes.AlreadyExists("eosclient: " + newPath)
+	}
+	if !c.can(id, n.parent, 'd') || !c.can(id, parent, 'w') {
+		return errtypes.PermissionDenied("eosclient: cannot move " + oldPath + " to " + newPath)
+	}
+
+	oldParent := n.parent
+	vf, hasVersions := oldParent.children[versionPrefix+n.name]
+	hasVersions = hasVersions && !n.isDir()
+
+	c.detach(n)
+	n.name = name
+	c.attach(parent, n)
+
+	// the versions follow the file
+	if hasVersions {
+		c.detach(vf)
+		if old, ok := parent.children[versionPrefix+name]; ok {
+			c.detach(old)
+		}
+		vf.name = versionPrefix + name
+		c.attach(parent, vf)
+	}
+
+	c.touch(oldParent)
+	c.touch(n)
+	return nil
+}
+
+// List the contents of the directory given by path.
+func (c *Client) List(ctx context.Context, auth eosclient.Authorization, path string) ([]*eosclient.FileInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return nil, err
+	}
+	n, err := c.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	if !c.can(id, n, 'x') {
+		return nil, errtypes.PermissionDenied("eosclient: cannot list " + path)
+	}
+
+	// like `eos find --maxdepth 1`, the entry itself is not listed
+	finfos := []*eosclient.FileInfo{}
+	for _, child := range n.sortedChildren() {
+		finfos = append(finfos, c.fileInfo(child))
+	}
+	return finfos, nil
+}
+
+// Read reads a file from the mgm.
+func (c *Client) Read(ctx context.Context, auth eosclient.Authorization, path string) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, err := c.stat(auth, path)
+	if err != nil {
+		return nil, err
+	}
+	if n.isDir() {
+		return nil, errtypes.BadRequest("eosclient: cannot read a directory: " + path)
+	}
+	return io.NopCloser(bytes.NewReader(n.data)), nil
+}
+
+// Write writes a stream to the mgm.
+func (c *Client) Write(ctx context.Context, auth eosclient.Authorization, p string, stream io.ReadCloser, app string) error {
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return err
+	}
+	parent, err := c.lookupDir(path.Dir(p))
+	if err != nil {
+		return err
+	}
+
+	n, ok := parent.children[path.Base(p)]
+	if !ok {
+		if !c.can(id, parent, 'w') {
+			return errtypes.PermissionDenied("eosclient: cannot create " + p)
+		}
+		if err := c.checkQuota(id.uid, p, int64(len(data)), true); err != nil {
+			return err
+		}
+		n = c.newNode(parent, path.Base(p), false, id)
+		n.data = data
+		return nil
+	}
+
+	if n.isDir() {
+		return errtypes.BadRequest("eosclient: cannot write a directory: " + p)
+	}
+	if err := checkAppLock(n, app); err != nil {
+		return err
+	}
+	if !c.can(id, n, 'u') {
+		return errtypes.PermissionDenied("eosclient: cannot update " + p)
+	}
+	if err := c.checkQuota(n.uid, p, int64(len(data))-int64(len(n.data)), false); err != nil {
+		return err
+	}
+
+	c.archiveVersion(n)
+	n.data = data
+	c.touch(n)
+	return nil
+}
+
+// archiveVersion stores the current content of the file in its version folder,
+// trimming the oldest versions beyond the configured limit.
+func (c *Client) archiveVersion(n *node) {
+	max := c.opt.MaxVersions
+	if v, err := strconv.Atoi(n.parent.attrs[versioningKey]); err == nil {
+		max = v
+	}
+	if max <= 0 {
+		return
+	}
+
+	owner := &identity{uid: n.uid, gid: n.gid}
+	vf, ok := n.parent.children[versionPrefix+n.name]
+	if !ok {
+		vf = c.newNode(n.parent, versionPrefix+n.name, true, owner)
+	}
+
+	name := fmt.Sprintf("%d.%08x", n.mtime.Unix(), c.lastInode+1)
+	version := c.newNode(vf, name, false, owner)
+	version.data = n.data
+	version.mtime = n.mtime
+
+	versions := vf.sortedChildren()
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].inode < versions[j].inode })
+	for len(versions) > max {
+		c.detach(versions[0])
+		versions = versions[1:]
+	}
+}
+
+// ListVersions list all the versions for a given file.
+func (c *Client) ListVersions(ctx context.Context, auth eosclient.Authorization, p string) ([]*eosclient.FileInfo, error) {
+	finfos, err := c.List(ctx, auth, getVersionFolder(p))
+	if err != nil {
+		// we send back an empty list
+		return []*eosclient.FileInfo{}, nil
+	}
+	return finfos, nil
+}
+
+// RollbackToVersion rollbacks a file to a previous version.
+// The current content of the file becomes a version in turn.
+func (c *Client) RollbackToVersion(ctx context.Context, auth eosclient.Authorization, p, version string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return err
+	}
+	n, err := c.lookup(p)
+	if err != nil {
+		return err
+	}
+	if !c.can(id, n, 'u') {
+		return errtypes.PermissionDenied("eosclient: cannot update " + p)
+	}
+	v, err := c.lookup(path.Join(getVersionFolder(p), version))
+	if err != nil {
+		return err
+	}
+
+	c.detach(v)
+	c.archiveVersion(n)
+	n.data = v.data
+	c.touch(n)
+	return nil
+}
+
+// ReadVersion reads the version for the given file.
+func (c *Client) ReadVersion(ctx context.Context, auth eosclient.Authorization, p, version string) (io.ReadCloser, error) {
+	return c.Read(ctx, auth, path.Join(getVersionFolder(p), version))
+}
+
+func getVersionFolder(p string) string {
+	return path.Join(path.Dir(p), versionPrefix+path.Base(p))
+}
+
+// GenerateToken returns a token on behalf of the resource owner to be used by lightweight accounts.
+func (c *Client) GenerateToken(ctx context.Context, auth eosclient.Authorization, p string, a *acl.Entry) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return "", err
+	}
+	n, err := c.lookup(p)
+	if err != nil {
+		return "", err
+	}
+	if !id.privileged && n.uid != id.uid {
+		return "", errtypes.PermissionDenied("eosclient: only the owner can generate tokens for " + p)
+	}
+
+	tkn := "zteos64:" + uuid.New().String()
+	c.tokens[tkn] = &token{
+		uid:         n.uid,
+		gid:         n.gid,
+		path:        path.Clean(p),
+		permissions: a.Permissions,
+		expiration:  time.Now().Add(time.Duration(c.opt.TokenExpiry) * time.Second),
+	}
+	return tkn, nil
+}
@@ -0,0 +1,417 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package eosmem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/eosclient"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/acl"
+)
+
+var (
+	root     = eosclient.Authorization{}
+	daemon   = eosclient.Authorization{Role: eosclient.Role{UID: "2", GID: "2"}}
+	einstein = eosclient.Authorization{Role: eosclient.Role{UID: "1000", GID: "1000"}}
+	marie    = eosclient.Authorization{Role: eosclient.Role{UID: "1001", GID: "1000"}}
+)
+
+// setup creates a home for einstein with a file in it.
+func setup(t *testing.T) *Client {
+	c, err := New(&Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := c.CreateDir(ctx, root, "/eos/user/e/einstein"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Chown(ctx, root, einstein, "/eos/user/e/einstein"); err != nil {
+		t.Fatal(err)
+	}
+	write(t, c, einstein, "/eos/user/e/einstein/file.txt", "v1")
+	return c
+}
+
+func write(t *testing.T, c *Client, auth eosclient.Authorization, p, content string) {
+	t.Helper()
+	if err := c.Write(context.Background(), auth, p, io.NopCloser(strings.NewReader(content)), ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, c *Client, auth eosclient.Authorization, p string) string {
+	t.Helper()
+	r, err := c.Read(context.Background(), auth, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestACLs(t *testing.T) {
+	c := setup(t)
+	ctx := context.Background()
+	home := "/eos/user/e/einstein"
+
+	if _, err := c.Read(ctx, marie, home+"/file.txt"); !isPermissionDenied(err) {
+		t.Fatalf("expected permission denied before sharing, got %v", err)
+	}
+
+	if err := c.AddACL(ctx, einstein, root, home, eosclient.EndPosition, &acl.Entry{Type: acl.TypeUser, Qualifier: "1001", Permissions: "rx"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, c, marie, home+"/file.txt"); got != "v1" {
+		t.Fatalf("got %q", got)
+	}
+	if err := c.Write(ctx, marie, home+"/new.txt", io.NopCloser(strings.NewReader("x")), ""); !isPermissionDenied(err) {
+		t.Fatalf("expected a read-only share, got %v", err)
+	}
+
+	// files inherit the acls of their directory
+	info, err := c.GetFileInfoByPath(ctx, marie, home+"/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.SysACL.Entries) != 1 || info.SysACL.Entries[0].Permissions != "rx" {
+		t.Fatalf("unexpected acls %+v", info.SysACL.Entries)
+	}
+
+	// an entry at a given position is inserted there, others are updated in place
+	if err := c.AddACL(ctx, einstein, root, home, eosclient.StartPosition, &acl.Entry{Type: acl.TypeGroup, Qualifier: "physics", Permissions: "rx"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdateACL(ctx, einstein, root, home, eosclient.EndPosition, &acl.Entry{Type: acl.TypeUser, Qualifier: "1001", Permissions: "rwx!d"}); err != nil {
+		t.Fatal(err)
+	}
+	attr, err := c.GetAttr(ctx, root, "sys.acl", home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr.Val != "egroup:physics=rx,u:1001=rwx!d" {
+		t.Fatalf("got %q", attr.Val)
+	}
+
+	write(t, c, marie, home+"/new.txt", "x")
+	if err := c.Remove(ctx, marie, home+"/new.txt", false); !isPermissionDenied(err) {
+		t.Fatalf("expected deletion to be denied, got %v", err)
+	}
+
+	if err := c.RemoveACL(ctx, einstein, root, home, &acl.Entry{Type: acl.TypeUser, Qualifier: "1001"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetACL(ctx, einstein, home, acl.TypeUser, "1001"); err == nil {
+		t.Fatal("expected the acl to be removed")
+	}
+
+	// acls can only be changed by sudoers or who holds the m permission
+	err = c.AddACL(ctx, einstein, marie, home, eosclient.EndPosition, &acl.Entry{Type: acl.TypeUser, Qualifier: "1001", Permissions: "rwx"})
+	if !isPermissionDenied(err) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+}
+
+func TestDaemonIsReadOnly(t *testing.T) {
+	c := setup(t)
+	if got := read(t, c, daemon, "/eos/user/e/einstein/file.txt"); got != "v1" {
+		t.Fatalf("got %q", got)
+	}
+	err := c.Write(context.Background(), daemon, "/eos/user/e/einstein/file.txt", io.NopCloser(strings.NewReader("v2")), "")
+	if !isPermissionDenied(err) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	c := setup(t)
+	ctx := context.Background()
+	fn := "/eos/user/e/einstein/file.txt"
+
+	before, err := c.GetFileInfoByPath(ctx, einstein, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, c, einstein, fn, "v2")
+	after, err := c.GetFileInfoByPath(ctx, einstein, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Inode != after.Inode || before.ETag == after.ETag {
+		t.Fatalf("expected a stable inode and a new etag, got %+v and %+v", before, after)
+	}
+
+	versions, err := c.ListVersions(ctx, einstein, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 {
+		t.Fatalf("expected one version, got %d", len(versions))
+	}
+	key := versions[0].File[strings.LastIndex(versions[0].File, "/")+1:]
+
+	r, err := c.ReadVersion(ctx, einstein, fn, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "v1" {
+		t.Fatalf("got %q", data)
+	}
+
+	if err := c.RollbackToVersion(ctx, einstein, fn, key); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, c, einstein, fn); got != "v1" {
+		t.Fatalf("got %q", got)
+	}
+	versions, _ = c.ListVersions(ctx, einstein, fn)
+	if len(versions) != 1 {
+		t.Fatalf("expected the replaced content to become a version, got %d versions", len(versions))
+	}
+}
+
+func TestVersionInvariance(t *testing.T) {
+	c := setup(t)
+	c.opt.VersionInvariant = true
+	ctx := context.Background()
+	fn := "/eos/user/e/einstein/file.txt"
+
+	info, err := c.GetFileInfoByPath(ctx, einstein, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vf, err := c.GetFileInfoByPath(ctx, einstein, getVersionFolder(fn))
+	if err != nil {
+		t.Fatalf("expected the version folder to be created: %v", err)
+	}
+	if info.Inode != vf.Inode {
+		t.Fatalf("expected the inode of the version folder, got %d and %d", info.Inode, vf.Inode)
+	}
+
+	// the file is found by the inode of its version folder, also once renamed
+	if err := c.Rename(ctx, einstein, fn, "/eos/user/e/einstein/renamed.txt"); err != nil {
+		t.Fatal(err)
+	}
+	byInode, err := c.GetFileInfoByInode(ctx, einstein, info.Inode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byInode.IsDir || byInode.File != "/eos/user/e/einstein/renamed.txt" || byInode.Inode != info.Inode {
+		t.Fatalf("unexpected info %+v", byInode)
+	}
+}
+
+func TestRecycle(t *testing.T) {
+	c := setup(t)
+	ctx := context.Background()
+	fn := "/eos/user/e/einstein/file.txt"
+	write(t, c, einstein, fn, "v2")
+
+	if err := c.Remove(ctx, einstein, fn, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetFileInfoByPath(ctx, einstein, fn); !isNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	now := time.Now()
+	entries, err := c.ListDeletedEntries(ctx, einstein, 10, now, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the version folder is recycled as well
+	if len(entries) != 2 {
+		t.Fatalf("expected two entries, got %+v", entries)
+	}
+	if _, err := c.ListDeletedEntries(ctx, einstein, 1, now, now); !isBadRequest(err) {
+		t.Fatalf("expected the list to be too long, got %v", err)
+	}
+	if entries, _ := c.ListDeletedEntries(ctx, marie, 10, now, now); len(entries) != 0 {
+		t.Fatalf("the recycle bin of marie should be empty, got %+v", entries)
+	}
+	if entries, _ := c.ListDeletedEntries(ctx, einstein, 10, now.AddDate(0, 0, -3), now.AddDate(0, 0, -1)); len(entries) != 0 {
+		t.Fatalf("nothing was deleted in the past days, got %+v", entries)
+	}
+
+	var key string
+	for _, e := range entries {
+		if e.RestorePath == fn {
+			key = e.RestoreKey
+		}
+	}
+	if err := c.RestoreDeletedEntry(ctx, einstein, key); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(t, c, einstein, fn); got != "v2" {
+		t.Fatalf("got %q", got)
+	}
+
+	if err := c.PurgeDeletedEntries(ctx, einstein); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := c.ListDeletedEntries(ctx, einstein, 10, now, now); len(entries) != 0 {
+		t.Fatalf("expected an empty recycle bin, got %+v", entries)
+	}
+}
+
+func TestAttrsAndLocks(t *testing.T) {
+	c := setup(t)
+	ctx := context.Background()
+	fn := "/eos/user/e/einstein/file.txt"
+
+	sysAttr := &eosclient.Attribute{Type: eosclient.SystemAttr, Key: "reva.test", Val: "1"}
+	if err := c.SetAttr(ctx, einstein, sysAttr, false, false, fn, ""); !isPermissionDenied(err) {
+		t.Fatalf("expected system attributes to be reserved, got %v", err)
+	}
+	if err := c.SetAttr(ctx, root, sysAttr, true, false, fn, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetAttr(ctx, root, sysAttr, true, false, fn, ""); err != eosclient.AttrAlreadyExistsError {
+		t.Fatalf("expected attr already exists, got %v", err)
+	}
+
+	userCtx := appctx.ContextSetUser(ctx, &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein"}})
+	fav := &eosclient.Attribute{Type: eosclient.UserAttr, Key: favoritesKey, Val: "1"}
+	if err := c.SetAttr(userCtx, einstein, fav, false, false, fn, ""); err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.GetFileInfoByPath(ctx, einstein, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Attrs["sys.reva.test"] != "1" || info.Attrs[favoritesKey] != "u:einstein=1" {
+		t.Fatalf("unexpected attrs %+v", info.Attrs)
+	}
+
+	lock := &eosclient.Attribute{
+		Type: eosclient.SystemAttr,
+		Key:  "app.lock",
+		Val:  fmt.Sprintf("expires:%d,type:shared,owner:*:wopi", time.Now().Add(time.Hour).Unix()),
+	}
+	if err := c.SetAttr(ctx, root, lock, false, false, fn, "wopi"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetAttr(ctx, root, lock, false, false, fn, "other"); err != eosclient.FileIsLockedError {
+		t.Fatalf("expected the lock to be held, got %v", err)
+	}
+	if err := c.Write(ctx, einstein, fn, io.NopCloser(strings.NewReader("v2")), "other"); err != eosclient.FileIsLockedError {
+		t.Fatalf("expected the write to be refused, got %v", err)
+	}
+	if err := c.Write(ctx, einstein, fn, io.NopCloser(strings.NewReader("v2")), "wopi"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UnsetAttr(ctx, root, lock, false, fn, "wopi"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UnsetAttr(ctx, root, lock, false, fn, "wopi"); err != eosclient.AttrNotExistsError {
+		t.Fatalf("expected attr not exists, got %v", err)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	c := setup(t)
+	ctx := context.Background()
+	if err := c.CreateDir(ctx, einstein, "/eos/user/e/einstein/public"); err != nil {
+		t.Fatal(err)
+	}
+	write(t, c, einstein, "/eos/user/e/einstein/public/file.txt", "shared")
+
+	if _, err := c.GenerateToken(ctx, marie, "/eos/user/e/einstein/public/", &acl.Entry{Permissions: "rx"}); !isPermissionDenied(err) {
+		t.Fatalf("only the owner should generate tokens, got %v", err)
+	}
+	tkn, err := c.GenerateToken(ctx, einstein, "/eos/user/e/einstein/public/", &acl.Entry{Permissions: "rx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := eosclient.Authorization{Token: tkn}
+	if got := read(t, c, auth, "/eos/user/e/einstein/public/file.txt"); got != "shared" {
+		t.Fatalf("got %q", got)
+	}
+	if _, err := c.Read(ctx, auth, "/eos/user/e/einstein/file.txt"); !isPermissionDenied(err) {
+		t.Fatalf("the token should be limited to its tree, got %v", err)
+	}
+	err = c.Write(ctx, auth, "/eos/user/e/einstein/public/new.txt", io.NopCloser(strings.NewReader("x")), "")
+	if !isPermissionDenied(err) {
+		t.Fatalf("the token should be read-only, got %v", err)
+	}
+	// the token of a file gives access to its versions
+	write(t, c, einstein, "/eos/user/e/einstein/file.txt", "v2")
+	tkn, err = c.GenerateToken(ctx, einstein, "/eos/user/e/einstein/file.txt", &acl.Entry{Permissions: "rx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if versions, _ := c.ListVersions(ctx, eosclient.Authorization{Token: tkn}, "/eos/user/e/einstein/file.txt"); len(versions) != 1 {
+		t.Fatalf("expected one version, got %d", len(versions))
+	}
+}
+
+func TestQuota(t *testing.T) {
+	c := setup(t)
+	ctx := context.Background()
+	err := c.SetQuota(ctx, root, &eosclient.SetQuotaInfo{
+		Username:  "einstein",
+		UID:       "1000",
+		GID:       "1000",
+		QuotaNode: "/eos/user",
+		MaxBytes:  10,
+		MaxFiles:  100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qi, err := c.GetQuota(ctx, "1000", root, "/eos/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qi.TotalBytes != 10 || qi.UsedBytes != 2 {
+		t.Fatalf("unexpected quota %+v", qi)
+	}
+
+	err = c.Write(ctx, einstein, "/eos/user/e/einstein/big.txt", io.NopCloser(strings.NewReader("0123456789")), "")
+	if _, ok := err.(errtypes.InsufficientStorage); !ok {
+		t.Fatalf("expected insufficient storage, got %v", err)
+	}
+}
+
+func isPermissionDenied(err error) bool {
+	_, ok := err.(errtypes.IsPermissionDenied)
+	return ok
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(errtypes.IsNotFound)
+	return ok
+}
+
+func isBadRequest(err error) bool {
+	_, ok := err.(errtypes.IsBadRequest)
+	return ok
+}
@@ -0,0 +1,143 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package eosmem
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/cs3org/reva/pkg/eosclient"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// deletedEntry is a subtree in the recycle bin of its owner.
+type deletedEntry struct {
+	key          string
+	owner        uint64
+	restorePath  string
+	node         *node
+	deletionTime time.Time
+}
+
+func newDeletedEntry(n *node, restorePath string) *deletedEntry {
+	return &deletedEntry{
+		key:          fmt.Sprintf("%016x", n.inode),
+		owner:        n.uid,
+		restorePath:  restorePath,
+		node:         n,
+		deletionTime: time.Now(),
+	}
+}
+
+// inBin reports whether the entry belongs to the recycle bin of the identity.
+func (e *deletedEntry) inBin(id *identity) bool {
+	return id.privileged || e.owner == id.uid
+}
+
+// ListDeletedEntries returns a list of the deleted entries.
+func (c *Client) ListDeletedEntries(ctx context.Context, auth eosclient.Authorization, maxentries int, from, to time.Time) ([]*eosclient.DeletedEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	// the recycle bin is organized by day, both ends are included
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, to.Location()).AddDate(0, 0, 1)
+
+	var entries []*deletedEntry
+	for _, e := range c.recycle {
+		if e.inBin(id) && !e.deletionTime.Before(start) && e.deletionTime.Before(end) {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) > maxentries {
+		return nil, errtypes.BadRequest("list too long")
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].deletionTime.After(entries[j].deletionTime) })
+
+	deleted := make([]*eosclient.DeletedEntry, 0, len(entries))
+	for _, e := range entries {
+		deleted = append(deleted, &eosclient.DeletedEntry{
+			RestorePath:   e.restorePath,
+			RestoreKey:    e.key,
+			Size:          e.node.treeSize(),
+			DeletionMTime: uint64(e.deletionTime.Unix()),
+			IsDir:         e.node.isDir(),
+		})
+	}
+	return deleted, nil
+}
+
+// RestoreDeletedEntry restores a deleted entry.
+func (c *Client) RestoreDeletedEntry(ctx context.Context, auth eosclient.Authorization, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return err
+	}
+
+	for i, e := range c.recycle {
+		if e.key != key || !e.inBin(id) {
+			continue
+		}
+
+		parent, err := c.lookupDir(path.Dir(e.restorePath))
+		if err != nil {
+			return err
+		}
+		if _, ok := parent.children[e.node.name]; ok {
+			return errtypes.AlreadyExists("eosclient: " + e.restorePath)
+		}
+
+		c.attach(parent, e.node)
+		c.touch(e.node)
+		c.recycle = append(c.recycle[:i], c.recycle[i+1:]...)
+		return nil
+	}
+	return errtypes.NotFound("eosclient: recycle entry " + key)
+}
+
+// PurgeDeletedEntries purges all entries from the recycle bin.
+func (c *Client) PurgeDeletedEntries(ctx context.Context, auth eosclient.Authorization) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id, err := c.identify(auth)
+	if err != nil {
+		return err
+	}
+
+	kept := c.recycle[:0]
+	for _, e := range c.recycle {
+		if !e.inBin(id) {
+			kept = append(kept, e)
+		}
+	}
+	c.recycle = kept
+	return nil
+}
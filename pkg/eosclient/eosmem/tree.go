@@ -0,0 +1,333 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package eosmem
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cs3org/reva/pkg/eosclient"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/acl"
+)
+
+// node is an entry of the emulated namespace.
+type node struct {
+	inode    uint64
+	name     string
+	parent   *node
+	children map[string]*node // nil for files
+	uid, gid uint64
+	mode     string
+	data     []byte
+	ctime    time.Time
+	mtime    time.Time
+	etag     string
+	attrs    map[string]string
+}
+
+func (n *node) isDir() bool {
+	return n.children != nil
+}
+
+func (n *node) path() string {
+	if n.parent == nil {
+		return "/"
+	}
+	return path.Join(n.parent.path(), n.name)
+}
+
+// walk calls fn on n and on all the entries below it.
+func (n *node) walk(fn func(*node)) {
+	fn(n)
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+}
+
+func (n *node) treeSize() uint64 {
+	var size uint64
+	n.walk(func(e *node) {
+		size += uint64(len(e.data))
+	})
+	return size
+}
+
+func (n *node) sortedChildren() []*node {
+	children := make([]*node, 0, len(n.children))
+	for _, child := range n.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].name < children[j].name })
+	return children
+}
+
+func (n *node) sysACL() []*acl.Entry {
+	a, err := acl.Parse(n.attrs["sys.acl"], acl.ShortTextForm)
+	if err != nil {
+		return nil
+	}
+	return a.Entries
+}
+
+// lookup returns the entry at the given absolute path.
+func (c *Client) lookup(p string) (*node, error) {
+	if !path.IsAbs(p) {
+		return nil, errtypes.BadRequest("eosclient: path must be absolute: " + p)
+	}
+	n := c.root
+	for _, name := range strings.Split(strings.Trim(path.Clean(p), "/"), "/") {
+		if name == "" {
+			continue
+		}
+		if !n.isDir() {
+			return nil, errtypes.NotFound("eosclient: " + p)
+		}
+		child, ok := n.children[name]
+		if !ok {
+			return nil, errtypes.NotFound("eosclient: " + p)
+		}
+		n = child
+	}
+	return n, nil
+}
+
+// lookupDir is like lookup but fails if the entry is not a directory.
+func (c *Client) lookupDir(p string) (*node, error) {
+	n, err := c.lookup(p)
+	if err != nil {
+		return nil, err
+	}
+	if !n.isDir() {
+		return nil, errtypes.NotFound("eosclient: not a directory: " + p)
+	}
+	return n, nil
+}
+
+func (c *Client) newNode(parent *node, name string, isDir bool, id *identity) *node {
+	c.lastInode++
+	now := time.Now()
+	n := &node{
+		inode:  c.lastInode,
+		name:   name,
+		parent: parent,
+		uid:    id.uid,
+		gid:    id.gid,
+		ctime:  now,
+		mtime:  now,
+		attrs:  map[string]string{},
+	}
+	if isDir {
+		n.children = map[string]*node{}
+		// like in EOS, new directories inherit the attributes of their parent
+		for k, v := range parent.attrs {
+			n.attrs[k] = v
+		}
+	}
+	c.attach(parent, n)
+	c.touch(n)
+	return n
+}
+
+// attach links the subtree n under parent and indexes it.
+func (c *Client) attach(parent, n *node) {
+	n.parent = parent
+	parent.children[n.name] = n
+	n.walk(func(e *node) {
+		c.inodes[e.inode] = e
+	})
+}
+
+// detach unlinks the subtree n from the namespace.
+func (c *Client) detach(n *node) {
+	delete(n.parent.children, n.name)
+	n.walk(func(e *node) {
+		delete(c.inodes, e.inode)
+	})
+}
+
+// touch updates the modification time and etag of the entry
+// and propagates them to all its ancestors.
+func (c *Client) touch(n *node) {
+	now := time.Now()
+	for e := n; e != nil; e = e.parent {
+		c.lastEtag++
+		e.mtime = now
+		e.etag = strconv.FormatUint(e.inode, 16) + ":" + strconv.FormatUint(c.lastEtag, 10)
+	}
+}
+
+// identity is the resolved authorization of a request.
+type identity struct {
+	uid, gid   uint64
+	privileged bool
+	daemon     bool
+	token      *token
+}
+
+const daemonUID = "2"
+
+func (c *Client) identify(auth eosclient.Authorization) (*identity, error) {
+	if auth.Token != "" {
+		t, ok := c.tokens[auth.Token]
+		if !ok {
+			return nil, errtypes.PermissionDenied("eosclient: invalid token")
+		}
+		if time.Now().After(t.expiration) {
+			return nil, errtypes.PermissionDenied("eosclient: token expired")
+		}
+		return &identity{uid: t.uid, gid: t.gid, token: t}, nil
+	}
+
+	// like for the gateway, an empty role maps to a sudoer account
+	if (auth.Role.UID == "" && auth.Role.GID == "") || auth.Role.UID == "0" {
+		return &identity{privileged: true}, nil
+	}
+	if auth.Role.UID == daemonUID {
+		return &identity{uid: 2, gid: 2, daemon: true}, nil
+	}
+
+	uid, err := strconv.ParseUint(auth.Role.UID, 10, 64)
+	if err != nil {
+		return nil, errtypes.PermissionDenied("eosclient: invalid uid " + auth.Role.UID)
+	}
+	gid, err := strconv.ParseUint(auth.Role.GID, 10, 64)
+	if err != nil {
+		return nil, errtypes.PermissionDenied("eosclient: invalid gid " + auth.Role.GID)
+	}
+	return &identity{uid: uid, gid: gid}, nil
+}
+
+// permSet holds the permissions granted and denied by a set of ACL entries.
+type permSet struct {
+	granted, denied map[byte]bool
+}
+
+func newPermSet() *permSet {
+	return &permSet{granted: map[byte]bool{}, denied: map[byte]bool{}}
+}
+
+// add merges permissions in the EOS form, e.g. rwx!d or rw+d.
+func (s *permSet) add(perms string) {
+	deny := false
+	for i := 0; i < len(perms); i++ {
+		switch p := perms[i]; p {
+		case '!':
+			deny = true
+		case '+':
+			deny = false
+		default:
+			if deny {
+				s.denied[p] = true
+			} else {
+				s.granted[p] = true
+			}
+			deny = false
+		}
+	}
+}
+
+// allows reports whether the permission is granted. The pseudo-permissions
+// 'd' (delete) and 'u' (update) derive from 'w' unless explicitly denied.
+func (s *permSet) allows(perm byte) bool {
+	switch perm {
+	case 'd':
+		return (s.granted['w'] || s.granted['d']) && !s.denied['w'] && !s.denied['d']
+	case 'u':
+		return s.granted['w'] && !s.denied['w'] && !s.denied['u']
+	case 'x':
+		// browsing is allowed to whoever can read
+		return (s.granted['x'] || s.granted['r']) && !s.denied['x']
+	default:
+		return s.granted[perm] && !s.denied[perm]
+	}
+}
+
+// can reports whether the identity holds the permission on the entry.
+// Owners of the entry or of its directory hold all permissions, the others
+// are evaluated against the ACLs of the directory, as EOS does.
+func (c *Client) can(id *identity, n *node, perm byte) bool {
+	switch {
+	case id.privileged:
+		return true
+	case id.daemon:
+		return perm == 'r' || perm == 'x'
+	case id.token != nil:
+		return id.token.allows(n.path(), perm)
+	}
+
+	dir := n
+	if !n.isDir() {
+		dir = n.parent
+	}
+	if n.uid == id.uid || dir.uid == id.uid {
+		return true
+	}
+
+	entries := dir.sysACL()
+	if !n.isDir() {
+		entries = append(n.sysACL(), entries...)
+	}
+	s := newPermSet()
+	for _, e := range entries {
+		if c.matches(id, e) {
+			s.add(e.Permissions)
+		}
+	}
+	return s.allows(perm)
+}
+
+func (c *Client) matches(id *identity, e *acl.Entry) bool {
+	uid := strconv.FormatUint(id.uid, 10)
+	switch e.Type {
+	case acl.TypeUser:
+		return e.Qualifier == uid
+	case "g":
+		return e.Qualifier == strconv.FormatUint(id.gid, 10)
+	case acl.TypeGroup:
+		for _, member := range c.opt.Egroups[e.Qualifier] {
+			if member == uid {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// token is a capability generated on behalf of the owner of a tree.
+type token struct {
+	uid, gid    uint64
+	path        string
+	permissions string
+	expiration  time.Time
+}
+
+func (t *token) allows(p string, perm byte) bool {
+	// the versions of a file are accessible with the token of the file
+	versions := getVersionFolder(t.path)
+	if p != t.path && !strings.HasPrefix(p, strings.TrimSuffix(t.path, "/")+"/") && p != versions && !strings.HasPrefix(p, versions+"/") {
+		return false
+	}
+	s := newPermSet()
+	s.add(t.permissions)
+	return s.allows(perm)
+}
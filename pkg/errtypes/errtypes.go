@@ -126,6 +126,14 @@ func (e InsufficientStorage) Error() string { return "error: insufficient storag
 // IsInsufficientStorage implements the IsInsufficientStorage interface.
 func (e InsufficientStorage) IsInsufficientStorage() {}
 
+// Locked is the error to use when a resource cannot be modified because of a lock.
+type Locked string
+
+func (e Locked) Error() string { return "error: locked: " + string(e) }
+
+// IsLocked implements the IsLocked interface.
+func (e Locked) IsLocked() {}
+
 // StatusInssufficientStorage 507 is an official http status code to indicate that there is insufficient storage
 // https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/507
 const StatusInssufficientStorage = 507
@@ -195,3 +203,9 @@ type IsChecksumMismatch interface {
 type IsInsufficientStorage interface {
 	IsInsufficientStorage()
 }
+
+// IsLocked is the interface to implement
+// to specify that a resource is locked.
+type IsLocked interface {
+	IsLocked()
+}
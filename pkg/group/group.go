@@ -33,3 +33,16 @@ type Manager interface {
 	GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error)
 	HasMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) (bool, error)
 }
+
+// Provisioner is the interface implemented by the group managers
+// that allow the groups to be created, updated and deleted.
+type Provisioner interface {
+	Manager
+	// CreateGroup creates the group g with its members, allocating
+	// its gid number if not set. It returns the created group.
+	CreateGroup(ctx context.Context, g *grouppb.Group) (*grouppb.Group, error)
+	// UpdateGroup replaces the metadata and the members of the group identified by g.Id.
+	UpdateGroup(ctx context.Context, g *grouppb.Group) error
+	// DeleteGroup deletes the group identified by gid.
+	DeleteGroup(ctx context.Context, gid *grouppb.GroupId) error
+}
@@ -0,0 +1,301 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sql implements a group manager reading the groups from
+// the database of the sql user manager.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/group"
+	"github.com/cs3org/reva/pkg/group/manager/registry"
+	usersql "github.com/cs3org/reva/pkg/user/manager/sql"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+const groupColumns = "idp, opaque_id, group_name, mail, display_name, gid_number"
+
+type manager struct {
+	db     *sql.DB
+	engine string
+}
+
+// New returns a group manager reading the groups from a sql database.
+func New(ctx context.Context, m map[string]interface{}) (group.Manager, error) {
+	var c usersql.Config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+
+	db, err := usersql.Open(ctx, &c)
+	if err != nil {
+		return nil, err
+	}
+	return &manager{db: db, engine: c.Engine}, nil
+}
+
+func (m *manager) GetGroup(ctx context.Context, gid *grouppb.GroupId, skipFetchingMembers bool) (*grouppb.Group, error) {
+	query, params := groupQuery(gid)
+	return m.getGroup(ctx, gid.OpaqueId, skipFetchingMembers, query, params...)
+}
+
+// groupQuery returns the query selecting the group identified
+// by gid, where the opaque id can also be the group name.
+func groupQuery(gid *grouppb.GroupId) (string, []any) {
+	query := "SELECT " + groupColumns + " FROM user_groups WHERE (opaque_id=? OR group_name=?)"
+	params := []any{gid.OpaqueId, gid.OpaqueId}
+	if gid.Idp != "" {
+		query += " AND idp=?"
+		params = append(params, gid.Idp)
+	}
+	return query, params
+}
+
+func (m *manager) GetGroupByClaim(ctx context.Context, claim, value string, skipFetchingMembers bool) (*grouppb.Group, error) {
+	var column string
+	var param any = value
+	switch claim {
+	case "group_name":
+		column = "group_name"
+	case "display_name":
+		column = "display_name"
+	case "mail":
+		column = "mail"
+	case "gid_number":
+		gid, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errtypes.BadRequest("sql: invalid gid " + value)
+		}
+		column, param = "gid_number", gid
+	default:
+		return nil, errtypes.NotSupported("sql: invalid field " + claim)
+	}
+	return m.getGroup(ctx, value, skipFetchingMembers, "SELECT "+groupColumns+" FROM user_groups WHERE "+column+"=?", param)
+}
+
+func (m *manager) getGroup(ctx context.Context, key string, skipFetchingMembers bool, query string, params ...any) (*grouppb.Group, error) {
+	g, err := scanGroup(m.db.QueryRowContext(ctx, query, params...))
+	if err == sql.ErrNoRows {
+		return nil, errtypes.NotFound(key)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error getting group")
+	}
+
+	if !skipFetchingMembers {
+		if g.Members, err = m.members(ctx, g.Id); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func scanGroup(row interface{ Scan(...any) error }) (*grouppb.Group, error) {
+	g := &grouppb.Group{Id: &grouppb.GroupId{}}
+	if err := row.Scan(&g.Id.Idp, &g.Id.OpaqueId, &g.GroupName, &g.Mail, &g.DisplayName, &g.GidNumber); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (m *manager) FindGroups(ctx context.Context, query string, skipFetchingMembers bool) ([]*grouppb.Group, error) {
+	pattern := usersql.Like(query)
+	rows, err := m.db.QueryContext(ctx, "SELECT "+groupColumns+` FROM user_groups
+		WHERE LOWER(group_name) LIKE ? ESCAPE '!' OR LOWER(display_name) LIKE ? ESCAPE '!'
+		OR LOWER(mail) LIKE ? ESCAPE '!' OR LOWER(opaque_id) LIKE ? ESCAPE '!'
+		ORDER BY group_name`, pattern, pattern, pattern, pattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error finding groups")
+	}
+	defer rows.Close()
+
+	groups := []*grouppb.Group{}
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "sql: error scanning group")
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "sql: error finding groups")
+	}
+	rows.Close()
+
+	if !skipFetchingMembers {
+		for _, g := range groups {
+			if g.Members, err = m.members(ctx, g.Id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return groups, nil
+}
+
+func (m *manager) GetMembers(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error) {
+	g, err := m.GetGroup(ctx, gid, true)
+	if err != nil {
+		return nil, err
+	}
+	return m.members(ctx, g.Id)
+}
+
+func (m *manager) members(ctx context.Context, gid *grouppb.GroupId) ([]*userpb.UserId, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT user_idp, user_id, user_type FROM group_members WHERE group_idp=? AND group_id=? ORDER BY user_id",
+		gid.Idp, gid.OpaqueId)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error getting group members")
+	}
+	defer rows.Close()
+
+	members := []*userpb.UserId{}
+	for rows.Next() {
+		u := &userpb.UserId{}
+		var userType int32
+		if err := rows.Scan(&u.Idp, &u.OpaqueId, &userType); err != nil {
+			return nil, errors.Wrap(err, "sql: error scanning group member")
+		}
+		u.Type = userpb.UserType(userType)
+		members = append(members, u)
+	}
+	return members, rows.Err()
+}
+
+func (m *manager) HasMember(ctx context.Context, gid *grouppb.GroupId, uid *userpb.UserId) (bool, error) {
+	g, err := m.GetGroup(ctx, gid, true)
+	if err != nil {
+		return false, err
+	}
+
+	var n int
+	if err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM group_members WHERE group_idp=? AND group_id=? AND user_idp=? AND user_id=?",
+		g.Id.Idp, g.Id.OpaqueId, uid.Idp, uid.OpaqueId).Scan(&n); err != nil {
+		return false, errors.Wrap(err, "sql: error checking group membership")
+	}
+	return n > 0, nil
+}
+
+func (m *manager) CreateGroup(ctx context.Context, g *grouppb.Group) (*grouppb.Group, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	g = proto.Clone(g).(*grouppb.Group)
+	if g.GidNumber == 0 {
+		if g.GidNumber, err = usersql.NextIDNumber(ctx, tx, m.engine, "user_groups", "gid_number"); err != nil {
+			return nil, errors.Wrap(err, "sql: error allocating gid number")
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO user_groups ("+groupColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+		g.Id.Idp, g.Id.OpaqueId, g.GroupName, g.Mail, g.DisplayName, g.GidNumber); err != nil {
+		if usersql.IsDuplicate(err) {
+			return nil, errtypes.AlreadyExists(g.GroupName)
+		}
+		return nil, errors.Wrap(err, "sql: error creating group")
+	}
+	if err := insertMembers(ctx, tx, g); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "sql: error creating group")
+	}
+	return g, nil
+}
+
+func (m *manager) UpdateGroup(ctx context.Context, g *grouppb.Group) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "sql: error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// the rows affected by an update do not include the unchanged ones in mysql,
+	// so the group is looked up first to tell apart a missing group
+	var n int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_groups WHERE idp=? AND opaque_id=?", g.Id.Idp, g.Id.OpaqueId).Scan(&n); err != nil {
+		return errors.Wrap(err, "sql: error getting group")
+	}
+	if n == 0 {
+		return errtypes.NotFound(g.Id.OpaqueId)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE user_groups SET group_name=?, mail=?, display_name=?, gid_number=? WHERE idp=? AND opaque_id=?",
+		g.GroupName, g.Mail, g.DisplayName, g.GidNumber, g.Id.Idp, g.Id.OpaqueId); err != nil {
+		if usersql.IsDuplicate(err) {
+			return errtypes.AlreadyExists(g.GroupName)
+		}
+		return errors.Wrap(err, "sql: error updating group")
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM group_members WHERE group_idp=? AND group_id=?", g.Id.Idp, g.Id.OpaqueId); err != nil {
+		return errors.Wrap(err, "sql: error updating group members")
+	}
+	if err := insertMembers(ctx, tx, g); err != nil {
+		return err
+	}
+	return errors.Wrap(tx.Commit(), "sql: error updating group")
+}
+
+func insertMembers(ctx context.Context, tx *sql.Tx, g *grouppb.Group) error {
+	for _, u := range g.Members {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO group_members VALUES (?, ?, ?, ?, ?)",
+			g.Id.Idp, g.Id.OpaqueId, u.Idp, u.OpaqueId, int32(u.Type)); err != nil {
+			if usersql.IsDuplicate(err) {
+				return errtypes.BadRequest("sql: duplicated member " + u.OpaqueId)
+			}
+			return errors.Wrap(err, "sql: error adding group member")
+		}
+	}
+	return nil
+}
+
+func (m *manager) DeleteGroup(ctx context.Context, gid *grouppb.GroupId) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "sql: error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM user_groups WHERE idp=? AND opaque_id=?", gid.Idp, gid.OpaqueId)
+	if err != nil {
+		return errors.Wrap(err, "sql: error deleting group")
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return errors.Wrap(err, "sql: error deleting group")
+	} else if n == 0 {
+		return errtypes.NotFound(gid.OpaqueId)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM group_members WHERE group_idp=? AND group_id=?", gid.Idp, gid.OpaqueId); err != nil {
+		return errors.Wrap(err, "sql: error deleting group members")
+	}
+	return errors.Wrap(tx.Commit(), "sql: error deleting group")
+}
@@ -0,0 +1,277 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+var (
+	einstein = &userpb.UserId{OpaqueId: "4c510ada-c86b-4815-8820-42cdf82c3d51", Idp: "cernbox.cern.ch", Type: userpb.UserType_USER_TYPE_PRIMARY}
+	marie    = &userpb.UserId{OpaqueId: "f7fbf8c8-139b-4376-b307-cf0a8c2d0d9c", Idp: "cernbox.cern.ch", Type: userpb.UserType_USER_TYPE_PRIMARY}
+)
+
+func newManager(t *testing.T) *manager {
+	ctx := context.Background()
+	mgr, err := New(ctx, map[string]interface{}{"db_file": filepath.Join(t.TempDir(), "identity.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mgr.(*manager)
+	t.Cleanup(func() { m.db.Close() })
+
+	for _, stmt := range []string{
+		`INSERT INTO user_groups VALUES ('cernbox.cern.ch', '6040aa17-9c64-4fef-9bd0-77234d71bad0', 'sailing-lovers', 'sailing@example.org', 'Sailing Lovers', 1234)`,
+		`INSERT INTO user_groups VALUES ('cernbox.cern.ch', 'dd58e5ec-2eff-4fba-8d23-1cf5b7e5f6b2', 'physics-lovers', 'physics@example.org', 'Physics Lovers', 4567)`,
+		`INSERT INTO user_groups VALUES ('cernbox.cern.ch', '262982c1-2362-4afa-bfdf-8cbfef64a06e', 'quantum_lovers', 'quantum@example.org', 'Quantum Lovers', 7890)`,
+		`INSERT INTO group_members VALUES ('cernbox.cern.ch', '6040aa17-9c64-4fef-9bd0-77234d71bad0', 'cernbox.cern.ch', '4c510ada-c86b-4815-8820-42cdf82c3d51', 1)`,
+		`INSERT INTO group_members VALUES ('cernbox.cern.ch', 'dd58e5ec-2eff-4fba-8d23-1cf5b7e5f6b2', 'cernbox.cern.ch', '4c510ada-c86b-4815-8820-42cdf82c3d51', 1)`,
+		`INSERT INTO group_members VALUES ('cernbox.cern.ch', 'dd58e5ec-2eff-4fba-8d23-1cf5b7e5f6b2', 'cernbox.cern.ch', 'f7fbf8c8-139b-4376-b307-cf0a8c2d0d9c', 1)`,
+	} {
+		if _, err := m.db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestGetGroup(t *testing.T) {
+	m := newManager(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		description string
+		gid         *grouppb.GroupId
+		expected    string
+		members     []*userpb.UserId
+	}{
+		{
+			description: "by opaque id",
+			gid:         &grouppb.GroupId{OpaqueId: "dd58e5ec-2eff-4fba-8d23-1cf5b7e5f6b2", Idp: "cernbox.cern.ch"},
+			expected:    "physics-lovers",
+			members:     []*userpb.UserId{einstein, marie},
+		},
+		{
+			description: "by group name",
+			gid:         &grouppb.GroupId{OpaqueId: "sailing-lovers"},
+			expected:    "sailing-lovers",
+			members:     []*userpb.UserId{einstein},
+		},
+		{
+			description: "without members",
+			gid:         &grouppb.GroupId{OpaqueId: "quantum_lovers"},
+			expected:    "quantum_lovers",
+			members:     []*userpb.UserId{},
+		},
+		{
+			description: "unknown group",
+			gid:         &grouppb.GroupId{OpaqueId: "chemistry-lovers"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			g, err := m.GetGroup(ctx, tt.gid, false)
+			if tt.expected == "" {
+				if _, ok := err.(errtypes.IsNotFound); !ok {
+					t.Fatalf("expected not found error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g.GroupName != tt.expected {
+				t.Fatalf("got group %s, expected %s", g.GroupName, tt.expected)
+			}
+			if len(g.Members) != len(tt.members) {
+				t.Fatalf("got members %v, expected %v", g.Members, tt.members)
+			}
+			for i, u := range g.Members {
+				if u.OpaqueId != tt.members[i].OpaqueId || u.Idp != tt.members[i].Idp || u.Type != tt.members[i].Type {
+					t.Fatalf("got members %v, expected %v", g.Members, tt.members)
+				}
+			}
+		})
+	}
+}
+
+func TestGetGroupByClaim(t *testing.T) {
+	m := newManager(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		description string
+		claim       string
+		value       string
+		expected    string
+	}{
+		{description: "group name", claim: "group_name", value: "sailing-lovers", expected: "sailing-lovers"},
+		{description: "gid number", claim: "gid_number", value: "4567", expected: "physics-lovers"},
+		{description: "display name", claim: "display_name", value: "Quantum Lovers", expected: "quantum_lovers"},
+		{description: "mail", claim: "mail", value: "physics@example.org", expected: "physics-lovers"},
+		{description: "unknown mail", claim: "mail", value: "chemistry@example.org"},
+		{description: "unsupported claim", claim: "uid", value: "123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			g, err := m.GetGroupByClaim(ctx, tt.claim, tt.value, true)
+			if tt.expected == "" {
+				if err == nil {
+					t.Fatalf("expected error, got group %+v", g)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g.GroupName != tt.expected || g.Members != nil {
+				t.Fatalf("got group %+v, expected %s without members", g, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFindGroups(t *testing.T) {
+	m := newManager(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		description string
+		query       string
+		expected    []string
+	}{
+		{description: "group name", query: "lovers", expected: []string{"physics-lovers", "quantum_lovers", "sailing-lovers"}},
+		{description: "case insensitive display name", query: "SAILING", expected: []string{"sailing-lovers"}},
+		{description: "wildcards are escaped", query: "_", expected: []string{"quantum_lovers"}},
+		{description: "no match", query: "chemistry", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			groups, err := m.FindGroups(ctx, tt.query, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(groups) != len(tt.expected) {
+				t.Fatalf("got %d groups, expected %v", len(groups), tt.expected)
+			}
+			for i, g := range groups {
+				if g.GroupName != tt.expected[i] {
+					t.Fatalf("got group %s, expected %s", g.GroupName, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHasMember(t *testing.T) {
+	m := newManager(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		description string
+		gid         *grouppb.GroupId
+		uid         *userpb.UserId
+		expected    bool
+	}{
+		{description: "member", gid: &grouppb.GroupId{OpaqueId: "sailing-lovers"}, uid: einstein, expected: true},
+		{description: "not a member", gid: &grouppb.GroupId{OpaqueId: "sailing-lovers"}, uid: marie, expected: false},
+		{description: "different idp", gid: &grouppb.GroupId{OpaqueId: "sailing-lovers"}, uid: &userpb.UserId{OpaqueId: einstein.OpaqueId, Idp: "cesnet.cz"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			ok, err := m.HasMember(ctx, tt.gid, tt.uid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.expected {
+				t.Fatalf("got %t, expected %t", ok, tt.expected)
+			}
+		})
+	}
+
+	if _, err := m.HasMember(ctx, &grouppb.GroupId{OpaqueId: "chemistry-lovers"}, einstein); err == nil {
+		t.Fatal("expected an error for an unknown group")
+	}
+}
+
+func TestProvisioning(t *testing.T) {
+	m := newManager(t)
+	ctx := context.Background()
+
+	created, err := m.CreateGroup(ctx, &grouppb.Group{
+		Id:          &grouppb.GroupId{OpaqueId: "e1b3b0c6-5a0e-4b71-9b56-0a3c1a2a8a46", Idp: "cernbox.cern.ch"},
+		GroupName:   "chemistry-lovers",
+		DisplayName: "Chemistry Lovers",
+		Members:     []*userpb.UserId{marie},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.GidNumber != 10000 {
+		t.Fatalf("got gid number %d, expected 10000", created.GidNumber)
+	}
+	if ok, err := m.HasMember(ctx, created.Id, marie); err != nil || !ok {
+		t.Fatalf("expected marie to be a member, got %t %v", ok, err)
+	}
+
+	if _, err := m.CreateGroup(ctx, &grouppb.Group{Id: &grouppb.GroupId{OpaqueId: "other", Idp: "cernbox.cern.ch"}, GroupName: "chemistry-lovers"}); err == nil {
+		t.Fatal("expected group names to be unique")
+	} else if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+
+	created.DisplayName = "Chemistry Fans"
+	created.Members = []*userpb.UserId{einstein}
+	if err := m.UpdateGroup(ctx, created); err != nil {
+		t.Fatal(err)
+	}
+	g, err := m.GetGroup(ctx, created.Id, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.DisplayName != "Chemistry Fans" || len(g.Members) != 1 || g.Members[0].OpaqueId != einstein.OpaqueId {
+		t.Fatalf("group was not updated: %+v", g)
+	}
+
+	if err := m.DeleteGroup(ctx, created.Id); err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range []error{
+		m.UpdateGroup(ctx, created),
+		m.DeleteGroup(ctx, created.Id),
+	} {
+		if _, ok := err.(errtypes.IsNotFound); !ok {
+			t.Fatalf("expected not found error, got %v", err)
+		}
+	}
+	var n int
+	if err := m.db.QueryRow("SELECT COUNT(*) FROM group_members WHERE group_id=?", created.Id.OpaqueId).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("expected the members to be deleted, got %d %v", n, err)
+	}
+}
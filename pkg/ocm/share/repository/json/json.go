@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"reflect"
 	"sync"
 	"time"
 
@@ -374,7 +375,64 @@ func receivedShareEqual(ref *ocm.ShareReference, s *ocm.ReceivedShare) bool {
 }
 
 func (m *mgr) UpdateShare(ctx context.Context, user *userpb.User, ref *ocm.ShareReference, f ...*ocm.UpdateOCMShareRequest_UpdateField) (*ocm.Share, error) {
-	return nil, errtypes.NotSupported("not yet implemented")
+	m.Lock()
+	defer m.Unlock()
+
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	for _, s := range m.model.Shares {
+		if !sharesEqual(ref, s) || !(utils.UserEqual(user.Id, s.Owner) || utils.UserEqual(user.Id, s.Creator)) {
+			continue
+		}
+		for _, field := range f {
+			switch u := field.Field.(type) {
+			case *ocm.UpdateOCMShareRequest_UpdateField_Expiration:
+				s.Expiration = u.Expiration
+			case *ocm.UpdateOCMShareRequest_UpdateField_AccessMethods:
+				updateAccessMethod(s, u.AccessMethods)
+			}
+		}
+		now := time.Now().UnixNano()
+		s.Mtime = &typespb.Timestamp{
+			Seconds: uint64(now / 1000000000),
+			Nanos:   uint32(now % 1000000000),
+		}
+		if err := m.save(); err != nil {
+			return nil, err
+		}
+		return cloneShare(s), nil
+	}
+	return nil, share.ErrShareNotFound
+}
+
+// updateAccessMethod replaces the access method of the share having the same type as am.
+func updateAccessMethod(s *ocm.Share, am *ocm.AccessMethod) {
+	for i, a := range s.AccessMethods {
+		if reflect.TypeOf(a.Term) == reflect.TypeOf(am.Term) {
+			s.AccessMethods[i] = am
+		}
+	}
+}
+
+// ListExpiredShares returns the shares of all the users expired before t.
+func (m *mgr) ListExpiredShares(ctx context.Context, t time.Time) ([]*ocm.Share, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	var ss []*ocm.Share
+	for _, s := range m.model.Shares {
+		e := s.GetExpiration()
+		if e.GetSeconds() != 0 && time.Unix(int64(e.Seconds), int64(e.Nanos)).Before(t) {
+			ss = append(ss, cloneShare(s))
+		}
+	}
+	return ss, nil
 }
 
 func (m *mgr) ListShares(ctx context.Context, user *userpb.User, filters []*ocm.ListOCMSharesRequest_Filter) ([]*ocm.Share, error) {
@@ -466,16 +524,36 @@ func (m *mgr) GetReceivedShare(ctx context.Context, user *userpb.User, ref *ocm.
 		return nil, err
 	}
 
-	for _, share := range m.model.ReceivedShares {
-		if receivedShareEqual(ref, share) {
-			if share.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER && utils.UserEqual(user.Id, share.Grantee.GetUserId()) {
-				return share, nil
+	for _, s := range m.model.ReceivedShares {
+		if ref.GetToken() != "" && share.SharedSecret(s) == ref.GetToken() {
+			return s, nil
+		}
+		if receivedShareEqual(ref, s) {
+			if s.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER && utils.UserEqual(user.Id, s.Grantee.GetUserId()) {
+				return s, nil
 			}
 		}
 	}
 	return nil, errtypes.NotFound(ref.String())
 }
 
+func (m *mgr) DeleteReceivedShare(ctx context.Context, user *userpb.User, ref *ocm.ShareReference) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if err := m.load(); err != nil {
+		return err
+	}
+
+	for id, s := range m.model.ReceivedShares {
+		if receivedShareEqual(ref, s) && utils.UserEqual(user.Id, s.Grantee.GetUserId()) {
+			delete(m.model.ReceivedShares, id)
+			return m.save()
+		}
+	}
+	return share.ErrShareNotFound
+}
+
 func (m *mgr) UpdateReceivedShare(ctx context.Context, user *userpb.User, share *ocm.ReceivedShare, fieldMask *field_mask.FieldMask) (*ocm.ReceivedShare, error) {
 	rs, err := m.GetReceivedShare(ctx, user, &ocm.ShareReference{Spec: &ocm.ShareReference_Id{Id: share.Id}})
 	if err != nil {
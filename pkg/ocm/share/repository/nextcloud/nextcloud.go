@@ -412,6 +412,17 @@ func (sm *Manager) GetReceivedShare(ctx context.Context, user *userpb.User, ref
 	return efssReceivedShareToOcm(&resp), nil
 }
 
+// DeleteReceivedShare deletes the received share pointed by ref.
+func (sm *Manager) DeleteReceivedShare(ctx context.Context, user *userpb.User, ref *ocm.ShareReference) error {
+	bodyStr, err := json.Marshal(ref)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = sm.do(ctx, Action{"DeleteReceivedShare", string(bodyStr)}, getUsername(user))
+	return err
+}
+
 // UpdateReceivedShare updates the received share with share state.
 func (sm *Manager) UpdateReceivedShare(ctx context.Context, user *userpb.User, share *ocm.ReceivedShare, fieldMask *field_mask.FieldMask) (*ocm.ReceivedShare, error) {
 	type paramsObj struct {
@@ -485,6 +485,17 @@ func (m *mgr) ListShares(ctx context.Context, user *userpb.User, filters []*ocm.
 		params = append(params, filterParams...)
 	}
 
+	return m.listShares(ctx, query, params...)
+}
+
+// ListExpiredShares returns the shares of all the users expired before t.
+func (m *mgr) ListExpiredShares(ctx context.Context, t time.Time) ([]*ocm.Share, error) {
+	query := "SELECT id, token, fileid_prefix, item_source, name, share_with, owner, initiator, ctime, mtime, expiration, type FROM ocm_shares WHERE expiration>0 AND expiration<?"
+	return m.listShares(ctx, query, t.Unix())
+}
+
+// listShares returns the shares, with their access methods, selected by query.
+func (m *mgr) listShares(ctx context.Context, query string, params ...any) ([]*ocm.Share, error) {
 	rows, err := m.db.QueryContext(ctx, query, params...)
 	if err != nil {
 		return nil, err
@@ -725,6 +736,8 @@ func (m *mgr) GetReceivedShare(ctx context.Context, user *userpb.User, ref *ocm.
 	switch {
 	case ref.GetId() != nil:
 		s, err = m.getReceivedByID(ctx, user, ref.GetId())
+	case ref.GetToken() != "":
+		s, err = m.getReceivedByToken(ctx, ref.GetToken())
 	default:
 		err = errtypes.NotFound(ref.String())
 	}
@@ -734,8 +747,15 @@ func (m *mgr) GetReceivedShare(ctx context.Context, user *userpb.User, ref *ocm.
 
 func (m *mgr) getReceivedByID(ctx context.Context, user *userpb.User, id *ocm.ShareId) (*ocm.ReceivedShare, error) {
 	query := "SELECT id, name, remote_share_id, item_type, share_with, owner, initiator, ctime, mtime, expiration, type, state FROM ocm_received_shares WHERE id=? AND share_with=?"
-	params := []any{id.OpaqueId, user.Id.OpaqueId}
+	return m.getReceived(ctx, query, id.OpaqueId, user.Id.OpaqueId)
+}
+
+func (m *mgr) getReceivedByToken(ctx context.Context, token string) (*ocm.ReceivedShare, error) {
+	query := "SELECT id, name, remote_share_id, item_type, share_with, owner, initiator, ctime, mtime, expiration, type, state FROM ocm_received_shares WHERE id IN (SELECT p.ocm_received_share_id FROM ocm_received_share_protocols as p LEFT JOIN ocm_protocol_webdav as dav ON p.id=dav.ocm_protocol_id LEFT JOIN ocm_protocol_transfer as tx ON p.id=tx.ocm_protocol_id WHERE dav.shared_secret=? OR tx.shared_secret=?)"
+	return m.getReceived(ctx, query, token, token)
+}
 
+func (m *mgr) getReceived(ctx context.Context, query string, params ...any) (*ocm.ReceivedShare, error) {
 	var s dbReceivedShare
 	if err := m.db.QueryRowContext(ctx, query, params...).Scan(&s.ID, &s.Name, &s.RemoteShareID, &s.ItemType, &s.ShareWith, &s.Owner, &s.Initiator, &s.Ctime, &s.Mtime, &s.Expiration, &s.Type, &s.State); err != nil {
 		if err == sql.ErrNoRows {
@@ -771,6 +791,23 @@ func (m *mgr) getProtocols(ctx context.Context, id int) ([]*ocm.Protocol, error)
 	return protocols, nil
 }
 
+// DeleteReceivedShare deletes the received share pointed by ref.
+func (m *mgr) DeleteReceivedShare(ctx context.Context, user *userpb.User, ref *ocm.ShareReference) error {
+	if ref.GetId() == nil {
+		return errtypes.NotFound(ref.String())
+	}
+
+	query := "DELETE FROM ocm_received_shares WHERE id=? AND share_with=?"
+	res, err := m.db.ExecContext(ctx, query, ref.GetId().OpaqueId, user.Id.OpaqueId)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return share.ErrShareNotFound
+	}
+	return nil
+}
+
 // UpdateReceivedShare updates the received share with share state.
 func (m *mgr) UpdateReceivedShare(ctx context.Context, user *userpb.User, s *ocm.ReceivedShare, fieldMask *field_mask.FieldMask) (*ocm.ReceivedShare, error) {
 	query := "UPDATE ocm_received_shares SET"
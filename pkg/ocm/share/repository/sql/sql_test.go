@@ -1078,6 +1078,59 @@ func TestListShares(t *testing.T) {
 	}
 }
 
+func TestListExpiredShares(t *testing.T) {
+	newShare := func(id, resource string, expiration uint64) *ocm.Share {
+		return &ocm.Share{
+			Id:         &ocm.ShareId{OpaqueId: id},
+			ResourceId: &providerv1beta1.ResourceId{StorageId: "storage", OpaqueId: resource},
+			Name:       "file-name",
+			Token:      "token-" + id,
+			Grantee:    &providerv1beta1.Grantee{Type: providerv1beta1.GranteeType_GRANTEE_TYPE_USER, Id: &providerv1beta1.Grantee_UserId{UserId: &userpb.UserId{Idp: "cesnet", OpaqueId: "marie"}}},
+			Owner:      &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"},
+			Creator:    &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"},
+			Ctime:      &typesv1beta1.Timestamp{Seconds: 1670859468},
+			Mtime:      &typesv1beta1.Timestamp{Seconds: 1670859468},
+			Expiration: &typesv1beta1.Timestamp{Seconds: expiration},
+			ShareType:  ocm.ShareType_SHARE_TYPE_USER,
+			AccessMethods: []*ocm.AccessMethod{
+				share.NewWebDavAccessMethod(conversions.NewViewerRole().CS3ResourcePermissions(), []string{}),
+			},
+		}
+	}
+
+	ctx := sql.NewEmptyContext()
+	tables := createShareTables(ctx, []*ocm.Share{
+		newShare("1", "no-expiration", 0),
+		newShare("2", "expired", 1670859468),
+		newShare("3", "not-expired", 1670859470),
+	})
+	_, port, cleanup := startDatabase(ctx, tables)
+	t.Cleanup(cleanup)
+
+	r, err := New(context.Background(), map[string]interface{}{
+		"db_username": "root",
+		"db_password": "",
+		"db_address":  fmt.Sprintf("%s:%d", address, port),
+		"db_name":     dbName,
+	})
+	if err != nil {
+		t.Fatalf("not expected error while creating share repository driver: %+v", err)
+	}
+
+	got, err := r.(share.ExpiredSharesLister).ListExpiredShares(context.TODO(), time.Unix(1670859469, 0))
+	if err != nil {
+		t.Fatalf("not expected error while listing expired shares: %+v", err)
+	}
+
+	expected := newShare("2", "expired", 1670859468)
+	expected.Grantee.GetUserId().Type = userpb.UserType_USER_TYPE_FEDERATED
+	expected.Owner = &userpb.UserId{OpaqueId: "einstein"}
+	expected.Creator = &userpb.UserId{OpaqueId: "einstein"}
+	if !reflect.DeepEqual(got, []*ocm.Share{expected}) {
+		t.Fatalf("list of expired shares do not match. got=%+v expected=%+v", render.AsCode(got), render.AsCode(expected))
+	}
+}
+
 type storeShareExpected struct {
 	shares        []sql.Row
 	accessmethods []sql.Row
@@ -1605,6 +1658,88 @@ func TestGetReceivedShare(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "query by token",
+			shares: []*ocm.ReceivedShare{
+				{
+					Id:            &ocm.ShareId{OpaqueId: "1"},
+					RemoteShareId: "1-remote",
+					Name:          "file-name",
+					Grantee:       &providerv1beta1.Grantee{Type: providerv1beta1.GranteeType_GRANTEE_TYPE_USER, Id: &providerv1beta1.Grantee_UserId{UserId: &userpb.UserId{Idp: "cesnet", OpaqueId: "marie"}}},
+					Owner:         &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"},
+					Creator:       &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"},
+					Ctime:         &typesv1beta1.Timestamp{Seconds: 1670859468},
+					Mtime:         &typesv1beta1.Timestamp{Seconds: 1670859468},
+					ShareType:     ocm.ShareType_SHARE_TYPE_USER,
+					State:         ocm.ShareState_SHARE_STATE_ACCEPTED,
+					ResourceType:  providerv1beta1.ResourceType_RESOURCE_TYPE_FILE,
+					Protocols: []*ocm.Protocol{
+						share.NewWebDAVProtocol("webdav+https//cernbox.cern.ch/dav/ocm/1", "secret", &ocm.SharePermissions{
+							Permissions: conversions.NewEditorRole().CS3ResourcePermissions(),
+						}, []string{}),
+					},
+				},
+				{
+					Id:            &ocm.ShareId{OpaqueId: "2"},
+					RemoteShareId: "2-remote",
+					Name:          "other-file-name",
+					Grantee:       &providerv1beta1.Grantee{Type: providerv1beta1.GranteeType_GRANTEE_TYPE_USER, Id: &providerv1beta1.Grantee_UserId{UserId: &userpb.UserId{Idp: "cesnet", OpaqueId: "marie"}}},
+					Owner:         &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"},
+					Creator:       &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"},
+					Ctime:         &typesv1beta1.Timestamp{Seconds: 1670859468},
+					Mtime:         &typesv1beta1.Timestamp{Seconds: 1670859468},
+					ShareType:     ocm.ShareType_SHARE_TYPE_USER,
+					State:         ocm.ShareState_SHARE_STATE_PENDING,
+					ResourceType:  providerv1beta1.ResourceType_RESOURCE_TYPE_FILE,
+					Protocols: []*ocm.Protocol{
+						share.NewTransferProtocol("webdav+https//cernbox.cern.ch/dav/ocm/2", "other-secret", 10),
+					},
+				},
+			},
+			query: &ocm.ShareReference{Spec: &ocm.ShareReference_Token{Token: "other-secret"}},
+			expected: &ocm.ReceivedShare{
+				Id:            &ocm.ShareId{OpaqueId: "2"},
+				RemoteShareId: "2-remote",
+				Name:          "other-file-name",
+				Grantee:       &providerv1beta1.Grantee{Type: providerv1beta1.GranteeType_GRANTEE_TYPE_USER, Id: &providerv1beta1.Grantee_UserId{UserId: &userpb.UserId{OpaqueId: "marie"}}},
+				Owner:         &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein", Type: userpb.UserType_USER_TYPE_FEDERATED},
+				Creator:       &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein", Type: userpb.UserType_USER_TYPE_FEDERATED},
+				Ctime:         &typesv1beta1.Timestamp{Seconds: 1670859468},
+				Mtime:         &typesv1beta1.Timestamp{Seconds: 1670859468},
+				ShareType:     ocm.ShareType_SHARE_TYPE_USER,
+				State:         ocm.ShareState_SHARE_STATE_PENDING,
+				ResourceType:  providerv1beta1.ResourceType_RESOURCE_TYPE_FILE,
+				Expiration:    &typesv1beta1.Timestamp{},
+				Protocols: []*ocm.Protocol{
+					share.NewTransferProtocol("webdav+https//cernbox.cern.ch/dav/ocm/2", "other-secret", 10),
+				},
+			},
+		},
+		{
+			description: "query by token - non existing secret",
+			shares: []*ocm.ReceivedShare{
+				{
+					Id:            &ocm.ShareId{OpaqueId: "1"},
+					RemoteShareId: "1-remote",
+					Name:          "file-name",
+					Grantee:       &providerv1beta1.Grantee{Type: providerv1beta1.GranteeType_GRANTEE_TYPE_USER, Id: &providerv1beta1.Grantee_UserId{UserId: &userpb.UserId{Idp: "cesnet", OpaqueId: "marie"}}},
+					Owner:         &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"},
+					Creator:       &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"},
+					Ctime:         &typesv1beta1.Timestamp{Seconds: 1670859468},
+					Mtime:         &typesv1beta1.Timestamp{Seconds: 1670859468},
+					ShareType:     ocm.ShareType_SHARE_TYPE_USER,
+					State:         ocm.ShareState_SHARE_STATE_ACCEPTED,
+					ResourceType:  providerv1beta1.ResourceType_RESOURCE_TYPE_FILE,
+					Protocols: []*ocm.Protocol{
+						share.NewWebDAVProtocol("webdav+https//cernbox.cern.ch/dav/ocm/1", "secret", &ocm.SharePermissions{
+							Permissions: conversions.NewEditorRole().CS3ResourcePermissions(),
+						}, []string{}),
+					},
+				},
+			},
+			query: &ocm.ShareReference{Spec: &ocm.ShareReference_Token{Token: "wrong-secret"}},
+			err:   share.ErrShareNotFound,
+		},
 		{
 			description: "query by id - different user",
 			shares: []*ocm.ReceivedShare{
@@ -1824,6 +1959,86 @@ func TestUpdateReceivedShare(t *testing.T) {
 	}
 }
 
+func TestDeleteReceivedShare(t *testing.T) {
+	shares := []*ocm.ReceivedShare{
+		{
+			Id:            &ocm.ShareId{OpaqueId: "1"},
+			RemoteShareId: "1-remote",
+			Name:          "file-name",
+			Grantee:       &providerv1beta1.Grantee{Type: providerv1beta1.GranteeType_GRANTEE_TYPE_USER, Id: &providerv1beta1.Grantee_UserId{UserId: &userpb.UserId{Idp: "cesnet", OpaqueId: "marie"}}},
+			Owner:         &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"},
+			Creator:       &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"},
+			Ctime:         &typesv1beta1.Timestamp{Seconds: 1670859468},
+			Mtime:         &typesv1beta1.Timestamp{Seconds: 1670859468},
+			ShareType:     ocm.ShareType_SHARE_TYPE_USER,
+			State:         ocm.ShareState_SHARE_STATE_ACCEPTED,
+			ResourceType:  providerv1beta1.ResourceType_RESOURCE_TYPE_FILE,
+			Protocols: []*ocm.Protocol{
+				share.NewWebDAVProtocol("webdav+https//cernbox.cern.ch/dav/ocm/1", "secret", &ocm.SharePermissions{
+					Permissions: conversions.NewEditorRole().CS3ResourcePermissions(),
+				}, []string{}),
+			},
+		},
+	}
+
+	tests := []struct {
+		description string
+		user        *userpb.User
+		ref         *ocm.ShareReference
+		err         error
+		expected    []sql.Row
+	}{
+		{
+			description: "delete existing share",
+			user:        &userpb.User{Id: &userpb.UserId{Idp: "cesnet", OpaqueId: "marie"}},
+			ref:         &ocm.ShareReference{Spec: &ocm.ShareReference_Id{Id: &ocm.ShareId{OpaqueId: "1"}}},
+			expected:    []sql.Row{},
+		},
+		{
+			description: "delete share of a different user",
+			user:        &userpb.User{Id: &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"}},
+			ref:         &ocm.ShareReference{Spec: &ocm.ShareReference_Id{Id: &ocm.ShareId{OpaqueId: "1"}}},
+			err:         share.ErrShareNotFound,
+		},
+		{
+			description: "delete non existing share",
+			user:        &userpb.User{Id: &userpb.UserId{Idp: "cesnet", OpaqueId: "marie"}},
+			ref:         &ocm.ShareReference{Spec: &ocm.ShareReference_Id{Id: &ocm.ShareId{OpaqueId: "2"}}},
+			err:         share.ErrShareNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			ctx := sql.NewEmptyContext()
+			tables := createReceivedShareTables(ctx, shares)
+			engine, port, cleanup := startDatabase(ctx, tables)
+			t.Cleanup(cleanup)
+
+			r, err := New(context.Background(), map[string]interface{}{
+				"db_username": "root",
+				"db_password": "",
+				"db_address":  fmt.Sprintf("%s:%d", address, port),
+				"db_name":     dbName,
+			})
+
+			if err != nil {
+				t.Fatalf("not expected error while creating share repository driver: %+v", err)
+			}
+
+			err = r.DeleteReceivedShare(context.TODO(), tt.user, tt.ref)
+			if err != tt.err {
+				t.Fatalf("not expected error deleting share. got=%+v expected=%+v", err, tt.err)
+			}
+
+			if tt.err == nil {
+				// the protocols are removed by the database on cascade
+				checkRows(ctx, engine, tt.expected, ocmReceivedShareTable, t)
+			}
+		})
+	}
+}
+
 func TestListReceviedShares(t *testing.T) {
 	tests := []struct {
 		description string
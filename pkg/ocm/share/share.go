@@ -20,10 +20,12 @@ package share
 
 import (
 	"context"
+	"time"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"google.golang.org/genproto/protobuf/field_mask"
 )
@@ -53,12 +55,34 @@ type Repository interface {
 	ListReceivedShares(ctx context.Context, user *userpb.User) ([]*ocm.ReceivedShare, error)
 
 	// GetReceivedShare returns the information for a received share the user has access.
+	// If ref is a token, the received share whose protocols have it as shared secret
+	// is returned, and user can be nil.
 	GetReceivedShare(ctx context.Context, user *userpb.User, ref *ocm.ShareReference) (*ocm.ReceivedShare, error)
 
+	// DeleteReceivedShare deletes the received share pointed by ref.
+	DeleteReceivedShare(ctx context.Context, user *userpb.User, ref *ocm.ShareReference) error
+
 	// UpdateReceivedShare updates the received share with share state.
 	UpdateReceivedShare(ctx context.Context, user *userpb.User, share *ocm.ReceivedShare, fieldMask *field_mask.FieldMask) (*ocm.ReceivedShare, error)
 }
 
+// ExpiredSharesLister is implemented by the repositories able to list
+// the expired shares of all the users.
+type ExpiredSharesLister interface {
+	// ListExpiredShares returns the shares expired before t.
+	ListExpiredShares(ctx context.Context, t time.Time) ([]*ocm.Share, error)
+}
+
+// IsExpired returns whether the share, either sent or received, is expired.
+// A share without expiration, or with a zero expiration, never expires.
+func IsExpired(s interface{ GetExpiration() *typespb.Timestamp }) bool {
+	e := s.GetExpiration()
+	if e.GetSeconds() == 0 && e.GetNanos() == 0 {
+		return false
+	}
+	return time.Now().After(time.Unix(int64(e.Seconds), int64(e.Nanos)))
+}
+
 // ResourceIDFilter is an abstraction for creating filter by resource id.
 func ResourceIDFilter(id *provider.ResourceId) *ocm.ListOCMSharesRequest_Filter {
 	return &ocm.ListOCMSharesRequest_Filter{
@@ -0,0 +1,67 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package share
+
+import (
+	"testing"
+	"time"
+
+	ocm "github.com/cs3org/go-cs3apis/cs3/sharing/ocm/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+func TestIsExpired(t *testing.T) {
+	tests := []struct {
+		description string
+		expiration  *typespb.Timestamp
+		expected    bool
+	}{
+		{
+			description: "no expiration",
+			expiration:  nil,
+			expected:    false,
+		},
+		{
+			description: "zero expiration",
+			expiration:  &typespb.Timestamp{},
+			expected:    false,
+		},
+		{
+			description: "expired",
+			expiration:  &typespb.Timestamp{Seconds: uint64(time.Now().Add(-time.Minute).Unix())},
+			expected:    true,
+		},
+		{
+			description: "not expired",
+			expiration:  &typespb.Timestamp{Seconds: uint64(time.Now().Add(time.Hour).Unix())},
+			expected:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			if got := IsExpired(&ocm.Share{Expiration: tt.expiration}); got != tt.expected {
+				t.Errorf("share: got %v, expected %v", got, tt.expected)
+			}
+			if got := IsExpired(&ocm.ReceivedShare{Expiration: tt.expiration}); got != tt.expected {
+				t.Errorf("received share: got %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -63,6 +63,20 @@ func NewTransferProtocol(sourceURI, sharedSecret string, size uint64) *ocm.Proto
 	}
 }
 
+// SharedSecret returns the secret shared by the remote provider
+// to access the received share.
+func SharedSecret(s *ocm.ReceivedShare) string {
+	for _, p := range s.GetProtocols() {
+		switch t := p.Term.(type) {
+		case *ocm.Protocol_WebdavOptions:
+			return t.WebdavOptions.SharedSecret
+		case *ocm.Protocol_TransferOptions:
+			return t.TransferOptions.SharedSecret
+		}
+	}
+	return ""
+}
+
 // NewWebDavAccessMethod is an abstraction for creating a WebDAV access method.
 func NewWebDavAccessMethod(perms *provider.ResourcePermissions, reqs []string) *ocm.AccessMethod {
 	return &ocm.AccessMethod{
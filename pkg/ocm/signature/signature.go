@@ -0,0 +1,384 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package signature implements the HTTP signatures used to authenticate
+// the requests between OCM providers, following
+// https://datatracker.ietf.org/doc/html/draft-cavage-http-signatures-12
+// as required by the OCM specifications.
+//
+// The public key of a provider is published in its OCM discovery document,
+// and identified by a key id in the form https://<domain>/ocm#<fingerprint>.
+// As the key id depends on the key itself, a provider rotates its key by
+// replacing it: the key ids not yet known are resolved again from the
+// discovery document of the provider, which keeps publishing the previous
+// keys until the requests signed with them are no longer accepted.
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Algorithm is the signature algorithm used to sign the requests.
+const Algorithm = "rsa-sha256"
+
+const (
+	// maxClockSkew is the maximum difference allowed between
+	// the date of a signed request and the time of its verification.
+	maxClockSkew = 5 * time.Minute
+	// keyCacheTTL is the duration after which a resolved key
+	// is resolved again, to detect if it has been rotated.
+	keyCacheTTL = time.Hour
+)
+
+// signedHeaders are the headers included in the signature of a request.
+var signedHeaders = []string{"(request-target)", "content-length", "date", "digest", "host"}
+
+// requiredHeaders are the headers that a signature must cover to be accepted.
+var requiredHeaders = []string{"(request-target)", "date", "digest", "host"}
+
+// ErrNotSigned is returned when verifying a request without signature.
+var ErrNotSigned = errors.New("signature: request is not signed")
+
+// LoadPrivateKey reads a PEM encoded RSA private key, in PKCS #1 or PKCS #8 form.
+func LoadPrivateKey(file string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "signature: error reading private key")
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("signature: no PEM data found in " + file)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "signature: error parsing private key")
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("signature: private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// EncodePublicKey returns the PEM encoding of the public key.
+func EncodePublicKey(pub *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// ParsePublicKey parses a PEM encoded RSA public key.
+func ParsePublicKey(s string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("signature: no PEM data found in public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "signature: error parsing public key")
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("signature: public key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// KeyID returns the id of the public key of the given provider domain.
+func KeyID(domain string, pub *rsa.PublicKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(pub)
+	sum := sha256.Sum256(der)
+	return fmt.Sprintf("https://%s/ocm#%s", domain, hex.EncodeToString(sum[:8]))
+}
+
+// Domain returns the domain of the provider owning the key id.
+func Domain(keyID string) (string, error) {
+	u, err := url.Parse(keyID)
+	if err != nil {
+		return "", errors.Wrap(err, "signature: invalid key id")
+	}
+	if u.Host == "" {
+		return "", errors.New("signature: invalid key id " + keyID)
+	}
+	return u.Host, nil
+}
+
+// Signer signs the requests sent to other OCM providers.
+type Signer struct {
+	keyID string
+	key   *rsa.PrivateKey
+	now   func() time.Time
+}
+
+// NewSigner returns a Signer for the given provider domain,
+// signing the requests with key.
+func NewSigner(domain string, key *rsa.PrivateKey) *Signer {
+	return &Signer{
+		keyID: KeyID(domain, &key.PublicKey),
+		key:   key,
+		now:   time.Now,
+	}
+}
+
+// NewSignerFromFile returns a Signer for the given provider domain,
+// signing the requests with the private key stored in file.
+func NewSignerFromFile(domain, file string) (*Signer, error) {
+	key, err := LoadPrivateKey(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewSigner(domain, key), nil
+}
+
+// KeyID returns the id of the key used to sign the requests.
+func (s *Signer) KeyID() string {
+	return s.keyID
+}
+
+// Sign adds to r the Date, Digest and Signature headers.
+func (s *Signer) Sign(r *http.Request) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Date", s.now().UTC().Format(http.TimeFormat))
+	r.Header.Set("Digest", digest(body))
+
+	toSign := signingString(r, signedHeaders)
+	hashed := sha256.Sum256([]byte(toSign))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return errors.Wrap(err, "signature: error signing request")
+	}
+
+	r.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="%s",headers="%s",signature="%s"`,
+		s.keyID, Algorithm, strings.Join(signedHeaders, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// KeyResolver returns the public key identified by keyID.
+type KeyResolver func(ctx context.Context, keyID string) (*rsa.PublicKey, error)
+
+type cachedKey struct {
+	key      *rsa.PublicKey
+	resolved time.Time
+}
+
+// Verifier verifies the signatures of the requests received from other OCM providers.
+type Verifier struct {
+	resolve KeyResolver
+	now     func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]cachedKey
+}
+
+// NewVerifier returns a Verifier getting the public keys of the
+// providers with resolve. The resolved keys are cached.
+func NewVerifier(resolve KeyResolver) *Verifier {
+	return &Verifier{
+		resolve: resolve,
+		now:     time.Now,
+		keys:    make(map[string]cachedKey),
+	}
+}
+
+// Verify verifies the signature of r, returning the id of the key used to
+// sign it. ErrNotSigned is returned if the request has no signature.
+func (v *Verifier) Verify(r *http.Request) (string, error) {
+	header := r.Header.Get("Signature")
+	if header == "" {
+		return "", ErrNotSigned
+	}
+	params := parseSignatureHeader(header)
+
+	keyID := params["keyId"]
+	if keyID == "" {
+		return "", errors.New("signature: missing key id")
+	}
+	if alg := params["algorithm"]; alg != "" && alg != Algorithm && alg != "hs2019" {
+		return "", errors.New("signature: unsupported algorithm " + alg)
+	}
+	sig, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil || len(sig) == 0 {
+		return "", errors.New("signature: malformed signature")
+	}
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	for _, h := range requiredHeaders {
+		if !contains(headers, h) {
+			return "", errors.New("signature: header " + h + " is not signed")
+		}
+	}
+
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return "", errors.Wrap(err, "signature: invalid date")
+	}
+	if skew := v.now().Sub(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return "", errors.New("signature: request date out of range")
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		return "", err
+	}
+	if r.Header.Get("Digest") != digest(body) {
+		return "", errors.New("signature: digest does not match the body")
+	}
+
+	hashed := sha256.Sum256([]byte(signingString(r, headers)))
+	verify := func(key *rsa.PublicKey) bool {
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig) == nil
+	}
+
+	key, cached, err := v.getKey(r.Context(), keyID, false)
+	if err != nil {
+		return "", err
+	}
+	if verify(key) {
+		return keyID, nil
+	}
+	// the cached key could be outdated, so it is resolved again
+	if cached {
+		if key, _, err = v.getKey(r.Context(), keyID, true); err != nil {
+			return "", err
+		}
+		if verify(key) {
+			return keyID, nil
+		}
+	}
+	return "", errors.New("signature: invalid signature")
+}
+
+// getKey returns the public key identified by keyID, and whether it came from the cache.
+func (v *Verifier) getKey(ctx context.Context, keyID string, refresh bool) (*rsa.PublicKey, bool, error) {
+	v.mu.Lock()
+	c, ok := v.keys[keyID]
+	v.mu.Unlock()
+	if ok && !refresh && v.now().Sub(c.resolved) < keyCacheTTL {
+		return c.key, true, nil
+	}
+
+	key, err := v.resolve(ctx, keyID)
+	if err != nil {
+		if !refresh {
+			v.mu.Lock()
+			delete(v.keys, keyID)
+			v.mu.Unlock()
+		}
+		return nil, false, errors.Wrap(err, "signature: error resolving key "+keyID)
+	}
+
+	v.mu.Lock()
+	v.keys[keyID] = cachedKey{key: key, resolved: v.now()}
+	v.mu.Unlock()
+	return key, false, nil
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return []byte{}, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "signature: error reading body")
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func signingString(r *http.Request, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		var value string
+		switch h {
+		case "(request-target)":
+			// RequestURI is set only for the requests received by a server,
+			// and it is the target of the request before any rewrite
+			target := r.RequestURI
+			if target == "" {
+				target = r.URL.RequestURI()
+			}
+			value = strings.ToLower(r.Method) + " " + target
+		case "host":
+			value = r.Host
+			if value == "" {
+				value = r.URL.Host
+			}
+		case "content-length":
+			value = strconv.FormatInt(r.ContentLength, 10)
+		default:
+			value = strings.TrimSpace(r.Header.Get(h))
+		}
+		lines = append(lines, h+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func parseSignatureHeader(h string) map[string]string {
+	params := make(map[string]string)
+	for _, p := range strings.Split(h, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok {
+			continue
+		}
+		params[k] = strings.Trim(v, `"`)
+	}
+	return params
+}
+
+func contains(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package signature
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// keyServer resolves the keys as published by the providers.
+type keyServer struct {
+	keys     map[string]*rsa.PublicKey
+	resolved int
+}
+
+func (s *keyServer) publish(signer *Signer, key *rsa.PrivateKey) {
+	s.keys[signer.KeyID()] = &key.PublicKey
+}
+
+func (s *keyServer) resolve(_ context.Context, keyID string) (*rsa.PublicKey, error) {
+	s.resolved++
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	return key, nil
+}
+
+// roundTrip signs a request with signer, and returns the request as received by a server.
+func roundTrip(t *testing.T, signer *Signer, body string, tamper func(r *http.Request)) *http.Request {
+	var received *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(strings.NewReader(string(b)))
+		received = r
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/ocm/shares?a=b", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if signer != nil {
+		if err := signer.Sign(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tamper != nil {
+		tamper(req)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return received
+}
+
+func TestSignAndVerify(t *testing.T) {
+	key, otherKey := generateKey(t), generateKey(t)
+	signer := NewSigner("cernbox.cern.ch", key)
+	impostor := NewSigner("cernbox.cern.ch", otherKey)
+	impostor.keyID = signer.KeyID()
+
+	tests := []struct {
+		description string
+		signer      *Signer
+		body        string
+		tamper      func(r *http.Request)
+		now         time.Time
+		err         bool
+		notSigned   bool
+	}{
+		{
+			description: "signed request",
+			signer:      signer,
+			body:        `{"shareWith":"marie@cesnet.cz"}`,
+		},
+		{
+			description: "signed request without body",
+			signer:      signer,
+		},
+		{
+			description: "not signed request",
+			body:        `{"shareWith":"marie@cesnet.cz"}`,
+			notSigned:   true,
+		},
+		{
+			description: "body changed after signing",
+			signer:      signer,
+			body:        `{"shareWith":"marie@cesnet.cz"}`,
+			tamper: func(r *http.Request) {
+				r.Body = io.NopCloser(strings.NewReader(`{"shareWith":"einst@cesnet.cz"}`))
+			},
+			err: true,
+		},
+		{
+			description: "digest changed after signing",
+			signer:      signer,
+			body:        `{"shareWith":"marie@cesnet.cz"}`,
+			tamper: func(r *http.Request) {
+				r.Body = io.NopCloser(strings.NewReader(`{"shareWith":"einst@cesnet.cz"}`))
+				r.Header.Set("Digest", digest([]byte(`{"shareWith":"einst@cesnet.cz"}`)))
+			},
+			err: true,
+		},
+		{
+			description: "signed by a different key",
+			signer:      impostor,
+			body:        `{"shareWith":"marie@cesnet.cz"}`,
+			err:         true,
+		},
+		{
+			description: "unknown key",
+			signer:      NewSigner("cesnet.cz", otherKey),
+			body:        `{"shareWith":"marie@cesnet.cz"}`,
+			err:         true,
+		},
+		{
+			description: "expired request",
+			signer:      signer,
+			body:        `{"shareWith":"marie@cesnet.cz"}`,
+			now:         time.Now().Add(10 * time.Minute),
+			err:         true,
+		},
+		{
+			description: "date not signed",
+			signer:      signer,
+			body:        `{"shareWith":"marie@cesnet.cz"}`,
+			tamper: func(r *http.Request) {
+				r.Header.Set("Signature", strings.Replace(r.Header.Get("Signature"), " date", "", 1))
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			keys := &keyServer{keys: map[string]*rsa.PublicKey{}}
+			keys.publish(signer, key)
+			v := NewVerifier(keys.resolve)
+			if !tt.now.IsZero() {
+				v.now = func() time.Time { return tt.now }
+			}
+
+			r := roundTrip(t, tt.signer, tt.body, tt.tamper)
+			keyID, err := v.Verify(r)
+			switch {
+			case tt.notSigned:
+				if err != ErrNotSigned {
+					t.Fatalf("expected ErrNotSigned, got %v", err)
+				}
+			case tt.err:
+				if err == nil {
+					t.Fatal("expected error verifying the signature")
+				}
+			default:
+				if err != nil {
+					t.Fatalf("not expected error verifying the signature: %v", err)
+				}
+				if keyID != signer.KeyID() {
+					t.Fatalf("got key id %s, expected %s", keyID, signer.KeyID())
+				}
+				// the body can still be read by the handlers
+				if b, _ := io.ReadAll(r.Body); string(b) != tt.body {
+					t.Fatalf("got body %s, expected %s", b, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, newKey := generateKey(t), generateKey(t)
+	oldSigner, newSigner := NewSigner("cernbox.cern.ch", oldKey), NewSigner("cernbox.cern.ch", newKey)
+	if oldSigner.KeyID() == newSigner.KeyID() {
+		t.Fatal("different keys should have different ids")
+	}
+
+	keys := &keyServer{keys: map[string]*rsa.PublicKey{}}
+	keys.publish(oldSigner, oldKey)
+	v := NewVerifier(keys.resolve)
+
+	for i := 0; i < 2; i++ {
+		if _, err := v.Verify(roundTrip(t, oldSigner, "{}", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if keys.resolved != 1 {
+		t.Fatalf("expected the key to be resolved once, got %d", keys.resolved)
+	}
+
+	// the provider rotates its key
+	delete(keys.keys, oldSigner.KeyID())
+	keys.publish(newSigner, newKey)
+	if _, err := v.Verify(roundTrip(t, newSigner, "{}", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the old key is not accepted anymore once it expires from the cache
+	v.now = func() time.Time { return time.Now().Add(keyCacheTTL) }
+	r := roundTrip(t, oldSigner, "{}", nil)
+	r.Header.Set("Date", v.now().UTC().Format(http.TimeFormat))
+	if _, err := v.Verify(r); err == nil {
+		t.Fatal("the rotated key should not be accepted")
+	}
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	key := generateKey(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		block       *pem.Block
+	}{
+		{
+			description: "pkcs1",
+			block:       &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		},
+		{
+			description: "pkcs8",
+			block:       &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "key.pem")
+			if err := os.WriteFile(file, pem.EncodeToMemory(tt.block), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadPrivateKey(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(key) {
+				t.Fatal("loaded key does not match")
+			}
+
+			pub, err := EncodePublicKey(&got.PublicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := ParsePublicKey(pub)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !parsed.Equal(&key.PublicKey) {
+				t.Fatal("parsed public key does not match")
+			}
+		})
+	}
+}
@@ -725,6 +725,10 @@ func (d *driver) GetQuota(ctx context.Context, ref *provider.Reference) ( /*Tota
 	return 0, 0, errtypes.NotSupported("operation not supported")
 }
 
+func (d *driver) CreateSymlink(ctx context.Context, ref *provider.Reference, target string) error {
+	return errtypes.NotSupported("operation not supported")
+}
+
 func (d *driver) CreateReference(ctx context.Context, path string, targetURI *url.URL) error {
 	return errtypes.NotSupported("operation not supported")
 }
@@ -740,3 +744,7 @@ func (d *driver) CreateStorageSpace(ctx context.Context, req *provider.CreateSto
 func (d *driver) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
 	return nil, errtypes.NotSupported("operation not supported")
 }
+
+func (d *driver) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) error {
+	return errtypes.NotSupported("operation not supported")
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/cs3org/reva/pkg/appctx"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/mime"
+	ocmshare "github.com/cs3org/reva/pkg/ocm/share"
 	"github.com/cs3org/reva/pkg/rgrpc/todo/pool"
 	"github.com/cs3org/reva/pkg/rhttp/router"
 	"github.com/cs3org/reva/pkg/sharedconf"
@@ -131,6 +132,9 @@ func (d *driver) getWebDAVFromShare(ctx context.Context, shareID *ocmpb.ShareId)
 		}
 		return nil, "", "", errtypes.InternalError(res.Status.Message)
 	}
+	if ocmshare.IsExpired(res.Share) {
+		return nil, "", "", errtypes.NotFound("share not found")
+	}
 
 	dav, ok := getWebDAVProtocol(res.Share.Protocols)
 	if !ok {
@@ -156,6 +160,10 @@ func (d *driver) webdavClient(ctx context.Context, ref *provider.Reference) (*go
 	// check first if we have a cached webdav client
 	if entry, err := d.ccache.Get(id.OpaqueId); err == nil {
 		cc := entry.(*cachedClient)
+		if ocmshare.IsExpired(cc.share) {
+			_ = d.ccache.Remove(id.OpaqueId)
+			return nil, nil, "", errtypes.NotFound("share not found")
+		}
 		log.Info().Interface("share", cc.share).Str("rel", rel).Msg("accessing OCM share via cached client")
 		return cc.client, cc.share, rel, nil
 	}
@@ -401,6 +409,10 @@ func (d *driver) GetQuota(ctx context.Context, ref *provider.Reference) ( /*Tota
 	return 0, 0, errtypes.NotSupported("operation not supported")
 }
 
+func (d *driver) CreateSymlink(ctx context.Context, ref *provider.Reference, target string) error {
+	return errtypes.NotSupported("operation not supported")
+}
+
 func (d *driver) CreateReference(ctx context.Context, path string, targetURI *url.URL) error {
 	return errtypes.NotSupported("operation not supported")
 }
@@ -440,3 +452,7 @@ func (d *driver) CreateStorageSpace(ctx context.Context, req *provider.CreateSto
 func (d *driver) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
 	return nil, errtypes.NotSupported("operation not supported")
 }
+
+func (d *driver) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) error {
+	return errtypes.NotSupported("operation not supported")
+}
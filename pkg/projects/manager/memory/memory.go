@@ -21,7 +21,9 @@ package memory
 import (
 	"context"
 	"slices"
+	"sync"
 
+	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/projects"
 	"github.com/cs3org/reva/pkg/projects/manager/registry"
 	"github.com/cs3org/reva/pkg/spaces"
@@ -51,7 +53,8 @@ type Config struct {
 }
 
 type service struct {
-	c *Config
+	c  *Config
+	mu sync.RWMutex
 }
 
 func New(ctx context.Context, m map[string]any) (projects.Catalogue, error) {
@@ -67,6 +70,9 @@ func NewWithConfig(ctx context.Context, c *Config) (projects.Catalogue, error) {
 }
 
 func (s *service) ListProjects(ctx context.Context, user *userpb.User) ([]*provider.StorageSpace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	projects := []*provider.StorageSpace{}
 	for _, space := range s.c.Spaces {
 		if perms, ok := projectBelongToUser(user, &space); ok {
@@ -91,6 +97,25 @@ func (s *service) ListProjects(ctx context.Context, user *userpb.User) ([]*provi
 	return projects, nil
 }
 
+// DeleteProject removes the project from the in-memory catalogue.
+// The catalogue does not keep deleted projects, hence purge has no effect.
+func (s *service) DeleteProject(ctx context.Context, user *userpb.User, storageID, path string, purge bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, space := range s.c.Spaces {
+		if space.StorageID != storageID || space.Path != path {
+			continue
+		}
+		if user.Id.OpaqueId != space.Owner && !slices.Contains(user.Groups, space.Admins) {
+			return errtypes.PermissionDenied("user is not allowed to delete project " + space.Name)
+		}
+		s.c.Spaces = slices.Delete(s.c.Spaces, i, i+1)
+		return nil
+	}
+	return errtypes.NotFound("project at " + path)
+}
+
 func projectBelongToUser(user *userpb.User, project *SpaceDescription) (*provider.ResourcePermissions, bool) {
 	if user.Id.OpaqueId == project.Owner {
 		return conversions.NewManagerRole().CS3ResourcePermissions(), true
@@ -28,6 +28,7 @@ import (
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/internal/http/services/owncloud/ocs/conversions"
+	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/projects"
 	"github.com/cs3org/reva/pkg/projects/manager/registry"
 	"github.com/cs3org/reva/pkg/spaces"
@@ -173,3 +174,31 @@ func projectBelongToUser(user *userpb.User, p *Project) (*provider.ResourcePermi
 	}
 	return nil, false
 }
+
+// DeleteProject soft deletes the project, unless purge is set. Only the owner
+// and the admins of a project are allowed to delete it.
+func (m *mgr) DeleteProject(ctx context.Context, user *userpb.User, storageID, path string, purge bool) error {
+	db := m.db
+	if purge {
+		db = db.Unscoped()
+	}
+
+	var p Project
+	res := db.Where("storage_id = ? AND path = ?", storageID, path).First(&p)
+	if res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return errtypes.NotFound("project at " + path)
+		}
+		return res.Error
+	}
+
+	if user.Id.OpaqueId != p.Owner && !slices.Contains(user.Groups, p.Admins) {
+		return errtypes.PermissionDenied("user is not allowed to delete project " + p.Name)
+	}
+
+	if res := db.Delete(&p); res.Error != nil {
+		return errors.Wrap(res.Error, "error deleting project "+p.Name)
+	}
+	_ = m.cache.Remove(cacheKey)
+	return nil
+}
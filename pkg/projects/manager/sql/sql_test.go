@@ -284,3 +284,54 @@ func TestListProjects(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteProject(t *testing.T) {
+	ctx := context.Background()
+	catalogue, err, teardown := setupSuite(t)
+	if err != nil {
+		t.Fatalf("not expected error while creating projects driver: %+v", err)
+	}
+	defer func() {
+		if err := teardown(t); err != nil {
+			t.Fatalf("failed to teardown test suite: %+v", err)
+		}
+	}()
+
+	catmgr := catalogue.(*mgr)
+	catmgr.db.Create(&Project{
+		StorageID: "storage_id",
+		Path:      "/path/to/project",
+		Name:      "project",
+		Owner:     "owner",
+		Readers:   "project-readers",
+		Writers:   "project-writers",
+		Admins:    "project-admins",
+	})
+
+	owner := &userpb.User{Id: &userpb.UserId{OpaqueId: "owner", Idp: "idp"}}
+	writer := &userpb.User{Id: &userpb.UserId{OpaqueId: "writer", Idp: "idp"}, Groups: []string{"project-writers"}}
+
+	if err := catalogue.DeleteProject(ctx, writer, "storage_id", "/path/to/project", false); err == nil {
+		t.Fatal("writers should not be allowed to delete a project")
+	}
+
+	if err := catalogue.DeleteProject(ctx, owner, "storage_id", "/path/to/project", false); err != nil {
+		t.Fatalf("not expected error while deleting project: %+v", err)
+	}
+	got, err := catalogue.ListProjects(ctx, owner)
+	if err != nil {
+		t.Fatalf("not expected error while listing projects: %+v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("deleted project should not be listed. got=%+v", render.AsCode(got))
+	}
+
+	if err := catalogue.DeleteProject(ctx, owner, "storage_id", "/path/to/project", true); err != nil {
+		t.Fatalf("not expected error while purging project: %+v", err)
+	}
+	var count int64
+	catmgr.db.Unscoped().Model(&Project{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("purged project should be removed from the database. got=%d projects", count)
+	}
+}
@@ -28,4 +28,8 @@ import (
 // Catalogue is the interface that stores the project spaces.
 type Catalogue interface {
 	ListProjects(ctx context.Context, user *userpb.User) ([]*provider.StorageSpace, error)
+	// DeleteProject removes the project stored at the given path.
+	// Unless purge is set, a project can be removed only temporarily
+	// by the drivers supporting it.
+	DeleteProject(ctx context.Context, user *userpb.User, storageID, path string, purge bool) error
 }
@@ -0,0 +1,83 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// jsonShare is an entry of the file of the json public share manager.
+type jsonShare struct {
+	Share    string `json:"share"`
+	Password string `json:"password"`
+}
+
+// ImportJSON imports the public shares stored by the json public share manager
+// in file into the sql public share manager m. The passwords are imported as
+// bcrypt hashes, so the links keep their passwords. The shares whose id or
+// token already exist are skipped. It returns the number of imported shares.
+func ImportJSON(ctx context.Context, m publicshare.Manager, file string) (int, error) {
+	mgr, ok := m.(*manager)
+	if !ok {
+		return 0, errors.New("sql: the public share manager is not a sql public share manager")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, errors.Wrap(err, "sql: error reading the json public shares file")
+	}
+	j := map[string]jsonShare{}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return 0, errors.Wrap(err, "sql: error decoding the json public shares file")
+	}
+
+	var imported int
+	err = transaction(ctx, mgr.db, func(tx *sql.Tx) error {
+		for _, enc := range j {
+			s := &link.PublicShare{}
+			if err := utils.UnmarshalJSONToProtoV1([]byte(enc.Share), s); err != nil {
+				return errors.Wrap(err, "sql: error decoding public share from json")
+			}
+			var n int
+			if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM public_shares WHERE id = ? OR token = ?", s.Id.GetOpaqueId(), s.Token).Scan(&n); err != nil {
+				return errors.Wrap(err, "sql: error querying public shares")
+			}
+			if n > 0 {
+				continue
+			}
+			if err := insertShare(ctx, tx, s, enc.Password); err != nil {
+				return err
+			}
+			imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
@@ -0,0 +1,591 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sql implements a public share manager storing the public links
+// in a sqlite or MySQL database.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/publicshare/manager/registry"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+	// Provides the mysql database/sql driver.
+	_ "github.com/go-sql-driver/mysql"
+	// Provides the sqlite3 database/sql driver.
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	Engine                     string `docs:"sqlite;The database engine, either sqlite or mysql."                        mapstructure:"engine"`
+	DBFile                     string `docs:"/var/tmp/reva/publicshares.db;The sqlite file holding the public shares."  mapstructure:"db_file"`
+	DBUsername                 string `docs:";The username to connect to the mysql database."                           mapstructure:"db_username"`
+	DBPassword                 string `docs:";The password to connect to the mysql database."                           mapstructure:"db_password"`
+	DBAddress                  string `docs:"localhost:3306;The address of the mysql database, as host:port."           mapstructure:"db_address"`
+	DBName                     string `docs:"reva;The name of the mysql database."                                      mapstructure:"db_name"`
+	SharePasswordHashCost      int    `docs:"11;The bcrypt cost of the hashes of the share passwords."                  mapstructure:"password_hash_cost"`
+	JanitorRunInterval         int    `docs:"60;The interval in seconds between two removals of the expired shares."    mapstructure:"janitor_run_interval"`
+	EnableExpiredSharesCleanup bool   `docs:"false;Whether to remove the expired shares."                               mapstructure:"enable_expired_shares_cleanup"`
+}
+
+func (c *config) ApplyDefaults() {
+	if c.Engine == "" {
+		c.Engine = "sqlite"
+	}
+	if c.DBFile == "" {
+		c.DBFile = "/var/tmp/reva/publicshares.db"
+	}
+	if c.DBAddress == "" {
+		c.DBAddress = "localhost:3306"
+	}
+	if c.DBName == "" {
+		c.DBName = "reva"
+	}
+	if c.SharePasswordHashCost == 0 {
+		c.SharePasswordHashCost = 11
+	}
+	if c.JanitorRunInterval == 0 {
+		c.JanitorRunInterval = 60
+	}
+}
+
+// schemas holds the statements creating the tables of every engine.
+// The public shares are looked up by token, by creator and by resource,
+// the expired ones by expiration.
+var schemas = map[string][]string{
+	"sqlite": {
+		`CREATE TABLE IF NOT EXISTS public_shares (
+			id TEXT PRIMARY KEY,
+			token TEXT NOT NULL UNIQUE,
+			storage_id TEXT NOT NULL,
+			space_id TEXT NOT NULL,
+			opaque_id TEXT NOT NULL,
+			owner_idp TEXT NOT NULL,
+			owner TEXT NOT NULL,
+			owner_type INTEGER NOT NULL,
+			creator_idp TEXT NOT NULL,
+			creator TEXT NOT NULL,
+			creator_type INTEGER NOT NULL,
+			permissions TEXT NOT NULL,
+			password TEXT NOT NULL,
+			display_name TEXT NOT NULL,
+			description TEXT NOT NULL,
+			quicklink INTEGER NOT NULL,
+			notify_uploads INTEGER NOT NULL,
+			notify_uploads_extra_recipients TEXT NOT NULL,
+			ctime INTEGER NOT NULL,
+			mtime INTEGER NOT NULL,
+			expiration INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS public_shares_creator ON public_shares (creator, creator_idp)`,
+		`CREATE INDEX IF NOT EXISTS public_shares_resource ON public_shares (storage_id, opaque_id)`,
+		`CREATE INDEX IF NOT EXISTS public_shares_expiration ON public_shares (expiration)`,
+	},
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS public_shares (
+			id VARCHAR(64) PRIMARY KEY,
+			token VARCHAR(64) NOT NULL,
+			storage_id VARCHAR(128) NOT NULL,
+			space_id VARCHAR(191) NOT NULL,
+			opaque_id VARCHAR(191) NOT NULL,
+			owner_idp VARCHAR(128) NOT NULL,
+			owner VARCHAR(191) NOT NULL,
+			owner_type INT NOT NULL,
+			creator_idp VARCHAR(128) NOT NULL,
+			creator VARCHAR(191) NOT NULL,
+			creator_type INT NOT NULL,
+			permissions TEXT NOT NULL,
+			password VARCHAR(255) NOT NULL,
+			display_name VARCHAR(255) NOT NULL,
+			description TEXT NOT NULL,
+			quicklink BOOLEAN NOT NULL,
+			notify_uploads BOOLEAN NOT NULL,
+			notify_uploads_extra_recipients TEXT NOT NULL,
+			ctime BIGINT NOT NULL,
+			mtime BIGINT NOT NULL,
+			expiration BIGINT NOT NULL,
+			UNIQUE KEY public_shares_token (token),
+			INDEX public_shares_creator (creator, creator_idp),
+			INDEX public_shares_resource (storage_id, opaque_id),
+			INDEX public_shares_expiration (expiration)
+		)`,
+	},
+}
+
+const shareColumns = "id, token, storage_id, space_id, opaque_id, owner_idp, owner, owner_type, creator_idp, creator, creator_type, " +
+	"permissions, password, display_name, description, quicklink, notify_uploads, notify_uploads_extra_recipients, ctime, mtime, expiration"
+
+type manager struct {
+	c  *config
+	db *sql.DB
+}
+
+// New returns a public share manager storing the public shares in a sql database.
+func New(ctx context.Context, m map[string]interface{}) (publicshare.Manager, error) {
+	var c config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+
+	db, err := open(&c)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range schemas[c.Engine] {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, errors.Wrap(err, "sql: error creating the public share tables")
+		}
+	}
+
+	mgr := &manager{c: &c, db: db}
+	if c.EnableExpiredSharesCleanup {
+		go mgr.startJanitorRun()
+	}
+	return mgr, nil
+}
+
+func open(c *config) (*sql.DB, error) {
+	switch c.Engine {
+	case "sqlite":
+		if err := os.MkdirAll(filepath.Dir(c.DBFile), 0700); err != nil {
+			return nil, errors.Wrap(err, "sql: error creating public shares folder")
+		}
+		// writers are serialized by sqlite, wait for the lock instead of failing
+		db, err := sql.Open("sqlite3", "file:"+c.DBFile+"?_busy_timeout=5000&_txlock=immediate")
+		return db, errors.Wrap(err, "sql: error opening the sqlite database")
+	case "mysql":
+		db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s", c.DBUsername, c.DBPassword, c.DBAddress, c.DBName))
+		return db, errors.Wrap(err, "sql: error opening connection to mysql database")
+	default:
+		return nil, errors.New("sql: unknown database engine " + c.Engine)
+	}
+}
+
+func (m *manager) startJanitorRun() {
+	ticker := time.NewTicker(time.Duration(m.c.JanitorRunInterval) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := m.cleanupExpiredShares(context.Background()); err != nil {
+			log.Err(err).Msg("publicShareSQLManager: error removing expired public shares")
+		}
+	}
+}
+
+// cleanupExpiredShares removes the expired shares, and returns how many were removed.
+func (m *manager) cleanupExpiredShares(ctx context.Context) (int64, error) {
+	res, err := m.db.ExecContext(ctx, "DELETE FROM public_shares WHERE expiration > 0 AND expiration < ?", time.Now().UnixNano())
+	if err != nil {
+		return 0, errors.Wrap(err, "sql: error removing expired public shares")
+	}
+	return res.RowsAffected()
+}
+
+func now() *typespb.Timestamp {
+	t := time.Now().UnixNano()
+	return &typespb.Timestamp{
+		Seconds: uint64(t / 1000000000),
+		Nanos:   uint32(t % 1000000000),
+	}
+}
+
+func (m *manager) hashPassword(password string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), m.c.SharePasswordHashCost)
+	if err != nil {
+		return "", errors.Wrap(err, "could not hash share password")
+	}
+	return string(h), nil
+}
+
+// CreatePublicShare stores a new public share.
+func (m *manager) CreatePublicShare(ctx context.Context, u *user.User, rInfo *provider.ResourceInfo, g *link.Grant, description string, internal bool, notifyUploads bool, notifyUploadsExtraRecipients string) (*link.PublicShare, error) {
+	tkn := utils.RandString(15)
+
+	displayName, ok := rInfo.ArbitraryMetadata.GetMetadata()["name"]
+	if !ok {
+		displayName = tkn
+	}
+
+	var password string
+	if g.Password != "" {
+		h, err := m.hashPassword(g.Password)
+		if err != nil {
+			return nil, err
+		}
+		password = h
+	}
+
+	ts := now()
+	s := &link.PublicShare{
+		Id:                           &link.PublicShareId{OpaqueId: utils.RandString(15)},
+		Owner:                        rInfo.GetOwner(),
+		Creator:                      u.Id,
+		ResourceId:                   rInfo.Id,
+		Token:                        tkn,
+		Permissions:                  g.Permissions,
+		Ctime:                        ts,
+		Mtime:                        ts,
+		PasswordProtected:            password != "",
+		Expiration:                   g.Expiration,
+		DisplayName:                  displayName,
+		Description:                  description,
+		NotifyUploads:                notifyUploads,
+		NotifyUploadsExtraRecipients: notifyUploadsExtraRecipients,
+	}
+	if err := insertShare(ctx, m.db, s, password); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// execer is implemented by both sql.DB and sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertShare(ctx context.Context, db execer, s *link.PublicShare, password string) error {
+	perms, err := utils.MarshalProtoV1ToJSON(s.Permissions)
+	if err != nil {
+		return errors.Wrap(err, "sql: error encoding public share permissions")
+	}
+	query := "INSERT INTO public_shares (" + shareColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err = db.ExecContext(ctx, query,
+		s.Id.OpaqueId, s.Token, s.ResourceId.GetStorageId(), s.ResourceId.GetSpaceId(), s.ResourceId.GetOpaqueId(),
+		s.Owner.GetIdp(), s.Owner.GetOpaqueId(), int(s.Owner.GetType()),
+		s.Creator.GetIdp(), s.Creator.GetOpaqueId(), int(s.Creator.GetType()),
+		string(perms), password, s.DisplayName, s.Description, s.Quicklink, s.NotifyUploads, s.NotifyUploadsExtraRecipients,
+		timestampToNanos(s.Ctime), timestampToNanos(s.Mtime), timestampToNanos(s.Expiration))
+	return errors.Wrap(err, "sql: error storing public share")
+}
+
+// UpdatePublicShare updates the public share.
+func (m *manager) UpdatePublicShare(ctx context.Context, u *user.User, req *link.UpdatePublicShareRequest, g *link.Grant) (*link.PublicShare, error) {
+	s, password, err := m.get(ctx, req.Ref)
+	if err != nil {
+		return nil, err
+	}
+	if !isCreatedByUser(s, u) {
+		return nil, errtypes.NotFound(req.Ref.String())
+	}
+
+	switch req.GetUpdate().GetType() {
+	case link.UpdatePublicShareRequest_Update_TYPE_DISPLAYNAME:
+		s.DisplayName = req.Update.GetDisplayName()
+	case link.UpdatePublicShareRequest_Update_TYPE_PERMISSIONS:
+		s.Permissions = req.Update.GetGrant().GetPermissions()
+	case link.UpdatePublicShareRequest_Update_TYPE_EXPIRATION:
+		s.Expiration = req.Update.GetGrant().GetExpiration()
+	case link.UpdatePublicShareRequest_Update_TYPE_PASSWORD:
+		password = ""
+		if req.Update.GetGrant().GetPassword() != "" {
+			if password, err = m.hashPassword(req.Update.GetGrant().GetPassword()); err != nil {
+				return nil, err
+			}
+		}
+		s.PasswordProtected = password != ""
+	case link.UpdatePublicShareRequest_Update_TYPE_DESCRIPTION:
+		s.Description = req.Update.GetDescription()
+	case link.UpdatePublicShareRequest_Update_TYPE_NOTIFYUPLOADS:
+		s.NotifyUploads = req.Update.GetNotifyUploads()
+	case link.UpdatePublicShareRequest_Update_TYPE_NOTIFYUPLOADSEXTRARECIPIENTS:
+		s.NotifyUploadsExtraRecipients = req.Update.GetNotifyUploadsExtraRecipients()
+	default:
+		return nil, fmt.Errorf("invalid update type: %v", req.GetUpdate().GetType())
+	}
+	s.Mtime = now()
+
+	perms, err := utils.MarshalProtoV1ToJSON(s.Permissions)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error encoding public share permissions")
+	}
+	query := "UPDATE public_shares SET permissions = ?, password = ?, display_name = ?, description = ?, notify_uploads = ?, notify_uploads_extra_recipients = ?, mtime = ?, expiration = ? WHERE id = ?"
+	if _, err := m.db.ExecContext(ctx, query, string(perms), password, s.DisplayName, s.Description, s.NotifyUploads, s.NotifyUploadsExtraRecipients,
+		timestampToNanos(s.Mtime), timestampToNanos(s.Expiration), s.Id.OpaqueId); err != nil {
+		return nil, errors.Wrap(err, "sql: error updating public share")
+	}
+	return s, nil
+}
+
+// GetPublicShare gets a public share either by ID or Token.
+func (m *manager) GetPublicShare(ctx context.Context, u *user.User, ref *link.PublicShareReference, sign bool) (*link.PublicShare, error) {
+	s, password, err := m.get(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if publicshare.IsExpired(s) {
+		m.revokeExpiredPublicShare(ctx, s)
+		return nil, errtypes.NotFound(ref.String())
+	}
+	if s.PasswordProtected && sign {
+		if err := publicshare.AddSignature(s, password); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (m *manager) get(ctx context.Context, ref *link.PublicShareReference) (*link.PublicShare, string, error) {
+	var column, value string
+	switch {
+	case ref.GetId().GetOpaqueId() != "":
+		column, value = "id", ref.GetId().GetOpaqueId()
+	case ref.GetToken() != "":
+		column, value = "token", ref.GetToken()
+	default:
+		return nil, "", errtypes.NotFound(ref.String())
+	}
+
+	shares, passwords, err := m.query(ctx, "SELECT "+shareColumns+" FROM public_shares WHERE "+column+" = ?", value)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(shares) == 0 {
+		return nil, "", errtypes.NotFound(ref.String())
+	}
+	return shares[0], passwords[0], nil
+}
+
+// ListPublicShares lists the valid public shares created by the user.
+func (m *manager) ListPublicShares(ctx context.Context, u *user.User, filters []*link.ListPublicSharesRequest_Filter, md *provider.ResourceInfo, sign bool) ([]*link.PublicShare, error) {
+	query := "SELECT " + shareColumns + " FROM public_shares WHERE creator = ?"
+	params := []any{u.Id.OpaqueId}
+	if u.Id.Idp != "" {
+		// shares created without an idp are matched by the opaque id only
+		query += " AND creator_idp IN (?, '')"
+		params = append(params, u.Id.Idp)
+	}
+	if fs := publicshare.GroupFiltersByType(filters)[link.ListPublicSharesRequest_Filter_TYPE_RESOURCE_ID]; len(fs) > 0 {
+		conds := make([]string, 0, len(fs))
+		for _, f := range fs {
+			conds = append(conds, "(storage_id = ? AND opaque_id = ?)")
+			params = append(params, f.GetResourceId().GetStorageId(), f.GetResourceId().GetOpaqueId())
+		}
+		query += " AND (" + strings.Join(conds, " OR ") + ")"
+	}
+
+	shares, passwords, err := m.query(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*link.PublicShare{}
+	for i, s := range shares {
+		if publicshare.IsExpired(s) {
+			m.revokeExpiredPublicShare(ctx, s)
+			continue
+		}
+		if len(filters) > 0 && !publicshare.MatchesFilters(s, filters) {
+			continue
+		}
+		if s.PasswordProtected && sign {
+			if err := publicshare.AddSignature(s, passwords[i]); err != nil {
+				return nil, err
+			}
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// revokeExpiredPublicShare removes an expired share, if the cleanup is enabled.
+func (m *manager) revokeExpiredPublicShare(ctx context.Context, s *link.PublicShare) {
+	if !m.c.EnableExpiredSharesCleanup {
+		return
+	}
+	if _, err := m.db.ExecContext(ctx, "DELETE FROM public_shares WHERE id = ?", s.Id.OpaqueId); err != nil {
+		appctx.GetLogger(ctx).Err(err).Str("id", s.Id.OpaqueId).Msg("publicShareSQLManager: error deleting expired public share")
+	}
+}
+
+// RevokePublicShare removes the public share.
+func (m *manager) RevokePublicShare(ctx context.Context, u *user.User, ref *link.PublicShareReference) error {
+	s, _, err := m.get(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if !isCreatedByUser(s, u) {
+		return errtypes.NotFound(ref.String())
+	}
+	_, err = m.db.ExecContext(ctx, "DELETE FROM public_shares WHERE id = ?", s.Id.OpaqueId)
+	return errors.Wrap(err, "sql: error removing public share")
+}
+
+// GetPublicShareByToken gets a public share by its opaque token.
+func (m *manager) GetPublicShareByToken(ctx context.Context, token string, auth *link.PublicShareAuthentication, sign bool) (*link.PublicShare, error) {
+	s, password, err := m.get(ctx, &link.PublicShareReference{Spec: &link.PublicShareReference_Token{Token: token}})
+	if err != nil {
+		return nil, err
+	}
+	if publicshare.IsExpired(s) {
+		m.revokeExpiredPublicShare(ctx, s)
+		return nil, errtypes.NotFound(fmt.Sprintf("share with token: `%v` not found", token))
+	}
+
+	if s.PasswordProtected {
+		if !authenticate(s, password, auth) {
+			return nil, errtypes.InvalidCredentials("sql: invalid password")
+		}
+		if sign {
+			if err := publicshare.AddSignature(s, password); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func (m *manager) query(ctx context.Context, query string, params ...any) ([]*link.PublicShare, []string, error) {
+	rows, err := m.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "sql: error querying public shares")
+	}
+	defer rows.Close()
+
+	var (
+		shares    []*link.PublicShare
+		passwords []string
+	)
+	for rows.Next() {
+		var d dbShare
+		if err := rows.Scan(d.fields()...); err != nil {
+			return nil, nil, errors.Wrap(err, "sql: error reading public shares")
+		}
+		s, err := d.share()
+		if err != nil {
+			return nil, nil, err
+		}
+		shares = append(shares, s)
+		passwords = append(passwords, d.Password)
+	}
+	return shares, passwords, errors.Wrap(rows.Err(), "sql: error reading public shares")
+}
+
+// dbShare is a row of the public_shares table.
+type dbShare struct {
+	ID, Token, StorageID, SpaceID, OpaqueID string
+	OwnerIdp, Owner                         string
+	OwnerType                               int
+	CreatorIdp, Creator                     string
+	CreatorType                             int
+	Permissions, Password                   string
+	DisplayName, Description                string
+	Quicklink, NotifyUploads                bool
+	NotifyUploadsExtraRecipients            string
+	Ctime, Mtime, Expiration                int64
+}
+
+func (d *dbShare) fields() []any {
+	return []any{&d.ID, &d.Token, &d.StorageID, &d.SpaceID, &d.OpaqueID, &d.OwnerIdp, &d.Owner, &d.OwnerType, &d.CreatorIdp, &d.Creator, &d.CreatorType,
+		&d.Permissions, &d.Password, &d.DisplayName, &d.Description, &d.Quicklink, &d.NotifyUploads, &d.NotifyUploadsExtraRecipients, &d.Ctime, &d.Mtime, &d.Expiration}
+}
+
+func (d *dbShare) share() (*link.PublicShare, error) {
+	perms := &link.PublicSharePermissions{}
+	if err := utils.UnmarshalJSONToProtoV1([]byte(d.Permissions), perms); err != nil {
+		return nil, errors.Wrap(err, "sql: error decoding public share permissions")
+	}
+	s := &link.PublicShare{
+		Id:                           &link.PublicShareId{OpaqueId: d.ID},
+		Token:                        d.Token,
+		ResourceId:                   &provider.ResourceId{StorageId: d.StorageID, SpaceId: d.SpaceID, OpaqueId: d.OpaqueID},
+		Permissions:                  perms,
+		Owner:                        &user.UserId{Idp: d.OwnerIdp, OpaqueId: d.Owner, Type: user.UserType(d.OwnerType)},
+		Creator:                      &user.UserId{Idp: d.CreatorIdp, OpaqueId: d.Creator, Type: user.UserType(d.CreatorType)},
+		Ctime:                        nanosToTimestamp(d.Ctime),
+		Mtime:                        nanosToTimestamp(d.Mtime),
+		PasswordProtected:            d.Password != "",
+		DisplayName:                  d.DisplayName,
+		Quicklink:                    d.Quicklink,
+		Description:                  d.Description,
+		NotifyUploads:                d.NotifyUploads,
+		NotifyUploadsExtraRecipients: d.NotifyUploadsExtraRecipients,
+	}
+	if d.Expiration != 0 {
+		s.Expiration = nanosToTimestamp(d.Expiration)
+	}
+	return s, nil
+}
+
+func isCreatedByUser(s *link.PublicShare, u *user.User) bool {
+	return utils.UserEqual(u.GetId(), s.Owner) || utils.UserEqual(u.GetId(), s.Creator)
+}
+
+func authenticate(share *link.PublicShare, pw string, auth *link.PublicShareAuthentication) bool {
+	switch {
+	case auth.GetPassword() != "":
+		if err := bcrypt.CompareHashAndPassword([]byte(pw), []byte(auth.GetPassword())); err == nil {
+			return true
+		}
+	case auth.GetSignature() != nil:
+		sig := auth.GetSignature()
+		now := time.Now()
+		expiration := time.Unix(int64(sig.GetSignatureExpiration().GetSeconds()), int64(sig.GetSignatureExpiration().GetNanos()))
+		if now.After(expiration) {
+			return false
+		}
+		s, err := publicshare.CreateSignature(share.Token, pw, expiration)
+		if err != nil {
+			return false
+		}
+		return sig.GetSignature() == s
+	}
+	return false
+}
+
+func timestampToNanos(ts *typespb.Timestamp) int64 {
+	return int64(ts.GetSeconds())*1000000000 + int64(ts.GetNanos())
+}
+
+func nanosToTimestamp(n int64) *typespb.Timestamp {
+	return &typespb.Timestamp{
+		Seconds: uint64(n / 1000000000),
+		Nanos:   uint32(n % 1000000000),
+	}
+}
+
+func transaction(ctx context.Context, db *sql.DB, f func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "sql: error starting transaction")
+	}
+	if err := f(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return errors.Wrap(tx.Commit(), "sql: error committing transaction")
+}
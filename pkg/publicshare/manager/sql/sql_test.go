@@ -0,0 +1,280 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	link "github.com/cs3org/go-cs3apis/cs3/sharing/link/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/publicshare"
+	"github.com/cs3org/reva/pkg/publicshare/manager/json"
+)
+
+var (
+	einstein = &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "einstein", Type: userpb.UserType_USER_TYPE_PRIMARY}}
+	marie    = &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "marie", Type: userpb.UserType_USER_TYPE_PRIMARY}}
+
+	folder = &provider.ResourceInfo{
+		Id:                &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "folder"},
+		Owner:             einstein.Id,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{"name": "folder"}},
+	}
+	file = &provider.ResourceInfo{
+		Id:                &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "file"},
+		Owner:             einstein.Id,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{"name": "file"}},
+	}
+	viewer = &link.PublicSharePermissions{Permissions: &provider.ResourcePermissions{Stat: true, InitiateFileDownload: true}}
+	editor = &link.PublicSharePermissions{Permissions: &provider.ResourcePermissions{Stat: true, InitiateFileDownload: true, InitiateFileUpload: true}}
+)
+
+func newManager(t *testing.T, conf map[string]interface{}) publicshare.Manager {
+	c := map[string]interface{}{
+		"db_file":            filepath.Join(t.TempDir(), "publicshares.db"),
+		"password_hash_cost": 4,
+	}
+	for k, v := range conf {
+		c[k] = v
+	}
+	m, err := New(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func byToken(token string) *link.PublicShareReference {
+	return &link.PublicShareReference{Spec: &link.PublicShareReference_Token{Token: token}}
+}
+
+func byID(id *link.PublicShareId) *link.PublicShareReference {
+	return &link.PublicShareReference{Spec: &link.PublicShareReference_Id{Id: id}}
+}
+
+func TestPublicShares(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t, nil)
+
+	s, err := m.CreatePublicShare(ctx, einstein, folder, &link.Grant{Permissions: viewer, Password: "secret"}, "my link", false, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.PasswordProtected || s.Token == "" {
+		t.Fatalf("unexpected public share %+v", s)
+	}
+
+	got, err := m.GetPublicShare(ctx, einstein, byID(s.Id), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Token != s.Token || got.Description != "my link" || !got.NotifyUploads || got.Permissions.Permissions.InitiateFileUpload {
+		t.Fatalf("unexpected public share %+v", got)
+	}
+
+	signed, err := m.GetPublicShare(ctx, einstein, byToken(s.Token), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.Signature == nil {
+		t.Fatal("expected the public share to be signed")
+	}
+
+	if _, err := m.GetPublicShareByToken(ctx, s.Token, &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Password{Password: "wrong"}}, false); !isInvalidCredentials(err) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+	if _, err := m.GetPublicShareByToken(ctx, s.Token, &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Password{Password: "secret"}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetPublicShareByToken(ctx, s.Token, &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Signature{Signature: signed.Signature}}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the creator and the owner can update or revoke a link
+	update := &link.UpdatePublicShareRequest{
+		Ref:    byID(s.Id),
+		Update: &link.UpdatePublicShareRequest_Update{Type: link.UpdatePublicShareRequest_Update_TYPE_PASSWORD, Grant: &link.Grant{}},
+	}
+	if _, err := m.UpdatePublicShare(ctx, marie, update, nil); !isNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if err := m.RevokePublicShare(ctx, marie, byID(s.Id)); !isNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	updated, err := m.UpdatePublicShare(ctx, einstein, update, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.PasswordProtected {
+		t.Fatal("expected the password to be removed")
+	}
+	if _, err := m.GetPublicShareByToken(ctx, s.Token, nil, false); err != nil {
+		t.Fatal(err)
+	}
+
+	update.Update = &link.UpdatePublicShareRequest_Update{Type: link.UpdatePublicShareRequest_Update_TYPE_PERMISSIONS, Grant: &link.Grant{Permissions: editor}}
+	if _, err := m.UpdatePublicShare(ctx, einstein, update, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err = m.GetPublicShareByToken(ctx, s.Token, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Permissions.Permissions.InitiateFileUpload {
+		t.Fatal("expected the permissions to be updated")
+	}
+
+	if err := m.RevokePublicShare(ctx, einstein, byToken(s.Token)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetPublicShareByToken(ctx, s.Token, nil, false); !isNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestListPublicShares(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t, nil)
+
+	for _, r := range []*provider.ResourceInfo{folder, folder, file} {
+		if _, err := m.CreatePublicShare(ctx, einstein, r, &link.Grant{Permissions: viewer}, "", false, false, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := m.ListPublicShares(ctx, einstein, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("expected 3 public shares, got %d", len(list))
+	}
+
+	list, err = m.ListPublicShares(ctx, einstein, []*link.ListPublicSharesRequest_Filter{publicshare.ResourceIDFilter(folder.Id)}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 public shares, got %d", len(list))
+	}
+
+	list, err = m.ListPublicShares(ctx, marie, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("expected no public shares, got %d", len(list))
+	}
+}
+
+func TestExpiredPublicShares(t *testing.T) {
+	ctx := context.Background()
+	m := newManager(t, map[string]interface{}{"enable_expired_shares_cleanup": true, "janitor_run_interval": 3600})
+
+	expired := &typespb.Timestamp{Seconds: uint64(time.Now().Add(-time.Hour).Unix())}
+	s, err := m.CreatePublicShare(ctx, einstein, folder, &link.Grant{Permissions: viewer, Expiration: expired}, "", false, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.CreatePublicShare(ctx, einstein, file, &link.Grant{Permissions: viewer, Expiration: expired}, "", false, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	valid, err := m.CreatePublicShare(ctx, einstein, file, &link.Grant{Permissions: viewer}, "", false, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.GetPublicShareByToken(ctx, s.Token, nil, false); !isNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	n, err := m.(*manager).cleanupExpiredShares(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected the janitor to remove 1 public share, got %d", n)
+	}
+
+	list, err := m.ListPublicShares(ctx, einstein, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Token != valid.Token {
+		t.Fatalf("expected only the valid public share, got %v", list)
+	}
+}
+
+func TestImportJSON(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "publicshares")
+
+	jm, err := json.New(ctx, map[string]interface{}{"file": file, "password_hash_cost": 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	protected, err := jm.CreatePublicShare(ctx, einstein, folder, &link.Grant{Permissions: viewer, Password: "secret"}, "", false, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jm.CreatePublicShare(ctx, einstein, folder, &link.Grant{Permissions: editor}, "", false, false, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newManager(t, nil)
+	n, err := ImportJSON(ctx, m, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 imported public shares, got %d", n)
+	}
+
+	// the password hashes are kept
+	if _, err := m.GetPublicShareByToken(ctx, protected.Token, &link.PublicShareAuthentication{Spec: &link.PublicShareAuthentication_Password{Password: "secret"}}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// importing again skips the existing shares
+	n, err = ImportJSON(ctx, m, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("expected no imported public shares, got %d", n)
+	}
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(errtypes.IsNotFound)
+	return ok
+}
+
+func isInvalidCredentials(err error) bool {
+	_, ok := err.(errtypes.IsInvalidCredentials)
+	return ok
+}
@@ -137,6 +137,17 @@ func NewAlreadyExists(ctx context.Context, err error, msg string) *rpc.Status {
 	}
 }
 
+// NewLocked returns a Status with CODE_LOCKED and logs the msg.
+func NewLocked(ctx context.Context, err error, msg string) *rpc.Status {
+	log := appctx.GetLogger(ctx).With().CallerWithSkipFrameCount(3).Logger()
+	log.Error().Err(err).Msg(msg)
+	return &rpc.Status{
+		Code:    rpc.Code_CODE_LOCKED,
+		Message: msg,
+		Trace:   getTrace(ctx),
+	}
+}
+
 // NewInvalidArg returns a Status with CODE_INVALID_ARGUMENT.
 func NewInvalidArg(ctx context.Context, msg string) *rpc.Status {
 	return &rpc.Status{Code: rpc.Code_CODE_INVALID_ARGUMENT,
@@ -183,6 +194,8 @@ func NewStatusFromErrType(ctx context.Context, msg string, err error) *rpc.Statu
 		return NewInvalidArg(ctx, "gateway: "+msg+":"+err.Error())
 	case errtypes.AlreadyExists:
 		return NewAlreadyExists(ctx, err, "gateway: "+msg+":"+err.Error())
+	case errtypes.IsLocked:
+		return NewLocked(ctx, err, "gateway: "+msg+":"+err.Error())
 	}
 
 	// map GRPC status codes coming from the auth middleware
@@ -40,6 +40,7 @@ import (
 	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	storageregistry "github.com/cs3org/go-cs3apis/cs3/storage/registry/v1beta1"
 	datatx "github.com/cs3org/go-cs3apis/cs3/tx/v1beta1"
+	sppb "github.com/cs3org/reva/internal/grpc/services/storageprovider/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -79,6 +80,7 @@ var (
 	userProviders          = newProvider()
 	groupProviders         = newProvider()
 	dataTxs                = newProvider()
+	searchProviders        = newProvider()
 )
 
 // NewConn creates a new connection to a grpc server
@@ -501,3 +503,23 @@ func GetDataTxClient(opts ...Option) (datatx.TxAPIClient, error) {
 	dataTxs.conn[options.Endpoint] = v
 	return v, nil
 }
+
+// GetSearchClient returns a new SearchAPIClient.
+func GetSearchClient(opts ...Option) (sppb.SearchAPIClient, error) {
+	searchProviders.m.Lock()
+	defer searchProviders.m.Unlock()
+
+	options := newOptions(opts...)
+	if c, ok := searchProviders.conn[options.Endpoint]; ok {
+		return c.(sppb.SearchAPIClient), nil
+	}
+
+	conn, err := NewConn(options)
+	if err != nil {
+		return nil, err
+	}
+
+	v := sppb.NewSearchAPIClient(conn)
+	searchProviders.conn[options.Endpoint] = v
+	return v, nil
+}
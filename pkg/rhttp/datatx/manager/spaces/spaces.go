@@ -123,6 +123,8 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 				w.WriteHeader(http.StatusInsufficientStorage)
 			case errtypes.Conflict:
 				w.WriteHeader(http.StatusConflict)
+			case errtypes.Locked:
+				w.WriteHeader(http.StatusLocked)
 			default:
 				sublog.Error().Err(v).Msg("error uploading file")
 				w.WriteHeader(http.StatusInternalServerError)
@@ -21,6 +21,8 @@ package tus
 import (
 	"context"
 	"net/http"
+	"path"
+	"time"
 
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/rhttp/datatx"
@@ -108,9 +110,72 @@ func (m *manager) Handler(fs storage.FS) (http.Handler, error) {
 		}
 	}))
 
+	if e, ok := fs.(expirer); ok {
+		h = withExpiration(h, e)
+	}
+
 	return h, nil
 }
 
+// withExpiration implements the expiration extension, see
+// https://tus.io/protocols/resumable-upload#expiration
+func withExpiration(h http.Handler, e expirer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(&expirationWriter{ResponseWriter: w, r: r, e: e}, r)
+	})
+}
+
+// expirationWriter adds the expiration headers right before the response is sent.
+type expirationWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	e           expirer
+	wroteHeader bool
+}
+
+func (w *expirationWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.setHeaders()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *expirationWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *expirationWriter) setHeaders() {
+	header := w.Header()
+	switch w.r.Method {
+	case http.MethodOptions:
+		if ext := header.Get("Tus-Extension"); ext != "" {
+			header.Set("Tus-Extension", ext+",expiration")
+		}
+		return
+	case http.MethodPost, http.MethodHead, http.MethodPatch:
+	default:
+		return
+	}
+
+	id := path.Base(w.r.URL.Path)
+	if loc := header.Get("Location"); loc != "" {
+		id = path.Base(loc)
+	}
+	if t, ok := w.e.UploadExpiration(w.r.Context(), id); ok {
+		header.Set("Upload-Expires", t.UTC().Format(http.TimeFormat))
+	}
+}
+
+// expirer is implemented by the file systems removing the unfinished uploads
+// after some time.
+type expirer interface {
+	UploadExpiration(ctx context.Context, id string) (time.Time, bool)
+}
+
 // Composable is the interface that a struct needs to implement
 // to be composable, so that it can support the TUS methods.
 type composable interface {
@@ -0,0 +1,113 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/search/registry"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type index struct {
+	sync.RWMutex
+	paths map[string]map[string]struct{}
+}
+
+// New returns an instance of the in-memory search index.
+func New(ctx context.Context, m map[string]interface{}) (search.Index, error) {
+	return &index{paths: make(map[string]map[string]struct{})}, nil
+}
+
+// contains tells whether p is root or one of its descendants.
+func contains(root, p string) bool {
+	return p == root || root == "/" || strings.HasPrefix(p, root+"/")
+}
+
+func (i *index) Add(_ context.Context, ns string, paths ...string) error {
+	i.Lock()
+	defer i.Unlock()
+	if i.paths[ns] == nil {
+		i.paths[ns] = make(map[string]struct{})
+	}
+	for _, p := range paths {
+		i.paths[ns][path.Clean(p)] = struct{}{}
+	}
+	return nil
+}
+
+func (i *index) Remove(_ context.Context, ns, p string) error {
+	i.Lock()
+	defer i.Unlock()
+	p = path.Clean(p)
+	for fn := range i.paths[ns] {
+		if contains(p, fn) {
+			delete(i.paths[ns], fn)
+		}
+	}
+	return nil
+}
+
+func (i *index) Move(_ context.Context, ns, oldPath, newPath string) error {
+	i.Lock()
+	defer i.Unlock()
+	oldPath, newPath = path.Clean(oldPath), path.Clean(newPath)
+	moved := []string{}
+	for fn := range i.paths[ns] {
+		if contains(oldPath, fn) {
+			delete(i.paths[ns], fn)
+			moved = append(moved, path.Join(newPath, strings.TrimPrefix(fn, oldPath)))
+		}
+	}
+	for _, fn := range moved {
+		i.paths[ns][fn] = struct{}{}
+	}
+	return nil
+}
+
+func (i *index) Search(_ context.Context, ns, root string, q search.Query) ([]string, error) {
+	i.RLock()
+	defer i.RUnlock()
+	root = path.Clean(root)
+	pattern := strings.ToLower(q.Pattern)
+	matches := []string{}
+	for fn := range i.paths[ns] {
+		if fn != root && contains(root, fn) && strings.Contains(strings.ToLower(path.Base(fn)), pattern) {
+			matches = append(matches, fn)
+		}
+	}
+	sort.Strings(matches)
+
+	if q.Offset >= len(matches) {
+		return []string{}, nil
+	}
+	matches = matches[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(matches) {
+		matches = matches[:q.Limit]
+	}
+	return matches, nil
+}
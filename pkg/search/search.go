@@ -0,0 +1,122 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package search defines the index used to look up resources by name.
+//
+// The index only records where resources live: the storage drivers
+// keep it up to date as resources change, and every match is checked
+// against the storage before being returned, so that the results always
+// reflect the current state of the storage and the permissions of the
+// user running the query.
+package search
+
+import (
+	"context"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// batchSize is the number of matches read from the index at a time
+// while looking for the visible ones.
+const batchSize = 100
+
+// Query is a search for resources.
+type Query struct {
+	// Pattern is matched, case insensitively, against the names of the resources.
+	Pattern string
+	// Limit is the maximum number of results. Zero means no limit.
+	Limit int
+	// Offset is the number of results to skip.
+	Offset int
+}
+
+// Index records the paths of the resources of a storage.
+// Paths are partitioned in namespaces, as the same path may refer to
+// different resources depending on the user accessing the storage.
+type Index interface {
+	// Add adds the resources at the given paths to the namespace ns.
+	Add(ctx context.Context, ns string, paths ...string) error
+	// Remove removes the resource at path p and its descendants from the namespace ns.
+	Remove(ctx context.Context, ns, p string) error
+	// Move moves the resource at oldPath and its descendants to newPath in the namespace ns.
+	Move(ctx context.Context, ns, oldPath, newPath string) error
+	// Search returns, ordered by path, the paths of the resources
+	// in the namespace ns below root whose name matches q.
+	Search(ctx context.Context, ns, root string, q Query) ([]string, error)
+}
+
+// Searcher is implemented by the storage drivers that are able to search their resources.
+type Searcher interface {
+	// Search returns the resources below ref matching q.
+	Search(ctx context.Context, ref *provider.Reference, q Query) ([]*provider.ResourceInfo, error)
+	// Reindex rebuilds the index of the resources below ref, dropping
+	// the ones that do not exist anymore and adding the missing ones.
+	Reindex(ctx context.Context, ref *provider.Reference) error
+}
+
+// StatFunc returns the current metadata of the resource at path p.
+type StatFunc func(ctx context.Context, p string) (*provider.ResourceInfo, error)
+
+// Find looks up in the index the resources below root matching q,
+// and returns the ones that stat can access. The paths found in the index
+// that do not exist anymore are removed from it.
+func Find(ctx context.Context, idx Index, ns, root string, q Query, stat StatFunc) ([]*provider.ResourceInfo, error) {
+	if q.Pattern == "" {
+		return nil, errtypes.BadRequest("search: empty pattern")
+	}
+
+	infos := []*provider.ResourceInfo{}
+	skip := q.Offset
+	batch := Query{Pattern: q.Pattern, Limit: batchSize}
+	for {
+		paths, err := idx.Search(ctx, ns, root, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			info, err := stat(ctx, p)
+			if err != nil {
+				switch err.(type) {
+				case errtypes.IsNotFound:
+					if err := idx.Remove(ctx, ns, p); err != nil {
+						return nil, err
+					}
+					batch.Offset--
+					continue
+				case errtypes.IsPermissionDenied:
+					continue
+				default:
+					return nil, err
+				}
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			infos = append(infos, info)
+			if q.Limit > 0 && len(infos) == q.Limit {
+				return infos, nil
+			}
+		}
+		if len(paths) < batchSize {
+			return infos, nil
+		}
+		batch.Offset += batchSize
+	}
+}
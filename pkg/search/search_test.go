@@ -0,0 +1,143 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package search_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/search/memory"
+)
+
+// storage is a fake storage holding the paths of its resources,
+// some of which are not accessible.
+type storage struct {
+	paths  map[string]bool
+	denied map[string]bool
+}
+
+func (s *storage) stat(_ context.Context, p string) (*provider.ResourceInfo, error) {
+	if !s.paths[p] {
+		return nil, errtypes.NotFound(p)
+	}
+	if s.denied[p] {
+		return nil, errtypes.PermissionDenied(p)
+	}
+	return &provider.ResourceInfo{Path: p}, nil
+}
+
+func paths(infos []*provider.ResourceInfo) []string {
+	l := []string{}
+	for _, info := range infos {
+		l = append(l, info.Path)
+	}
+	return l
+}
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	idx, _ := memory.New(ctx, nil)
+	s := &storage{paths: map[string]bool{}, denied: map[string]bool{}}
+
+	// more matches than a batch, to go through the pagination of the index
+	all := []string{}
+	for i := 0; i < 250; i++ {
+		p := fmt.Sprintf("/docs/report-%03d.txt", i)
+		all = append(all, p)
+		s.paths[p] = true
+		if err := idx.Add(ctx, "einstein", p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := idx.Add(ctx, "einstein", "/docs", "/notes.txt", "/docs/gone-report.txt"); err != nil {
+		t.Fatal(err)
+	}
+	s.paths["/docs"], s.paths["/notes.txt"] = true, true
+	s.denied["/docs/report-001.txt"] = true
+
+	visible := append([]string{}, all[0], all[2])
+	visible = append(visible, all[3:]...)
+
+	tests := []struct {
+		description string
+		q           search.Query
+		expected    []string
+	}{
+		{"all the visible matches", search.Query{Pattern: "REPORT"}, visible},
+		{"first page", search.Query{Pattern: "report", Limit: 3}, visible[:3]},
+		{"second page", search.Query{Pattern: "report", Limit: 3, Offset: 3}, visible[3:6]},
+		{"page across batches", search.Query{Pattern: "report", Limit: 10, Offset: 95}, visible[95:105]},
+		{"offset past the end", search.Query{Pattern: "report", Offset: 1000}, []string{}},
+		{"no matches", search.Query{Pattern: "einstein"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			infos, err := search.Find(ctx, idx, "einstein", "/", tt.q, s.stat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := paths(infos)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("got %d results, expected %d", len(got), len(tt.expected))
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("result %d: got %s, expected %s", i, got[i], tt.expected[i])
+				}
+			}
+		})
+	}
+
+	// the resources that do not exist anymore are dropped from the index
+	matches, err := idx.Search(ctx, "einstein", "/docs", search.Query{Pattern: "gone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("stale entries should be removed from the index, got %v", matches)
+	}
+
+	if _, err := search.Find(ctx, idx, "einstein", "/", search.Query{}, s.stat); err == nil {
+		t.Fatal("an empty pattern should be rejected")
+	}
+}
+
+func TestFindNamespaces(t *testing.T) {
+	ctx := context.Background()
+	idx, _ := memory.New(ctx, nil)
+	s := &storage{paths: map[string]bool{"/docs/thesis.pdf": true}}
+	if err := idx.Add(ctx, "einstein", "/docs/thesis.pdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	for ns, expected := range map[string]int{"einstein": 1, "marie": 0} {
+		infos, err := search.Find(ctx, idx, ns, "/docs", search.Query{Pattern: "thesis"}, s.stat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) != expected {
+			t.Fatalf("namespace %s: got %d results, expected %d", ns, len(infos), expected)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/search/registry"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+	// Provides the sqlite3 database/sql driver.
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("sqlite", New)
+}
+
+type config struct {
+	DBFile string `docs:"/var/tmp/reva/search.db;The sqlite file holding the index." mapstructure:"db_file"`
+}
+
+func (c *config) ApplyDefaults() {
+	if c.DBFile == "" {
+		c.DBFile = "/var/tmp/reva/search.db"
+	}
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS entries (
+	ns TEXT NOT NULL,
+	path TEXT NOT NULL,
+	name TEXT NOT NULL,
+	PRIMARY KEY (ns, path)
+);`
+
+// below matches the rows whose path is the second argument or one of its descendants.
+const below = `(path = ?2 OR ?2 = '/' OR substr(path, 1, length(?2) + 1) = ?2 || '/')`
+
+type index struct {
+	db *sql.DB
+}
+
+// New returns a search index stored in a sqlite database.
+func New(ctx context.Context, m map[string]interface{}) (search.Index, error) {
+	var c config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.DBFile), 0700); err != nil {
+		return nil, errors.Wrap(err, "sqlite: error creating index folder")
+	}
+	// writers are serialized by sqlite, wait for the lock instead of failing
+	db, err := sql.Open("sqlite3", "file:"+c.DBFile+"?_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, errors.Wrap(err, "sqlite: error opening index")
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, errors.Wrap(err, "sqlite: error creating index table")
+	}
+	return &index{db: db}, nil
+}
+
+// name returns the value matched against the search patterns for p.
+func name(p string) string {
+	return strings.ToLower(path.Base(p))
+}
+
+func (i *index) Add(ctx context.Context, ns string, paths ...string) error {
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "sqlite: error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, p := range paths {
+		p = path.Clean(p)
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO entries (ns, path, name) VALUES (?, ?, ?)", ns, p, name(p)); err != nil {
+			return errors.Wrap(err, "sqlite: error adding "+p)
+		}
+	}
+	return errors.Wrap(tx.Commit(), "sqlite: error committing transaction")
+}
+
+func (i *index) Remove(ctx context.Context, ns, p string) error {
+	_, err := i.db.ExecContext(ctx, "DELETE FROM entries WHERE ns = ?1 AND "+below, ns, path.Clean(p))
+	return errors.Wrap(err, "sqlite: error removing "+p)
+}
+
+func (i *index) Move(ctx context.Context, ns, oldPath, newPath string) error {
+	oldPath, newPath = path.Clean(oldPath), path.Clean(newPath)
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "sqlite: error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// the entries already at the destination are overwritten by the move
+	if _, err := tx.ExecContext(ctx, "DELETE FROM entries WHERE ns = ?1 AND "+below, ns, newPath); err != nil {
+		return errors.Wrap(err, "sqlite: error removing "+newPath)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE entries SET path = ?3 || substr(path, length(?2) + 1) WHERE ns = ?1 AND "+below, ns, oldPath, newPath); err != nil {
+		return errors.Wrap(err, "sqlite: error moving "+oldPath)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE entries SET name = ?3 WHERE ns = ?1 AND path = ?2", ns, newPath, name(newPath)); err != nil {
+		return errors.Wrap(err, "sqlite: error renaming "+newPath)
+	}
+	return errors.Wrap(tx.Commit(), "sqlite: error committing transaction")
+}
+
+func (i *index) Search(ctx context.Context, ns, root string, q search.Query) ([]string, error) {
+	root = path.Clean(root)
+	limit := q.Limit
+	if limit == 0 {
+		limit = -1
+	}
+	rows, err := i.db.QueryContext(ctx, "SELECT path FROM entries WHERE ns = ?1 AND path != ?2 AND "+below+
+		" AND instr(name, ?3) > 0 ORDER BY path LIMIT ?4 OFFSET ?5", ns, root, strings.ToLower(q.Pattern), limit, q.Offset)
+	if err != nil {
+		return nil, errors.Wrap(err, "sqlite: error searching "+root)
+	}
+	defer rows.Close()
+
+	paths := []string{}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, errors.Wrap(err, "sqlite: error reading search results")
+		}
+		paths = append(paths, p)
+	}
+	return paths, errors.Wrap(rows.Err(), "sqlite: error reading search results")
+}
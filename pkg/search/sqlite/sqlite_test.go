@@ -0,0 +1,87 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/search"
+)
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	idx, err := New(ctx, map[string]interface{}{"db_file": filepath.Join(t.TempDir(), "search.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := idx.Add(ctx, "einstein",
+		"/docs", "/docs/Thesis.pdf", "/docs/thesis-notes.txt", "/docs/old", "/docs/old/thesis-draft.pdf",
+		"/docs-thesis", "/photos/holidays.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Add(ctx, "marie", "/docs/thesis.pdf"); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(root string, q search.Query, expected ...string) {
+		t.Helper()
+		got, err := idx.Search(ctx, "einstein", root, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected == nil {
+			expected = []string{}
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("search %q in %s: got %v, expected %v", q.Pattern, root, got, expected)
+		}
+	}
+
+	expect("/", search.Query{Pattern: "THESIS"}, "/docs-thesis", "/docs/Thesis.pdf", "/docs/old/thesis-draft.pdf", "/docs/thesis-notes.txt")
+	expect("/docs", search.Query{Pattern: "thesis"}, "/docs/Thesis.pdf", "/docs/old/thesis-draft.pdf", "/docs/thesis-notes.txt")
+	expect("/docs", search.Query{Pattern: "thesis", Limit: 1, Offset: 1}, "/docs/old/thesis-draft.pdf")
+	// the root is not part of the results, and only the names are matched
+	expect("/docs/old", search.Query{Pattern: "old"})
+	expect("/", search.Query{Pattern: "docs"}, "/docs", "/docs-thesis")
+
+	// moving a folder moves its descendants
+	if err := idx.Move(ctx, "einstein", "/docs/old", "/archive/thesis"); err != nil {
+		t.Fatal(err)
+	}
+	expect("/", search.Query{Pattern: "thesis"}, "/archive/thesis", "/archive/thesis/thesis-draft.pdf", "/docs-thesis", "/docs/Thesis.pdf", "/docs/thesis-notes.txt")
+	expect("/docs", search.Query{Pattern: "draft"})
+
+	// removing a folder removes its descendants, but not its siblings
+	if err := idx.Remove(ctx, "einstein", "/docs"); err != nil {
+		t.Fatal(err)
+	}
+	expect("/", search.Query{Pattern: "thesis"}, "/archive/thesis", "/archive/thesis/thesis-draft.pdf", "/docs-thesis")
+
+	got, err := idx.Search(ctx, "marie", "/", search.Query{Pattern: "thesis"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"/docs/thesis.pdf"}) {
+		t.Fatalf("the namespaces should be independent, got %v", got)
+	}
+}
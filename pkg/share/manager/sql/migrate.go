@@ -0,0 +1,105 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/prototext"
+)
+
+// jsonEncoding is the content of the file of the json share manager.
+type jsonEncoding struct {
+	State  map[string]map[string]collaboration.ShareState `json:"state"` // map[user id]map[share id]ShareState
+	Shares []string                                       `json:"shares"`
+}
+
+// ImportJSON imports the shares, and the state of the received shares, stored
+// by the json share manager in file into the sql share manager m.
+// The shares that already exist are skipped. It returns the number of imported shares.
+func ImportJSON(ctx context.Context, m share.Manager, file string) (int, error) {
+	mgr, ok := m.(*mgr)
+	if !ok {
+		return 0, errors.New("sql: the share manager is not a sql share manager")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, errors.Wrap(err, "sql: error reading the json shares file")
+	}
+	j := &jsonEncoding{}
+	if err := json.Unmarshal(data, j); err != nil {
+		return 0, errors.Wrap(err, "sql: error decoding the json shares file")
+	}
+
+	imported := map[string]bool{}
+	err = transaction(ctx, mgr.db, func(tx *sql.Tx) error {
+		for _, enc := range j.Shares {
+			s := &collaboration.Share{}
+			if err := utils.UnmarshalJSONToProtoV1([]byte(enc), s); err != nil {
+				return errors.Wrap(err, "sql: error decoding share from json")
+			}
+			if _, err := getByID(ctx, tx, s.Id); err == nil {
+				continue
+			}
+			key := &collaboration.ShareKey{Owner: s.Owner, ResourceId: s.ResourceId, Grantee: s.Grantee}
+			if _, err := getByKey(ctx, tx, key); err == nil {
+				continue
+			}
+			if err := insertShare(ctx, tx, s); err != nil {
+				return err
+			}
+			imported[s.Id.OpaqueId] = true
+		}
+
+		// the json share manager keys the states by the text format of the ids
+		for u, states := range j.State {
+			user := &userpb.UserId{}
+			if err := prototext.Unmarshal([]byte(u), user); err != nil {
+				return errors.Wrap(err, "sql: error decoding user id "+u)
+			}
+			for id, state := range states {
+				shareID := &collaboration.ShareId{}
+				if err := prototext.Unmarshal([]byte(id), shareID); err != nil {
+					return errors.Wrap(err, "sql: error decoding share id "+id)
+				}
+				if !imported[shareID.OpaqueId] {
+					// the state of a removed or already imported share
+					continue
+				}
+				if _, err := tx.ExecContext(ctx, upserts[mgr.c.Engine], shareID.OpaqueId, user.Idp, user.OpaqueId, int(state), false, ""); err != nil {
+					return errors.Wrap(err, "sql: error storing received share state")
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(imported), nil
+}
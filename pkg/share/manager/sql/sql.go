@@ -0,0 +1,643 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sql implements a share manager storing the user shares
+// in a sqlite or MySQL database.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typespb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/share/manager/registry"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+	// Provides the mysql database/sql driver.
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	// Provides the sqlite3 database/sql driver.
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/protobuf/field_mask"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	Engine     string `docs:"sqlite;The database engine, either sqlite or mysql."                mapstructure:"engine"`
+	DBFile     string `docs:"/var/tmp/reva/shares.db;The sqlite file holding the shares."       mapstructure:"db_file"`
+	DBUsername string `docs:";The username to connect to the mysql database."                   mapstructure:"db_username"`
+	DBPassword string `docs:";The password to connect to the mysql database."                   mapstructure:"db_password"`
+	DBAddress  string `docs:"localhost:3306;The address of the mysql database, as host:port."   mapstructure:"db_address"`
+	DBName     string `docs:"reva;The name of the mysql database."                              mapstructure:"db_name"`
+}
+
+func (c *config) ApplyDefaults() {
+	if c.Engine == "" {
+		c.Engine = "sqlite"
+	}
+	if c.DBFile == "" {
+		c.DBFile = "/var/tmp/reva/shares.db"
+	}
+	if c.DBAddress == "" {
+		c.DBAddress = "localhost:3306"
+	}
+	if c.DBName == "" {
+		c.DBName = "reva"
+	}
+}
+
+// schemas holds the statements creating the tables of every engine.
+// The shares are looked up by resource, by grantee and by owner or creator,
+// the state of a received share is stored per recipient.
+var schemas = map[string][]string{
+	"sqlite": {
+		`CREATE TABLE IF NOT EXISTS user_shares (
+			id TEXT PRIMARY KEY,
+			storage_id TEXT NOT NULL,
+			space_id TEXT NOT NULL,
+			opaque_id TEXT NOT NULL,
+			owner_idp TEXT NOT NULL,
+			owner TEXT NOT NULL,
+			owner_type INTEGER NOT NULL,
+			creator_idp TEXT NOT NULL,
+			creator TEXT NOT NULL,
+			creator_type INTEGER NOT NULL,
+			grantee_type INTEGER NOT NULL,
+			grantee_idp TEXT NOT NULL,
+			grantee TEXT NOT NULL,
+			grantee_user_type INTEGER NOT NULL,
+			permissions TEXT NOT NULL,
+			ctime INTEGER NOT NULL,
+			mtime INTEGER NOT NULL,
+			expiration INTEGER NOT NULL,
+			UNIQUE (storage_id, opaque_id, grantee_type, grantee_idp, grantee)
+		)`,
+		`CREATE INDEX IF NOT EXISTS user_shares_owner ON user_shares (owner, owner_idp)`,
+		`CREATE INDEX IF NOT EXISTS user_shares_creator ON user_shares (creator, creator_idp)`,
+		`CREATE INDEX IF NOT EXISTS user_shares_grantee ON user_shares (grantee, grantee_type)`,
+		`CREATE TABLE IF NOT EXISTS user_share_states (
+			share_id TEXT NOT NULL,
+			user_idp TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			state INTEGER NOT NULL,
+			hidden INTEGER NOT NULL,
+			mount_point TEXT NOT NULL,
+			PRIMARY KEY (share_id, user_idp, user_id)
+		)`,
+	},
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS user_shares (
+			id VARCHAR(64) PRIMARY KEY,
+			storage_id VARCHAR(128) NOT NULL,
+			space_id VARCHAR(191) NOT NULL,
+			opaque_id VARCHAR(191) NOT NULL,
+			owner_idp VARCHAR(128) NOT NULL,
+			owner VARCHAR(191) NOT NULL,
+			owner_type INT NOT NULL,
+			creator_idp VARCHAR(128) NOT NULL,
+			creator VARCHAR(191) NOT NULL,
+			creator_type INT NOT NULL,
+			grantee_type INT NOT NULL,
+			grantee_idp VARCHAR(128) NOT NULL,
+			grantee VARCHAR(191) NOT NULL,
+			grantee_user_type INT NOT NULL,
+			permissions TEXT NOT NULL,
+			ctime BIGINT NOT NULL,
+			mtime BIGINT NOT NULL,
+			expiration BIGINT NOT NULL,
+			UNIQUE KEY user_shares_key (storage_id, opaque_id, grantee_type, grantee_idp, grantee),
+			INDEX user_shares_owner (owner, owner_idp),
+			INDEX user_shares_creator (creator, creator_idp),
+			INDEX user_shares_grantee (grantee, grantee_type)
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_share_states (
+			share_id VARCHAR(64) NOT NULL,
+			user_idp VARCHAR(128) NOT NULL,
+			user_id VARCHAR(191) NOT NULL,
+			state INT NOT NULL,
+			hidden BOOLEAN NOT NULL,
+			mount_point VARCHAR(1024) NOT NULL,
+			PRIMARY KEY (share_id, user_idp, user_id)
+		)`,
+	},
+}
+
+// upserts holds the statements storing the state of a received share.
+var upserts = map[string]string{
+	"sqlite": `INSERT INTO user_share_states (share_id, user_idp, user_id, state, hidden, mount_point) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (share_id, user_idp, user_id) DO UPDATE SET state = excluded.state, hidden = excluded.hidden, mount_point = excluded.mount_point`,
+	"mysql": `INSERT INTO user_share_states (share_id, user_idp, user_id, state, hidden, mount_point) VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE state = VALUES(state), hidden = VALUES(hidden), mount_point = VALUES(mount_point)`,
+}
+
+const shareColumns = "s.id, s.storage_id, s.space_id, s.opaque_id, s.owner_idp, s.owner, s.owner_type, s.creator_idp, s.creator, s.creator_type, " +
+	"s.grantee_type, s.grantee_idp, s.grantee, s.grantee_user_type, s.permissions, s.ctime, s.mtime, s.expiration"
+
+type mgr struct {
+	c  *config
+	db *sql.DB
+}
+
+// New returns a share manager storing the shares in a sql database.
+func New(ctx context.Context, m map[string]interface{}) (share.Manager, error) {
+	var c config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+
+	db, err := open(&c)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range schemas[c.Engine] {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, errors.Wrap(err, "sql: error creating the share tables")
+		}
+	}
+	return &mgr{c: &c, db: db}, nil
+}
+
+func open(c *config) (*sql.DB, error) {
+	switch c.Engine {
+	case "sqlite":
+		if err := os.MkdirAll(filepath.Dir(c.DBFile), 0700); err != nil {
+			return nil, errors.Wrap(err, "sql: error creating shares folder")
+		}
+		// writers are serialized by sqlite, wait for the lock instead of failing
+		db, err := sql.Open("sqlite3", "file:"+c.DBFile+"?_busy_timeout=5000&_txlock=immediate")
+		return db, errors.Wrap(err, "sql: error opening the sqlite database")
+	case "mysql":
+		db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s", c.DBUsername, c.DBPassword, c.DBAddress, c.DBName))
+		return db, errors.Wrap(err, "sql: error opening connection to mysql database")
+	default:
+		return nil, errors.New("sql: unknown database engine " + c.Engine)
+	}
+}
+
+func now() *typespb.Timestamp {
+	t := time.Now().UnixNano()
+	return &typespb.Timestamp{
+		Seconds: uint64(t / 1000000000),
+		Nanos:   uint32(t % 1000000000),
+	}
+}
+
+func (m *mgr) Share(ctx context.Context, md *provider.ResourceInfo, g *collaboration.ShareGrant) (*collaboration.Share, error) {
+	user := appctx.ContextMustGetUser(ctx)
+
+	// do not allow share to myself or the owner if share is for a user
+	if g.Grantee.Type == provider.GranteeType_GRANTEE_TYPE_USER &&
+		(utils.UserEqual(g.Grantee.GetUserId(), user.Id) || utils.UserEqual(g.Grantee.GetUserId(), md.Owner)) {
+		return nil, errtypes.BadRequest("sql: owner/creator and grantee are the same")
+	}
+
+	ts := now()
+	s := &collaboration.Share{
+		Id:          &collaboration.ShareId{OpaqueId: uuid.New().String()},
+		ResourceId:  md.Id,
+		Permissions: g.Permissions,
+		Grantee:     g.Grantee,
+		Owner:       md.Owner,
+		Creator:     user.Id,
+		Ctime:       ts,
+		Mtime:       ts,
+		Expiration:  g.Expiration,
+	}
+
+	key := &collaboration.ShareKey{
+		Owner:      md.Owner,
+		ResourceId: md.Id,
+		Grantee:    g.Grantee,
+	}
+	err := transaction(ctx, m.db, func(tx *sql.Tx) error {
+		if _, err := getByKey(ctx, tx, key); err == nil {
+			return errtypes.AlreadyExists(key.String())
+		}
+		return insertShare(ctx, tx, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func insertShare(ctx context.Context, tx *sql.Tx, s *collaboration.Share) error {
+	perms, err := utils.MarshalProtoV1ToJSON(s.Permissions)
+	if err != nil {
+		return errors.Wrap(err, "sql: error encoding share permissions")
+	}
+	gtype, gidp, gid, gutype := formatGrantee(s.Grantee)
+	query := "INSERT INTO user_shares (id, storage_id, space_id, opaque_id, owner_idp, owner, owner_type, creator_idp, creator, creator_type, " +
+		"grantee_type, grantee_idp, grantee, grantee_user_type, permissions, ctime, mtime, expiration) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err = tx.ExecContext(ctx, query,
+		s.Id.OpaqueId, s.ResourceId.GetStorageId(), s.ResourceId.GetSpaceId(), s.ResourceId.GetOpaqueId(),
+		s.Owner.GetIdp(), s.Owner.GetOpaqueId(), int(s.Owner.GetType()),
+		s.Creator.GetIdp(), s.Creator.GetOpaqueId(), int(s.Creator.GetType()),
+		gtype, gidp, gid, gutype, string(perms),
+		timestampToNanos(s.Ctime), timestampToNanos(s.Mtime), int64(s.Expiration.GetSeconds()))
+	return errors.Wrap(err, "sql: error storing share")
+}
+
+func (m *mgr) GetShare(ctx context.Context, ref *collaboration.ShareReference) (*collaboration.Share, error) {
+	s, err := m.get(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	user := appctx.ContextMustGetUser(ctx)
+	if share.IsCreatedByUser(s, user) || share.IsGrantedToUser(s, user) {
+		return s, nil
+	}
+	// we return not found to not disclose information
+	return nil, errtypes.NotFound(ref.String())
+}
+
+func (m *mgr) get(ctx context.Context, ref *collaboration.ShareReference) (*collaboration.Share, error) {
+	switch {
+	case ref.GetId() != nil:
+		return getByID(ctx, m.db, ref.GetId())
+	case ref.GetKey() != nil:
+		return getByKey(ctx, m.db, ref.GetKey())
+	default:
+		return nil, errtypes.NotFound(ref.String())
+	}
+}
+
+// querier is implemented by both sql.DB and sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func getByID(ctx context.Context, q querier, id *collaboration.ShareId) (*collaboration.Share, error) {
+	shares, err := queryShares(ctx, q, "SELECT "+shareColumns+" FROM user_shares s WHERE s.id = ?", id.OpaqueId)
+	if err != nil {
+		return nil, err
+	}
+	if len(shares) == 0 {
+		return nil, errtypes.NotFound(id.String())
+	}
+	return shares[0], nil
+}
+
+func getByKey(ctx context.Context, q querier, key *collaboration.ShareKey) (*collaboration.Share, error) {
+	gtype, gidp, gid, _ := formatGrantee(key.Grantee)
+	query := "SELECT " + shareColumns + " FROM user_shares s WHERE s.storage_id = ? AND s.opaque_id = ? AND s.grantee_type = ? AND s.grantee_idp = ? AND s.grantee = ?"
+	shares, err := queryShares(ctx, q, query, key.ResourceId.GetStorageId(), key.ResourceId.GetOpaqueId(), gtype, gidp, gid)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range shares {
+		if utils.UserEqual(key.Owner, s.Owner) || utils.UserEqual(key.Owner, s.Creator) {
+			return s, nil
+		}
+	}
+	return nil, errtypes.NotFound(key.String())
+}
+
+func (m *mgr) Unshare(ctx context.Context, ref *collaboration.ShareReference) error {
+	s, err := m.get(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if !share.IsCreatedByUser(s, appctx.ContextMustGetUser(ctx)) {
+		return errtypes.NotFound(ref.String())
+	}
+
+	return transaction(ctx, m.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM user_share_states WHERE share_id = ?", s.Id.OpaqueId); err != nil {
+			return errors.Wrap(err, "sql: error removing share states")
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM user_shares WHERE id = ?", s.Id.OpaqueId)
+		return errors.Wrap(err, "sql: error removing share")
+	})
+}
+
+func (m *mgr) UpdateShare(ctx context.Context, ref *collaboration.ShareReference, p *collaboration.SharePermissions) (*collaboration.Share, error) {
+	s, err := m.get(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if !share.IsCreatedByUser(s, appctx.ContextMustGetUser(ctx)) {
+		return nil, errtypes.NotFound(ref.String())
+	}
+
+	perms, err := utils.MarshalProtoV1ToJSON(p)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error encoding share permissions")
+	}
+	s.Permissions = p
+	s.Mtime = now()
+	if _, err := m.db.ExecContext(ctx, "UPDATE user_shares SET permissions = ?, mtime = ? WHERE id = ?", string(perms), timestampToNanos(s.Mtime), s.Id.OpaqueId); err != nil {
+		return nil, errors.Wrap(err, "sql: error updating share")
+	}
+	return s, nil
+}
+
+func (m *mgr) ListShares(ctx context.Context, filters []*collaboration.Filter) ([]*collaboration.Share, error) {
+	user := appctx.ContextMustGetUser(ctx)
+	query := "SELECT " + shareColumns + " FROM user_shares s WHERE ((s.owner_idp = ? AND s.owner = ?) OR (s.creator_idp = ? AND s.creator = ?))"
+	params := []any{user.Id.Idp, user.Id.OpaqueId, user.Id.Idp, user.Id.OpaqueId}
+	filterQuery, filterParams := translateFilters(filters)
+	shares, err := queryShares(ctx, m.db, query+filterQuery, append(params, filterParams...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the filters not translated to the query are applied here
+	ss := []*collaboration.Share{}
+	for _, s := range shares {
+		if len(filters) == 0 || share.MatchesFilters(s, filters) {
+			ss = append(ss, s)
+		}
+	}
+	return ss, nil
+}
+
+// we list the shares that are targeted to the user in context or to the user groups.
+func (m *mgr) ListReceivedShares(ctx context.Context, filters []*collaboration.Filter) ([]*collaboration.ReceivedShare, error) {
+	user := appctx.ContextMustGetUser(ctx)
+	query, params := receivedSharesQuery(user)
+	filterQuery, filterParams := translateFilters(filters)
+	rss, err := queryReceivedShares(ctx, m.db, query+filterQuery, append(params, filterParams...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*collaboration.ReceivedShare{}
+	for _, rs := range rss {
+		if share.IsCreatedByUser(rs.Share, user) {
+			// omit shares created by the user
+			continue
+		}
+		if len(filters) == 0 || share.MatchesFilters(rs.Share, filters) {
+			list = append(list, rs)
+		}
+	}
+	return list, nil
+}
+
+// receivedSharesQuery returns the query selecting the shares granted to the user
+// or to one of their groups, along with the state set by the user.
+func receivedSharesQuery(user *userpb.User) (string, []any) {
+	query := "SELECT " + shareColumns + ", st.state, st.hidden, st.mount_point FROM user_shares s " +
+		"LEFT JOIN user_share_states st ON st.share_id = s.id AND st.user_idp = ? AND st.user_id = ? " +
+		"WHERE ((s.grantee_type = ? AND s.grantee_idp = ? AND s.grantee = ?)"
+	params := []any{user.Id.Idp, user.Id.OpaqueId, int(provider.GranteeType_GRANTEE_TYPE_USER), user.Id.Idp, user.Id.OpaqueId}
+	if len(user.Groups) > 0 {
+		query += " OR (s.grantee_type = ? AND s.grantee IN (?" + strings.Repeat(", ?", len(user.Groups)-1) + "))"
+		params = append(params, int(provider.GranteeType_GRANTEE_TYPE_GROUP))
+		for _, g := range user.Groups {
+			params = append(params, g)
+		}
+	}
+	return query + ")", params
+}
+
+func (m *mgr) GetReceivedShare(ctx context.Context, ref *collaboration.ShareReference) (*collaboration.ReceivedShare, error) {
+	s, err := m.get(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	user := appctx.ContextMustGetUser(ctx)
+	if !share.IsGrantedToUser(s, user) {
+		return nil, errtypes.NotFound(ref.String())
+	}
+
+	query, params := receivedSharesQuery(user)
+	rss, err := queryReceivedShares(ctx, m.db, query+" AND s.id = ?", append(params, s.Id.OpaqueId)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(rss) == 0 {
+		return nil, errtypes.NotFound(ref.String())
+	}
+	return rss[0], nil
+}
+
+func (m *mgr) UpdateReceivedShare(ctx context.Context, receivedShare *collaboration.ReceivedShare, fieldMask *field_mask.FieldMask) (*collaboration.ReceivedShare, error) {
+	rs, err := m.GetReceivedShare(ctx, &collaboration.ShareReference{Spec: &collaboration.ShareReference_Id{Id: receivedShare.Share.Id}})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range fieldMask.Paths {
+		switch fieldMask.Paths[i] {
+		case "state":
+			rs.State = receivedShare.State
+		case "mount_point":
+			rs.MountPoint = receivedShare.MountPoint
+		case "hidden":
+			rs.Hidden = receivedShare.Hidden
+		default:
+			return nil, errtypes.NotSupported("updating " + fieldMask.Paths[i] + " is not supported")
+		}
+	}
+
+	user := appctx.ContextMustGetUser(ctx)
+	if _, err := m.db.ExecContext(ctx, upserts[m.c.Engine], rs.Share.Id.OpaqueId, user.Id.Idp, user.Id.OpaqueId, int(rs.State), rs.Hidden, rs.MountPoint.GetPath()); err != nil {
+		return nil, errors.Wrap(err, "sql: error storing received share state")
+	}
+	return rs, nil
+}
+
+// translateFilters returns the conditions selecting the shares matching the filters
+// on the indexed columns. Filters of the same type are or-ed, of different types and-ed.
+func translateFilters(filters []*collaboration.Filter) (string, []any) {
+	var query strings.Builder
+	params := []any{}
+	grouped := share.GroupFiltersByType(filters)
+
+	if fs := grouped[collaboration.Filter_TYPE_RESOURCE_ID]; len(fs) > 0 {
+		conds := make([]string, 0, len(fs))
+		for _, f := range fs {
+			conds = append(conds, "(s.storage_id = ? AND s.opaque_id = ?)")
+			params = append(params, f.GetResourceId().GetStorageId(), f.GetResourceId().GetOpaqueId())
+		}
+		query.WriteString(" AND (" + strings.Join(conds, " OR ") + ")")
+	}
+	if fs := grouped[collaboration.Filter_TYPE_GRANTEE_TYPE]; len(fs) > 0 {
+		query.WriteString(" AND s.grantee_type IN (?" + strings.Repeat(", ?", len(fs)-1) + ")")
+		for _, f := range fs {
+			params = append(params, int(f.GetGranteeType()))
+		}
+	}
+	return query.String(), params
+}
+
+func queryShares(ctx context.Context, q querier, query string, params ...any) ([]*collaboration.Share, error) {
+	rows, err := q.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error querying shares")
+	}
+	defer rows.Close()
+
+	shares := []*collaboration.Share{}
+	for rows.Next() {
+		var d dbShare
+		if err := rows.Scan(d.fields()...); err != nil {
+			return nil, errors.Wrap(err, "sql: error reading shares")
+		}
+		s, err := d.share()
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, s)
+	}
+	return shares, errors.Wrap(rows.Err(), "sql: error reading shares")
+}
+
+func queryReceivedShares(ctx context.Context, q querier, query string, params ...any) ([]*collaboration.ReceivedShare, error) {
+	rows, err := q.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error querying received shares")
+	}
+	defer rows.Close()
+
+	rss := []*collaboration.ReceivedShare{}
+	for rows.Next() {
+		var (
+			d          dbShare
+			state      sql.NullInt64
+			hidden     sql.NullBool
+			mountPoint sql.NullString
+		)
+		if err := rows.Scan(append(d.fields(), &state, &hidden, &mountPoint)...); err != nil {
+			return nil, errors.Wrap(err, "sql: error reading received shares")
+		}
+		s, err := d.share()
+		if err != nil {
+			return nil, err
+		}
+		rs := &collaboration.ReceivedShare{
+			Share:  s,
+			State:  collaboration.ShareState_SHARE_STATE_PENDING,
+			Hidden: hidden.Bool,
+		}
+		if state.Valid {
+			rs.State = collaboration.ShareState(state.Int64)
+		}
+		if mountPoint.String != "" {
+			rs.MountPoint = &provider.Reference{Path: mountPoint.String}
+		}
+		rss = append(rss, rs)
+	}
+	return rss, errors.Wrap(rows.Err(), "sql: error reading received shares")
+}
+
+// dbShare is a row of the user_shares table.
+type dbShare struct {
+	ID, StorageID, SpaceID, OpaqueID string
+	OwnerIdp, Owner                  string
+	OwnerType                        int
+	CreatorIdp, Creator              string
+	CreatorType                      int
+	GranteeType                      int
+	GranteeIdp, Grantee              string
+	GranteeUserType                  int
+	Permissions                      string
+	Ctime, Mtime, Expiration         int64
+}
+
+func (d *dbShare) fields() []any {
+	return []any{&d.ID, &d.StorageID, &d.SpaceID, &d.OpaqueID, &d.OwnerIdp, &d.Owner, &d.OwnerType, &d.CreatorIdp, &d.Creator, &d.CreatorType,
+		&d.GranteeType, &d.GranteeIdp, &d.Grantee, &d.GranteeUserType, &d.Permissions, &d.Ctime, &d.Mtime, &d.Expiration}
+}
+
+func (d *dbShare) share() (*collaboration.Share, error) {
+	perms := &collaboration.SharePermissions{}
+	if err := utils.UnmarshalJSONToProtoV1([]byte(d.Permissions), perms); err != nil {
+		return nil, errors.Wrap(err, "sql: error decoding share permissions")
+	}
+	s := &collaboration.Share{
+		Id:          &collaboration.ShareId{OpaqueId: d.ID},
+		ResourceId:  &provider.ResourceId{StorageId: d.StorageID, SpaceId: d.SpaceID, OpaqueId: d.OpaqueID},
+		Permissions: perms,
+		Grantee:     extractGrantee(d.GranteeType, d.GranteeIdp, d.Grantee, d.GranteeUserType),
+		Owner:       &userpb.UserId{Idp: d.OwnerIdp, OpaqueId: d.Owner, Type: userpb.UserType(d.OwnerType)},
+		Creator:     &userpb.UserId{Idp: d.CreatorIdp, OpaqueId: d.Creator, Type: userpb.UserType(d.CreatorType)},
+		Ctime:       nanosToTimestamp(d.Ctime),
+		Mtime:       nanosToTimestamp(d.Mtime),
+	}
+	if d.Expiration != 0 {
+		s.Expiration = &typespb.Timestamp{Seconds: uint64(d.Expiration)}
+	}
+	return s, nil
+}
+
+// formatGrantee returns the type, idp, id and user type of a grantee.
+func formatGrantee(g *provider.Grantee) (int, string, string, int) {
+	switch g.GetType() {
+	case provider.GranteeType_GRANTEE_TYPE_USER:
+		u := g.GetUserId()
+		return int(g.Type), u.GetIdp(), u.GetOpaqueId(), int(u.GetType())
+	case provider.GranteeType_GRANTEE_TYPE_GROUP:
+		return int(g.Type), g.GetGroupId().GetIdp(), g.GetGroupId().GetOpaqueId(), 0
+	default:
+		return int(g.GetType()), "", "", 0
+	}
+}
+
+func extractGrantee(t int, idp, id string, userType int) *provider.Grantee {
+	g := &provider.Grantee{Type: provider.GranteeType(t)}
+	switch g.Type {
+	case provider.GranteeType_GRANTEE_TYPE_USER:
+		g.Id = &provider.Grantee_UserId{UserId: &userpb.UserId{Idp: idp, OpaqueId: id, Type: userpb.UserType(userType)}}
+	case provider.GranteeType_GRANTEE_TYPE_GROUP:
+		g.Id = &provider.Grantee_GroupId{GroupId: &grouppb.GroupId{Idp: idp, OpaqueId: id}}
+	}
+	return g
+}
+
+func timestampToNanos(ts *typespb.Timestamp) int64 {
+	return int64(ts.GetSeconds())*1000000000 + int64(ts.GetNanos())
+}
+
+func nanosToTimestamp(n int64) *typespb.Timestamp {
+	return &typespb.Timestamp{
+		Seconds: uint64(n / 1000000000),
+		Nanos:   uint32(n % 1000000000),
+	}
+}
+
+func transaction(ctx context.Context, db *sql.DB, f func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "sql: error starting transaction")
+	}
+	if err := f(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return errors.Wrap(tx.Commit(), "sql: error committing transaction")
+}
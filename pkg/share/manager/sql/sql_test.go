@@ -0,0 +1,314 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/share"
+	"github.com/cs3org/reva/pkg/share/manager/json"
+	"github.com/cs3org/reva/pkg/utils"
+	"google.golang.org/genproto/protobuf/field_mask"
+)
+
+var (
+	einstein = &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "einstein", Type: userpb.UserType_USER_TYPE_PRIMARY}}
+	marie    = &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "marie", Type: userpb.UserType_USER_TYPE_PRIMARY}, Groups: []string{"physics"}}
+	richard  = &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "richard", Type: userpb.UserType_USER_TYPE_PRIMARY}}
+
+	resource = &provider.ResourceInfo{
+		Id:    &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "folder"},
+		Owner: einstein.Id,
+	}
+	viewer = &collaboration.SharePermissions{Permissions: &provider.ResourcePermissions{Stat: true, InitiateFileDownload: true}}
+	editor = &collaboration.SharePermissions{Permissions: &provider.ResourcePermissions{Stat: true, InitiateFileDownload: true, InitiateFileUpload: true}}
+)
+
+func userGrant(u *userpb.User, p *collaboration.SharePermissions) *collaboration.ShareGrant {
+	return &collaboration.ShareGrant{
+		Grantee:     &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_USER, Id: &provider.Grantee_UserId{UserId: u.Id}},
+		Permissions: p,
+	}
+}
+
+func groupGrant(group string, p *collaboration.SharePermissions) *collaboration.ShareGrant {
+	return &collaboration.ShareGrant{
+		Grantee:     &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_GROUP, Id: &provider.Grantee_GroupId{GroupId: &grouppb.GroupId{OpaqueId: group}}},
+		Permissions: p,
+	}
+}
+
+func ctxFor(u *userpb.User) context.Context {
+	return appctx.ContextSetUser(context.Background(), u)
+}
+
+func newManager(t *testing.T) share.Manager {
+	m, err := New(context.Background(), map[string]interface{}{"db_file": filepath.Join(t.TempDir(), "shares.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func byID(id *collaboration.ShareId) *collaboration.ShareReference {
+	return &collaboration.ShareReference{Spec: &collaboration.ShareReference_Id{Id: id}}
+}
+
+func TestShares(t *testing.T) {
+	m := newManager(t)
+	ctx := ctxFor(einstein)
+
+	s, err := m.Share(ctx, resource, userGrant(marie, viewer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Share(ctx, resource, userGrant(marie, editor)); err == nil {
+		t.Fatal("sharing twice with the same grantee should fail")
+	} else if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+		t.Fatalf("expected an already exists error, got %v", err)
+	}
+	if _, err := m.Share(ctx, resource, userGrant(einstein, viewer)); err == nil {
+		t.Fatal("sharing with the owner should fail")
+	}
+
+	got, err := m.GetShare(ctx, byID(s.Id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utils.ResourceIDEqual(got.ResourceId, resource.Id) || got.ResourceId.SpaceId != "space" || !utils.GranteeEqual(got.Grantee, s.Grantee) ||
+		!utils.UserEqual(got.Owner, einstein.Id) || got.Permissions.Permissions.InitiateFileUpload || got.Ctime.Nanos != s.Ctime.Nanos {
+		t.Fatalf("got share %+v, expected %+v", got, s)
+	}
+
+	key := &collaboration.ShareReference{Spec: &collaboration.ShareReference_Key{Key: &collaboration.ShareKey{
+		Owner: einstein.Id, ResourceId: resource.Id, Grantee: s.Grantee,
+	}}}
+	if got, err := m.GetShare(ctxFor(marie), key); err != nil || got.Id.OpaqueId != s.Id.OpaqueId {
+		t.Fatalf("the grantee should get the share by key, got %v %v", got, err)
+	}
+	if _, err := m.GetShare(ctxFor(richard), byID(s.Id)); err == nil {
+		t.Fatal("the share should not be visible to other users")
+	}
+
+	if _, err := m.UpdateShare(ctxFor(marie), byID(s.Id), editor); err == nil {
+		t.Fatal("only the creator should update the share")
+	}
+	updated, err := m.UpdateShare(ctx, byID(s.Id), editor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.GetShare(ctx, byID(s.Id)); !got.Permissions.Permissions.InitiateFileUpload || timestampToNanos(got.Mtime) != timestampToNanos(updated.Mtime) {
+		t.Fatalf("the share was not updated: %+v", got)
+	}
+
+	if err := m.Unshare(ctxFor(marie), byID(s.Id)); err == nil {
+		t.Fatal("only the creator should remove the share")
+	}
+	if err := m.Unshare(ctx, byID(s.Id)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetShare(ctx, byID(s.Id)); err == nil {
+		t.Fatal("the share was not removed")
+	}
+}
+
+func TestListShares(t *testing.T) {
+	m := newManager(t)
+	ctx := ctxFor(einstein)
+	other := &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "storage", OpaqueId: "file"}, Owner: einstein.Id}
+
+	for _, share := range []struct {
+		md *provider.ResourceInfo
+		g  *collaboration.ShareGrant
+	}{
+		{resource, userGrant(marie, viewer)},
+		{resource, groupGrant("physics", viewer)},
+		{other, userGrant(marie, editor)},
+	} {
+		if _, err := m.Share(ctx, share.md, share.g); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		description string
+		filters     []*collaboration.Filter
+		expected    int
+	}{
+		{"no filters", nil, 3},
+		{"by resource", []*collaboration.Filter{share.ResourceIDFilter(resource.Id)}, 2},
+		{"by resources", []*collaboration.Filter{share.ResourceIDFilter(resource.Id), share.ResourceIDFilter(other.Id)}, 3},
+		{"by grantee type", []*collaboration.Filter{share.GroupGranteeFilter()}, 1},
+		{"by resource and grantee type", []*collaboration.Filter{share.ResourceIDFilter(other.Id), share.GroupGranteeFilter()}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			list, err := m.ListShares(ctx, tt.filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != tt.expected {
+				t.Fatalf("got %d shares, expected %d", len(list), tt.expected)
+			}
+		})
+	}
+
+	if list, err := m.ListShares(ctxFor(marie), nil); err != nil || len(list) != 0 {
+		t.Fatalf("the grantee should not list the shares as created, got %v %v", list, err)
+	}
+}
+
+func TestReceivedShares(t *testing.T) {
+	m := newManager(t)
+	ctx := ctxFor(einstein)
+	direct, err := m.Share(ctx, resource, userGrant(marie, viewer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := m.Share(ctx, resource, groupGrant("physics", viewer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mctx := ctxFor(marie)
+	list, err := m.ListReceivedShares(mctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected the user and group shares to be received, got %d", len(list))
+	}
+	for _, rs := range list {
+		if rs.State != collaboration.ShareState_SHARE_STATE_PENDING {
+			t.Fatalf("expected a pending share, got %s", rs.State)
+		}
+	}
+	if list, _ := m.ListReceivedShares(ctxFor(richard), nil); len(list) != 0 {
+		t.Fatalf("no shares should be received by other users, got %d", len(list))
+	}
+	if list, _ := m.ListReceivedShares(ctx, nil); len(list) != 0 {
+		t.Fatalf("the shares created by the user should not be received, got %d", len(list))
+	}
+
+	rs, err := m.UpdateReceivedShare(mctx, &collaboration.ReceivedShare{
+		Share:      group,
+		State:      collaboration.ShareState_SHARE_STATE_ACCEPTED,
+		MountPoint: &provider.Reference{Path: "relativity"},
+		Hidden:     true,
+	}, &field_mask.FieldMask{Paths: []string{"state", "mount_point", "hidden"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.State != collaboration.ShareState_SHARE_STATE_ACCEPTED || !rs.Hidden || rs.MountPoint.GetPath() != "relativity" {
+		t.Fatalf("the received share was not updated: %+v", rs)
+	}
+
+	got, err := m.GetReceivedShare(mctx, byID(group.Id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != collaboration.ShareState_SHARE_STATE_ACCEPTED || !got.Hidden || got.MountPoint.GetPath() != "relativity" {
+		t.Fatalf("the received share state was not stored: %+v", got)
+	}
+	if got, _ := m.GetReceivedShare(mctx, byID(direct.Id)); got.State != collaboration.ShareState_SHARE_STATE_PENDING {
+		t.Fatalf("the state of the other share should not change, got %s", got.State)
+	}
+
+	// the state is stored per recipient
+	physicist := &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "physicist"}, Groups: []string{"physics"}}
+	if got, err := m.GetReceivedShare(ctxFor(physicist), byID(group.Id)); err != nil || got.State != collaboration.ShareState_SHARE_STATE_PENDING || got.Hidden {
+		t.Fatalf("the state of another member of the group should not change, got %+v %v", got, err)
+	}
+
+	rs, err = m.UpdateReceivedShare(mctx, &collaboration.ReceivedShare{
+		Share: group,
+		State: collaboration.ShareState_SHARE_STATE_REJECTED,
+	}, &field_mask.FieldMask{Paths: []string{"state"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.State != collaboration.ShareState_SHARE_STATE_REJECTED || !rs.Hidden {
+		t.Fatalf("only the state should be updated, got %+v", rs)
+	}
+
+	if _, err := m.GetReceivedShare(ctxFor(richard), byID(group.Id)); err == nil {
+		t.Fatal("the share should not be received by other users")
+	}
+}
+
+func TestImportJSON(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "shares.json")
+	jm, err := json.New(context.Background(), map[string]interface{}{"file": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := ctxFor(einstein)
+	s, err := jm.Share(ctx, resource, userGrant(marie, viewer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jm.Share(ctx, resource, groupGrant("physics", editor)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jm.UpdateReceivedShare(ctxFor(marie), &collaboration.ReceivedShare{
+		Share: s,
+		State: collaboration.ShareState_SHARE_STATE_ACCEPTED,
+	}, &field_mask.FieldMask{Paths: []string{"state"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newManager(t)
+	n, err := ImportJSON(context.Background(), m, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 imported shares, got %d", n)
+	}
+	if n, err := ImportJSON(context.Background(), m, file); err != nil || n != 0 {
+		t.Fatalf("the shares should only be imported once, got %d %v", n, err)
+	}
+
+	list, err := m.ListShares(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 shares, got %d", len(list))
+	}
+	rs, err := m.GetReceivedShare(ctxFor(marie), byID(s.Id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.State != collaboration.ShareState_SHARE_STATE_ACCEPTED || !utils.ResourceIDEqual(rs.Share.ResourceId, resource.Id) {
+		t.Fatalf("the share was not imported with its state: %+v", rs)
+	}
+}
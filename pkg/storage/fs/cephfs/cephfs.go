@@ -42,6 +42,7 @@ import (
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/storage"
 	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/storage/utils/tus"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/cs3org/reva/pkg/utils/cfg"
 	"github.com/pkg/errors"
@@ -57,6 +58,7 @@ type cephfs struct {
 	conn         *connections
 	adminConn    *adminConn
 	chunkHandler *ChunkHandler
+	uploads      *tus.Store
 }
 
 func init() {
@@ -81,11 +83,13 @@ func New(ctx context.Context, m map[string]interface{}) (fs storage.FS, err erro
 		return nil, errors.Wrap(err, "cephfs: couldn't create admin connections")
 	}
 
-	return &cephfs{
+	cfs := &cephfs{
 		conf:      &o,
 		conn:      cache,
 		adminConn: adminConn,
-	}, nil
+	}
+	cfs.uploads = tus.New(o.UploadsSpool, time.Duration(o.UploadExpiration)*time.Second, cfs.Upload)
+	return cfs, nil
 }
 
 func (fs *cephfs) GetHome(ctx context.Context) (string, error) {
@@ -217,7 +221,7 @@ func (fs *cephfs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []s
 
 	user.op(func(cv *cacheVal) {
 		var stat Statx
-		if stat, err = cv.mount.Statx(path, goceph.StatxBasicStats, 0); err != nil {
+		if stat, err = cv.mount.Statx(path, goceph.StatxBasicStats, goceph.AtSymlinkNofollow); err != nil {
 			log.Debug().Str("path", path).Err(err).Msg("cv.mount.Statx returned")
 			return
 		}
@@ -255,7 +259,7 @@ func (fs *cephfs) ListFolder(ctx context.Context, ref *provider.Reference, mdKey
 		var entry *goceph.DirEntryPlus
 		var ri *provider.ResourceInfo
 
-		for entry, err = dir.ReadDirPlus(goceph.StatxBasicStats, 0); entry != nil && err == nil; entry, err = dir.ReadDirPlus(goceph.StatxBasicStats, 0) {
+		for entry, err = dir.ReadDirPlus(goceph.StatxBasicStats, goceph.AtSymlinkNofollow); entry != nil && err == nil; entry, err = dir.ReadDirPlus(goceph.StatxBasicStats, goceph.AtSymlinkNofollow) {
 			if fs.conf.HiddenDirs[entry.Name()] {
 				continue
 			}
@@ -426,6 +430,7 @@ func (fs *cephfs) CreateReference(ctx context.Context, path string, targetURI *u
 
 func (fs *cephfs) Shutdown(ctx context.Context) (err error) {
 	ctx.Done()
+	fs.uploads.Close()
 	fs.conn.clearCache()
 	_ = fs.adminConn.adminMount.Unmount()
 	_ = fs.adminConn.adminMount.Release()
@@ -502,6 +507,39 @@ func (fs *cephfs) TouchFile(ctx context.Context, ref *provider.Reference) error
 	return getRevaError(ctx, err)
 }
 
+// CreateSymlink creates a symbolic link at the given reference. Relative targets
+// must not climb above the root of the mount, while absolute ones are stored
+// relative to the link so that they resolve the same way from a direct mount.
+func (fs *cephfs) CreateSymlink(ctx context.Context, ref *provider.Reference, target string) error {
+	user := fs.makeUser(ctx)
+	path, err := user.resolveRef(ref)
+	if err != nil {
+		return getRevaError(ctx, err)
+	}
+
+	if target == "" {
+		return errtypes.BadRequest("cephfs: missing symlink target")
+	}
+	dir := filepath.Dir(path)
+	if filepath.IsAbs(target) {
+		if target, err = filepath.Rel(dir, filepath.Clean(target)); err != nil {
+			return errors.Wrap(err, "cephfs: error computing symlink target")
+		}
+	} else if escapesRoot(dir, target) {
+		return errtypes.PermissionDenied("cephfs: symlink target " + target + " is outside of the namespace")
+	}
+
+	log := appctx.GetLogger(ctx)
+	user.op(func(cv *cacheVal) {
+		if err = cv.mount.Symlink(target, path); err != nil {
+			log.Debug().Any("ref", ref).Str("target", target).Err(err).Msg("cv.mount.Symlink returned")
+			return
+		}
+	})
+
+	return getRevaError(ctx, err)
+}
+
 func (fs *cephfs) EmptyRecycle(ctx context.Context) error {
 	return errtypes.NotSupported("unimplemented")
 }
@@ -530,6 +568,10 @@ func (fs *cephfs) UpdateStorageSpace(ctx context.Context, req *provider.UpdateSt
 	return nil, errtypes.NotSupported("unimplemented")
 }
 
+func (fs *cephfs) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) error {
+	return errtypes.NotSupported("unimplemented")
+}
+
 var fnvHash = fnv.New32a()
 
 func getHash(s string) uint64 {
@@ -22,6 +22,9 @@
 package cephfs
 
 import (
+	"os"
+	"path/filepath"
+
 	"github.com/cs3org/reva/pkg/sharedconf"
 )
 
@@ -39,6 +42,12 @@ type Options struct {
 	FilePerms      uint32 `mapstructure:"file_perms"`
 	UserQuotaBytes uint64 `mapstructure:"user_quota_bytes"`
 	HiddenDirs     map[string]bool
+
+	// UploadsSpool is the local or shared folder where the partial tus uploads
+	// are staged, it must be shared by the storage provider and its data servers.
+	UploadsSpool string `mapstructure:"uploads_spool"`
+	// UploadExpiration is the time in seconds after which the unfinished tus uploads are removed.
+	UploadExpiration int `mapstructure:"upload_expiration"`
 }
 
 func (c *Options) ApplyDefaults() {
@@ -95,4 +104,12 @@ func (c *Options) ApplyDefaults() {
 	if c.UserQuotaBytes == 0 {
 		c.UserQuotaBytes = 50000000000
 	}
+
+	if c.UploadsSpool == "" {
+		c.UploadsSpool = filepath.Join(os.TempDir(), "reva", "cephfs", "uploads")
+	}
+
+	if c.UploadExpiration == 0 {
+		c.UploadExpiration = 86400
+	}
 }
@@ -25,10 +25,12 @@ import (
 	"context"
 	"io"
 	"os"
+	"time"
 
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
 )
 
 func (fs *cephfs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser, metadata map[string]string) error {
@@ -59,7 +61,7 @@ func (fs *cephfs) Upload(ctx context.Context, ref *provider.Reference, r io.Read
 			}
 		})
 
-		return nil
+		return err
 	}
 
 	// upload is chunked
@@ -94,7 +96,30 @@ func (fs *cephfs) InitiateUpload(ctx context.Context, ref *provider.Reference, u
 		return nil, errors.Wrap(err, "cephfs: error resolving reference")
 	}
 
-	return map[string]string{
+	uploadIDs := map[string]string{
 		"simple": np,
-	}, nil
+	}
+
+	// tus uploads are staged in the spool and written through Upload once complete,
+	// the spool entry is only created for the clients asking for tus
+	if metadata["protocol"] == "tus" {
+		id, err := fs.uploads.Initiate(ctx, &provider.Reference{Path: np}, uploadLength, metadata)
+		if err != nil {
+			return nil, errors.Wrap(err, "cephfs: error initiating tus upload")
+		}
+		uploadIDs["tus"] = id
+	}
+
+	return uploadIDs, nil
+}
+
+// UseIn tells the tus upload middleware which extensions it supports.
+func (fs *cephfs) UseIn(composer *tusd.StoreComposer) {
+	fs.uploads.UseIn(composer)
+}
+
+// UploadExpiration returns the time after which the unfinished tus upload
+// with the given id is removed.
+func (fs *cephfs) UploadExpiration(ctx context.Context, id string) (time.Time, bool) {
+	return fs.uploads.Expiration(ctx, id)
 }
@@ -23,6 +23,7 @@ package cephfs
 
 import (
 	"path/filepath"
+	"strings"
 
 	goceph "github.com/ceph/go-ceph/cephfs"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
@@ -68,6 +69,28 @@ func isDir(t provider.ResourceType) bool {
 	return t == provider.ResourceType_RESOURCE_TYPE_CONTAINER
 }
 
+// escapesRoot tells whether the relative target, resolved against the
+// absolute directory dir, climbs above the root.
+func escapesRoot(dir, target string) bool {
+	depth := len(strings.Split(strings.Trim(dir, "/"), "/"))
+	if dir == "/" {
+		depth = 0
+	}
+	for _, c := range strings.Split(target, "/") {
+		switch c {
+		case "", ".":
+		case "..":
+			depth--
+			if depth < 0 {
+				return true
+			}
+		default:
+			depth++
+		}
+	}
+	return false
+}
+
 func removeLeadingSlash(path string) string {
 	return filepath.Join(".", path)
 }
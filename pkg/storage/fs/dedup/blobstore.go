@@ -0,0 +1,98 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package dedup
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/indexfs"
+	"github.com/pkg/errors"
+)
+
+// blobstore stores the blobs as files in a directory,
+// sharded by the first two characters of their key.
+type blobstore struct {
+	root string
+}
+
+var _ indexfs.Blobstore = (*blobstore)(nil)
+
+func newBlobstore(root string) (*blobstore, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, errors.Wrap(err, "dedup: error creating blobs directory "+root)
+	}
+	return &blobstore{root: root}, nil
+}
+
+func (b *blobstore) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(b.root, key)
+	}
+	return filepath.Join(b.root, key[:2], key)
+}
+
+// Upload stores the content read from r. The content is written to a
+// temporary file first, so that a blob is never seen partially written.
+func (b *blobstore) Upload(ctx context.Context, key string, r io.Reader) error {
+	fn := b.path(key)
+	if err := os.MkdirAll(filepath.Dir(fn), 0700); err != nil {
+		return errors.Wrap(err, "dedup: error creating directory of blob "+key)
+	}
+	f, err := os.CreateTemp(filepath.Dir(fn), ".upload-*")
+	if err != nil {
+		return errors.Wrap(err, "dedup: error creating blob "+key)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return errors.Wrap(err, "dedup: error writing blob "+key)
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "dedup: error writing blob "+key)
+	}
+	if err := os.Rename(f.Name(), fn); err != nil {
+		return errors.Wrap(err, "dedup: error storing blob "+key)
+	}
+	return nil
+}
+
+// Download returns the content of the given blob.
+func (b *blobstore) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(b.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errtypes.NotFound("dedup: blob " + key)
+		}
+		return nil, errors.Wrap(err, "dedup: error reading blob "+key)
+	}
+	return f, nil
+}
+
+// Delete removes the given blob.
+func (b *blobstore) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(b.path(key)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "dedup: error deleting blob "+key)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package dedup implements a storage driver deduplicating the content
+// of the files on the local disk. The content is stored in blobs keyed by
+// its checksum and shared between all the files, versions and recycled
+// resources with the same content. The blobs are reference counted and
+// removed once nothing references them anymore, so that the quota reflects
+// the logical size of the files while the disk usage reflects the unique content.
+package dedup
+
+import (
+	"context"
+	"path"
+
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/storage/utils/indexfs"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+)
+
+func init() {
+	registry.Register("dedup", New)
+}
+
+type config struct {
+	Root       string `docs:"/var/tmp/reva/dedup;Directory holding the metadata index."                mapstructure:"root"`
+	Blobs      string `docs:"<root>/blobs;Directory where the content of the files is stored."         mapstructure:"blobs"`
+	EnableHome bool   `docs:"false;Whether to root the namespace of the users in their home."           mapstructure:"enable_home"`
+	UserLayout string `docs:"{{.Username}};Template for the home of the users."                         mapstructure:"user_layout"`
+	Quota      uint64 `docs:"0;Quota of the users, in bytes, accounting for the logical size of the files and their versions. 0 means unlimited." mapstructure:"quota"`
+}
+
+func (c *config) ApplyDefaults() {
+	if c.Root == "" {
+		c.Root = "/var/tmp/reva/dedup"
+	}
+	if c.Blobs == "" {
+		c.Blobs = path.Join(c.Root, "blobs")
+	}
+}
+
+// New returns an implementation of the storage.FS interface
+// deduplicating the content of the files on the local disk.
+func New(ctx context.Context, m map[string]interface{}) (storage.FS, error) {
+	var c config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+
+	blobs, err := newBlobstore(c.Blobs)
+	if err != nil {
+		return nil, err
+	}
+
+	return indexfs.New(ctx, &indexfs.Config{
+		Root:             c.Root,
+		EnableHome:       c.EnableHome,
+		UserLayout:       c.UserLayout,
+		Quota:            c.Quota,
+		ContentAddressed: true,
+	}, blobs)
+}
@@ -0,0 +1,356 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package dedup
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/indexfs"
+)
+
+var (
+	einstein = &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein", Idp: "cernbox.cern.ch"}, Username: "einstein"}
+	marie    = &userpb.User{Id: &userpb.UserId{OpaqueId: "marie", Idp: "cernbox.cern.ch"}, Username: "marie"}
+)
+
+func setup(t *testing.T) (storage.FS, string, context.Context) {
+	root := t.TempDir()
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	driver, err := New(ctx, map[string]interface{}{
+		"root":        root,
+		"enable_home": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = driver.Shutdown(ctx) })
+
+	if err := driver.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return driver, filepath.Join(root, "blobs"), ctx
+}
+
+func upload(ctx context.Context, driver storage.FS, fn string, length int64, content string) error {
+	ids, err := driver.InitiateUpload(ctx, &provider.Reference{Path: fn}, length, nil)
+	if err != nil {
+		return err
+	}
+	return driver.Upload(ctx, &provider.Reference{Path: "/" + ids["simple"]}, io.NopCloser(strings.NewReader(content)), nil)
+}
+
+func uploadFile(t *testing.T, ctx context.Context, driver storage.FS, fn, content string) {
+	if err := upload(ctx, driver, fn, int64(len(content)), content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func downloadFile(t *testing.T, ctx context.Context, driver storage.FS, fn string) string {
+	r, err := driver.Download(ctx, &provider.Reference{Path: fn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func deleteFile(t *testing.T, ctx context.Context, driver storage.FS, fn string) {
+	if err := driver.Delete(ctx, &provider.Reference{Path: fn}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// blobs returns the content of the blobs stored on disk.
+func blobs(t *testing.T, dir string) []string {
+	var contents []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		contents = append(contents, string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(contents)
+	return contents
+}
+
+func expectBlobs(t *testing.T, dir string, expected ...string) {
+	t.Helper()
+	got := blobs(t, dir)
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected blobs %v, got %v", expected, got)
+	}
+}
+
+func expectUsage(t *testing.T, ctx context.Context, driver storage.FS, expected uint64) {
+	t.Helper()
+	_, used, err := driver.GetQuota(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used != expected {
+		t.Fatalf("expected %d bytes used, got %d", expected, used)
+	}
+}
+
+func TestDeduplication(t *testing.T) {
+	driver, dir, ctx := setup(t)
+
+	uploadFile(t, ctx, driver, "/a.txt", "relativity")
+	uploadFile(t, ctx, driver, "/b.txt", "relativity")
+	expectBlobs(t, dir, "relativity")
+	expectUsage(t, ctx, driver, 20)
+
+	for _, fn := range []string{"/a.txt", "/b.txt"} {
+		if got := downloadFile(t, ctx, driver, fn); got != "relativity" {
+			t.Fatalf("got %q", got)
+		}
+	}
+
+	md, err := driver.GetMD(ctx, &provider.Reference{Path: "/a.txt"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum([]byte("relativity"))
+	if md.Checksum.GetType() != provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_SHA1 || md.Checksum.GetSum() != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected checksum %v", md.Checksum)
+	}
+
+	// the content of other users is shared as well
+	other := appctx.ContextSetUser(context.Background(), marie)
+	if err := driver.CreateHome(other); err != nil {
+		t.Fatal(err)
+	}
+	uploadFile(t, other, driver, "/c.txt", "relativity")
+	expectBlobs(t, dir, "relativity")
+	expectUsage(t, other, driver, 10)
+}
+
+func TestGarbageCollection(t *testing.T) {
+	driver, dir, ctx := setup(t)
+
+	uploadFile(t, ctx, driver, "/a.txt", "v1")
+	uploadFile(t, ctx, driver, "/b.txt", "v1")
+
+	// the previous content is referenced by the version
+	uploadFile(t, ctx, driver, "/a.txt", "v2")
+	expectBlobs(t, dir, "v1", "v2")
+
+	// and by the recycled file
+	deleteFile(t, ctx, driver, "/b.txt")
+	expectBlobs(t, dir, "v1", "v2")
+
+	items, err := driver.ListRecycle(ctx, "/", "", "", nil, nil)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected one recycle item, got %v %v", items, err)
+	}
+	if err := driver.PurgeRecycleItem(ctx, "/", items[0].Key, ""); err != nil {
+		t.Fatal(err)
+	}
+	expectBlobs(t, dir, "v1", "v2")
+
+	revisions, err := driver.ListRevisions(ctx, &provider.Reference{Path: "/a.txt"})
+	if err != nil || len(revisions) != 1 {
+		t.Fatalf("expected one revision, got %v %v", revisions, err)
+	}
+	if err := driver.RestoreRevision(ctx, &provider.Reference{Path: "/a.txt"}, revisions[0].Key); err != nil {
+		t.Fatal(err)
+	}
+	if got := downloadFile(t, ctx, driver, "/a.txt"); got != "v1" {
+		t.Fatalf("got %q", got)
+	}
+	expectBlobs(t, dir, "v1", "v2")
+
+	// once nothing references the blobs anymore they are removed
+	deleteFile(t, ctx, driver, "/a.txt")
+	expectBlobs(t, dir, "v1", "v2")
+	if err := driver.EmptyRecycle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectBlobs(t, dir)
+	expectUsage(t, ctx, driver, 0)
+}
+
+// blockingBlobstore blocks the deletions of blobs until released.
+type blockingBlobstore struct {
+	*blobstore
+	deleting chan string
+	release  chan struct{}
+}
+
+func (b *blockingBlobstore) Delete(ctx context.Context, key string) error {
+	b.deleting <- key
+	<-b.release
+	return b.blobstore.Delete(ctx, key)
+}
+
+func TestCollectWhileUploading(t *testing.T) {
+	root := t.TempDir()
+	bs, err := newBlobstore(filepath.Join(root, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs := &blockingBlobstore{blobstore: bs, deleting: make(chan string), release: make(chan struct{})}
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	driver, err := indexfs.New(ctx, &indexfs.Config{Root: root, EnableHome: true, ContentAddressed: true}, blobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = driver.Shutdown(ctx) })
+	if err := driver.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	uploadFile(t, ctx, driver, "/a.txt", "relativity")
+	deleteFile(t, ctx, driver, "/a.txt")
+	purged := make(chan error)
+	go func() { purged <- driver.EmptyRecycle(ctx) }()
+	<-blobs.deleting
+
+	// other uploads are not blocked by the deletion
+	uploadFile(t, ctx, driver, "/b.txt", "gravity")
+
+	// while the ones of the same content wait for it, and store the blob again
+	uploaded := make(chan error)
+	go func() { uploaded <- upload(ctx, driver, "/c.txt", 10, "relativity") }()
+	close(blobs.release)
+	if err := <-purged; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-uploaded; err != nil {
+		t.Fatal(err)
+	}
+	expectBlobs(t, filepath.Join(root, "blobs"), "gravity", "relativity")
+	if got := downloadFile(t, ctx, driver, "/c.txt"); got != "relativity" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestFailedUpload(t *testing.T) {
+	driver, dir, ctx := setup(t)
+
+	uploadFile(t, ctx, driver, "/a.txt", "relativity")
+
+	// a failed upload of the same content keeps the blob in use
+	if err := upload(ctx, driver, "/b.txt", 20, "relativity"); err == nil {
+		t.Fatal("expected the upload to fail")
+	}
+	expectBlobs(t, dir, "relativity")
+
+	// while the blob of a new content is removed
+	if err := upload(ctx, driver, "/b.txt", 20, "gravity"); err == nil {
+		t.Fatal("expected the upload to fail")
+	}
+	expectBlobs(t, dir, "relativity")
+
+	if got := downloadFile(t, ctx, driver, "/a.txt"); got != "relativity" {
+		t.Fatalf("got %q", got)
+	}
+	if _, err := driver.GetMD(ctx, &provider.Reference{Path: "/b.txt"}, nil); err == nil {
+		t.Fatal("the failed upload should not have created the file")
+	}
+}
+
+func TestQuota(t *testing.T) {
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	driver, err := New(ctx, map[string]interface{}{
+		"root":        t.TempDir(),
+		"enable_home": true,
+		"quota":       25,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = driver.Shutdown(ctx) })
+	other := appctx.ContextSetUser(context.Background(), marie)
+	for _, c := range []context.Context{ctx, other} {
+		if err := driver.CreateHome(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the previous versions count against the quota
+	uploadFile(t, ctx, driver, "/a.txt", "relativity")
+	uploadFile(t, ctx, driver, "/a.txt", "gravity!!!")
+	expectUsage(t, ctx, driver, 20)
+	if err := upload(ctx, driver, "/a.txt", 10, "quantum!!!"); err == nil {
+		t.Fatal("expected the quota to be exceeded")
+	}
+
+	// the files uploaded in a shared folder count against the quota of its owner
+	if err := driver.CreateDir(ctx, &provider.Reference{Path: "/shared"}); err != nil {
+		t.Fatal(err)
+	}
+	shared, err := driver.GetMD(ctx, &provider.Reference{Path: "/shared"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.AddGrant(ctx, &provider.Reference{Path: "/shared"}, &provider.Grant{
+		Grantee: &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id:   &provider.Grantee_UserId{UserId: marie.Id},
+		},
+		Permissions: &provider.ResourcePermissions{Stat: true, GetQuota: true, InitiateFileUpload: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	ref := &provider.Reference{ResourceId: shared.Id, Path: "b.txt"}
+	ids, err := driver.InitiateUpload(other, ref, 5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Upload(other, &provider.Reference{Path: "/" + ids["simple"]}, io.NopCloser(strings.NewReader("light")), nil); err != nil {
+		t.Fatal(err)
+	}
+	expectUsage(t, ctx, driver, 25)
+	expectUsage(t, other, driver, 0)
+
+	// the quota of the owner of the referenced resource is returned
+	total, used, err := driver.GetQuota(other, &provider.Reference{ResourceId: shared.Id})
+	if err != nil || total != 25 || used != 25 {
+		t.Fatalf("expected the quota of the owner of the folder, got %d %d %v", total, used, err)
+	}
+	if _, err := driver.InitiateUpload(other, &provider.Reference{ResourceId: shared.Id, Path: "c.txt"}, 1, nil); err == nil {
+		t.Fatal("expected the quota of the owner to be exceeded")
+	}
+}
@@ -0,0 +1,441 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package indexed implements a storage driver keeping a search index
+// of the resources of another storage driver. The index is updated as
+// the resources are changed through the driver, and the folders are
+// crawled the first time they are searched, to pick up the resources
+// created before the index or out of band. The files are recorded when
+// their upload is initiated, so that the uploads finalized by another
+// instance of the driver, such as the one of the data provider, are found too.
+// A folder can also be reindexed on demand, through the SearchAPI of the
+// storage provider, when it has been changed out of band.
+package indexed
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/search"
+	searchregistry "github.com/cs3org/reva/pkg/search/registry"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+)
+
+func init() {
+	registry.Register("indexed", New)
+}
+
+type config struct {
+	Driver        string                            `docs:"localhome;The storage driver whose resources are indexed."                     mapstructure:"driver"`
+	Drivers       map[string]map[string]interface{} `docs:"url:pkg/storage/fs/localhome/localhome.go"                                    mapstructure:"drivers"`
+	Index         string                            `docs:"sqlite;The search index to use."                                               mapstructure:"index"`
+	Indexes       map[string]map[string]interface{} `docs:"url:pkg/search/sqlite/sqlite.go"                                              mapstructure:"indexes"`
+	CrawlInterval int                               `docs:"300;Seconds after which a searched folder is crawled again, to pick up the changes made by other users or out of band. A negative value means never." mapstructure:"crawl_interval"`
+}
+
+func (c *config) ApplyDefaults() {
+	if c.Driver == "" {
+		c.Driver = "localhome"
+	}
+	if c.Index == "" {
+		c.Index = "sqlite"
+	}
+	if c.CrawlInterval == 0 {
+		c.CrawlInterval = 300
+	}
+}
+
+type fs struct {
+	storage.FS
+	index         search.Index
+	crawlInterval time.Duration
+
+	// crawls holds the crawl state of every namespace, so that the
+	// crawls of one user do not hold back the searches of the others
+	crawlMu sync.Mutex
+	crawls  map[string]*crawlState
+}
+
+// crawlState records when the folders of a namespace were last crawled.
+// Its lock is held during the crawls, so that a folder is crawled once
+// when it is searched concurrently.
+type crawlState struct {
+	sync.Mutex
+	crawled map[string]time.Time
+}
+
+var _ search.Searcher = (*fs)(nil)
+
+// New returns an implementation of the storage.FS interface
+// indexing the resources of the configured driver.
+func New(ctx context.Context, m map[string]interface{}) (storage.FS, error) {
+	var c config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+
+	if c.Driver == "indexed" {
+		return nil, errtypes.BadRequest("indexed: the indexed driver cannot index itself")
+	}
+	newFS, ok := registry.NewFuncs[c.Driver]
+	if !ok {
+		return nil, errtypes.NotFound("indexed: driver not found: " + c.Driver)
+	}
+	newIndex, ok := searchregistry.NewFuncs[c.Index]
+	if !ok {
+		return nil, errtypes.NotFound("indexed: search index not found: " + c.Index)
+	}
+
+	wrapped, err := newFS(ctx, c.Drivers[c.Driver])
+	if err != nil {
+		return nil, err
+	}
+	index, err := newIndex(ctx, c.Indexes[c.Index])
+	if err != nil {
+		return nil, err
+	}
+	return Wrap(wrapped, index, time.Duration(c.CrawlInterval)*time.Second), nil
+}
+
+// Wrap returns a storage.FS recording in index the resources of wrapped.
+// The searched folders are crawled again after crawlInterval, if positive.
+func Wrap(wrapped storage.FS, index search.Index, crawlInterval time.Duration) storage.FS {
+	return &fs{
+		FS:            wrapped,
+		index:         index,
+		crawlInterval: crawlInterval,
+		crawls:        make(map[string]*crawlState),
+	}
+}
+
+// crawlState returns the crawl state of the namespace ns.
+func (fs *fs) crawlState(ns string) *crawlState {
+	fs.crawlMu.Lock()
+	defer fs.crawlMu.Unlock()
+	s, ok := fs.crawls[ns]
+	if !ok {
+		s = &crawlState{crawled: make(map[string]time.Time)}
+		fs.crawls[ns] = s
+	}
+	return s
+}
+
+// namespace returns the namespace of the index holding the paths
+// seen by the user in ctx. The paths returned by the drivers may
+// depend on the user, so every user has its own namespace.
+func namespace(ctx context.Context) (string, bool) {
+	u, ok := appctx.ContextGetUser(ctx)
+	if !ok || u.Id == nil {
+		return "", false
+	}
+	return u.Id.Idp + "!" + u.Id.OpaqueId, true
+}
+
+// getPath returns the path of the resource referenced by ref,
+// or an empty string if it cannot be resolved.
+func (fs *fs) getPath(ctx context.Context, ref *provider.Reference) string {
+	md, err := fs.FS.GetMD(ctx, ref, nil)
+	if err != nil {
+		return ""
+	}
+	return md.Path
+}
+
+// added records in the index the resource referenced by ref.
+// Failures to update the index do not fail the operations on the
+// resources, they only delay their results in the searches.
+func (fs *fs) added(ctx context.Context, ref *provider.Reference) {
+	ns, ok := namespace(ctx)
+	if !ok {
+		return
+	}
+	if p := fs.getPath(ctx, ref); p != "" {
+		if err := fs.index.Add(ctx, ns, p); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Str("path", p).Msg("indexed: error adding resource to the index")
+		}
+	}
+}
+
+func (fs *fs) removed(ctx context.Context, p string) {
+	ns, ok := namespace(ctx)
+	if !ok || p == "" {
+		return
+	}
+	if err := fs.index.Remove(ctx, ns, p); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("path", p).Msg("indexed: error removing resource from the index")
+	}
+}
+
+func (fs *fs) CreateDir(ctx context.Context, ref *provider.Reference) error {
+	if err := fs.FS.CreateDir(ctx, ref); err != nil {
+		return err
+	}
+	fs.added(ctx, ref)
+	return nil
+}
+
+func (fs *fs) TouchFile(ctx context.Context, ref *provider.Reference) error {
+	if err := fs.FS.TouchFile(ctx, ref); err != nil {
+		return err
+	}
+	fs.added(ctx, ref)
+	return nil
+}
+
+func (fs *fs) CreateSymlink(ctx context.Context, ref *provider.Reference, target string) error {
+	if err := fs.FS.CreateSymlink(ctx, ref, target); err != nil {
+		return err
+	}
+	fs.added(ctx, ref)
+	return nil
+}
+
+func (fs *fs) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	ids, err := fs.FS.InitiateUpload(ctx, ref, uploadLength, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	// the uploads are usually referenced by their id once initiated,
+	// so the files are recorded as soon as their upload is initiated
+	ns, ok := namespace(ctx)
+	if !ok {
+		return ids, nil
+	}
+	p := ref.GetPath()
+	if ref.GetResourceId() != nil {
+		parent := fs.getPath(ctx, &provider.Reference{ResourceId: ref.ResourceId})
+		if parent == "" {
+			return ids, nil
+		}
+		p = path.Join(parent, p)
+	}
+	if err := fs.index.Add(ctx, ns, p); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("path", p).Msg("indexed: error adding resource to the index")
+	}
+	return ids, nil
+}
+
+// ConcatenatesUploads tells whether the wrapped driver concatenates uploads.
+func (fs *fs) ConcatenatesUploads() bool {
+	return storage.ConcatenatesUploads(fs.FS)
+}
+
+func (fs *fs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser, metadata map[string]string) error {
+	if err := fs.FS.Upload(ctx, ref, r, metadata); err != nil {
+		return err
+	}
+	fs.added(ctx, ref)
+	return nil
+}
+
+func (fs *fs) Delete(ctx context.Context, ref *provider.Reference) error {
+	p := fs.getPath(ctx, ref)
+	if err := fs.FS.Delete(ctx, ref); err != nil {
+		return err
+	}
+	fs.removed(ctx, p)
+	return nil
+}
+
+func (fs *fs) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	oldPath := fs.getPath(ctx, oldRef)
+	if err := fs.FS.Move(ctx, oldRef, newRef); err != nil {
+		return err
+	}
+
+	ns, ok := namespace(ctx)
+	if !ok {
+		return nil
+	}
+	newPath := fs.getPath(ctx, newRef)
+	if oldPath == "" || newPath == "" {
+		return nil
+	}
+	if err := fs.index.Move(ctx, ns, oldPath, newPath); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("path", oldPath).Str("destination", newPath).Msg("indexed: error moving resource in the index")
+		return nil
+	}
+	// the resource is indexed even if its previous location was not
+	fs.added(ctx, &provider.Reference{Path: newPath})
+	return nil
+}
+
+func (fs *fs) RestoreRecycleItem(ctx context.Context, basePath, key, relativePath string, restoreRef *provider.Reference) error {
+	var restored string
+	if restoreRef == nil {
+		// the item is restored to its original location
+		items, err := fs.FS.ListRecycle(ctx, basePath, "", "", nil, nil)
+		if err == nil {
+			for _, item := range items {
+				if item.Key == key && item.Ref != nil {
+					restored = path.Join(item.Ref.Path, relativePath)
+					break
+				}
+			}
+		}
+	}
+
+	if err := fs.FS.RestoreRecycleItem(ctx, basePath, key, relativePath, restoreRef); err != nil {
+		return err
+	}
+
+	if restoreRef != nil {
+		restored = fs.getPath(ctx, restoreRef)
+	}
+	ns, ok := namespace(ctx)
+	if !ok || restored == "" {
+		return nil
+	}
+	// the content of the restored folders is not in the index anymore
+	if err := fs.index.Add(ctx, ns, restored); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("path", restored).Msg("indexed: error adding resource to the index")
+		return nil
+	}
+	if err := fs.crawl(ctx, ns, restored); err != nil {
+		appctx.GetLogger(ctx).Error().Err(err).Str("path", restored).Msg("indexed: error crawling restored resource")
+	}
+	return nil
+}
+
+// crawl adds to the index the descendants of the folder at p.
+func (fs *fs) crawl(ctx context.Context, ns, p string) error {
+	mds, err := fs.FS.ListFolder(ctx, &provider.Reference{Path: p}, nil)
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(mds))
+	for _, md := range mds {
+		paths = append(paths, md.Path)
+	}
+	if err := fs.index.Add(ctx, ns, paths...); err != nil {
+		return err
+	}
+
+	for _, md := range mds {
+		if md.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			continue
+		}
+		if err := fs.crawl(ctx, ns, md.Path); err != nil {
+			switch err.(type) {
+			case errtypes.IsNotFound, errtypes.IsPermissionDenied:
+				continue
+			default:
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// within tells whether p is root or one of its descendants.
+func within(root, p string) bool {
+	return p == root || root == "/" || strings.HasPrefix(p, root+"/")
+}
+
+// ensureCrawled crawls the folder at root, unless it or one of its
+// ancestors has been crawled recently.
+func (fs *fs) ensureCrawled(ctx context.Context, ns, root string) error {
+	s := fs.crawlState(ns)
+	s.Lock()
+	defer s.Unlock()
+
+	for p, t := range s.crawled {
+		if within(p, root) && (fs.crawlInterval <= 0 || time.Since(t) < fs.crawlInterval) {
+			return nil
+		}
+	}
+
+	start := time.Now()
+	if err := fs.crawl(ctx, ns, root); err != nil {
+		return err
+	}
+	s.crawled[root] = start
+	return nil
+}
+
+// Search returns the resources below the folder referenced by ref
+// whose name matches q, as seen by the user in ctx.
+func (fs *fs) Search(ctx context.Context, ref *provider.Reference, q search.Query) ([]*provider.ResourceInfo, error) {
+	ns, ok := namespace(ctx)
+	if !ok {
+		return nil, errtypes.UserRequired("indexed: error getting user from ctx")
+	}
+
+	md, err := fs.FS.GetMD(ctx, ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		return nil, errtypes.BadRequest("indexed: " + md.Path + " is not a folder")
+	}
+
+	if err := fs.ensureCrawled(ctx, ns, md.Path); err != nil {
+		return nil, err
+	}
+	return search.Find(ctx, fs.index, ns, md.Path, q, func(ctx context.Context, p string) (*provider.ResourceInfo, error) {
+		return fs.FS.GetMD(ctx, &provider.Reference{Path: p}, nil)
+	})
+}
+
+// Reindex drops from the index the resources below the folder referenced
+// by ref and crawls it again, as seen by the user in ctx. It is meant to
+// bring the index back in sync after changes made out of band, without
+// waiting for the crawl interval.
+func (fs *fs) Reindex(ctx context.Context, ref *provider.Reference) error {
+	ns, ok := namespace(ctx)
+	if !ok {
+		return errtypes.UserRequired("indexed: error getting user from ctx")
+	}
+
+	md, err := fs.FS.GetMD(ctx, ref, nil)
+	if err != nil {
+		return err
+	}
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		return errtypes.BadRequest("indexed: " + md.Path + " is not a folder")
+	}
+
+	s := fs.crawlState(ns)
+	s.Lock()
+	defer s.Unlock()
+
+	start := time.Now()
+	if err := fs.index.Remove(ctx, ns, md.Path); err != nil {
+		return err
+	}
+	if md.Path != "/" {
+		if err := fs.index.Add(ctx, ns, md.Path); err != nil {
+			return err
+		}
+	}
+	if err := fs.crawl(ctx, ns, md.Path); err != nil {
+		return err
+	}
+	s.crawled[md.Path] = start
+	return nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package indexed
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/search"
+	"github.com/cs3org/reva/pkg/search/memory"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/localfs"
+)
+
+var (
+	einstein = &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein", Idp: "cernbox.cern.ch"}, Username: "einstein"}
+	marie    = &userpb.User{Id: &userpb.UserId{OpaqueId: "marie", Idp: "cernbox.cern.ch"}, Username: "marie"}
+)
+
+func ref(p string) *provider.Reference {
+	return &provider.Reference{Path: p}
+}
+
+func setup(t *testing.T) (inner storage.FS, indexed storage.FS) {
+	ctx := context.Background()
+	inner, err := localfs.NewLocalFS(&localfs.Config{Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []*userpb.User{einstein, marie} {
+		if err := inner.CreateHome(appctx.ContextSetUser(ctx, u)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index, err := memory.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the folders are crawled once, the index must follow the changes
+	return inner, Wrap(inner, index, -1)
+}
+
+func searchPaths(t *testing.T, ctx context.Context, fs storage.FS, root, pattern string) []string {
+	t.Helper()
+	infos, err := fs.(search.Searcher).Search(ctx, ref(root), search.Query{Pattern: pattern})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{}
+	for _, info := range infos {
+		paths = append(paths, info.Path)
+	}
+	return paths
+}
+
+func expectPaths(t *testing.T, got []string, expected ...string) {
+	t.Helper()
+	if expected == nil {
+		expected = []string{}
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got %v, expected %v", got, expected)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	inner, fs := setup(t)
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	marieCtx := appctx.ContextSetUser(context.Background(), marie)
+
+	// resources created before the search are found by crawling
+	for _, p := range []string{"/docs", "/docs/old"} {
+		if err := inner.CreateDir(ctx, ref(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := inner.TouchFile(ctx, ref("/docs/old/thesis.txt")); err != nil {
+		t.Fatal(err)
+	}
+	expectPaths(t, searchPaths(t, ctx, fs, "/", "THESIS"), "/docs/old/thesis.txt")
+
+	// the other users see their own resources at the same paths
+	if err := fs.CreateDir(marieCtx, ref("/docs")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.TouchFile(marieCtx, ref("/docs/thesis-radium.txt")); err != nil {
+		t.Fatal(err)
+	}
+	expectPaths(t, searchPaths(t, marieCtx, fs, "/docs", "thesis"), "/docs/thesis-radium.txt")
+	expectPaths(t, searchPaths(t, ctx, fs, "/docs", "thesis"), "/docs/old/thesis.txt")
+
+	// the upload is finalized by another instance of the driver
+	ids, err := fs.InitiateUpload(ctx, ref("/docs/thesis-v2.txt"), 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inner.Upload(ctx, ref("/"+ids["simple"]), io.NopCloser(strings.NewReader("relativity")), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.CreateDir(ctx, ref("/docs/thesis-figures")); err != nil {
+		t.Fatal(err)
+	}
+	expectPaths(t, searchPaths(t, ctx, fs, "/", "thesis"), "/docs/old/thesis.txt", "/docs/thesis-figures", "/docs/thesis-v2.txt")
+
+	if err := fs.Move(ctx, ref("/docs/old"), ref("/archive")); err != nil {
+		t.Fatal(err)
+	}
+	expectPaths(t, searchPaths(t, ctx, fs, "/", "thesis"), "/archive/thesis.txt", "/docs/thesis-figures", "/docs/thesis-v2.txt")
+	expectPaths(t, searchPaths(t, ctx, fs, "/", "archive"), "/archive")
+
+	if err := fs.Delete(ctx, ref("/archive")); err != nil {
+		t.Fatal(err)
+	}
+	expectPaths(t, searchPaths(t, ctx, fs, "/", "thesis"), "/docs/thesis-figures", "/docs/thesis-v2.txt")
+
+	items, err := fs.ListRecycle(ctx, "/", "", "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected one item in the recycle bin, got %d", len(items))
+	}
+	if err := fs.RestoreRecycleItem(ctx, "/", items[0].Key, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	expectPaths(t, searchPaths(t, ctx, fs, "/", "thesis"), "/archive/thesis.txt", "/docs/thesis-figures", "/docs/thesis-v2.txt")
+}
+
+func TestSearchFile(t *testing.T) {
+	_, fs := setup(t)
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	if err := fs.TouchFile(ctx, ref("/thesis.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := fs.(search.Searcher).Search(ctx, ref("/thesis.txt"), search.Query{Pattern: "thesis"})
+	if _, ok := err.(errtypes.BadRequest); !ok {
+		t.Fatalf("expected bad request searching a file, got %v", err)
+	}
+	if _, err := fs.(search.Searcher).Search(context.Background(), ref("/"), search.Query{Pattern: "thesis"}); err == nil {
+		t.Fatal("searching without a user should fail")
+	}
+}
+
+func TestReindex(t *testing.T) {
+	inner, fs := setup(t)
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	if err := fs.CreateDir(ctx, ref("/docs")); err != nil {
+		t.Fatal(err)
+	}
+	expectPaths(t, searchPaths(t, ctx, fs, "/", "thesis"))
+
+	// the folders are not crawled again, the resources created
+	// out of band are only found once reindexed
+	if err := inner.TouchFile(ctx, ref("/docs/thesis.txt")); err != nil {
+		t.Fatal(err)
+	}
+	expectPaths(t, searchPaths(t, ctx, fs, "/", "thesis"))
+	if err := fs.(search.Searcher).Reindex(ctx, ref("/docs")); err != nil {
+		t.Fatal(err)
+	}
+	expectPaths(t, searchPaths(t, ctx, fs, "/", "thesis"), "/docs/thesis.txt")
+	expectPaths(t, searchPaths(t, ctx, fs, "/", "docs"), "/docs")
+
+	if err := fs.(search.Searcher).Reindex(ctx, ref("/docs/thesis.txt")); err == nil {
+		t.Fatal("reindexing a file should fail")
+	}
+}
+
+// blockingFS blocks the listings of the user until release is closed.
+type blockingFS struct {
+	storage.FS
+	user    *userpb.User
+	listing chan struct{}
+	release chan struct{}
+}
+
+func (fs *blockingFS) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	if u, ok := appctx.ContextGetUser(ctx); ok && u.Id.OpaqueId == fs.user.Id.OpaqueId {
+		select {
+		case fs.listing <- struct{}{}:
+		default:
+		}
+		<-fs.release
+	}
+	return fs.FS.ListFolder(ctx, ref, mdKeys)
+}
+
+func TestConcurrentCrawls(t *testing.T) {
+	inner, _ := setup(t)
+	blocking := &blockingFS{FS: inner, user: einstein, listing: make(chan struct{}, 1), release: make(chan struct{})}
+	index, err := memory.New(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := Wrap(blocking, index, -1)
+
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	done := make(chan error)
+	go func() {
+		_, err := fs.(search.Searcher).Search(ctx, ref("/"), search.Query{Pattern: "thesis"})
+		done <- err
+	}()
+	<-blocking.listing
+
+	// the crawl of einstein must not hold back the searches of marie
+	mctx := appctx.ContextSetUser(context.Background(), marie)
+	if err := fs.TouchFile(mctx, ref("/thesis.txt")); err != nil {
+		t.Fatal(err)
+	}
+	expectPaths(t, searchPaths(t, mctx, fs, "/", "thesis"), "/thesis.txt")
+
+	close(blocking.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -23,12 +23,15 @@ import (
 	_ "github.com/cs3org/reva/pkg/ocm/storage/outcoming"
 	_ "github.com/cs3org/reva/pkg/ocm/storage/received"
 	_ "github.com/cs3org/reva/pkg/storage/fs/cephfs"
+	_ "github.com/cs3org/reva/pkg/storage/fs/dedup"
 	_ "github.com/cs3org/reva/pkg/storage/fs/eos"
 	_ "github.com/cs3org/reva/pkg/storage/fs/eosgrpc"
 	_ "github.com/cs3org/reva/pkg/storage/fs/eosgrpchome"
 	_ "github.com/cs3org/reva/pkg/storage/fs/eoshome"
+	_ "github.com/cs3org/reva/pkg/storage/fs/indexed"
 	_ "github.com/cs3org/reva/pkg/storage/fs/local"
 	_ "github.com/cs3org/reva/pkg/storage/fs/localhome"
 	_ "github.com/cs3org/reva/pkg/storage/fs/nextcloud"
+	_ "github.com/cs3org/reva/pkg/storage/fs/s3"
 	// Add your own here.
 )
@@ -32,8 +32,9 @@ func init() {
 }
 
 type config struct {
-	Root        string `docs:"/var/tmp/reva/;Path of root directory for user storage." mapstructure:"root"`
-	ShareFolder string `docs:"/MyShares;Path for storing share references."            mapstructure:"share_folder"`
+	Root           string `docs:"/var/tmp/reva/;Path of root directory for user storage." mapstructure:"root"`
+	ShareFolder    string `docs:"/MyShares;Path for storing share references."            mapstructure:"share_folder"`
+	ProjectsFolder string `docs:"/projects;Path where the project spaces are created."    mapstructure:"projects_folder"`
 }
 
 func (c *config) ApplyDefaults() {
@@ -54,9 +55,10 @@ func New(ctx context.Context, m map[string]interface{}) (storage.FS, error) {
 	}
 
 	conf := localfs.Config{
-		Root:        c.Root,
-		ShareFolder: c.ShareFolder,
-		DisableHome: true,
+		Root:           c.Root,
+		ShareFolder:    c.ShareFolder,
+		ProjectsFolder: c.ProjectsFolder,
+		DisableHome:    true,
 	}
 	return localfs.NewLocalFS(&conf)
 }
@@ -33,9 +33,10 @@ func init() {
 }
 
 type config struct {
-	Root        string `docs:"/var/tmp/reva/;Path of root directory for user storage." mapstructure:"root"`
-	ShareFolder string `docs:"/MyShares;Path for storing share references."            mapstructure:"share_folder"`
-	UserLayout  string `docs:"{{.Username}};Template for user home directories"        mapstructure:"user_layout"`
+	Root           string `docs:"/var/tmp/reva/;Path of root directory for user storage." mapstructure:"root"`
+	ShareFolder    string `docs:"/MyShares;Path for storing share references."            mapstructure:"share_folder"`
+	ProjectsFolder string `docs:"/projects;Path where the project spaces are created."    mapstructure:"projects_folder"`
+	UserLayout     string `docs:"{{.Username}};Template for user home directories"        mapstructure:"user_layout"`
 }
 
 func (c *config) ApplyDefaults() {
@@ -68,9 +69,10 @@ func New(ctx context.Context, m map[string]interface{}) (storage.FS, error) {
 	}
 
 	conf := localfs.Config{
-		Root:        c.Root,
-		ShareFolder: c.ShareFolder,
-		UserLayout:  c.UserLayout,
+		Root:           c.Root,
+		ShareFolder:    c.ShareFolder,
+		ProjectsFolder: c.ProjectsFolder,
+		UserLayout:     c.UserLayout,
 	}
 	return localfs.NewLocalFS(&conf)
 }
@@ -234,6 +234,11 @@ func (nc *StorageDriver) TouchFile(ctx context.Context, ref *provider.Reference)
 	return fmt.Errorf("unimplemented: TouchFile")
 }
 
+// CreateSymlink as defined in the storage.FS interface.
+func (nc *StorageDriver) CreateSymlink(ctx context.Context, ref *provider.Reference, target string) error {
+	return errtypes.NotSupported("unimplemented")
+}
+
 // Delete as defined in the storage.FS interface.
 func (nc *StorageDriver) Delete(ctx context.Context, ref *provider.Reference) error {
 	return nc.do(ctx, http.MethodPost, "Delete", ref, nil)
@@ -586,3 +591,8 @@ func (nc *StorageDriver) CreateStorageSpace(ctx context.Context, req *provider.C
 func (nc *StorageDriver) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
 	return nil, errtypes.NotSupported("unimplemented")
 }
+
+// DeleteStorageSpace deletes a storage space.
+func (nc *StorageDriver) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) error {
+	return errtypes.NotSupported("unimplemented")
+}
@@ -0,0 +1,114 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"context"
+	"io"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage/utils/indexfs"
+	"github.com/pkg/errors"
+)
+
+// blobstore stores the content of the files as objects in a bucket.
+type blobstore struct {
+	client   *awss3.S3
+	uploader *s3manager.Uploader
+	bucket   string
+	prefix   string
+}
+
+var _ indexfs.Blobstore = (*blobstore)(nil)
+
+func newBlobstore(c *Config) (*blobstore, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(c.Endpoint),
+		Region:           aws.String(c.Region),
+		Credentials:      credentials.NewStaticCredentials(c.AccessKey, c.SecretKey, ""),
+		S3ForcePathStyle: aws.Bool(true),
+		DisableSSL:       aws.Bool(c.DisableSSL),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "s3: error creating session")
+	}
+
+	client := awss3.New(sess)
+	return &blobstore{
+		client: client,
+		uploader: s3manager.NewUploaderWithClient(client, func(u *s3manager.Uploader) {
+			u.PartSize = c.PartSize
+			u.Concurrency = c.UploadConcurrency
+		}),
+		bucket: c.Bucket,
+		prefix: c.Prefix,
+	}, nil
+}
+
+func (b *blobstore) key(blob string) string {
+	return path.Join(b.prefix, blob)
+}
+
+// Upload stores the content read from r. Contents bigger than
+// the configured part size are uploaded through multipart.
+func (b *blobstore) Upload(ctx context.Context, blob string, r io.Reader) error {
+	_, err := b.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.key(blob)),
+		Body:   r,
+	})
+	if err != nil {
+		return errors.Wrap(err, "s3: error uploading blob "+blob)
+	}
+	return nil
+}
+
+// Download returns the content of the given blob.
+func (b *blobstore) Download(ctx context.Context, blob string) (io.ReadCloser, error) {
+	res, err := b.client.GetObjectWithContext(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.key(blob)),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == awss3.ErrCodeNoSuchKey {
+			return nil, errtypes.NotFound("s3: blob " + blob)
+		}
+		return nil, errors.Wrap(err, "s3: error downloading blob "+blob)
+	}
+	return res.Body, nil
+}
+
+// Delete removes the given blob.
+func (b *blobstore) Delete(ctx context.Context, blob string) error {
+	_, err := b.client.DeleteObjectWithContext(ctx, &awss3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.key(blob)),
+	})
+	if err != nil {
+		return errors.Wrap(err, "s3: error deleting blob "+blob)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package s3 implements a storage driver keeping the content of the files
+// in an S3 compatible object storage. The namespace tree and the metadata
+// of the resources (ids, etags, arbitrary metadata, grants, versions and
+// recycle bin) are kept in an embedded index.
+package s3
+
+import (
+	"context"
+
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/registry"
+	"github.com/cs3org/reva/pkg/storage/utils/indexfs"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+)
+
+func init() {
+	registry.Register("s3", New)
+}
+
+// Config holds the configuration of the s3 driver.
+type Config struct {
+	Endpoint          string `docs:";Endpoint of the S3 service, e.g. http://localhost:9000." mapstructure:"endpoint"           validate:"required"`
+	Region            string `docs:"us-east-1;Region of the bucket."                                mapstructure:"region"`
+	Bucket            string `docs:";Bucket where the content of the files is stored."             mapstructure:"bucket"             validate:"required"`
+	Prefix            string `docs:";Prefix of the keys of the objects in the bucket."              mapstructure:"prefix"`
+	AccessKey         string `docs:";Access key of the S3 account."                                 mapstructure:"access_key"`
+	SecretKey         string `docs:";Secret key of the S3 account."                                 mapstructure:"secret_key"`
+	DisableSSL        bool   `docs:"false;Whether to use plain http to talk to the S3 service."    mapstructure:"disable_ssl"`
+	PartSize          int64  `docs:"5242880;Size of the parts of multipart uploads, in bytes."     mapstructure:"part_size"`
+	UploadConcurrency int    `docs:"5;Number of parts of an upload sent in parallel."              mapstructure:"upload_concurrency"`
+	Root              string `docs:"/var/tmp/reva/s3;Directory holding the metadata index."        mapstructure:"root"`
+	EnableHome        bool   `docs:"false;Whether to root the namespace of the users in their home." mapstructure:"enable_home"`
+	UserLayout        string `docs:"{{.Username}};Template for the home of the users."             mapstructure:"user_layout"`
+	Quota             uint64 `docs:"0;Quota of the users, in bytes, accounting for the files and their versions. 0 means unlimited." mapstructure:"quota"`
+	ContentAddressed  bool   `docs:"false;Whether to store the objects by checksum, sharing them between files with the same content." mapstructure:"content_addressed"`
+}
+
+// ApplyDefaults applies the default configuration.
+func (c *Config) ApplyDefaults() {
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	if c.PartSize == 0 {
+		c.PartSize = 5 * 1024 * 1024
+	}
+	if c.UploadConcurrency == 0 {
+		c.UploadConcurrency = 5
+	}
+	if c.Root == "" {
+		c.Root = "/var/tmp/reva/s3"
+	}
+}
+
+// New returns an implementation of the storage.FS interface
+// storing the files in an S3 compatible object storage.
+func New(ctx context.Context, m map[string]interface{}) (storage.FS, error) {
+	var c Config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+
+	blobs, err := newBlobstore(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	return indexfs.New(ctx, &indexfs.Config{
+		Root:             c.Root,
+		EnableHome:       c.EnableHome,
+		UserLayout:       c.UserLayout,
+		Quota:            c.Quota,
+		ContentAddressed: c.ContentAddressed,
+	}, blobs)
+}
@@ -0,0 +1,498 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/fs/s3/fakes3"
+)
+
+var (
+	einstein = &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein", Idp: "cernbox.cern.ch"}, Username: "einstein"}
+	marie    = &userpb.User{Id: &userpb.UserId{OpaqueId: "marie", Idp: "cernbox.cern.ch"}, Username: "marie"}
+)
+
+func setup(t *testing.T) (storage.FS, *fakes3.Server, context.Context) {
+	server := fakes3.New("reva")
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	fs, err := New(ctx, map[string]interface{}{
+		"endpoint":    ts.URL,
+		"bucket":      "reva",
+		"prefix":      "blobs",
+		"access_key":  "reva",
+		"secret_key":  "secret",
+		"disable_ssl": true,
+		"root":        t.TempDir(),
+		"enable_home": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = fs.Shutdown(ctx) })
+
+	if err := fs.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return fs, server, ctx
+}
+
+func uploadFile(t *testing.T, ctx context.Context, fs storage.FS, fn string, content []byte) {
+	ref := &provider.Reference{Path: fn}
+	ids, err := fs.InitiateUpload(ctx, ref, int64(len(content)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Upload(ctx, &provider.Reference{Path: "/" + ids["simple"]}, io.NopCloser(bytes.NewReader(content)), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func downloadFile(t *testing.T, ctx context.Context, fs storage.FS, fn string) []byte {
+	r, err := fs.Download(ctx, &provider.Reference{Path: fn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestUploadDownload(t *testing.T) {
+	fs, server, ctx := setup(t)
+
+	if err := fs.CreateDir(ctx, &provider.Reference{Path: "/docs"}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := fs.GetMD(ctx, &provider.Reference{Path: "/docs"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// bigger than the part size, uploaded through multipart
+	big := bytes.Repeat([]byte("relativity"), 700*1024)
+	uploadFile(t, ctx, fs, "/docs/big.txt", big)
+	uploadFile(t, ctx, fs, "/docs/small.txt", []byte("E=mc²"))
+
+	if got := downloadFile(t, ctx, fs, "/docs/big.txt"); !bytes.Equal(got, big) {
+		t.Fatalf("downloaded content differs from the uploaded one")
+	}
+	if got := string(downloadFile(t, ctx, fs, "/docs/small.txt")); got != "E=mc²" {
+		t.Fatalf("got %q", got)
+	}
+	if n := server.PendingUploads(); n != 0 {
+		t.Fatalf("%d multipart uploads were not completed", n)
+	}
+	if n := len(server.Objects("reva")); n != 2 {
+		t.Fatalf("expected 2 blobs, got %d", n)
+	}
+
+	after, err := fs.GetMD(ctx, &provider.Reference{Path: "/docs"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Etag == before.Etag || after.Size != uint64(len(big)+len("E=mc²")) {
+		t.Fatalf("changes were not propagated: %+v", after)
+	}
+
+	list, err := fs.ListFolder(ctx, &provider.Reference{Path: "/docs"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Path != "/docs/big.txt" || list[1].Path != "/docs/small.txt" {
+		t.Fatalf("unexpected listing %v", list)
+	}
+
+	p, err := fs.GetPathByID(ctx, list[1].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != "/docs/small.txt" {
+		t.Fatalf("got path %s", p)
+	}
+}
+
+func TestMove(t *testing.T) {
+	fs, _, ctx := setup(t)
+
+	for _, d := range []string{"/a", "/a/b", "/c"} {
+		if err := fs.CreateDir(ctx, &provider.Reference{Path: d}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uploadFile(t, ctx, fs, "/a/b/file", []byte("data"))
+	info, err := fs.GetMD(ctx, &provider.Reference{Path: "/a/b/file"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fs.Move(ctx, &provider.Reference{Path: "/a"}, &provider.Reference{Path: "/a/b/a"})
+	if _, ok := err.(errtypes.IsBadRequest); !ok {
+		t.Fatalf("expected a folder not to be moved into itself, got %v", err)
+	}
+	err = fs.Move(ctx, &provider.Reference{Path: "/a"}, &provider.Reference{Path: "/c"})
+	if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+
+	if err := fs.Move(ctx, &provider.Reference{Path: "/a"}, &provider.Reference{Path: "/c/d"}); err != nil {
+		t.Fatal(err)
+	}
+	moved, err := fs.GetMD(ctx, &provider.Reference{ResourceId: info.Id}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Path != "/c/d/b/file" {
+		t.Fatalf("got path %s", moved.Path)
+	}
+	if got := string(downloadFile(t, ctx, fs, "/c/d/b/file")); got != "data" {
+		t.Fatalf("got %q", got)
+	}
+	if _, err := fs.GetMD(ctx, &provider.Reference{Path: "/a"}, nil); err == nil {
+		t.Fatal("source should not exist anymore")
+	}
+	c, err := fs.GetMD(ctx, &provider.Reference{Path: "/c"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Size != 4 {
+		t.Fatalf("expected size of the destination to be updated, got %d", c.Size)
+	}
+}
+
+func TestRevisions(t *testing.T) {
+	fs, server, ctx := setup(t)
+	ref := &provider.Reference{Path: "/file"}
+
+	uploadFile(t, ctx, fs, "/file", []byte("v1"))
+	uploadFile(t, ctx, fs, "/file", []byte("version 2"))
+
+	revisions, err := fs.ListRevisions(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].Size != 2 {
+		t.Fatalf("unexpected revisions %v", revisions)
+	}
+
+	r, err := fs.DownloadRevision(ctx, ref, revisions[0].Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "v1" {
+		t.Fatalf("got %q", data)
+	}
+
+	if err := fs.RestoreRevision(ctx, ref, revisions[0].Key); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(downloadFile(t, ctx, fs, "/file")); got != "v1" {
+		t.Fatalf("got %q", got)
+	}
+	revisions, err = fs.ListRevisions(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].Size != 9 {
+		t.Fatalf("expected the replaced content to be kept as a version, got %v", revisions)
+	}
+
+	// versions are removed together with the file
+	if err := fs.Delete(ctx, ref); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.EmptyRecycle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if objects := server.Objects("reva"); len(objects) != 0 {
+		t.Fatalf("expected all blobs to be purged, got %v", objects)
+	}
+}
+
+func TestRecycle(t *testing.T) {
+	fs, server, ctx := setup(t)
+
+	if err := fs.CreateDir(ctx, &provider.Reference{Path: "/dir"}); err != nil {
+		t.Fatal(err)
+	}
+	uploadFile(t, ctx, fs, "/dir/file", []byte("data"))
+	uploadFile(t, ctx, fs, "/other", []byte("data"))
+
+	for _, fn := range []string{"/dir", "/other"} {
+		if err := fs.Delete(ctx, &provider.Reference{Path: fn}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, err := fs.ListRecycle(ctx, "/", "", "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %v", items)
+	}
+
+	// recycle bins are per user
+	other := appctx.ContextSetUser(context.Background(), marie)
+	if list, err := fs.ListRecycle(other, "/", "", "", nil, nil); err != nil || len(list) != 0 {
+		t.Fatalf("recycle bin of other users should be empty, got %v %v", list, err)
+	}
+
+	var dir, file *provider.RecycleItem
+	for _, item := range items {
+		if item.Ref.Path == "/dir" {
+			dir = item
+		} else {
+			file = item
+		}
+	}
+	children, err := fs.ListRecycle(ctx, "/", dir.Key, "/", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(children) != 1 || children[0].Ref.Path != "/dir/file" {
+		t.Fatalf("unexpected content of the deleted folder %v", children)
+	}
+
+	// a new resource can take the place of a deleted one
+	if err := fs.CreateDir(ctx, &provider.Reference{Path: "/dir"}); err != nil {
+		t.Fatal(err)
+	}
+	err = fs.RestoreRecycleItem(ctx, "/", dir.Key, "", nil)
+	if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+	if err := fs.RestoreRecycleItem(ctx, "/", dir.Key, "", &provider.Reference{Path: "/restored"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(downloadFile(t, ctx, fs, "/restored/file")); got != "data" {
+		t.Fatalf("got %q", got)
+	}
+
+	if err := fs.PurgeRecycleItem(ctx, "/", file.Key, ""); err != nil {
+		t.Fatal(err)
+	}
+	if items, err := fs.ListRecycle(ctx, "/", "", "", nil, nil); err != nil || len(items) != 0 {
+		t.Fatalf("expected the recycle bin to be empty, got %v %v", items, err)
+	}
+	if objects := server.Objects("reva"); len(objects) != 1 {
+		t.Fatalf("expected the blob of the purged file to be removed, got %v", objects)
+	}
+}
+
+func TestMetadataAndGrants(t *testing.T) {
+	fs, _, ctx := setup(t)
+	ref := &provider.Reference{Path: "/shared"}
+	if err := fs.CreateDir(ctx, ref); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.TouchFile(ctx, &provider.Reference{Path: "/shared/empty"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: map[string]string{
+		"color": "blue",
+		"mtime": "1000000000.5",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := fs.GetMD(ctx, ref, []string{"color"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ArbitraryMetadata.Metadata["color"] != "blue" || info.Mtime.Seconds != 1000000000 || info.Mtime.Nanos != 5 {
+		t.Fatalf("metadata was not set: %+v", info)
+	}
+	if err := fs.UnsetArbitraryMetadata(ctx, ref, []string{"color"}); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ = fs.GetMD(ctx, ref, nil); len(info.ArbitraryMetadata.Metadata) != 0 {
+		t.Fatalf("metadata was not unset: %v", info.ArbitraryMetadata.Metadata)
+	}
+
+	grant := &provider.Grant{
+		Grantee: &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id:   &provider.Grantee_UserId{UserId: marie.Id},
+		},
+		Permissions: &provider.ResourcePermissions{Stat: true, ListContainer: true, InitiateFileDownload: true},
+	}
+	if err := fs.AddGrant(ctx, ref, grant); err != nil {
+		t.Fatal(err)
+	}
+	grants, err := fs.ListGrants(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grants) != 1 || !grants[0].Permissions.Stat {
+		t.Fatalf("unexpected grants %v", grants)
+	}
+
+	// the grant applies to the content of the folder
+	other := appctx.ContextSetUser(context.Background(), marie)
+	child, err := fs.GetMD(other, &provider.Reference{ResourceId: info.Id, Path: "empty"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := child.PermissionSet; !p.Stat || !p.InitiateFileDownload || p.Delete {
+		t.Fatalf("unexpected permissions %+v", p)
+	}
+
+	if err := fs.RemoveGrant(ctx, ref, grant); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.GetMD(other, &provider.Reference{ResourceId: info.Id, Path: "empty"}, nil); !isPermissionDenied(err) {
+		t.Fatalf("grant was not removed: %v", err)
+	}
+}
+
+func isPermissionDenied(err error) bool {
+	_, ok := err.(errtypes.IsPermissionDenied)
+	return ok
+}
+
+func TestPermissions(t *testing.T) {
+	fs, _, ctx := setup(t)
+	other := appctx.ContextSetUser(context.Background(), marie)
+	if err := fs.CreateHome(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.CreateDir(ctx, &provider.Reference{Path: "/private"}); err != nil {
+		t.Fatal(err)
+	}
+	uploadFile(t, ctx, fs, "/private/file", []byte("data"))
+	private, err := fs.GetMD(ctx, &provider.Reference{Path: "/private"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := &provider.Reference{ResourceId: private.Id, Path: "file"}
+
+	denied := map[string]func() error{
+		"stat": func() error {
+			_, err := fs.GetMD(other, file, nil)
+			return err
+		},
+		"list": func() error {
+			_, err := fs.ListFolder(other, &provider.Reference{ResourceId: private.Id}, nil)
+			return err
+		},
+		"get path": func() error {
+			_, err := fs.GetPathByID(other, private.Id)
+			return err
+		},
+		"download": func() error {
+			_, err := fs.Download(other, file)
+			return err
+		},
+		"upload": func() error {
+			_, err := fs.InitiateUpload(other, file, 4, nil)
+			return err
+		},
+		"create dir": func() error {
+			return fs.CreateDir(other, &provider.Reference{ResourceId: private.Id, Path: "dir"})
+		},
+		"touch": func() error {
+			return fs.TouchFile(other, &provider.Reference{ResourceId: private.Id, Path: "new"})
+		},
+		"move away": func() error {
+			return fs.Move(other, file, &provider.Reference{Path: "/stolen"})
+		},
+		"delete": func() error {
+			return fs.Delete(other, file)
+		},
+		"set metadata": func() error {
+			return fs.SetArbitraryMetadata(other, file, &provider.ArbitraryMetadata{Metadata: map[string]string{"color": "blue"}})
+		},
+		"list grants": func() error {
+			_, err := fs.ListGrants(other, file)
+			return err
+		},
+		"add grant": func() error {
+			return fs.AddGrant(other, file, &provider.Grant{
+				Grantee: &provider.Grantee{
+					Type: provider.GranteeType_GRANTEE_TYPE_USER,
+					Id:   &provider.Grantee_UserId{UserId: marie.Id},
+				},
+				Permissions: &provider.ResourcePermissions{Stat: true},
+			})
+		},
+		"list revisions": func() error {
+			_, err := fs.ListRevisions(other, file)
+			return err
+		},
+		"quota": func() error {
+			_, _, err := fs.GetQuota(other, file)
+			return err
+		},
+	}
+	for op, f := range denied {
+		if err := f(); !isPermissionDenied(err) {
+			t.Fatalf("%s: expected permission denied, got %v", op, err)
+		}
+	}
+
+	// moving own resources into a folder of another user needs permissions on it
+	uploadFile(t, other, fs, "/mine", []byte("data"))
+	if err := fs.Move(other, &provider.Reference{Path: "/mine"}, &provider.Reference{ResourceId: private.Id, Path: "mine"}); !isPermissionDenied(err) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	// with a viewer grant the folder can be read but not written
+	viewer := &provider.Grant{
+		Grantee: &provider.Grantee{
+			Type: provider.GranteeType_GRANTEE_TYPE_USER,
+			Id:   &provider.Grantee_UserId{UserId: marie.Id},
+		},
+		Permissions: &provider.ResourcePermissions{Stat: true, ListContainer: true, InitiateFileDownload: true},
+	}
+	if err := fs.AddGrant(ctx, &provider.Reference{Path: "/private"}, viewer); err != nil {
+		t.Fatal(err)
+	}
+	r, err := fs.Download(other, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if list, err := fs.ListFolder(other, &provider.Reference{ResourceId: private.Id}, nil); err != nil || len(list) != 1 {
+		t.Fatalf("expected to list the shared folder, got %v %v", list, err)
+	}
+	if err := fs.Delete(other, file); !isPermissionDenied(err) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	if _, err := fs.InitiateUpload(other, file, 4, nil); !isPermissionDenied(err) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+}
@@ -0,0 +1,635 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package fstest
+
+import (
+	"bytes"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/spaces"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+func upload(t *testing.T, d *Driver, fn string, content []byte, metadata map[string]string) {
+	t.Helper()
+	ids, err := d.FS.InitiateUpload(d.Owner, d.ref(fn), int64(len(content)), metadata)
+	if err != nil {
+		t.Fatalf("error initiating the upload of %s: %v", fn, err)
+	}
+	id, ok := ids["simple"]
+	if !ok {
+		t.Fatalf("the driver does not offer the simple upload protocol for %s: %v", fn, ids)
+	}
+	ref := &provider.Reference{Path: path.Join("/", id)}
+	if err := d.FS.Upload(d.Owner, ref, io.NopCloser(bytes.NewReader(content)), nil); err != nil {
+		t.Fatalf("error uploading %s: %v", fn, err)
+	}
+}
+
+func download(t *testing.T, d *Driver, fn string) []byte {
+	t.Helper()
+	r, err := d.FS.Download(d.Owner, d.ref(fn))
+	if err != nil {
+		t.Fatalf("error downloading %s: %v", fn, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", fn, err)
+	}
+	return data
+}
+
+func getMD(t *testing.T, d *Driver, fn string, mdKeys []string) *provider.ResourceInfo {
+	t.Helper()
+	md, err := d.FS.GetMD(d.Owner, d.ref(fn), mdKeys)
+	if err != nil {
+		t.Fatalf("error getting the metadata of %s: %v", fn, err)
+	}
+	return md
+}
+
+func mustNotExist(t *testing.T, d *Driver, fn string) {
+	t.Helper()
+	_, err := d.FS.GetMD(d.Owner, d.ref(fn), nil)
+	if !isNotFound(err) {
+		t.Fatalf("expected %s not to be found, got %v", fn, err)
+	}
+}
+
+func list(t *testing.T, d *Driver, fn string) []string {
+	t.Helper()
+	infos, err := d.FS.ListFolder(d.Owner, d.ref(fn), nil)
+	if err != nil {
+		t.Fatalf("error listing %s: %v", fn, err)
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, path.Base(info.Path))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func testCreateDir(t *testing.T, s *suite, d *Driver) {
+	if err := d.FS.CreateDir(d.Owner, d.ref("folder")); err != nil {
+		t.Fatal(err)
+	}
+	md := getMD(t, d, "folder", nil)
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		t.Fatalf("expected a container, got %s", md.Type)
+	}
+	if md.Path != d.path("folder") {
+		t.Fatalf("expected path %s, got %s", d.path("folder"), md.Path)
+	}
+	if md.Id == nil {
+		t.Fatal("the resource has no id")
+	}
+
+	p, err := d.FS.GetPathByID(d.Owner, md.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != md.Path {
+		t.Fatalf("the id resolves to %s, expected %s", p, md.Path)
+	}
+
+	if err := d.FS.CreateDir(d.Owner, d.ref("folder", "sub")); err != nil {
+		t.Fatal(err)
+	}
+	if names := list(t, d, "folder"); len(names) != 1 || names[0] != "sub" {
+		t.Fatalf("expected folder to only contain sub, got %v", names)
+	}
+
+	mustNotExist(t, d, "missing")
+}
+
+func testTouchFile(t *testing.T, s *suite, d *Driver) {
+	if err := d.FS.TouchFile(d.Owner, d.ref("empty")); err != nil {
+		t.Fatal(err)
+	}
+	md := getMD(t, d, "empty", nil)
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_FILE || md.Size != 0 {
+		t.Fatalf("expected an empty file, got a %s of %d bytes", md.Type, md.Size)
+	}
+	if names := list(t, d, ""); !contains(names, "empty") {
+		t.Fatalf("the file is not listed in its folder: %v", names)
+	}
+}
+
+func testSymlink(t *testing.T, s *suite, d *Driver) {
+	if err := d.FS.CreateDir(d.Owner, d.ref("target")); err != nil {
+		t.Fatal(err)
+	}
+	err := d.FS.CreateSymlink(d.Owner, d.ref("link"), "target")
+	s.optional(t, "symlinks", err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := getMD(t, d, "link", nil)
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_SYMLINK || md.Target != "target" {
+		t.Fatalf("expected a symlink to target, got a %s to %q", md.Type, md.Target)
+	}
+}
+
+func testMove(t *testing.T, s *suite, d *Driver) {
+	if err := d.FS.CreateDir(d.Owner, d.ref("src")); err != nil {
+		t.Fatal(err)
+	}
+	upload(t, d, "src/file", []byte("moving around"), nil)
+
+	if err := d.FS.Move(d.Owner, d.ref("src", "file"), d.ref("src", "renamed")); err != nil {
+		t.Fatal(err)
+	}
+	mustNotExist(t, d, "src/file")
+	if data := download(t, d, "src/renamed"); string(data) != "moving around" {
+		t.Fatalf("the content changed when renaming the file: %q", data)
+	}
+
+	if err := d.FS.Move(d.Owner, d.ref("src"), d.ref("dst")); err != nil {
+		t.Fatal(err)
+	}
+	mustNotExist(t, d, "src")
+	if names := list(t, d, "dst"); len(names) != 1 || names[0] != "renamed" {
+		t.Fatalf("the children did not follow the folder: %v", names)
+	}
+	if md := getMD(t, d, "dst/renamed", nil); md.Path != d.path("dst", "renamed") {
+		t.Fatalf("expected path %s, got %s", d.path("dst", "renamed"), md.Path)
+	}
+}
+
+func testDelete(t *testing.T, s *suite, d *Driver) {
+	upload(t, d, "file", []byte("to be deleted"), nil)
+	if err := d.FS.Delete(d.Owner, d.ref("file")); err != nil {
+		t.Fatal(err)
+	}
+	mustNotExist(t, d, "file")
+
+	if err := d.FS.CreateDir(d.Owner, d.ref("folder")); err != nil {
+		t.Fatal(err)
+	}
+	upload(t, d, "folder/file", []byte("to be deleted with its folder"), nil)
+	if err := d.FS.Delete(d.Owner, d.ref("folder")); err != nil {
+		t.Fatal(err)
+	}
+	mustNotExist(t, d, "folder")
+	mustNotExist(t, d, "folder/file")
+
+	if err := d.FS.Delete(d.Owner, d.ref("missing")); err == nil {
+		t.Fatal("deleting a missing resource should fail")
+	}
+}
+
+func testUpload(t *testing.T, s *suite, d *Driver) {
+	mtime := time.Date(2023, time.November, 14, 22, 13, 20, 0, time.UTC)
+	upload(t, d, "file", []byte("first version"), map[string]string{"mtime": strconv.FormatInt(mtime.Unix(), 10)})
+
+	md := getMD(t, d, "file", nil)
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_FILE || md.Size != uint64(len("first version")) {
+		t.Fatalf("expected a file of %d bytes, got a %s of %d bytes", len("first version"), md.Type, md.Size)
+	}
+	if md.Etag == "" {
+		t.Fatal("the file has no etag")
+	}
+	if md.Mtime.GetSeconds() != uint64(mtime.Unix()) {
+		s.limitation(t, "client mtime", "the mtime given at upload time is not preserved")
+	}
+	if data := download(t, d, "file"); string(data) != "first version" {
+		t.Fatalf("unexpected content %q", data)
+	}
+	if names := list(t, d, ""); !contains(names, "file") {
+		t.Fatalf("the file is not listed in its folder: %v", names)
+	}
+
+	upload(t, d, "file", []byte("second"), nil)
+	updated := getMD(t, d, "file", nil)
+	if updated.Size != uint64(len("second")) {
+		t.Fatalf("expected %d bytes after the overwrite, got %d", len("second"), updated.Size)
+	}
+	if updated.Etag == md.Etag {
+		t.Fatal("the etag did not change when overwriting the file")
+	}
+	if data := download(t, d, "file"); string(data) != "second" {
+		t.Fatalf("unexpected content %q after the overwrite", data)
+	}
+}
+
+func testRevisions(t *testing.T, s *suite, d *Driver) {
+	upload(t, d, "file", []byte("v1"), nil)
+	upload(t, d, "file", []byte("v2"), nil)
+
+	revisions, err := d.FS.ListRevisions(d.Owner, d.ref("file"))
+	s.optional(t, "revisions", err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) == 0 {
+		t.Fatal("overwriting the file did not create a revision")
+	}
+
+	var key string
+	for _, rev := range revisions {
+		r, err := d.FS.DownloadRevision(d.Owner, d.ref("file"), rev.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) == "v1" {
+			key = rev.Key
+		}
+	}
+	if key == "" {
+		t.Fatal("no revision holds the content of the first upload")
+	}
+
+	if err := d.FS.RestoreRevision(d.Owner, d.ref("file"), key); err != nil {
+		t.Fatal(err)
+	}
+	if data := download(t, d, "file"); string(data) != "v1" {
+		t.Fatalf("expected the restored content, got %q", data)
+	}
+}
+
+// findRecycleItem returns the recycle bin entry of the resource
+// originally at fn, or nil if it is not in the recycle bin.
+func findRecycleItem(t *testing.T, s *suite, d *Driver, fn string) *provider.RecycleItem {
+	t.Helper()
+	items, err := d.FS.ListRecycle(d.Owner, "/", "", "", nil, nil)
+	s.optional(t, "recycle bin", err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if path.Clean(item.Ref.GetPath()) == d.path(fn) {
+			return item
+		}
+	}
+	return nil
+}
+
+func testRecycle(t *testing.T, s *suite, d *Driver) {
+	if err := d.FS.CreateDir(d.Owner, d.ref("folder")); err != nil {
+		t.Fatal(err)
+	}
+	upload(t, d, "folder/file", []byte("recycled"), nil)
+	if err := d.FS.Delete(d.Owner, d.ref("folder")); err != nil {
+		t.Fatal(err)
+	}
+
+	item := findRecycleItem(t, s, d, "folder")
+	if item == nil {
+		t.Fatal("the deleted folder is not in the recycle bin")
+	}
+	if item.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		t.Fatalf("expected the recycle item to be a container, got %s", item.Type)
+	}
+	if err := d.FS.RestoreRecycleItem(d.Owner, "/", item.Key, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if data := download(t, d, "folder/file"); string(data) != "recycled" {
+		t.Fatalf("unexpected content %q after the restore", data)
+	}
+	if findRecycleItem(t, s, d, "folder") != nil {
+		t.Fatal("the restored folder is still in the recycle bin")
+	}
+
+	t.Run("RestoreElsewhere", func(t *testing.T) {
+		if err := d.FS.Delete(d.Owner, d.ref("folder", "file")); err != nil {
+			t.Fatal(err)
+		}
+		item := findRecycleItem(t, s, d, "folder/file")
+		if item == nil {
+			t.Fatal("the deleted file is not in the recycle bin")
+		}
+		err := d.FS.RestoreRecycleItem(d.Owner, "/", item.Key, "", d.ref("restored"))
+		s.optional(t, "restoring recycle items to a different location", err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.FS.GetMD(d.Owner, d.ref("restored"), nil); err != nil {
+			s.limitation(t, "restoring recycle items to a different location", "the restore location is ignored")
+			return
+		}
+		mustNotExist(t, d, "folder/file")
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		upload(t, d, "purged", []byte("purged"), nil)
+		if err := d.FS.Delete(d.Owner, d.ref("purged")); err != nil {
+			t.Fatal(err)
+		}
+		item := findRecycleItem(t, s, d, "purged")
+		if item == nil {
+			t.Fatal("the deleted file is not in the recycle bin")
+		}
+		err := d.FS.PurgeRecycleItem(d.Owner, "/", item.Key, "")
+		s.optional(t, "purging recycle items", err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if findRecycleItem(t, s, d, "purged") != nil {
+			t.Fatal("the purged file is still in the recycle bin")
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		upload(t, d, "emptied", []byte("emptied"), nil)
+		if err := d.FS.Delete(d.Owner, d.ref("emptied")); err != nil {
+			t.Fatal(err)
+		}
+		err := d.FS.EmptyRecycle(d.Owner)
+		s.optional(t, "emptying the recycle bin", err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if findRecycleItem(t, s, d, "emptied") != nil {
+			t.Fatal("the recycle bin was not emptied")
+		}
+	})
+}
+
+// findGrant returns the grant of the grantee of the suite on fn, if any.
+func findGrant(t *testing.T, d *Driver, fn string) *provider.Grant {
+	t.Helper()
+	grants, err := d.FS.ListGrants(d.Owner, d.ref(fn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range grants {
+		if utils.UserEqual(g.Grantee.GetUserId(), d.Grantee.Id) {
+			return g
+		}
+	}
+	return nil
+}
+
+func testGrants(t *testing.T, s *suite, d *Driver) {
+	if err := d.FS.CreateDir(d.Owner, d.ref("shared")); err != nil {
+		t.Fatal(err)
+	}
+	grantee := &provider.Grantee{
+		Type: provider.GranteeType_GRANTEE_TYPE_USER,
+		Id:   &provider.Grantee_UserId{UserId: d.Grantee.Id},
+	}
+	viewer := &provider.ResourcePermissions{
+		Stat:                 true,
+		GetPath:              true,
+		ListContainer:        true,
+		InitiateFileDownload: true,
+	}
+	editor := &provider.ResourcePermissions{
+		Stat:                 true,
+		GetPath:              true,
+		ListContainer:        true,
+		InitiateFileDownload: true,
+		CreateContainer:      true,
+		InitiateFileUpload:   true,
+		Delete:               true,
+		Move:                 true,
+	}
+
+	err := d.FS.AddGrant(d.Owner, d.ref("shared"), &provider.Grant{Grantee: grantee, Permissions: viewer})
+	s.optional(t, "grants", err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := findGrant(t, d, "shared")
+	if g == nil {
+		t.Fatal("the grant is not listed")
+	}
+	if !g.Permissions.InitiateFileDownload || g.Permissions.InitiateFileUpload {
+		t.Fatalf("expected read-only permissions, got %+v", g.Permissions)
+	}
+
+	if err := d.FS.UpdateGrant(d.Owner, d.ref("shared"), &provider.Grant{Grantee: grantee, Permissions: editor}); err != nil {
+		t.Fatal(err)
+	}
+	g = findGrant(t, d, "shared")
+	if g == nil {
+		t.Fatal("the updated grant is not listed")
+	}
+	if !g.Permissions.InitiateFileDownload || !g.Permissions.InitiateFileUpload {
+		t.Fatalf("expected read-write permissions, got %+v", g.Permissions)
+	}
+
+	if err := d.FS.RemoveGrant(d.Owner, d.ref("shared"), &provider.Grant{Grantee: grantee, Permissions: editor}); err != nil {
+		t.Fatal(err)
+	}
+	if g := findGrant(t, d, "shared"); g != nil {
+		t.Fatalf("the removed grant is still listed: %+v", g)
+	}
+
+	t.Run("Deny", func(t *testing.T) {
+		err := d.FS.DenyGrant(d.Owner, d.ref("shared"), grantee)
+		s.optional(t, "deny grants", err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g := findGrant(t, d, "shared"); g != nil && (g.Permissions.Stat || g.Permissions.InitiateFileDownload) {
+			t.Fatalf("the denied grantee can still access the resource: %+v", g.Permissions)
+		}
+	})
+}
+
+func testArbitraryMetadata(t *testing.T, s *suite, d *Driver) {
+	upload(t, d, "file", []byte("annotated"), nil)
+
+	err := d.FS.SetArbitraryMetadata(d.Owner, d.ref("file"), &provider.ArbitraryMetadata{
+		Metadata: map[string]string{"project": "relativity", "reviewed": "yes"},
+	})
+	s.optional(t, "arbitrary metadata", err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := getMD(t, d, "file", []string{"project", "reviewed"})
+	if got := md.ArbitraryMetadata.GetMetadata(); got["project"] != "relativity" || got["reviewed"] != "yes" {
+		t.Fatalf("the metadata was not set: %v", got)
+	}
+
+	err = d.FS.UnsetArbitraryMetadata(d.Owner, d.ref("file"), []string{"reviewed"})
+	s.optional(t, "unsetting arbitrary metadata", err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md = getMD(t, d, "file", []string{"project", "reviewed"})
+	got := md.ArbitraryMetadata.GetMetadata()
+	if _, ok := got["reviewed"]; ok {
+		t.Fatalf("the metadata was not unset: %v", got)
+	}
+	if got["project"] != "relativity" {
+		t.Fatalf("unsetting a key removed the others: %v", got)
+	}
+}
+
+func testLocks(t *testing.T, s *suite, d *Driver) {
+	upload(t, d, "file", []byte("locked"), nil)
+	owner := appctx.ContextMustGetUser(d.Owner)
+	expiration := func(in time.Duration) *types.Timestamp {
+		return &types.Timestamp{Seconds: uint64(time.Now().Add(in).Unix())}
+	}
+	lock := &provider.Lock{
+		LockId:     "fstest-lock",
+		Type:       provider.LockType_LOCK_TYPE_WRITE,
+		User:       owner.Id,
+		AppName:    "fstest",
+		Expiration: expiration(time.Hour),
+	}
+
+	err := d.FS.SetLock(d.Owner, d.ref("file"), lock)
+	s.optional(t, "locks", err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := d.FS.GetLock(d.Owner, d.ref("file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.LockId != lock.LockId || l.Type != lock.Type {
+		t.Fatalf("got lock %+v, expected %+v", l, lock)
+	}
+
+	other := &provider.Lock{
+		LockId:     "fstest-other-lock",
+		Type:       provider.LockType_LOCK_TYPE_WRITE,
+		User:       owner.Id,
+		AppName:    "fstest",
+		Expiration: expiration(time.Hour),
+	}
+	if err := d.FS.SetLock(d.Owner, d.ref("file"), other); err == nil {
+		t.Fatal("a locked resource should not be locked again")
+	}
+
+	refreshed := &provider.Lock{
+		LockId:     lock.LockId,
+		Type:       lock.Type,
+		User:       lock.User,
+		AppName:    lock.AppName,
+		Expiration: expiration(2 * time.Hour),
+	}
+	if err := d.FS.RefreshLock(d.Owner, d.ref("file"), refreshed, ""); err != nil {
+		t.Fatal(err)
+	}
+	l, err = d.FS.GetLock(d.Owner, d.ref("file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Expiration.GetSeconds() != refreshed.Expiration.Seconds {
+		t.Fatalf("the lock was not refreshed: %+v", l)
+	}
+
+	if err := d.FS.Unlock(d.Owner, d.ref("file"), other); err == nil {
+		t.Fatal("a lock should not be released with a different lock id")
+	}
+	if err := d.FS.Unlock(d.Owner, d.ref("file"), lock); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.FS.GetLock(d.Owner, d.ref("file")); !isNotFound(err) {
+		t.Fatalf("expected the lock not to be found after the unlock, got %v", err)
+	}
+}
+
+func spaceNames(list []*provider.StorageSpace) []string {
+	names := make([]string, 0, len(list))
+	for _, s := range list {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func testSpaces(t *testing.T, s *suite, d *Driver) {
+	_, err := d.FS.ListStorageSpaces(d.Owner, nil)
+	s.optional(t, "storage spaces", err)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := d.FS.CreateStorageSpace(d.Owner, &provider.CreateStorageSpaceRequest{
+		Type:  spaces.SpaceTypeProject.AsString(),
+		Name:  "fstest",
+		Quota: &provider.Quota{QuotaMaxBytes: 1024},
+	})
+	s.optional(t, "creating storage spaces", err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	space := res.StorageSpace
+	// drivers may only return the root of the space,
+	// in which case the storage provider derives the id from it
+	id := space.Id
+	if id.GetOpaqueId() == "" {
+		id = &provider.StorageSpaceId{OpaqueId: space.Root.GetOpaqueId()}
+	}
+
+	filter := spaces.ListStorageSpaceFilter{}.BySpaceType(spaces.SpaceTypeProject).List()
+	list, err := d.FS.ListStorageSpaces(d.Owner, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := spaceNames(list); !contains(names, "fstest") {
+		t.Fatalf("the new space is not listed: %v", names)
+	}
+
+	updated, err := d.FS.UpdateStorageSpace(d.Owner, &provider.UpdateStorageSpaceRequest{
+		StorageSpace: &provider.StorageSpace{Id: id, Name: "fstest-renamed"},
+	})
+	s.optional(t, "updating storage spaces", err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.StorageSpace.GetName() != "fstest-renamed" {
+		t.Fatalf("the space was not renamed: %+v", updated.StorageSpace)
+	}
+
+	err = d.FS.DeleteStorageSpace(d.Owner, &provider.DeleteStorageSpaceRequest{Id: id})
+	s.optional(t, "deleting storage spaces", err)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err = d.FS.ListStorageSpaces(d.Owner, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range spaceNames(list) {
+		if strings.HasPrefix(name, "fstest") {
+			t.Fatalf("the deleted space is still listed: %s", name)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package fstest implements a conformance suite for storage.FS drivers.
+//
+// A driver test only needs to provide a factory returning a fresh instance
+// of the driver, and calls Run:
+//
+//	func TestConformance(t *testing.T) {
+//		fstest.Run(t, func(t *testing.T) *fstest.Driver {
+//			return &fstest.Driver{FS: newFS(t), Owner: ctx, Grantee: marie}
+//		})
+//	}
+//
+// Every area of the storage.FS contract is exercised in its own subtest.
+// Optional capabilities that a driver reports as errtypes.NotSupported are
+// skipped, and the list of the unsupported capabilities is logged once the
+// suite has completed.
+package fstest
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+// Driver is an instance of the storage driver under test.
+type Driver struct {
+	// FS is the driver under test.
+	FS storage.FS
+	// Owner is the context used to perform all the operations,
+	// carrying the user owning Root.
+	Owner context.Context
+	// Grantee is the user the suite shares resources with.
+	Grantee *userpb.User
+	// Root is the existing folder, as seen by Owner, in which the
+	// suite creates its resources. It defaults to "/".
+	Root string
+}
+
+// Factory returns a new instance of the driver under test.
+// The resources used by the driver should be released with t.Cleanup.
+type Factory func(t *testing.T) *Driver
+
+type suite struct {
+	mu          sync.Mutex
+	unsupported map[string]string
+}
+
+var cases = []struct {
+	name string
+	run  func(t *testing.T, s *suite, d *Driver)
+}{
+	{"CreateDir", testCreateDir},
+	{"TouchFile", testTouchFile},
+	{"Symlink", testSymlink},
+	{"Move", testMove},
+	{"Delete", testDelete},
+	{"Upload", testUpload},
+	{"Revisions", testRevisions},
+	{"Recycle", testRecycle},
+	{"Grants", testGrants},
+	{"ArbitraryMetadata", testArbitraryMetadata},
+	{"Locks", testLocks},
+	{"Spaces", testSpaces},
+}
+
+// Run runs the conformance suite against the drivers returned by newDriver.
+// Every test gets its own instance of the driver.
+func Run(t *testing.T, newDriver Factory) {
+	s := &suite{unsupported: map[string]string{}}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			d := newDriver(t)
+			if d.Root == "" {
+				d.Root = "/"
+			}
+			c.run(t, s, d)
+		})
+	}
+	s.report(t)
+}
+
+// optional skips the current test if err reports that the driver
+// does not implement the given capability.
+func (s *suite) optional(t *testing.T, capability string, err error) {
+	t.Helper()
+	if !isNotSupported(err) {
+		return
+	}
+	s.record(capability, err.Error())
+	t.Skipf("%s not supported by the driver: %v", capability, err)
+}
+
+// limitation records a behaviour that the CS3 APIs do not mandate
+// and that the driver does not implement, without failing the test.
+func (s *suite) limitation(t *testing.T, capability, reason string) {
+	t.Helper()
+	s.record(capability, reason)
+	t.Logf("%s not supported by the driver: %s", capability, reason)
+}
+
+func (s *suite) record(capability, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unsupported[capability] = reason
+}
+
+func (s *suite) report(t *testing.T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.unsupported) == 0 {
+		t.Log("the driver supports all the optional capabilities")
+		return
+	}
+	capabilities := make([]string, 0, len(s.unsupported))
+	for c := range s.unsupported {
+		capabilities = append(capabilities, c)
+	}
+	sort.Strings(capabilities)
+	for i, c := range capabilities {
+		capabilities[i] = "  " + c + ": " + s.unsupported[c]
+	}
+	t.Logf("the driver does not support the following optional capabilities:\n%s", strings.Join(capabilities, "\n"))
+}
+
+func isNotSupported(err error) bool {
+	var e errtypes.IsNotSupported
+	return errors.As(err, &e)
+}
+
+func isNotFound(err error) bool {
+	var e errtypes.IsNotFound
+	return errors.As(err, &e)
+}
+
+func (d *Driver) path(elem ...string) string {
+	return path.Join(append([]string{d.Root}, elem...)...)
+}
+
+func (d *Driver) ref(elem ...string) *provider.Reference {
+	return &provider.Reference{Path: d.path(elem...)}
+}
@@ -34,6 +34,7 @@ type FS interface {
 	CreateHome(ctx context.Context) error
 	CreateDir(ctx context.Context, ref *provider.Reference) error
 	TouchFile(ctx context.Context, ref *provider.Reference) error
+	CreateSymlink(ctx context.Context, ref *provider.Reference, target string) error
 	Delete(ctx context.Context, ref *provider.Reference) error
 	Move(ctx context.Context, oldRef, newRef *provider.Reference) error
 	GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error)
@@ -66,6 +67,7 @@ type FS interface {
 	ListStorageSpaces(ctx context.Context, filter []*provider.ListStorageSpacesRequest_Filter) ([]*provider.StorageSpace, error)
 	CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error)
 	UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error)
+	DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) error
 }
 
 // Registry is the interface that storage registries implement
@@ -81,3 +83,16 @@ type PathWrapper interface {
 	Unwrap(ctx context.Context, rp string) (string, error)
 	Wrap(ctx context.Context, rp string) (string, error)
 }
+
+// UploadConcatenator is implemented by the drivers able to concatenate
+// uploads, as in the concatenation extension of the tus protocol.
+type UploadConcatenator interface {
+	ConcatenatesUploads() bool
+}
+
+// ConcatenatesUploads tells whether fs is able to concatenate uploads.
+// The uploads asking for a concatenation must be rejected otherwise.
+func ConcatenatesUploads(fs FS) bool {
+	c, ok := fs.(UploadConcatenator)
+	return ok && c.ConcatenatesUploads()
+}
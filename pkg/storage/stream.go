@@ -0,0 +1,92 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package storage
+
+import (
+	"context"
+	"io"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	typepb "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+// Iterator returns the results of a listing one at a time.
+type Iterator[T any] interface {
+	// Next returns the next element, or io.EOF once all of them have been returned.
+	Next() (T, error)
+	// Close releases the resources held by the iterator.
+	Close() error
+}
+
+// StreamingFS is implemented by the drivers able to list folders and recycle
+// bins incrementally, without holding all the entries in memory.
+type StreamingFS interface {
+	ListFolderStream(ctx context.Context, ref *provider.Reference, mdKeys []string) (Iterator[*provider.ResourceInfo], error)
+	ListRecycleStream(ctx context.Context, basePath, key, relativePath string, from, to *typepb.Timestamp) (Iterator[*provider.RecycleItem], error)
+}
+
+// ListFolderStream lists the folder at ref through the streaming API of the
+// driver when available, falling back to ListFolder otherwise.
+func ListFolderStream(ctx context.Context, fs FS, ref *provider.Reference, mdKeys []string) (Iterator[*provider.ResourceInfo], error) {
+	if s, ok := fs.(StreamingFS); ok {
+		return s.ListFolderStream(ctx, ref, mdKeys)
+	}
+	mds, err := fs.ListFolder(ctx, ref, mdKeys)
+	if err != nil {
+		return nil, err
+	}
+	return NewSliceIterator(mds), nil
+}
+
+// ListRecycleStream lists the recycle bin through the streaming API of the
+// driver when available, falling back to ListRecycle otherwise.
+func ListRecycleStream(ctx context.Context, fs FS, basePath, key, relativePath string, from, to *typepb.Timestamp) (Iterator[*provider.RecycleItem], error) {
+	if s, ok := fs.(StreamingFS); ok {
+		return s.ListRecycleStream(ctx, basePath, key, relativePath, from, to)
+	}
+	items, err := fs.ListRecycle(ctx, basePath, key, relativePath, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return NewSliceIterator(items), nil
+}
+
+// NewSliceIterator returns an iterator over the elements of l.
+func NewSliceIterator[T any](l []T) Iterator[T] {
+	return &sliceIterator[T]{l: l}
+}
+
+type sliceIterator[T any] struct {
+	l []T
+}
+
+func (it *sliceIterator[T]) Next() (T, error) {
+	var zero T
+	if len(it.l) == 0 {
+		return zero, io.EOF
+	}
+	e := it.l[0]
+	it.l = it.l[1:]
+	return e, nil
+}
+
+func (it *sliceIterator[T]) Close() error {
+	it.l = nil
+	return nil
+}
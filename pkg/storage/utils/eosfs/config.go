@@ -162,4 +162,14 @@ type Config struct {
 	// Maximum time span in days a ListRecycle call may return: if exceeded, ListRecycle
 	// will override the "to" date with "from" + this value
 	MaxDaysInRecycleList int `mapstructure:"max_days_in_recycle_list"`
+
+	// Location where the partial tus uploads are staged before being written to EOS.
+	// It must be shared by the storage provider and its data servers.
+	// Defaults to <cache_directory>/uploads
+	UploadsSpool string `mapstructure:"uploads_spool"`
+
+	// UploadExpiration stores in seconds the time after which unfinished
+	// tus uploads are removed from the spool.
+	// Default is 86400
+	UploadExpiration int `mapstructure:"upload_expiration"`
 }
@@ -57,6 +57,7 @@ import (
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	"github.com/cs3org/reva/pkg/storage/utils/grants"
 	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/cs3org/reva/pkg/storage/utils/tus"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/pkg/errors"
 )
@@ -135,6 +136,14 @@ func (c *Config) ApplyDefaults() {
 		c.MaxDaysInRecycleList = 14
 	}
 
+	if c.UploadsSpool == "" {
+		c.UploadsSpool = path.Join(c.CacheDirectory, "uploads")
+	}
+
+	if c.UploadExpiration == 0 {
+		c.UploadExpiration = 86400
+	}
+
 	c.GatewaySvc = sharedconf.GetGatewaySVC(c.GatewaySvc)
 }
 
@@ -146,6 +155,7 @@ type Eosfs struct {
 	userIDCache    *ttlcache.Cache
 	tokenCache     gcache.Cache
 	binaryClient   eosclient.EOSClient
+	uploads        *tus.Store
 }
 
 // NewEOSFS returns a storage.FS interface implementation that connects to an EOS instance.
@@ -227,12 +237,24 @@ func NewEOSFS(ctx context.Context, c *Config) (storage.FS, error) {
 			TokenExpiry:         c.TokenExpiry,
 		}
 		eosClient, err = eosbinary.New(eosClientOpts)
+		eosBinaryClient = eosClient
 	}
 
 	if err != nil {
 		return nil, errors.Wrap(err, "error initializing eosclient")
 	}
 
+	return newEosfs(c, eosClient, eosBinaryClient), nil
+}
+
+// NewEOSFSWithClient returns a storage.FS interface implementation that
+// accesses EOS through the given client, e.g. an in-memory one in tests.
+func NewEOSFSWithClient(ctx context.Context, c *Config, client eosclient.EOSClient) (storage.FS, error) {
+	c.ApplyDefaults()
+	return newEosfs(c, client, client), nil
+}
+
+func newEosfs(c *Config, eosClient, eosBinaryClient eosclient.EOSClient) *Eosfs {
 	eosfs := &Eosfs{
 		c:            eosClient,
 		binaryClient: eosBinaryClient,
@@ -241,6 +263,7 @@ func NewEOSFS(ctx context.Context, c *Config) (storage.FS, error) {
 		userIDCache:  ttlcache.NewCache(),
 		tokenCache:   gcache.New(c.UserIDCacheSize).LFU().Build(),
 	}
+	eosfs.uploads = tus.New(c.UploadsSpool, time.Duration(c.UploadExpiration)*time.Second, eosfs.Upload)
 
 	eosfs.userIDCache.SetCacheSizeLimit(c.UserIDCacheSize)
 	eosfs.userIDCache.SetExpirationReasonCallback(func(key string, reason ttlcache.EvictionReason, value interface{}) {
@@ -253,7 +276,7 @@ func NewEOSFS(ctx context.Context, c *Config) (storage.FS, error) {
 
 	go eosfs.userIDcacheWarmup()
 
-	return eosfs, nil
+	return eosfs
 }
 
 func (fs *Eosfs) userIDcacheWarmup() {
@@ -278,7 +301,10 @@ func (fs *Eosfs) userIDcacheWarmup() {
 }
 
 func (fs *Eosfs) ListWithRegex(ctx context.Context, path, regex string, depth uint, user *userpb.User) ([]*provider.ResourceInfo, error) {
-	client := fs.binaryClient.(*eosbinary.Client)
+	client, ok := fs.binaryClient.(*eosbinary.Client)
+	if !ok {
+		return nil, errtypes.NotSupported("eosfs: list with regex requires the eos binary client")
+	}
 	userAuth, err := fs.getUserAuth(ctx, user, "")
 	if err != nil {
 		return nil, err
@@ -304,6 +330,7 @@ func (fs *Eosfs) ListWithRegex(ctx context.Context, path, regex string, depth ui
 
 func (fs *Eosfs) Shutdown(ctx context.Context) error {
 	// TODO(labkode): in a grpc implementation we can close connections.
+	fs.uploads.Close()
 	return nil
 }
 
@@ -735,6 +762,18 @@ func (fs *Eosfs) SetLock(ctx context.Context, ref *provider.Reference, l *provid
 	}
 
 	path = fs.wrap(ctx, path)
+
+	// the eos app lock lets its holder set it again,
+	// while a resource already locked must not be locked anew
+	_, err = fs.getLock(ctx, user, path, ref)
+	switch err.(type) {
+	case nil:
+		return errtypes.Conflict("resource already locked")
+	case errtypes.IsNotFound:
+	default:
+		return err
+	}
+
 	return fs.setLock(ctx, l, path)
 }
 
@@ -1119,7 +1158,7 @@ func (fs *Eosfs) convertACLsToGrants(ctx context.Context, acls *acl.ACLs) ([]*pr
 }
 
 func isSysACLs(a *eosclient.Attribute) bool {
-	return a.Type == SystemAttr && a.Key == "sys"
+	return a.Type == SystemAttr && a.Key == "acl"
 }
 
 func isLightweightACL(a *eosclient.Attribute) bool {
@@ -1281,7 +1320,7 @@ func (fs *Eosfs) listWithNominalHome(ctx context.Context, p string) (finfos []*p
 
 // CreateStorageSpace creates a storage space.
 func (fs *Eosfs) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
-	return nil, fmt.Errorf("unimplemented: CreateStorageSpace")
+	return nil, errtypes.NotSupported("create storage space")
 }
 
 func (fs *Eosfs) GetQuota(ctx context.Context, ref *provider.Reference) (totalbytes, usedbytes uint64, err error) {
@@ -1404,6 +1443,11 @@ func (fs *Eosfs) TouchFile(ctx context.Context, ref *provider.Reference) error {
 	return fs.c.Touch(ctx, auth, fn)
 }
 
+// CreateSymlink as defined in the storage.FS interface.
+func (fs *Eosfs) CreateSymlink(ctx context.Context, ref *provider.Reference, target string) error {
+	return errtypes.NotSupported("eosfs: symlinks are not supported")
+}
+
 func (fs *Eosfs) CreateReference(ctx context.Context, p string, targetURI *url.URL) error {
 	_, err := utils.GetUser(ctx)
 	if err != nil {
@@ -1739,6 +1783,11 @@ func (fs *Eosfs) UpdateStorageSpace(ctx context.Context, req *provider.UpdateSto
 	return nil, errtypes.NotSupported("update storage space")
 }
 
+// DeleteStorageSpace deletes a storage space.
+func (fs *Eosfs) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) error {
+	return errtypes.NotSupported("delete storage space")
+}
+
 func (fs *Eosfs) convertToRecycleItem(ctx context.Context, eosDeletedItem *eosclient.DeletedEntry) (*provider.RecycleItem, error) {
 	path, err := fs.unwrap(ctx, eosDeletedItem.RestorePath)
 	if err != nil {
@@ -0,0 +1,332 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package eosfs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/eosclient"
+	"github.com/cs3org/reva/pkg/eosclient/eosmem"
+	"github.com/cs3org/reva/pkg/rhttp/datatx/manager/tus"
+	"github.com/cs3org/reva/pkg/storage/utils/acl"
+	"github.com/cs3org/reva/pkg/utils"
+)
+
+var (
+	einstein = &userpb.User{
+		Id:        &userpb.UserId{OpaqueId: "einstein", Idp: "cernbox.cern.ch", Type: userpb.UserType_USER_TYPE_PRIMARY},
+		Username:  "einstein",
+		UidNumber: 1000,
+		GidNumber: 1000,
+	}
+	marie = &userpb.User{
+		Id:        &userpb.UserId{OpaqueId: "marie", Idp: "cernbox.cern.ch", Type: userpb.UserType_USER_TYPE_PRIMARY},
+		Username:  "marie",
+		UidNumber: 1001,
+		GidNumber: 1000,
+	}
+)
+
+// newMemoryFS returns an eoshome-like driver backed by an in-memory EOS,
+// where the homes of the test users already exist.
+func newMemoryFS(t *testing.T) *Eosfs {
+	return newMemoryFSWithConfig(t, &Config{EnableHome: true})
+}
+
+// newMemoryFSWithConfig is like newMemoryFS with the given configuration,
+// whose namespace is /eos/user.
+func newMemoryFSWithConfig(t *testing.T, c *Config) *Eosfs {
+	client, err := eosmem.New(&eosmem.Options{VersionInvariant: c.VersionInvariant})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Namespace = "/eos/user"
+	c.CacheDirectory = t.TempDir()
+	fs, err := NewEOSFSWithClient(context.Background(), c, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eosfs := fs.(*Eosfs)
+
+	ctx := context.Background()
+	for _, u := range []*userpb.User{einstein, marie} {
+		home := "/eos/user/" + u.Username
+		auth, _ := eosfs.extractUIDAndGID(u)
+		if err := client.CreateDir(ctx, utils.GetEmptyAuth(), home); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Chown(ctx, utils.GetEmptyAuth(), auth, home); err != nil {
+			t.Fatal(err)
+		}
+		// like the create home hook, grant the owner access to the tree
+		ownerACL := &acl.Entry{Type: acl.TypeUser, Qualifier: auth.Role.UID, Permissions: "rwxm"}
+		if err := client.AddACL(ctx, auth, utils.GetEmptyAuth(), home, eosclient.EndPosition, ownerACL); err != nil {
+			t.Fatal(err)
+		}
+		// no user provider is available, resolve the users from the cache
+		_ = eosfs.userIDCache.Set(auth.Role.UID, u.Id)
+		_ = eosfs.userIDCache.Set(u.Id.OpaqueId, u)
+	}
+	return eosfs
+}
+
+func upload(t *testing.T, fs *Eosfs, ctx context.Context, p, content string) {
+	t.Helper()
+	if err := fs.Upload(ctx, &provider.Reference{Path: p}, io.NopCloser(strings.NewReader(content)), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUploadAndRevisions(t *testing.T) {
+	fs := newMemoryFS(t)
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	ref := &provider.Reference{Path: "/docs/file.txt"}
+
+	if err := fs.CreateDir(ctx, &provider.Reference{Path: "/docs"}); err != nil {
+		t.Fatal(err)
+	}
+	upload(t, fs, ctx, ref.Path, "v1")
+	upload(t, fs, ctx, ref.Path, "v2")
+
+	md, err := fs.GetMD(ctx, ref, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Size != 2 || !utils.UserEqual(md.Owner, einstein.Id) || !md.PermissionSet.AddGrant {
+		t.Fatalf("unexpected metadata %+v", md)
+	}
+	if path, err := fs.GetPathByID(ctx, md.Id); err != nil || path != ref.Path {
+		t.Fatalf("got path %q, err %v", path, err)
+	}
+
+	// version folders are hidden
+	list, err := fs.ListFolder(ctx, &provider.Reference{Path: "/docs"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Path != ref.Path {
+		t.Fatalf("unexpected listing %+v", list)
+	}
+
+	revisions, err := fs.ListRevisions(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 {
+		t.Fatalf("expected one revision, got %d", len(revisions))
+	}
+	if err := fs.RestoreRevision(ctx, ref, revisions[0].Key); err != nil {
+		t.Fatal(err)
+	}
+	r, err := fs.Download(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "v1" {
+		t.Fatalf("got %q", data)
+	}
+}
+
+func TestTusUpload(t *testing.T) {
+	fs := newMemoryFS(t)
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	ref := &provider.Reference{Path: "/file.txt"}
+
+	// simple uploads do not stage anything in the spool
+	ids, err := fs.InitiateUpload(ctx, ref, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ids["tus"]; ok || ids["simple"] == "" {
+		t.Fatalf("unexpected upload ids %v", ids)
+	}
+
+	ids, err = fs.InitiateUpload(ctx, ref, 10, map[string]string{"protocol": "tus"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtx, err := tus.New(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := dtx.Handler(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patch := func(offset, content string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPatch, "/"+ids["tus"], strings.NewReader(content))
+		r.Header.Set("Tus-Resumable", "1.0.0")
+		r.Header.Set("Content-Type", "application/offset+octet-stream")
+		r.Header.Set("Upload-Offset", offset)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// the upload is resumed after the first chunk, and committed once complete
+	w := patch("0", "hello")
+	if w.Code != http.StatusNoContent || w.Header().Get("Upload-Expires") == "" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	if _, err := fs.GetMD(ctx, ref, nil); err == nil {
+		t.Fatal("partial uploads should not be visible")
+	}
+	if w = patch("5", "world"); w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected response %d", w.Code)
+	}
+
+	r, err := fs.Download(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if b, _ := io.ReadAll(r); string(b) != "helloworld" {
+		t.Fatalf("unexpected content %q", b)
+	}
+	if w = patch("10", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("committed uploads should be removed, got %d", w.Code)
+	}
+}
+
+func TestRecycle(t *testing.T) {
+	fs := newMemoryFS(t)
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	upload(t, fs, ctx, "/file.txt", "content")
+
+	if err := fs.Delete(ctx, &provider.Reference{Path: "/file.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	items, err := fs.ListRecycle(ctx, "/", "", "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Ref.Path != "/file.txt" || items[0].Size != 7 {
+		t.Fatalf("unexpected recycle items %+v", items)
+	}
+
+	if err := fs.RestoreRecycleItem(ctx, "/", items[0].Key, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.GetMD(ctx, &provider.Reference{Path: "/file.txt"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := fs.ListRecycle(ctx, "/", "", "", nil, nil); len(items) != 0 {
+		t.Fatalf("expected an empty recycle bin, got %+v", items)
+	}
+}
+
+func TestGrants(t *testing.T) {
+	fs := newMemoryFS(t)
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	ref := &provider.Reference{Path: "/shared"}
+	if err := fs.CreateDir(ctx, ref); err != nil {
+		t.Fatal(err)
+	}
+
+	grant := &provider.Grant{
+		Grantee:     &provider.Grantee{Type: provider.GranteeType_GRANTEE_TYPE_USER, Id: &provider.Grantee_UserId{UserId: marie.Id}},
+		Permissions: &provider.ResourcePermissions{Stat: true, ListContainer: true, InitiateFileDownload: true},
+	}
+	if err := fs.AddGrant(ctx, ref, grant); err != nil {
+		t.Fatal(err)
+	}
+	grants, err := fs.ListGrants(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the acl of the owner is inherited from the home
+	if g := findGrant(grants, marie.Id); g == nil || !g.Permissions.Stat || g.Permissions.InitiateFileUpload {
+		t.Fatalf("unexpected grants %+v", grants)
+	}
+
+	// marie can now read the folder
+	info, err := fs.c.GetFileInfoByPath(ctx, eosclient.Authorization{Role: eosclient.Role{UID: "1001", GID: "1000"}}, "/eos/user/einstein/shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	marieCtx := appctx.ContextSetUser(context.Background(), marie)
+	if perms := fs.permissionSet(marieCtx, info, einstein.Id); !perms.Stat || perms.InitiateFileUpload {
+		t.Fatalf("unexpected permissions %+v", perms)
+	}
+
+	if err := fs.RemoveGrant(ctx, ref, grant); err != nil {
+		t.Fatal(err)
+	}
+	if grants, _ := fs.ListGrants(ctx, ref); findGrant(grants, marie.Id) != nil {
+		t.Fatalf("expected the grant to be removed, got %+v", grants)
+	}
+}
+
+func findGrant(grants []*provider.Grant, id *userpb.UserId) *provider.Grant {
+	for _, g := range grants {
+		if utils.UserEqual(g.Grantee.GetUserId(), id) {
+			return g
+		}
+	}
+	return nil
+}
+
+func TestArbitraryMetadata(t *testing.T) {
+	fs := newMemoryFS(t)
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	ref := &provider.Reference{Path: "/file.txt"}
+	upload(t, fs, ctx, ref.Path, "content")
+
+	md := &provider.ArbitraryMetadata{Metadata: map[string]string{"color": "blue", FavoritesKey: "1"}}
+	if err := fs.SetArbitraryMetadata(ctx, ref, md); err != nil {
+		t.Fatal(err)
+	}
+	info, err := fs.GetMD(ctx, ref, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := info.ArbitraryMetadata.Metadata; m["color"] != "blue" || m[FavoritesKey] != "1" {
+		t.Fatalf("unexpected metadata %+v", m)
+	}
+
+	// favorites are per user
+	eosFileInfo, err := fs.c.GetFileInfoByPath(ctx, utils.GetEmptyAuth(), "/eos/user/einstein/file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parseAndSetFavoriteAttr(appctx.ContextSetUser(context.Background(), marie), eosFileInfo.Attrs)
+	if _, ok := eosFileInfo.Attrs[FavoritesKey]; ok {
+		t.Fatal("the favorite of einstein should not be visible to marie")
+	}
+
+	if err := fs.UnsetArbitraryMetadata(ctx, ref, []string{"color"}); err != nil {
+		t.Fatal(err)
+	}
+	info, err = fs.GetMD(ctx, ref, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := info.ArbitraryMetadata.Metadata["color"]; ok {
+		t.Fatal("expected the metadata to be removed")
+	}
+}
@@ -23,12 +23,14 @@ import (
 	"io"
 	"os"
 	"path"
+	"time"
 
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	"github.com/cs3org/reva/pkg/utils"
 	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
 )
 
 func (fs *Eosfs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser, metadata map[string]string) error {
@@ -91,7 +93,31 @@ func (fs *Eosfs) InitiateUpload(ctx context.Context, ref *provider.Reference, up
 	if err != nil {
 		return nil, err
 	}
-	return map[string]string{
+
+	uploadIDs := map[string]string{
 		"simple": p,
-	}, nil
+	}
+
+	// tus uploads are staged in the spool and written through Upload once complete,
+	// the spool entry is only created for the clients asking for tus
+	if metadata["protocol"] == "tus" {
+		id, err := fs.uploads.Initiate(ctx, &provider.Reference{Path: p}, uploadLength, metadata)
+		if err != nil {
+			return nil, errors.Wrap(err, "eos: error initiating tus upload")
+		}
+		uploadIDs["tus"] = id
+	}
+
+	return uploadIDs, nil
+}
+
+// UseIn tells the tus upload middleware which extensions it supports.
+func (fs *Eosfs) UseIn(composer *tusd.StoreComposer) {
+	fs.uploads.UseIn(composer)
+}
+
+// UploadExpiration returns the time after which the unfinished tus upload
+// with the given id is removed.
+func (fs *Eosfs) UploadExpiration(ctx context.Context, id string) (time.Time, bool) {
+	return fs.uploads.Expiration(ctx, id)
 }
@@ -0,0 +1,184 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package indexfs
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/crypto"
+	"github.com/pkg/errors"
+)
+
+// Blobs are reference counted: every file and every version referencing
+// a blob holds a reference, and recycled resources keep theirs until purged.
+// Blobs without references are garbage collected, unless pinned by an
+// upload that is about to reference them.
+
+// pin prevents the blob from being collected. If the blob is being deleted,
+// it waits for the deletion to complete, so that the blob is stored again.
+func (fs *indexfs) pin(key string) {
+	fs.pinsMu.Lock()
+	defer fs.pinsMu.Unlock()
+	for {
+		deleted, ok := fs.deleting[key]
+		if !ok {
+			break
+		}
+		fs.pinsMu.Unlock()
+		<-deleted
+		fs.pinsMu.Lock()
+	}
+	fs.pins[key]++
+}
+
+func (fs *indexfs) unpin(key string) {
+	fs.pinsMu.Lock()
+	defer fs.pinsMu.Unlock()
+	if fs.pins[key]--; fs.pins[key] <= 0 {
+		delete(fs.pins, key)
+	}
+}
+
+// spool writes the content read from r to a temporary file, and returns
+// the file together with the checksum of the content.
+func (fs *indexfs) spool(ctx context.Context, id string, r io.Reader) (*os.File, string, error) {
+	f, err := os.Create(path.Join(fs.conf.Root, "uploads", id))
+	if err != nil {
+		return nil, "", errors.Wrap(err, "indexfs: error creating upload file")
+	}
+	sum, err := crypto.ComputeSHA1XS(io.TeeReader(r, f))
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, "", errors.Wrap(err, "indexfs: error writing upload file")
+	}
+	return f, sum, nil
+}
+
+// storeBlob stores the content read from r in the blob with the given key,
+// which must be pinned. Already referenced blobs are not uploaded again.
+func (fs *indexfs) storeBlob(ctx context.Context, key string, r io.Reader) error {
+	var refs int
+	err := fs.db.QueryRowContext(ctx, "SELECT refs FROM blobs WHERE key = ?", key).Scan(&refs)
+	switch {
+	case err == nil && refs > 0:
+		return nil
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return errors.Wrap(err, "indexfs: error reading blob "+key)
+	}
+
+	// the blob is tracked before being uploaded, so that it gets
+	// collected if the upload is interrupted
+	if _, err := fs.db.ExecContext(ctx, "INSERT OR IGNORE INTO blobs (key, refs) VALUES (?, 0)", key); err != nil {
+		return errors.Wrap(err, "indexfs: error adding entry to DB")
+	}
+	return fs.blobs.Upload(ctx, key, r)
+}
+
+// addRef adds a reference to the given blob.
+func addRef(ctx context.Context, q querier, key string) error {
+	if _, err := q.ExecContext(ctx, "INSERT INTO blobs (key, refs) VALUES (?, 1) ON CONFLICT(key) DO UPDATE SET refs = refs + 1", key); err != nil {
+		return errors.Wrap(err, "indexfs: error referencing blob "+key)
+	}
+	return nil
+}
+
+// releaseRefs removes a reference from each of the given blobs.
+func releaseRefs(ctx context.Context, q querier, keys []string) error {
+	for _, key := range keys {
+		if _, err := q.ExecContext(ctx, "UPDATE blobs SET refs = refs - 1 WHERE key = ?", key); err != nil {
+			return errors.Wrap(err, "indexfs: error releasing blob "+key)
+		}
+	}
+	return nil
+}
+
+// collect removes the given blobs if they are not referenced nor pinned anymore.
+// Failures are only logged, as the blobs are collected again at the next start.
+func (fs *indexfs) collect(ctx context.Context, keys []string) {
+	log := appctx.GetLogger(ctx)
+	for _, key := range keys {
+		if err := fs.collectBlob(ctx, key); err != nil {
+			log.Error().Err(err).Str("blob", key).Msg("indexfs: error collecting blob")
+		}
+	}
+}
+
+func (fs *indexfs) collectBlob(ctx context.Context, key string) error {
+	fs.pinsMu.Lock()
+	if _, ok := fs.deleting[key]; ok || fs.pins[key] > 0 {
+		fs.pinsMu.Unlock()
+		return nil
+	}
+	res, err := fs.db.ExecContext(ctx, "DELETE FROM blobs WHERE key = ? AND refs <= 0", key)
+	if err != nil {
+		fs.pinsMu.Unlock()
+		return errors.Wrap(err, "indexfs: error removing entry from DB")
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		// still referenced, or already collected
+		fs.pinsMu.Unlock()
+		return err
+	}
+	// the blob is deleted without holding the lock, the uploads
+	// of the same content wait in pin until it is gone
+	deleted := make(chan struct{})
+	fs.deleting[key] = deleted
+	fs.pinsMu.Unlock()
+
+	err = fs.blobs.Delete(ctx, key)
+
+	fs.pinsMu.Lock()
+	delete(fs.deleting, key)
+	close(deleted)
+	fs.pinsMu.Unlock()
+	return err
+}
+
+// collectGarbage removes all the blobs that are not referenced anymore.
+func (fs *indexfs) collectGarbage(ctx context.Context) error {
+	rows, err := fs.db.QueryContext(ctx, "SELECT key FROM blobs WHERE refs <= 0")
+	if err != nil {
+		return errors.Wrap(err, "indexfs: error listing blobs")
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return errors.Wrap(err, "indexfs: error scanning db rows")
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "indexfs: error listing blobs")
+	}
+
+	fs.collect(ctx, keys)
+	return nil
+}
@@ -0,0 +1,251 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package indexfs
+
+import (
+	"context"
+	"database/sql"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/google/uuid"
+	// sqlite3 driver for the metadata index.
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// node is an entry of the namespace tree. Paths are internal,
+// i.e. they include the home layout when homes are enabled.
+type node struct {
+	id      string
+	parent  string
+	name    string
+	path    string
+	isDir   bool
+	size    uint64
+	mtime   int64 // nanoseconds
+	etag    string
+	blob    string // key of the blob holding the content, empty for containers and empty files
+	owner   *userpb.UserId
+	trashed string // key of the recycle item the node belongs to, if deleted
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS nodes (
+	id VARCHAR(64) PRIMARY KEY,
+	parent VARCHAR(64) NOT NULL,
+	name TEXT NOT NULL,
+	path TEXT NOT NULL,
+	is_dir INTEGER NOT NULL,
+	size INTEGER NOT NULL DEFAULT 0,
+	mtime INTEGER NOT NULL,
+	etag VARCHAR(64) NOT NULL,
+	blob VARCHAR(64) NOT NULL DEFAULT '',
+	owner_idp TEXT NOT NULL DEFAULT '',
+	owner_id TEXT NOT NULL DEFAULT '',
+	trashed VARCHAR(64) NOT NULL DEFAULT ''
+);
+CREATE UNIQUE INDEX IF NOT EXISTS nodes_path ON nodes (path) WHERE trashed = '';
+CREATE INDEX IF NOT EXISTS nodes_parent ON nodes (parent);
+CREATE INDEX IF NOT EXISTS nodes_trashed ON nodes (trashed);
+CREATE INDEX IF NOT EXISTS nodes_owner ON nodes (owner_idp, owner_id);
+CREATE TABLE IF NOT EXISTS metadata (
+	node VARCHAR(64) NOT NULL,
+	key TEXT NOT NULL,
+	value TEXT NOT NULL,
+	PRIMARY KEY (node, key)
+);
+CREATE TABLE IF NOT EXISTS grants (
+	node VARCHAR(64) NOT NULL,
+	grantee TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	PRIMARY KEY (node, grantee)
+);
+CREATE TABLE IF NOT EXISTS versions (
+	node VARCHAR(64) NOT NULL,
+	key VARCHAR(64) NOT NULL,
+	blob VARCHAR(64) NOT NULL,
+	size INTEGER NOT NULL,
+	mtime INTEGER NOT NULL,
+	etag VARCHAR(64) NOT NULL,
+	PRIMARY KEY (node, key)
+);
+CREATE TABLE IF NOT EXISTS recycle (
+	key VARCHAR(64) PRIMARY KEY,
+	node VARCHAR(64) NOT NULL,
+	path TEXT NOT NULL,
+	owner TEXT NOT NULL,
+	deletion_time INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS blobs (
+	key VARCHAR(64) PRIMARY KEY,
+	refs INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS uploads (
+	id VARCHAR(64) PRIMARY KEY,
+	path TEXT NOT NULL,
+	length INTEGER NOT NULL,
+	mtime TEXT NOT NULL DEFAULT '',
+	owner_idp TEXT NOT NULL,
+	owner_id TEXT NOT NULL,
+	created INTEGER NOT NULL
+);`
+
+func openIndex(ctx context.Context, file string) (*sql.DB, error) {
+	// writers are serialized by sqlite, wait for the lock instead of failing
+	db, err := sql.Open("sqlite3", "file:"+file+"?_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, errors.Wrap(err, "indexfs: error opening index")
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, errors.Wrap(err, "indexfs: error creating index tables")
+	}
+
+	// make sure the root of the namespace exists
+	if _, err := db.ExecContext(ctx, `INSERT INTO nodes (id, parent, name, path, is_dir, mtime, etag)
+		SELECT ?, '', '', '/', 1, 0, ? WHERE NOT EXISTS (SELECT 1 FROM nodes WHERE path = '/' AND trashed = '')`,
+		uuid.New().String(), newEtag()); err != nil {
+		return nil, errors.Wrap(err, "indexfs: error creating root node")
+	}
+	return db, nil
+}
+
+func newEtag() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+const nodeColumns = "id, parent, name, path, is_dir, size, mtime, etag, blob, owner_idp, owner_id, trashed"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanNode(s scanner) (*node, error) {
+	n := &node{owner: &userpb.UserId{}}
+	if err := s.Scan(&n.id, &n.parent, &n.name, &n.path, &n.isDir, &n.size, &n.mtime, &n.etag, &n.blob, &n.owner.Idp, &n.owner.OpaqueId, &n.trashed); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func getNode(ctx context.Context, q querier, fn string) (*node, error) {
+	n, err := scanNode(q.QueryRowContext(ctx, "SELECT "+nodeColumns+" FROM nodes WHERE path = ? AND trashed = ''", fn))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errtypes.NotFound(fn)
+		}
+		return nil, errors.Wrap(err, "indexfs: error reading node "+fn)
+	}
+	return n, nil
+}
+
+func getNodeByID(ctx context.Context, q querier, id string) (*node, error) {
+	n, err := scanNode(q.QueryRowContext(ctx, "SELECT "+nodeColumns+" FROM nodes WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errtypes.NotFound(id)
+		}
+		return nil, errors.Wrap(err, "indexfs: error reading node "+id)
+	}
+	return n, nil
+}
+
+func queryNodes(ctx context.Context, q querier, query string, args ...any) ([]*node, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+nodeColumns+" FROM nodes WHERE "+query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "indexfs: error querying nodes")
+	}
+	defer rows.Close()
+
+	var nodes []*node
+	for rows.Next() {
+		n, err := scanNode(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "indexfs: error scanning db rows")
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+func listChildren(ctx context.Context, q querier, parent *node) ([]*node, error) {
+	return queryNodes(ctx, q, "parent = ? AND trashed = '' ORDER BY name", parent.id)
+}
+
+// subtree returns the condition matching the given path and all its descendants,
+// as paths are stored as text the lengths are expressed in characters.
+func subtree(fn string) (string, []any) {
+	prefix := strings.TrimSuffix(fn, "/") + "/"
+	return "(path = ? OR substr(path, 1, ?) = ?)", []any{fn, utf8.RuneCountInString(prefix), prefix}
+}
+
+func insertNode(ctx context.Context, q querier, n *node) error {
+	_, err := q.ExecContext(ctx, "INSERT INTO nodes ("+nodeColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		n.id, n.parent, n.name, n.path, n.isDir, n.size, n.mtime, n.etag, n.blob, n.owner.GetIdp(), n.owner.GetOpaqueId(), n.trashed)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return errtypes.AlreadyExists(n.path)
+		}
+		return errors.Wrap(err, "indexfs: error inserting node "+n.path)
+	}
+	return nil
+}
+
+// ancestors returns the paths of the ancestors of fn, starting from its parent.
+func ancestors(fn string) []string {
+	var list []string
+	for fn != "/" {
+		fn = path.Dir(fn)
+		list = append(list, fn)
+	}
+	return list
+}
+
+// usage returns the bytes used by the files of the owner, including their versions.
+func usage(ctx context.Context, q querier, owner *userpb.UserId) (uint64, error) {
+	var used uint64
+	if err := q.QueryRowContext(ctx, `SELECT
+		(SELECT COALESCE(SUM(size), 0) FROM nodes WHERE is_dir = 0 AND trashed = '' AND owner_idp = ? AND owner_id = ?) +
+		(SELECT COALESCE(SUM(v.size), 0) FROM versions v JOIN nodes n ON v.node = n.id WHERE n.trashed = '' AND n.owner_idp = ? AND n.owner_id = ?)`,
+		owner.GetIdp(), owner.GetOpaqueId(), owner.GetIdp(), owner.GetOpaqueId()).Scan(&used); err != nil {
+		return 0, errors.Wrap(err, "indexfs: error computing usage")
+	}
+	return used, nil
+}
+
+// propagate updates the size, the mtime and the etag of the ancestors of fn.
+func propagate(ctx context.Context, q querier, fn string, delta int64, mtime int64) error {
+	for _, p := range ancestors(fn) {
+		if _, err := q.ExecContext(ctx, "UPDATE nodes SET size = max(size + ?, 0), mtime = max(mtime, ?), etag = ? WHERE path = ? AND trashed = ''",
+			delta, mtime, newEtag(), p); err != nil {
+			return errors.Wrap(err, "indexfs: error propagating to "+p)
+		}
+	}
+	return nil
+}
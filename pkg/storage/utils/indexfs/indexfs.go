@@ -0,0 +1,926 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package indexfs implements the core of the storage drivers keeping the
+// namespace tree and the metadata of the resources (ids, etags, arbitrary
+// metadata, grants, versions and recycle bin) in an embedded index, and the
+// content of the files in a Blobstore.
+package indexfs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/mime"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// Blobstore stores the content of the files.
+// Blobs are written once and never modified.
+type Blobstore interface {
+	// Upload stores the content read from r in the blob with the given key.
+	Upload(ctx context.Context, key string, r io.Reader) error
+	// Download returns the content of the blob with the given key.
+	Download(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob with the given key.
+	Delete(ctx context.Context, key string) error
+}
+
+// Config holds the configuration of the index.
+type Config struct {
+	// Root is the directory holding the index.
+	Root string
+	// EnableHome roots the namespace of the users in their home.
+	EnableHome bool
+	// UserLayout is the template for the home of the users.
+	UserLayout string
+	// Quota of the users, in bytes, accounting for their files and the
+	// versions of the files. 0 means unlimited.
+	Quota uint64
+	// ContentAddressed stores the content of the files in blobs keyed
+	// by their checksum, so that files with the same content share a blob.
+	ContentAddressed bool
+}
+
+// ApplyDefaults applies the default configuration.
+func (c *Config) ApplyDefaults() {
+	if c.UserLayout == "" {
+		c.UserLayout = "{{.Username}}"
+	}
+}
+
+type indexfs struct {
+	conf  *Config
+	db    *sql.DB
+	blobs Blobstore
+
+	// blobs being uploaded, that cannot be garbage collected,
+	// and blobs being deleted, closing their channel once gone
+	pinsMu   sync.Mutex
+	pins     map[string]int
+	deleting map[string]chan struct{}
+}
+
+// New returns an implementation of the storage.FS interface keeping the
+// namespace in an index stored in the configured root directory and the
+// content of the files in the given blobstore.
+func New(ctx context.Context, c *Config, blobs Blobstore) (storage.FS, error) {
+	c.ApplyDefaults()
+
+	if err := os.MkdirAll(path.Join(c.Root, "uploads"), 0700); err != nil {
+		return nil, errors.Wrap(err, "indexfs: error creating root directory "+c.Root)
+	}
+
+	db, err := openIndex(ctx, path.Join(c.Root, "index.db"))
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &indexfs{
+		conf:     c,
+		db:       db,
+		blobs:    blobs,
+		pins:     map[string]int{},
+		deleting: map[string]chan struct{}{},
+	}
+	// collect the blobs left behind by interrupted operations
+	if err := fs.collectGarbage(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return fs, nil
+}
+
+func (fs *indexfs) Shutdown(ctx context.Context) error {
+	return fs.db.Close()
+}
+
+func getUser(ctx context.Context) (*userpb.User, error) {
+	u, ok := appctx.ContextGetUser(ctx)
+	if !ok {
+		return nil, errtypes.UserRequired("indexfs: no user in ctx")
+	}
+	return u, nil
+}
+
+// home returns the internal path of the home of the user in context,
+// which is the root of the namespace if homes are disabled.
+func (fs *indexfs) home(ctx context.Context) string {
+	if !fs.conf.EnableHome {
+		return "/"
+	}
+	u, ok := appctx.ContextGetUser(ctx)
+	if !ok {
+		return "/"
+	}
+	return path.Join("/", templates.WithUser(u, fs.conf.UserLayout))
+}
+
+func (fs *indexfs) wrap(ctx context.Context, fn string) string {
+	return path.Join(fs.home(ctx), fn)
+}
+
+func (fs *indexfs) unwrap(ctx context.Context, np string) string {
+	home := fs.home(ctx)
+	switch {
+	case home == "/":
+		return np
+	case np == home:
+		return "/"
+	case strings.HasPrefix(np, home+"/"):
+		return strings.TrimPrefix(np, home)
+	}
+	// resources outside of the home, accessed by id
+	return np
+}
+
+// resolve returns the internal path of the resource pointed by the reference.
+func (fs *indexfs) resolve(ctx context.Context, ref *provider.Reference) (string, error) {
+	if id := ref.GetResourceId().GetOpaqueId(); id != "" {
+		n, err := getNodeByID(ctx, fs.db, id)
+		if err != nil {
+			return "", err
+		}
+		if n.trashed != "" {
+			return "", errtypes.NotFound(id)
+		}
+		return path.Join(n.path, path.Join("/", ref.Path)), nil
+	}
+	if ref.GetPath() != "" {
+		return fs.wrap(ctx, ref.Path), nil
+	}
+	return "", errtypes.BadRequest("indexfs: invalid reference " + ref.String())
+}
+
+// getNode returns the node pointed by the reference.
+func (fs *indexfs) getNode(ctx context.Context, ref *provider.Reference) (*node, error) {
+	np, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	n, err := getNode(ctx, fs.db, np)
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return nil, errtypes.NotFound(fs.unwrap(ctx, np))
+		}
+		return nil, err
+	}
+	return n, nil
+}
+
+// withTx runs f in a transaction, committed if f succeeds.
+func (fs *indexfs) withTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := fs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "indexfs: error starting transaction")
+	}
+	if err := f(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "indexfs: error committing transaction")
+	}
+	return nil
+}
+
+func ownerID(u *userpb.User) *userpb.UserId {
+	return &userpb.UserId{Idp: u.GetId().GetIdp(), OpaqueId: u.GetId().GetOpaqueId()}
+}
+
+func sameUserID(u1, u2 *userpb.UserId) bool {
+	return u1.GetOpaqueId() != "" && u1.GetOpaqueId() == u2.GetOpaqueId() && u1.GetIdp() == u2.GetIdp()
+}
+
+// fullPermissions is the permission set of the owner of a resource.
+var fullPermissions = &provider.ResourcePermissions{
+	AddGrant:             true,
+	CreateContainer:      true,
+	Delete:               true,
+	GetPath:              true,
+	GetQuota:             true,
+	InitiateFileDownload: true,
+	InitiateFileUpload:   true,
+	ListContainer:        true,
+	ListFileVersions:     true,
+	ListGrants:           true,
+	ListRecycle:          true,
+	Move:                 true,
+	PurgeRecycle:         true,
+	RemoveGrant:          true,
+	RestoreFileVersion:   true,
+	RestoreRecycleItem:   true,
+	Stat:                 true,
+	UpdateGrant:          true,
+}
+
+// grantedPermissions returns the union of the permissions granted
+// to the user in context on the resources at the given paths.
+func (fs *indexfs) grantedPermissions(ctx context.Context, q querier, paths []string) (*provider.ResourcePermissions, error) {
+	perms := &provider.ResourcePermissions{}
+	u, ok := appctx.ContextGetUser(ctx)
+	if !ok || len(paths) == 0 {
+		return perms, nil
+	}
+
+	args := make([]any, 0, len(paths))
+	for _, p := range paths {
+		args = append(args, p)
+	}
+	rows, err := q.QueryContext(ctx, `SELECT g.payload FROM grants g JOIN nodes n ON g.node = n.id
+		WHERE n.trashed = '' AND n.path IN (?`+strings.Repeat(", ?", len(paths)-1)+`)`, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "indexfs: error listing grants")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&payload); err != nil {
+			return nil, errors.Wrap(err, "indexfs: error scanning db rows")
+		}
+		g := &provider.Grant{}
+		if err := utils.UnmarshalJSONToProtoV1([]byte(payload), g); err != nil {
+			return nil, errors.Wrap(err, "indexfs: malformed grant")
+		}
+		if isGrantee(u, g.Grantee) {
+			proto.Merge(perms, g.Permissions)
+		}
+	}
+	return perms, rows.Err()
+}
+
+func isGrantee(u *userpb.User, g *provider.Grantee) bool {
+	switch g.GetType() {
+	case provider.GranteeType_GRANTEE_TYPE_USER:
+		return sameUserID(u.Id, g.GetUserId())
+	case provider.GranteeType_GRANTEE_TYPE_GROUP:
+		for _, group := range u.Groups {
+			if group == g.GetGroupId().GetOpaqueId() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// permissionSet returns the permissions of the user in context on the node.
+// The owner has all permissions, the others the ones granted on the node
+// and its ancestors, on top of the inherited ones if already known.
+// Nodes without an owner, as the root of the namespace, are accessible to everybody.
+func (fs *indexfs) permissionSet(ctx context.Context, q querier, n *node, inherited *provider.ResourcePermissions) (*provider.ResourcePermissions, error) {
+	u, ok := appctx.ContextGetUser(ctx)
+	if !ok {
+		return &provider.ResourcePermissions{}, nil
+	}
+	if n.owner.GetOpaqueId() == "" || sameUserID(n.owner, u.Id) {
+		return fullPermissions, nil
+	}
+
+	paths := []string{n.path}
+	if inherited == nil {
+		paths = append(paths, ancestors(n.path)...)
+	}
+	perms, err := fs.grantedPermissions(ctx, q, paths)
+	if err != nil {
+		return nil, err
+	}
+	if inherited != nil {
+		proto.Merge(perms, inherited)
+	}
+	return perms, nil
+}
+
+// checkPermission fails if the user in context lacks the permission
+// selected by perm on the node.
+func (fs *indexfs) checkPermission(ctx context.Context, q querier, n *node, perm func(*provider.ResourcePermissions) bool) error {
+	perms, err := fs.permissionSet(ctx, q, n, nil)
+	if err != nil {
+		return err
+	}
+	if !perm(perms) {
+		return errtypes.PermissionDenied("indexfs: permission denied on resource " + n.id)
+	}
+	return nil
+}
+
+// createPermission selects the permission needed to create a resource of the given type in a container.
+func createPermission(isDir bool) func(*provider.ResourcePermissions) bool {
+	if isDir {
+		return func(p *provider.ResourcePermissions) bool { return p.CreateContainer }
+	}
+	return func(p *provider.ResourcePermissions) bool { return p.InitiateFileUpload }
+}
+
+// newOwner returns the owner of the resources created in parent, that is
+// the owner of parent or the user in context if parent does not belong to anybody.
+func newOwner(parent *node, u *userpb.User) *userpb.UserId {
+	if parent.owner.GetOpaqueId() != "" {
+		return parent.owner
+	}
+	return ownerID(u)
+}
+
+func (fs *indexfs) getMetadata(ctx context.Context, n *node, mdKeys []string) (*provider.ArbitraryMetadata, error) {
+	rows, err := fs.db.QueryContext(ctx, "SELECT key, value FROM metadata WHERE node = ?", n.id)
+	if err != nil {
+		return nil, errors.Wrap(err, "indexfs: error listing metadata")
+	}
+	defer rows.Close()
+
+	keys := map[string]struct{}{}
+	for _, k := range mdKeys {
+		keys[k] = struct{}{}
+	}
+	_, all := keys["*"]
+	all = all || len(mdKeys) == 0
+
+	metadata := map[string]string{}
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, errors.Wrap(err, "indexfs: error scanning db rows")
+		}
+		if _, ok := keys[k]; all || ok {
+			metadata[k] = v
+		}
+	}
+	return &provider.ArbitraryMetadata{Metadata: metadata}, rows.Err()
+}
+
+func getResourceType(isDir bool) provider.ResourceType {
+	if isDir {
+		return provider.ResourceType_RESOURCE_TYPE_CONTAINER
+	}
+	return provider.ResourceType_RESOURCE_TYPE_FILE
+}
+
+func (fs *indexfs) convertToResourceInfo(ctx context.Context, n *node, mdKeys []string, inherited *provider.ResourcePermissions) (*provider.ResourceInfo, error) {
+	metadata, err := fs.getMetadata(ctx, n, mdKeys)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := fs.permissionSet(ctx, fs.db, n, inherited)
+	if err != nil {
+		return nil, err
+	}
+
+	fn := fs.unwrap(ctx, n.path)
+	ri := &provider.ResourceInfo{
+		Id:                &provider.ResourceId{OpaqueId: n.id},
+		Path:              fn,
+		Type:              getResourceType(n.isDir),
+		Etag:              fmt.Sprintf("%q", n.etag),
+		MimeType:          mime.Detect(n.isDir, fn),
+		Size:              n.size,
+		PermissionSet:     perms,
+		Mtime:             timestamp(n.mtime),
+		ArbitraryMetadata: metadata,
+	}
+	if n.parent != "" {
+		ri.ParentId = &provider.ResourceId{OpaqueId: n.parent}
+	}
+	if n.owner.GetOpaqueId() != "" {
+		ri.Owner = n.owner
+	}
+	if fs.conf.ContentAddressed && n.blob != "" {
+		// the key of the blob is the checksum of the content
+		ri.Checksum = &provider.ResourceChecksum{
+			Type: provider.ResourceChecksumType_RESOURCE_CHECKSUM_TYPE_SHA1,
+			Sum:  n.blob,
+		}
+	}
+	return ri, nil
+}
+
+func timestamp(ns int64) *types.Timestamp {
+	return &types.Timestamp{
+		Seconds: uint64(ns / int64(time.Second)),
+		Nanos:   uint32(ns % int64(time.Second)),
+	}
+}
+
+func (fs *indexfs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []string) (*provider.ResourceInfo, error) {
+	n, err := fs.getNode(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.checkPermission(ctx, fs.db, n, func(p *provider.ResourcePermissions) bool { return p.Stat }); err != nil {
+		return nil, err
+	}
+	return fs.convertToResourceInfo(ctx, n, mdKeys, nil)
+}
+
+func (fs *indexfs) ListFolder(ctx context.Context, ref *provider.Reference, mdKeys []string) ([]*provider.ResourceInfo, error) {
+	n, err := fs.getNode(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if !n.isDir {
+		return nil, errtypes.BadRequest("indexfs: " + fs.unwrap(ctx, n.path) + " is not a container")
+	}
+
+	// the permissions on the folder are inherited by its children
+	inherited, err := fs.permissionSet(ctx, fs.db, n, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !inherited.ListContainer {
+		return nil, errtypes.PermissionDenied("indexfs: permission denied on resource " + n.id)
+	}
+
+	children, err := listChildren(ctx, fs.db, n)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*provider.ResourceInfo, 0, len(children))
+	for _, c := range children {
+		ri, err := fs.convertToResourceInfo(ctx, c, mdKeys, inherited)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, ri)
+	}
+	return infos, nil
+}
+
+func (fs *indexfs) GetPathByID(ctx context.Context, id *provider.ResourceId) (string, error) {
+	n, err := getNodeByID(ctx, fs.db, id.GetOpaqueId())
+	if err != nil {
+		return "", err
+	}
+	if n.trashed != "" {
+		return "", errtypes.NotFound(id.GetOpaqueId())
+	}
+	if err := fs.checkPermission(ctx, fs.db, n, func(p *provider.ResourcePermissions) bool { return p.GetPath }); err != nil {
+		return "", err
+	}
+	return fs.unwrap(ctx, n.path), nil
+}
+
+func (fs *indexfs) GetHome(ctx context.Context) (string, error) {
+	if !fs.conf.EnableHome {
+		return "", errtypes.NotSupported("indexfs: get home not supported")
+	}
+	u, err := getUser(ctx)
+	if err != nil {
+		return "", err
+	}
+	return templates.WithUser(u, fs.conf.UserLayout), nil
+}
+
+func (fs *indexfs) CreateHome(ctx context.Context) error {
+	if !fs.conf.EnableHome {
+		return errtypes.NotSupported("indexfs: create home not supported")
+	}
+	u, err := getUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	home := fs.home(ctx)
+	return fs.withTx(ctx, func(tx *sql.Tx) error {
+		parent, err := getNode(ctx, tx, "/")
+		if err != nil {
+			return err
+		}
+		// the intermediate folders of the layout do not belong to anybody
+		var p string
+		for _, name := range strings.Split(strings.TrimPrefix(home, "/"), "/") {
+			p = path.Join("/", p, name)
+			n, err := getNode(ctx, tx, p)
+			if err == nil {
+				parent = n
+				continue
+			}
+			if _, ok := err.(errtypes.IsNotFound); !ok {
+				return err
+			}
+			n = &node{
+				id:     uuid.New().String(),
+				parent: parent.id,
+				name:   name,
+				path:   p,
+				isDir:  true,
+				mtime:  time.Now().UnixNano(),
+				etag:   newEtag(),
+				owner:  &userpb.UserId{},
+			}
+			if p == home {
+				n.owner = ownerID(u)
+			}
+			if err := insertNode(ctx, tx, n); err != nil {
+				return err
+			}
+			parent = n
+		}
+		return nil
+	})
+}
+
+// createNode creates a new node at the given internal path, whose parent must be an existing container.
+func (fs *indexfs) createNode(ctx context.Context, tx *sql.Tx, np string, n *node) error {
+	if np == "/" {
+		return errtypes.AlreadyExists("/")
+	}
+	parent, err := getNode(ctx, tx, path.Dir(np))
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return errtypes.Conflict("indexfs: parent of " + fs.unwrap(ctx, np) + " does not exist")
+		}
+		return err
+	}
+	if !parent.isDir {
+		return errtypes.BadRequest("indexfs: parent of " + fs.unwrap(ctx, np) + " is not a container")
+	}
+	if err := fs.checkPermission(ctx, tx, parent, createPermission(n.isDir)); err != nil {
+		return err
+	}
+
+	u, err := getUser(ctx)
+	if err != nil {
+		return err
+	}
+	n.id = uuid.New().String()
+	n.parent = parent.id
+	n.name = path.Base(np)
+	n.path = np
+	n.etag = newEtag()
+	n.owner = newOwner(parent, u)
+	if n.mtime == 0 {
+		n.mtime = time.Now().UnixNano()
+	}
+
+	if err := insertNode(ctx, tx, n); err != nil {
+		if _, ok := err.(errtypes.IsAlreadyExists); ok {
+			return errtypes.AlreadyExists(fs.unwrap(ctx, np))
+		}
+		return err
+	}
+	return propagate(ctx, tx, np, int64(n.size), n.mtime)
+}
+
+func (fs *indexfs) CreateDir(ctx context.Context, ref *provider.Reference) error {
+	np, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return fs.withTx(ctx, func(tx *sql.Tx) error {
+		return fs.createNode(ctx, tx, np, &node{isDir: true})
+	})
+}
+
+func (fs *indexfs) TouchFile(ctx context.Context, ref *provider.Reference) error {
+	np, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return fs.withTx(ctx, func(tx *sql.Tx) error {
+		return fs.createNode(ctx, tx, np, &node{})
+	})
+}
+
+func (fs *indexfs) Move(ctx context.Context, oldRef, newRef *provider.Reference) error {
+	oldPath, err := fs.resolve(ctx, oldRef)
+	if err != nil {
+		return err
+	}
+	newPath, err := fs.resolve(ctx, newRef)
+	if err != nil {
+		return err
+	}
+	if oldPath == newPath {
+		return nil
+	}
+	if oldPath == fs.home(ctx) || strings.HasPrefix(newPath, strings.TrimSuffix(oldPath, "/")+"/") {
+		return errtypes.BadRequest("indexfs: cannot move " + fs.unwrap(ctx, oldPath) + " to " + fs.unwrap(ctx, newPath))
+	}
+
+	return fs.withTx(ctx, func(tx *sql.Tx) error {
+		n, err := getNode(ctx, tx, oldPath)
+		if err != nil {
+			return errtypes.NotFound(fs.unwrap(ctx, oldPath))
+		}
+		if err := fs.checkPermission(ctx, tx, n, func(p *provider.ResourcePermissions) bool { return p.Move }); err != nil {
+			return err
+		}
+		if _, err := getNode(ctx, tx, newPath); err == nil {
+			return errtypes.AlreadyExists(fs.unwrap(ctx, newPath))
+		}
+		parent, err := getNode(ctx, tx, path.Dir(newPath))
+		if err != nil {
+			return errtypes.Conflict("indexfs: parent of " + fs.unwrap(ctx, newPath) + " does not exist")
+		}
+		if !parent.isDir {
+			return errtypes.BadRequest("indexfs: parent of " + fs.unwrap(ctx, newPath) + " is not a container")
+		}
+		if err := fs.checkPermission(ctx, tx, parent, createPermission(n.isDir)); err != nil {
+			return err
+		}
+
+		cond, args := subtree(oldPath)
+		args = append([]any{newPath, len([]rune(oldPath)) + 1}, args...)
+		if _, err := tx.ExecContext(ctx, "UPDATE nodes SET path = ? || substr(path, ?) WHERE trashed = '' AND "+cond, args...); err != nil {
+			return errors.Wrap(err, "indexfs: error moving "+oldPath)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE nodes SET parent = ?, name = ? WHERE id = ?", parent.id, path.Base(newPath), n.id); err != nil {
+			return errors.Wrap(err, "indexfs: error moving "+oldPath)
+		}
+
+		now := time.Now().UnixNano()
+		if err := propagate(ctx, tx, oldPath, -int64(n.size), now); err != nil {
+			return err
+		}
+		return propagate(ctx, tx, newPath, int64(n.size), now)
+	})
+}
+
+func (fs *indexfs) Download(ctx context.Context, ref *provider.Reference) (io.ReadCloser, error) {
+	n, err := fs.getNode(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if n.isDir {
+		return nil, errtypes.BadRequest("indexfs: cannot download container " + fs.unwrap(ctx, n.path))
+	}
+	if err := fs.checkPermission(ctx, fs.db, n, func(p *provider.ResourcePermissions) bool { return p.InitiateFileDownload }); err != nil {
+		return nil, err
+	}
+	return fs.download(ctx, n.blob)
+}
+
+func (fs *indexfs) download(ctx context.Context, blob string) (io.ReadCloser, error) {
+	if blob == "" {
+		// files created empty have no blob
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	return fs.blobs.Download(ctx, blob)
+}
+
+func (fs *indexfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
+	n, err := fs.getNode(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if err := fs.checkPermission(ctx, fs.db, n, func(p *provider.ResourcePermissions) bool { return p.InitiateFileUpload }); err != nil {
+		return err
+	}
+
+	return fs.withTx(ctx, func(tx *sql.Tx) error {
+		for k, v := range md.GetMetadata() {
+			if k == "mtime" {
+				mtime, err := parseMTime(v)
+				if err != nil {
+					return errors.Wrap(err, "could not parse mtime")
+				}
+				if _, err := tx.ExecContext(ctx, "UPDATE nodes SET mtime = ? WHERE id = ?", mtime.UnixNano(), n.id); err != nil {
+					return errors.Wrap(err, "indexfs: error setting mtime")
+				}
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO metadata (node, key, value) VALUES (?, ?, ?)", n.id, k, v); err != nil {
+				return errors.Wrap(err, "indexfs: error adding entry to DB")
+			}
+		}
+		return fs.touch(ctx, tx, n)
+	})
+}
+
+func (fs *indexfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Reference, keys []string) error {
+	n, err := fs.getNode(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if err := fs.checkPermission(ctx, fs.db, n, func(p *provider.ResourcePermissions) bool { return p.InitiateFileUpload }); err != nil {
+		return err
+	}
+
+	return fs.withTx(ctx, func(tx *sql.Tx) error {
+		for _, k := range keys {
+			if k == "mtime" || k == "etag" {
+				return errors.Wrap(errtypes.NotSupported("unsetting "+k+" not supported"), "could not unset metadata")
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM metadata WHERE node = ? AND key = ?", n.id, k); err != nil {
+				return errors.Wrap(err, "indexfs: error removing entry from DB")
+			}
+		}
+		return fs.touch(ctx, tx, n)
+	})
+}
+
+// touch changes the etag of the node and of its ancestors.
+func (fs *indexfs) touch(ctx context.Context, tx *sql.Tx, n *node) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE nodes SET etag = ? WHERE id = ?", newEtag(), n.id); err != nil {
+		return errors.Wrap(err, "indexfs: error updating etag")
+	}
+	return propagate(ctx, tx, n.path, 0, 0)
+}
+
+func parseMTime(v string) (t time.Time, err error) {
+	p := strings.SplitN(v, ".", 2)
+	var sec, nsec int64
+	if sec, err = strconv.ParseInt(p[0], 10, 64); err == nil {
+		if len(p) > 1 {
+			nsec, err = strconv.ParseInt(p[1], 10, 64)
+		}
+	}
+	return time.Unix(sec, nsec), err
+}
+
+func granteeKey(g *provider.Grantee) (string, error) {
+	switch g.GetType() {
+	case provider.GranteeType_GRANTEE_TYPE_USER:
+		return "u:" + g.GetUserId().GetIdp() + ":" + g.GetUserId().GetOpaqueId(), nil
+	case provider.GranteeType_GRANTEE_TYPE_GROUP:
+		return "g:" + g.GetGroupId().GetIdp() + ":" + g.GetGroupId().GetOpaqueId(), nil
+	}
+	return "", errtypes.BadRequest("indexfs: unsupported grantee type " + g.GetType().String())
+}
+
+func (fs *indexfs) AddGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	return fs.setGrant(ctx, ref, g, func(p *provider.ResourcePermissions) bool { return p.AddGrant })
+}
+
+func (fs *indexfs) UpdateGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	return fs.setGrant(ctx, ref, g, func(p *provider.ResourcePermissions) bool { return p.UpdateGrant })
+}
+
+func (fs *indexfs) setGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant, perm func(*provider.ResourcePermissions) bool) error {
+	n, err := fs.getNode(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if err := fs.checkPermission(ctx, fs.db, n, perm); err != nil {
+		return err
+	}
+	grantee, err := granteeKey(g.Grantee)
+	if err != nil {
+		return err
+	}
+	payload, err := utils.MarshalProtoV1ToJSON(g)
+	if err != nil {
+		return errors.Wrap(err, "indexfs: error encoding grant")
+	}
+	if _, err := fs.db.ExecContext(ctx, "INSERT OR REPLACE INTO grants (node, grantee, payload) VALUES (?, ?, ?)", n.id, grantee, string(payload)); err != nil {
+		return errors.Wrap(err, "indexfs: error adding entry to DB")
+	}
+	return nil
+}
+
+func (fs *indexfs) RemoveGrant(ctx context.Context, ref *provider.Reference, g *provider.Grant) error {
+	n, err := fs.getNode(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if err := fs.checkPermission(ctx, fs.db, n, func(p *provider.ResourcePermissions) bool { return p.RemoveGrant }); err != nil {
+		return err
+	}
+	grantee, err := granteeKey(g.Grantee)
+	if err != nil {
+		return err
+	}
+	if _, err := fs.db.ExecContext(ctx, "DELETE FROM grants WHERE node = ? AND grantee = ?", n.id, grantee); err != nil {
+		return errors.Wrap(err, "indexfs: error removing entry from DB")
+	}
+	return nil
+}
+
+func (fs *indexfs) DenyGrant(ctx context.Context, ref *provider.Reference, g *provider.Grantee) error {
+	return errtypes.NotSupported("indexfs: deny grant not supported")
+}
+
+func (fs *indexfs) ListGrants(ctx context.Context, ref *provider.Reference) ([]*provider.Grant, error) {
+	n, err := fs.getNode(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.checkPermission(ctx, fs.db, n, func(p *provider.ResourcePermissions) bool { return p.ListGrants }); err != nil {
+		return nil, err
+	}
+	rows, err := fs.db.QueryContext(ctx, "SELECT payload FROM grants WHERE node = ? ORDER BY grantee", n.id)
+	if err != nil {
+		return nil, errors.Wrap(err, "indexfs: error listing grants")
+	}
+	defer rows.Close()
+
+	grants := []*provider.Grant{}
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&payload); err != nil {
+			return nil, errors.Wrap(err, "indexfs: error scanning db rows")
+		}
+		g := &provider.Grant{}
+		if err := utils.UnmarshalJSONToProtoV1([]byte(payload), g); err != nil {
+			return nil, errors.Wrap(err, "indexfs: malformed grant")
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+// GetQuota returns the quota of the owner of the referenced resource, or of the
+// user in context if no reference is given, and the space used by their files.
+func (fs *indexfs) GetQuota(ctx context.Context, ref *provider.Reference) (uint64, uint64, error) {
+	var n *node
+	var err error
+	if ref.GetPath() == "" && ref.GetResourceId() == nil {
+		n, err = getNode(ctx, fs.db, fs.home(ctx))
+	} else {
+		n, err = fs.getNode(ctx, ref)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := fs.checkPermission(ctx, fs.db, n, func(p *provider.ResourcePermissions) bool { return p.GetQuota }); err != nil {
+		return 0, 0, err
+	}
+
+	owner := n.owner
+	if owner.GetOpaqueId() == "" {
+		u, err := getUser(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		owner = ownerID(u)
+	}
+	used, err := usage(ctx, fs.db, owner)
+	if err != nil {
+		return 0, 0, err
+	}
+	return fs.conf.Quota, used, nil
+}
+
+func (fs *indexfs) CreateReference(ctx context.Context, p string, targetURI *url.URL) error {
+	return errtypes.NotSupported("indexfs: create reference not supported")
+}
+
+func (fs *indexfs) CreateSymlink(ctx context.Context, ref *provider.Reference, target string) error {
+	return errtypes.NotSupported("indexfs: symlinks not supported")
+}
+
+func (fs *indexfs) SetLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
+	return errtypes.NotSupported("indexfs: locks not supported")
+}
+
+func (fs *indexfs) GetLock(ctx context.Context, ref *provider.Reference) (*provider.Lock, error) {
+	return nil, errtypes.NotSupported("indexfs: locks not supported")
+}
+
+func (fs *indexfs) RefreshLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock, existingLockID string) error {
+	return errtypes.NotSupported("indexfs: locks not supported")
+}
+
+func (fs *indexfs) Unlock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
+	return errtypes.NotSupported("indexfs: locks not supported")
+}
+
+func (fs *indexfs) ListStorageSpaces(ctx context.Context, filter []*provider.ListStorageSpacesRequest_Filter) ([]*provider.StorageSpace, error) {
+	return nil, errtypes.NotSupported("indexfs: storage spaces not supported")
+}
+
+func (fs *indexfs) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
+	return nil, errtypes.NotSupported("indexfs: storage spaces not supported")
+}
+
+func (fs *indexfs) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
+	return nil, errtypes.NotSupported("indexfs: storage spaces not supported")
+}
+
+func (fs *indexfs) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) error {
+	return errtypes.NotSupported("indexfs: storage spaces not supported")
+}
@@ -0,0 +1,322 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package indexfs
+
+import (
+	"context"
+	"database/sql"
+	"path"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// recycleItem is a deleted subtree. Its nodes keep the paths they had
+// when deleted and are marked as trashed with the key of the item.
+type recycleItem struct {
+	key          string
+	node         string
+	path         string
+	deletionTime int64
+}
+
+// binOwner returns the key identifying the recycle bin of the user in context.
+func binOwner(ctx context.Context) (string, error) {
+	u, err := getUser(ctx)
+	if err != nil {
+		return "", err
+	}
+	return u.GetId().GetIdp() + ":" + u.GetId().GetOpaqueId(), nil
+}
+
+func (fs *indexfs) Delete(ctx context.Context, ref *provider.Reference) error {
+	np, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if np == fs.home(ctx) {
+		return errtypes.BadRequest("indexfs: cannot delete the root of the namespace")
+	}
+	owner, err := binOwner(ctx)
+	if err != nil {
+		return err
+	}
+
+	return fs.withTx(ctx, func(tx *sql.Tx) error {
+		n, err := getNode(ctx, tx, np)
+		if err != nil {
+			return errtypes.NotFound(fs.unwrap(ctx, np))
+		}
+		if err := fs.checkPermission(ctx, tx, n, func(p *provider.ResourcePermissions) bool { return p.Delete }); err != nil {
+			return err
+		}
+
+		key := uuid.New().String()
+		cond, args := subtree(np)
+		if _, err := tx.ExecContext(ctx, "UPDATE nodes SET trashed = ? WHERE trashed = '' AND "+cond, append([]any{key}, args...)...); err != nil {
+			return errors.Wrap(err, "indexfs: error trashing "+np)
+		}
+
+		now := time.Now()
+		if _, err := tx.ExecContext(ctx, "INSERT INTO recycle (key, node, path, owner, deletion_time) VALUES (?, ?, ?, ?, ?)",
+			key, n.id, np, owner, now.Unix()); err != nil {
+			return errors.Wrap(err, "indexfs: error adding entry to DB")
+		}
+		return propagate(ctx, tx, np, -int64(n.size), now.UnixNano())
+	})
+}
+
+func (fs *indexfs) getRecycleItem(ctx context.Context, q querier, key string) (*recycleItem, error) {
+	owner, err := binOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	item := &recycleItem{key: key}
+	if err := q.QueryRowContext(ctx, "SELECT node, path, deletion_time FROM recycle WHERE key = ? AND owner = ?", key, owner).
+		Scan(&item.node, &item.path, &item.deletionTime); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errtypes.NotFound("indexfs: recycle item " + key)
+		}
+		return nil, errors.Wrap(err, "indexfs: error reading recycle item "+key)
+	}
+	return item, nil
+}
+
+func (fs *indexfs) listRecycleItems(ctx context.Context, q querier) ([]*recycleItem, error) {
+	owner, err := binOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.QueryContext(ctx, "SELECT key, node, path, deletion_time FROM recycle WHERE owner = ? ORDER BY deletion_time", owner)
+	if err != nil {
+		return nil, errors.Wrap(err, "indexfs: error listing recycle items")
+	}
+	defer rows.Close()
+
+	var items []*recycleItem
+	for rows.Next() {
+		item := &recycleItem{}
+		if err := rows.Scan(&item.key, &item.node, &item.path, &item.deletionTime); err != nil {
+			return nil, errors.Wrap(err, "indexfs: error scanning db rows")
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (fs *indexfs) convertToRecycleItem(ctx context.Context, key string, n *node, deletionTime int64) *provider.RecycleItem {
+	return &provider.RecycleItem{
+		Type:         getResourceType(n.isDir),
+		Key:          key,
+		Ref:          &provider.Reference{Path: fs.unwrap(ctx, n.path)},
+		Size:         n.size,
+		DeletionTime: &types.Timestamp{Seconds: uint64(deletionTime)},
+	}
+}
+
+// ListRecycle lists the items in the recycle bin of the user in context deleted between
+// from and to, or the content of a deleted folder if key and relativePath are given.
+func (fs *indexfs) ListRecycle(ctx context.Context, basePath, key, relativePath string, from, to *types.Timestamp) ([]*provider.RecycleItem, error) {
+	if key == "" {
+		items, err := fs.listRecycleItems(ctx, fs.db)
+		if err != nil {
+			return nil, err
+		}
+		list := []*provider.RecycleItem{}
+		for _, item := range items {
+			if from != nil && item.deletionTime < int64(from.Seconds) {
+				continue
+			}
+			if to != nil && item.deletionTime > int64(to.Seconds) {
+				continue
+			}
+			n, err := getNodeByID(ctx, fs.db, item.node)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, fs.convertToRecycleItem(ctx, item.key, n, item.deletionTime))
+		}
+		return list, nil
+	}
+
+	item, err := fs.getRecycleItem(ctx, fs.db, key)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := scanNode(fs.db.QueryRowContext(ctx, "SELECT "+nodeColumns+" FROM nodes WHERE path = ? AND trashed = ?",
+		path.Join(item.path, relativePath), key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errtypes.NotFound(path.Join(key, relativePath))
+		}
+		return nil, errors.Wrap(err, "indexfs: error reading recycle item "+key)
+	}
+	if !parent.isDir {
+		return []*provider.RecycleItem{fs.convertToRecycleItem(ctx, path.Join(key, relativePath), parent, item.deletionTime)}, nil
+	}
+
+	children, err := queryNodes(ctx, fs.db, "parent = ? AND trashed = ? ORDER BY name", parent.id, key)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*provider.RecycleItem, 0, len(children))
+	for _, c := range children {
+		list = append(list, fs.convertToRecycleItem(ctx, path.Join(key, relativePath, c.name), c, item.deletionTime))
+	}
+	return list, nil
+}
+
+// RestoreRecycleItem restores a deleted resource to its original location,
+// or to restoreRef if given. Only whole items can be restored.
+func (fs *indexfs) RestoreRecycleItem(ctx context.Context, basePath, key, relativePath string, restoreRef *provider.Reference) error {
+	if path.Join("/", relativePath) != "/" {
+		return errtypes.NotSupported("indexfs: restoring part of a recycle item not supported")
+	}
+
+	var dst string
+	if restoreRef != nil && (restoreRef.Path != "" || restoreRef.ResourceId != nil) {
+		var err error
+		if dst, err = fs.resolve(ctx, restoreRef); err != nil {
+			return err
+		}
+	}
+
+	return fs.withTx(ctx, func(tx *sql.Tx) error {
+		item, err := fs.getRecycleItem(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		n, err := getNodeByID(ctx, tx, item.node)
+		if err != nil {
+			return err
+		}
+		if dst == "" {
+			dst = item.path
+		}
+
+		if _, err := getNode(ctx, tx, dst); err == nil {
+			return errtypes.AlreadyExists(fs.unwrap(ctx, dst))
+		}
+		parent, err := getNode(ctx, tx, path.Dir(dst))
+		if err != nil {
+			return errtypes.Conflict("indexfs: parent of " + fs.unwrap(ctx, dst) + " does not exist")
+		}
+		if !parent.isDir {
+			return errtypes.BadRequest("indexfs: parent of " + fs.unwrap(ctx, dst) + " is not a container")
+		}
+		if err := fs.checkPermission(ctx, tx, parent, createPermission(n.isDir)); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE nodes SET path = ? || substr(path, ?), trashed = '' WHERE trashed = ?",
+			dst, len([]rune(item.path))+1, key); err != nil {
+			return errors.Wrap(err, "indexfs: error restoring "+key)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE nodes SET parent = ?, name = ? WHERE id = ?", parent.id, path.Base(dst), n.id); err != nil {
+			return errors.Wrap(err, "indexfs: error restoring "+key)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM recycle WHERE key = ?", key); err != nil {
+			return errors.Wrap(err, "indexfs: error removing entry from DB")
+		}
+		return propagate(ctx, tx, dst, int64(n.size), time.Now().UnixNano())
+	})
+}
+
+// PurgeRecycleItem removes for good a deleted resource, its versions and its content.
+func (fs *indexfs) PurgeRecycleItem(ctx context.Context, basePath, key, relativePath string) error {
+	if path.Join("/", relativePath) != "/" {
+		return errtypes.NotSupported("indexfs: purging part of a recycle item not supported")
+	}
+
+	var blobs []string
+	err := fs.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := fs.getRecycleItem(ctx, tx, key); err != nil {
+			return err
+		}
+
+		var err error
+		if blobs, err = purgeNodes(ctx, tx, key); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM recycle WHERE key = ?", key); err != nil {
+			return errors.Wrap(err, "indexfs: error removing entry from DB")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fs.collect(ctx, blobs)
+	return nil
+}
+
+// purgeNodes removes the nodes trashed with the given key together with their
+// metadata, grants and versions, releasing their references to the blobs,
+// and returns the blobs that may be collected.
+func purgeNodes(ctx context.Context, tx *sql.Tx, key string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT blob FROM nodes WHERE trashed = ? AND blob != ''
+		UNION ALL SELECT v.blob FROM versions v JOIN nodes n ON v.node = n.id WHERE n.trashed = ? AND v.blob != ''`, key, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "indexfs: error listing blobs")
+	}
+	var blobs []string
+	for rows.Next() {
+		var blob string
+		if err := rows.Scan(&blob); err != nil {
+			rows.Close()
+			return nil, errors.Wrap(err, "indexfs: error scanning db rows")
+		}
+		blobs = append(blobs, blob)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "indexfs: error listing blobs")
+	}
+	if err := releaseRefs(ctx, tx, blobs); err != nil {
+		return nil, err
+	}
+
+	for _, table := range []string{"metadata", "grants", "versions"} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE node IN (SELECT id FROM nodes WHERE trashed = ?)", key); err != nil {
+			return nil, errors.Wrap(err, "indexfs: error removing entries from DB")
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM nodes WHERE trashed = ?", key); err != nil {
+		return nil, errors.Wrap(err, "indexfs: error removing entries from DB")
+	}
+	return blobs, nil
+}
+
+// EmptyRecycle purges all the items in the recycle bin of the user in context.
+func (fs *indexfs) EmptyRecycle(ctx context.Context) error {
+	items, err := fs.listRecycleItems(ctx, fs.db)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := fs.PurgeRecycleItem(ctx, "", item.key, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package indexfs
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type upload struct {
+	id     string
+	path   string
+	length int64
+	mtime  string
+}
+
+// InitiateUpload registers an upload to the given reference,
+// to be completed through the simple protocol.
+func (fs *indexfs) InitiateUpload(ctx context.Context, ref *provider.Reference, uploadLength int64, metadata map[string]string) (map[string]string, error) {
+	np, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	u, err := getUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// fail early if the upload cannot succeed, this is checked again when the upload completes
+	parent, err := getNode(ctx, fs.db, path.Dir(np))
+	if err != nil {
+		if _, ok := err.(errtypes.IsNotFound); ok {
+			return nil, errtypes.Conflict("indexfs: parent of " + fs.unwrap(ctx, np) + " does not exist")
+		}
+		return nil, err
+	}
+	if !parent.isDir {
+		return nil, errtypes.BadRequest("indexfs: parent of " + fs.unwrap(ctx, np) + " is not a container")
+	}
+	// the file is written with the permissions on it if it exists, and on its parent otherwise
+	target, owner := parent, newOwner(parent, u)
+	if n, err := getNode(ctx, fs.db, np); err == nil {
+		if n.isDir {
+			return nil, errtypes.BadRequest("indexfs: cannot upload to container " + fs.unwrap(ctx, np))
+		}
+		target, owner = n, n.owner
+	}
+	if err := fs.checkPermission(ctx, fs.db, target, func(p *provider.ResourcePermissions) bool { return p.InitiateFileUpload }); err != nil {
+		return nil, err
+	}
+	if err := fs.checkQuota(ctx, owner, uploadLength); err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	if _, err := fs.db.ExecContext(ctx, "INSERT INTO uploads (id, path, length, mtime, owner_idp, owner_id, created) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		id, np, uploadLength, metadata["mtime"], u.GetId().GetIdp(), u.GetId().GetOpaqueId(), time.Now().Unix()); err != nil {
+		return nil, errors.Wrap(err, "indexfs: error adding entry to DB")
+	}
+
+	return map[string]string{
+		"simple": id,
+	}, nil
+}
+
+// checkQuota fails if the owner has no room left for length more bytes.
+func (fs *indexfs) checkQuota(ctx context.Context, owner *userpb.UserId, length int64) error {
+	if fs.conf.Quota == 0 {
+		return nil
+	}
+	used, err := usage(ctx, fs.db, owner)
+	if err != nil {
+		return err
+	}
+	if used+uint64(length) > fs.conf.Quota {
+		return errtypes.InsufficientStorage("indexfs: quota exceeded")
+	}
+	return nil
+}
+
+func (fs *indexfs) getUpload(ctx context.Context, id string) (*upload, error) {
+	u, err := getUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	up := &upload{id: id}
+	if err := fs.db.QueryRowContext(ctx, "SELECT path, length, mtime FROM uploads WHERE id = ? AND owner_idp = ? AND owner_id = ?",
+		id, u.GetId().GetIdp(), u.GetId().GetOpaqueId()).Scan(&up.path, &up.length, &up.mtime); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errtypes.NotFound("indexfs: upload " + id)
+		}
+		return nil, errors.Wrap(err, "indexfs: error reading upload "+id)
+	}
+	return up, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Upload completes the upload with the given id, passed as the path of the reference.
+// The content is stored in a blob, shared with the files having the same content
+// in content addressed mode, and the previous content of the file is kept as a version.
+func (fs *indexfs) Upload(ctx context.Context, ref *provider.Reference, r io.ReadCloser, metadata map[string]string) error {
+	defer r.Close()
+
+	up, err := fs.getUpload(ctx, strings.TrimPrefix(ref.GetPath(), "/"))
+	if err != nil {
+		return err
+	}
+
+	mtime := time.Now().UnixNano()
+	if up.mtime != "" {
+		t, err := parseMTime(up.mtime)
+		if err != nil {
+			return errors.Wrap(err, "could not parse mtime")
+		}
+		mtime = t.UnixNano()
+	}
+
+	cr := &countingReader{r: r}
+	var content io.Reader = cr
+	blob := uuid.New().String()
+	if fs.conf.ContentAddressed {
+		// the key of the blob is only known once the whole content is read
+		f, sum, err := fs.spool(ctx, up.id, cr)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		content, blob = f, sum
+	}
+
+	// the blob cannot be collected until it is referenced by the node
+	fs.pin(blob)
+	err = fs.commitUpload(ctx, up, blob, content, cr, mtime)
+	fs.unpin(blob)
+	if err != nil {
+		fs.collect(ctx, []string{blob})
+	}
+	return err
+}
+
+func (fs *indexfs) commitUpload(ctx context.Context, up *upload, blob string, content io.Reader, cr *countingReader, mtime int64) error {
+	if err := fs.storeBlob(ctx, blob, content); err != nil {
+		return err
+	}
+	if up.length > 0 && cr.n != up.length {
+		return errtypes.BadRequest("indexfs: expected " + strconv.FormatInt(up.length, 10) + " bytes, got " + strconv.FormatInt(cr.n, 10))
+	}
+
+	return fs.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM uploads WHERE id = ?", up.id); err != nil {
+			return errors.Wrap(err, "indexfs: error removing entry from DB")
+		}
+		if err := addRef(ctx, tx, blob); err != nil {
+			return err
+		}
+
+		n, err := getNode(ctx, tx, up.path)
+		if err != nil {
+			if _, ok := err.(errtypes.IsNotFound); !ok {
+				return err
+			}
+			return fs.createNode(ctx, tx, up.path, &node{size: uint64(cr.n), mtime: mtime, blob: blob})
+		}
+		if n.isDir {
+			return errtypes.BadRequest("indexfs: cannot upload to container " + fs.unwrap(ctx, up.path))
+		}
+		if err := fs.checkPermission(ctx, tx, n, func(p *provider.ResourcePermissions) bool { return p.InitiateFileUpload }); err != nil {
+			return err
+		}
+
+		if err := archiveVersion(ctx, tx, n); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE nodes SET size = ?, mtime = ?, etag = ?, blob = ? WHERE id = ?",
+			cr.n, mtime, newEtag(), blob, n.id); err != nil {
+			return errors.Wrap(err, "indexfs: error updating node "+up.path)
+		}
+		return propagate(ctx, tx, up.path, cr.n-int64(n.size), mtime)
+	})
+}
+
+// archiveVersion keeps the current content of the file as a version,
+// which takes over the reference of the node to its blob.
+func archiveVersion(ctx context.Context, tx *sql.Tx, n *node) error {
+	if n.blob == "" {
+		// nothing worth keeping
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO versions (node, key, blob, size, mtime, etag) VALUES (?, ?, ?, ?, ?, ?)",
+		n.id, uuid.New().String(), n.blob, n.size, n.mtime, n.etag); err != nil {
+		return errors.Wrap(err, "indexfs: error adding version of "+n.path)
+	}
+	return nil
+}
+
+func (fs *indexfs) ListRevisions(ctx context.Context, ref *provider.Reference) ([]*provider.FileVersion, error) {
+	n, err := fs.getNode(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.checkPermission(ctx, fs.db, n, func(p *provider.ResourcePermissions) bool { return p.ListFileVersions }); err != nil {
+		return nil, err
+	}
+
+	rows, err := fs.db.QueryContext(ctx, "SELECT key, size, mtime, etag FROM versions WHERE node = ? ORDER BY mtime", n.id)
+	if err != nil {
+		return nil, errors.Wrap(err, "indexfs: error listing versions")
+	}
+	defer rows.Close()
+
+	revisions := []*provider.FileVersion{}
+	for rows.Next() {
+		var key, etag string
+		var size uint64
+		var mtime int64
+		if err := rows.Scan(&key, &size, &mtime, &etag); err != nil {
+			return nil, errors.Wrap(err, "indexfs: error scanning db rows")
+		}
+		revisions = append(revisions, &provider.FileVersion{
+			Key:   key,
+			Size:  size,
+			Mtime: uint64(mtime / int64(time.Second)),
+			Etag:  `"` + etag + `"`,
+		})
+	}
+	return revisions, rows.Err()
+}
+
+func getVersion(ctx context.Context, q querier, n *node, key string) (*node, error) {
+	v := *n
+	if err := q.QueryRowContext(ctx, "SELECT blob, size, mtime, etag FROM versions WHERE node = ? AND key = ?", n.id, key).
+		Scan(&v.blob, &v.size, &v.mtime, &v.etag); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errtypes.NotFound("indexfs: version " + key + " of " + n.path)
+		}
+		return nil, errors.Wrap(err, "indexfs: error reading version "+key)
+	}
+	return &v, nil
+}
+
+func (fs *indexfs) DownloadRevision(ctx context.Context, ref *provider.Reference, key string) (io.ReadCloser, error) {
+	n, err := fs.getNode(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.checkPermission(ctx, fs.db, n, func(p *provider.ResourcePermissions) bool { return p.InitiateFileDownload }); err != nil {
+		return nil, err
+	}
+	v, err := getVersion(ctx, fs.db, n, key)
+	if err != nil {
+		return nil, err
+	}
+	return fs.download(ctx, v.blob)
+}
+
+// RestoreRevision makes the given version the current content of the file,
+// the current content being kept as a version.
+func (fs *indexfs) RestoreRevision(ctx context.Context, ref *provider.Reference, key string) error {
+	np, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	return fs.withTx(ctx, func(tx *sql.Tx) error {
+		n, err := getNode(ctx, tx, np)
+		if err != nil {
+			return errtypes.NotFound(fs.unwrap(ctx, np))
+		}
+		if err := fs.checkPermission(ctx, tx, n, func(p *provider.ResourcePermissions) bool { return p.RestoreFileVersion }); err != nil {
+			return err
+		}
+		v, err := getVersion(ctx, tx, n, key)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM versions WHERE node = ? AND key = ?", n.id, key); err != nil {
+			return errors.Wrap(err, "indexfs: error removing entry from DB")
+		}
+		if err := archiveVersion(ctx, tx, n); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE nodes SET size = ?, mtime = ?, etag = ?, blob = ? WHERE id = ?",
+			v.size, v.mtime, newEtag(), v.blob, n.id); err != nil {
+			return errors.Wrap(err, "indexfs: error updating node "+np)
+		}
+		return propagate(ctx, tx, np, int64(v.size)-int64(n.size), time.Now().UnixNano())
+	})
+}
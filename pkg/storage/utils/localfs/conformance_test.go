@@ -0,0 +1,41 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage/fstest"
+)
+
+func TestConformance(t *testing.T) {
+	fstest.Run(t, func(t *testing.T) *fstest.Driver {
+		fs, err := NewLocalFS(&Config{Root: t.TempDir()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := appctx.ContextSetUser(context.Background(), einstein)
+		if err := fs.CreateHome(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return &fstest.Driver{FS: fs, Owner: ctx, Grantee: marie}
+	})
+}
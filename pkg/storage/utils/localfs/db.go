@@ -22,6 +22,8 @@ import (
 	"context"
 	"database/sql"
 	"path"
+	"strings"
+	"unicode/utf8"
 
 	// Provides sqlite drivers.
 	_ "github.com/mattn/go-sqlite3"
@@ -71,6 +73,24 @@ func initializeDB(root, dbName string) (*sql.DB, error) {
 		return nil, errors.Wrap(err, "localfs: error executing create statement")
 	}
 
+	stmt, err = db.Prepare("CREATE TABLE IF NOT EXISTS locks (resource TEXT PRIMARY KEY, lock_id TEXT, payload TEXT, expiration INTEGER DEFAULT 0)")
+	if err != nil {
+		return nil, errors.Wrap(err, "localfs: error preparing statement")
+	}
+	_, err = stmt.Exec()
+	if err != nil {
+		return nil, errors.Wrap(err, "localfs: error executing create statement")
+	}
+
+	stmt, err = db.Prepare("CREATE TABLE IF NOT EXISTS spaces (id TEXT PRIMARY KEY, space_type TEXT, name TEXT, description TEXT DEFAULT '', owner TEXT, path TEXT, resource TEXT UNIQUE, quota INTEGER DEFAULT 0, trashed TEXT DEFAULT '')")
+	if err != nil {
+		return nil, errors.Wrap(err, "localfs: error preparing statement")
+	}
+	_, err = stmt.Exec()
+	if err != nil {
+		return nil, errors.Wrap(err, "localfs: error executing create statement")
+	}
+
 	return db, nil
 }
 
@@ -243,5 +263,182 @@ func (fs *localfs) copyMD(s string, t string) (err error) {
 	if err != nil {
 		return errors.Wrap(err, "localfs: error executing delete statement")
 	}
+
+	// locks held on children of a moved folder follow the folder
+	stmt, err = fs.db.Prepare("UPDATE locks SET resource=? || substr(resource, ?) WHERE resource=? OR resource LIKE ? ESCAPE '\\'")
+	if err != nil {
+		return errors.Wrap(err, "localfs: error preparing statement")
+	}
+	_, err = stmt.Exec(t, utf8.RuneCountInString(s)+1, s, escapeLike(s)+"/%")
+	if err != nil {
+		return errors.Wrap(err, "localfs: error executing update statement")
+	}
+	return nil
+}
+
+func (fs *localfs) addToLocksDB(ctx context.Context, resource, lockID, payload string, expiration int64) (bool, error) {
+	stmt, err := fs.db.Prepare("INSERT INTO locks (resource, lock_id, payload, expiration) VALUES (?, ?, ?, ?) ON CONFLICT(resource) DO NOTHING")
+	if err != nil {
+		return false, errors.Wrap(err, "localfs: error preparing statement")
+	}
+	res, err := stmt.Exec(resource, lockID, payload, expiration)
+	if err != nil {
+		return false, errors.Wrap(err, "localfs: error executing insert statement")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "localfs: error getting affected rows")
+	}
+	return n == 1, nil
+}
+
+func (fs *localfs) updateLockDB(ctx context.Context, resource, oldLockID, lockID, payload string, expiration int64) (bool, error) {
+	stmt, err := fs.db.Prepare("UPDATE locks SET lock_id=?, payload=?, expiration=? WHERE resource=? AND lock_id=?")
+	if err != nil {
+		return false, errors.Wrap(err, "localfs: error preparing statement")
+	}
+	res, err := stmt.Exec(lockID, payload, expiration, resource, oldLockID)
+	if err != nil {
+		return false, errors.Wrap(err, "localfs: error executing update statement")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "localfs: error getting affected rows")
+	}
+	return n == 1, nil
+}
+
+func (fs *localfs) getLockEntry(ctx context.Context, resource string) (string, error) {
+	var payload string
+	err := fs.db.QueryRow("SELECT payload FROM locks WHERE resource=?", resource).Scan(&payload)
+	if err != nil {
+		return "", err
+	}
+	return payload, nil
+}
+
+// getLockEntries returns the locks held on the resource and on all its children.
+func (fs *localfs) getLockEntries(ctx context.Context, resource string) (*sql.Rows, error) {
+	locks, err := fs.db.Query("SELECT resource, payload FROM locks WHERE resource=? OR resource LIKE ? ESCAPE '\\'", resource, escapeLike(resource)+"/%")
+	if err != nil {
+		return nil, err
+	}
+	return locks, nil
+}
+
+// getChildLockEntries returns the locks held on the direct children of the resource.
+func (fs *localfs) getChildLockEntries(ctx context.Context, resource string) (*sql.Rows, error) {
+	prefix := escapeLike(resource)
+	locks, err := fs.db.Query("SELECT resource, payload FROM locks WHERE resource LIKE ? ESCAPE '\\' AND resource NOT LIKE ? ESCAPE '\\'", prefix+"/%", prefix+"/%/%")
+	if err != nil {
+		return nil, err
+	}
+	return locks, nil
+}
+
+func (fs *localfs) removeFromLocksDB(ctx context.Context, resource string) error {
+	stmt, err := fs.db.Prepare("DELETE FROM locks WHERE resource=?")
+	if err != nil {
+		return errors.Wrap(err, "localfs: error preparing statement")
+	}
+	_, err = stmt.Exec(resource)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error executing delete statement")
+	}
+	return nil
+}
+
+// removeTreeFromLocksDB removes the locks held on the resource and on all its children.
+func (fs *localfs) removeTreeFromLocksDB(ctx context.Context, resource string) error {
+	stmt, err := fs.db.Prepare("DELETE FROM locks WHERE resource=? OR resource LIKE ? ESCAPE '\\'")
+	if err != nil {
+		return errors.Wrap(err, "localfs: error preparing statement")
+	}
+	_, err = stmt.Exec(resource, escapeLike(resource)+"/%")
+	if err != nil {
+		return errors.Wrap(err, "localfs: error executing delete statement")
+	}
+	return nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+func (fs *localfs) addToSpacesDB(ctx context.Context, id, spaceType, name, description, owner, p, resource string, quota uint64) (bool, error) {
+	stmt, err := fs.db.Prepare("INSERT INTO spaces (id, space_type, name, description, owner, path, resource, quota) VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING")
+	if err != nil {
+		return false, errors.Wrap(err, "localfs: error preparing statement")
+	}
+	res, err := stmt.Exec(id, spaceType, name, description, owner, p, resource, quota)
+	if err != nil {
+		return false, errors.Wrap(err, "localfs: error executing insert statement")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "localfs: error getting affected rows")
+	}
+	return n == 1, nil
+}
+
+func (fs *localfs) updateSpaceDB(ctx context.Context, id, name, description string, quota uint64) error {
+	stmt, err := fs.db.Prepare("UPDATE spaces SET name=?, description=?, quota=? WHERE id=?")
+	if err != nil {
+		return errors.Wrap(err, "localfs: error preparing statement")
+	}
+	_, err = stmt.Exec(name, description, quota, id)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error executing update statement")
+	}
+	return nil
+}
+
+func (fs *localfs) getSpaces(ctx context.Context) (*sql.Rows, error) {
+	spaces, err := fs.db.Query("SELECT id, space_type, name, description, owner, path, resource, quota, trashed FROM spaces")
+	if err != nil {
+		return nil, err
+	}
+	return spaces, nil
+}
+
+func (fs *localfs) updateSpaceTrashDB(ctx context.Context, id, trashed string) error {
+	stmt, err := fs.db.Prepare("UPDATE spaces SET trashed=? WHERE id=?")
+	if err != nil {
+		return errors.Wrap(err, "localfs: error preparing statement")
+	}
+	_, err = stmt.Exec(trashed, id)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error executing update statement")
+	}
+	return nil
+}
+
+// removeFromSpacesDB removes the space together with the metadata
+// of all the resources it contains.
+func (fs *localfs) removeFromSpacesDB(ctx context.Context, id, resource string) (err error) {
+	tx, err := fs.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "localfs: error starting transaction")
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	pattern := escapeLike(resource) + "/%"
+	for _, table := range []string{"user_interaction", "metadata", "share_references", "locks"} {
+		if _, err = tx.Exec("DELETE FROM "+table+" WHERE resource=? OR resource LIKE ? ESCAPE '\\'", resource, pattern); err != nil {
+			return errors.Wrap(err, "localfs: error executing delete statement")
+		}
+	}
+	if _, err = tx.Exec("DELETE FROM spaces WHERE id=?", id); err != nil {
+		return errors.Wrap(err, "localfs: error executing delete statement")
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "localfs: error committing transaction")
+	}
 	return nil
 }
@@ -29,6 +29,7 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	grouppb "github.com/cs3org/go-cs3apis/cs3/identity/group/v1beta1"
@@ -55,9 +56,11 @@ type Config struct {
 	UserLayout          string `mapstructure:"user_layout"`
 	ShareFolder         string `mapstructure:"share_folder"`
 	DataTransfersFolder string `mapstructure:"data_transfers_folder"`
+	ProjectsFolder      string `mapstructure:"projects_folder"`
 	Uploads             string `mapstructure:"uploads"`
 	DataDirectory       string `mapstructure:"data_directory"`
 	RecycleBin          string `mapstructure:"recycle_bin"`
+	SpacesTrash         string `mapstructure:"spaces_trash"`
 	Versions            string `mapstructure:"versions"`
 	Shadow              string `mapstructure:"shadow"`
 	References          string `mapstructure:"references"`
@@ -80,8 +83,13 @@ func (c *Config) ApplyDefaults() {
 		c.DataTransfersFolder = "/DataTransfers"
 	}
 
+	if c.ProjectsFolder == "" {
+		c.ProjectsFolder = "/projects"
+	}
+
 	// ensure share folder always starts with slash
 	c.ShareFolder = path.Join("/", c.ShareFolder)
+	c.ProjectsFolder = path.Join("/", c.ProjectsFolder)
 
 	c.DataDirectory = path.Join(c.Root, "data")
 	c.Uploads = path.Join(c.Root, ".uploads")
@@ -89,6 +97,7 @@ func (c *Config) ApplyDefaults() {
 
 	c.References = path.Join(c.Shadow, "references")
 	c.RecycleBin = path.Join(c.Shadow, "recycle_bin")
+	c.SpacesTrash = path.Join(c.Shadow, "spaces_trash")
 	c.Versions = path.Join(c.Shadow, "versions")
 }
 
@@ -96,6 +105,7 @@ type localfs struct {
 	conf         *Config
 	db           *sql.DB
 	chunkHandler *chunking.ChunkHandler
+	usage        sync.Map // space root -> spaceUsage
 }
 
 // NewLocalFS returns a storage.FS interface implementation that controls then
@@ -104,7 +114,7 @@ func NewLocalFS(c *Config) (storage.FS, error) {
 	c.ApplyDefaults()
 
 	// create namespaces if they do not exist
-	namespaces := []string{c.DataDirectory, c.Uploads, c.Shadow, c.References, c.RecycleBin, c.Versions}
+	namespaces := []string{c.DataDirectory, c.Uploads, c.Shadow, c.References, c.RecycleBin, c.SpacesTrash, c.Versions}
 	for _, v := range namespaces {
 		if err := os.MkdirAll(v, 0755); err != nil {
 			return nil, errors.Wrap(err, "could not create home dir "+v)
@@ -333,6 +343,16 @@ func (fs *localfs) permissionSet(ctx context.Context, owner *userpb.UserId) *pro
 }
 
 func (fs *localfs) normalize(ctx context.Context, fi os.FileInfo, fn string, mdKeys []string) (*provider.ResourceInfo, error) {
+	lock, err := fs.getLock(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	return fs.normalizeWithLock(ctx, fi, fn, mdKeys, lock)
+}
+
+// normalizeWithLock is like normalize for a resource whose lock was already
+// loaded, as when listing a folder.
+func (fs *localfs) normalizeWithLock(ctx context.Context, fi os.FileInfo, fn string, mdKeys []string, lock *provider.Lock) (*provider.ResourceInfo, error) {
 	fp := fs.unwrap(ctx, path.Join("/", fn))
 	owner, err := getUser(ctx)
 	if err != nil {
@@ -365,6 +385,16 @@ func (fs *localfs) normalize(ctx context.Context, fi os.FileInfo, fn string, mdK
 		},
 		Owner:             owner.Id,
 		ArbitraryMetadata: metadata,
+		Lock:              lock,
+	}
+
+	if fi.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(fn)
+		if err != nil {
+			return nil, errors.Wrap(err, "localfs: error reading symlink "+fn)
+		}
+		md.Type = provider.ResourceType_RESOURCE_TYPE_SYMLINK
+		md.Target = target
 	}
 
 	return md, nil
@@ -437,6 +467,21 @@ func (fs *localfs) GetPathByID(ctx context.Context, ref *provider.ResourceId) (s
 	return url.QueryUnescape(strings.TrimPrefix(ref.OpaqueId, "fileid-"+layout))
 }
 
+// granteeKey returns the key identifying the grantee in the grants table.
+func granteeKey(g *provider.Grantee) (string, error) {
+	granteeType, err := grants.GetACLType(g.Type)
+	if err != nil {
+		return "", errors.Wrap(err, "localfs: error getting grantee type")
+	}
+	var grantee string
+	if granteeType == acl.TypeUser {
+		grantee = fmt.Sprintf("%s:%s:%s@%s", granteeType, g.GetUserId().OpaqueId, utils.UserTypeToString(g.GetUserId().Type), g.GetUserId().Idp)
+	} else if granteeType == acl.TypeGroup {
+		grantee = fmt.Sprintf("%s::%s@%s", granteeType, g.GetGroupId().OpaqueId, g.GetGroupId().Idp)
+	}
+	return grantee, nil
+}
+
 func (fs *localfs) DenyGrant(ctx context.Context, ref *provider.Reference, g *provider.Grantee) error {
 	return errtypes.NotSupported("localfs: deny grant not supported")
 }
@@ -453,15 +498,9 @@ func (fs *localfs) AddGrant(ctx context.Context, ref *provider.Reference, g *pro
 		return errors.Wrap(err, "localfs: unknown set permissions")
 	}
 
-	granteeType, err := grants.GetACLType(g.Grantee.Type)
+	grantee, err := granteeKey(g.Grantee)
 	if err != nil {
-		return errors.Wrap(err, "localfs: error getting grantee type")
-	}
-	var grantee string
-	if granteeType == acl.TypeUser {
-		grantee = fmt.Sprintf("%s:%s:%s@%s", granteeType, g.Grantee.GetUserId().OpaqueId, utils.UserTypeToString(g.Grantee.GetUserId().Type), g.Grantee.GetUserId().Idp)
-	} else if granteeType == acl.TypeGroup {
-		grantee = fmt.Sprintf("%s::%s@%s", granteeType, g.Grantee.GetGroupId().OpaqueId, g.Grantee.GetGroupId().Idp)
+		return err
 	}
 
 	err = fs.addToACLDB(ctx, fn, grantee, role)
@@ -491,11 +530,22 @@ func (fs *localfs) ListGrants(ctx context.Context, ref *provider.Reference) ([]*
 		if err != nil {
 			return nil, errors.Wrap(err, "localfs: error scanning db rows")
 		}
-		grantSplit := strings.Split(granteeID, ":")
+		// removed grants and favorites are kept in the same table with an empty role
+		if role == "" {
+			continue
+		}
+		// users are stored as u:<id>:<type>@<idp>, groups as g::<id>@<idp>
+		grantSplit := strings.SplitN(granteeID, ":", 3)
+		if len(grantSplit) != 3 {
+			continue
+		}
 		grantee := &provider.Grantee{Type: grants.GetGranteeType(grantSplit[0])}
-		parts := strings.Split(grantSplit[2], "@")
+		parts := strings.SplitN(grantSplit[2], "@", 2)
+		if len(parts) != 2 {
+			continue
+		}
 		if grantSplit[0] == acl.TypeUser {
-			grantee.Id = &provider.Grantee_UserId{UserId: &userpb.UserId{OpaqueId: parts[0], Idp: parts[1], Type: utils.UserTypeMap(grantSplit[1])}}
+			grantee.Id = &provider.Grantee_UserId{UserId: &userpb.UserId{OpaqueId: grantSplit[1], Idp: parts[1], Type: utils.UserTypeMap(parts[0])}}
 		} else if grantSplit[0] == acl.TypeGroup {
 			grantee.Id = &provider.Grantee_GroupId{GroupId: &grouppb.GroupId{OpaqueId: parts[0], Idp: parts[1]}}
 		}
@@ -516,15 +566,9 @@ func (fs *localfs) RemoveGrant(ctx context.Context, ref *provider.Reference, g *
 	}
 	fn = fs.wrap(ctx, fn)
 
-	granteeType, err := grants.GetACLType(g.Grantee.Type)
+	grantee, err := granteeKey(g.Grantee)
 	if err != nil {
-		return errors.Wrap(err, "localfs: error getting grantee type")
-	}
-	var grantee string
-	if granteeType == acl.TypeUser {
-		grantee = fmt.Sprintf("%s:%s@%s", granteeType, g.Grantee.GetUserId().OpaqueId, g.Grantee.GetUserId().Idp)
-	} else if granteeType == acl.TypeGroup {
-		grantee = fmt.Sprintf("%s:%s@%s", granteeType, g.Grantee.GetGroupId().OpaqueId, g.Grantee.GetGroupId().Idp)
+		return err
 	}
 
 	err = fs.removeFromACLDB(ctx, fn, grantee)
@@ -565,11 +609,6 @@ func (fs *localfs) CreateReference(ctx context.Context, path string, targetURI *
 	return fs.propagate(ctx, fn)
 }
 
-// CreateStorageSpace creates a storage space.
-func (fs *localfs) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
-	return nil, fmt.Errorf("unimplemented: CreateStorageSpace")
-}
-
 func (fs *localfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Reference, md *provider.ArbitraryMetadata) error {
 	np, err := fs.resolve(ctx, ref)
 	if err != nil {
@@ -584,6 +623,9 @@ func (fs *localfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Refer
 		np = fs.wrapReferences(ctx, np)
 	} else {
 		np = fs.wrap(ctx, np)
+		if err := fs.checkNamespace(ctx, np); err != nil {
+			return err
+		}
 	}
 
 	fi, err := os.Stat(np)
@@ -594,6 +636,10 @@ func (fs *localfs) SetArbitraryMetadata(ctx context.Context, ref *provider.Refer
 		return errors.Wrap(err, "localfs: error stating "+np)
 	}
 
+	if err := fs.checkLock(ctx, np, lockIDFromContext(ctx), ""); err != nil {
+		return err
+	}
+
 	if md.Metadata != nil {
 		if val, ok := md.Metadata["mtime"]; ok {
 			if mtime, err := parseMTime(val); err == nil {
@@ -670,6 +716,9 @@ func (fs *localfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Ref
 		np = fs.wrapReferences(ctx, np)
 	} else {
 		np = fs.wrap(ctx, np)
+		if err := fs.checkNamespace(ctx, np); err != nil {
+			return err
+		}
 	}
 
 	_, err = os.Stat(np)
@@ -680,6 +729,10 @@ func (fs *localfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Ref
 		return errors.Wrap(err, "localfs: error stating "+np)
 	}
 
+	if err := fs.checkLock(ctx, np, lockIDFromContext(ctx), ""); err != nil {
+		return err
+	}
+
 	for _, k := range keys {
 		switch k {
 		case "favorite":
@@ -710,26 +763,6 @@ func (fs *localfs) UnsetArbitraryMetadata(ctx context.Context, ref *provider.Ref
 	return fs.propagate(ctx, np)
 }
 
-// GetLock returns an existing lock on the given reference.
-func (fs *localfs) GetLock(ctx context.Context, ref *provider.Reference) (*provider.Lock, error) {
-	return nil, errtypes.NotSupported("unimplemented")
-}
-
-// SetLock puts a lock on the given reference.
-func (fs *localfs) SetLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
-	return errtypes.NotSupported("unimplemented")
-}
-
-// RefreshLock refreshes an existing lock on the given reference.
-func (fs *localfs) RefreshLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock, existingLockID string) error {
-	return errtypes.NotSupported("unimplemented")
-}
-
-// Unlock removes an existing lock from the given reference.
-func (fs *localfs) Unlock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
-	return errtypes.NotSupported("unimplemented")
-}
-
 func (fs *localfs) GetHome(ctx context.Context) (string, error) {
 	if fs.conf.DisableHome {
 		return "", errtypes.NotSupported("local: get home not supported")
@@ -758,7 +791,7 @@ func (fs *localfs) CreateHome(ctx context.Context) error {
 		}
 	}
 
-	return nil
+	return fs.createPersonalSpace(ctx)
 }
 
 func (fs *localfs) createHomeInternal(ctx context.Context, fn string) error {
@@ -786,7 +819,10 @@ func (fs *localfs) CreateDir(ctx context.Context, ref *provider.Reference) error
 	}
 
 	fn = fs.wrap(ctx, fn)
-	if _, err := os.Stat(fn); err == nil {
+	if err := fs.checkNamespace(ctx, path.Dir(fn)); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(fn); err == nil {
 		return errtypes.AlreadyExists(fn)
 	}
 	err = os.Mkdir(fn, 0700)
@@ -802,7 +838,34 @@ func (fs *localfs) CreateDir(ctx context.Context, ref *provider.Reference) error
 
 // TouchFile as defined in the storage.FS interface.
 func (fs *localfs) TouchFile(ctx context.Context, ref *provider.Reference) error {
-	return fmt.Errorf("unimplemented: TouchFile")
+	fn, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error resolving ref")
+	}
+
+	if fs.isShareFolder(ctx, fn) {
+		return errtypes.PermissionDenied("localfs: cannot create file under the share folder")
+	}
+
+	fn = fs.wrap(ctx, fn)
+	if err := fs.checkNamespace(ctx, fn); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(fn, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		switch {
+		case os.IsExist(err):
+			return errtypes.AlreadyExists(fn)
+		case os.IsNotExist(err):
+			return errtypes.NotFound(fn)
+		}
+		return errors.Wrap(err, "localfs: error creating file "+fn)
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "localfs: error closing file "+fn)
+	}
+
+	return fs.propagate(ctx, path.Dir(fn))
 }
 
 func (fs *localfs) Delete(ctx context.Context, ref *provider.Reference) error {
@@ -820,9 +883,13 @@ func (fs *localfs) Delete(ctx context.Context, ref *provider.Reference) error {
 		fp = fs.wrapReferences(ctx, fn)
 	} else {
 		fp = fs.wrap(ctx, fn)
+		if err := fs.checkNamespace(ctx, fp); err != nil {
+			return err
+		}
 	}
 
-	_, err = os.Stat(fp)
+	// a symlink is deleted on its own, regardless of its target
+	_, err = os.Lstat(fp)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return errtypes.NotFound(fn)
@@ -830,6 +897,10 @@ func (fs *localfs) Delete(ctx context.Context, ref *provider.Reference) error {
 		return errors.Wrap(err, "localfs: error stating "+fp)
 	}
 
+	if err := fs.checkTreeLocks(ctx, fp, lockIDFromContext(ctx)); err != nil {
+		return err
+	}
+
 	key := fmt.Sprintf("%s.d%d", path.Base(fn), time.Now().UnixNano()/int64(time.Millisecond))
 	if err := os.Rename(fp, fs.wrapRecycleBin(ctx, key)); err != nil {
 		return errors.Wrap(err, "localfs: could not delete item")
@@ -840,6 +911,11 @@ func (fs *localfs) Delete(ctx context.Context, ref *provider.Reference) error {
 		return errors.Wrap(err, "localfs: error adding entry to DB")
 	}
 
+	// locks do not survive in the recycle bin
+	if err := fs.removeTreeFromLocksDB(ctx, fp); err != nil {
+		return errors.Wrap(err, "localfs: error removing entry from DB")
+	}
+
 	return fs.propagate(ctx, path.Dir(fp))
 }
 
@@ -862,12 +938,37 @@ func (fs *localfs) Move(ctx context.Context, oldRef, newRef *provider.Reference)
 
 	oldName = fs.wrap(ctx, oldName)
 	newName = fs.wrap(ctx, newName)
+	for _, np := range []string{oldName, newName} {
+		if err := fs.checkNamespace(ctx, np); err != nil {
+			return err
+		}
+	}
+	if err := fs.checkMovedSymlink(ctx, oldName, newName); err != nil {
+		return err
+	}
+
+	lockID := lockIDFromContext(ctx)
+	if err := fs.checkTreeLocks(ctx, oldName, lockID); err != nil {
+		return err
+	}
+	// the destination may be locked with a different lock than the source
+	if dstLockID, ok := appctx.ContextGetDestinationLockID(ctx); ok {
+		lockID = dstLockID
+	}
+	if err := fs.checkLock(ctx, newName, lockID, ""); err != nil {
+		return err
+	}
 
 	if err := os.Rename(oldName, newName); err != nil {
 		log.Error().Err(err).Msg("localfs: error moving " + oldName + " to " + newName)
 		return errors.Wrap(err, "localfs: error moving "+oldName+" to "+newName)
 	}
 
+	// an overwritten destination loses its locks, the ones of the source follow it in copyMD
+	if err := fs.removeTreeFromLocksDB(ctx, newName); err != nil {
+		return errors.Wrap(err, "localfs: error removing entry from DB")
+	}
+
 	if err := fs.copyMD(oldName, newName); err != nil {
 		return errors.Wrap(err, "localfs: error copying metadata")
 	}
@@ -930,7 +1031,11 @@ func (fs *localfs) GetMD(ctx context.Context, ref *provider.Reference, mdKeys []
 	}
 
 	fn = fs.wrap(ctx, fn)
-	md, err := os.Stat(fn)
+	if err := fs.checkNamespace(ctx, path.Dir(fn)); err != nil {
+		return nil, err
+	}
+	// symlinks are reported as such rather than as the resource they point to
+	md, err := os.Lstat(fn)
 	if err != nil {
 		log.Warn().Str("path", fn).Any("md", md).Err(err).Msg("failed stat call in localfs")
 		if os.IsNotExist(err) {
@@ -1002,6 +1107,9 @@ func (fs *localfs) ListFolder(ctx context.Context, ref *provider.Reference, mdKe
 
 func (fs *localfs) listFolder(ctx context.Context, fn string, mdKeys []string) ([]*provider.ResourceInfo, error) {
 	fn = fs.wrap(ctx, fn)
+	if err := fs.checkNamespace(ctx, fn); err != nil {
+		return nil, err
+	}
 
 	entries, err := os.ReadDir(fn)
 	if err != nil {
@@ -1020,9 +1128,15 @@ func (fs *localfs) listFolder(ctx context.Context, fn string, mdKeys []string) (
 		mds = append(mds, info)
 	}
 
+	locks, err := fs.getChildLocks(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+
 	finfos := []*provider.ResourceInfo{}
 	for _, md := range mds {
-		info, err := fs.normalize(ctx, md, path.Join(fn, md.Name()), mdKeys)
+		cp := path.Join(fn, md.Name())
+		info, err := fs.normalizeWithLock(ctx, md, cp, mdKeys, locks[cp])
 		if err == nil {
 			finfos = append(finfos, info)
 		}
@@ -1079,6 +1193,9 @@ func (fs *localfs) Download(ctx context.Context, ref *provider.Reference) (io.Re
 	}
 
 	fn = fs.wrap(ctx, fn)
+	if err := fs.checkNamespace(ctx, fn); err != nil {
+		return nil, err
+	}
 	r, err := os.Open(fn)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -1092,12 +1209,27 @@ func (fs *localfs) Download(ctx context.Context, ref *provider.Reference) (io.Re
 }
 
 func (fs *localfs) archiveRevision(ctx context.Context, np string) error {
+	// only the content of regular files is versioned, symlinks are simply replaced
+	if fi, err := os.Lstat(np); err == nil && !fi.Mode().IsRegular() {
+		return nil
+	}
+
 	versionsDir := fs.wrapVersions(ctx, fs.unwrap(ctx, np))
 	if err := os.MkdirAll(versionsDir, 0700); err != nil {
 		return errors.Wrap(err, "localfs: error creating file versions dir "+versionsDir)
 	}
 
-	vp := path.Join(versionsDir, fmt.Sprintf("v%d", time.Now().UnixNano()/int64(time.Millisecond)))
+	// versions are named after the time they were archived at:
+	// never overwrite a version archived in the same millisecond
+	version := time.Now().UnixNano() / int64(time.Millisecond)
+	vp := path.Join(versionsDir, fmt.Sprintf("v%d", version))
+	for {
+		if _, err := os.Lstat(vp); os.IsNotExist(err) {
+			break
+		}
+		version++
+		vp = path.Join(versionsDir, fmt.Sprintf("v%d", version))
+	}
 	if err := os.Rename(np, vp); err != nil {
 		return errors.Wrap(err, "localfs: error renaming from "+np+" to "+vp)
 	}
@@ -1114,6 +1246,9 @@ func (fs *localfs) ListRevisions(ctx context.Context, ref *provider.Reference) (
 	if fs.isShareFolder(ctx, np) {
 		return nil, errtypes.PermissionDenied("localfs: cannot list revisions under the virtual share folder")
 	}
+	if err := fs.checkNamespace(ctx, fs.wrap(ctx, np)); err != nil {
+		return nil, err
+	}
 
 	versionsDir := fs.wrapVersions(ctx, np)
 	revisions := []*provider.FileVersion{}
@@ -1157,10 +1292,17 @@ func (fs *localfs) DownloadRevision(ctx context.Context, ref *provider.Reference
 	if fs.isShareFolder(ctx, np) {
 		return nil, errtypes.PermissionDenied("localfs: cannot download revisions under the virtual share folder")
 	}
+	if err := fs.checkNamespace(ctx, fs.wrap(ctx, np)); err != nil {
+		return nil, err
+	}
 
 	versionsDir := fs.wrapVersions(ctx, np)
-	vp := path.Join(versionsDir, revisionKey)
+	vp := path.Join(versionsDir, "v"+revisionKey)
 
+	// versions are regular files, never follow a link archived by older versions
+	if vs, err := os.Lstat(vp); err == nil && !vs.Mode().IsRegular() {
+		return nil, errtypes.NotFound(revisionKey)
+	}
 	r, err := os.Open(vp)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -1183,11 +1325,14 @@ func (fs *localfs) RestoreRevision(ctx context.Context, ref *provider.Reference,
 	}
 
 	versionsDir := fs.wrapVersions(ctx, np)
-	vp := path.Join(versionsDir, revisionKey)
+	vp := path.Join(versionsDir, "v"+revisionKey)
 	np = fs.wrap(ctx, np)
+	if err := fs.checkNamespace(ctx, np); err != nil {
+		return err
+	}
 
 	// check revision exists
-	vs, err := os.Stat(vp)
+	vs, err := os.Lstat(vp)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return errtypes.NotFound(revisionKey)
@@ -1199,6 +1344,10 @@ func (fs *localfs) RestoreRevision(ctx context.Context, ref *provider.Reference,
 		return fmt.Errorf("%s is not a regular file", vp)
 	}
 
+	if err := fs.checkLock(ctx, np, lockIDFromContext(ctx), ""); err != nil {
+		return err
+	}
+
 	if err := fs.archiveRevision(ctx, np); err != nil {
 		return err
 	}
@@ -1305,13 +1454,22 @@ func (fs *localfs) RestoreRecycleItem(ctx context.Context, basePath, key, relati
 	default:
 		localRestorePath = fs.wrap(ctx, filePath)
 	}
+	rp := fs.wrapRecycleBin(ctx, key)
+
+	if !fs.isShareFolder(ctx, filePath) || restoreRef.GetPath() != "" {
+		if err := fs.checkNamespace(ctx, localRestorePath); err != nil {
+			return err
+		}
+		if err := fs.checkMovedSymlink(ctx, rp, localRestorePath); err != nil {
+			return err
+		}
+	}
 
-	if _, err = os.Stat(localRestorePath); err == nil {
+	if _, err = os.Lstat(localRestorePath); err == nil {
 		return errors.New("localfs: can't restore - file already exists at original path")
 	}
 
-	rp := fs.wrapRecycleBin(ctx, key)
-	if _, err = os.Stat(rp); err != nil {
+	if _, err = os.Lstat(rp); err != nil {
 		if os.IsNotExist(err) {
 			return errtypes.NotFound(key)
 		}
@@ -1330,15 +1488,6 @@ func (fs *localfs) RestoreRecycleItem(ctx context.Context, basePath, key, relati
 	return fs.propagate(ctx, localRestorePath)
 }
 
-func (fs *localfs) ListStorageSpaces(ctx context.Context, filter []*provider.ListStorageSpacesRequest_Filter) ([]*provider.StorageSpace, error) {
-	return nil, errtypes.NotSupported("list storage spaces")
-}
-
-// UpdateStorageSpace updates a storage space.
-func (fs *localfs) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
-	return nil, errtypes.NotSupported("update storage space")
-}
-
 func (fs *localfs) propagate(ctx context.Context, leafPath string) error {
 	var root string
 	if fs.isShareFolderChild(ctx, leafPath) || strings.HasSuffix(path.Clean(leafPath), fs.conf.ShareFolder) {
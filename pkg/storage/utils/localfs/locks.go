@@ -0,0 +1,341 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// resolveLockTarget returns the internal path of a lockable resource.
+func (fs *localfs) resolveLockTarget(ctx context.Context, ref *provider.Reference) (string, error) {
+	fn, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return "", errors.Wrap(err, "localfs: error resolving ref")
+	}
+
+	if fs.isShareFolder(ctx, fn) {
+		return "", errtypes.PermissionDenied("localfs: cannot lock resources under the virtual share folder")
+	}
+
+	np := fs.wrap(ctx, fn)
+	if err := fs.checkNamespace(ctx, np); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(np); err != nil {
+		if os.IsNotExist(err) {
+			return "", errtypes.NotFound(fn)
+		}
+		return "", errors.Wrap(err, "localfs: error stating "+np)
+	}
+	return np, nil
+}
+
+func encodeLock(l *provider.Lock) (string, int64, error) {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return "", 0, err
+	}
+	var expiration int64
+	if l.Expiration != nil {
+		expiration = int64(l.Expiration.Seconds)
+	}
+	return string(data), expiration, nil
+}
+
+func decodeLock(payload string) (*provider.Lock, error) {
+	l := new(provider.Lock)
+	if err := json.Unmarshal([]byte(payload), l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func isExpired(l *provider.Lock) bool {
+	return l.Expiration != nil && time.Unix(int64(l.Expiration.Seconds), 0).Before(time.Now())
+}
+
+// getLock returns the lock held on the given internal path,
+// or nil if the resource is not locked. Expired locks are removed.
+func (fs *localfs) getLock(ctx context.Context, np string) (*provider.Lock, error) {
+	payload, err := fs.getLockEntry(ctx, np)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "localfs: error reading lock")
+	}
+
+	l, err := decodeLock(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "localfs: malformed lock payload")
+	}
+
+	if isExpired(l) {
+		if err := fs.removeFromLocksDB(ctx, np); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return l, nil
+}
+
+// getChildLocks returns the locks held on the direct children of the given
+// internal path keyed by their internal path, loading them in a single query.
+// Expired locks are skipped and left to be removed by getLock.
+func (fs *localfs) getChildLocks(ctx context.Context, np string) (map[string]*provider.Lock, error) {
+	rows, err := fs.getChildLockEntries(ctx, np)
+	if err != nil {
+		return nil, errors.Wrap(err, "localfs: error listing locks")
+	}
+	defer rows.Close()
+
+	locks := map[string]*provider.Lock{}
+	var resource, payload string
+	for rows.Next() {
+		if err := rows.Scan(&resource, &payload); err != nil {
+			return nil, errors.Wrap(err, "localfs: error scanning db rows")
+		}
+		l, err := decodeLock(payload)
+		if err != nil {
+			return nil, errors.Wrap(err, "localfs: malformed lock payload")
+		}
+		if !isExpired(l) {
+			locks[resource] = l
+		}
+	}
+	return locks, rows.Err()
+}
+
+// checkLock verifies that the resource at the given internal path can be
+// modified by a caller presenting lockID and, optionally, the app name holder.
+func (fs *localfs) checkLock(ctx context.Context, np, lockID, holder string) error {
+	l, err := fs.getLock(ctx, np)
+	if err != nil {
+		return err
+	}
+	return checkLockHolder(l, fs.unwrap(ctx, np), lockID, holder)
+}
+
+// checkTreeLocks is like checkLock but also considers the locks held on
+// the children of the resource, as needed when deleting or moving a folder.
+func (fs *localfs) checkTreeLocks(ctx context.Context, np, lockID string) error {
+	rows, err := fs.getLockEntries(ctx, np)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error listing locks")
+	}
+	defer rows.Close()
+
+	var resource, payload string
+	for rows.Next() {
+		if err := rows.Scan(&resource, &payload); err != nil {
+			return errors.Wrap(err, "localfs: error scanning db rows")
+		}
+		l, err := decodeLock(payload)
+		if err != nil {
+			return errors.Wrap(err, "localfs: malformed lock payload")
+		}
+		if isExpired(l) {
+			continue
+		}
+		if err := checkLockHolder(l, fs.unwrap(ctx, resource), lockID, ""); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func checkLockHolder(l *provider.Lock, fn, lockID, holder string) error {
+	switch {
+	case l == nil:
+		return nil
+	case l.Type == provider.LockType_LOCK_TYPE_SHARED:
+		// shared locks are advisory
+		return nil
+	case l.LockId != lockID:
+		return errtypes.Locked(fn)
+	case holder != "" && l.AppName != "" && l.AppName != holder:
+		return errtypes.Locked(fn)
+	}
+	return nil
+}
+
+func sameHolder(l1, l2 *provider.Lock) bool {
+	same := true
+	if l1.User != nil || l2.User != nil {
+		same = utils.UserEqual(l1.User, l2.User)
+	}
+	if l1.AppName != "" || l2.AppName != "" {
+		same = same && l1.AppName == l2.AppName
+	}
+	return same
+}
+
+func validateLock(l *provider.Lock) error {
+	if l == nil || l.LockId == "" {
+		return errtypes.BadRequest("localfs: missing lock id")
+	}
+	switch l.Type {
+	case provider.LockType_LOCK_TYPE_SHARED, provider.LockType_LOCK_TYPE_WRITE, provider.LockType_LOCK_TYPE_EXCL:
+		return nil
+	default:
+		return errtypes.BadRequest("localfs: invalid lock type " + l.Type.String())
+	}
+}
+
+// GetLock returns an existing lock on the given reference.
+func (fs *localfs) GetLock(ctx context.Context, ref *provider.Reference) (*provider.Lock, error) {
+	np, err := fs.resolveLockTarget(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := fs.getLock(ctx, np)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return nil, errtypes.NotFound("lock not found for ref")
+	}
+	return l, nil
+}
+
+// SetLock puts a lock on the given reference.
+func (fs *localfs) SetLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
+	if err := validateLock(lock); err != nil {
+		return err
+	}
+
+	np, err := fs.resolveLockTarget(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	// clean up an eventual expired lock
+	if _, err := fs.getLock(ctx, np); err != nil {
+		return err
+	}
+
+	payload, expiration, err := encodeLock(lock)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error encoding lock")
+	}
+
+	ok, err := fs.addToLocksDB(ctx, np, lock.LockId, payload, expiration)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error adding entry to DB")
+	}
+	if !ok {
+		return errtypes.Conflict("resource already locked")
+	}
+	return nil
+}
+
+// RefreshLock refreshes an existing lock on the given reference.
+func (fs *localfs) RefreshLock(ctx context.Context, ref *provider.Reference, lock *provider.Lock, existingLockID string) error {
+	if err := validateLock(lock); err != nil {
+		return err
+	}
+
+	np, err := fs.resolveLockTarget(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	oldLock, err := fs.getLock(ctx, np)
+	if err != nil {
+		return err
+	}
+	if oldLock == nil {
+		return errtypes.BadRequest("file was not locked")
+	}
+
+	if oldLock.Type != provider.LockType_LOCK_TYPE_SHARED && !sameHolder(oldLock, lock) {
+		return errtypes.BadRequest("caller does not hold the lock")
+	}
+
+	// the lock id may change on refresh, in which case the caller must provide the current one
+	lockID := existingLockID
+	if lockID == "" {
+		lockID = lock.LockId
+	}
+	if oldLock.LockId != lockID {
+		return errtypes.BadRequest("lock id does not match")
+	}
+
+	payload, expiration, err := encodeLock(lock)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error encoding lock")
+	}
+
+	ok, err := fs.updateLockDB(ctx, np, oldLock.LockId, lock.LockId, payload, expiration)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error updating entry in DB")
+	}
+	if !ok {
+		// the lock was changed in the meantime
+		return errtypes.BadRequest("lock id does not match")
+	}
+	return nil
+}
+
+// Unlock removes an existing lock from the given reference.
+func (fs *localfs) Unlock(ctx context.Context, ref *provider.Reference, lock *provider.Lock) error {
+	np, err := fs.resolveLockTarget(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	oldLock, err := fs.getLock(ctx, np)
+	if err != nil {
+		return err
+	}
+	if oldLock == nil {
+		return errtypes.BadRequest("file was not locked")
+	}
+
+	if oldLock.LockId != lock.GetLockId() {
+		return errtypes.BadRequest("lock id does not match")
+	}
+
+	// shared locks can be released by everyone who has access
+	if oldLock.Type != provider.LockType_LOCK_TYPE_SHARED && !sameHolder(oldLock, lock) {
+		return errtypes.BadRequest("caller does not hold the lock")
+	}
+
+	if err := fs.removeFromLocksDB(ctx, np); err != nil {
+		return errors.Wrap(err, "localfs: error removing entry from DB")
+	}
+	return nil
+}
+
+// lockIDFromContext returns the lock id the caller presented, if any.
+func lockIDFromContext(ctx context.Context) string {
+	lockID, _ := appctx.ContextGetLockID(ctx)
+	return lockID
+}
@@ -0,0 +1,282 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+func newLock(id string, expiration time.Duration) *provider.Lock {
+	return &provider.Lock{
+		LockId:     id,
+		Type:       provider.LockType_LOCK_TYPE_WRITE,
+		User:       einstein.Id,
+		Expiration: &types.Timestamp{Seconds: uint64(time.Now().Add(expiration).Unix())},
+	}
+}
+
+func TestLocks(t *testing.T) {
+	fs, err := NewLocalFS(&Config{Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	if err := fs.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ref := &provider.Reference{Path: "/file.txt"}
+	if err := fs.TouchFile(ctx, ref); err != nil {
+		t.Fatal(err)
+	}
+
+	byMarie := newLock("c", time.Hour)
+	byMarie.User = marie.Id
+
+	// the steps run in order on the same file
+	tests := []struct {
+		description string
+		action      func() error
+		err         error
+		// lockID is the id of the lock expected on the file afterwards
+		lockID string
+	}{
+		{
+			description: "get without lock",
+			action:      func() error { _, err := fs.GetLock(ctx, ref); return err },
+			err:         errtypes.NotFound(""),
+		},
+		{
+			description: "unlock without lock",
+			action:      func() error { return fs.Unlock(ctx, ref, newLock("a", time.Hour)) },
+			err:         errtypes.BadRequest(""),
+		},
+		{
+			description: "set without lock id",
+			action:      func() error { return fs.SetLock(ctx, ref, newLock("", time.Hour)) },
+			err:         errtypes.BadRequest(""),
+		},
+		{
+			description: "set",
+			action:      func() error { return fs.SetLock(ctx, ref, newLock("a", time.Hour)) },
+			lockID:      "a",
+		},
+		{
+			description: "set on a locked file",
+			action:      func() error { return fs.SetLock(ctx, ref, newLock("b", time.Hour)) },
+			err:         errtypes.Conflict(""),
+			lockID:      "a",
+		},
+		{
+			description: "refresh with a mismatching lock id",
+			action:      func() error { return fs.RefreshLock(ctx, ref, newLock("c", time.Hour), "b") },
+			err:         errtypes.BadRequest(""),
+			lockID:      "a",
+		},
+		{
+			description: "refresh by another holder",
+			action:      func() error { return fs.RefreshLock(ctx, ref, byMarie, "a") },
+			err:         errtypes.BadRequest(""),
+			lockID:      "a",
+		},
+		{
+			description: "refresh changing the lock id",
+			action:      func() error { return fs.RefreshLock(ctx, ref, newLock("c", time.Hour), "a") },
+			lockID:      "c",
+		},
+		{
+			description: "unlock with a mismatching lock id",
+			action:      func() error { return fs.Unlock(ctx, ref, newLock("a", time.Hour)) },
+			err:         errtypes.BadRequest(""),
+			lockID:      "c",
+		},
+		{
+			description: "unlock",
+			action:      func() error { return fs.Unlock(ctx, ref, newLock("c", time.Hour)) },
+		},
+		{
+			description: "expired locks are not returned",
+			action:      func() error { return fs.SetLock(ctx, ref, newLock("d", -time.Hour)) },
+		},
+		{
+			description: "refresh an expired lock",
+			action:      func() error { return fs.RefreshLock(ctx, ref, newLock("d", time.Hour), "") },
+			err:         errtypes.BadRequest(""),
+		},
+		{
+			description: "set over an expired lock",
+			action: func() error {
+				if err := fs.SetLock(ctx, ref, newLock("e", -time.Hour)); err != nil {
+					return err
+				}
+				return fs.SetLock(ctx, ref, newLock("f", time.Hour))
+			},
+			lockID: "f",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			err := tt.action()
+			if tt.err != nil {
+				if err == nil || !sameErrType(err, tt.err) {
+					t.Fatalf("expected error of type %T, got %v", tt.err, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			l, err := fs.GetLock(ctx, ref)
+			if tt.lockID == "" {
+				if _, ok := err.(errtypes.NotFound); !ok {
+					t.Fatalf("expected no lock, got %v, %v", l, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.LockId != tt.lockID {
+				t.Fatalf("got lock %q, expected %q", l.LockId, tt.lockID)
+			}
+		})
+	}
+}
+
+func TestListFolderLocks(t *testing.T) {
+	fs, err := NewLocalFS(&Config{Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	if err := fs.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/dir", "/dir/sub"} {
+		if err := fs.CreateDir(ctx, &provider.Reference{Path: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []string{"/dir/locked.txt", "/dir/expired.txt", "/dir/free.txt", "/dir/sub/nested.txt"} {
+		if err := fs.TouchFile(ctx, &provider.Reference{Path: p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	locks := map[string]*provider.Lock{
+		"/dir":                newLock("parent", time.Hour),
+		"/dir/locked.txt":     newLock("locked", time.Hour),
+		"/dir/expired.txt":    newLock("expired", -time.Hour),
+		"/dir/sub/nested.txt": newLock("nested", time.Hour),
+	}
+	for p, l := range locks {
+		if err := fs.SetLock(ctx, &provider.Reference{Path: p}, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := map[string]string{
+		"/dir/locked.txt":  "locked",
+		"/dir/expired.txt": "",
+		"/dir/free.txt":    "",
+		"/dir/sub":         "",
+	}
+
+	check := func(t *testing.T, mds []*provider.ResourceInfo) {
+		if len(mds) != len(expected) {
+			t.Fatalf("got %d entries, expected %d", len(mds), len(expected))
+		}
+		for _, md := range mds {
+			if got := md.GetLock().GetLockId(); got != expected[md.Path] {
+				t.Fatalf("%s: got lock %q, expected %q", md.Path, got, expected[md.Path])
+			}
+		}
+	}
+
+	t.Run("list", func(t *testing.T) {
+		mds, err := fs.ListFolder(ctx, &provider.Reference{Path: "/dir"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, mds)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		it, err := storage.ListFolderStream(ctx, fs, &provider.Reference{Path: "/dir"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer it.Close()
+		var mds []*provider.ResourceInfo
+		for {
+			md, err := it.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			mds = append(mds, md)
+		}
+		check(t, mds)
+	})
+}
+
+func TestMoveLocks(t *testing.T) {
+	fs, err := NewLocalFS(&Config{Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	if err := fs.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+	src, dst := &provider.Reference{Path: "/a.txt"}, &provider.Reference{Path: "/b.txt"}
+	for _, ref := range []*provider.Reference{src, dst} {
+		if err := fs.TouchFile(ctx, ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.SetLock(ctx, src, newLock("a", time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.SetLock(ctx, dst, newLock("b", time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the token of the source does not unlock the destination
+	if _, ok := fs.Move(appctx.ContextSetLockID(ctx, "a"), src, dst).(errtypes.IsLocked); !ok {
+		t.Fatal("expected the destination to be locked")
+	}
+	moveCtx := appctx.ContextSetDestinationLockID(appctx.ContextSetLockID(ctx, "a"), "b")
+	if err := fs.Move(moveCtx, src, dst); err != nil {
+		t.Fatal(err)
+	}
+	l, err := fs.GetLock(ctx, dst)
+	if err != nil || l.LockId != "a" {
+		t.Fatalf("expected the lock of the source on the destination, got %v %v", l, err)
+	}
+}
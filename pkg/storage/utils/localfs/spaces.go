@@ -0,0 +1,618 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"encoding/json"
+	iofs "io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/rgrpc/status"
+	"github.com/cs3org/reva/pkg/spaces"
+	"github.com/cs3org/reva/pkg/storage/utils/acl"
+	"github.com/cs3org/reva/pkg/storage/utils/templates"
+	"github.com/pkg/errors"
+)
+
+// space is a storage space as persisted in the spaces table.
+type space struct {
+	id          string
+	spaceType   string
+	name        string
+	description string
+	owner       *userpb.User
+	path        string // path of the space root as seen by its owner
+	resource    string // internal path of the space root
+	quota       uint64
+	trashed     string // internal path of the space root in the trash, if the space was disabled
+}
+
+// spaceRoot returns the external and internal paths of a space
+// rooted at p in the namespace of the given owner, together with the
+// layout of the owner home, if homes are enabled.
+func (fs *localfs) spaceRoot(owner *userpb.User, p string) (external, internal, layout string) {
+	external = path.Join("/", p)
+	if !fs.conf.DisableHome {
+		layout = templates.WithUser(owner, fs.conf.UserLayout)
+	}
+	internal = path.Join(fs.conf.DataDirectory, layout, external)
+	return external, internal, layout
+}
+
+// spaceID builds the id of a space, which is the file id of its root.
+// See normalize for the file id format.
+func spaceID(layout, external string) string {
+	return "fileid-" + url.QueryEscape(path.Join(layout, external))
+}
+
+func descriptionFromOpaque(o *types.Opaque) string {
+	if o == nil || o.Map == nil {
+		return ""
+	}
+	if e, ok := o.Map["description"]; ok && e.Decoder == "plain" {
+		return string(e.Value)
+	}
+	return ""
+}
+
+// CreateStorageSpace creates a storage space.
+func (fs *localfs) CreateStorageSpace(ctx context.Context, req *provider.CreateStorageSpaceRequest) (*provider.CreateStorageSpaceResponse, error) {
+	owner := req.Owner
+	if owner == nil || owner.Id == nil {
+		u, err := getUser(ctx)
+		if err != nil {
+			return nil, err
+		}
+		owner = u
+	}
+
+	var p, name string
+	switch spaces.SpaceType(req.Type) {
+	case spaces.SpaceTypeHome:
+		if fs.conf.DisableHome {
+			p = templates.WithUser(owner, fs.conf.UserLayout)
+		} else {
+			p = "/"
+		}
+		name = req.Name
+		if name == "" {
+			name = owner.Username
+		}
+	case spaces.SpaceTypeProject:
+		name = req.Name
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+			return nil, errtypes.BadRequest("localfs: invalid space name " + name)
+		}
+		p = path.Join(fs.conf.ProjectsFolder, name)
+	default:
+		return nil, errtypes.BadRequest("localfs: unsupported space type " + req.Type)
+	}
+
+	external, internal, layout := fs.spaceRoot(owner, p)
+	if fs.isShareFolder(ctx, external) {
+		return nil, errtypes.PermissionDenied("localfs: cannot create a space under the virtual share folder")
+	}
+
+	s := &space{
+		id:          spaceID(layout, external),
+		spaceType:   req.Type,
+		name:        name,
+		description: descriptionFromOpaque(req.Opaque),
+		owner:       owner,
+		path:        external,
+		resource:    internal,
+		quota:       req.Quota.GetQuotaMaxBytes(),
+	}
+
+	if err := fs.addSpace(ctx, s); err != nil {
+		return nil, err
+	}
+
+	sp, err := fs.convertToStorageSpace(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &provider.CreateStorageSpaceResponse{
+		Status:       status.NewOK(ctx),
+		StorageSpace: sp,
+	}, nil
+}
+
+func (fs *localfs) addSpace(ctx context.Context, s *space) error {
+	if err := os.MkdirAll(s.resource, 0700); err != nil {
+		return errors.Wrap(err, "localfs: error creating space root "+s.resource)
+	}
+
+	owner, err := json.Marshal(s.owner)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error encoding space owner")
+	}
+
+	ok, err := fs.addToSpacesDB(ctx, s.id, s.spaceType, s.name, s.description, string(owner), s.path, s.resource, s.quota)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error adding entry to DB")
+	}
+	if !ok {
+		return errtypes.AlreadyExists("localfs: space already exists at " + s.path)
+	}
+	return nil
+}
+
+// createPersonalSpace registers the home of the user in context as
+// its personal space, if not already done.
+func (fs *localfs) createPersonalSpace(ctx context.Context) error {
+	u, err := getUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	external, internal, layout := fs.spaceRoot(u, "/")
+	err = fs.addSpace(ctx, &space{
+		id:        spaceID(layout, external),
+		spaceType: spaces.SpaceTypeHome.AsString(),
+		name:      u.Username,
+		owner:     u,
+		path:      external,
+		resource:  internal,
+	})
+	if _, ok := err.(errtypes.IsAlreadyExists); ok {
+		return nil
+	}
+	return err
+}
+
+// ListStorageSpaces lists the spaces accessible by the user in context
+// matching the given filters. Filters of the same type are or-ed, filters
+// of different types are and-ed.
+func (fs *localfs) ListStorageSpaces(ctx context.Context, filter []*provider.ListStorageSpacesRequest_Filter) ([]*provider.StorageSpace, error) {
+	u, err := getUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := fs.listSpaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byType := map[provider.ListStorageSpacesRequest_Filter_Type][]*provider.ListStorageSpacesRequest_Filter{}
+	for _, f := range filter {
+		switch f.Type {
+		case provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+			provider.ListStorageSpacesRequest_Filter_TYPE_OWNER,
+			provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE,
+			provider.ListStorageSpacesRequest_Filter_TYPE_PATH,
+			provider.ListStorageSpacesRequest_Filter_TYPE_USER:
+			byType[f.Type] = append(byType[f.Type], f)
+		default:
+			return nil, errtypes.NotSupported("localfs: unsupported space filter " + f.Type.String())
+		}
+	}
+
+	list := []*provider.StorageSpace{}
+	for _, s := range all {
+		if s.trashed != "" {
+			continue
+		}
+		if _, err := os.Stat(s.resource); err != nil {
+			// the root of the space was removed behind our back
+			continue
+		}
+
+		accessible, err := fs.hasSpaceAccess(ctx, s, u.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !accessible {
+			continue
+		}
+
+		match := true
+		for _, filters := range byType {
+			ok, err := fs.matchesAny(ctx, s, filters)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+
+		sp, err := fs.convertToStorageSpace(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, sp)
+	}
+
+	return list, nil
+}
+
+func (fs *localfs) matchesAny(ctx context.Context, s *space, filters []*provider.ListStorageSpacesRequest_Filter) (bool, error) {
+	for _, f := range filters {
+		switch f.Type {
+		case provider.ListStorageSpacesRequest_Filter_TYPE_ID:
+			if matchSpaceID(s, f.GetId().GetOpaqueId()) {
+				return true, nil
+			}
+		case provider.ListStorageSpacesRequest_Filter_TYPE_OWNER:
+			if sameUserID(s.owner.GetId(), f.GetOwner()) {
+				return true, nil
+			}
+		case provider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE:
+			if s.spaceType == f.GetSpaceType() {
+				return true, nil
+			}
+		case provider.ListStorageSpacesRequest_Filter_TYPE_PATH:
+			if s.path == path.Join("/", f.GetPath()) {
+				return true, nil
+			}
+		case provider.ListStorageSpacesRequest_Filter_TYPE_USER:
+			ok, err := fs.hasSpaceAccess(ctx, s, f.GetUser())
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// matchSpaceID matches the given id against the space. The id may be
+// the bare id returned by the driver, prefixed with the storage id as
+// done by the storage provider, or encoded with spaces.EncodeSpaceID.
+func matchSpaceID(s *space, id string) bool {
+	if _, p, ok := spaces.DecodeSpaceID(id); ok {
+		return path.Join("/", p) == s.path
+	}
+	if i := strings.LastIndex(id, "!"); i >= 0 {
+		id = id[i+1:]
+	}
+	return id == s.id
+}
+
+func sameUserID(u1, u2 *userpb.UserId) bool {
+	if u1 == nil || u2 == nil {
+		return false
+	}
+	if u1.OpaqueId != u2.OpaqueId {
+		return false
+	}
+	return u1.Idp == "" || u2.Idp == "" || u1.Idp == u2.Idp
+}
+
+// hasSpaceAccess tells whether the user owns the space or has been
+// granted access to its root.
+func (fs *localfs) hasSpaceAccess(ctx context.Context, s *space, user *userpb.UserId) (bool, error) {
+	if sameUserID(s.owner.GetId(), user) {
+		return true, nil
+	}
+
+	rows, err := fs.getACLs(ctx, s.resource)
+	if err != nil {
+		return false, errors.Wrap(err, "localfs: error listing grants")
+	}
+	defer rows.Close()
+
+	var grantee, role string
+	for rows.Next() {
+		if err := rows.Scan(&grantee, &role); err != nil {
+			return false, errors.Wrap(err, "localfs: error scanning db rows")
+		}
+		// removed grants and favorites are kept in the same table with an empty role
+		if role == "" {
+			continue
+		}
+		// grantees are stored as u:<opaque_id>:<type>@<idp>, see granteeKey
+		parts := strings.SplitN(grantee, ":", 3)
+		if len(parts) != 3 || parts[0] != acl.TypeUser {
+			continue
+		}
+		uid := &userpb.UserId{OpaqueId: parts[1]}
+		if id := strings.SplitN(parts[2], "@", 2); len(id) == 2 {
+			uid.Idp = id[1]
+		}
+		if sameUserID(uid, user) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+func (fs *localfs) listSpaces(ctx context.Context) ([]*space, error) {
+	rows, err := fs.getSpaces(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "localfs: error listing spaces")
+	}
+	defer rows.Close()
+
+	var list []*space
+	for rows.Next() {
+		s := &space{}
+		var owner string
+		if err := rows.Scan(&s.id, &s.spaceType, &s.name, &s.description, &owner, &s.path, &s.resource, &s.quota, &s.trashed); err != nil {
+			return nil, errors.Wrap(err, "localfs: error scanning db rows")
+		}
+		s.owner = &userpb.User{}
+		if err := json.Unmarshal([]byte(owner), s.owner); err != nil {
+			return nil, errors.Wrap(err, "localfs: malformed space owner")
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+func (fs *localfs) getSpace(ctx context.Context, id string) (*space, error) {
+	all, err := fs.listSpaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range all {
+		if matchSpaceID(s, id) {
+			return s, nil
+		}
+	}
+	return nil, errtypes.NotFound("localfs: space " + id)
+}
+
+func (fs *localfs) convertToStorageSpace(ctx context.Context, s *space) (*provider.StorageSpace, error) {
+	fi, err := os.Stat(s.resource)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errtypes.NotFound(s.path)
+		}
+		return nil, errors.Wrap(err, "localfs: error stating "+s.resource)
+	}
+
+	mtime := &types.Timestamp{Seconds: uint64(fi.ModTime().Unix())}
+	root := &provider.ResourceId{OpaqueId: s.id}
+
+	sp := &provider.StorageSpace{
+		Root:      root,
+		Owner:     s.owner,
+		Name:      s.name,
+		SpaceType: s.spaceType,
+		Mtime:     mtime,
+		RootInfo: &provider.ResourceInfo{
+			Id:            root,
+			Path:          s.path,
+			Type:          provider.ResourceType_RESOURCE_TYPE_CONTAINER,
+			Etag:          calcEtag(ctx, fi),
+			MimeType:      "httpd/unix-directory",
+			Mtime:         mtime,
+			Owner:         s.owner.GetId(),
+			PermissionSet: fs.permissionSet(ctx, s.owner.GetId()),
+		},
+	}
+
+	if s.description != "" {
+		sp.Opaque = &types.Opaque{
+			Map: map[string]*types.OpaqueEntry{
+				"description": {
+					Decoder: "plain",
+					Value:   []byte(s.description),
+				},
+			},
+		}
+	}
+
+	if s.quota > 0 {
+		used, err := fs.spaceUsage(s.resource)
+		if err != nil {
+			return nil, errors.Wrap(err, "localfs: error computing space usage")
+		}
+		var remaining uint64
+		if used < s.quota {
+			remaining = s.quota - used
+		}
+		sp.Quota = &provider.Quota{
+			QuotaMaxBytes:  s.quota,
+			RemainingBytes: remaining,
+		}
+	}
+
+	return sp, nil
+}
+
+// spaceUsageTTL is how long the computed usage of a space is reused.
+const spaceUsageTTL = time.Minute
+
+type spaceUsage struct {
+	size     uint64
+	computed time.Time
+}
+
+// spaceUsage returns the size of the tree rooted at the given space root.
+// Walking the tree is expensive, so the size is computed when first needed
+// and then reused for spaceUsageTTL.
+func (fs *localfs) spaceUsage(root string) (uint64, error) {
+	if u, ok := fs.usage.Load(root); ok && time.Since(u.(spaceUsage).computed) < spaceUsageTTL {
+		return u.(spaceUsage).size, nil
+	}
+	size, err := dirSize(root)
+	if err != nil {
+		return 0, err
+	}
+	fs.usage.Store(root, spaceUsage{size: size, computed: time.Now()})
+	return size, nil
+}
+
+func dirSize(root string) (uint64, error) {
+	var size uint64
+	err := filepath.WalkDir(root, func(_ string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	return size, err
+}
+
+// UpdateStorageSpace updates the name, the description and the quota of a storage space.
+// Only the owner of the space is allowed to update it.
+func (fs *localfs) UpdateStorageSpace(ctx context.Context, req *provider.UpdateStorageSpaceRequest) (*provider.UpdateStorageSpaceResponse, error) {
+	u, err := getUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	update := req.StorageSpace
+	if update.GetId().GetOpaqueId() == "" {
+		return nil, errtypes.BadRequest("localfs: missing space id")
+	}
+
+	s, err := fs.getSpace(ctx, update.Id.OpaqueId)
+	if err != nil {
+		return nil, err
+	}
+	if s.trashed != "" {
+		return nil, errtypes.NotFound("localfs: space " + update.Id.OpaqueId)
+	}
+
+	if !sameUserID(s.owner.GetId(), u.Id) {
+		return nil, errtypes.PermissionDenied("localfs: only the owner can update the space " + s.name)
+	}
+
+	if update.Name != "" {
+		s.name = update.Name
+	}
+	if d, ok := update.GetOpaque().GetMap()["description"]; ok && d.Decoder == "plain" {
+		s.description = string(d.Value)
+	}
+	if update.Quota != nil {
+		s.quota = update.Quota.QuotaMaxBytes
+	}
+
+	if err := fs.updateSpaceDB(ctx, s.id, s.name, s.description, s.quota); err != nil {
+		return nil, errors.Wrap(err, "localfs: error updating entry in DB")
+	}
+
+	sp, err := fs.convertToStorageSpace(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &provider.UpdateStorageSpaceResponse{
+		Status:       status.NewOK(ctx),
+		StorageSpace: sp,
+	}, nil
+}
+
+func purgeFromOpaque(o *types.Opaque) bool {
+	if o == nil || o.Map == nil {
+		return false
+	}
+	e, ok := o.Map["purge"]
+	return ok && e.Decoder == "plain" && string(e.Value) == "true"
+}
+
+// DeleteStorageSpace deletes a storage space. A space is first disabled,
+// moving its root to the trash and hiding it from the listings, and
+// can then be purged for good by setting the purge opaque entry.
+// Only the owner of a project space is allowed to delete it.
+func (fs *localfs) DeleteStorageSpace(ctx context.Context, req *provider.DeleteStorageSpaceRequest) error {
+	u, err := getUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if req.GetId().GetOpaqueId() == "" {
+		return errtypes.BadRequest("localfs: missing space id")
+	}
+
+	s, err := fs.getSpace(ctx, req.Id.OpaqueId)
+	if err != nil {
+		return err
+	}
+
+	if !sameUserID(s.owner.GetId(), u.Id) {
+		return errtypes.PermissionDenied("localfs: only the owner can delete the space " + s.name)
+	}
+	if s.spaceType == spaces.SpaceTypeHome.AsString() {
+		return errtypes.PermissionDenied("localfs: personal spaces cannot be deleted")
+	}
+
+	if purgeFromOpaque(req.Opaque) {
+		return fs.purgeSpace(ctx, s)
+	}
+	return fs.disableSpace(ctx, s)
+}
+
+func (fs *localfs) disableSpace(ctx context.Context, s *space) error {
+	if s.trashed != "" {
+		// already disabled
+		return nil
+	}
+
+	if err := fs.checkTreeLocks(ctx, s.resource, lockIDFromContext(ctx)); err != nil {
+		return err
+	}
+
+	trashed := path.Join(fs.conf.SpacesTrash, s.id)
+	if err := os.Rename(s.resource, trashed); err != nil {
+		if os.IsNotExist(err) {
+			return errtypes.NotFound(s.path)
+		}
+		return errors.Wrap(err, "localfs: error moving space root to the trash")
+	}
+
+	if err := fs.updateSpaceTrashDB(ctx, s.id, trashed); err != nil {
+		return errors.Wrap(err, "localfs: error updating entry in DB")
+	}
+	return nil
+}
+
+func (fs *localfs) purgeSpace(ctx context.Context, s *space) error {
+	if s.trashed == "" {
+		return errtypes.BadRequest("localfs: space " + s.name + " must be disabled before being purged")
+	}
+
+	if err := os.RemoveAll(s.trashed); err != nil {
+		return errors.Wrap(err, "localfs: error purging space "+s.trashed)
+	}
+
+	if err := fs.removeFromSpacesDB(ctx, s.id, s.resource); err != nil {
+		return errors.Wrap(err, "localfs: error removing entry from DB")
+	}
+	return nil
+}
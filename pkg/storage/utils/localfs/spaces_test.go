@@ -0,0 +1,315 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/spaces"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+var (
+	einstein = &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein", Idp: "cernbox.cern.ch"}, Username: "einstein"}
+	marie    = &userpb.User{Id: &userpb.UserId{OpaqueId: "marie", Idp: "cernbox.cern.ch"}, Username: "marie"}
+)
+
+func setupSpaces(t *testing.T) storage.FS {
+	fs, err := NewLocalFS(&Config{Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, u := range []*userpb.User{einstein, marie} {
+		ctx := appctx.ContextSetUser(context.Background(), u)
+		if err := fs.CreateHome(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	for _, name := range []string{"physics", "relativity"} {
+		if _, err := fs.CreateStorageSpace(ctx, &provider.CreateStorageSpaceRequest{
+			Type:  spaces.SpaceTypeProject.AsString(),
+			Name:  name,
+			Quota: &provider.Quota{QuotaMaxBytes: 1024},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+func spaceNames(list []*provider.StorageSpace) []string {
+	names := []string{}
+	for _, s := range list {
+		names = append(names, s.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestListStorageSpaces(t *testing.T) {
+	fs := setupSpaces(t)
+
+	tests := []struct {
+		description string
+		user        *userpb.User
+		filters     spaces.ListStorageSpaceFilter
+		expected    []string
+	}{
+		{
+			description: "no filters",
+			user:        einstein,
+			expected:    []string{"einstein", "physics", "relativity"},
+		},
+		{
+			description: "spaces of other users are not listed",
+			user:        marie,
+			expected:    []string{"marie"},
+		},
+		{
+			description: "by space type",
+			user:        einstein,
+			filters:     spaces.ListStorageSpaceFilter{}.BySpaceType(spaces.SpaceTypeProject),
+			expected:    []string{"physics", "relativity"},
+		},
+		{
+			description: "by path",
+			user:        einstein,
+			filters:     spaces.ListStorageSpaceFilter{}.ByPath("/projects/physics"),
+			expected:    []string{"physics"},
+		},
+		{
+			description: "by id as returned by the storage provider",
+			user:        einstein,
+			filters:     spaces.ListStorageSpaceFilter{}.ByID(&provider.StorageSpaceId{OpaqueId: "storage-id!fileid-einstein%2Fprojects%2Frelativity"}),
+			expected:    []string{"relativity"},
+		},
+		{
+			description: "by encoded space id",
+			user:        einstein,
+			filters:     spaces.ListStorageSpaceFilter{}.ByID(&provider.StorageSpaceId{OpaqueId: spaces.EncodeSpaceID("storage-id", "/projects/physics")}),
+			expected:    []string{"physics"},
+		},
+		{
+			description: "filters of different types are and-ed",
+			user:        einstein,
+			filters:     spaces.ListStorageSpaceFilter{}.BySpaceType(spaces.SpaceTypeHome).ByPath("/projects/physics"),
+			expected:    []string{},
+		},
+		{
+			description: "filters of the same type are or-ed",
+			user:        einstein,
+			filters:     spaces.ListStorageSpaceFilter{}.BySpaceType(spaces.SpaceTypeHome).BySpaceType(spaces.SpaceTypeProject).ByOwner(einstein.Id),
+			expected:    []string{"einstein", "physics", "relativity"},
+		},
+		{
+			description: "by owner",
+			user:        einstein,
+			filters:     spaces.ListStorageSpaceFilter{}.ByOwner(marie.Id),
+			expected:    []string{},
+		},
+		{
+			description: "by user",
+			user:        einstein,
+			filters:     spaces.ListStorageSpaceFilter{}.ByUser(einstein.Id),
+			expected:    []string{"einstein", "physics", "relativity"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			ctx := appctx.ContextSetUser(context.Background(), tt.user)
+			list, err := fs.ListStorageSpaces(ctx, tt.filters.List())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := spaceNames(list)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("got %v, expected %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("got %v, expected %v", got, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestListStorageSpacesGrantees(t *testing.T) {
+	fs := setupSpaces(t)
+	feynman := &userpb.User{Id: &userpb.UserId{OpaqueId: "feynman", Idp: "cernbox.cern.ch", Type: userpb.UserType_USER_TYPE_PRIMARY}, Username: "feynman"}
+
+	grant := func(u *userpb.User) *provider.Grant {
+		return &provider.Grant{
+			Grantee: &provider.Grantee{
+				Type: provider.GranteeType_GRANTEE_TYPE_USER,
+				Id:   &provider.Grantee_UserId{UserId: u.Id},
+			},
+			Permissions: &provider.ResourcePermissions{Stat: true, ListContainer: true, InitiateFileDownload: true},
+		}
+	}
+
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	relativity := &provider.Reference{Path: "/projects/relativity"}
+	physics := &provider.Reference{Path: "/projects/physics"}
+	if err := fs.AddGrant(ctx, relativity, grant(marie)); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.AddGrant(ctx, physics, grant(feynman)); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.RemoveGrant(ctx, physics, grant(feynman)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		user        *userpb.User
+		filters     spaces.ListStorageSpaceFilter
+		expected    []string
+	}{
+		{
+			description: "granted spaces are listed",
+			user:        marie,
+			expected:    []string{"marie", "relativity"},
+		},
+		{
+			description: "removed grants give no access",
+			user:        feynman,
+			expected:    []string{},
+		},
+		{
+			description: "by grantee",
+			user:        einstein,
+			filters:     spaces.ListStorageSpaceFilter{}.ByUser(marie.Id),
+			expected:    []string{"relativity"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			ctx := appctx.ContextSetUser(context.Background(), tt.user)
+			list, err := fs.ListStorageSpaces(ctx, tt.filters.List())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := spaceNames(list); strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Fatalf("got %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCreateStorageSpaceAlreadyExists(t *testing.T) {
+	fs := setupSpaces(t)
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+
+	_, err := fs.CreateStorageSpace(ctx, &provider.CreateStorageSpaceRequest{
+		Type: spaces.SpaceTypeProject.AsString(),
+		Name: "physics",
+	})
+	if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+}
+
+func TestUpdateStorageSpace(t *testing.T) {
+	fs := setupSpaces(t)
+	id := &provider.StorageSpaceId{OpaqueId: "fileid-einstein%2Fprojects%2Fphysics"}
+
+	ctx := appctx.ContextSetUser(context.Background(), marie)
+	if _, err := fs.UpdateStorageSpace(ctx, &provider.UpdateStorageSpaceRequest{
+		StorageSpace: &provider.StorageSpace{Id: id, Name: "chemistry"},
+	}); err == nil {
+		t.Fatal("only the owner should be allowed to update a space")
+	}
+
+	ctx = appctx.ContextSetUser(context.Background(), einstein)
+	res, err := fs.UpdateStorageSpace(ctx, &provider.UpdateStorageSpaceRequest{
+		StorageSpace: &provider.StorageSpace{Id: id, Name: "particle physics", Quota: &provider.Quota{QuotaMaxBytes: 2048}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StorageSpace.Name != "particle physics" || res.StorageSpace.Quota.QuotaMaxBytes != 2048 {
+		t.Fatalf("space was not updated: %+v", res.StorageSpace)
+	}
+}
+
+func TestDeleteStorageSpace(t *testing.T) {
+	fs := setupSpaces(t)
+	id := &provider.StorageSpaceId{OpaqueId: "fileid-einstein%2Fprojects%2Fphysics"}
+	purge := &types.Opaque{Map: map[string]*types.OpaqueEntry{"purge": {Decoder: "plain", Value: []byte("true")}}}
+
+	ctx := appctx.ContextSetUser(context.Background(), marie)
+	if err := fs.DeleteStorageSpace(ctx, &provider.DeleteStorageSpaceRequest{Id: id}); err == nil {
+		t.Fatal("only the owner should be allowed to delete a space")
+	}
+
+	ctx = appctx.ContextSetUser(context.Background(), einstein)
+	err := fs.DeleteStorageSpace(ctx, &provider.DeleteStorageSpaceRequest{Id: &provider.StorageSpaceId{OpaqueId: "fileid-einstein"}})
+	if _, ok := err.(errtypes.PermissionDenied); !ok {
+		t.Fatalf("expected personal spaces not to be deletable, got %v", err)
+	}
+
+	if err := fs.DeleteStorageSpace(ctx, &provider.DeleteStorageSpaceRequest{Id: id, Opaque: purge}); err == nil {
+		t.Fatal("a space should be disabled before being purged")
+	}
+
+	if err := fs.DeleteStorageSpace(ctx, &provider.DeleteStorageSpaceRequest{Id: id}); err != nil {
+		t.Fatal(err)
+	}
+	list, err := fs.ListStorageSpaces(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := spaceNames(list); len(got) != 2 || got[0] != "einstein" || got[1] != "relativity" {
+		t.Fatalf("disabled space should not be listed, got %v", got)
+	}
+	if _, err := fs.GetMD(ctx, &provider.Reference{Path: "/projects/physics"}, nil); err == nil {
+		t.Fatal("the root of a disabled space should not be accessible")
+	}
+
+	if err := fs.DeleteStorageSpace(ctx, &provider.DeleteStorageSpaceRequest{Id: id, Opaque: purge}); err != nil {
+		t.Fatal(err)
+	}
+	err = fs.DeleteStorageSpace(ctx, &provider.DeleteStorageSpaceRequest{Id: id})
+	if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Fatalf("expected purged space not to be found, got %v", err)
+	}
+
+	// the name can be reused once the space is purged
+	if _, err := fs.CreateStorageSpace(ctx, &provider.CreateStorageSpaceRequest{
+		Type: spaces.SpaceTypeProject.AsString(),
+		Name: "physics",
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// number of directory entries read from disk at once by the streaming listings.
+const streamBatchSize = 1000
+
+var _ storage.StreamingFS = (*localfs)(nil)
+
+// ListFolderStream lists the given folder, reading its entries from disk in batches.
+func (fs *localfs) ListFolderStream(ctx context.Context, ref *provider.Reference, mdKeys []string) (storage.Iterator[*provider.ResourceInfo], error) {
+	fn, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "localfs: error resolving ref")
+	}
+
+	// the references under the share folder are few, and listed at once
+	if fs.isShareFolder(ctx, fn) {
+		mds, err := fs.ListFolder(ctx, ref, mdKeys)
+		if err != nil {
+			return nil, err
+		}
+		return storage.NewSliceIterator(mds), nil
+	}
+
+	var tail []*provider.ResourceInfo
+	if fn == "/" && !fs.conf.DisableHome {
+		if tail, err = fs.listShareFolderRoot(ctx, fn, mdKeys); err != nil {
+			return nil, err
+		}
+	}
+
+	np := fs.wrap(ctx, fn)
+	if err := fs.checkNamespace(ctx, np); err != nil {
+		return nil, err
+	}
+	locks, err := fs.getChildLocks(ctx, np)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.Open(np)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errtypes.NotFound(fn)
+		}
+		return nil, errors.Wrap(err, "localfs: error listing "+np)
+	}
+
+	return &dirIterator[*provider.ResourceInfo]{
+		dir: dir,
+		convert: func(fi os.FileInfo) (*provider.ResourceInfo, bool) {
+			cp := path.Join(np, fi.Name())
+			md, err := fs.normalizeWithLock(ctx, fi, cp, mdKeys, locks[cp])
+			return md, err == nil
+		},
+		tail: tail,
+	}, nil
+}
+
+// ListRecycleStream lists the recycle bin of the user, reading its entries from disk in batches.
+func (fs *localfs) ListRecycleStream(ctx context.Context, basePath, key, relativePath string, from, to *types.Timestamp) (storage.Iterator[*provider.RecycleItem], error) {
+	rp := fs.wrapRecycleBin(ctx, "/")
+
+	dir, err := os.Open(rp)
+	if err != nil {
+		return nil, errors.Wrap(err, "localfs: error listing deleted files")
+	}
+
+	return &dirIterator[*provider.RecycleItem]{
+		dir: dir,
+		convert: func(fi os.FileInfo) (*provider.RecycleItem, bool) {
+			ri := fs.convertToRecycleItem(ctx, rp, fi)
+			return ri, ri != nil
+		},
+	}, nil
+}
+
+// dirIterator converts the entries of a directory as they are read,
+// skipping the ones that cannot be converted, and then returns tail.
+type dirIterator[T any] struct {
+	dir     *os.File
+	batch   []os.DirEntry
+	convert func(fi os.FileInfo) (T, bool)
+	tail    []T
+}
+
+func (it *dirIterator[T]) Next() (T, error) {
+	var zero T
+	for it.dir != nil || len(it.batch) > 0 {
+		if len(it.batch) == 0 {
+			entries, err := it.dir.ReadDir(streamBatchSize)
+			if err == io.EOF {
+				_ = it.dir.Close()
+				it.dir = nil
+				continue
+			}
+			if err != nil {
+				return zero, errors.Wrap(err, "localfs: error reading "+it.dir.Name())
+			}
+			it.batch = entries
+			continue
+		}
+
+		entry := it.batch[0]
+		it.batch = it.batch[1:]
+		fi, err := entry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				// removed in the meantime
+				continue
+			}
+			return zero, err
+		}
+		if e, ok := it.convert(fi); ok {
+			return e, nil
+		}
+	}
+
+	if len(it.tail) == 0 {
+		return zero, io.EOF
+	}
+	e := it.tail[0]
+	it.tail = it.tail[1:]
+	return e, nil
+}
+
+func (it *dirIterator[T]) Close() error {
+	it.batch, it.tail = nil, nil
+	if it.dir == nil {
+		return nil
+	}
+	err := it.dir.Close()
+	it.dir = nil
+	return err
+}
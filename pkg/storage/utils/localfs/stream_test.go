@@ -0,0 +1,90 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+func TestListFolderStream(t *testing.T) {
+	fs, err := NewLocalFS(&Config{Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	if err := fs.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.CreateDir(ctx, &provider.Reference{Path: "/data"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// more entries than read from disk at once
+	n := streamBatchSize + 10
+	for i := 0; i < n; i++ {
+		if err := fs.CreateDir(ctx, &provider.Reference{Path: fmt.Sprintf("/data/dir-%05d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	it, err := storage.ListFolderStream(ctx, fs, &provider.Reference{Path: "/data"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+
+	var paths []string
+	for {
+		md, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, md.Path)
+	}
+
+	mds, err := fs.ListFolder(ctx, &provider.Reference{Path: "/data"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != len(mds) {
+		t.Fatalf("streamed %d entries, listed %d", len(paths), len(mds))
+	}
+	sort.Strings(paths)
+	for i := 0; i < n; i++ {
+		if expected := fmt.Sprintf("/data/dir-%05d", i); paths[i] != expected {
+			t.Fatalf("got %s, expected %s", paths[i], expected)
+		}
+	}
+
+	if _, err := storage.ListFolderStream(ctx, fs, &provider.Reference{Path: "/missing"}, nil); err == nil {
+		t.Fatal("listing a missing folder should fail upfront")
+	}
+}
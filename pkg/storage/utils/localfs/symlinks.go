@@ -0,0 +1,179 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/pkg/errors"
+)
+
+// CreateSymlink creates a symbolic link at the given reference pointing to target.
+// Relative targets are resolved against the folder containing the link,
+// absolute ones against the namespace of the user. In both cases the
+// target must stay within the namespace of the user.
+func (fs *localfs) CreateSymlink(ctx context.Context, ref *provider.Reference, target string) error {
+	fn, err := fs.resolve(ctx, ref)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error resolving ref")
+	}
+
+	if fs.isShareFolder(ctx, fn) {
+		return errtypes.PermissionDenied("localfs: cannot create symlinks under the virtual share folder")
+	}
+	if target == "" {
+		return errtypes.BadRequest("localfs: missing symlink target")
+	}
+
+	np := fs.wrap(ctx, fn)
+	if err := fs.checkNamespace(ctx, path.Dir(np)); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(np); err == nil {
+		return errtypes.AlreadyExists(fn)
+	}
+
+	linkTarget, err := fs.symlinkTarget(ctx, np, target)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Symlink(linkTarget, np); err != nil {
+		if os.IsNotExist(err) {
+			return errtypes.NotFound(fn)
+		}
+		return errors.Wrap(err, "localfs: error creating symlink "+np)
+	}
+
+	return fs.propagate(ctx, path.Dir(np))
+}
+
+// symlinkTarget returns the target to be stored on disk for a symlink
+// created at the internal path np. Absolute targets are made relative
+// to the link, so that they keep pointing inside the namespace of the user.
+func (fs *localfs) symlinkTarget(ctx context.Context, np, target string) (string, error) {
+	var resolved string
+	if path.IsAbs(target) {
+		resolved = fs.wrap(ctx, target)
+	} else {
+		resolved = filepath.Join(filepath.Dir(np), target)
+	}
+
+	if !isWithin(fs.wrap(ctx, "/"), resolved) {
+		return "", errtypes.PermissionDenied("localfs: symlink target " + target + " is outside of the user namespace")
+	}
+
+	if !path.IsAbs(target) {
+		return target, nil
+	}
+	rel, err := filepath.Rel(filepath.Dir(np), resolved)
+	if err != nil {
+		return "", errors.Wrap(err, "localfs: error computing symlink target")
+	}
+	return rel, nil
+}
+
+// maxSymlinkHops is the number of symlinks followed when checking a path,
+// as done by the kernel before failing with ELOOP.
+const maxSymlinkHops = 40
+
+// checkNamespace verifies that the internal path np still belongs to the
+// namespace of the user once the symlinks along it, including its last
+// element, are resolved. It must be called on the full path before any
+// operation following symlinks. Paths that do not exist yet are checked
+// through their closest existing ancestor, dangling symlinks through the
+// path they point to.
+func (fs *localfs) checkNamespace(ctx context.Context, np string) error {
+	ns := fs.wrap(ctx, "/")
+	if np != ns && isWithin(np, ns) {
+		// np is the parent of the namespace root, when operating on the root itself
+		return nil
+	}
+	root, err := filepath.EvalSymlinks(ns)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// nothing can point anywhere if the namespace does not exist yet
+			return nil
+		}
+		return errors.Wrap(err, "localfs: error resolving user namespace")
+	}
+
+	hops := 0
+	for p := np; ; {
+		real, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			if !isWithin(root, real) {
+				return errtypes.PermissionDenied("localfs: " + fs.unwrap(ctx, np) + " resolves outside of the user namespace")
+			}
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return errors.Wrap(err, "localfs: error resolving "+np)
+		}
+
+		if target, err := os.Readlink(p); err == nil {
+			// a dangling symlink, that operations may create its target through
+			if hops++; hops > maxSymlinkHops {
+				return errtypes.BadRequest("localfs: too many levels of symbolic links in " + fs.unwrap(ctx, np))
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(p), target)
+			}
+			p = target
+			continue
+		}
+
+		parent := filepath.Dir(p)
+		if parent == p {
+			return nil
+		}
+		p = parent
+	}
+}
+
+// checkMovedSymlink verifies that a symlink at the internal path np keeps
+// pointing inside the namespace of the user once moved to dst, as relative
+// targets are resolved against the location of the link.
+func (fs *localfs) checkMovedSymlink(ctx context.Context, np, dst string) error {
+	fi, err := os.Lstat(np)
+	if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		return nil
+	}
+	target, err := os.Readlink(np)
+	if err != nil {
+		return errors.Wrap(err, "localfs: error reading symlink "+np)
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(dst), target)
+	}
+	if !isWithin(fs.wrap(ctx, "/"), target) {
+		return errtypes.PermissionDenied("localfs: moving " + fs.unwrap(ctx, np) + " would make it point outside of the user namespace")
+	}
+	return nil
+}
+
+func isWithin(root, p string) bool {
+	return p == root || strings.HasPrefix(p, strings.TrimSuffix(root, "/")+"/")
+}
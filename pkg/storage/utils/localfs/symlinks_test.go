@@ -0,0 +1,321 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func TestCreateSymlink(t *testing.T) {
+	root := t.TempDir()
+	fs, err := NewLocalFS(&Config{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	if err := fs.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.CreateDir(ctx, &provider.Reference{Path: "/papers"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		path        string
+		target      string
+		expected    string
+		err         error
+	}{
+		{
+			description: "relative target",
+			path:        "/papers/link",
+			target:      "../notes",
+			expected:    "../notes",
+		},
+		{
+			description: "absolute targets are resolved in the user namespace",
+			path:        "/papers/abs",
+			target:      "/papers",
+			expected:    ".",
+		},
+		{
+			description: "relative target escaping the namespace",
+			path:        "/papers/escape",
+			target:      "../../../etc/passwd",
+			err:         errtypes.PermissionDenied(""),
+		},
+		{
+			description: "missing target",
+			path:        "/papers/empty",
+			err:         errtypes.BadRequest(""),
+		},
+		{
+			description: "existing resource",
+			path:        "/papers/link",
+			target:      "../other",
+			err:         errtypes.AlreadyExists(""),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			ref := &provider.Reference{Path: tt.path}
+			err := fs.CreateSymlink(ctx, ref, tt.target)
+			if tt.err != nil {
+				if err == nil || !sameErrType(err, tt.err) {
+					t.Fatalf("expected error of type %T, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			md, err := fs.GetMD(ctx, ref, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if md.Type != provider.ResourceType_RESOURCE_TYPE_SYMLINK || md.Target != tt.expected {
+				t.Fatalf("got type %s with target %q, expected a symlink to %q", md.Type, md.Target, tt.expected)
+			}
+		})
+	}
+
+	list, err := fs.ListFolder(ctx, &provider.Reference{Path: "/papers"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected the two symlinks to be listed, got %d entries", len(list))
+	}
+	for _, md := range list {
+		if md.Type != provider.ResourceType_RESOURCE_TYPE_SYMLINK {
+			t.Fatalf("%s should be listed as a symlink, got %s", md.Path, md.Type)
+		}
+	}
+}
+
+func TestSymlinkOutsideNamespace(t *testing.T) {
+	root := t.TempDir()
+	fs, err := NewLocalFS(&Config{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	if err := fs.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// links created behind the back of the storage, e.g. by a migration
+	home := filepath.Join(root, "data", "einstein")
+	if err := os.Symlink(t.TempDir(), filepath.Join(home, "outside")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.GetMD(ctx, &provider.Reference{Path: "/outside"}, nil); err != nil {
+		t.Fatalf("the link itself should be visible: %v", err)
+	}
+	for _, ref := range []*provider.Reference{{Path: "/outside"}, {Path: "/outside/file"}} {
+		if _, err := fs.ListFolder(ctx, ref, nil); !sameErrType(err, errtypes.PermissionDenied("")) {
+			t.Fatalf("expected permission denied listing %s, got %v", ref.Path, err)
+		}
+	}
+	if err := fs.CreateDir(ctx, &provider.Reference{Path: "/outside/dir"}); !sameErrType(err, errtypes.PermissionDenied("")) {
+		t.Fatalf("expected permission denied creating a folder through the link, got %v", err)
+	}
+	if _, err := fs.Download(ctx, &provider.Reference{Path: "/outside/file"}); !sameErrType(err, errtypes.PermissionDenied("")) {
+		t.Fatalf("expected permission denied downloading through the link, got %v", err)
+	}
+}
+
+func TestMovedSymlink(t *testing.T) {
+	root := t.TempDir()
+	fs, err := NewLocalFS(&Config{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []*userpb.User{einstein, marie} {
+		if err := fs.CreateHome(appctx.ContextSetUser(context.Background(), u)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	victim := filepath.Join(root, "data", "marie", "secret.txt")
+	if err := os.WriteFile(victim, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Unix(1000, 0)
+	if err := os.Chtimes(victim, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	if err := fs.CreateDir(ctx, &provider.Reference{Path: "/papers"}); err != nil {
+		t.Fatal(err)
+	}
+	// the link points inside the namespace of einstein, to /marie/secret.txt,
+	// but to the file of marie once moved one level up
+	if err := fs.CreateSymlink(ctx, &provider.Reference{Path: "/papers/link"}, "../marie/secret.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Move(ctx, &provider.Reference{Path: "/papers/link"}, &provider.Reference{Path: "/link"}); !sameErrType(err, errtypes.PermissionDenied("")) {
+		t.Fatalf("expected permission denied moving the link, got %v", err)
+	}
+
+	// a link moved behind the back of the storage is not followed
+	home := filepath.Join(root, "data", "einstein")
+	if err := os.Rename(filepath.Join(home, "papers", "link"), filepath.Join(home, "link")); err != nil {
+		t.Fatal(err)
+	}
+	ref := &provider.Reference{Path: "/link"}
+
+	tests := []struct {
+		description string
+		action      func() error
+	}{
+		{
+			description: "set mtime",
+			action: func() error {
+				return fs.SetArbitraryMetadata(ctx, ref, &provider.ArbitraryMetadata{Metadata: map[string]string{"mtime": "2000"}})
+			},
+		},
+		{
+			description: "unset metadata",
+			action:      func() error { return fs.UnsetArbitraryMetadata(ctx, ref, []string{"color"}) },
+		},
+		{
+			description: "download",
+			action:      func() error { _, err := fs.Download(ctx, ref); return err },
+		},
+		{
+			description: "lock",
+			action:      func() error { return fs.SetLock(ctx, ref, newLock("a", time.Hour)) },
+		},
+		{
+			description: "list revisions",
+			action:      func() error { _, err := fs.ListRevisions(ctx, ref); return err },
+		},
+		{
+			description: "touch",
+			action:      func() error { return fs.TouchFile(ctx, ref) },
+		},
+		{
+			description: "move",
+			action: func() error {
+				return fs.Move(ctx, ref, &provider.Reference{Path: "/papers/link"})
+			},
+		},
+		{
+			description: "delete",
+			action:      func() error { return fs.Delete(ctx, ref) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			if err := tt.action(); !sameErrType(err, errtypes.PermissionDenied("")) {
+				t.Fatalf("expected permission denied, got %v", err)
+			}
+		})
+	}
+
+	fi, err := os.Stat(victim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(mtime) {
+		t.Fatalf("the file of marie was modified through the link")
+	}
+}
+
+func TestDanglingSymlink(t *testing.T) {
+	root := t.TempDir()
+	fs, err := NewLocalFS(&Config{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	if err := fs.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// a dangling link to a file outside of the namespace
+	outside := filepath.Join(t.TempDir(), "created")
+	if err := os.Symlink(outside, filepath.Join(root, "data", "einstein", "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.TouchFile(ctx, &provider.Reference{Path: "/dangling"}); !sameErrType(err, errtypes.PermissionDenied("")) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	if _, err := os.Lstat(outside); !os.IsNotExist(err) {
+		t.Fatalf("a file was created through the link: %v", err)
+	}
+}
+
+func TestNamespaceRoot(t *testing.T) {
+	root := t.TempDir()
+	fs, err := NewLocalFS(&Config{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	if err := fs.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the parent of the namespace root is outside of the namespace,
+	// which must not prevent operating on the root itself
+	md, err := fs.GetMD(ctx, &provider.Reference{Path: "/"}, nil)
+	if err != nil {
+		t.Fatalf("expected the root to be stated: %v", err)
+	}
+	if md.Type != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		t.Fatalf("expected the root to be a container, got %v", md.Type)
+	}
+}
+
+func sameErrType(err, expected error) bool {
+	switch expected.(type) {
+	case errtypes.PermissionDenied:
+		_, ok := err.(errtypes.PermissionDenied)
+		return ok
+	case errtypes.BadRequest:
+		_, ok := err.(errtypes.BadRequest)
+		return ok
+	case errtypes.AlreadyExists:
+		_, ok := err.(errtypes.AlreadyExists)
+		return ok
+	case errtypes.NotFound:
+		_, ok := err.(errtypes.NotFound)
+		return ok
+	case errtypes.Conflict:
+		_, ok := err.(errtypes.Conflict)
+		return ok
+	}
+	return false
+}
@@ -24,11 +24,12 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/cs3org/reva/pkg/appctx"
-
+	"github.com/cs3org/reva/pkg/crypto"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/storage/utils/chunking"
 	"github.com/cs3org/reva/pkg/utils"
@@ -49,6 +50,13 @@ func (fs *localfs) Upload(ctx context.Context, ref *provider.Reference, r io.Rea
 
 	uploadInfo := upload.(*fileUpload)
 
+	// the lock may be presented to the data server rather than at initiation time
+	for _, k := range []string{"lockid", "lockholder"} {
+		if v := metadata[k]; v != "" {
+			uploadInfo.info.MetaData[k] = v
+		}
+	}
+
 	p := uploadInfo.info.Storage["InternalDestination"]
 	ok, err := chunking.IsChunked(p)
 	if err != nil {
@@ -109,6 +117,24 @@ func (fs *localfs) InitiateUpload(ctx context.Context, ref *provider.Reference,
 		if _, ok := metadata["sizedeferred"]; ok {
 			info.SizeIsDeferred = true
 		}
+		if metadata["lockid"] != "" {
+			info.MetaData["lockid"] = metadata["lockid"]
+		}
+		if metadata["checksum"] != "" {
+			info.MetaData["checksum"] = metadata["checksum"]
+		}
+		if metadata["concat"] == "partial" {
+			info.IsPartial = true
+		}
+	}
+
+	// fail early if the file is locked, the lock is checked again when the upload completes
+	if err := fs.checkLock(ctx, fs.wrap(ctx, np), info.MetaData["lockid"], ""); err != nil {
+		return nil, err
+	}
+
+	if concat := metadata["concat"]; strings.HasPrefix(concat, "final;") {
+		return fs.initiateFinalUpload(ctx, info, strings.Fields(strings.TrimPrefix(concat, "final;")))
 	}
 
 	upload, err := fs.NewUpload(ctx, info)
@@ -124,11 +150,16 @@ func (fs *localfs) InitiateUpload(ctx context.Context, ref *provider.Reference,
 	}, nil
 }
 
+// ConcatenatesUploads tells that the partial uploads can be concatenated.
+func (fs *localfs) ConcatenatesUploads() bool {
+	return true
+}
+
 // UseIn tells the tus upload middleware which extensions it supports.
 func (fs *localfs) UseIn(composer *tusd.StoreComposer) {
 	composer.UseCore(fs)
 	composer.UseTerminater(fs)
-	// TODO composer.UseConcater(fs)
+	composer.UseConcater(fs)
 	// TODO composer.UseLengthDeferrer(fs)
 }
 
@@ -152,6 +183,9 @@ func (fs *localfs) NewUpload(ctx context.Context, info tusd.FileInfo) (upload tu
 	info.MetaData["dir"] = filepath.Clean(info.MetaData["dir"])
 
 	np := fs.wrap(ctx, filepath.Join(info.MetaData["dir"], info.MetaData["filename"]))
+	if err := fs.checkNamespace(ctx, filepath.Dir(np)); err != nil {
+		return nil, err
+	}
 
 	log.Debug().Interface("info", info).Msg("localfs: resolved filename")
 
@@ -321,9 +355,27 @@ func (upload *fileUpload) FinishUpload(ctx context.Context) error {
 	// the local storage does not track revisions
 	//}
 
+	// partial uploads are only written once concatenated
+	if upload.info.IsPartial {
+		return nil
+	}
+
+	if checksum := upload.info.MetaData["checksum"]; checksum != "" {
+		if err := upload.verifyChecksum(checksum); err != nil {
+			if _, ok := err.(errtypes.IsNotSupported); !ok {
+				_ = upload.Terminate(ctx)
+				return err
+			}
+			log.Debug().Err(err).Msg("localfs: checksum not verified")
+		}
+	}
+
 	// if destination exists
 	log.Info().Str("oldpath", upload.binPath).Str("newpath", np).Msg("localfs: FinishUpload")
 	if _, err := os.Stat(np); err == nil {
+		if err := upload.fs.checkLock(upload.ctx, np, upload.info.MetaData["lockid"], upload.info.MetaData["lockholder"]); err != nil {
+			return err
+		}
 		// create revision
 		if err := upload.fs.archiveRevision(upload.ctx, np); err != nil {
 			return err
@@ -369,3 +421,113 @@ func (upload *fileUpload) Terminate(ctx context.Context) error {
 	}
 	return nil
 }
+
+func (upload *fileUpload) verifyChecksum(checksum string) error {
+	f, err := os.Open(upload.binPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return crypto.VerifyChecksum(f, checksum)
+}
+
+// To implement the concatenation extension as specified in https://tus.io/protocols/resumable-upload.html#concatenation
+// - the storage needs to implement AsConcatableUpload
+// - the upload needs to implement ConcatUploads
+
+// initiateFinalUpload creates the upload concatenating the partial uploads with
+// the given ids, and writes it to its destination.
+func (fs *localfs) initiateFinalUpload(ctx context.Context, info tusd.FileInfo, ids []string) (map[string]string, error) {
+	if len(ids) == 0 {
+		return nil, errtypes.BadRequest("localfs: no partial uploads to concatenate")
+	}
+	u := appctx.ContextMustGetUser(ctx)
+
+	partials := make([]tusd.Upload, 0, len(ids))
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, errtypes.NotFound("localfs: partial upload " + id)
+		}
+		partial, err := fs.GetUpload(ctx, id)
+		if err != nil {
+			if err == tusd.ErrNotFound {
+				return nil, errtypes.NotFound("localfs: partial upload " + id)
+			}
+			return nil, err
+		}
+		pinfo, _ := partial.GetInfo(ctx)
+		if !pinfo.IsPartial || pinfo.Storage["Idp"] != u.Id.Idp || pinfo.Storage["UserId"] != u.Id.OpaqueId {
+			return nil, errtypes.NotFound("localfs: partial upload " + id)
+		}
+		if pinfo.Offset != pinfo.Size {
+			return nil, errtypes.BadRequest("localfs: partial upload " + id + " is not complete")
+		}
+		info.Size += pinfo.Size
+		partials = append(partials, partial)
+	}
+	info.IsFinal = true
+	info.PartialUploads = ids
+
+	upload, err := fs.NewUpload(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.AsConcatableUpload(upload).ConcatUploads(ctx, partials); err != nil {
+		return nil, err
+	}
+
+	info, _ = upload.GetInfo(ctx)
+	return map[string]string{
+		"simple": info.ID,
+		"tus":    info.ID,
+	}, nil
+}
+
+// AsConcatableUpload returns a ConcatableUpload.
+func (fs *localfs) AsConcatableUpload(upload tusd.Upload) tusd.ConcatableUpload {
+	return upload.(*fileUpload)
+}
+
+// ConcatUploads appends the content of the partial uploads, and writes
+// the result to the internal destination. The partial uploads are removed.
+func (upload *fileUpload) ConcatUploads(ctx context.Context, partialUploads []tusd.Upload) error {
+	file, err := os.OpenFile(upload.binPath, os.O_WRONLY|os.O_APPEND, defaultFilePerm)
+	if err != nil {
+		return err
+	}
+
+	// the file is closed before finishing the upload, which reads it back
+	err = appendUploads(file, partialUploads)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := upload.FinishUpload(ctx); err != nil {
+		return err
+	}
+	for _, partial := range partialUploads {
+		if err := partial.(*fileUpload).Terminate(ctx); err != nil {
+			appctx.GetLogger(ctx).Error().Err(err).Msg("localfs: could not remove partial upload")
+		}
+	}
+	return nil
+}
+
+// appendUploads appends the content of the partial uploads to file.
+func appendUploads(file *os.File, partialUploads []tusd.Upload) error {
+	for _, partial := range partialUploads {
+		src, err := os.Open(partial.(*fileUpload).binPath)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package localfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/storage"
+)
+
+func setupUploads(t *testing.T) (storage.FS, context.Context) {
+	fs, err := NewLocalFS(&Config{Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	if err := fs.CreateHome(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return fs, ctx
+}
+
+// uploadPartials creates a partial upload for every part, and returns their ids.
+func uploadPartials(t *testing.T, fs storage.FS, ctx context.Context, parts ...string) []string {
+	t.Helper()
+	ids := make([]string, 0, len(parts))
+	for _, part := range parts {
+		res, err := fs.InitiateUpload(ctx, &provider.Reference{Path: "/file"}, int64(len(part)), map[string]string{"concat": "partial"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Upload(ctx, &provider.Reference{Path: res["simple"]}, io.NopCloser(strings.NewReader(part)), nil); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, res["tus"])
+	}
+	// partial uploads are not written to their destination
+	if _, err := fs.GetMD(ctx, &provider.Reference{Path: "/file"}, nil); err == nil {
+		t.Fatal("a partial upload should not create the file")
+	}
+	return ids
+}
+
+func TestConcatUploads(t *testing.T) {
+	fs, ctx := setupUploads(t)
+	ids := uploadPartials(t, fs, ctx, "hello ", "parallel ", "world")
+
+	sum := md5.Sum([]byte("hello parallel world"))
+	if _, err := fs.InitiateUpload(ctx, &provider.Reference{Path: "/file"}, 0, map[string]string{
+		"concat":   "final;" + strings.Join(ids, " "),
+		"checksum": "md5 " + hex.EncodeToString(sum[:]),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := fs.Download(ctx, &provider.Reference{Path: "/file"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte("hello parallel world")) {
+		t.Fatalf("unexpected content %q", data)
+	}
+
+	// the partial uploads are consumed by the final upload
+	_, err = fs.InitiateUpload(ctx, &provider.Reference{Path: "/other"}, 0, map[string]string{"concat": "final;" + ids[0]})
+	if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Fatalf("expected the partial upload not to be found, got %v", err)
+	}
+}
+
+func TestConcatUploadsChecksumMismatch(t *testing.T) {
+	fs, ctx := setupUploads(t)
+	ids := uploadPartials(t, fs, ctx, "hello ", "world")
+
+	_, err := fs.InitiateUpload(ctx, &provider.Reference{Path: "/file"}, 0, map[string]string{
+		"concat":   "final;" + strings.Join(ids, " "),
+		"checksum": "md5 " + strings.Repeat("0", 32),
+	})
+	if _, ok := err.(errtypes.IsChecksumMismatch); !ok {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+	if _, err := fs.GetMD(ctx, &provider.Reference{Path: "/file"}, nil); err == nil {
+		t.Fatal("the file should not be written when the checksum does not match")
+	}
+}
+
+func TestConcatUploadsOfOtherUsers(t *testing.T) {
+	fs, ctx := setupUploads(t)
+	ids := uploadPartials(t, fs, ctx, "hello")
+
+	mctx := appctx.ContextSetUser(context.Background(), marie)
+	if err := fs.CreateHome(mctx); err != nil {
+		t.Fatal(err)
+	}
+	_, err := fs.InitiateUpload(mctx, &provider.Reference{Path: "/file"}, 0, map[string]string{"concat": "final;" + ids[0]})
+	if _, ok := err.(errtypes.IsNotFound); !ok {
+		t.Fatalf("expected the partial upload of another user not to be found, got %v", err)
+	}
+
+	// incomplete partial uploads cannot be concatenated
+	res, err := fs.InitiateUpload(ctx, &provider.Reference{Path: "/file"}, 10, map[string]string{"concat": "partial"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fs.InitiateUpload(ctx, &provider.Reference{Path: "/file"}, 0, map[string]string{"concat": "final;" + ids[0] + " " + res["tus"]})
+	if _, ok := err.(errtypes.IsBadRequest); !ok {
+		t.Fatalf("expected incomplete partial uploads to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,368 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package tus implements a tus data store for the storage drivers that
+// cannot append to their files.
+//
+// The partial uploads are staged in a spool folder, on a local or shared
+// file system, and handed to the driver through its Upload path once they
+// are complete. Unfinished uploads expire after a configurable time.
+package tus
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+var defaultFilePerm = os.FileMode(0600)
+
+// purgeInterval is how often the expired uploads are removed from the spool.
+const purgeInterval = time.Minute
+
+// the metadata forwarded to the driver when the upload is committed.
+var committedMetadata = []string{"mtime", "checksum", "lockid", "lockholder"}
+
+// CommitFunc writes the content of a complete upload to the resource
+// referenced by ref, on behalf of the user in ctx.
+type CommitFunc func(ctx context.Context, ref *provider.Reference, r io.ReadCloser, metadata map[string]string) error
+
+// Store is a tusd data store staging the uploads in a spool folder.
+// It supports the core protocol, and the termination and expiration extensions.
+type Store struct {
+	spool      string
+	expiration time.Duration
+	commit     CommitFunc
+
+	mu            sync.Mutex
+	lastPurge     time.Time
+	purgeInterval time.Duration
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+// New returns a store staging the uploads in the spool folder, and writing
+// them through commit once complete. Unfinished uploads are removed after
+// the expiration time, which is checked periodically until the store is closed.
+func New(spool string, expiration time.Duration, commit CommitFunc) *Store {
+	return newStore(spool, expiration, purgeInterval, commit)
+}
+
+func newStore(spool string, expiration, purgeInterval time.Duration, commit CommitFunc) *Store {
+	s := &Store{
+		spool:         spool,
+		expiration:    expiration,
+		commit:        commit,
+		purgeInterval: purgeInterval,
+		done:          make(chan struct{}),
+	}
+	go s.janitor()
+	return s
+}
+
+// Close stops the periodic removal of the expired uploads.
+func (s *Store) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// janitor removes the expired uploads periodically, so that the uploads
+// abandoned by their clients do not pile up when no new upload is initiated.
+func (s *Store) janitor() {
+	ticker := time.NewTicker(s.purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.purge(context.Background())
+		}
+	}
+}
+
+// UseIn tells the tus upload middleware which extensions the store supports.
+func (s *Store) UseIn(composer *tusd.StoreComposer) {
+	composer.UseCore(s)
+	composer.UseTerminater(s)
+}
+
+// Initiate creates an upload of the given size to the resource referenced
+// by ref on behalf of the user in ctx, and returns its id.
+// The ref is given back to the CommitFunc once the upload is complete.
+func (s *Store) Initiate(ctx context.Context, ref *provider.Reference, size int64, metadata map[string]string) (string, error) {
+	u, ok := appctx.ContextGetUser(ctx)
+	if !ok {
+		return "", errtypes.UserRequired("tus: no user in ctx")
+	}
+	user, err := utils.MarshalProtoV1ToJSON(u)
+	if err != nil {
+		return "", errors.Wrap(err, "tus: error marshalling user")
+	}
+	target, err := utils.MarshalProtoV1ToJSON(ref)
+	if err != nil {
+		return "", errors.Wrap(err, "tus: error marshalling reference")
+	}
+
+	// the spooled uploads are written one by one through the driver
+	if metadata["concat"] != "" {
+		return "", errtypes.NotSupported("tus: uploads cannot be concatenated")
+	}
+
+	s.purge(ctx)
+
+	info := tusd.FileInfo{
+		Size:     size,
+		MetaData: tusd.MetaData{},
+		Storage: map[string]string{
+			"Type":      "SpoolStore",
+			"Reference": string(target),
+			"User":      string(user),
+			"Expires":   time.Now().Add(s.expiration).UTC().Format(time.RFC3339),
+		},
+	}
+	for _, k := range committedMetadata {
+		if v := metadata[k]; v != "" {
+			info.MetaData[k] = v
+		}
+	}
+
+	upload, err := s.NewUpload(ctx, info)
+	if err != nil {
+		return "", err
+	}
+	info, _ = upload.GetInfo(ctx)
+	return info.ID, nil
+}
+
+// NewUpload creates a new upload. The upload must be initiated through
+// Initiate, as tus clients cannot create uploads by themselves.
+func (s *Store) NewUpload(ctx context.Context, info tusd.FileInfo) (tusd.Upload, error) {
+	if info.Storage["Type"] != "SpoolStore" {
+		return nil, errtypes.NotSupported("tus: uploads must be initiated through the storage provider")
+	}
+	if err := os.MkdirAll(s.spool, 0700); err != nil {
+		return nil, errors.Wrap(err, "tus: error creating spool folder")
+	}
+
+	info.ID = uuid.New().String()
+	u := &upload{
+		info:     info,
+		binPath:  filepath.Join(s.spool, info.ID),
+		infoPath: filepath.Join(s.spool, info.ID+".info"),
+		store:    s,
+	}
+	file, err := os.OpenFile(u.binPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, defaultFilePerm)
+	if err != nil {
+		return nil, errors.Wrap(err, "tus: error creating upload file")
+	}
+	defer file.Close()
+
+	if err := u.writeInfo(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// GetUpload returns the unfinished upload with the given id.
+func (s *Store) GetUpload(ctx context.Context, id string) (tusd.Upload, error) {
+	// the id comes from the url, make sure it does not escape the spool
+	if id == "" || id != filepath.Base(id) || strings.HasSuffix(id, ".info") {
+		return nil, tusd.ErrNotFound
+	}
+	u := &upload{
+		binPath:  filepath.Join(s.spool, id),
+		infoPath: filepath.Join(s.spool, id+".info"),
+		store:    s,
+	}
+	data, err := os.ReadFile(u.infoPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, tusd.ErrNotFound
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &u.info); err != nil {
+		return nil, err
+	}
+
+	if u.expired(time.Now()) {
+		_ = u.Terminate(ctx)
+		return nil, tusd.ErrNotFound
+	}
+
+	stat, err := os.Stat(u.binPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, tusd.ErrNotFound
+		}
+		return nil, err
+	}
+	u.info.Offset = stat.Size()
+	return u, nil
+}
+
+// Expiration returns the time after which the unfinished upload with
+// the given id is removed.
+func (s *Store) Expiration(ctx context.Context, id string) (time.Time, bool) {
+	u, err := s.GetUpload(ctx, id)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return u.(*upload).expires(), true
+}
+
+// AsTerminatableUpload returns a TerminatableUpload.
+func (s *Store) AsTerminatableUpload(u tusd.Upload) tusd.TerminatableUpload {
+	return u.(*upload)
+}
+
+// purge removes the expired uploads from the spool, at most once per purge interval.
+func (s *Store) purge(ctx context.Context) {
+	s.mu.Lock()
+	now := time.Now()
+	if now.Sub(s.lastPurge) < s.purgeInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPurge = now
+	s.mu.Unlock()
+
+	log := appctx.GetLogger(ctx)
+	infos, err := filepath.Glob(filepath.Join(s.spool, "*.info"))
+	if err != nil {
+		return
+	}
+	for _, p := range infos {
+		if _, err := s.GetUpload(ctx, strings.TrimSuffix(filepath.Base(p), ".info")); err == tusd.ErrNotFound {
+			log.Debug().Str("upload", p).Msg("tus: purged expired upload")
+		}
+	}
+}
+
+type upload struct {
+	// info stores the current information about the upload
+	info tusd.FileInfo
+	// infoPath is the path to the .info file
+	infoPath string
+	// binPath is the path to the staged content
+	binPath string
+	store   *Store
+}
+
+func (u *upload) expires() time.Time {
+	t, _ := time.Parse(time.RFC3339, u.info.Storage["Expires"])
+	return t
+}
+
+func (u *upload) expired(now time.Time) bool {
+	return now.After(u.expires())
+}
+
+// GetInfo returns the FileInfo.
+func (u *upload) GetInfo(ctx context.Context) (tusd.FileInfo, error) {
+	return u.info, nil
+}
+
+// GetReader returns an io.Reader for the staged content.
+func (u *upload) GetReader(ctx context.Context) (io.Reader, error) {
+	return os.Open(u.binPath)
+}
+
+// WriteChunk appends the stream from the reader to the staged content.
+func (u *upload) WriteChunk(ctx context.Context, offset int64, src io.Reader) (int64, error) {
+	file, err := os.OpenFile(u.binPath, os.O_WRONLY|os.O_APPEND, defaultFilePerm)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	n, err := io.Copy(file, src)
+	// an interrupted PATCH request is expected, the client resumes from the new offset
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return n, err
+	}
+	u.info.Offset += n
+	return n, nil
+}
+
+// writeInfo updates the entire information. Everything will be overwritten.
+func (u *upload) writeInfo() error {
+	data, err := json.Marshal(u.info)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(u.infoPath, data, defaultFilePerm)
+}
+
+// FinishUpload writes the staged content through the driver on behalf
+// of the user who initiated the upload, and removes it from the spool.
+func (u *upload) FinishUpload(ctx context.Context) error {
+	user := &userpb.User{}
+	if err := utils.UnmarshalJSONToProtoV1([]byte(u.info.Storage["User"]), user); err != nil {
+		return errors.Wrap(err, "tus: error unmarshalling user")
+	}
+	ref := &provider.Reference{}
+	if err := utils.UnmarshalJSONToProtoV1([]byte(u.info.Storage["Reference"]), ref); err != nil {
+		return errors.Wrap(err, "tus: error unmarshalling reference")
+	}
+	// tusd does not give us the context of the request
+	ctx = appctx.ContextSetUser(ctx, user)
+
+	file, err := os.Open(u.binPath)
+	if err != nil {
+		return errors.Wrap(err, "tus: error opening staged upload")
+	}
+	defer file.Close()
+	metadata := map[string]string{}
+	for _, k := range committedMetadata {
+		if v := u.info.MetaData[k]; v != "" {
+			metadata[k] = v
+		}
+	}
+	if err := u.store.commit(ctx, ref, file, metadata); err != nil {
+		return err
+	}
+
+	// the upload is only removed once committed, so that it can be retried
+	return u.Terminate(ctx)
+}
+
+// Terminate removes the upload from the spool.
+func (u *upload) Terminate(ctx context.Context) error {
+	if err := os.Remove(u.infoPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(u.binPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
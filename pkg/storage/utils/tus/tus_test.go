@@ -0,0 +1,162 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package tus
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/errtypes"
+	tusd "github.com/tus/tusd/pkg/handler"
+)
+
+var einstein = &userpb.User{Id: &userpb.UserId{OpaqueId: "einstein", Idp: "cernbox.cern.ch"}, Username: "einstein", UidNumber: 1000}
+
+func TestCommit(t *testing.T) {
+	var committed string
+	s := New(t.TempDir(), time.Hour, func(ctx context.Context, ref *provider.Reference, r io.ReadCloser, metadata map[string]string) error {
+		u := appctx.ContextMustGetUser(ctx)
+		if u.UidNumber != einstein.UidNumber || ref.Path != "/file.txt" || metadata["mtime"] != "42" {
+			t.Fatalf("unexpected commit of %v by %v with %v", ref, u, metadata)
+		}
+		b, _ := io.ReadAll(r)
+		committed = string(b)
+		return nil
+	})
+	defer s.Close()
+
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	id, err := s.Initiate(ctx, &provider.Reference{Path: "/file.txt"}, 4, map[string]string{"mtime": "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expires, ok := s.Expiration(ctx, id); !ok || expires.Before(time.Now()) {
+		t.Fatalf("unexpected expiration %v", expires)
+	}
+
+	// tusd does not propagate the context of the requests
+	for _, chunk := range []string{"ab", "cd"} {
+		u, err := s.GetUpload(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, _ := u.GetInfo(context.Background())
+		if _, err := u.WriteChunk(context.Background(), info.Offset, strings.NewReader(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u, err := s.GetUpload(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := u.FinishUpload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if committed != "abcd" {
+		t.Fatalf("unexpected content %q", committed)
+	}
+	if _, err := s.GetUpload(context.Background(), id); err != tusd.ErrNotFound {
+		t.Fatalf("expected committed upload to be removed, got %v", err)
+	}
+}
+
+func TestExpirationAndTermination(t *testing.T) {
+	s := New(t.TempDir(), -time.Second, nil)
+	defer s.Close()
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+
+	id, err := s.Initiate(ctx, &provider.Reference{Path: "/file.txt"}, 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetUpload(ctx, id); err != tusd.ErrNotFound {
+		t.Fatalf("expected expired upload to be removed, got %v", err)
+	}
+
+	s.expiration = time.Hour
+	id, err = s.Initiate(ctx, &provider.Reference{Path: "/file.txt"}, 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := s.GetUpload(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AsTerminatableUpload(u).Terminate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetUpload(ctx, id); err != tusd.ErrNotFound {
+		t.Fatalf("expected terminated upload to be removed, got %v", err)
+	}
+
+	for _, id := range []string{"", "../file", id + ".info"} {
+		if _, err := s.GetUpload(ctx, id); err != tusd.ErrNotFound {
+			t.Fatalf("expected upload %q not to be found, got %v", id, err)
+		}
+	}
+}
+
+func TestPeriodicPurge(t *testing.T) {
+	spool := t.TempDir()
+	s := newStore(spool, 50*time.Millisecond, 10*time.Millisecond, nil)
+	defer s.Close()
+
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+	id, err := s.Initiate(ctx, &provider.Reference{Path: "/file.txt"}, 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nobody accesses the upload again, the janitor has to remove it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := os.Stat(filepath.Join(spool, id))
+		if os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected expired upload to be purged, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat(filepath.Join(spool, id+".info")); !os.IsNotExist(err) {
+		t.Fatalf("expected the info of the expired upload to be purged, got %v", err)
+	}
+}
+
+func TestConcatenationNotSupported(t *testing.T) {
+	s := New(t.TempDir(), time.Hour, nil)
+	defer s.Close()
+	ctx := appctx.ContextSetUser(context.Background(), einstein)
+
+	for _, concat := range []string{"partial", "final;a b"} {
+		_, err := s.Initiate(ctx, &provider.Reference{Path: "/file.txt"}, 4, map[string]string{"concat": concat})
+		if _, ok := err.(errtypes.IsNotSupported); !ok {
+			t.Errorf("expected a not supported error for %q, got %v", concat, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/tags"
+	"github.com/cs3org/reva/pkg/tags/registry"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type mgr struct {
+	sync.RWMutex
+	lastID int
+	byName map[string]*tags.Tag
+	byID   map[string]*tags.Tag
+	// resources maps the tag ids to the resources they are attached to
+	resources map[string]map[string]*provider.ResourceId
+}
+
+// New returns an instance of the in-memory tags manager.
+func New(ctx context.Context, m map[string]interface{}) (tags.Manager, error) {
+	return &mgr{
+		byName:    make(map[string]*tags.Tag),
+		byID:      make(map[string]*tags.Tag),
+		resources: make(map[string]map[string]*provider.ResourceId),
+	}, nil
+}
+
+func resourceKey(id *provider.ResourceId) string {
+	return id.StorageId + "!" + id.OpaqueId
+}
+
+func (m *mgr) ListTags(_ context.Context) ([]*tags.Tag, error) {
+	m.RLock()
+	defer m.RUnlock()
+	list := make([]*tags.Tag, 0, len(m.byID))
+	for _, t := range m.byID {
+		list = append(list, &tags.Tag{ID: t.ID, Name: t.Name})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+func (m *mgr) GetTag(_ context.Context, id string) (*tags.Tag, error) {
+	m.RLock()
+	defer m.RUnlock()
+	t, ok := m.byID[id]
+	if !ok {
+		return nil, errtypes.NotFound("tag " + id)
+	}
+	return &tags.Tag{ID: t.ID, Name: t.Name}, nil
+}
+
+func (m *mgr) TagResource(_ context.Context, id *provider.ResourceId, names ...string) ([]*tags.Tag, error) {
+	m.Lock()
+	defer m.Unlock()
+	attached := make([]*tags.Tag, 0, len(names))
+	for _, n := range names {
+		t, ok := m.byName[n]
+		if !ok {
+			m.lastID++
+			t = &tags.Tag{ID: strconv.Itoa(m.lastID), Name: n}
+			m.byName[n], m.byID[t.ID] = t, t
+			m.resources[t.ID] = make(map[string]*provider.ResourceId)
+		}
+		m.resources[t.ID][resourceKey(id)] = id
+		attached = append(attached, &tags.Tag{ID: t.ID, Name: t.Name})
+	}
+	return attached, nil
+}
+
+func (m *mgr) UntagResource(_ context.Context, id *provider.ResourceId, names ...string) error {
+	m.Lock()
+	defer m.Unlock()
+	for _, n := range names {
+		if t, ok := m.byName[n]; ok {
+			delete(m.resources[t.ID], resourceKey(id))
+		}
+	}
+	return nil
+}
+
+func (m *mgr) ListResources(_ context.Context, tagID string) ([]*provider.ResourceId, error) {
+	m.RLock()
+	defer m.RUnlock()
+	if _, ok := m.byID[tagID]; !ok {
+		return nil, errtypes.NotFound("tag " + tagID)
+	}
+	ids := make([]*provider.ResourceId, 0, len(m.resources[tagID]))
+	for _, id := range m.resources[tagID] {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
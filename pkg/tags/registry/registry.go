@@ -0,0 +1,75 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/tags"
+)
+
+// NewFunc is the function that tags manager implementations
+// should register at init time.
+type NewFunc func(context.Context, map[string]interface{}) (tags.Manager, error)
+
+// NewFuncs is a map containing all the registered tags managers.
+var NewFuncs = map[string]NewFunc{}
+
+// Register registers a new tags manager function.
+// Not safe for concurrent use. Safe for use from package init.
+func Register(name string, f NewFunc) {
+	NewFuncs[name] = f
+}
+
+var (
+	managersMu sync.Mutex
+	managers   = map[string]tags.Manager{}
+)
+
+// GetManager returns the tags manager registered under name, configured with m.
+// The services asking for the same driver with the same configuration share
+// the same instance, so that they see the same system tags even when the
+// driver keeps them in memory.
+func GetManager(ctx context.Context, name string, m map[string]interface{}) (tags.Manager, error) {
+	f, ok := NewFuncs[name]
+	if !ok {
+		return nil, errtypes.NotFound("driver not found: " + name)
+	}
+
+	conf, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	key := name + ":" + string(conf)
+
+	managersMu.Lock()
+	defer managersMu.Unlock()
+	if tm, ok := managers[key]; ok {
+		return tm, nil
+	}
+	tm, err := f(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	managers[key] = tm
+	return tm, nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/tags"
+	"github.com/cs3org/reva/pkg/tags/registry"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+	// Provides the sqlite3 database/sql driver.
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("sqlite", New)
+}
+
+type config struct {
+	DBFile string `docs:"/var/tmp/reva/tags.db;The sqlite file holding the tags." mapstructure:"db_file"`
+}
+
+func (c *config) ApplyDefaults() {
+	if c.DBFile == "" {
+		c.DBFile = "/var/tmp/reva/tags.db"
+	}
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS assignments (
+	tag INTEGER NOT NULL REFERENCES tags (id),
+	storage_id TEXT NOT NULL,
+	opaque_id TEXT NOT NULL,
+	PRIMARY KEY (tag, storage_id, opaque_id)
+);`
+
+type mgr struct {
+	db *sql.DB
+}
+
+// New returns a tags manager storing the tags in a sqlite database.
+func New(ctx context.Context, m map[string]interface{}) (tags.Manager, error) {
+	var c config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.DBFile), 0700); err != nil {
+		return nil, errors.Wrap(err, "sqlite: error creating tags folder")
+	}
+	// writers are serialized by sqlite, wait for the lock instead of failing
+	db, err := sql.Open("sqlite3", "file:"+c.DBFile+"?_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, errors.Wrap(err, "sqlite: error opening tags database")
+	}
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, errors.Wrap(err, "sqlite: error creating tags tables")
+	}
+	return &mgr{db: db}, nil
+}
+
+func (m *mgr) ListTags(ctx context.Context) ([]*tags.Tag, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT id, name FROM tags ORDER BY name")
+	if err != nil {
+		return nil, errors.Wrap(err, "sqlite: error listing tags")
+	}
+	defer rows.Close()
+
+	list := []*tags.Tag{}
+	for rows.Next() {
+		var id int64
+		t := &tags.Tag{}
+		if err := rows.Scan(&id, &t.Name); err != nil {
+			return nil, errors.Wrap(err, "sqlite: error reading tags")
+		}
+		t.ID = strconv.FormatInt(id, 10)
+		list = append(list, t)
+	}
+	return list, errors.Wrap(rows.Err(), "sqlite: error reading tags")
+}
+
+func (m *mgr) GetTag(ctx context.Context, id string) (*tags.Tag, error) {
+	t := &tags.Tag{ID: id}
+	if err := m.db.QueryRowContext(ctx, "SELECT name FROM tags WHERE id = ?", id).Scan(&t.Name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errtypes.NotFound("tag " + id)
+		}
+		return nil, errors.Wrap(err, "sqlite: error reading tag "+id)
+	}
+	return t, nil
+}
+
+func (m *mgr) TagResource(ctx context.Context, id *provider.ResourceId, names ...string) ([]*tags.Tag, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "sqlite: error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	attached := make([]*tags.Tag, 0, len(names))
+	for _, n := range names {
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO tags (name) VALUES (?)", n); err != nil {
+			return nil, errors.Wrap(err, "sqlite: error creating tag "+n)
+		}
+		var tagID int64
+		if err := tx.QueryRowContext(ctx, "SELECT id FROM tags WHERE name = ?", n).Scan(&tagID); err != nil {
+			return nil, errors.Wrap(err, "sqlite: error reading tag "+n)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO assignments (tag, storage_id, opaque_id) VALUES (?, ?, ?)",
+			tagID, id.StorageId, id.OpaqueId); err != nil {
+			return nil, errors.Wrap(err, "sqlite: error tagging resource with "+n)
+		}
+		attached = append(attached, &tags.Tag{ID: strconv.FormatInt(tagID, 10), Name: n})
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "sqlite: error committing transaction")
+	}
+	return attached, nil
+}
+
+func (m *mgr) UntagResource(ctx context.Context, id *provider.ResourceId, names ...string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "sqlite: error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, n := range names {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM assignments WHERE tag = (SELECT id FROM tags WHERE name = ?) AND storage_id = ? AND opaque_id = ?",
+			n, id.StorageId, id.OpaqueId); err != nil {
+			return errors.Wrap(err, "sqlite: error untagging resource from "+n)
+		}
+	}
+	return errors.Wrap(tx.Commit(), "sqlite: error committing transaction")
+}
+
+func (m *mgr) ListResources(ctx context.Context, tagID string) ([]*provider.ResourceId, error) {
+	if _, err := m.GetTag(ctx, tagID); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, "SELECT storage_id, opaque_id FROM assignments WHERE tag = ?", tagID)
+	if err != nil {
+		return nil, errors.Wrap(err, "sqlite: error listing resources tagged with "+tagID)
+	}
+	defer rows.Close()
+
+	ids := []*provider.ResourceId{}
+	for rows.Next() {
+		id := &provider.ResourceId{}
+		if err := rows.Scan(&id.StorageId, &id.OpaqueId); err != nil {
+			return nil, errors.Wrap(err, "sqlite: error reading tagged resources")
+		}
+		ids = append(ids, id)
+	}
+	return ids, errors.Wrap(rows.Err(), "sqlite: error reading tagged resources")
+}
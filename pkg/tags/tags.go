@@ -0,0 +1,264 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package tags implements the labels that users attach to resources.
+//
+// System tags are shared by all the users: they are stored in the
+// arbitrary metadata of the resources, and recorded in a Manager that
+// assigns them an id and keeps track of the resources they are attached to.
+// User tags are private to the user who set them, and only live in the
+// arbitrary metadata of the resources.
+package tags
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+// SystemTagsKey is the arbitrary metadata key holding the system tags of a resource.
+const SystemTagsKey = "tags"
+
+// UserTagsKey returns the arbitrary metadata key holding the tags set by the user u.
+// The key includes the identity provider of the user, so that users with the
+// same username coming from different providers do not share their tags.
+func UserTagsKey(u *userpb.UserId) string {
+	return SystemTagsKey + "." + escapeKey(u.Idp) + "." + escapeKey(u.OpaqueId)
+}
+
+// StripUserTags removes from the arbitrary metadata of the resource the
+// tags set by the users other than u, as user tags are private.
+// All the user tags are removed when u is nil.
+func StripUserTags(md *provider.ResourceInfo, u *userpb.UserId) {
+	m := md.GetArbitraryMetadata().GetMetadata()
+	var own string
+	if u != nil {
+		own = UserTagsKey(u)
+	}
+	for k := range m {
+		if strings.HasPrefix(k, SystemTagsKey+".") && k != own {
+			delete(m, k)
+		}
+	}
+}
+
+// escapeKey escapes s to be used as a component of an arbitrary metadata key,
+// where the components are separated by dots.
+func escapeKey(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), ".", "%2E")
+}
+
+// Tag is a system tag.
+type Tag struct {
+	ID   string
+	Name string
+}
+
+// Manager records the system tags and the resources they are attached to.
+type Manager interface {
+	// ListTags returns all the system tags.
+	ListTags(ctx context.Context) ([]*Tag, error)
+	// GetTag returns the system tag with the given id.
+	GetTag(ctx context.Context, id string) (*Tag, error)
+	// TagResource attaches the tags with the given names to the resource,
+	// creating the ones that do not exist yet.
+	TagResource(ctx context.Context, id *provider.ResourceId, names ...string) ([]*Tag, error)
+	// UntagResource detaches the tags with the given names from the resource.
+	UntagResource(ctx context.Context, id *provider.ResourceId, names ...string) error
+	// ListResources returns the resources the tag with the given id is attached to.
+	ListResources(ctx context.Context, tagID string) ([]*provider.ResourceId, error)
+}
+
+// Parse returns the tags stored in the arbitrary metadata value v.
+func Parse(v string) []string {
+	names := []string{}
+	for _, n := range strings.Split(v, ",") {
+		if n = strings.TrimSpace(n); n != "" && !contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// Format returns the arbitrary metadata value storing the tags with the given names.
+func Format(names []string) string {
+	return strings.Join(names, ",")
+}
+
+// Validate checks that the names are valid tag names.
+func Validate(names []string) error {
+	if len(names) == 0 {
+		return errtypes.BadRequest("tags: no tags given")
+	}
+	for _, n := range names {
+		if strings.TrimSpace(n) == "" || strings.Contains(n, ",") {
+			return errtypes.BadRequest("tags: invalid tag name " + n)
+		}
+	}
+	return nil
+}
+
+func contains(names []string, n string) bool {
+	for _, m := range names {
+		if m == n {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge returns the tags in names followed by the tags in added that are not in names.
+func Merge(names, added []string) []string {
+	merged := append([]string{}, names...)
+	for _, n := range added {
+		if n = strings.TrimSpace(n); !contains(merged, n) {
+			merged = append(merged, n)
+		}
+	}
+	return merged
+}
+
+// Subtract returns the tags in names that are not in removed.
+func Subtract(names, removed []string) []string {
+	kept := []string{}
+	for _, n := range names {
+		if !contains(removed, n) {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+// Get returns the tags stored in the arbitrary metadata of the resource under key.
+func Get(md *provider.ResourceInfo, key string) []string {
+	return Parse(md.GetArbitraryMetadata().GetMetadata()[key])
+}
+
+// statusError returns the error matching the failed status st.
+func statusError(st *rpc.Status) error {
+	switch st.Code {
+	case rpc.Code_CODE_NOT_FOUND:
+		return errtypes.NotFound(st.Message)
+	case rpc.Code_CODE_PERMISSION_DENIED:
+		return errtypes.PermissionDenied(st.Message)
+	case rpc.Code_CODE_INVALID_ARGUMENT:
+		return errtypes.BadRequest(st.Message)
+	case rpc.Code_CODE_LOCKED:
+		return errtypes.Locked(st.Message)
+	default:
+		return errtypes.InternalError(st.Code.String() + ": " + st.Message)
+	}
+}
+
+// stat returns the resource referenced by ref along with its tags stored under key.
+func stat(ctx context.Context, client gateway.GatewayAPIClient, ref *provider.Reference, key string) (*provider.ResourceInfo, []string, error) {
+	res, err := client.Stat(ctx, &provider.StatRequest{Ref: ref, ArbitraryMetadataKeys: []string{key}})
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return nil, nil, statusError(res.Status)
+	}
+	return res.Info, Get(res.Info, key), nil
+}
+
+// store writes the tags of the resource referenced by ref under key.
+func store(ctx context.Context, client gateway.GatewayAPIClient, ref *provider.Reference, key string, names []string) error {
+	if len(names) == 0 {
+		res, err := client.UnsetArbitraryMetadata(ctx, &provider.UnsetArbitraryMetadataRequest{Ref: ref, ArbitraryMetadataKeys: []string{key}})
+		if err != nil {
+			return err
+		}
+		if res.Status.Code != rpc.Code_CODE_OK {
+			return statusError(res.Status)
+		}
+		return nil
+	}
+
+	res, err := client.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+		Ref:               ref,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{key: Format(names)}},
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status.Code != rpc.Code_CODE_OK {
+		return statusError(res.Status)
+	}
+	return nil
+}
+
+// Assign attaches the system tags with the given names to the resource referenced by ref.
+func Assign(ctx context.Context, client gateway.GatewayAPIClient, m Manager, ref *provider.Reference, names []string) error {
+	if err := Validate(names); err != nil {
+		return err
+	}
+	names = Parse(Format(names))
+	info, current, err := stat(ctx, client, ref, SystemTagsKey)
+	if err != nil {
+		return err
+	}
+	if err := store(ctx, client, ref, SystemTagsKey, Merge(current, names)); err != nil {
+		return err
+	}
+	_, err = m.TagResource(ctx, info.Id, names...)
+	return err
+}
+
+// Unassign detaches the system tags with the given names from the resource referenced by ref.
+func Unassign(ctx context.Context, client gateway.GatewayAPIClient, m Manager, ref *provider.Reference, names []string) error {
+	if err := Validate(names); err != nil {
+		return err
+	}
+	names = Parse(Format(names))
+	info, current, err := stat(ctx, client, ref, SystemTagsKey)
+	if err != nil {
+		return err
+	}
+	if err := store(ctx, client, ref, SystemTagsKey, Subtract(current, names)); err != nil {
+		return err
+	}
+	return m.UntagResource(ctx, info.Id, names...)
+}
+
+// SetUserTags replaces the tags set by the user u on the resource referenced by ref.
+func SetUserTags(ctx context.Context, client gateway.GatewayAPIClient, u *userpb.UserId, ref *provider.Reference, names []string) error {
+	if len(names) > 0 {
+		if err := Validate(names); err != nil {
+			return err
+		}
+	}
+	return store(ctx, client, ref, UserTagsKey(u), Parse(Format(names)))
+}
+
+// Names returns the names of the given tags, sorted.
+func Names(tags []*Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.Name)
+	}
+	sort.Strings(names)
+	return names
+}
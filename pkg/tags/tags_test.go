@@ -0,0 +1,270 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package tags_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/tags"
+	"github.com/cs3org/reva/pkg/tags/memory"
+	"github.com/cs3org/reva/pkg/tags/registry"
+	"github.com/cs3org/reva/pkg/tags/sqlite"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string][]string{
+		"":                      {},
+		"physics":               {"physics"},
+		" physics , maths ,":    {"physics", "maths"},
+		"physics,maths,physics": {"physics", "maths"},
+	}
+	for v, expected := range tests {
+		if got := tags.Parse(v); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Parse(%q) = %v, expected %v", v, got, expected)
+		}
+	}
+}
+
+func TestMergeSubtract(t *testing.T) {
+	names := []string{"physics", "maths"}
+	if got := tags.Merge(names, []string{"maths", "chemistry"}); !reflect.DeepEqual(got, []string{"physics", "maths", "chemistry"}) {
+		t.Errorf("unexpected merged tags %v", got)
+	}
+	if got := tags.Subtract(names, []string{"maths", "chemistry"}); !reflect.DeepEqual(got, []string{"physics"}) {
+		t.Errorf("unexpected subtracted tags %v", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, names := range [][]string{nil, {""}, {"physics", " "}, {"physics,maths"}} {
+		if _, ok := tags.Validate(names).(errtypes.IsBadRequest); !ok {
+			t.Errorf("expected %q to be invalid", names)
+		}
+	}
+	if err := tags.Validate([]string{"physics", "quantum mechanics"}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestUserTagsKey(t *testing.T) {
+	tests := []struct {
+		description string
+		id          *userpb.UserId
+		expected    string
+	}{
+		{
+			description: "plain",
+			id:          &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"},
+			expected:    "tags.cernbox.einstein",
+		},
+		{
+			description: "dots are escaped",
+			id:          &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "albert.einstein"},
+			expected:    "tags.cernbox%2Ecern%2Ech.albert%2Eeinstein",
+		},
+		{
+			description: "url idp",
+			id:          &userpb.UserId{Idp: "https://idp.example.org/", OpaqueId: "einstein"},
+			expected:    "tags.https%3A%2F%2Fidp%2Eexample%2Eorg%2F.einstein",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			if got := tags.UserTagsKey(tt.id); got != tt.expected {
+				t.Errorf("UserTagsKey(%v) = %q, expected %q", tt.id, got, tt.expected)
+			}
+		})
+	}
+
+	a := tags.UserTagsKey(&userpb.UserId{Idp: "a.b", OpaqueId: "c"})
+	b := tags.UserTagsKey(&userpb.UserId{Idp: "a", OpaqueId: "b.c"})
+	if a == b {
+		t.Errorf("users from different providers share the key %q", a)
+	}
+}
+
+func TestStripUserTags(t *testing.T) {
+	einstein := &userpb.UserId{Idp: "cernbox", OpaqueId: "einstein"}
+	marie := &userpb.UserId{Idp: "cernbox", OpaqueId: "marie"}
+	tests := []struct {
+		description string
+		id          *userpb.UserId
+		expected    map[string]string
+	}{
+		{
+			description: "own tags are kept",
+			id:          einstein,
+			expected:    map[string]string{"tags": "physics", "tags.cernbox.einstein": "relativity", "etag": "1"},
+		},
+		{
+			description: "other user",
+			id:          marie,
+			expected:    map[string]string{"tags": "physics", "etag": "1"},
+		},
+		{
+			description: "no user",
+			expected:    map[string]string{"tags": "physics", "etag": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			md := &provider.ResourceInfo{ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{
+				"tags":                  "physics",
+				"tags.cernbox.einstein": "relativity",
+				"etag":                  "1",
+			}}}
+			tags.StripUserTags(md, tt.id)
+			if !reflect.DeepEqual(md.ArbitraryMetadata.Metadata, tt.expected) {
+				t.Errorf("got %v, expected %v", md.ArbitraryMetadata.Metadata, tt.expected)
+			}
+		})
+	}
+
+	// resources without arbitrary metadata are left alone
+	tags.StripUserTags(&provider.ResourceInfo{}, einstein)
+}
+
+func TestGetManager(t *testing.T) {
+	ctx := context.Background()
+	a, err := registry.GetManager(ctx, "memory", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := registry.GetManager(ctx, "memory", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Error("expected the services to share the same memory manager")
+	}
+
+	if _, err := a.TagResource(ctx, &provider.ResourceId{StorageId: "s", OpaqueId: "o"}, "physics"); err != nil {
+		t.Fatal(err)
+	}
+	list, err := b.ListTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := tags.Names(list); !reflect.DeepEqual(names, []string{"physics"}) {
+		t.Errorf("unexpected tags %v", names)
+	}
+
+	if _, err := registry.GetManager(ctx, "nope", nil); !isNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestManagers(t *testing.T) {
+	managers := map[string]func(t *testing.T) tags.Manager{
+		"memory": func(t *testing.T) tags.Manager {
+			m, err := memory.New(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return m
+		},
+		"sqlite": func(t *testing.T) tags.Manager {
+			m, err := sqlite.New(context.Background(), map[string]interface{}{
+				"db_file": filepath.Join(t.TempDir(), "tags.db"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return m
+		},
+	}
+	for name, newManager := range managers {
+		t.Run(name, func(t *testing.T) {
+			testManager(t, newManager(t))
+		})
+	}
+}
+
+func testManager(t *testing.T, m tags.Manager) {
+	ctx := context.Background()
+	file := &provider.ResourceId{StorageId: "storage", OpaqueId: "file"}
+	folder := &provider.ResourceId{StorageId: "storage", OpaqueId: "folder"}
+
+	attached, err := m.TagResource(ctx, file, "physics", "maths")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attached) != 2 {
+		t.Fatalf("expected two tags, got %v", attached)
+	}
+	again, err := m.TagResource(ctx, folder, "physics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again[0].ID != attached[0].ID {
+		t.Fatalf("expected existing tag to be reused, got %v and %v", again[0], attached[0])
+	}
+
+	list, err := m.ListTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tags.Names(list); !reflect.DeepEqual(got, []string{"maths", "physics"}) {
+		t.Fatalf("unexpected tags %v", got)
+	}
+
+	physics, err := m.GetTag(ctx, attached[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if physics.Name != "physics" {
+		t.Fatalf("unexpected tag %v", physics)
+	}
+	if _, err := m.GetTag(ctx, "42"); !isNotFound(err) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if _, err := m.ListResources(ctx, "42"); !isNotFound(err) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	ids, err := m.ListResources(ctx, physics.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("expected two tagged resources, got %v", ids)
+	}
+
+	if err := m.UntagResource(ctx, file, "physics", "unknown"); err != nil {
+		t.Fatal(err)
+	}
+	ids, err = m.ListResources(ctx, physics.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0].OpaqueId != "folder" {
+		t.Fatalf("expected only the folder to be tagged, got %v", ids)
+	}
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(errtypes.IsNotFound)
+	return ok
+}
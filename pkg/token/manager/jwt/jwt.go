@@ -20,6 +20,8 @@ package jwt
 
 import (
 	"context"
+	"crypto"
+	"fmt"
 	"time"
 
 	auth "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
@@ -30,6 +32,7 @@ import (
 	"github.com/cs3org/reva/pkg/token/manager/registry"
 	"github.com/cs3org/reva/pkg/utils/cfg"
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
@@ -40,13 +43,24 @@ func init() {
 }
 
 type config struct {
-	Secret             string `mapstructure:"secret"`
-	Expires            int64  `mapstructure:"expires"`
-	ExpiresNextWeekend bool   `mapstructure:"expires_next_weekend"`
+	Secret             string   `docs:";The shared secret signing and verifying the tokens with HS256."                             mapstructure:"secret"`
+	Expires            int64    `docs:"86400;The lifetime of the tokens in seconds."                                                mapstructure:"expires"`
+	ExpiresNextWeekend bool     `docs:"false;Whether the tokens expire at the end of the next weekend."                             mapstructure:"expires_next_weekend"`
+	SigningMethod      string   `docs:"HS256;The algorithm signing the tokens: one of HS256, RS256, ES256 or EdDSA."                mapstructure:"signing_method"`
+	SigningKey         string   `docs:";Path to the PEM encoded private key signing the tokens. Leave empty to only verify tokens." mapstructure:"signing_key"`
+	VerificationKeys   []string `docs:";Paths to the PEM encoded public keys verifying the tokens, besides the signing key."        mapstructure:"verification_keys"`
+	JWKSURL            string   `docs:";URL of a JWKS document publishing further keys verifying the tokens."                       mapstructure:"jwks_url"`
+	JWKSInsecure       bool     `docs:"false;Whether to skip the verification of the TLS certificate of the JWKS endpoint."         mapstructure:"jwks_insecure"`
 }
 
 type manager struct {
 	conf *config
+
+	// signingKey and kid are only set for the asymmetric signing methods,
+	// and signingKey is nil when the manager can only verify tokens.
+	signingKey crypto.Signer
+	kid        string
+	keys       *keySet
 }
 
 // claims are custom claims for the JWT token.
@@ -61,6 +75,10 @@ func (c *config) ApplyDefaults() {
 		c.Expires = defaultExpiration
 	}
 
+	if c.SigningMethod == "" {
+		c.SigningMethod = "HS256"
+	}
+
 	c.Secret = sharedconf.GetJWTSecret(c.Secret)
 }
 
@@ -71,11 +89,56 @@ func New(m map[string]interface{}) (token.Manager, error) {
 		return nil, err
 	}
 
-	if c.Secret == "" {
-		return nil, errors.New("jwt: secret for signing payloads is not defined in config")
+	switch c.SigningMethod {
+	case "HS256":
+		if c.Secret == "" {
+			return nil, errors.New("jwt: secret for signing payloads is not defined in config")
+		}
+		return &manager{conf: &c}, nil
+	case "RS256", "ES256", "EdDSA":
+		return newAsymmetric(&c)
+	default:
+		return nil, fmt.Errorf("jwt: unsupported signing method %s", c.SigningMethod)
+	}
+}
+
+func newAsymmetric(c *config) (*manager, error) {
+	mgr := &manager{
+		conf: c,
+		keys: newKeySet(c.JWKSURL, c.JWKSInsecure),
+	}
+
+	if c.SigningKey != "" {
+		key, err := loadPrivateKey(c.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		alg, err := algorithm(key.Public())
+		if err != nil {
+			return nil, err
+		}
+		if alg != c.SigningMethod {
+			return nil, fmt.Errorf("jwt: the signing key is a %s key, while the signing method is %s", alg, c.SigningMethod)
+		}
+		if mgr.kid, err = mgr.keys.add(key.Public()); err != nil {
+			return nil, err
+		}
+		mgr.signingKey = key
+	}
+
+	for _, f := range c.VerificationKeys {
+		pub, err := loadPublicKey(f)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := mgr.keys.add(pub); err != nil {
+			return nil, errors.Wrapf(err, "jwt: error loading verification key %s", f)
+		}
 	}
 
-	mgr := &manager{conf: &c}
+	if mgr.keys.empty() {
+		return nil, errors.New("jwt: no key for verifying tokens is defined in config")
+	}
 	return mgr, nil
 }
 
@@ -86,14 +149,25 @@ func (m *manager) MintToken(ctx context.Context, u *user.User, scope map[string]
 			Issuer:    u.Id.Idp,
 			Audience:  "reva",
 			IssuedAt:  time.Now().Unix(),
+			// makes every token unique, so that it can be revoked on its own
+			Id: uuid.New().String(),
 		},
 		User:  u,
 		Scope: scope,
 	}
 
-	t := jwt.NewWithClaims(jwt.GetSigningMethod("HS256"), claims)
+	t := jwt.NewWithClaims(jwt.GetSigningMethod(m.conf.SigningMethod), claims)
 
-	tkn, err := t.SignedString([]byte(m.conf.Secret))
+	var key interface{} = []byte(m.conf.Secret)
+	if m.keys != nil {
+		if m.signingKey == nil {
+			return "", errtypes.NotSupported("jwt: no signing key is defined in config, tokens can only be verified")
+		}
+		t.Header["kid"] = m.kid
+		key = m.signingKey
+	}
+
+	tkn, err := t.SignedString(key)
 	if err != nil {
 		return "", errors.Wrapf(err, "error signing token with claims %+v", claims)
 	}
@@ -119,7 +193,7 @@ func setTime(t time.Time, hour, min, sec int) time.Time {
 
 func (m *manager) DismantleToken(ctx context.Context, tkn string) (*user.User, map[string]*auth.Scope, error) {
 	token, err := jwt.ParseWithClaims(tkn, &claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(m.conf.Secret), nil
+		return m.verificationKey(ctx, token)
 	})
 
 	if err != nil {
@@ -132,3 +206,38 @@ func (m *manager) DismantleToken(ctx context.Context, tkn string) (*user.User, m
 
 	return nil, nil, errtypes.InvalidCredentials("invalid token")
 }
+
+func (m *manager) InspectToken(ctx context.Context, tkn string) (time.Time, time.Time, error) {
+	token, _, err := new(jwt.Parser).ParseUnverified(tkn, &claims{})
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.Wrap(err, "error parsing token")
+	}
+	c := token.Claims.(*claims)
+	return time.Unix(c.IssuedAt, 0), time.Unix(c.ExpiresAt, 0), nil
+}
+
+// verificationKey returns the key verifying the signature of t.
+// With the asymmetric signing methods, the key is selected by the kid
+// in the token header, and the token must be signed with the algorithm
+// of that key, so that the key of one algorithm cannot be abused with another.
+func (m *manager) verificationKey(ctx context.Context, t *jwt.Token) (interface{}, error) {
+	if m.keys == nil {
+		if t.Method.Alg() != m.conf.SigningMethod {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		return []byte(m.conf.Secret), nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("missing kid in token header")
+	}
+	k, err := m.keys.get(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	if t.Method.Alg() != k.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method %s for key %s", t.Method.Alg(), kid)
+	}
+	return k.Key, nil
+}
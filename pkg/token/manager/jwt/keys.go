@@ -0,0 +1,326 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/pkg/errors"
+)
+
+const (
+	// jwksTTL is how long the keys fetched from a JWKS endpoint are trusted.
+	jwksTTL = time.Hour
+	// jwksMinRefresh limits how often an unknown kid triggers a refresh.
+	jwksMinRefresh = time.Minute
+)
+
+// KeySet returns the JSON Web Key Set publishing the public keys
+// found in the given PEM files, which may contain either public or
+// private keys. The kid of every key is its RFC 7638 thumbprint,
+// matching the kid set in the tokens signed with it.
+func KeySet(files []string) (*jose.JSONWebKeySet, error) {
+	set := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, f := range files {
+		pub, err := loadPublicKey(f)
+		if err != nil {
+			return nil, err
+		}
+		k, err := newJWK(pub)
+		if err != nil {
+			return nil, errors.Wrapf(err, "jwt: error loading key %s", f)
+		}
+		set.Keys = append(set.Keys, k)
+	}
+	return set, nil
+}
+
+// newJWK returns the JSON Web Key for the public key pub.
+func newJWK(pub crypto.PublicKey) (jose.JSONWebKey, error) {
+	alg, err := algorithm(pub)
+	if err != nil {
+		return jose.JSONWebKey{}, err
+	}
+	k := jose.JSONWebKey{Key: pub, Algorithm: alg, Use: "sig"}
+	tp, err := k.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return jose.JSONWebKey{}, err
+	}
+	k.KeyID = base64.RawURLEncoding.EncodeToString(tp)
+	return k, nil
+}
+
+// algorithm returns the signing method of the tokens verified by pub.
+func algorithm(pub crypto.PublicKey) (string, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return "RS256", nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return "", fmt.Errorf("jwt: unsupported curve %s, only P-256 is supported", k.Curve.Params().Name)
+		}
+		return "ES256", nil
+	case ed25519.PublicKey:
+		return "EdDSA", nil
+	default:
+		return "", fmt.Errorf("jwt: unsupported key type %T", pub)
+	}
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "jwt: error reading key")
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwt: no PEM data found in %s", file)
+	}
+	return block, nil
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("jwt: unsupported PEM block %s", block.Type)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "jwt: error parsing private key")
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("jwt: unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// loadPrivateKey reads the PEM encoded private key in file.
+func loadPrivateKey(file string) (crypto.Signer, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	return parsePrivateKey(block)
+}
+
+// loadPublicKey reads the PEM encoded public key in file.
+// If file holds a private key, its public part is returned.
+func loadPublicKey(file string) (crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "jwt: error parsing public key")
+		}
+		return pub, nil
+	case "RSA PUBLIC KEY":
+		pub, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "jwt: error parsing public key")
+		}
+		return pub, nil
+	default:
+		signer, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	}
+}
+
+// keySet holds the keys verifying the tokens, indexed by kid.
+// The keys configured locally never expire, while the ones
+// published by the JWKS endpoint are periodically refreshed.
+type keySet struct {
+	local map[string]jose.JSONWebKey
+
+	url    string
+	client *http.Client
+
+	// refreshMu serializes the fetches of the JWKS, which are made
+	// without holding mu so that the known keys are still served
+	refreshMu sync.Mutex
+
+	mu      sync.Mutex
+	remote  map[string]jose.JSONWebKey
+	fetched time.Time
+	checked time.Time
+}
+
+func newKeySet(url string, insecure bool) *keySet {
+	return &keySet{
+		local: map[string]jose.JSONWebKey{},
+		url:   url,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure}},
+		},
+		remote: map[string]jose.JSONWebKey{},
+	}
+}
+
+func (s *keySet) add(pub crypto.PublicKey) (string, error) {
+	k, err := newJWK(pub)
+	if err != nil {
+		return "", err
+	}
+	s.local[k.KeyID] = k
+	return k.KeyID, nil
+}
+
+func (s *keySet) empty() bool {
+	return len(s.local) == 0 && s.url == ""
+}
+
+// get returns the key identified by kid.
+func (s *keySet) get(ctx context.Context, kid string) (*jose.JSONWebKey, error) {
+	if k, ok := s.local[kid]; ok {
+		return &k, nil
+	}
+	if s.url == "" {
+		return nil, fmt.Errorf("jwt: unknown key %s", kid)
+	}
+
+	k, ok, stale := s.lookup(kid)
+	if stale {
+		if err := s.refresh(ctx); err != nil {
+			// the keys fetched before keep being served until the endpoint is back
+			if !ok {
+				return nil, err
+			}
+		} else {
+			k, ok, _ = s.lookup(kid)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("jwt: unknown key %s", kid)
+	}
+	return &k, nil
+}
+
+// lookup returns the key identified by kid among the ones fetched from
+// the JWKS endpoint, and whether the keys must be fetched again, either
+// because they expired or because kid is unknown. The endpoint is not
+// contacted more than once every jwksMinRefresh, even if it fails.
+func (s *keySet) lookup(kid string) (jose.JSONWebKey, bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.remote[kid]
+	if time.Since(s.checked) < jwksMinRefresh {
+		return k, ok, false
+	}
+	return k, ok, !ok || time.Since(s.fetched) > jwksTTL
+}
+
+// refresh fetches the keys published by the JWKS endpoint, and replaces
+// the ones fetched before if it succeeds.
+func (s *keySet) refresh(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	// the keys may have been fetched while waiting for the lock
+	s.mu.Lock()
+	recent := time.Since(s.checked) < jwksMinRefresh
+	s.mu.Unlock()
+	if recent {
+		return nil
+	}
+
+	keys, err := s.fetch(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checked = time.Now()
+	if err != nil {
+		return err
+	}
+	s.remote = keys
+	s.fetched = s.checked
+	return nil
+}
+
+// fetch returns the signing keys published by the JWKS endpoint.
+func (s *keySet) fetch(ctx context.Context) (map[string]jose.JSONWebKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "jwt: error creating JWKS request")
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "jwt: error fetching JWKS")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwt: error fetching JWKS: unexpected status %s", res.Status)
+	}
+
+	var set jose.JSONWebKeySet
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, errors.Wrap(err, "jwt: error decoding JWKS")
+	}
+
+	keys := make(map[string]jose.JSONWebKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if !k.IsPublic() {
+			k = k.Public()
+		}
+		alg, err := algorithm(k.Key)
+		if err != nil {
+			continue
+		}
+		// a key announced for another algorithm than the one of its type is not trusted
+		if k.Algorithm != "" && k.Algorithm != alg {
+			continue
+		}
+		k.Algorithm = alg
+		keys[k.KeyID] = k
+	}
+	return keys, nil
+}
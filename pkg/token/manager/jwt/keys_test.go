@@ -0,0 +1,375 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/token"
+	"github.com/golang-jwt/jwt"
+)
+
+var einstein = &user.User{
+	Id:       &user.UserId{Idp: "cernbox.cern.ch", OpaqueId: "einstein"},
+	Username: "einstein",
+}
+
+// writeKey generates a key for the given signing method and stores it in
+// dir, returning the paths of the private and the public key files.
+func writeKey(t *testing.T, dir, method string) (string, string) {
+	t.Helper()
+	var key crypto.Signer
+	var err error
+	switch method {
+	case "RS256":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	priv, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.CreateTemp(dir, method)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	privFile, pubFile := f.Name()+".key", f.Name()+".pub"
+	if err := os.WriteFile(privFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return privFile, pubFile
+}
+
+func newManager(t *testing.T, m map[string]interface{}) token.Manager {
+	t.Helper()
+	mgr, err := New(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mgr
+}
+
+func mint(t *testing.T, mgr token.Manager) string {
+	t.Helper()
+	tkn, err := mgr.MintToken(context.Background(), einstein, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tkn
+}
+
+func TestAsymmetricSigningMethods(t *testing.T) {
+	dir := t.TempDir()
+	for _, method := range []string{"RS256", "ES256", "EdDSA"} {
+		t.Run(method, func(t *testing.T) {
+			key, _ := writeKey(t, dir, method)
+			mgr := newManager(t, map[string]interface{}{"signing_method": method, "signing_key": key})
+
+			tkn := mint(t, mgr)
+			parsed, _, err := new(jwt.Parser).ParseUnverified(tkn, &claims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Method.Alg() != method || parsed.Header["kid"] != mgr.(*manager).kid {
+				t.Fatalf("unexpected token header %v", parsed.Header)
+			}
+
+			u, _, err := mgr.DismantleToken(context.Background(), tkn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Id.OpaqueId != einstein.Id.OpaqueId {
+				t.Fatalf("expected user %s, got %s", einstein.Id.OpaqueId, u.Id.OpaqueId)
+			}
+		})
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := writeKey(t, dir, "RS256")
+
+	tests := []struct {
+		description string
+		config      map[string]interface{}
+	}{
+		{
+			description: "unsupported signing method",
+			config:      map[string]interface{}{"signing_method": "HS512", "secret": "secret"},
+		},
+		{
+			description: "signing key not matching the signing method",
+			config:      map[string]interface{}{"signing_method": "ES256", "signing_key": rsaKey},
+		},
+		{
+			description: "no keys",
+			config:      map[string]interface{}{"signing_method": "RS256"},
+		},
+		{
+			description: "missing signing key",
+			config:      map[string]interface{}{"signing_method": "RS256", "signing_key": filepath.Join(dir, "missing")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			if _, err := New(tt.config); err == nil {
+				t.Fatal("expected an error creating the token manager")
+			}
+		})
+	}
+}
+
+func TestVerification(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, oldPub := writeKey(t, dir, "RS256")
+	newKey, _ := writeKey(t, dir, "ES256")
+	otherKey, otherPub := writeKey(t, dir, "EdDSA")
+
+	oldMgr := newManager(t, map[string]interface{}{"signing_method": "RS256", "signing_key": oldKey})
+	newMgr := newManager(t, map[string]interface{}{"signing_method": "ES256", "signing_key": newKey, "verification_keys": []string{oldPub}})
+	otherMgr := newManager(t, map[string]interface{}{"signing_method": "EdDSA", "signing_key": otherKey})
+	hmacMgr := newManager(t, map[string]interface{}{"secret": "secret"})
+
+	// a token signed by the EdDSA key, but claiming to be signed by the old RSA key
+	forged := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims{User: einstein})
+	forged.Header["kid"] = oldMgr.(*manager).kid
+	forgedTkn, err := forged.SignedString(otherMgr.(*manager).signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		token       string
+		valid       bool
+	}{
+		{
+			description: "signed with the signing key",
+			token:       mint(t, newMgr),
+			valid:       true,
+		},
+		{
+			description: "signed with a previous key",
+			token:       mint(t, oldMgr),
+			valid:       true,
+		},
+		{
+			description: "signed with an unknown key",
+			token:       mint(t, otherMgr),
+		},
+		{
+			description: "signed with the shared secret",
+			token:       mint(t, hmacMgr),
+		},
+		{
+			description: "algorithm not matching the key",
+			token:       forgedTkn,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			_, _, err := newMgr.DismantleToken(context.Background(), tt.token)
+			if tt.valid && err != nil {
+				t.Fatalf("expected token to be valid, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected token to be rejected")
+			}
+		})
+	}
+
+	_, _, err = hmacMgr.DismantleToken(context.Background(), mint(t, oldMgr))
+	if err == nil {
+		t.Fatal("expected the HS256 manager to reject an RS256 token")
+	}
+
+	verifier := newManager(t, map[string]interface{}{"signing_method": "EdDSA", "verification_keys": []string{otherPub}})
+	if _, err := verifier.MintToken(context.Background(), einstein, nil); !errorIsNotSupported(err) {
+		t.Fatalf("expected a verifier only manager not to mint tokens, got %v", err)
+	}
+	if _, _, err := verifier.DismantleToken(context.Background(), mint(t, otherMgr)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	key, pub := writeKey(t, dir, "RS256")
+	otherKey, _ := writeKey(t, dir, "ES256")
+	signer := newManager(t, map[string]interface{}{"signing_method": "RS256", "signing_key": key})
+	other := newManager(t, map[string]interface{}{"signing_method": "ES256", "signing_key": otherKey})
+
+	set, err := KeySet([]string{pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].KeyID != signer.(*manager).kid || set.Keys[0].Algorithm != "RS256" {
+		t.Fatalf("unexpected key set %+v", set)
+	}
+
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+
+	verifier := newManager(t, map[string]interface{}{"signing_method": "RS256", "jwks_url": srv.URL})
+	for i := 0; i < 2; i++ {
+		if _, _, err := verifier.DismantleToken(context.Background(), mint(t, signer)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := verifier.DismantleToken(context.Background(), mint(t, other)); err == nil {
+		t.Fatal("expected a token signed with a key not in the JWKS to be rejected")
+	}
+	if fetches != 1 {
+		t.Fatalf("expected the JWKS to be fetched once, got %d", fetches)
+	}
+}
+
+func errorIsNotSupported(err error) bool {
+	_, ok := err.(errtypes.IsNotSupported)
+	return ok
+}
+
+func TestJWKSRefresh(t *testing.T) {
+	dir := t.TempDir()
+	_, pub := writeKey(t, dir, "RS256")
+	set, err := KeySet([]string{pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid := set.Keys[0].KeyID
+
+	var mu sync.Mutex
+	status := http.StatusOK
+	block := make(chan struct{})
+	close(block)
+	requested := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		st, b := status, block
+		mu.Unlock()
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+		<-b
+		w.WriteHeader(st)
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+
+	s := newKeySet(srv.URL, false)
+	if _, err := s.get(context.Background(), kid); err != nil {
+		t.Fatal(err)
+	}
+	<-requested
+
+	// the known keys are served while the JWKS is fetched again
+	mu.Lock()
+	block = make(chan struct{})
+	mu.Unlock()
+	s.mu.Lock()
+	s.checked = s.checked.Add(-2 * jwksMinRefresh)
+	s.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		_, _ = s.get(context.Background(), "unknown")
+		close(done)
+	}()
+	<-requested
+	if _, err := s.get(context.Background(), kid); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	close(block)
+	mu.Unlock()
+	<-done
+
+	// the keys fetched before are kept when the endpoint fails
+	mu.Lock()
+	status = http.StatusInternalServerError
+	mu.Unlock()
+	s.mu.Lock()
+	s.checked = s.checked.Add(-2 * jwksTTL)
+	s.fetched = s.fetched.Add(-2 * jwksTTL)
+	s.mu.Unlock()
+	if _, err := s.get(context.Background(), kid); err != nil {
+		t.Fatalf("expected the expired key to be served when the refresh fails, got %v", err)
+	}
+	if _, err := s.get(context.Background(), "unknown"); err == nil {
+		t.Fatal("expected an unknown key to be rejected")
+	}
+}
+
+func TestJWKSAlgorithmMismatch(t *testing.T) {
+	dir := t.TempDir()
+	_, pub := writeKey(t, dir, "RS256")
+	set, err := KeySet([]string{pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.Keys[0].Algorithm = "ES256"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+
+	s := newKeySet(srv.URL, false)
+	if _, err := s.get(context.Background(), set.Keys[0].KeyID); err == nil {
+		t.Fatal("expected a key announced for the algorithm of another key type to be rejected")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package memory implements a revocation store keeping the revoked
+// tokens in memory. The store is shared by all the services running
+// in the same process, and is lost on restart.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/cs3org/reva/pkg/token/revocation/registry"
+)
+
+func init() {
+	registry.Register("memory", New)
+}
+
+type store struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+	users  map[string]time.Time
+}
+
+var shared = &store{
+	tokens: map[string]time.Time{},
+	users:  map[string]time.Time{},
+}
+
+// New returns the in-memory revocation store of the process.
+func New(m map[string]interface{}) (revocation.Store, error) {
+	return shared, nil
+}
+
+func userKey(u *userpb.UserId) string {
+	return u.Idp + "!" + u.OpaqueId
+}
+
+func (s *store) RevokeToken(ctx context.Context, id string, expiration time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// forget the tokens that are expired anyway
+	now := time.Now()
+	for t, exp := range s.tokens {
+		if exp.Before(now) {
+			delete(s.tokens, t)
+		}
+	}
+	s.tokens[id] = expiration
+	return nil
+}
+
+func (s *store) IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.tokens[id]
+	return ok, nil
+}
+
+func (s *store) RevokeUser(ctx context.Context, u *userpb.UserId, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userKey(u)] = before
+	return nil
+}
+
+func (s *store) UserRevokedBefore(ctx context.Context, u *userpb.UserId) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.users[userKey(u)], nil
+}
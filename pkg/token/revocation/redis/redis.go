@@ -0,0 +1,162 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package redis implements a revocation store keeping the revoked
+// tokens in redis, shared by all the reva instances using it.
+package redis
+
+import (
+	"context"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/cs3org/reva/pkg/token/revocation/registry"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("redis", New)
+}
+
+type config struct {
+	RedisAddress  string `docs:"localhost:6379;The address of the redis server."          mapstructure:"redis_address"`
+	RedisUsername string `docs:";The username to authenticate to the redis server."       mapstructure:"redis_username"`
+	RedisPassword string `docs:";The password to authenticate to the redis server."       mapstructure:"redis_password"`
+	Prefix        string `docs:"reva:revocation:;The prefix of the keys stored in redis." mapstructure:"prefix"`
+}
+
+func (c *config) ApplyDefaults() {
+	if c.RedisAddress == "" {
+		c.RedisAddress = "localhost:6379"
+	}
+	if c.Prefix == "" {
+		c.Prefix = "reva:revocation:"
+	}
+}
+
+type store struct {
+	c         *config
+	redisPool *redis.Pool
+}
+
+// New returns a revocation store keeping the revoked tokens in redis.
+// The revoked tokens expire together with the tokens themselves.
+func New(m map[string]interface{}) (revocation.Store, error) {
+	var c config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+
+	pool := &redis.Pool{
+		MaxIdle:     50,
+		MaxActive:   1000,
+		IdleTimeout: 240 * time.Second,
+
+		Dial: func() (redis.Conn, error) {
+			var opts []redis.DialOption
+			if c.RedisUsername != "" {
+				opts = append(opts, redis.DialUsername(c.RedisUsername))
+			}
+			if c.RedisPassword != "" {
+				opts = append(opts, redis.DialPassword(c.RedisPassword))
+			}
+			return redis.Dial("tcp", c.RedisAddress, opts...)
+		},
+
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+
+	return &store{c: &c, redisPool: pool}, nil
+}
+
+func (s *store) tokenKey(id string) string {
+	return s.c.Prefix + "token:" + id
+}
+
+func (s *store) userKey(u *userpb.UserId) string {
+	return s.c.Prefix + "user:" + u.Idp + "!" + u.OpaqueId
+}
+
+func (s *store) RevokeToken(ctx context.Context, id string, expiration time.Time) error {
+	ttl := int64(time.Until(expiration).Seconds()) + 1
+	if ttl <= 1 {
+		// the token is expired already
+		return nil
+	}
+
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "redis: error getting connection")
+	}
+	defer conn.Close()
+
+	if _, err := conn.Do("SET", s.tokenKey(id), 1, "EX", ttl); err != nil {
+		return errors.Wrap(err, "redis: error revoking token")
+	}
+	return nil
+}
+
+func (s *store) IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "redis: error getting connection")
+	}
+	defer conn.Close()
+
+	revoked, err := redis.Bool(conn.Do("EXISTS", s.tokenKey(id)))
+	if err != nil {
+		return false, errors.Wrap(err, "redis: error checking token")
+	}
+	return revoked, nil
+}
+
+func (s *store) RevokeUser(ctx context.Context, u *userpb.UserId, before time.Time) error {
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "redis: error getting connection")
+	}
+	defer conn.Close()
+
+	if _, err := conn.Do("SET", s.userKey(u), before.Unix()); err != nil {
+		return errors.Wrap(err, "redis: error revoking user tokens")
+	}
+	return nil
+}
+
+func (s *store) UserRevokedBefore(ctx context.Context, u *userpb.UserId) (time.Time, error) {
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "redis: error getting connection")
+	}
+	defer conn.Close()
+
+	before, err := redis.Int64(conn.Do("GET", s.userKey(u)))
+	if err == redis.ErrNil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "redis: error checking user")
+	}
+	return time.Unix(before, 0), nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package registry
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/token"
+	"github.com/cs3org/reva/pkg/token/revocation"
+)
+
+// NewFunc is the function that revocation store implementations
+// should register at init time.
+type NewFunc func(map[string]interface{}) (revocation.Store, error)
+
+// NewFuncs is a map containing all the registered revocation stores.
+var NewFuncs = map[string]NewFunc{}
+
+// Register registers a new revocation store function.
+// Not safe for concurrent use. Safe for use from package init.
+func Register(name string, f NewFunc) {
+	NewFuncs[name] = f
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = map[string]revocation.Store{}
+)
+
+// GetStore returns the revocation store registered under name, configured
+// with conf[name]. The services configured with the same store share the
+// same instance, so that the tokens revoked through one of them are rejected
+// by the others even when the store is kept in memory.
+func GetStore(name string, conf map[string]map[string]interface{}) (revocation.Store, error) {
+	f, ok := NewFuncs[name]
+	if !ok {
+		return nil, errtypes.NotFound("revocation store not found: " + name)
+	}
+
+	c, err := json.Marshal(conf[name])
+	if err != nil {
+		return nil, err
+	}
+	key := name + ":" + string(c)
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if s, ok := stores[key]; ok {
+		return s, nil
+	}
+	s, err := f(conf[name])
+	if err != nil {
+		return nil, err
+	}
+	stores[key] = s
+	return s, nil
+}
+
+// WrapManager wraps mgr to reject the tokens revoked in the store registered
+// under name, configured with conf[name]. mgr is returned as is if name is empty.
+func WrapManager(mgr token.Manager, name string, conf map[string]map[string]interface{}) (token.Manager, error) {
+	if name == "" {
+		return mgr, nil
+	}
+	s, err := GetStore(name, conf)
+	if err != nil {
+		return nil, err
+	}
+	return revocation.NewManager(mgr, s), nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package revocation allows to revoke reva tokens before they expire,
+// either one at a time, e.g. on logout, or all the tokens of a user at once,
+// e.g. after a password change or when the account is disabled.
+package revocation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	auth "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/token"
+	"github.com/pkg/errors"
+)
+
+// defaultExpiration is how long a revoked token is remembered
+// when the token manager cannot tell when the token expires.
+const defaultExpiration = 7 * 24 * time.Hour
+
+// Store is the interface to implement to keep track of the revoked tokens.
+// The stores never hold the tokens, but only their ids as returned by TokenID.
+type Store interface {
+	// RevokeToken revokes the token with the given id, which can be forgotten once expired.
+	RevokeToken(ctx context.Context, id string, expiration time.Time) error
+	// IsTokenRevoked tells whether the token with the given id has been revoked.
+	IsTokenRevoked(ctx context.Context, id string) (bool, error)
+	// RevokeUser revokes all the tokens issued to a user before the given time.
+	RevokeUser(ctx context.Context, u *userpb.UserId, before time.Time) error
+	// UserRevokedBefore returns the time before which the tokens of a user
+	// are revoked, or the zero time if they never have been.
+	UserRevokedBefore(ctx context.Context, u *userpb.UserId) (time.Time, error)
+}
+
+// TokenID returns the id of a token in the stores.
+func TokenID(tkn string) string {
+	sum := sha256.Sum256([]byte(tkn))
+	return hex.EncodeToString(sum[:])
+}
+
+// Revoke revokes tkn, that has been minted by mgr.
+func Revoke(ctx context.Context, s Store, mgr token.Manager, tkn string) error {
+	if m, ok := mgr.(*manager); ok {
+		mgr = m.Manager
+	}
+	expiration := time.Now().Add(defaultExpiration)
+	if i, ok := mgr.(token.Inspector); ok {
+		_, exp, err := i.InspectToken(ctx, tkn)
+		if err != nil {
+			return err
+		}
+		expiration = exp
+	}
+	return s.RevokeToken(ctx, TokenID(tkn), expiration)
+}
+
+// RevokeUser revokes all the tokens issued to u so far.
+func RevokeUser(ctx context.Context, s Store, u *userpb.UserId) error {
+	// the tokens carry the issuing time in seconds: the ones issued
+	// in the same second as the revocation are revoked too, as they
+	// cannot be told apart from the ones issued before it
+	return s.RevokeUser(ctx, u, time.Now().Truncate(time.Second).Add(time.Second))
+}
+
+type manager struct {
+	token.Manager
+	store Store
+}
+
+// NewManager returns a token manager that dismantles the tokens with mgr,
+// rejecting the ones revoked in the store s.
+// The revocation of all the tokens of a user is only enforced
+// if mgr implements the token.Inspector interface.
+func NewManager(mgr token.Manager, s Store) token.Manager {
+	return &manager{Manager: mgr, store: s}
+}
+
+func (m *manager) DismantleToken(ctx context.Context, tkn string) (*userpb.User, map[string]*auth.Scope, error) {
+	u, scope, err := m.Manager.DismantleToken(ctx, tkn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	revoked, err := m.store.IsTokenRevoked(ctx, TokenID(tkn))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "revocation: error checking token")
+	}
+	if revoked {
+		return nil, nil, errtypes.InvalidCredentials("token has been revoked")
+	}
+
+	if i, ok := m.Manager.(token.Inspector); ok {
+		before, err := m.store.UserRevokedBefore(ctx, u.Id)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "revocation: error checking user")
+		}
+		if !before.IsZero() {
+			issued, _, err := i.InspectToken(ctx, tkn)
+			if err != nil {
+				return nil, nil, err
+			}
+			if issued.Before(before) {
+				return nil, nil, errtypes.InvalidCredentials("token has been revoked")
+			}
+		}
+	}
+
+	return u, scope, nil
+}
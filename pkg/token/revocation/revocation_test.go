@@ -0,0 +1,138 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package revocation_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/token"
+	"github.com/cs3org/reva/pkg/token/manager/jwt"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/cs3org/reva/pkg/token/revocation/memory"
+	"github.com/cs3org/reva/pkg/token/revocation/registry"
+	"github.com/cs3org/reva/pkg/token/revocation/sql"
+)
+
+var (
+	einstein = &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "einstein"}, Username: "einstein"}
+	marie    = &userpb.User{Id: &userpb.UserId{Idp: "cernbox.cern.ch", OpaqueId: "marie"}, Username: "marie"}
+)
+
+func stores(t *testing.T) map[string]revocation.Store {
+	mem, err := memory.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.New(map[string]interface{}{"db_file": filepath.Join(t.TempDir(), "revocation.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]revocation.Store{"memory": mem, "sql": db}
+}
+
+func mint(t *testing.T, mgr token.Manager, u *userpb.User) string {
+	t.Helper()
+	tkn, err := mgr.MintToken(context.Background(), u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tkn
+}
+
+func TestRevocation(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := jwt.New(map[string]interface{}{"secret": "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			revoking := revocation.NewManager(mgr, store)
+			einsteinTkn := mint(t, mgr, einstein)
+			marieTkn := mint(t, mgr, marie)
+
+			if err := revocation.Revoke(ctx, store, mgr, einsteinTkn); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := revoking.DismantleToken(ctx, einsteinTkn); err == nil {
+				t.Fatal("expected the revoked token to be rejected")
+			}
+			if _, _, err := revoking.DismantleToken(ctx, marieTkn); err != nil {
+				t.Fatalf("expected the token of another user to be valid, got %v", err)
+			}
+
+			// tokens issued in the same second as the revocation are revoked too
+			if err := revocation.RevokeUser(ctx, store, marie.Id); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := revoking.DismantleToken(ctx, marieTkn); err == nil {
+				t.Fatal("expected the tokens issued before the revocation to be rejected")
+			}
+			before, err := store.UserRevokedBefore(ctx, marie.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(time.Until(before))
+			if _, _, err := revoking.DismantleToken(ctx, mint(t, mgr, marie)); err != nil {
+				t.Fatalf("expected a token issued after the revocation to be valid, got %v", err)
+			}
+			if _, _, err := mgr.DismantleToken(ctx, marieTkn); err != nil {
+				t.Fatalf("expected the wrapped manager not to check revocations, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGetStore(t *testing.T) {
+	ctx := context.Background()
+	mgr, err := jwt.New(map[string]interface{}{"secret": "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the gateway revokes the tokens in its store,
+	// the interceptors check them in theirs
+	gateway, err := registry.GetStore("memory", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor, err := registry.WrapManager(mgr, "memory", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tkn := mint(t, mgr, einstein)
+	if err := revocation.Revoke(ctx, gateway, mgr, tkn); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := interceptor.DismantleToken(ctx, tkn); err == nil {
+		t.Fatal("expected the token revoked by the gateway to be rejected")
+	}
+
+	if m, err := registry.WrapManager(mgr, "", nil); err != nil || m != mgr {
+		t.Fatalf("expected the manager not to be wrapped without store, got %v %v", m, err)
+	}
+	if _, err := registry.GetStore("nope", nil); err == nil {
+		t.Fatal("expected an error for an unknown store")
+	}
+}
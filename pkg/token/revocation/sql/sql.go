@@ -0,0 +1,196 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sql implements a revocation store keeping the revoked
+// tokens in a sqlite or MySQL database.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/token/revocation"
+	"github.com/cs3org/reva/pkg/token/revocation/registry"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+	// Provides the mysql database/sql driver.
+	_ "github.com/go-sql-driver/mysql"
+	// Provides the sqlite3 database/sql driver.
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+type config struct {
+	Engine     string `docs:"sqlite;The database engine, either sqlite or mysql."                     mapstructure:"engine"`
+	DBFile     string `docs:"/var/tmp/reva/revocation.db;The sqlite file holding the revoked tokens." mapstructure:"db_file"`
+	DBUsername string `docs:";The username to connect to the mysql database."                         mapstructure:"db_username"`
+	DBPassword string `docs:";The password to connect to the mysql database."                         mapstructure:"db_password"`
+	DBAddress  string `docs:"localhost:3306;The address of the mysql database, as host:port."         mapstructure:"db_address"`
+	DBName     string `docs:"reva;The name of the mysql database."                                    mapstructure:"db_name"`
+}
+
+func (c *config) ApplyDefaults() {
+	if c.Engine == "" {
+		c.Engine = "sqlite"
+	}
+	if c.DBFile == "" {
+		c.DBFile = "/var/tmp/reva/revocation.db"
+	}
+	if c.DBAddress == "" {
+		c.DBAddress = "localhost:3306"
+	}
+	if c.DBName == "" {
+		c.DBName = "reva"
+	}
+}
+
+// schemas holds the statements creating the tables of every engine.
+var schemas = map[string][]string{
+	"sqlite": {
+		`CREATE TABLE IF NOT EXISTS revoked_tokens (
+			id TEXT PRIMARY KEY,
+			expiration INTEGER NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS revoked_users (
+			user_idp TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			revoked_before INTEGER NOT NULL,
+			PRIMARY KEY (user_idp, user_id)
+		)`,
+	},
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS revoked_tokens (
+			id VARCHAR(64) PRIMARY KEY,
+			expiration BIGINT NOT NULL,
+			INDEX revoked_tokens_expiration (expiration)
+		)`,
+		`CREATE TABLE IF NOT EXISTS revoked_users (
+			user_idp VARCHAR(128) NOT NULL,
+			user_id VARCHAR(191) NOT NULL,
+			revoked_before BIGINT NOT NULL,
+			PRIMARY KEY (user_idp, user_id)
+		)`,
+	},
+}
+
+// upserts holds the statements storing a revocation, by table.
+var upserts = map[string]map[string]string{
+	"sqlite": {
+		"revoked_tokens": `INSERT INTO revoked_tokens (id, expiration) VALUES (?, ?)
+			ON CONFLICT (id) DO UPDATE SET expiration = excluded.expiration`,
+		"revoked_users": `INSERT INTO revoked_users (user_idp, user_id, revoked_before) VALUES (?, ?, ?)
+			ON CONFLICT (user_idp, user_id) DO UPDATE SET revoked_before = excluded.revoked_before`,
+	},
+	"mysql": {
+		"revoked_tokens": `INSERT INTO revoked_tokens (id, expiration) VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE expiration = VALUES(expiration)`,
+		"revoked_users": `INSERT INTO revoked_users (user_idp, user_id, revoked_before) VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE revoked_before = VALUES(revoked_before)`,
+	},
+}
+
+type store struct {
+	c  *config
+	db *sql.DB
+}
+
+// New returns a revocation store keeping the revoked tokens in a sql database.
+func New(m map[string]interface{}) (revocation.Store, error) {
+	var c config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+
+	db, err := open(&c)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range schemas[c.Engine] {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, errors.Wrap(err, "sql: error creating the revocation tables")
+		}
+	}
+	return &store{c: &c, db: db}, nil
+}
+
+func open(c *config) (*sql.DB, error) {
+	switch c.Engine {
+	case "sqlite":
+		if err := os.MkdirAll(filepath.Dir(c.DBFile), 0700); err != nil {
+			return nil, errors.Wrap(err, "sql: error creating revocation folder")
+		}
+		// writers are serialized by sqlite, wait for the lock instead of failing
+		db, err := sql.Open("sqlite3", "file:"+c.DBFile+"?_busy_timeout=5000&_txlock=immediate")
+		return db, errors.Wrap(err, "sql: error opening the sqlite database")
+	case "mysql":
+		db, err := sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s", c.DBUsername, c.DBPassword, c.DBAddress, c.DBName))
+		return db, errors.Wrap(err, "sql: error opening connection to mysql database")
+	default:
+		return nil, errors.New("sql: unknown database engine " + c.Engine)
+	}
+}
+
+func (s *store) RevokeToken(ctx context.Context, id string, expiration time.Time) error {
+	// forget the tokens that are expired anyway
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expiration < ?", time.Now().Unix()); err != nil {
+		return errors.Wrap(err, "sql: error purging expired tokens")
+	}
+	if _, err := s.db.ExecContext(ctx, upserts[s.c.Engine]["revoked_tokens"], id, expiration.Unix()); err != nil {
+		return errors.Wrap(err, "sql: error revoking token")
+	}
+	return nil
+}
+
+func (s *store) IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	var expiration int64
+	err := s.db.QueryRowContext(ctx, "SELECT expiration FROM revoked_tokens WHERE id=?", id).Scan(&expiration)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "sql: error checking token")
+	}
+	return true, nil
+}
+
+func (s *store) RevokeUser(ctx context.Context, u *userpb.UserId, before time.Time) error {
+	if _, err := s.db.ExecContext(ctx, upserts[s.c.Engine]["revoked_users"], u.Idp, u.OpaqueId, before.Unix()); err != nil {
+		return errors.Wrap(err, "sql: error revoking user tokens")
+	}
+	return nil
+}
+
+func (s *store) UserRevokedBefore(ctx context.Context, u *userpb.UserId) (time.Time, error) {
+	var before int64
+	err := s.db.QueryRowContext(ctx, "SELECT revoked_before FROM revoked_users WHERE user_idp=? AND user_id=?", u.Idp, u.OpaqueId).Scan(&before)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "sql: error checking user")
+	}
+	return time.Unix(before, 0), nil
+}
@@ -26,6 +26,8 @@ import (
 	"strings"
 
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/appctx"
+	"github.com/cs3org/reva/pkg/auth/password"
 	"github.com/cs3org/reva/pkg/errtypes"
 	"github.com/cs3org/reva/pkg/user"
 	"github.com/cs3org/reva/pkg/user/manager/registry"
@@ -40,6 +42,9 @@ func init() {
 
 type manager struct {
 	users []*userpb.User
+	// plaintext counts the users with a plaintext secret
+	// in the file shared with the json auth manager.
+	plaintext int
 }
 
 type config struct {
@@ -54,12 +59,15 @@ func (c *config) ApplyDefaults() {
 }
 
 // New returns a user manager implementation that reads a json file to provide user metadata.
-func New(_ context.Context, m map[string]interface{}) (user.Manager, error) {
+func New(ctx context.Context, m map[string]interface{}) (user.Manager, error) {
 	mgr := &manager{}
 	err := mgr.Configure(m)
 	if err != nil {
 		return nil, err
 	}
+	if mgr.plaintext > 0 {
+		appctx.GetLogger(ctx).Warn().Int("users", mgr.plaintext).Msg("json: plaintext secrets found, hash them with `reva hash-secrets`")
+	}
 	return mgr, nil
 }
 
@@ -81,6 +89,21 @@ func (m *manager) Configure(ml map[string]interface{}) error {
 		return err
 	}
 	m.users = users
+
+	// the secrets are only used by the json auth manager,
+	// either plaintext or hashed, and never kept in the users
+	secrets := []struct {
+		Secret string `json:"secret"`
+	}{}
+	if err := json.Unmarshal(f, &secrets); err != nil {
+		return err
+	}
+	m.plaintext = 0
+	for _, s := range secrets {
+		if s.Secret != "" && !password.IsHash(s.Secret) {
+			m.plaintext++
+		}
+	}
 	return nil
 }
 
@@ -21,6 +21,7 @@ package json
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -148,3 +149,30 @@ func TestUserManager(t *testing.T) {
 		t.Fatalf("user differ: expected=%v got=%v", "einstein", resUser[0].Username)
 	}
 }
+
+func TestSecrets(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "users.json")
+	users := `[
+		{"id": {"opaque_id": "einstein", "idp": "localhost"}, "username": "einstein", "secret": "$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"},
+		{"id": {"opaque_id": "marie", "idp": "localhost"}, "username": "marie", "secret": "$2y$10$hash"},
+		{"id": {"opaque_id": "richard", "idp": "localhost"}, "username": "richard", "secret": "superfluidity"}
+	]`
+	if err := os.WriteFile(file, []byte(users), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr, err := New(ctx, map[string]interface{}{"users": file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := mgr.(*manager).plaintext; n != 1 {
+		t.Fatalf("expected one plaintext secret, got %d", n)
+	}
+	u, err := mgr.GetUser(ctx, &userpb.UserId{OpaqueId: "einstein"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Username != "einstein" || u.Opaque != nil {
+		t.Fatalf("unexpected user %+v", u)
+	}
+}
@@ -24,5 +24,6 @@ import (
 	_ "github.com/cs3org/reva/pkg/user/manager/json"
 	_ "github.com/cs3org/reva/pkg/user/manager/ldap"
 	_ "github.com/cs3org/reva/pkg/user/manager/nextcloud"
+	_ "github.com/cs3org/reva/pkg/user/manager/sql"
 	// Add your own here.
 )
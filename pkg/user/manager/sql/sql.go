@@ -0,0 +1,482 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// Package sql implements a user manager reading the users from
+// a sqlite or MySQL database. The database also holds the groups
+// and the password hashes, used by the sql group and auth managers.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+	"github.com/cs3org/reva/pkg/user"
+	"github.com/cs3org/reva/pkg/user/manager/registry"
+	"github.com/cs3org/reva/pkg/utils/cfg"
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+func init() {
+	registry.Register("sql", New)
+}
+
+// Config is the configuration of the database holding the users and the groups.
+type Config struct {
+	Engine     string `docs:"sqlite;The database engine, either sqlite or mysql."                     mapstructure:"engine"`
+	DBFile     string `docs:"/var/tmp/reva/identity.db;The sqlite file holding the users and groups." mapstructure:"db_file"`
+	DBUsername string `docs:";The username to connect to the mysql database."                         mapstructure:"db_username"`
+	DBPassword string `docs:";The password to connect to the mysql database."                      mapstructure:"db_password"`
+	DBAddress  string `docs:"localhost:3306;The address of the mysql database, as host:port."      mapstructure:"db_address"`
+	DBName     string `docs:"reva;The name of the mysql database."                                 mapstructure:"db_name"`
+}
+
+// ApplyDefaults sets the defaults of the configuration.
+func (c *Config) ApplyDefaults() {
+	if c.Engine == "" {
+		c.Engine = "sqlite"
+	}
+	if c.DBFile == "" {
+		c.DBFile = "/var/tmp/reva/identity.db"
+	}
+	if c.DBAddress == "" {
+		c.DBAddress = "localhost:3306"
+	}
+	if c.DBName == "" {
+		c.DBName = "reva"
+	}
+}
+
+// schemas holds the statements creating the tables of every engine.
+// The users are looked up by id and by their unique username,
+// the groups of a user are found through the group_members table.
+var schemas = map[string][]string{
+	"sqlite": {
+		`CREATE TABLE IF NOT EXISTS users (
+			idp TEXT NOT NULL,
+			opaque_id TEXT NOT NULL,
+			type INTEGER NOT NULL,
+			username TEXT NOT NULL UNIQUE,
+			mail TEXT NOT NULL,
+			mail_verified INTEGER NOT NULL,
+			display_name TEXT NOT NULL,
+			uid_number INTEGER NOT NULL,
+			gid_number INTEGER NOT NULL,
+			password TEXT NOT NULL,
+			active INTEGER NOT NULL DEFAULT 1,
+			PRIMARY KEY (idp, opaque_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_groups (
+			idp TEXT NOT NULL,
+			opaque_id TEXT NOT NULL,
+			group_name TEXT NOT NULL UNIQUE,
+			mail TEXT NOT NULL,
+			display_name TEXT NOT NULL,
+			gid_number INTEGER NOT NULL,
+			PRIMARY KEY (idp, opaque_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS group_members (
+			group_idp TEXT NOT NULL,
+			group_id TEXT NOT NULL,
+			user_idp TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			user_type INTEGER NOT NULL,
+			PRIMARY KEY (group_idp, group_id, user_idp, user_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS group_members_user ON group_members (user_id, user_idp)`,
+	},
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS users (
+			idp VARCHAR(128) NOT NULL,
+			opaque_id VARCHAR(191) NOT NULL,
+			type INT NOT NULL,
+			username VARCHAR(191) NOT NULL,
+			mail VARCHAR(255) NOT NULL,
+			mail_verified BOOLEAN NOT NULL,
+			display_name VARCHAR(255) NOT NULL,
+			uid_number BIGINT NOT NULL,
+			gid_number BIGINT NOT NULL,
+			password VARCHAR(255) NOT NULL,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			PRIMARY KEY (idp, opaque_id),
+			UNIQUE KEY users_username (username)
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_groups (
+			idp VARCHAR(128) NOT NULL,
+			opaque_id VARCHAR(191) NOT NULL,
+			group_name VARCHAR(191) NOT NULL,
+			mail VARCHAR(255) NOT NULL,
+			display_name VARCHAR(255) NOT NULL,
+			gid_number BIGINT NOT NULL,
+			PRIMARY KEY (idp, opaque_id),
+			UNIQUE KEY user_groups_group_name (group_name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS group_members (
+			group_idp VARCHAR(128) NOT NULL,
+			group_id VARCHAR(191) NOT NULL,
+			user_idp VARCHAR(128) NOT NULL,
+			user_id VARCHAR(191) NOT NULL,
+			user_type INT NOT NULL,
+			PRIMARY KEY (group_idp, group_id, user_idp, user_id),
+			INDEX group_members_user (user_id, user_idp)
+		)`,
+	},
+}
+
+// Open opens the database described by c, creating the tables if needed.
+func Open(ctx context.Context, c *Config) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
+	switch c.Engine {
+	case "sqlite":
+		if err := os.MkdirAll(filepath.Dir(c.DBFile), 0700); err != nil {
+			return nil, errors.Wrap(err, "sql: error creating identity folder")
+		}
+		// writers are serialized by sqlite, wait for the lock instead of failing
+		db, err = sql.Open("sqlite3", "file:"+c.DBFile+"?_busy_timeout=5000&_txlock=immediate")
+		if err != nil {
+			return nil, errors.Wrap(err, "sql: error opening the sqlite database")
+		}
+	case "mysql":
+		db, err = sql.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s)/%s", c.DBUsername, c.DBPassword, c.DBAddress, c.DBName))
+		if err != nil {
+			return nil, errors.Wrap(err, "sql: error opening connection to mysql database")
+		}
+	default:
+		return nil, errors.New("sql: unknown database engine " + c.Engine)
+	}
+
+	for _, stmt := range schemas[c.Engine] {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, errors.Wrap(err, "sql: error creating the identity tables")
+		}
+	}
+	return db, nil
+}
+
+// firstIDNumber is the first uid and gid number allocated to
+// the users and groups created without one.
+const firstIDNumber = 10000
+
+// NextIDNumber returns the number to allocate in column of table,
+// that is the highest one in use plus one. It must be called in the
+// transaction inserting the number: the rows are locked until it ends
+// in mysql, while the sqlite transactions are already serialized.
+func NextIDNumber(ctx context.Context, tx *sql.Tx, engine, table, column string) (int64, error) {
+	query := "SELECT MAX(" + column + ") FROM " + table
+	if engine == "mysql" {
+		query += " FOR UPDATE"
+	}
+	var max sql.NullInt64
+	if err := tx.QueryRowContext(ctx, query).Scan(&max); err != nil {
+		return 0, err
+	}
+	if max.Int64 < firstIDNumber {
+		return firstIDNumber, nil
+	}
+	return max.Int64 + 1, nil
+}
+
+// IsDuplicate returns whether err reports the violation of a unique constraint.
+func IsDuplicate(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrConstraint
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1062 // ER_DUP_ENTRY
+	}
+	return false
+}
+
+// Like returns the pattern of a LIKE clause escaped with '!'
+// matching the values containing s.
+func Like(s string) string {
+	s = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(strings.ToLower(s))
+	return "%" + s + "%"
+}
+
+type manager struct {
+	db     *sql.DB
+	engine string
+}
+
+// New returns a user manager reading the users from a sql database.
+func New(ctx context.Context, m map[string]interface{}) (user.Manager, error) {
+	var c Config
+	if err := cfg.Decode(m, &c); err != nil {
+		return nil, err
+	}
+
+	db, err := Open(ctx, &c)
+	if err != nil {
+		return nil, err
+	}
+	return &manager{db: db, engine: c.Engine}, nil
+}
+
+func (m *manager) GetUser(ctx context.Context, uid *userpb.UserId, skipFetchingGroups bool) (*userpb.User, error) {
+	query := "SELECT " + UserColumns + " FROM users WHERE (opaque_id=? OR username=?)"
+	params := []any{uid.OpaqueId, uid.OpaqueId}
+	if uid.Idp != "" {
+		query += " AND idp=?"
+		params = append(params, uid.Idp)
+	}
+	return m.getUser(ctx, uid.OpaqueId, skipFetchingGroups, query, params...)
+}
+
+func (m *manager) GetUserByClaim(ctx context.Context, claim, value string, skipFetchingGroups bool) (*userpb.User, error) {
+	var column string
+	var param any = value
+	switch claim {
+	case "mail":
+		column = "mail"
+	case "username":
+		column = "username"
+	case "uid":
+		uid, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errtypes.BadRequest("sql: invalid uid " + value)
+		}
+		column, param = "uid_number", uid
+	default:
+		return nil, errtypes.NotSupported("sql: invalid field " + claim)
+	}
+	return m.getUser(ctx, value, skipFetchingGroups, "SELECT "+UserColumns+" FROM users WHERE "+column+"=?", param)
+}
+
+func (m *manager) getUser(ctx context.Context, key string, skipFetchingGroups bool, query string, params ...any) (*userpb.User, error) {
+	u, err := ScanUser(m.db.QueryRowContext(ctx, query, params...))
+	if err == sql.ErrNoRows {
+		return nil, errtypes.NotFound(key)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error getting user")
+	}
+
+	if !skipFetchingGroups {
+		if u.Groups, err = m.GetUserGroups(ctx, u.Id); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// UserColumns are the columns to select to scan a user with ScanUser.
+const UserColumns = "idp, opaque_id, type, username, mail, mail_verified, display_name, uid_number, gid_number"
+
+// ScanUser scans a user selected with the user columns from row.
+func ScanUser(row interface{ Scan(...any) error }) (*userpb.User, error) {
+	u := &userpb.User{Id: &userpb.UserId{}}
+	var userType int32
+	if err := row.Scan(&u.Id.Idp, &u.Id.OpaqueId, &userType, &u.Username, &u.Mail, &u.MailVerified,
+		&u.DisplayName, &u.UidNumber, &u.GidNumber); err != nil {
+		return nil, err
+	}
+	u.Id.Type = userpb.UserType(userType)
+	return u, nil
+}
+
+func (m *manager) GetUserGroups(ctx context.Context, uid *userpb.UserId) ([]string, error) {
+	return UserGroups(ctx, m.db, uid)
+}
+
+// UserGroups returns the names of the groups uid is a member of.
+func UserGroups(ctx context.Context, db *sql.DB, uid *userpb.UserId) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT g.group_name FROM group_members m
+		JOIN user_groups g ON g.idp=m.group_idp AND g.opaque_id=m.group_id
+		WHERE m.user_idp=? AND m.user_id=? ORDER BY g.group_name`, uid.Idp, uid.OpaqueId)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error getting user groups")
+	}
+	defer rows.Close()
+
+	groups := []string{}
+	for rows.Next() {
+		var g string
+		if err := rows.Scan(&g); err != nil {
+			return nil, errors.Wrap(err, "sql: error scanning user groups")
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (m *manager) FindUsers(ctx context.Context, query string, skipFetchingGroups bool) ([]*userpb.User, error) {
+	pattern := Like(query)
+	rows, err := m.db.QueryContext(ctx, "SELECT "+UserColumns+` FROM users
+		WHERE LOWER(username) LIKE ? ESCAPE '!' OR LOWER(display_name) LIKE ? ESCAPE '!'
+		OR LOWER(mail) LIKE ? ESCAPE '!' OR LOWER(opaque_id) LIKE ? ESCAPE '!'
+		ORDER BY username`, pattern, pattern, pattern, pattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error finding users")
+	}
+	defer rows.Close()
+
+	users := []*userpb.User{}
+	for rows.Next() {
+		u, err := ScanUser(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "sql: error scanning user")
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "sql: error finding users")
+	}
+	rows.Close()
+
+	if !skipFetchingGroups {
+		for _, u := range users {
+			if u.Groups, err = m.GetUserGroups(ctx, u.Id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return users, nil
+}
+
+func (m *manager) CreateUser(ctx context.Context, u *userpb.User) (*userpb.User, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "sql: error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	u = proto.Clone(u).(*userpb.User)
+	u.Groups = nil
+	if u.UidNumber == 0 {
+		if u.UidNumber, err = NextIDNumber(ctx, tx, m.engine, "users", "uid_number"); err != nil {
+			return nil, errors.Wrap(err, "sql: error allocating uid number")
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO users ("+UserColumns+", password) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, '')",
+		u.Id.Idp, u.Id.OpaqueId, int32(u.Id.Type), u.Username, u.Mail, u.MailVerified, u.DisplayName, u.UidNumber, u.GidNumber); err != nil {
+		if IsDuplicate(err) {
+			return nil, errtypes.AlreadyExists(u.Username)
+		}
+		return nil, errors.Wrap(err, "sql: error creating user")
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Wrap(err, "sql: error creating user")
+	}
+	return u, nil
+}
+
+func (m *manager) UpdateUser(ctx context.Context, u *userpb.User) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "sql: error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// the rows affected by an update do not include the unchanged ones in mysql,
+	// so the user is looked up first to tell apart a missing user
+	var n int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE idp=? AND opaque_id=?", u.Id.Idp, u.Id.OpaqueId).Scan(&n); err != nil {
+		return errors.Wrap(err, "sql: error getting user")
+	}
+	if n == 0 {
+		return errtypes.NotFound(u.Id.OpaqueId)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET type=?, username=?, mail=?, mail_verified=?, display_name=?, uid_number=?, gid_number=?
+		WHERE idp=? AND opaque_id=?`, int32(u.Id.Type), u.Username, u.Mail, u.MailVerified, u.DisplayName, u.UidNumber, u.GidNumber,
+		u.Id.Idp, u.Id.OpaqueId); err != nil {
+		if IsDuplicate(err) {
+			return errtypes.AlreadyExists(u.Username)
+		}
+		return errors.Wrap(err, "sql: error updating user")
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE group_members SET user_type=? WHERE user_idp=? AND user_id=?",
+		int32(u.Id.Type), u.Id.Idp, u.Id.OpaqueId); err != nil {
+		return errors.Wrap(err, "sql: error updating user")
+	}
+	return errors.Wrap(tx.Commit(), "sql: error updating user")
+}
+
+func (m *manager) SetPassword(ctx context.Context, uid *userpb.UserId, hash string) error {
+	res, err := m.db.ExecContext(ctx, "UPDATE users SET password=? WHERE idp=? AND opaque_id=?", hash, uid.Idp, uid.OpaqueId)
+	if err != nil {
+		return errors.Wrap(err, "sql: error setting password")
+	}
+	// the hash is salted, so a matching row is always changed
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errtypes.NotFound(uid.OpaqueId)
+	}
+	return nil
+}
+
+func (m *manager) SetActive(ctx context.Context, uid *userpb.UserId, active bool) error {
+	res, err := m.db.ExecContext(ctx, "UPDATE users SET active=? WHERE idp=? AND opaque_id=?", active, uid.Idp, uid.OpaqueId)
+	if err != nil {
+		return errors.Wrap(err, "sql: error setting user active")
+	}
+	// the rows affected by an update do not include the unchanged ones in mysql,
+	// so the user is looked up to tell apart a missing user
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		_, err := m.IsActive(ctx, uid)
+		return err
+	}
+	return nil
+}
+
+func (m *manager) IsActive(ctx context.Context, uid *userpb.UserId) (bool, error) {
+	var active bool
+	err := m.db.QueryRowContext(ctx, "SELECT active FROM users WHERE idp=? AND opaque_id=?", uid.Idp, uid.OpaqueId).Scan(&active)
+	switch {
+	case err == sql.ErrNoRows:
+		return false, errtypes.NotFound(uid.OpaqueId)
+	case err != nil:
+		return false, errors.Wrap(err, "sql: error getting user active")
+	}
+	return active, nil
+}
+
+func (m *manager) DeleteUser(ctx context.Context, uid *userpb.UserId) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "sql: error starting transaction")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM users WHERE idp=? AND opaque_id=?", uid.Idp, uid.OpaqueId)
+	if err != nil {
+		return errors.Wrap(err, "sql: error deleting user")
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return errors.Wrap(err, "sql: error deleting user")
+	} else if n == 0 {
+		return errtypes.NotFound(uid.OpaqueId)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM group_members WHERE user_idp=? AND user_id=?", uid.Idp, uid.OpaqueId); err != nil {
+		return errors.Wrap(err, "sql: error deleting user memberships")
+	}
+	return errors.Wrap(tx.Commit(), "sql: error deleting user")
+}
@@ -0,0 +1,307 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/cs3org/reva/pkg/errtypes"
+)
+
+func newManager(t *testing.T) *manager {
+	ctx := context.Background()
+	mgr, err := New(ctx, map[string]interface{}{"db_file": filepath.Join(t.TempDir(), "identity.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := mgr.(*manager)
+	t.Cleanup(func() { m.db.Close() })
+
+	for _, stmt := range []string{
+		`INSERT INTO users VALUES ('cernbox.cern.ch', '4c510ada-c86b-4815-8820-42cdf82c3d51', 1, 'einstein', 'einstein@example.org', 1, 'Albert Einstein', 123, 987, '', 1)`,
+		`INSERT INTO users VALUES ('cernbox.cern.ch', 'f7fbf8c8-139b-4376-b307-cf0a8c2d0d9c', 1, 'marie', 'marie@example.org', 0, 'Marie Curie', 456, 987, '', 1)`,
+		`INSERT INTO users VALUES ('cesnet.cz', '932b4540-8d16-481e-8ef4-588e4b6b151c', 1, 'richard', 'richard_feynman@example.org', 0, 'Richard Feynman', 789, 987, '', 1)`,
+		`INSERT INTO user_groups VALUES ('cernbox.cern.ch', 'sailing-lovers', 'sailing-lovers', 'sailing@example.org', 'Sailing Lovers', 1234)`,
+		`INSERT INTO user_groups VALUES ('cernbox.cern.ch', 'physics-lovers', 'physics-lovers', 'physics@example.org', 'Physics Lovers', 4567)`,
+		`INSERT INTO group_members VALUES ('cernbox.cern.ch', 'sailing-lovers', 'cernbox.cern.ch', '4c510ada-c86b-4815-8820-42cdf82c3d51', 1)`,
+		`INSERT INTO group_members VALUES ('cernbox.cern.ch', 'physics-lovers', 'cernbox.cern.ch', '4c510ada-c86b-4815-8820-42cdf82c3d51', 1)`,
+	} {
+		if _, err := m.db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+func TestGetUser(t *testing.T) {
+	m := newManager(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		description string
+		uid         *userpb.UserId
+		expected    string
+		groups      []string
+	}{
+		{
+			description: "by opaque id",
+			uid:         &userpb.UserId{OpaqueId: "4c510ada-c86b-4815-8820-42cdf82c3d51", Idp: "cernbox.cern.ch"},
+			expected:    "einstein",
+			groups:      []string{"physics-lovers", "sailing-lovers"},
+		},
+		{
+			description: "by username without idp",
+			uid:         &userpb.UserId{OpaqueId: "marie"},
+			expected:    "marie",
+			groups:      []string{},
+		},
+		{
+			description: "wrong idp",
+			uid:         &userpb.UserId{OpaqueId: "richard", Idp: "cernbox.cern.ch"},
+		},
+		{
+			description: "unknown user",
+			uid:         &userpb.UserId{OpaqueId: "bob"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			u, err := m.GetUser(ctx, tt.uid, false)
+			if tt.expected == "" {
+				if _, ok := err.(errtypes.IsNotFound); !ok {
+					t.Fatalf("expected not found error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Username != tt.expected || u.Id.Type != userpb.UserType_USER_TYPE_PRIMARY {
+				t.Fatalf("got user %+v, expected %s", u, tt.expected)
+			}
+			if len(u.Groups) != len(tt.groups) {
+				t.Fatalf("got groups %v, expected %v", u.Groups, tt.groups)
+			}
+			for i := range u.Groups {
+				if u.Groups[i] != tt.groups[i] {
+					t.Fatalf("got groups %v, expected %v", u.Groups, tt.groups)
+				}
+			}
+		})
+	}
+}
+
+func TestGetUserByClaim(t *testing.T) {
+	m := newManager(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		description string
+		claim       string
+		value       string
+		expected    string
+		err         error
+	}{
+		{description: "mail", claim: "mail", value: "marie@example.org", expected: "marie"},
+		{description: "username", claim: "username", value: "einstein", expected: "einstein"},
+		{description: "uid", claim: "uid", value: "789", expected: "richard"},
+		{description: "unknown mail", claim: "mail", value: "bob@example.org", err: errtypes.NotFound("")},
+		{description: "invalid uid", claim: "uid", value: "abc", err: errtypes.BadRequest("")},
+		{description: "unsupported claim", claim: "display_name", value: "Marie Curie", err: errtypes.NotSupported("")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			u, err := m.GetUserByClaim(ctx, tt.claim, tt.value, true)
+			switch tt.err.(type) {
+			case nil:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if u.Username != tt.expected || u.Groups != nil {
+					t.Fatalf("got user %+v, expected %s without groups", u, tt.expected)
+				}
+			case errtypes.NotFound:
+				if _, ok := err.(errtypes.IsNotFound); !ok {
+					t.Fatalf("expected not found error, got %v", err)
+				}
+			case errtypes.BadRequest:
+				if _, ok := err.(errtypes.IsBadRequest); !ok {
+					t.Fatalf("expected bad request error, got %v", err)
+				}
+			case errtypes.NotSupported:
+				if _, ok := err.(errtypes.IsNotSupported); !ok {
+					t.Fatalf("expected not supported error, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestFindUsers(t *testing.T) {
+	m := newManager(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		description string
+		query       string
+		expected    []string
+	}{
+		{description: "username", query: "ein", expected: []string{"einstein"}},
+		{description: "case insensitive display name", query: "CURIE", expected: []string{"marie"}},
+		{description: "mail", query: "example.org", expected: []string{"einstein", "marie", "richard"}},
+		{description: "opaque id", query: "932b4540", expected: []string{"richard"}},
+		{description: "wildcards are escaped", query: "_", expected: []string{"richard"}},
+		{description: "no match", query: "bob", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			users, err := m.FindUsers(ctx, tt.query, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(users) != len(tt.expected) {
+				t.Fatalf("got %d users, expected %v", len(users), tt.expected)
+			}
+			for i, u := range users {
+				if u.Username != tt.expected[i] {
+					t.Fatalf("got user %s, expected %s", u.Username, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProvisioning(t *testing.T) {
+	m := newManager(t)
+	ctx := context.Background()
+
+	bob := &userpb.User{
+		Id:          &userpb.UserId{OpaqueId: "e4fb0282-fabf-4cff-b1ee-90bdc01c4eef", Idp: "cernbox.cern.ch", Type: userpb.UserType_USER_TYPE_PRIMARY},
+		Username:    "bob",
+		Mail:        "bob@example.org",
+		DisplayName: "Bob",
+		GidNumber:   987,
+	}
+	created, err := m.CreateUser(ctx, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.UidNumber != firstIDNumber {
+		t.Fatalf("got uid number %d, expected %d", created.UidNumber, firstIDNumber)
+	}
+
+	if _, err := m.CreateUser(ctx, &userpb.User{Id: &userpb.UserId{OpaqueId: "other", Idp: "cernbox.cern.ch"}, Username: "bob"}); err == nil {
+		t.Fatal("expected usernames to be unique")
+	} else if _, ok := err.(errtypes.IsAlreadyExists); !ok {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+
+	created.DisplayName = "Bob Marley"
+	if err := m.UpdateUser(ctx, created); err != nil {
+		t.Fatal(err)
+	}
+	u, err := m.GetUserByClaim(ctx, "uid", "10000", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.DisplayName != "Bob Marley" {
+		t.Fatalf("user was not updated: %+v", u)
+	}
+	// updating without changes must not report the user as missing
+	if err := m.UpdateUser(ctx, created); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.SetPassword(ctx, created.Id, "$2y$10$hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	if active, err := m.IsActive(ctx, created.Id); err != nil || !active {
+		t.Fatalf("expected the new user to be active, got %t %v", active, err)
+	}
+	// deactivating twice must not report the user as missing
+	for i := 0; i < 2; i++ {
+		if err := m.SetActive(ctx, created.Id, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if active, err := m.IsActive(ctx, created.Id); err != nil || active {
+		t.Fatalf("expected the user to be inactive, got %t %v", active, err)
+	}
+
+	einstein := &userpb.UserId{OpaqueId: "4c510ada-c86b-4815-8820-42cdf82c3d51", Idp: "cernbox.cern.ch"}
+	if err := m.DeleteUser(ctx, einstein); err != nil {
+		t.Fatal(err)
+	}
+	if groups, err := m.GetUserGroups(ctx, einstein); err != nil || len(groups) != 0 {
+		t.Fatalf("expected the memberships to be deleted, got %v %v", groups, err)
+	}
+
+	for _, err := range []error{
+		m.UpdateUser(ctx, &userpb.User{Id: einstein}),
+		m.SetPassword(ctx, einstein, "$2y$10$hash"),
+		m.SetActive(ctx, einstein, false),
+		m.DeleteUser(ctx, einstein),
+	} {
+		if _, ok := err.(errtypes.IsNotFound); !ok {
+			t.Fatalf("expected not found error, got %v", err)
+		}
+	}
+}
+
+func TestConcurrentUidNumbers(t *testing.T) {
+	m := newManager(t)
+	ctx := context.Background()
+
+	const n = 10
+	uids := make([]int64, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			u, err := m.CreateUser(ctx, &userpb.User{
+				Id:       &userpb.UserId{OpaqueId: fmt.Sprintf("user-%d", i), Idp: "cernbox.cern.ch"},
+				Username: fmt.Sprintf("user-%d", i),
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			uids[i] = u.UidNumber
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[int64]bool{}
+	for _, uid := range uids {
+		if seen[uid] {
+			t.Fatalf("uid number %d allocated twice: %v", uid, uids)
+		}
+		seen[uid] = true
+	}
+}
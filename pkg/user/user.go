@@ -37,3 +37,23 @@ type Manager interface {
 	// FindUsers returns all the user objects which match a query parameter.
 	FindUsers(ctx context.Context, query string, skipFetchingGroups bool) ([]*userpb.User, error)
 }
+
+// Provisioner is the interface implemented by the user managers
+// that allow the users to be created, updated and deleted.
+type Provisioner interface {
+	Manager
+	// CreateUser creates the user u, allocating its uid number if not set.
+	// It returns the created user.
+	CreateUser(ctx context.Context, u *userpb.User) (*userpb.User, error)
+	// UpdateUser replaces the metadata of the user identified by u.Id.
+	// The groups of u are ignored.
+	UpdateUser(ctx context.Context, u *userpb.User) error
+	// SetPassword sets the password hash used to authenticate the user.
+	SetPassword(ctx context.Context, uid *userpb.UserId, hash string) error
+	// SetActive enables or disables the authentication of the user.
+	SetActive(ctx context.Context, uid *userpb.UserId, active bool) error
+	// IsActive tells whether the user is allowed to authenticate.
+	IsActive(ctx context.Context, uid *userpb.UserId) (bool, error)
+	// DeleteUser deletes the user identified by uid and its group memberships.
+	DeleteUser(ctx context.Context, uid *userpb.UserId) error
+}
@@ -0,0 +1,62 @@
+// Copyright 2018-2024 CERN
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// In applying this license, CERN does not waive the privileges and immunities
+// granted to it by virtue of its status as an Intergovernmental Organization
+// or submit itself to any jurisdiction.
+
+// migrate-json-shares imports the shares stored by the json share manager
+// into the sql share manager.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cs3org/reva/pkg/share/manager/sql"
+)
+
+func main() {
+	file := flag.String("file", "/var/tmp/reva/shares.json", "the file of the json share manager")
+	engine := flag.String("engine", "sqlite", "the database engine, either sqlite or mysql")
+	dbFile := flag.String("db-file", "/var/tmp/reva/shares.db", "the sqlite file holding the shares")
+	dbUsername := flag.String("db-username", "", "the username to connect to the mysql database")
+	dbPassword := flag.String("db-password", "", "the password to connect to the mysql database")
+	dbAddress := flag.String("db-address", "localhost:3306", "the address of the mysql database")
+	dbName := flag.String("db-name", "reva", "the name of the mysql database")
+	flag.Parse()
+
+	ctx := context.Background()
+	m, err := sql.New(ctx, map[string]interface{}{
+		"engine":      *engine,
+		"db_file":     *dbFile,
+		"db_username": *dbUsername,
+		"db_password": *dbPassword,
+		"db_address":  *dbAddress,
+		"db_name":     *dbName,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	n, err := sql.ImportJSON(ctx, m, *file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("imported %d shares from %s\n", n, *file)
+}